@@ -0,0 +1,287 @@
+package archive
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+const (
+	dayPartition    = "2006-01-02"
+	monthPartition  = "2006-01"
+	maxEmptyBuckets = 5 // the longest run of buckets a quiet product is taken to have gone without a trade
+)
+
+// Gap is a run of buckets missing from the archive, From the first missing bucket and To the one after the last.
+type Gap struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Missing int       `json:"missing"`
+}
+
+// CandleArchive keeps closed candles on disk per symbol and candle size, as JSONL files partitioned by UTC day
+// (by month from 1h up): <dir>/<symbol>/<candle size>/<partition>.jsonl. Files are only ever appended to, so a
+// bucket written twice is read back as its last write.
+type CandleArchive struct {
+	mu      sync.Mutex
+	dir     string
+	running map[string]models.Candle // symbol/candle size -> the feed's candle for the bucket still open
+}
+
+func Open(dir string) (*CandleArchive, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("open candle archive %s: %w", dir, err)
+	}
+	return &CandleArchive{dir: dir, running: make(map[string]models.Candle)}, nil
+}
+
+// Write appends candles whose bucket has closed. The exchanges include the one still open in their history, it
+// is left for Record or a later fetch to write once it is final.
+func (a *CandleArchive) Write(symbol string, candleSize enum.CandleSize, candles []models.Candle) error {
+	size := enum.GetTimeDurationFromCandleSize(candleSize)
+	now := time.Now()
+	partitions := make(map[string][]models.Candle)
+	for _, c := range candles {
+		if c.Start.Add(size).After(now) {
+			continue
+		}
+		c.ProductID = symbol
+		name := getPartitionName(c.Start, size)
+		partitions[name] = append(partitions[name], c)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for name, batch := range partitions {
+		if err := a.appendPartition(symbol, candleSize, name, batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteMissing appends only the closed candles whose bucket the archive doesn't have yet, so history fetched again
+// on every seed fills the archive's gaps and extends it without writing the buckets it already holds once more.
+func (a *CandleArchive) WriteMissing(symbol string, candleSize enum.CandleSize, candles []models.Candle) error {
+	if len(candles) == 0 {
+		return nil
+	}
+	from, to := candles[0].Start, candles[0].Start
+	for _, c := range candles {
+		from = minTime(from, c.Start)
+		to = maxTime(to, c.Start)
+	}
+	archived, err := a.Read(symbol, candleSize, from, to.Add(time.Nanosecond))
+	if err != nil {
+		return err
+	}
+	have := make(map[int64]bool, len(archived))
+	for _, c := range archived {
+		have[c.Start.Unix()] = true
+	}
+	missing := make([]models.Candle, 0, len(candles))
+	for _, c := range candles {
+		if !have[c.Start.Unix()] {
+			missing = append(missing, c)
+		}
+	}
+	return a.Write(symbol, candleSize, missing)
+}
+
+// Record takes a running candle off an exchange feed. The feed sends the same bucket again with every update, so
+// the bucket is only written once the first update of the next one shows it has closed.
+func (a *CandleArchive) Record(candleSize enum.CandleSize, candle models.Candle) error {
+	key := candle.ProductID + "/" + candleSize.String()
+	a.mu.Lock()
+	last, ok := a.running[key]
+	a.running[key] = candle
+	a.mu.Unlock()
+	if !ok || !candle.Start.After(last.Start) {
+		return nil
+	}
+	return a.Write(last.ProductID, candleSize, []models.Candle{last})
+}
+
+// Read returns the symbol's candles with from <= Start < to, oldest first. A zero from or to leaves that side
+// open.
+func (a *CandleArchive) Read(symbol string, candleSize enum.CandleSize, from time.Time, to time.Time) ([]models.Candle, error) {
+	size := enum.GetTimeDurationFromCandleSize(candleSize)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entries, err := os.ReadDir(a.getSeriesDir(symbol, candleSize))
+	if os.IsNotExist(err) {
+		return []models.Candle{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	byStart := make(map[int64]models.Candle)
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".jsonl")
+		start, end, ok := getPartitionRange(name, size)
+		if !ok || (!from.IsZero() && !end.After(from)) || (!to.IsZero() && !start.Before(to)) {
+			continue
+		}
+		if err := a.readPartition(filepath.Join(a.getSeriesDir(symbol, candleSize), entry.Name()), byStart); err != nil {
+			return nil, err
+		}
+	}
+
+	candles := make([]models.Candle, 0, len(byStart))
+	for _, c := range byStart {
+		if (!from.IsZero() && c.Start.Before(from)) || (!to.IsZero() && !c.Start.Before(to)) {
+			continue
+		}
+		candles = append(candles, c)
+	}
+	sort.Slice(candles, func(i, j int) bool { return candles[i].Start.Before(candles[j].Start) })
+	return candles, nil
+}
+
+// GetLastClosedCandles is the last count candles up to the bucket that closed most recently, if the archive has
+// them. Exchanges leave out the buckets without a trade, so a run of up to maxEmptyBuckets missing, the most recent
+// ones included, is taken for a quiet spell. Anything longer and the caller should fetch the history from the
+// exchange instead.
+func (a *CandleArchive) GetLastClosedCandles(symbol string, candleSize enum.CandleSize, count int) ([]models.Candle, bool) {
+	size := enum.GetTimeDurationFromCandleSize(candleSize)
+	end := time.Now().Truncate(size)
+	from := end.Add(-time.Duration(2*count) * size) // room for the buckets a quiet product skipped
+	candles, err := a.Read(symbol, candleSize, from, end)
+	if err != nil || count <= 0 || len(candles) < count {
+		return nil, false
+	}
+	candles = candles[len(candles)-count:]
+	for _, gap := range a.findGaps(candles, size, candles[0].Start, end) {
+		if gap.Missing > maxEmptyBuckets {
+			return nil, false
+		}
+	}
+	return candles, true
+}
+
+// FindGaps lists the buckets between from and to the archive has no candle for. A zero from starts at the first
+// candle archived, a zero to ends after the last.
+func (a *CandleArchive) FindGaps(symbol string, candleSize enum.CandleSize, from time.Time, to time.Time) ([]Gap, error) {
+	candles, err := a.Read(symbol, candleSize, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return a.findGaps(candles, enum.GetTimeDurationFromCandleSize(candleSize), from, to), nil
+}
+
+func (a *CandleArchive) findGaps(candles []models.Candle, size time.Duration, from time.Time, to time.Time) []Gap {
+	gaps := make([]Gap, 0)
+	if len(candles) == 0 {
+		if !from.IsZero() && !to.IsZero() && to.After(from) {
+			gaps = append(gaps, getGap(from.Truncate(size), to, size))
+		}
+		return gaps
+	}
+
+	expected := candles[0].Start
+	if !from.IsZero() {
+		expected = from.Add(size - 1).Truncate(size) // the first bucket starting at or after from
+	}
+	for _, c := range candles {
+		if c.Start.After(expected) {
+			gaps = append(gaps, getGap(expected, c.Start, size))
+		}
+		expected = c.Start.Add(size)
+	}
+	if !to.IsZero() && to.After(expected) {
+		gaps = append(gaps, getGap(expected, to, size))
+	}
+	return gaps
+}
+
+func minTime(a time.Time, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+func maxTime(a time.Time, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+func getGap(from time.Time, to time.Time, size time.Duration) Gap {
+	return Gap{From: from, To: to, Missing: int((to.Sub(from) + size - 1) / size)}
+}
+
+func (a *CandleArchive) getSeriesDir(symbol string, candleSize enum.CandleSize) string {
+	return filepath.Join(a.dir, symbol, candleSize.String())
+}
+
+func (a *CandleArchive) appendPartition(symbol string, candleSize enum.CandleSize, name string, candles []models.Candle) error {
+	dir := a.getSeriesDir(symbol, candleSize)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, name+".jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	for _, c := range candles {
+		raw, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(append(raw, '\n')); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func (a *CandleArchive) readPartition(path string, byStart map[int64]models.Candle) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var c models.Candle
+		if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+			continue // a torn last line from a crash mid-write
+		}
+		byStart[c.Start.Unix()] = c
+	}
+	return scanner.Err()
+}
+
+func getPartitionName(start time.Time, size time.Duration) string {
+	if size >= time.Hour {
+		return start.UTC().Format(monthPartition)
+	}
+	return start.UTC().Format(dayPartition)
+}
+
+func getPartitionRange(name string, size time.Duration) (time.Time, time.Time, bool) {
+	if size >= time.Hour {
+		start, err := time.Parse(monthPartition, name)
+		return start, start.AddDate(0, 1, 0), err == nil
+	}
+	start, err := time.Parse(dayPartition, name)
+	return start, start.AddDate(0, 0, 1), err == nil
+}
@@ -0,0 +1,113 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+// closedCandles is one 1m candle for each of the n buckets that closed last, oldest first, less the buckets
+// skip holds, counted back from the newest.
+func closedCandles(n int, skip ...int) []models.Candle {
+	end := time.Now().Truncate(time.Minute)
+	candles := make([]models.Candle, 0, n)
+	for i := n; i >= 1; i-- {
+		skipped := false
+		for _, s := range skip {
+			skipped = skipped || s == i
+		}
+		if !skipped {
+			candles = append(candles, models.Candle{Start: end.Add(-time.Duration(i) * time.Minute), Open: 1, High: 2, Low: 1, Close: 2, Volume: 1})
+		}
+	}
+	return candles
+}
+
+func TestGetLastClosedCandles(t *testing.T) {
+	tests := []struct {
+		name    string
+		candles []models.Candle
+		count   int
+		wantOk  bool
+	}{
+		{name: "every bucket", candles: closedCandles(30), count: 20, wantOk: true},
+		{name: "a quiet spell", candles: closedCandles(30, 5, 6, 7), count: 20, wantOk: true},
+		{name: "newest buckets quiet", candles: closedCandles(30, 1, 2), count: 20, wantOk: true},
+		{name: "too few candles", candles: closedCandles(15), count: 20},
+		{name: "a hole longer than a quiet spell", candles: closedCandles(40, 10, 11, 12, 13, 14, 15, 16), count: 20},
+		{name: "archive behind", candles: closedCandles(40)[:30], count: 20},
+		{name: "nothing archived", count: 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := Open(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := a.Write("ETH-USD", enum.CandleSize1m, tt.candles); err != nil {
+				t.Fatal(err)
+			}
+			got, ok := a.GetLastClosedCandles("ETH-USD", enum.CandleSize1m, tt.count)
+			if ok != tt.wantOk {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOk)
+			}
+			if ok && len(got) != tt.count {
+				t.Errorf("%d candles, want %d", len(got), tt.count)
+			}
+		})
+	}
+}
+
+func TestWriteMissing(t *testing.T) {
+	tests := []struct {
+		name     string
+		archived []models.Candle
+		fetched  []models.Candle
+		want     int // lines across the series' files after the write
+	}{
+		{name: "empty archive", fetched: closedCandles(10), want: 10},
+		{name: "same history again", archived: closedCandles(10), fetched: closedCandles(10), want: 10},
+		{name: "newer candles", archived: closedCandles(10)[:6], fetched: closedCandles(10), want: 10},
+		{name: "fills a gap", archived: closedCandles(10, 4, 5), fetched: closedCandles(10), want: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			a, err := Open(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := a.Write("ETH-USD", enum.CandleSize1m, tt.archived); err != nil {
+				t.Fatal(err)
+			}
+			if err := a.WriteMissing("ETH-USD", enum.CandleSize1m, tt.fetched); err != nil {
+				t.Fatal(err)
+			}
+			if got := countLines(t, a.getSeriesDir("ETH-USD", enum.CandleSize1m)); got != tt.want {
+				t.Errorf("%d lines archived, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func countLines(t *testing.T, dir string) int {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := 0
+	for _, f := range files {
+		raw, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines += strings.Count(string(raw), "\n")
+	}
+	return lines
+}
@@ -0,0 +1,48 @@
+package archive
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+// WriteCandlesCSV writes a start,open,high,low,close,volume header and one row per candle, start as RFC3339, which
+// is what backtest.ReadCandlesCSV reads back.
+func WriteCandlesCSV(w io.Writer, candles []models.Candle) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"start", "open", "high", "low", "close", "volume"}); err != nil {
+		return err
+	}
+	for _, c := range candles {
+		row := []string{
+			c.Start.UTC().Format(time.RFC3339),
+			strconv.FormatFloat(c.Open, 'f', -1, 64),
+			strconv.FormatFloat(c.High, 'f', -1, 64),
+			strconv.FormatFloat(c.Low, 'f', -1, 64),
+			strconv.FormatFloat(c.Close, 'f', -1, 64),
+			strconv.FormatFloat(c.Volume, 'f', -1, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteCandlesJSONL writes one models.Candle JSON object per line.
+func WriteCandlesJSONL(w io.Writer, candles []models.Candle) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, c := range candles {
+		if err := enc.Encode(c); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,163 @@
+package backtest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+	cb_models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models/coinbase"
+)
+
+// LoadCandlesFromFile reads a candle series from a .csv, .json or .jsonl file and returns it sorted by start time.
+//
+// CSV files are read as start,open,high,low,close,volume unless a header row names the columns. JSON files may be
+// either an array of candles or a Coinbase historical candles response ({"candles": [...]}). Start times may be
+// unix seconds, unix milliseconds or RFC3339.
+func LoadCandlesFromFile(path string, symbol string) ([]models.Candle, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var candles []models.Candle
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		candles, err = ReadCandlesCSV(bytes.NewReader(raw), symbol)
+	case ".json":
+		candles, err = readCandlesJSON(raw, symbol)
+	case ".jsonl", ".ndjson":
+		candles, err = ReadCandlesJSONL(bytes.NewReader(raw), symbol)
+	default:
+		return nil, fmt.Errorf("unsupported candle file extension %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	sort.Slice(candles, func(i, j int) bool { return candles[i].Start.Before(candles[j].Start) })
+	return candles, nil
+}
+
+// ReadCandlesCSV parses start,open,high,low,close,volume rows, honoring a header row if there is one.
+func ReadCandlesCSV(r io.Reader, symbol string) ([]models.Candle, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return []models.Candle{}, nil
+	}
+
+	columns := map[string]int{"start": 0, "open": 1, "high": 2, "low": 3, "close": 4, "volume": 5}
+	if _, err := strconv.ParseFloat(strings.TrimSpace(rows[0][1]), 64); err != nil {
+		columns = map[string]int{}
+		for i, name := range rows[0] {
+			name = strings.ToLower(strings.TrimSpace(name))
+			switch name {
+			case "time", "timestamp", "date", "start":
+				columns["start"] = i
+			default:
+				columns[name] = i
+			}
+		}
+		rows = rows[1:]
+		for _, required := range []string{"start", "open", "high", "low", "close"} {
+			if _, ok := columns[required]; !ok {
+				return nil, fmt.Errorf("csv header is missing a %q column", required)
+			}
+		}
+	}
+
+	candles := make([]models.Candle, 0, len(rows))
+	for lineNo, row := range rows {
+		field := func(name string) (float64, error) {
+			idx, ok := columns[name]
+			if !ok || idx >= len(row) {
+				return 0, nil
+			}
+			return strconv.ParseFloat(strings.TrimSpace(row[idx]), 64)
+		}
+		start, err := ParseCandleTime(row[columns["start"]])
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", lineNo+1, err)
+		}
+		c := models.Candle{Start: start, ProductID: symbol}
+		for name, dst := range map[string]*float64{"open": &c.Open, "high": &c.High, "low": &c.Low, "close": &c.Close, "volume": &c.Volume} {
+			if *dst, err = field(name); err != nil {
+				return nil, fmt.Errorf("row %d column %s: %w", lineNo+1, name, err)
+			}
+		}
+		candles = append(candles, c)
+	}
+	return candles, nil
+}
+
+// ReadCandlesJSONL parses one models.Candle JSON object per line.
+func ReadCandlesJSONL(r io.Reader, symbol string) ([]models.Candle, error) {
+	candles := make([]models.Candle, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var c models.Candle
+		if err := json.Unmarshal(line, &c); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if c.ProductID == "" {
+			c.ProductID = symbol
+		}
+		candles = append(candles, c)
+	}
+	return candles, scanner.Err()
+}
+
+func readCandlesJSON(raw []byte, symbol string) ([]models.Candle, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var resp cb_models.CandlesResponse
+		if err := json.Unmarshal(trimmed, &resp); err != nil {
+			return nil, err
+		}
+		return models.GetDomainCandlesFromHistoricalCandles(symbol, resp.Candles), nil
+	}
+
+	var candles []models.Candle
+	if err := json.Unmarshal(trimmed, &candles); err != nil {
+		return nil, err
+	}
+	for i := range candles {
+		if candles[i].ProductID == "" {
+			candles[i].ProductID = symbol
+		}
+	}
+	return candles, nil
+}
+
+// ParseCandleTime accepts unix seconds, unix milliseconds or RFC3339 timestamps.
+func ParseCandleTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if n > 1e12 {
+			return time.UnixMilli(n).UTC(), nil
+		}
+		return time.Unix(n, 0).UTC(), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,146 @@
+package backtest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/trader"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+// Config describes a single-symbol, single-strategy backtest.
+type Config struct {
+	Symbol        string
+	Strategy      enum.Strategy
+	CandleSize    enum.CandleSize
+	InitialFunds  float64
+	FeeRate       float64 // fraction of notional, e.g. 0.006 for Coinbase taker
+	SlippageBps   float64
+	HistoryWindow int // candles visible to the strategy, the live store keeps 100 or the strategy's warm-up if longer
+	Sizing        trader.SizingCfg
+	AllowShort    bool                                        // act on short and cover signals, otherwise long-only as live traders default to
+	Params        signaler.StrategyParams                     // nil runs the strategy's defaults
+	MemberParams  func(enum.Strategy) signaler.StrategyParams // an ensemble's members' params, nil for their defaults
+	TradeFrom     time.Time                                   // signals of candles starting before it are dropped, those are only history
+}
+
+type Result struct {
+	Symbol      string        `json:"symbol"`
+	Strategy    string        `json:"strategy"`
+	CandleSize  string        `json:"candleSize"`
+	Start       time.Time     `json:"start"`
+	End         time.Time     `json:"end"`
+	Bars        int           `json:"bars"`
+	Metrics     Metrics       `json:"metrics"`
+	Trades      []Trade       `json:"trades"`
+	Fills       []Fill        `json:"fills"`
+	EquityCurve []EquityPoint `json:"equityCurve"`
+}
+
+func DefaultConfig(symbol string, strategy enum.Strategy, candleSize enum.CandleSize) Config {
+	return Config{
+		Symbol:        symbol,
+		Strategy:      strategy,
+		CandleSize:    candleSize,
+		InitialFunds:  10000,
+		FeeRate:       0.006,
+		SlippageBps:   5,
+		HistoryWindow: 100,
+		Sizing:        trader.DefaultSizingCfg(),
+	}
+}
+
+// Run replays candles through a fresh instance of cfg.Strategy built with cfg.Params and cfg.MemberParams.
+func Run(cfg Config, candles []models.Candle) (*Result, error) {
+	strategy := signaler.NewStrategyWithMemberParams(cfg.Strategy, cfg.Params, cfg.MemberParams)
+	if strategy == nil {
+		return nil, fmt.Errorf("unknown strategy %d", cfg.Strategy)
+	}
+	return RunStrategy(cfg, strategy, candles)
+}
+
+// RunStrategy replays candles through the given strategy instance. Each bar the strategy sees the history up to
+// and including that bar's close; whatever target it produces is traded at the next bar's open, so there is no
+// look-ahead.
+func RunStrategy(cfg Config, strategy signaler.Strategy, candles []models.Candle) (result *Result, err error) {
+	if len(candles) < 2 {
+		return nil, fmt.Errorf("need at least 2 candles to backtest, got %d", len(candles))
+	}
+	if cfg.HistoryWindow <= 0 {
+		cfg.HistoryWindow = 100
+	}
+	// the live store keeps at least the strategy's warm-up, so the replay window does too
+	cfg.HistoryWindow = max(cfg.HistoryWindow, strategy.GetWarmUpCandles())
+
+	ex := NewReplayExchange(cfg.Symbol, cfg.CandleSize, candles, cfg.HistoryWindow)
+	sim := newSimTrader(cfg.InitialFunds, cfg.FeeRate, cfg.SlippageBps, cfg.Sizing, cfg.AllowShort)
+	curve := make([]EquityPoint, 0, len(candles))
+	peak := cfg.InitialFunds
+	barDuration := enum.GetTimeDurationFromCandleSize(cfg.CandleSize)
+
+	strategy.ConfirmSignalDelivered(cfg.Symbol, models.Signal{Symbol: cfg.Symbol, Type: enum.SignalHold})
+
+	bar := 0
+	defer func() {
+		if r := recover(); r != nil {
+			result = nil
+			err = fmt.Errorf("strategy %s panicked at bar %d (%s): %v", cfg.Strategy.String(), bar, candles[bar].Start.Format(time.RFC3339), r)
+		}
+	}()
+
+	for bar = 0; bar < len(candles); bar++ {
+		candle := candles[bar]
+		if bar > 0 {
+			sim.rebalance(candle.Open, candle.Start)
+		}
+
+		ex.SetCursor(bar)
+		strategy.UpdateTrailingStop(cfg.Symbol, models.Ticker{Symbol: cfg.Symbol, Price: candle.Close, Time: candle.Start.Add(barDuration)})
+		signal := signaler.FilterShortSide(signaler.CalculateSignal(strategy, cfg.Symbol, ex), cfg.AllowShort)
+		if candle.Start.Before(cfg.TradeFrom) {
+			signal = models.Signal{Symbol: cfg.Symbol, Type: enum.SignalHold}
+		}
+		signal.Time = candle.Start.Add(barDuration)
+		sim.handleSignal(signal, candle.Close, ex)
+		strategy.ConfirmSignalDelivered(cfg.Symbol, signal)
+
+		equity := sim.equity(candle.Close)
+		if equity > peak {
+			peak = equity
+		}
+		curve = append(curve, EquityPoint{
+			Time:     candle.Start.Add(barDuration),
+			Equity:   equity,
+			Position: sim.tokens * candle.Close,
+			Drawdown: (equity/peak - 1) * 100,
+		})
+	}
+	bar = len(candles) - 1
+
+	// mark any open round trip at the last close so it shows up in the trade list
+	if sim.openTrade != nil {
+		last := candles[len(candles)-1]
+		open := *sim.openTrade
+		open.ExitTime = last.Start.Add(barDuration)
+		open.PnL += (last.Close - sim.avgEntryPrice) * sim.tokens
+		if sim.tradeCost > 0 {
+			open.ReturnPct = open.PnL / sim.tradeCost * 100
+		}
+		sim.trades = append(sim.trades, open)
+	}
+
+	return &Result{
+		Symbol:      cfg.Symbol,
+		Strategy:    cfg.Strategy.String(),
+		CandleSize:  cfg.CandleSize.String(),
+		Start:       candles[0].Start,
+		End:         candles[len(candles)-1].Start.Add(barDuration),
+		Bars:        len(candles),
+		Metrics:     computeMetrics(curve, sim.trades, sim.fills, sim.totalFees, barDuration),
+		Trades:      sim.trades,
+		Fills:       sim.fills,
+		EquityCurve: curve,
+	}, nil
+}
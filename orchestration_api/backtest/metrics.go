@@ -0,0 +1,103 @@
+package backtest
+
+import (
+	"math"
+	"time"
+)
+
+type EquityPoint struct {
+	Time     time.Time `json:"time"`
+	Equity   float64   `json:"equity"`
+	Position float64   `json:"position"`
+	Drawdown float64   `json:"drawdown"`
+}
+
+// Metrics summarises an equity curve. Ratios are annualised assuming 24/7 markets.
+type Metrics struct {
+	InitialEquity float64 `json:"initialEquity"`
+	FinalEquity   float64 `json:"finalEquity"`
+	TotalReturn   float64 `json:"totalReturnPct"`
+	MaxDrawdown   float64 `json:"maxDrawdownPct"`
+	Sharpe        float64 `json:"sharpe"`
+	Sortino       float64 `json:"sortino"`
+	WinRate       float64 `json:"winRatePct"`
+	Exposure      float64 `json:"exposurePct"`
+	NumTrades     int     `json:"numTrades"`
+	NumFills      int     `json:"numFills"`
+	TotalFees     float64 `json:"totalFees"`
+}
+
+func computeMetrics(curve []EquityPoint, trades []Trade, fills []Fill, totalFees float64, barDuration time.Duration) Metrics {
+	m := Metrics{NumTrades: len(trades), NumFills: len(fills), TotalFees: totalFees}
+	if len(curve) == 0 {
+		return m
+	}
+	m.InitialEquity = curve[0].Equity
+	m.FinalEquity = curve[len(curve)-1].Equity
+	if m.InitialEquity > 0 {
+		m.TotalReturn = (m.FinalEquity/m.InitialEquity - 1) * 100
+	}
+
+	exposedBars := 0
+	for _, p := range curve {
+		if p.Position > 0 {
+			exposedBars++
+		}
+		if -p.Drawdown > m.MaxDrawdown {
+			m.MaxDrawdown = -p.Drawdown
+		}
+	}
+	m.Exposure = float64(exposedBars) / float64(len(curve)) * 100
+
+	if len(trades) > 0 {
+		wins := 0
+		for _, tr := range trades {
+			if tr.PnL > 0 {
+				wins++
+			}
+		}
+		m.WinRate = float64(wins) / float64(len(trades)) * 100
+	}
+
+	returns := make([]float64, 0, len(curve))
+	for i := 1; i < len(curve); i++ {
+		if curve[i-1].Equity > 0 {
+			returns = append(returns, curve[i].Equity/curve[i-1].Equity-1)
+		}
+	}
+	periodsPerYear := float64(365*24*time.Hour) / float64(barDuration)
+	m.Sharpe, m.Sortino = sharpeAndSortino(returns, periodsPerYear)
+	return m
+}
+
+func sharpeAndSortino(returns []float64, periodsPerYear float64) (float64, float64) {
+	if len(returns) < 2 {
+		return 0, 0
+	}
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+
+	variance := 0.0
+	downside := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+		if r < 0 {
+			downside += r * r
+		}
+	}
+	std := math.Sqrt(variance / float64(len(returns)-1))
+	downsideDev := math.Sqrt(downside / float64(len(returns)))
+
+	annualiser := math.Sqrt(periodsPerYear)
+	sharpe, sortino := 0.0, 0.0
+	if std > 0 {
+		sharpe = mean / std * annualiser
+	}
+	if downsideDev > 0 {
+		sortino = mean / downsideDev * annualiser
+	}
+	return sharpe, sortino
+}
@@ -0,0 +1,324 @@
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+// OptimizeConfig is a search over the numeric params of Backtest's strategy, starting from Backtest.Params (the
+// defaults when nil) and backtesting every param set the way Run does.
+type OptimizeConfig struct {
+	Backtest  Config
+	Space     []ParamRange // empty searches every numeric field, see GetParamSpace
+	Method    enum.SearchMethod
+	Objective enum.Objective
+	Trials    int     // backtests per search for random and Bayesian search, the most points a grid may have
+	Top       int     // trials kept in the ranking, and per fold the ones re-run out of sample
+	MinTrades int     // trials that trade less are left out of the ranking
+	Workers   int     // backtests run at once, 0 for one per CPU
+	Seed      uint64  // the same seed draws the same params, Bayesian search on as many workers
+	Folds     int     // walk-forward folds, 0 for only the search over all the candles
+	InSample  float64 // share of each fold's candles searched, the rest tests what won
+	Anchored  bool    // every fold searches from the first candle rather than a window rolling forward
+}
+
+func DefaultOptimizeConfig(cfg Config) OptimizeConfig {
+	return OptimizeConfig{
+		Backtest:  cfg,
+		Method:    enum.SearchRandom,
+		Objective: enum.ObjectiveSharpe,
+		Trials:    100,
+		Top:       10,
+		Folds:     4,
+		InSample:  0.75,
+	}
+}
+
+// TrialScore is how a param set did over one stretch of candles.
+type TrialScore struct {
+	Score   float64 `json:"score"` // the objective
+	Metrics Metrics `json:"metrics"`
+}
+
+// Trial is a param set the search backtested. OutOfSample is set on a fold's best trials, re-run on the candles
+// after the ones they were picked on.
+type Trial struct {
+	Params      map[string]float64 `json:"params"`
+	InSample    TrialScore         `json:"inSample"`
+	OutOfSample *TrialScore        `json:"outOfSample,omitempty"`
+}
+
+// window is candles[from:to], the candles before from are history for it but aren't traded.
+type window struct {
+	from, to int
+}
+
+type optimizer struct {
+	cfg       OptimizeConfig
+	base      signaler.StrategyParams
+	candles   []models.Candle
+	rng       *rand.Rand
+	backtests atomic.Int64
+	invalid   atomic.Int64 // param sets the strategy rejected or panicked on
+}
+
+// evaluation is a point's backtest, nil when the params didn't validate or the strategy panicked.
+type evaluation struct {
+	point paramPoint
+	score *TrialScore
+}
+
+func (o *optimizer) getWorkers() int {
+	if o.cfg.Workers > 0 {
+		return o.cfg.Workers
+	}
+	return runtime.NumCPU()
+}
+
+// evaluateAll backtests points over w on the workers, in the points' order.
+func (o *optimizer) evaluateAll(points []paramPoint, w window) []evaluation {
+	evaluations := make([]evaluation, len(points))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(o.getWorkers(), len(points)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				evaluations[i] = evaluation{point: points[i], score: o.evaluate(points[i], w)}
+			}
+		}()
+	}
+	for i := range points {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return evaluations
+}
+
+func (o *optimizer) evaluate(point paramPoint, w window) *TrialScore {
+	params, err := point.apply(o.base)
+	if err != nil {
+		o.invalid.Add(1)
+		return nil
+	}
+	strategy := signaler.NewStrategyWithMemberParams(o.cfg.Backtest.Strategy, params, o.cfg.Backtest.MemberParams)
+	cfg := o.cfg.Backtest
+	cfg.Params = params
+	cfg.TradeFrom = o.candles[w.from].Start
+	// as much history before the window as the live store would have
+	start := max(w.from-max(cfg.HistoryWindow, strategy.GetWarmUpCandles()), 0)
+
+	o.backtests.Add(1)
+	result, err := RunStrategy(cfg, strategy, o.candles[start:w.to])
+	if err != nil {
+		o.invalid.Add(1)
+		return nil
+	}
+	fees := 0.0
+	for _, f := range result.Fills {
+		fees += f.Fee
+	}
+	barDuration := enum.GetTimeDurationFromCandleSize(cfg.CandleSize)
+	metrics := computeMetrics(result.EquityCurve[w.from-start:], result.Trades, result.Fills, fees, barDuration)
+	return &TrialScore{Score: o.getScore(metrics), Metrics: metrics}
+}
+
+func (o *optimizer) getScore(m Metrics) float64 {
+	switch o.cfg.Objective {
+	case enum.ObjectiveSortino:
+		return m.Sortino
+	case enum.ObjectiveReturn:
+		return m.TotalReturn
+	case enum.ObjectiveReturnOverDrawdown:
+		// drawdowns under 1% count as 1%, a few lucky trades shouldn't divide by next to nothing
+		return m.TotalReturn / math.Max(m.MaxDrawdown, 1)
+	default:
+		return m.Sharpe
+	}
+}
+
+func (o *optimizer) isQualified(e evaluation) bool {
+	return e.score != nil && e.score.Metrics.NumTrades >= o.cfg.MinTrades
+}
+
+// search runs the configured search over w and returns the qualifying trials, best first.
+func (o *optimizer) search(w window) ([]Trial, error) {
+	var evaluations []evaluation
+	switch o.cfg.Method {
+	case enum.SearchGrid:
+		points, err := getGridPoints(o.cfg.Space, o.cfg.Trials)
+		if err != nil {
+			return nil, err
+		}
+		evaluations = o.evaluateAll(points, w)
+	case enum.SearchRandom:
+		evaluations = o.evaluateAll(o.drawPoints(o.cfg.Trials, make(map[string]bool)), w)
+	case enum.SearchBayesian:
+		evaluations = o.searchBayesian(w)
+	default:
+		return nil, fmt.Errorf("unknown search method %d", o.cfg.Method)
+	}
+
+	trials := make([]Trial, 0, len(evaluations))
+	for _, e := range evaluations {
+		if o.isQualified(e) {
+			trials = append(trials, Trial{Params: e.point, InSample: *e.score})
+		}
+	}
+	sortTrials(trials)
+	return trials, nil
+}
+
+// sortTrials orders trials best first, ties by their params so reports don't change from run to run.
+func sortTrials(trials []Trial) {
+	sort.SliceStable(trials, func(i, j int) bool {
+		if trials[i].InSample.Score != trials[j].InSample.Score {
+			return trials[i].InSample.Score > trials[j].InSample.Score
+		}
+		return paramPoint(trials[i].Params).key() < paramPoint(trials[j].Params).key()
+	})
+}
+
+// drawPoints draws up to n points not in seen, fewer when the space runs out of them.
+func (o *optimizer) drawPoints(n int, seen map[string]bool) []paramPoint {
+	points := make([]paramPoint, 0, n)
+	for attempts := 0; len(points) < n && attempts < 20*n; attempts++ {
+		if p := o.drawPoint(seen); p != nil {
+			points = append(points, p)
+		}
+	}
+	return points
+}
+
+func (o *optimizer) drawPoint(seen map[string]bool) paramPoint {
+	p := make(paramPoint, len(o.cfg.Space))
+	for _, r := range o.cfg.Space {
+		p[r.Name] = r.draw(o.rng)
+	}
+	if seen[p.key()] {
+		return nil
+	}
+	seen[p.key()] = true
+	return p
+}
+
+// searchBayesian is a tree-structured Parzen estimator. After a round of random draws the trials so far are split
+// into the best quarter and the rest, and each later draw is, of a batch of candidates sampled around the best,
+// the one whose values are likeliest among the best and least likely among the rest. Draws go out a batch of
+// workers at a time.
+func (o *optimizer) searchBayesian(w window) []evaluation {
+	seen := make(map[string]bool)
+	startup := min(max(10, o.cfg.Trials/4), o.cfg.Trials)
+	evaluations := o.evaluateAll(o.drawPoints(startup, seen), w)
+	for len(evaluations) < o.cfg.Trials {
+		good, bad := o.splitEvaluations(evaluations)
+		points := make([]paramPoint, 0, o.getWorkers())
+		for range min(o.getWorkers(), o.cfg.Trials-len(evaluations)) {
+			if p := o.proposePoint(good, bad, seen); p != nil {
+				points = append(points, p)
+			}
+		}
+		if len(points) == 0 {
+			break // every point of the space has been tried
+		}
+		evaluations = append(evaluations, o.evaluateAll(points, w)...)
+	}
+	return evaluations
+}
+
+// splitEvaluations is the best quarter of the qualifying points and the rest, rejected ones included.
+func (o *optimizer) splitEvaluations(evaluations []evaluation) ([]paramPoint, []paramPoint) {
+	qualified := make([]Trial, 0, len(evaluations))
+	var bad []paramPoint
+	for _, e := range evaluations {
+		if o.isQualified(e) {
+			qualified = append(qualified, Trial{Params: e.point, InSample: *e.score})
+		} else {
+			bad = append(bad, e.point)
+		}
+	}
+	sortTrials(qualified)
+	nGood := int(math.Ceil(float64(len(qualified)) / 4))
+	good := make([]paramPoint, 0, nGood)
+	for i, t := range qualified {
+		if i < nGood {
+			good = append(good, t.Params)
+		} else {
+			bad = append(bad, t.Params)
+		}
+	}
+	return good, bad
+}
+
+func (o *optimizer) proposePoint(good []paramPoint, bad []paramPoint, seen map[string]bool) paramPoint {
+	if len(good) == 0 {
+		return o.drawFreshPoint(seen)
+	}
+	var best paramPoint
+	bestRatio := math.Inf(-1)
+	for range 24 {
+		p := make(paramPoint, len(o.cfg.Space))
+		ratio := 0.0
+		for _, r := range o.cfg.Space {
+			v := r.snap(sampleParzen(r, good, o.rng))
+			p[r.Name] = v
+			ratio += math.Log(getParzenDensity(r, good, v)) - math.Log(getParzenDensity(r, bad, v))
+		}
+		if !seen[p.key()] && ratio > bestRatio {
+			best, bestRatio = p, ratio
+		}
+	}
+	if best == nil {
+		return o.drawFreshPoint(seen)
+	}
+	seen[best.key()] = true
+	return best
+}
+
+// drawFreshPoint is a random point not in seen, nil when the draws keep landing on ones that are.
+func (o *optimizer) drawFreshPoint(seen map[string]bool) paramPoint {
+	if points := o.drawPoints(1, seen); len(points) > 0 {
+		return points[0]
+	}
+	return nil
+}
+
+// getParzenBandwidth narrows the kernels around the points as more come in.
+func getParzenBandwidth(r ParamRange, n int) float64 {
+	return 0.5 * (r.Max - r.Min) * math.Pow(float64(n), -0.2)
+}
+
+// sampleParzen draws from a Gaussian around one of points' values, or from the whole range as often as from any
+// one point so the search keeps looking elsewhere.
+func sampleParzen(r ParamRange, points []paramPoint, rng *rand.Rand) float64 {
+	i := rng.IntN(len(points) + 1)
+	if i == len(points) || r.Max == r.Min {
+		return r.Min + rng.Float64()*(r.Max-r.Min)
+	}
+	return points[i][r.Name] + rng.NormFloat64()*getParzenBandwidth(r, len(points))
+}
+
+// getParzenDensity is the density sampleParzen draws v with.
+func getParzenDensity(r ParamRange, points []paramPoint, v float64) float64 {
+	if r.Max == r.Min {
+		return 1
+	}
+	density := 1 / (r.Max - r.Min)
+	bandwidth := getParzenBandwidth(r, max(len(points), 1))
+	for _, p := range points {
+		z := (v - p[r.Name]) / bandwidth
+		density += math.Exp(-z*z/2) / (bandwidth * math.Sqrt(2*math.Pi))
+	}
+	return density / float64(len(points)+1)
+}
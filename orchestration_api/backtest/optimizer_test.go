@@ -0,0 +1,199 @@
+package backtest
+
+import (
+	"math"
+	"math/rand/v2"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+// trendingCandles is n 1h candles of a drifting sine with noise, enough swings for the strategies to trade.
+func trendingCandles(n int, seed uint64) []models.Candle {
+	rng := rand.New(rand.NewPCG(seed, seed))
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	candles := make([]models.Candle, n)
+	price := 100.0
+	for i := range candles {
+		open := price
+		price = 100 + 0.02*float64(i) + 15*math.Sin(float64(i)/40) + rng.NormFloat64()
+		candles[i] = models.Candle{
+			Start:  start.Add(time.Duration(i) * time.Hour),
+			Open:   open,
+			High:   math.Max(open, price) + rng.Float64(),
+			Low:    math.Min(open, price) - rng.Float64(),
+			Close:  price,
+			Volume: 100 + 50*rng.Float64(),
+		}
+	}
+	return candles
+}
+
+func bayesianConfig(seed uint64, workers int) OptimizeConfig {
+	cfg := DefaultOptimizeConfig(DefaultConfig("ETH-USD", enum.Supertrend, enum.CandleSize1h))
+	cfg.Method = enum.SearchBayesian
+	cfg.Space = []ParamRange{
+		{Name: "atrPeriod", Min: 5, Max: 40, Step: 1, Int: true},
+		{Name: "factor", Min: 1, Max: 5, Step: 0.25},
+	}
+	cfg.Trials = 30
+	cfg.Top = 5
+	cfg.Folds = 0
+	cfg.Seed = seed
+	cfg.Workers = workers
+	return cfg
+}
+
+func TestBayesianSearch(t *testing.T) {
+	candles := trendingCandles(1500, 1)
+	report, err := Optimize(bayesianConfig(7, 4), candles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Backtests != 30 {
+		t.Errorf("%d backtests, want one per trial", report.Backtests)
+	}
+	if len(report.Ranked) == 0 {
+		t.Fatal("nothing ranked")
+	}
+	seen := make(map[string]bool)
+	for i, trial := range report.Ranked {
+		key := paramPoint(trial.Params).key()
+		if seen[key] {
+			t.Errorf("%s was backtested twice", key)
+		}
+		seen[key] = true
+		if i > 0 && trial.InSample.Score > report.Ranked[i-1].InSample.Score {
+			t.Errorf("trial %d scores above the one ranked before it", i)
+		}
+		if p := trial.Params["factor"]; p < 1 || p > 5 || math.Mod(p-1, 0.25) != 0 {
+			t.Errorf("factor %v is off the range's steps", p)
+		}
+	}
+
+	again, err := Optimize(bayesianConfig(7, 4), candles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Ranked, again.Ranked) {
+		t.Error("the same seed on as many workers ranked differently")
+	}
+}
+
+// After the random start the draws should gather around the best points rather than anywhere in the range.
+func TestProposePointFollowsGoodPoints(t *testing.T) {
+	r := ParamRange{Name: "x", Min: 0, Max: 100, Step: 1, Int: true}
+	o := &optimizer{cfg: OptimizeConfig{Space: []ParamRange{r}}, rng: rand.New(rand.NewPCG(3, 3))}
+	good := []paramPoint{{"x": 78}, {"x": 80}, {"x": 82}}
+	var bad []paramPoint
+	for x := 0.0; x <= 50; x += 5 {
+		bad = append(bad, paramPoint{"x": x})
+	}
+	seen := make(map[string]bool)
+	near := 0
+	for range 20 {
+		p := o.proposePoint(good, bad, seen)
+		if p == nil {
+			t.Fatal("no point proposed")
+		}
+		if p["x"] >= 60 {
+			near++
+		}
+	}
+	if near < 15 {
+		t.Errorf("%d of 20 proposals near the good points, want most", near)
+	}
+}
+
+func TestGetFoldWindows(t *testing.T) {
+	tests := []struct {
+		name     string
+		n        int
+		folds    int
+		inSample float64
+		anchored bool
+		want     [][2]window
+		wantErr  bool
+	}{
+		{name: "no folds", n: 100, folds: 0, inSample: 0.75},
+		{
+			name: "rolling", n: 100, folds: 2, inSample: 0.5,
+			want: [][2]window{{{from: 0, to: 34}, {from: 34, to: 67}}, {{from: 33, to: 67}, {from: 67, to: 100}}},
+		},
+		{
+			name: "anchored", n: 100, folds: 2, inSample: 0.5, anchored: true,
+			want: [][2]window{{{from: 0, to: 34}, {from: 34, to: 67}}, {{from: 0, to: 67}, {from: 67, to: 100}}},
+		},
+		{
+			name: "in sample takes the remainder", n: 103, folds: 4, inSample: 0.75,
+			want: [][2]window{
+				{{from: 0, to: 47}, {from: 47, to: 61}}, {{from: 14, to: 61}, {from: 61, to: 75}},
+				{{from: 28, to: 75}, {from: 75, to: 89}}, {{from: 42, to: 89}, {from: 89, to: 103}},
+			},
+		},
+		{name: "too few candles", n: 10, folds: 8, inSample: 0.75, wantErr: true},
+		{name: "negative folds", n: 100, folds: -1, inSample: 0.75, wantErr: true},
+		{name: "in sample of everything", n: 100, folds: 2, inSample: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getFoldWindows(tt.n, tt.folds, tt.inSample, tt.anchored)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want an error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("windows %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkForward(t *testing.T) {
+	cfg := bayesianConfig(11, 2)
+	cfg.Method = enum.SearchRandom
+	cfg.Trials = 12
+	cfg.Folds = 3
+	report, err := Optimize(cfg, trendingCandles(2000, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Folds) != 3 || report.Diagnostics == nil {
+		t.Fatalf("%d folds, diagnostics %v", len(report.Folds), report.Diagnostics)
+	}
+	for i, f := range report.Folds {
+		if !f.InSampleEnd.Before(f.OutOfSampleStart) {
+			t.Errorf("fold %d is tested on candles it was searched on", i)
+		}
+		if i > 0 && !report.Folds[i-1].OutOfSampleEnd.Before(f.OutOfSampleStart) {
+			t.Errorf("fold %d's out-of-sample candles overlap the fold before", i)
+		}
+		if len(f.Top) > 0 && f.Top[0].OutOfSample != nil && (f.Efficiency == nil) != (f.Top[0].InSample.Score <= 0) {
+			t.Errorf("fold %d: efficiency %v for an in-sample score of %v", i, f.Efficiency, f.Top[0].InSample.Score)
+		}
+	}
+}
+
+func TestGetEfficiency(t *testing.T) {
+	tests := []struct {
+		inSample, outOfSample float64
+		want                  *float64
+	}{
+		{inSample: 2, outOfSample: 1, want: ptr(0.5)},
+		{inSample: 1, outOfSample: -1, want: ptr(-1)},
+		{inSample: 0, outOfSample: 1},
+		{inSample: -1, outOfSample: -2},
+	}
+	for _, tt := range tests {
+		got := getEfficiency(tt.inSample, tt.outOfSample)
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("getEfficiency(%v, %v) = %v, want %v", tt.inSample, tt.outOfSample, got, tt.want)
+		}
+	}
+}
+
+func ptr(v float64) *float64 {
+	return &v
+}
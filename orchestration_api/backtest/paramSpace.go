@@ -0,0 +1,173 @@
+package backtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler"
+)
+
+// ParamRange is one numeric field of a strategy's params for the optimizer to search, by its JSON name, e.g.
+// factor for Supertrend. Grid search walks Min to Max in Steps, random and Bayesian search draw anywhere in
+// between, snapped to Step when it is set. Whole number fields only ever take whole values.
+type ParamRange struct {
+	Name string  `json:"name"`
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	Step float64 `json:"step"`
+	Int  bool    `json:"int"` // from the field's type, not something to set
+}
+
+// paramPoint is one set of values for a space, by field name.
+type paramPoint map[string]float64
+
+// GetParamSpace is a range for every numeric field of params, from half its value to double it in four steps.
+func GetParamSpace(params signaler.StrategyParams) []ParamRange {
+	fields := getNumericFields(params)
+	space := make([]ParamRange, 0, len(fields))
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		field := fields[name]
+		r := ParamRange{Name: name, Int: field.isInt, Min: field.value / 2, Max: field.value * 2}
+		if field.value == 0 {
+			r.Max = 1
+		}
+		if r.Min > r.Max {
+			r.Min, r.Max = r.Max, r.Min // negative defaults
+		}
+		r.Step = (r.Max - r.Min) / 4
+		if r.Int {
+			r.Min, r.Max = math.Max(math.Round(r.Min), 1), math.Max(math.Round(r.Max), 1)
+			r.Step = math.Max(math.Round(r.Step), 1)
+		}
+		space = append(space, r)
+	}
+	return space
+}
+
+// NewParamSpace checks ranges, given by field name, against params' numeric fields.
+func NewParamSpace(params signaler.StrategyParams, ranges map[string]ParamRange) ([]ParamRange, error) {
+	fields := getNumericFields(params)
+	space := make([]ParamRange, 0, len(ranges))
+	for _, name := range slices.Sorted(maps.Keys(ranges)) {
+		r := ranges[name]
+		field, ok := fields[name]
+		if !ok {
+			return nil, fmt.Errorf("%s is not a numeric param of %T", name, params)
+		}
+		r.Name, r.Int = name, field.isInt
+		if r.Min > r.Max {
+			return nil, fmt.Errorf("%s: min is above max", name)
+		}
+		if r.Step < 0 {
+			return nil, fmt.Errorf("%s: step can't be negative", name)
+		}
+		if r.Int && r.Step == 0 {
+			r.Step = 1
+		}
+		space = append(space, r)
+	}
+	return space, nil
+}
+
+type numericField struct {
+	value float64
+	isInt bool
+}
+
+// getNumericFields are params' int and float64 fields by JSON name. Named types, e.g. an enum.CandleSize, are
+// choices rather than quantities and are left out.
+func getNumericFields(params signaler.StrategyParams) map[string]numericField {
+	fields := make(map[string]numericField)
+	v := reflect.ValueOf(params)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		switch f.Type {
+		case reflect.TypeFor[int]():
+			fields[name] = numericField{value: float64(v.Field(i).Int()), isInt: true}
+		case reflect.TypeFor[float64]():
+			fields[name] = numericField{value: v.Field(i).Float()}
+		}
+	}
+	return fields
+}
+
+// snap puts v on the range's steps and inside it.
+func (r ParamRange) snap(v float64) float64 {
+	if r.Step > 0 {
+		v = r.Min + math.Round((v-r.Min)/r.Step)*r.Step
+	}
+	if r.Int {
+		v = math.Round(v)
+	}
+	v = math.Min(math.Max(v, r.Min), r.Max)
+	return math.Round(v*1e9) / 1e9 // so 0.1 steps print as 0.3 rather than 0.30000000000000004
+}
+
+func (r ParamRange) draw(rng *rand.Rand) float64 {
+	return r.snap(r.Min + rng.Float64()*(r.Max-r.Min))
+}
+
+// getGridPoints is every combination of the ranges' steps, failing when there are more than limit of them.
+func getGridPoints(space []ParamRange, limit int) ([]paramPoint, error) {
+	values := make([][]float64, len(space))
+	total := 1
+	for i, r := range space {
+		steps := 0
+		if r.Step > 0 {
+			steps = int(math.Floor((r.Max-r.Min)/r.Step + 1e-9))
+		} else if r.Min != r.Max {
+			return nil, fmt.Errorf("%s: grid search needs a step", r.Name)
+		}
+		if total *= steps + 1; total > limit {
+			return nil, fmt.Errorf("the grid has more than %d points, narrow the ranges or search randomly", limit)
+		}
+		for k := 0; k <= steps; k++ {
+			values[i] = append(values[i], r.snap(r.Min+float64(k)*r.Step))
+		}
+		values[i] = slices.Compact(values[i]) // half steps of a whole number field round onto each other
+	}
+
+	points := []paramPoint{{}}
+	for i, r := range space {
+		next := make([]paramPoint, 0, len(points)*len(values[i]))
+		for _, p := range points {
+			for _, v := range values[i] {
+				q := maps.Clone(p)
+				q[r.Name] = v
+				next = append(next, q)
+			}
+		}
+		points = next
+	}
+	return points, nil
+}
+
+func (p paramPoint) key() string {
+	names := slices.Sorted(maps.Keys(p))
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + "=" + strconv.FormatFloat(p[name], 'g', -1, 64)
+	}
+	return strings.Join(parts, ",")
+}
+
+// apply sets the point's values on base, failing when the strategy rejects the combination.
+func (p paramPoint) apply(base signaler.StrategyParams) (signaler.StrategyParams, error) {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return signaler.ApplyParams(base, raw)
+}
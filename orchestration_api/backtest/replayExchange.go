@@ -0,0 +1,259 @@
+package backtest
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	exchange_helper "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange/helper"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/indicators"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+	cb_models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models/coinbase"
+)
+
+var errReplayOnly = errors.New("replay exchange only serves historical market data")
+
+// ReplayExchange implements exchange.IExchange over a fixed candle series. The engine moves the cursor forward
+// one bar at a time and strategies only ever see the candles up to and including the cursor, exactly like they
+// would only see closed + in-progress candles from the live PriceActionStore.
+type ReplayExchange struct {
+	symbol     string
+	candleSize enum.CandleSize
+	candles    []models.Candle
+	cursor     int
+	window     int
+	indicators *indicators.Set
+	fed        int // candles the indicators have been updated with
+
+	renkoBuilt     bool
+	renkoBrickSize float64
+}
+
+func NewReplayExchange(symbol string, candleSize enum.CandleSize, candles []models.Candle, window int) *ReplayExchange {
+	return &ReplayExchange{
+		symbol:     symbol,
+		candleSize: candleSize,
+		candles:    candles,
+		window:     window,
+		indicators: indicators.NewSet(nil),
+	}
+}
+
+// SetCursor makes candles[0..idx] visible to the strategy, and updates the indicators with the candles that
+// became visible. Moving it back starts them over.
+func (e *ReplayExchange) SetCursor(idx int) {
+	e.cursor = idx
+	if idx < e.fed-1 {
+		e.indicators, e.fed = indicators.NewSet(nil), 0
+	}
+	for ; e.fed <= idx && e.fed < len(e.candles); e.fed++ {
+		e.indicators.Update(e.candles[e.fed])
+	}
+}
+
+func (e *ReplayExchange) visibleCandles() []models.Candle {
+	if len(e.candles) == 0 {
+		return []models.Candle{}
+	}
+	end := e.cursor + 1
+	if end > len(e.candles) {
+		end = len(e.candles)
+	}
+	return e.candles[:end]
+}
+
+func (e *ReplayExchange) lastWindow(candles []models.Candle) models.CandleHistory {
+	start := 0
+	if e.window > 0 && len(candles) > e.window {
+		start = len(candles) - e.window
+	}
+	out := make([]models.Candle, len(candles)-start)
+	copy(out, candles[start:])
+	return models.CandleHistory{Candles: out}
+}
+
+func (e *ReplayExchange) SubscribeToOrderUpdates(symbol string) (<-chan models.OrderUpdate, func()) {
+	return make(chan models.OrderUpdate), func() {}
+}
+
+func (e *ReplayExchange) SubscribeToTicker(symbol string) (<-chan models.Ticker, func()) {
+	return make(chan models.Ticker), func() {}
+}
+
+func (e *ReplayExchange) SubscribeToCandle(symbol string) (<-chan models.Candle, func()) {
+	return make(chan models.Candle), func() {}
+}
+
+func (e *ReplayExchange) SubscribeToOrderBook(symbol string) (<-chan models.OrderBook, func()) {
+	return make(chan models.OrderBook), func() {}
+}
+
+func (e *ReplayExchange) SubscribeToTrades(symbol string) (<-chan models.Trade, func()) {
+	return make(chan models.Trade), func() {}
+}
+
+// GetOrderBook has nothing to give, a replay only has candles.
+func (e *ReplayExchange) GetOrderBook(symbol string) (models.OrderBook, bool) {
+	return models.OrderBook{}, false
+}
+
+func (e *ReplayExchange) GetCandleHistory(symbol string) models.CandleHistory {
+	if symbol != e.symbol {
+		return models.CandleHistory{Candles: []models.Candle{}}
+	}
+	return e.lastWindow(e.visibleCandles())
+}
+
+func (e *ReplayExchange) GetLongCandleHistory(symbol string) models.CandleHistory {
+	if symbol != e.symbol {
+		return models.CandleHistory{Candles: []models.Candle{}}
+	}
+	if e.candleSize >= enum.CandleSize6h {
+		// there is no longer candle size defined past 4h, so the long series is the series itself
+		return e.GetCandleHistory(symbol)
+	}
+	longSize := enum.GetTimeDurationFromCandleSize(enum.GetLongCandleSizeFromCandleSize(e.candleSize))
+	return e.lastWindow(models.ResampleCandles(e.visibleCandles(), longSize))
+}
+
+// GetResampledCandleHistory resamples everything visible so far, since a strategy asking for a custom timeframe
+// usually wants more than one window of it.
+func (e *ReplayExchange) GetResampledCandleHistory(symbol string, size time.Duration) models.CandleHistory {
+	if symbol != e.symbol || size < time.Minute {
+		return models.CandleHistory{Candles: []models.Candle{}}
+	}
+	return models.CandleHistory{Candles: models.ResampleCandles(e.visibleCandles(), size.Truncate(time.Minute))}
+}
+
+func (e *ReplayExchange) GetPriceHistory(symbol string) []models.Ticker {
+	if symbol != e.symbol {
+		return []models.Ticker{}
+	}
+	visible := e.visibleCandles()
+	prices := make([]models.Ticker, len(visible))
+	for i, c := range visible {
+		prices[i] = models.Ticker{Symbol: symbol, Price: c.Close, Time: c.Start}
+	}
+	return prices
+}
+
+func (e *ReplayExchange) GetPriceHistoryPage(symbol string, before time.Time, limit int) []models.Ticker {
+	prices := e.GetPriceHistory(symbol)
+	end := len(prices)
+	if !before.IsZero() {
+		end = sort.Search(len(prices), func(i int) bool { return !prices[i].Time.Before(before) })
+	}
+	return prices[max(0, end-max(limit, 0)):end]
+}
+
+func (e *ReplayExchange) GetLastPrice(symbol string) (float64, bool) {
+	visible := e.visibleCandles()
+	if symbol != e.symbol || len(visible) == 0 {
+		return 0, false
+	}
+	return visible[len(visible)-1].Close, true
+}
+
+func (e *ReplayExchange) GetRenkoCandleHistory(symbol string) models.RenkoCandleHistory {
+	if !e.renkoBuilt || symbol != e.symbol {
+		return models.RenkoCandleHistory{RenkoCandles: []models.RenkoCandle{}}
+	}
+	visible := e.visibleCandles()
+	closes := make([]float64, len(visible))
+	for i, c := range visible {
+		closes[i] = c.Close
+	}
+	return exchange_helper.GetRenkoCandleHistoryFromPrices(closes, e.renkoBrickSize)
+}
+
+// GetIndicators sees every candle up to the cursor, not only the last window, as the live store's set has seen
+// every candle since the symbol was added.
+func (e *ReplayExchange) GetIndicators(symbol string) *indicators.Set {
+	if symbol != e.symbol {
+		return indicators.NewSet(nil)
+	}
+	return e.indicators
+}
+
+func (e *ReplayExchange) IsRenkoCandleHistoryBuilt(symbol string) bool {
+	return e.renkoBuilt && symbol == e.symbol
+}
+
+func (e *ReplayExchange) BuildRenkoCandleHistory(symbol string, brickSize float64) {
+	e.renkoBuilt = true
+	e.renkoBrickSize = brickSize
+}
+
+func (e *ReplayExchange) UpdateInboundCandleSize(candleSize enum.CandleSize) {}
+
+// StartNewTokenDataStream has nothing to fetch, the whole file is loaded up front.
+func (e *ReplayExchange) StartNewTokenDataStream(symbol string, candleSize enum.CandleSize, historyDepth int) error {
+	return nil
+}
+
+func (e *ReplayExchange) StopTokenDataStream(symbol string) error {
+	return nil
+}
+
+func (e *ReplayExchange) UpdateCandleSizeForSymbol(symbol string, candleSize enum.CandleSize, historyDepth int) error {
+	return errReplayOnly
+}
+
+func (e *ReplayExchange) StartOrderAndPositionValuationWebSocket(ctx context.Context, wsURL string) {}
+
+func (e *ReplayExchange) StartCoinbaseFeed(ctx context.Context, cbAdvUrl string) {}
+
+func (e *ReplayExchange) GetHistoricalCandles(ctx context.Context, productID string, candleSize enum.CandleSize) (cb_models.CandlesResponse, error) {
+	visible := models.ResampleCandles(e.visibleCandles(), enum.GetTimeDurationFromCandleSize(candleSize))
+	out := cb_models.CandlesResponse{Candles: make([]cb_models.CoinbaseHistoricalCandle, 0, len(visible))}
+	for _, c := range visible {
+		out.Candles = append(out.Candles, cb_models.CoinbaseHistoricalCandle{
+			Start:  strconv.FormatInt(c.Start.Unix(), 10),
+			High:   c.High,
+			Low:    c.Low,
+			Open:   c.Open,
+			Close:  c.Close,
+			Volume: c.Volume,
+		})
+	}
+	return out, nil
+}
+
+func (e *ReplayExchange) ListAccounts(ctx context.Context) (cb_models.AccountsListResponse, error) {
+	return cb_models.AccountsListResponse{}, errReplayOnly
+}
+
+func (e *ReplayExchange) GetAllTokenBalances(ctx context.Context) (map[string]float64, error) {
+	return map[string]float64{}, nil
+}
+
+func (e *ReplayExchange) ListOrders(ctx context.Context, productID string, limit int) (cb_models.ListOrdersResponse, error) {
+	return cb_models.ListOrdersResponse{}, nil
+}
+
+func (e *ReplayExchange) CreateOrder(ctx context.Context, productID string, amountOfUSD float64, isBuy bool, spec models.OrderSpec) (cb_models.CreateOrderResponse, error) {
+	return cb_models.CreateOrderResponse{}, errReplayOnly
+}
+
+func (e *ReplayExchange) SellTokens(ctx context.Context, productID string, amountOfUSD float64) (cb_models.CreateOrderResponse, error) {
+	return cb_models.CreateOrderResponse{}, errReplayOnly
+}
+
+func (e *ReplayExchange) EditOrder(ctx context.Context, body []byte) (cb_models.EditOrderResponse, error) {
+	return cb_models.EditOrderResponse{}, errReplayOnly
+}
+
+func (e *ReplayExchange) CancelOrders(ctx context.Context, orderID string) error {
+	return errReplayOnly
+}
+
+func (e *ReplayExchange) GetPerpPosition(ctx context.Context, productID string) (models.PerpPosition, error) {
+	return models.PerpPosition{}, errReplayOnly
+}
+
+func (e *ReplayExchange) GetFundingRate(ctx context.Context, productID string) (models.FundingRate, error) {
+	return models.FundingRate{}, errReplayOnly
+}
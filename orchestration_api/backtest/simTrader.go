@@ -0,0 +1,242 @@
+package backtest
+
+import (
+	"math"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/trader"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/journal"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+// Fill is a single simulated execution.
+type Fill struct {
+	Time        time.Time `json:"time"`
+	Side        string    `json:"side"`
+	Price       float64   `json:"price"`
+	Quantity    float64   `json:"quantity"`
+	ValueUSD    float64   `json:"valueUSD"`
+	Fee         float64   `json:"fee"`
+	RealizedPnL float64   `json:"realizedPnL"`
+	SignalType  string    `json:"signalType"`
+	SignalPct   float64   `json:"signalPercent"`
+}
+
+// Trade is a round trip from flat back to flat, long or short.
+type Trade struct {
+	EntryTime time.Time `json:"entryTime"`
+	ExitTime  time.Time `json:"exitTime"`
+	PnL       float64   `json:"pnl"`
+	ReturnPct float64   `json:"returnPct"`
+	NumFills  int       `json:"numFills"`
+}
+
+// simTrader mirrors the live Trader: signals move a USD target through the same sizing rule, and trades are
+// only placed when the fulfilled-orders position drifts from that target by more than 1% of allocated funds.
+// tokens go negative while short; a short's proceeds sit in cash until it is covered.
+type simTrader struct {
+	allocatedFunds              float64
+	sizing                      trader.SizingCfg
+	allowShort                  bool
+	feeRate                     float64
+	slippage                    float64
+	cash                        float64
+	tokens                      float64
+	avgEntryPrice               float64
+	usdAmountPerFulfilledOrders float64
+	targetPositionUSD           float64
+	lastSignal                  models.Signal
+
+	fills      []Fill
+	trades     []Trade
+	tradeStats journal.TradeStats // of trades, for Kelly sizing, like the journal's round trips live
+	openTrade  *Trade
+	tradeCost  float64
+	totalFees  float64
+}
+
+func newSimTrader(funds float64, feeRate float64, slippageBps float64, sizing trader.SizingCfg, allowShort bool) *simTrader {
+	return &simTrader{
+		allocatedFunds: funds,
+		sizing:         sizing,
+		allowShort:     allowShort,
+		feeRate:        feeRate,
+		slippage:       slippageBps / 10000.0,
+		cash:           funds,
+	}
+}
+
+func (t *simTrader) equity(price float64) float64 {
+	return t.cash + t.tokens*price
+}
+
+func (t *simTrader) handleSignal(s models.Signal, price float64, ex *ReplayExchange) {
+	if s.Type == enum.SignalHold || s.Percent <= 0 || (s.Type.IsShortSide() && !t.allowShort) {
+		return
+	}
+	t.lastSignal = s
+	if (s.Type == enum.SignalBuy || s.Type == enum.SignalShort) && t.sizing.Method != enum.SizingFixedPercent {
+		s = trader.GetSizedSignal(s, t.sizing, t.allocatedFunds, 1, price, ex.GetCandleHistory(ex.symbol).Candles, t.tradeStats)
+	}
+	t.targetPositionUSD = trader.GetTargetPositionUSDAfterSignal(t.targetPositionUSD, t.tokens*price, t.allocatedFunds, s)
+}
+
+// rebalance fills at the given price (the next bar's open) the same way executeTradesToMakeActualTrackTarget would.
+func (t *simTrader) rebalance(price float64, at time.Time) {
+	if price <= 0 {
+		return
+	}
+	tolerance := t.allocatedFunds * 0.01
+	deficit := t.targetPositionUSD - t.usdAmountPerFulfilledOrders
+	switch {
+	case deficit > tolerance:
+		t.buy(math.Min(deficit, t.cash/(1+t.feeRate)), price, at)
+	case deficit < -tolerance:
+		t.sell(-deficit, price, at)
+	}
+}
+
+// buy covers a short first and, once flat with a long target, buys the whole target.
+func (t *simTrader) buy(amountUSD float64, price float64, at time.Time) {
+	if amountUSD <= 0 {
+		return
+	}
+	if t.tokens < 0 {
+		t.cover(amountUSD, price, at)
+		if t.tokens < 0 || t.targetPositionUSD <= 0 {
+			return
+		}
+		amountUSD = math.Min(t.targetPositionUSD, t.cash/(1+t.feeRate))
+	}
+	fillPrice := price * (1 + t.slippage)
+	qty := amountUSD / fillPrice
+	fee := amountUSD * t.feeRate
+
+	if t.tokens <= 0 {
+		t.openTrade = &Trade{EntryTime: at}
+		t.tradeCost = 0
+	}
+	t.avgEntryPrice = (t.avgEntryPrice*t.tokens + fillPrice*qty) / (t.tokens + qty)
+	t.tokens += qty
+	t.cash -= amountUSD + fee
+	t.usdAmountPerFulfilledOrders += amountUSD
+	t.totalFees += fee
+	t.tradeCost += amountUSD
+	t.recordFill("BUY", at, fillPrice, qty, fee, -fee)
+}
+
+// sell sells a long first and, once flat with a short target, shorts the whole target.
+func (t *simTrader) sell(amountUSD float64, price float64, at time.Time) {
+	if t.tokens <= 0 {
+		if t.targetPositionUSD < 0 {
+			t.short(amountUSD, price, at)
+			return
+		}
+		t.usdAmountPerFulfilledOrders = 0
+		return
+	}
+	fillPrice := price * (1 - t.slippage)
+	qty := math.Min(amountUSD/fillPrice, t.tokens)
+	// don't leave dust behind when the target is flat
+	if t.targetPositionUSD <= 0 || (t.tokens-qty)*fillPrice < t.allocatedFunds*0.001 {
+		qty = t.tokens
+	}
+	value := qty * fillPrice
+	fee := value * t.feeRate
+	realized := (fillPrice-t.avgEntryPrice)*qty - fee
+
+	t.tokens -= qty
+	t.cash += value - fee
+	t.usdAmountPerFulfilledOrders -= amountUSD
+	t.totalFees += fee
+	t.recordFill("SELL", at, fillPrice, qty, fee, realized)
+
+	if t.tokens <= 0 {
+		t.tokens = 0
+		t.avgEntryPrice = 0
+		t.usdAmountPerFulfilledOrders = 0
+		t.closeTrade(at)
+		if t.targetPositionUSD < 0 {
+			t.short(-t.targetPositionUSD, price, at)
+		}
+	}
+}
+
+func (t *simTrader) short(amountUSD float64, price float64, at time.Time) {
+	if amountUSD <= 0 {
+		return
+	}
+	fillPrice := price * (1 - t.slippage)
+	qty := amountUSD / fillPrice
+	fee := amountUSD * t.feeRate
+
+	if t.tokens >= 0 {
+		t.openTrade = &Trade{EntryTime: at}
+		t.tradeCost = 0
+	}
+	t.avgEntryPrice = (t.avgEntryPrice*-t.tokens + fillPrice*qty) / (-t.tokens + qty)
+	t.tokens -= qty
+	t.cash += amountUSD - fee
+	t.usdAmountPerFulfilledOrders -= amountUSD
+	t.totalFees += fee
+	t.tradeCost += amountUSD
+	t.recordFill("SELL", at, fillPrice, qty, fee, -fee)
+}
+
+// cover buys back up to amountUSD of a short, the whole short when the target is no longer short or the rest
+// would be dust.
+func (t *simTrader) cover(amountUSD float64, price float64, at time.Time) {
+	fillPrice := price * (1 + t.slippage)
+	qty := math.Min(amountUSD/fillPrice, -t.tokens)
+	if t.targetPositionUSD >= 0 || (-t.tokens-qty)*fillPrice < t.allocatedFunds*0.001 {
+		qty = -t.tokens
+	}
+	value := qty * fillPrice
+	fee := value * t.feeRate
+	realized := (t.avgEntryPrice-fillPrice)*qty - fee
+
+	t.tokens += qty
+	t.cash -= value + fee
+	t.usdAmountPerFulfilledOrders += value
+	t.totalFees += fee
+	t.recordFill("BUY", at, fillPrice, qty, fee, realized)
+
+	if t.tokens >= 0 {
+		t.tokens = 0
+		t.avgEntryPrice = 0
+		t.usdAmountPerFulfilledOrders = 0
+		t.closeTrade(at)
+	}
+}
+
+func (t *simTrader) recordFill(side string, at time.Time, price float64, qty float64, fee float64, realized float64) {
+	t.fills = append(t.fills, Fill{
+		Time:        at,
+		Side:        side,
+		Price:       price,
+		Quantity:    qty,
+		ValueUSD:    price * qty,
+		Fee:         fee,
+		RealizedPnL: realized,
+		SignalType:  t.lastSignal.Type.String(),
+		SignalPct:   t.lastSignal.Percent,
+	})
+	if t.openTrade != nil {
+		t.openTrade.PnL += realized
+		t.openTrade.NumFills++
+	}
+}
+
+func (t *simTrader) closeTrade(at time.Time) {
+	if t.openTrade == nil {
+		return
+	}
+	t.openTrade.ExitTime = at
+	if t.tradeCost > 0 {
+		t.openTrade.ReturnPct = t.openTrade.PnL / t.tradeCost * 100
+	}
+	t.trades = append(t.trades, *t.openTrade)
+	t.tradeStats.Add(t.openTrade.PnL)
+	t.openTrade = nil
+}
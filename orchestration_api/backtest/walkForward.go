@@ -0,0 +1,308 @@
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+// OptimizeReport is a search's ranked param sets and, with walk-forward folds, how the picks held up on the
+// candles after the ones they were picked on.
+type OptimizeReport struct {
+	Symbol      string       `json:"symbol"`
+	Strategy    string       `json:"strategy"`
+	CandleSize  string       `json:"candleSize"`
+	Method      string       `json:"method"`
+	Objective   string       `json:"objective"`
+	Space       []ParamRange `json:"space"`
+	Backtests   int          `json:"backtests"` // over every fold and the final search
+	Invalid     int          `json:"invalid"`   // param sets the strategy rejected or panicked on
+	Ranked      []Trial      `json:"ranked"`    // the final search over all the candles, best first
+	Folds       []Fold       `json:"folds,omitempty"`
+	Diagnostics *Diagnostics `json:"diagnostics,omitempty"`
+}
+
+// Fold is one walk-forward step: a search over the in-sample candles, then its best trials re-run on the
+// out-of-sample candles right after.
+type Fold struct {
+	InSampleStart    time.Time `json:"inSampleStart"`
+	InSampleEnd      time.Time `json:"inSampleEnd"`
+	OutOfSampleStart time.Time `json:"outOfSampleStart"`
+	OutOfSampleEnd   time.Time `json:"outOfSampleEnd"`
+	Top              []Trial   `json:"top"` // best in sample first, Top[0] is the fold's pick
+	// Efficiency is the pick's out-of-sample score over its in-sample score, null when the in-sample score isn't
+	// positive and the ratio says nothing. Well under 1 means the search fitted noise.
+	Efficiency *float64 `json:"efficiency"`
+	// RankCorrelation is the Spearman correlation of Top's in-sample and out-of-sample scores, near 0 or below
+	// when doing well in sample says nothing about doing well after.
+	RankCorrelation float64 `json:"rankCorrelation"`
+	// PickOutOfSampleRank is where the pick lands among Top out of sample, 1 is best.
+	PickOutOfSampleRank int `json:"pickOutOfSampleRank"`
+}
+
+// Diagnostics sum the folds up, the signs of overfitting.
+type Diagnostics struct {
+	MeanInSample    float64  `json:"meanInSample"` // the picks' scores
+	MeanOutOfSample float64  `json:"meanOutOfSample"`
+	Efficiency      *float64 `json:"efficiency"`      // walk-forward efficiency, mean out of sample over mean in sample, null as in Fold
+	RankCorrelation float64  `json:"rankCorrelation"` // mean over the folds
+	// OverfitProbability is the share of folds whose pick lands in the bottom half of Top out of sample, around
+	// 0.5 or more when the search's ranking is no better than chance.
+	OverfitProbability float64 `json:"overfitProbability"`
+	ProfitableFolds    int     `json:"profitableFolds"` // picks that made money out of sample
+	// OutOfSampleReturn is the picks' out-of-sample returns compounded, what trading each fold's pick would have made.
+	OutOfSampleReturn float64                   `json:"outOfSampleReturnPct"`
+	ParamStability    map[string]ParamStability `json:"paramStability"`
+}
+
+// ParamStability is how much a param's pick moved from fold to fold.
+type ParamStability struct {
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stdDev"`
+	Spread float64 `json:"spread"` // StdDev over the range's width, 0 when every fold picked the same value
+}
+
+// Optimize searches the params of cfg.Backtest's strategy over candles. With folds it first walks forward,
+// searching each fold's in-sample candles and re-running the best on the out-of-sample ones, and then searches
+// all the candles for the ranking.
+func Optimize(cfg OptimizeConfig, candles []models.Candle) (*OptimizeReport, error) {
+	base := cfg.Backtest.Params
+	if base == nil {
+		base = signaler.GetDefaultParams(cfg.Backtest.Strategy)
+	}
+	if base == nil {
+		return nil, fmt.Errorf("unknown strategy %d", cfg.Backtest.Strategy)
+	}
+	if len(cfg.Space) == 0 {
+		cfg.Space = GetParamSpace(base)
+	}
+	if len(cfg.Space) == 0 {
+		return nil, fmt.Errorf("%s has no numeric params to search", cfg.Backtest.Strategy.String())
+	}
+	if cfg.Trials < 1 || cfg.Top < 1 {
+		return nil, fmt.Errorf("trials and top must be at least 1")
+	}
+	if cfg.Backtest.HistoryWindow <= 0 {
+		cfg.Backtest.HistoryWindow = 100
+	}
+	folds, err := getFoldWindows(len(candles), cfg.Folds, cfg.InSample, cfg.Anchored)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &optimizer{cfg: cfg, base: base, candles: candles, rng: rand.New(rand.NewPCG(cfg.Seed, cfg.Seed))}
+	report := &OptimizeReport{
+		Symbol:     cfg.Backtest.Symbol,
+		Strategy:   cfg.Backtest.Strategy.String(),
+		CandleSize: cfg.Backtest.CandleSize.String(),
+		Method:     cfg.Method.String(),
+		Objective:  cfg.Objective.String(),
+		Space:      cfg.Space,
+	}
+	for _, f := range folds {
+		fold, err := o.runFold(f[0], f[1])
+		if err != nil {
+			return nil, err
+		}
+		report.Folds = append(report.Folds, fold)
+	}
+	if len(folds) > 0 {
+		report.Diagnostics = getDiagnostics(report.Folds, cfg.Space)
+	}
+
+	ranked, err := o.search(window{from: 0, to: len(candles)})
+	if err != nil {
+		return nil, err
+	}
+	report.Ranked = ranked[:min(cfg.Top, len(ranked))]
+	report.Backtests = int(o.backtests.Load())
+	report.Invalid = int(o.invalid.Load())
+	return report, nil
+}
+
+// getFoldWindows splits n candles into folds in-sample and out-of-sample windows. The out-of-sample windows
+// follow each other to the last candle, each after an in-sample window inSample of its fold long, or reaching
+// back to the first candle when anchored.
+func getFoldWindows(n int, folds int, inSample float64, anchored bool) ([][2]window, error) {
+	if folds == 0 {
+		return nil, nil
+	}
+	if folds < 0 || inSample <= 0 || inSample >= 1 {
+		return nil, fmt.Errorf("folds can't be negative and inSample must be within (0, 1)")
+	}
+	outLen := int(float64(n) / (float64(folds) + inSample/(1-inSample)))
+	inLen := n - folds*outLen
+	if outLen < 2 || inLen < 2 {
+		return nil, fmt.Errorf("%d candles are too few for %d folds", n, folds)
+	}
+	windows := make([][2]window, folds)
+	for k := range windows {
+		outFrom := inLen + k*outLen
+		outTo := outFrom + outLen
+		if k == folds-1 {
+			outTo = n
+		}
+		inFrom := outFrom - inLen
+		if anchored {
+			inFrom = 0
+		}
+		windows[k] = [2]window{{from: inFrom, to: outFrom}, {from: outFrom, to: outTo}}
+	}
+	return windows, nil
+}
+
+func (o *optimizer) runFold(in window, out window) (Fold, error) {
+	fold := Fold{
+		InSampleStart:    o.candles[in.from].Start,
+		InSampleEnd:      o.candles[in.to-1].Start,
+		OutOfSampleStart: o.candles[out.from].Start,
+		OutOfSampleEnd:   o.candles[out.to-1].Start,
+	}
+	trials, err := o.search(in)
+	if err != nil {
+		return fold, err
+	}
+	top := trials[:min(o.cfg.Top, len(trials))]
+	points := make([]paramPoint, len(top))
+	for i, t := range top {
+		points[i] = t.Params
+	}
+	for i, e := range o.evaluateAll(points, out) {
+		top[i].OutOfSample = e.score
+	}
+	fold.Top = top
+	if len(top) == 0 || top[0].OutOfSample == nil {
+		return fold, nil
+	}
+
+	pick := top[0]
+	fold.Efficiency = getEfficiency(pick.InSample.Score, pick.OutOfSample.Score)
+	var inScores, outScores []float64
+	fold.PickOutOfSampleRank = 1
+	for _, t := range top {
+		if t.OutOfSample == nil {
+			continue
+		}
+		inScores = append(inScores, t.InSample.Score)
+		outScores = append(outScores, t.OutOfSample.Score)
+		if t.OutOfSample.Score > pick.OutOfSample.Score {
+			fold.PickOutOfSampleRank++
+		}
+	}
+	fold.RankCorrelation = getSpearmanCorrelation(inScores, outScores)
+	return fold, nil
+}
+
+func getDiagnostics(folds []Fold, space []ParamRange) *Diagnostics {
+	d := &Diagnostics{ParamStability: make(map[string]ParamStability)}
+	picks := make([]Trial, 0, len(folds))
+	overfit := 0
+	compounded := 1.0
+	for _, f := range folds {
+		if len(f.Top) == 0 || f.Top[0].OutOfSample == nil {
+			continue
+		}
+		pick := f.Top[0]
+		picks = append(picks, pick)
+		d.MeanInSample += pick.InSample.Score
+		d.MeanOutOfSample += pick.OutOfSample.Score
+		d.RankCorrelation += f.RankCorrelation
+		if f.PickOutOfSampleRank > (len(f.Top)+1)/2 {
+			overfit++
+		}
+		if pick.OutOfSample.Metrics.TotalReturn > 0 {
+			d.ProfitableFolds++
+		}
+		compounded *= 1 + pick.OutOfSample.Metrics.TotalReturn/100
+	}
+	if len(picks) == 0 {
+		return d
+	}
+	n := float64(len(picks))
+	d.MeanInSample /= n
+	d.MeanOutOfSample /= n
+	d.RankCorrelation /= n
+	d.OverfitProbability = float64(overfit) / n
+	d.OutOfSampleReturn = (compounded - 1) * 100
+	d.Efficiency = getEfficiency(d.MeanInSample, d.MeanOutOfSample)
+
+	for _, r := range space {
+		mean, variance := 0.0, 0.0
+		for _, p := range picks {
+			mean += p.Params[r.Name]
+		}
+		mean /= n
+		for _, p := range picks {
+			variance += (p.Params[r.Name] - mean) * (p.Params[r.Name] - mean)
+		}
+		stability := ParamStability{Mean: mean, StdDev: math.Sqrt(variance / n)}
+		if r.Max > r.Min {
+			stability.Spread = stability.StdDev / (r.Max - r.Min)
+		}
+		d.ParamStability[r.Name] = stability
+	}
+	return d
+}
+
+// getEfficiency is outOfSample over inSample, nil unless inSample is positive: over a loss or nothing the ratio's
+// sign and size mean nothing, and 0 would read as a pick that fell apart.
+func getEfficiency(inSample float64, outOfSample float64) *float64 {
+	if inSample <= 0 {
+		return nil
+	}
+	efficiency := outOfSample / inSample
+	return &efficiency
+}
+
+// getSpearmanCorrelation is the rank correlation of a and b, 0 when there are too few values or either is
+// constant.
+func getSpearmanCorrelation(a []float64, b []float64) float64 {
+	if len(a) < 3 || len(a) != len(b) {
+		return 0
+	}
+	ra, rb := getRanks(a), getRanks(b)
+	n := float64(len(a))
+	meanA, meanB := 0.0, 0.0
+	for i := range ra {
+		meanA += ra[i]
+		meanB += rb[i]
+	}
+	meanA /= n
+	meanB /= n
+	cov, varA, varB := 0.0, 0.0, 0.0
+	for i := range ra {
+		cov += (ra[i] - meanA) * (rb[i] - meanB)
+		varA += (ra[i] - meanA) * (ra[i] - meanA)
+		varB += (rb[i] - meanB) * (rb[i] - meanB)
+	}
+	if varA == 0 || varB == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varA*varB)
+}
+
+// getRanks ranks values from 1, ties sharing the mean of their ranks.
+func getRanks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return values[order[i]] < values[order[j]] })
+	ranks := make([]float64, len(values))
+	for i := 0; i < len(order); {
+		j := i
+		for j+1 < len(order) && values[order[j+1]] == values[order[i]] {
+			j++
+		}
+		for k := i; k <= j; k++ {
+			ranks[order[k]] = float64(i+j)/2 + 1
+		}
+		i = j + 1
+	}
+	return ranks
+}
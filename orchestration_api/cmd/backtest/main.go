@@ -0,0 +1,118 @@
+// Command backtest replays a candle file, or a series from the candle archive, through one or all strategies and
+// prints the results as JSON.
+//
+//	go run ./cmd/backtest -file eth_5m.csv -symbol ETH-USD -strategy Supertrend -candleSize CandleSize5m
+//	go run ./cmd/backtest -archive data/candles -symbol ETH-USD -strategy Supertrend -candleSize CandleSize5m
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/archive"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/backtest"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+func main() {
+	file := flag.String("file", "", "candle file (.csv, .json or .jsonl)")
+	archiveDir := flag.String("archive", "", "read the symbol's candles of candleSize from this candle archive instead of a file")
+	symbol := flag.String("symbol", "ETH-USD", "product id the candles belong to")
+	strategyName := flag.String("strategy", "all", "strategy name, or 'all' to run every strategy")
+	candleSizeName := flag.String("candleSize", "CandleSize5m", "candle size of the file")
+	funds := flag.Float64("funds", 10000, "allocated funds in USD")
+	fee := flag.Float64("fee", 0.006, "fee rate as a fraction of notional")
+	slippage := flag.Float64("slippageBps", 5, "slippage in basis points applied to every fill")
+	window := flag.Int("window", 100, "number of candles visible to the strategy each bar")
+	out := flag.String("out", "", "write the JSON report here instead of stdout")
+	full := flag.Bool("full", false, "include fills and the equity curve in the report")
+	sizing := flag.String("sizing", "SizingFixedPercent", "position sizing: SizingFixedPercent, SizingFixedRisk or SizingKelly")
+	riskPct := flag.Float64("riskPct", 1, "fixed risk sizing: percent of funds lost when a full position is stopped out")
+	allowShort := flag.Bool("short", false, "act on the strategies' short entries and covers")
+	paramsPath := flag.String("params", "", "strategy params file to run with, its overrides for symbol included (default: the built-in defaults)")
+	flag.Parse()
+
+	if *file == "" && *archiveDir == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	candleSize := enum.GetCandleSizeFromString(*candleSizeName)
+	candles, err := loadCandles(*file, *archiveDir, *symbol, candleSize)
+	if err != nil {
+		log.Fatalf("failed to load candles: %v", err)
+	}
+
+	params := signaler.NewParamsStore()
+	if *paramsPath != "" {
+		if params, err = signaler.LoadParamsStore(*paramsPath); err != nil {
+			log.Fatalf("failed to load strategy params: %v", err)
+		}
+	}
+
+	strategies := enum.GetAllStrategies()
+	if *strategyName != "all" {
+		strategies = []enum.Strategy{enum.GetStrategy(*strategyName)}
+	}
+
+	results := make([]*backtest.Result, 0, len(strategies))
+	for _, strategy := range strategies {
+		cfg := backtest.DefaultConfig(*symbol, strategy, candleSize)
+		cfg.InitialFunds = *funds
+		cfg.FeeRate = *fee
+		cfg.SlippageBps = *slippage
+		cfg.HistoryWindow = *window
+		cfg.Sizing.Method = enum.GetPositionSizingFromString(*sizing)
+		cfg.Sizing.RiskPct = *riskPct
+		cfg.AllowShort = *allowShort
+		cfg.Params = params.GetTokenParams(*symbol, strategy)
+		cfg.MemberParams = params.GetMemberParams(*symbol)
+
+		result, err := backtest.Run(cfg, candles)
+		if err != nil {
+			log.Printf("%s: %v", strategy.String(), err)
+			continue
+		}
+		if !*full {
+			result.Fills = nil
+			result.EquityCurve = nil
+		}
+		m := result.Metrics
+		fmt.Fprintf(os.Stderr, "%-24s return %7.2f%%  maxDD %6.2f%%  sharpe %6.2f  sortino %6.2f  win %5.1f%%  exposure %5.1f%%  trades %d\n",
+			strategy.String(), m.TotalReturn, m.MaxDrawdown, m.Sharpe, m.Sortino, m.WinRate, m.Exposure, m.NumTrades)
+		results = append(results, result)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("failed to create %s: %v", *out, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(results); err != nil {
+		log.Fatalf("failed to write report: %v", err)
+	}
+}
+
+func loadCandles(file string, archiveDir string, symbol string, candleSize enum.CandleSize) ([]models.Candle, error) {
+	if file != "" {
+		return backtest.LoadCandlesFromFile(file, symbol)
+	}
+	a, err := archive.Open(archiveDir)
+	if err != nil {
+		return nil, err
+	}
+	return a.Read(symbol, candleSize, time.Time{}, time.Time{})
+}
@@ -0,0 +1,164 @@
+// Command candles moves candles in and out of the local candle archive and reports the gaps in it.
+//
+//	go run ./cmd/candles import -file eth_5m.csv -symbol ETH-USD -candleSize CandleSize5m
+//	go run ./cmd/candles export -symbol ETH-USD -candleSize CandleSize5m -from 2024-01-01T00:00:00Z -format jsonl -out eth_5m.jsonl
+//	go run ./cmd/candles gaps -symbol ETH-USD -candleSize CandleSize5m
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/archive"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/backtest"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+)
+
+type seriesFlags struct {
+	dir        *string
+	symbol     *string
+	candleSize *string
+	from       *string
+	to         *string
+}
+
+func addSeriesFlags(fs *flag.FlagSet) seriesFlags {
+	return seriesFlags{
+		dir:        fs.String("dir", getEnvOrDefault("CANDLE_ARCHIVE_DIR", "data/candles"), "candle archive directory"),
+		symbol:     fs.String("symbol", "ETH-USD", "product id"),
+		candleSize: fs.String("candleSize", "CandleSize5m", "candle size"),
+		from:       fs.String("from", "", "first candle start, unix seconds or RFC3339 (default: the start of the archive)"),
+		to:         fs.String("to", "", "end of the range, exclusive (default: the end of the archive)"),
+	}
+}
+
+func (f seriesFlags) open() (*archive.CandleArchive, enum.CandleSize, time.Time, time.Time) {
+	a, err := archive.Open(*f.dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	from, err := parseTimeFlag(*f.from)
+	if err != nil {
+		log.Fatalf("invalid -from: %v", err)
+	}
+	to, err := parseTimeFlag(*f.to)
+	if err != nil {
+		log.Fatalf("invalid -to: %v", err)
+	}
+	return a, enum.GetCandleSizeFromString(*f.candleSize), from, to
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "import":
+		runImport(os.Args[2:])
+	case "export":
+		runExport(os.Args[2:])
+	case "gaps":
+		runGaps(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: candles import|export|gaps [flags]")
+	os.Exit(2)
+}
+
+// runImport archives a .csv, .json or .jsonl candle file, in any of the layouts the backtester reads.
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	series := addSeriesFlags(fs)
+	file := fs.String("file", "", "candle file (.csv, .json or .jsonl)")
+	fs.Parse(args)
+	if *file == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	a, candleSize, _, _ := series.open()
+	candles, err := backtest.LoadCandlesFromFile(*file, *series.symbol)
+	if err != nil {
+		log.Fatalf("failed to load candles: %v", err)
+	}
+	if err := a.Write(*series.symbol, candleSize, candles); err != nil {
+		log.Fatalf("failed to archive candles: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "archived %d %s %s candles\n", len(candles), *series.symbol, candleSize.String())
+}
+
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	series := addSeriesFlags(fs)
+	format := fs.String("format", "csv", "csv or jsonl")
+	out := fs.String("out", "", "write here instead of stdout")
+	fs.Parse(args)
+
+	a, candleSize, from, to := series.open()
+	candles, err := a.Read(*series.symbol, candleSize, from, to)
+	if err != nil {
+		log.Fatalf("failed to read the archive: %v", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("failed to create %s: %v", *out, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	switch *format {
+	case "csv":
+		err = archive.WriteCandlesCSV(w, candles)
+	case "jsonl":
+		err = archive.WriteCandlesJSONL(w, candles)
+	default:
+		log.Fatalf("unknown format %q, expected csv or jsonl", *format)
+	}
+	if err != nil {
+		log.Fatalf("failed to export candles: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "exported %d %s %s candles\n", len(candles), *series.symbol, candleSize.String())
+}
+
+func runGaps(args []string) {
+	fs := flag.NewFlagSet("gaps", flag.ExitOnError)
+	series := addSeriesFlags(fs)
+	fs.Parse(args)
+
+	a, candleSize, from, to := series.open()
+	gaps, err := a.FindGaps(*series.symbol, candleSize, from, to)
+	if err != nil {
+		log.Fatalf("failed to read the archive: %v", err)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(gaps); err != nil {
+		log.Fatalf("failed to write gaps: %v", err)
+	}
+}
+
+func parseTimeFlag(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return backtest.ParseCandleTime(v)
+}
+
+func getEnvOrDefault(key string, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
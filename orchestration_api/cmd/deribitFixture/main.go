@@ -0,0 +1,30 @@
+// Command deribitFixture serves a recorded Deribit session over a websocket so the Deribit exchange can be run
+// without a Deribit account.
+//
+//	go run ./cmd/deribitFixture -fixture exchange/deribit/fixtures/eth_perpetual.json
+//	DERIBIT_WS_URL=ws://localhost:8765/ws/api/v2 DERIBIT_CLIENT_ID=fixture DERIBIT_CLIENT_SECRET=fixture go run .
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	deribit_exchange "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange/deribit"
+)
+
+func main() {
+	fixturePath := flag.String("fixture", "exchange/deribit/fixtures/eth_perpetual.json", "recorded session to replay")
+	addr := flag.String("addr", ":8765", "listen address")
+	flag.Parse()
+
+	fixture, err := deribit_exchange.LoadFixture(*fixturePath)
+	if err != nil {
+		log.Fatalf("failed to load fixture: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/ws/api/v2", deribit_exchange.NewFixtureServer(fixture))
+	log.Printf("deribit fixture server listening on %s/ws/api/v2", *addr)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
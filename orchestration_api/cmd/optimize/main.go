@@ -0,0 +1,166 @@
+// Command optimize searches a strategy's params over a candle file, or a series from the candle archive, walking
+// forward through in-sample and out-of-sample folds, and prints the ranked param sets and the overfitting
+// diagnostics as JSON.
+//
+//	go run ./cmd/optimize -file eth_5m.csv -strategy Supertrend -method SearchBayesian -trials 200
+//	go run ./cmd/optimize -archive data/candles -strategy TurtleTrader -space turtle.yaml -method SearchGrid
+//
+// The space file gives the ranges to search by param name, every numeric param from half to double its value
+// when there is none:
+//
+//	numberOfPeriods: {min: 10, max: 60, step: 2}
+//	predictionMultiplier: {min: 1, max: 6, step: 0.5}
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/archive"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/backtest"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+	"gopkg.in/yaml.v3"
+)
+
+func main() {
+	file := flag.String("file", "", "candle file (.csv, .json or .jsonl)")
+	archiveDir := flag.String("archive", "", "read the symbol's candles of candleSize from this candle archive instead of a file")
+	symbol := flag.String("symbol", "ETH-USD", "product id the candles belong to")
+	strategyName := flag.String("strategy", "", "strategy name")
+	candleSizeName := flag.String("candleSize", "CandleSize5m", "candle size of the file")
+	funds := flag.Float64("funds", 10000, "allocated funds in USD")
+	fee := flag.Float64("fee", 0.006, "fee rate as a fraction of notional")
+	slippage := flag.Float64("slippageBps", 5, "slippage in basis points applied to every fill")
+	window := flag.Int("window", 100, "number of candles visible to the strategy each bar")
+	sizing := flag.String("sizing", "SizingFixedPercent", "position sizing: SizingFixedPercent, SizingFixedRisk or SizingKelly")
+	riskPct := flag.Float64("riskPct", 1, "fixed risk sizing: percent of funds lost when a full position is stopped out")
+	allowShort := flag.Bool("short", false, "act on the strategies' short entries and covers")
+	paramsPath := flag.String("params", "", "strategy params file to start from, its overrides for symbol included (default: the built-in defaults)")
+	spacePath := flag.String("space", "", "YAML or JSON file of the param ranges to search (default: every numeric param)")
+	method := flag.String("method", "SearchRandom", "search: SearchGrid, SearchRandom or SearchBayesian")
+	objective := flag.String("objective", "ObjectiveSharpe", "metric to maximise: ObjectiveSharpe, ObjectiveSortino, ObjectiveReturn or ObjectiveReturnOverDrawdown")
+	trials := flag.Int("trials", 100, "backtests per search, the most points a grid may have")
+	top := flag.Int("top", 10, "param sets to rank, and per fold to re-run out of sample")
+	minTrades := flag.Int("minTrades", 0, "leave param sets that trade less than this out of the ranking")
+	workers := flag.Int("workers", 0, "backtests to run at once (default: one per CPU)")
+	seed := flag.Uint64("seed", 1, "random seed")
+	folds := flag.Int("folds", 4, "walk-forward folds, 0 to only search over all the candles")
+	inSample := flag.Float64("inSample", 0.75, "share of each fold's candles searched, the rest tests the pick")
+	anchored := flag.Bool("anchored", false, "search every fold from the first candle instead of a rolling window")
+	out := flag.String("out", "", "write the JSON report here instead of stdout")
+	flag.Parse()
+
+	if (*file == "" && *archiveDir == "") || *strategyName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	strategy, ok := enum.LookupStrategy(*strategyName)
+	if !ok {
+		log.Fatalf("unknown strategy %s", *strategyName)
+	}
+	candleSize := enum.GetCandleSizeFromString(*candleSizeName)
+	candles, err := loadCandles(*file, *archiveDir, *symbol, candleSize)
+	if err != nil {
+		log.Fatalf("failed to load candles: %v", err)
+	}
+
+	params := signaler.NewParamsStore()
+	if *paramsPath != "" {
+		if params, err = signaler.LoadParamsStore(*paramsPath); err != nil {
+			log.Fatalf("failed to load strategy params: %v", err)
+		}
+	}
+
+	cfg := backtest.DefaultConfig(*symbol, strategy, candleSize)
+	cfg.InitialFunds = *funds
+	cfg.FeeRate = *fee
+	cfg.SlippageBps = *slippage
+	cfg.HistoryWindow = *window
+	cfg.Sizing.Method = enum.GetPositionSizingFromString(*sizing)
+	cfg.Sizing.RiskPct = *riskPct
+	cfg.AllowShort = *allowShort
+	cfg.Params = params.GetTokenParams(*symbol, strategy)
+	cfg.MemberParams = params.GetMemberParams(*symbol)
+
+	optCfg := backtest.DefaultOptimizeConfig(cfg)
+	optCfg.Method = enum.GetSearchMethodFromString(*method)
+	optCfg.Objective = enum.GetObjectiveFromString(*objective)
+	optCfg.Trials = *trials
+	optCfg.Top = *top
+	optCfg.MinTrades = *minTrades
+	optCfg.Workers = *workers
+	optCfg.Seed = *seed
+	optCfg.Folds = *folds
+	optCfg.InSample = *inSample
+	optCfg.Anchored = *anchored
+	if *spacePath != "" {
+		if optCfg.Space, err = loadSpace(*spacePath, cfg.Params); err != nil {
+			log.Fatalf("failed to load param space: %v", err)
+		}
+	}
+
+	started := time.Now()
+	report, err := backtest.Optimize(optCfg, candles)
+	if err != nil {
+		log.Fatalf("optimize %s: %v", strategy.String(), err)
+	}
+	fmt.Fprintf(os.Stderr, "%s: %d backtests (%d invalid) in %s\n", strategy.String(), report.Backtests, report.Invalid, time.Since(started).Round(time.Millisecond))
+	if d := report.Diagnostics; d != nil {
+		efficiency := "n/a"
+		if d.Efficiency != nil {
+			efficiency = fmt.Sprintf("%.2f", *d.Efficiency)
+		}
+		fmt.Fprintf(os.Stderr, "walk-forward: in sample %.2f  out of sample %.2f  efficiency %s  rank corr %.2f  overfit %.2f  profitable folds %d/%d  oos return %.2f%%\n",
+			d.MeanInSample, d.MeanOutOfSample, efficiency, d.RankCorrelation, d.OverfitProbability, d.ProfitableFolds, len(report.Folds), d.OutOfSampleReturn)
+	}
+	if len(report.Ranked) > 0 {
+		best, _ := json.Marshal(report.Ranked[0].Params)
+		fmt.Fprintf(os.Stderr, "best: %s  score %.2f\n", best, report.Ranked[0].InSample.Score)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("failed to create %s: %v", *out, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		log.Fatalf("failed to write report: %v", err)
+	}
+}
+
+func loadSpace(path string, params signaler.StrategyParams) ([]backtest.ParamRange, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var ranges map[string]backtest.ParamRange
+	if err := yaml.Unmarshal(raw, &ranges); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return backtest.NewParamSpace(params, ranges)
+}
+
+func loadCandles(file string, archiveDir string, symbol string, candleSize enum.CandleSize) ([]models.Candle, error) {
+	if file != "" {
+		return backtest.LoadCandlesFromFile(file, symbol)
+	}
+	a, err := archive.Open(archiveDir)
+	if err != nil {
+		return nil, err
+	}
+	return a.Read(symbol, candleSize, time.Time{}, time.Time{})
+}
@@ -0,0 +1,75 @@
+package allocation
+
+import (
+	"math"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	exchange "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange"
+)
+
+/* ------------------------------------------------------------------------ PUBLIC INTERFACE ------------------------------------------------------------------------ */
+type AllocationPolicy interface {
+	// GetWeights returns each symbol's share of the funds. The shares sum to 1.
+	GetWeights(symbols []string, exchange exchange.IExchange) map[string]float64
+}
+
+// Status is what the manager last allocated, for the front end.
+type Status struct {
+	Policy           string             `json:"policy"`
+	FixedWeights     map[string]float64 `json:"fixedWeights,omitempty"`
+	RebalanceMinutes float64            `json:"rebalanceMinutes"`
+	Weights          map[string]float64 `json:"weights"`
+	AllocatedFunds   map[string]float64 `json:"allocatedFunds"`
+	LastRebalance    time.Time          `json:"lastRebalance"`
+}
+
+/* ------------------------------------------------------------------------ FACTORY ------------------------------------------------------------------------ */
+func NewAllocationPolicy(policy enum.AllocationPolicy, fixedWeights map[string]float64) AllocationPolicy {
+	switch policy {
+	case enum.AllocationInverseVolatility:
+		return &InverseVolatilityPolicy{AtrLen: 14}
+	case enum.AllocationRiskParity:
+		return &RiskParityPolicy{Lookback: 100, MaxIterations: 500, Tolerance: 1e-10}
+	case enum.AllocationFixedWeights:
+		return &FixedWeightsPolicy{Weights: fixedWeights}
+	default:
+		return &EqualWeightPolicy{}
+	}
+}
+
+/* ------------------------------------------------------------------------ HELPERS ------------------------------------------------------------------------ */
+
+// normalizeWeights scales raw weights to sum to 1. Symbols without a usable weight (no history yet) get the mean
+// of the others, so a newly started token is neither starved nor favoured, and with nothing usable at all every
+// symbol gets an equal share.
+func normalizeWeights(symbols []string, raw map[string]float64) map[string]float64 {
+	sum, count := 0.0, 0
+	for _, symbol := range symbols {
+		if w, ok := raw[symbol]; ok && w > 0 && !math.IsInf(w, 0) && !math.IsNaN(w) {
+			sum += w
+			count++
+		}
+	}
+	weights := make(map[string]float64, len(symbols))
+	if count == 0 {
+		for _, symbol := range symbols {
+			weights[symbol] = 1.0 / float64(len(symbols))
+		}
+		return weights
+	}
+	mean := sum / float64(count)
+	total := 0.0
+	for _, symbol := range symbols {
+		w, ok := raw[symbol]
+		if !ok || w <= 0 || math.IsInf(w, 0) || math.IsNaN(w) {
+			w = mean
+		}
+		weights[symbol] = w
+		total += w
+	}
+	for symbol := range weights {
+		weights[symbol] /= total
+	}
+	return weights
+}
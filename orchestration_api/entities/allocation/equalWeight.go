@@ -0,0 +1,10 @@
+package allocation
+
+import exchange "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange"
+
+// EqualWeightPolicy splits the funds evenly, the way the manager always has.
+type EqualWeightPolicy struct{}
+
+func (p *EqualWeightPolicy) GetWeights(symbols []string, exchange exchange.IExchange) map[string]float64 {
+	return normalizeWeights(symbols, map[string]float64{})
+}
@@ -0,0 +1,24 @@
+package allocation
+
+import exchange "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange"
+
+// FixedWeightsPolicy uses weights set by hand, renormalized over the tokens that are running. A running token
+// without a weight gets nothing; if none of them has one, the funds are split evenly.
+type FixedWeightsPolicy struct {
+	Weights map[string]float64
+}
+
+func (p *FixedWeightsPolicy) GetWeights(symbols []string, exchange exchange.IExchange) map[string]float64 {
+	total := 0.0
+	for _, symbol := range symbols {
+		total += max(p.Weights[symbol], 0)
+	}
+	if total <= 0 {
+		return normalizeWeights(symbols, map[string]float64{})
+	}
+	weights := make(map[string]float64, len(symbols))
+	for _, symbol := range symbols {
+		weights[symbol] = max(p.Weights[symbol], 0) / total
+	}
+	return weights
+}
@@ -0,0 +1,34 @@
+package allocation
+
+import (
+	exchange "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange"
+	talib "github.com/markcheno/go-talib"
+)
+
+// InverseVolatilityPolicy weights each token by the inverse of its ATR as a share of price on the long candles,
+// so a token that moves twice as much gets half the funds.
+type InverseVolatilityPolicy struct {
+	AtrLen int
+}
+
+func (p *InverseVolatilityPolicy) GetWeights(symbols []string, exchange exchange.IExchange) map[string]float64 {
+	raw := make(map[string]float64, len(symbols))
+	for _, symbol := range symbols {
+		candles := exchange.GetLongCandleHistory(symbol).Candles
+		if len(candles) <= p.AtrLen {
+			continue
+		}
+		highs := make([]float64, len(candles))
+		lows := make([]float64, len(candles))
+		closes := make([]float64, len(candles))
+		for i, c := range candles {
+			highs[i], lows[i], closes[i] = c.High, c.Low, c.Close
+		}
+		atr := talib.Atr(highs, lows, closes, p.AtrLen)
+		lastClose := closes[len(closes)-1]
+		if atrPct := atr[len(atr)-1] / lastClose; lastClose > 0 && atrPct > 0 {
+			raw[symbol] = 1.0 / atrPct
+		}
+	}
+	return normalizeWeights(symbols, raw)
+}
@@ -0,0 +1,165 @@
+package allocation
+
+import (
+	"math"
+	"time"
+
+	exchange "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange"
+)
+
+// RiskParityPolicy sizes the tokens so each contributes the same share of the portfolio's variance, using the
+// covariance of log returns over the last Lookback long candles every token has in common. Correlated tokens
+// share a risk budget rather than each getting its own.
+type RiskParityPolicy struct {
+	Lookback      int
+	MaxIterations int
+	Tolerance     float64
+}
+
+func (p *RiskParityPolicy) GetWeights(symbols []string, exchange exchange.IExchange) map[string]float64 {
+	if len(symbols) < 2 {
+		return normalizeWeights(symbols, map[string]float64{})
+	}
+	returns, covered := p.getAlignedReturns(symbols, exchange)
+	if len(covered) < 2 {
+		return normalizeWeights(symbols, map[string]float64{})
+	}
+	raw := getEqualRiskContributionWeights(getCovariance(returns), p.MaxIterations, p.Tolerance)
+	weights := make(map[string]float64, len(covered))
+	for i, symbol := range covered {
+		weights[symbol] = raw[i]
+	}
+	return normalizeWeights(symbols, weights)
+}
+
+// getAlignedReturns lines the symbols' closes up by candle start and returns the log returns of the last Lookback
+// candles they all have. Symbols without enough history are left out, covered lists the rest in column order.
+func (p *RiskParityPolicy) getAlignedReturns(symbols []string, exchange exchange.IExchange) ([][]float64, []string) {
+	closes := make(map[string]map[time.Time]float64)
+	var starts []time.Time
+	var covered []string
+	for _, symbol := range symbols {
+		candles := exchange.GetLongCandleHistory(symbol).Candles
+		if len(candles) <= p.Lookback/2 {
+			continue
+		}
+		byStart := make(map[time.Time]float64, len(candles))
+		for _, c := range candles {
+			byStart[c.Start] = c.Close
+		}
+		if len(covered) == 0 {
+			for _, c := range candles {
+				starts = append(starts, c.Start)
+			}
+		}
+		closes[symbol] = byStart
+		covered = append(covered, symbol)
+	}
+
+	var common []time.Time
+	for _, start := range starts {
+		inAll := true
+		for _, symbol := range covered {
+			if _, ok := closes[symbol][start]; !ok {
+				inAll = false
+				break
+			}
+		}
+		if inAll {
+			common = append(common, start)
+		}
+	}
+	if len(common) > p.Lookback+1 {
+		common = common[len(common)-p.Lookback-1:]
+	}
+	if len(common) < 3 {
+		return nil, nil
+	}
+
+	returns := make([][]float64, 0, len(common)-1)
+	for t := 1; t < len(common); t++ {
+		row := make([]float64, len(covered))
+		for i, symbol := range covered {
+			previous, current := closes[symbol][common[t-1]], closes[symbol][common[t]]
+			if previous > 0 && current > 0 {
+				row[i] = math.Log(current / previous)
+			}
+		}
+		returns = append(returns, row)
+	}
+	return returns, covered
+}
+
+// getCovariance is the sample covariance matrix of the return columns.
+func getCovariance(returns [][]float64) [][]float64 {
+	n := len(returns[0])
+	means := make([]float64, n)
+	for _, row := range returns {
+		for i, r := range row {
+			means[i] += r / float64(len(returns))
+		}
+	}
+	covariance := make([][]float64, n)
+	for i := range covariance {
+		covariance[i] = make([]float64, n)
+	}
+	for _, row := range returns {
+		for i := 0; i < n; i++ {
+			for j := i; j < n; j++ {
+				covariance[i][j] += (row[i] - means[i]) * (row[j] - means[j]) / float64(len(returns)-1)
+			}
+		}
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < i; j++ {
+			covariance[i][j] = covariance[j][i]
+		}
+	}
+	return covariance
+}
+
+// getEqualRiskContributionWeights solves for weights with equal risk contributions by cyclical coordinate descent
+// on ½wᵀΣw − Σ ln(wᵢ)/n: each step sets wᵢ to the positive root of Σᵢᵢwᵢ² + (Σⱼ≠ᵢ Σᵢⱼwⱼ)wᵢ − 1/n = 0. The result
+// is normalized to sum to 1. A token with no variance is left at zero, there is nothing to size it against, and
+// normalizeWeights gives it the mean weight.
+func getEqualRiskContributionWeights(covariance [][]float64, maxIterations int, tolerance float64) []float64 {
+	n := len(covariance)
+	budget := 1.0 / float64(n)
+	weights := make([]float64, n)
+	for i := range weights {
+		if covariance[i][i] > 0 {
+			weights[i] = 1.0 / math.Sqrt(covariance[i][i])
+		}
+	}
+	for iteration := 0; iteration < maxIterations; iteration++ {
+		change := 0.0
+		for i := 0; i < n; i++ {
+			if covariance[i][i] <= 0 {
+				continue
+			}
+			cross := 0.0
+			for j := 0; j < n; j++ {
+				if j != i {
+					cross += covariance[i][j] * weights[j]
+				}
+			}
+			updated := (-cross + math.Sqrt(cross*cross+4*covariance[i][i]*budget)) / (2 * covariance[i][i])
+			change = max(change, math.Abs(updated-weights[i]))
+			weights[i] = updated
+		}
+		if change < tolerance {
+			break
+		}
+	}
+
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	if total > 0 {
+		for i := range weights {
+			weights[i] /= total
+		}
+	}
+	return weights
+}
@@ -5,26 +5,45 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/archive"
 	"github.com/A-Here-And-Now/algo-trader/orchestration_api/channel_helper"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/allocation"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/risk"
 	"github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler"
 	"github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/trader"
 	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
 	exchange "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange"
 	coinbase_exchange "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange/coinbase"
+	exchange_helper "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange/helper"
+	deribit_exchange "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange/deribit"
+	paper_exchange "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange/paper"
+	uniswap_exchange "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange/uniswap"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/journal"
 	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/persistence"
+)
+
+const (
+	coinbaseMarketDataWsUrl = "wss://advanced-trade-ws.coinbase.com"
+	coinbaseUserDataWsUrl   = "wss://advanced-trade-ws-user.coinbase.com"
+	defaultRebalanceInterval = time.Hour
 )
 
 type Manager struct {
 	mu                  	sync.RWMutex
+	cfgMu               	sync.RWMutex // guards the token maps in Cfg, which the API and RestoreTraders both write
 	ctx                 	context.Context
 	wg                  	sync.WaitGroup
 	Cfg                 	ManagerCfg
 	updates             	chan ManagerCfg
-	tokenProfitLossTotals 	map[string]float64
 	profitLossTotalChannel  chan models.TokenProfitLossUpdate
+	executionProgressChannel chan models.ExecutionProgress
+	executionHub            *exchange_helper.SubscriptionHub[models.ExecutionProgress] // fans progress out to the frontend websocket
 	engine              	*signaler.SignalEngine
 	traderResources     	map[string]*trader.TraderResource
 	frontendConnected   	bool
@@ -34,8 +53,23 @@ type Manager struct {
 	apiSecret           	string
 	tokens              	[]string
 	exchange            	exchange.IExchange
+	exchangeCancel      	context.CancelFunc
 	signalEngineUpdates 	chan signaler.SignalEngineConfigUpdate
 	tokenToggles       		*models.ToggleStore
+	store               	*persistence.StateStore
+	journal             	*journal.Journal
+	candleArchive       	*archive.CandleArchive
+	strategyParams      	*signaler.ParamsStore
+	risk                	*risk.RiskManager
+	allocationMu        	sync.Mutex // serializes reallocateFunds and guards the allocation fields below
+	allocation          	allocation.AllocationPolicy
+	allocationPolicy    	enum.AllocationPolicy
+	fixedWeights        	map[string]float64
+	rebalanceInterval   	time.Duration
+	rebalanceReset      	chan struct{} // restarts the rebalance timer after the interval changes
+	tokenWeights        	map[string]float64
+	lastRebalance       	time.Time
+	backfillMu          	sync.Mutex // one candle backfill at a time, so the last one to run is at the latest cfg
 }
 
 type ManagerCfg struct {
@@ -43,18 +77,63 @@ type ManagerCfg struct {
 	maxPL            int64
 	tokenStrategies  map[string]enum.Strategy
 	tokenCandleSizes map[string]enum.CandleSize
+	tokenOrderTypes  map[string]enum.OrderType
+	tokenExecutions  map[string]trader.ExecutionCfg
+	tokenSizings     map[string]trader.SizingCfg
+	tokenDerivatives map[string]trader.DerivativesCfg
 	tokenEnabled     map[string]bool
 }
 
 func (m *Manager) GetStrategy(token string) enum.Strategy {
+	m.cfgMu.RLock()
+	defer m.cfgMu.RUnlock()
 	return m.Cfg.tokenStrategies[token]
 }
 
+func (m *Manager) isShortAllowed(token string) bool {
+	m.cfgMu.RLock()
+	defer m.cfgMu.RUnlock()
+	return m.Cfg.tokenDerivatives[token].AllowShort
+}
+
 func (m *Manager) GetCandleSize(token string) enum.CandleSize {
+	m.cfgMu.RLock()
+	defer m.cfgMu.RUnlock()
 	return m.Cfg.tokenCandleSizes[token]
 }
 
-func NewManager(funds float64, maxPL int64, startingStrategy enum.Strategy, startingCandleSize enum.CandleSize, ctx context.Context, apiKey string, apiSecret string, tokens []string) *Manager {
+func (m *Manager) GetOrderType(token string) enum.OrderType {
+	m.cfgMu.RLock()
+	defer m.cfgMu.RUnlock()
+	return m.Cfg.tokenOrderTypes[token]
+}
+
+func (m *Manager) GetExecutionCfg(token string) trader.ExecutionCfg {
+	m.cfgMu.RLock()
+	defer m.cfgMu.RUnlock()
+	return m.Cfg.tokenExecutions[token]
+}
+
+func (m *Manager) GetSizingCfg(token string) trader.SizingCfg {
+	m.cfgMu.RLock()
+	defer m.cfgMu.RUnlock()
+	return m.Cfg.tokenSizings[token]
+}
+
+func (m *Manager) GetDerivativesCfg(token string) trader.DerivativesCfg {
+	m.cfgMu.RLock()
+	defer m.cfgMu.RUnlock()
+	return m.Cfg.tokenDerivatives[token]
+}
+
+// getFunds is the funds split across the traders, read under cfgMu since the rebalancing goroutine reads them too.
+func (m *Manager) getFunds() float64 {
+	m.cfgMu.RLock()
+	defer m.cfgMu.RUnlock()
+	return m.Cfg.funds
+}
+
+func NewManager(funds float64, maxPL int64, startingStrategy enum.Strategy, startingCandleSize enum.CandleSize, ctx context.Context, apiKey string, apiSecret string, tokens []string, store *persistence.StateStore, journal *journal.Journal, candleArchive *archive.CandleArchive, strategyParams *signaler.ParamsStore) *Manager {
 	updates := make(chan ManagerCfg)
 	signalEngineUpdates := make(chan signaler.SignalEngineConfigUpdate, 10)
 
@@ -64,11 +143,16 @@ func NewManager(funds float64, maxPL int64, startingStrategy enum.Strategy, star
 			maxPL:            	maxPL,
 			tokenStrategies:  	make(map[string]enum.Strategy),
 			tokenCandleSizes: 	make(map[string]enum.CandleSize),
+			tokenOrderTypes:  	make(map[string]enum.OrderType),
+			tokenExecutions:  	make(map[string]trader.ExecutionCfg),
+			tokenSizings:     	make(map[string]trader.SizingCfg),
+			tokenDerivatives: 	make(map[string]trader.DerivativesCfg),
 			tokenEnabled:     	make(map[string]bool),
 		},
 		ctx:                 	ctx,
 		profitLossTotalChannel: make(chan models.TokenProfitLossUpdate, 2),
-		tokenProfitLossTotals: 	make(map[string]float64),
+		executionProgressChannel: make(chan models.ExecutionProgress, 16),
+		executionHub:        	exchange_helper.NewSubscriptionHub[models.ExecutionProgress](16),
 		updates:             	updates,
 		traderResources:     	make(map[string]*trader.TraderResource),
 		frontendConnected:   	false,
@@ -76,24 +160,41 @@ func NewManager(funds float64, maxPL int64, startingStrategy enum.Strategy, star
 		apiKey:              	apiKey,
 		apiSecret:           	apiSecret,
 		tokens:              	tokens,
-		exchange:            	coinbase_exchange.NewCoinbaseExchange(ctx, apiKey, apiSecret),
+		exchange:            	coinbase_exchange.NewCoinbaseExchange(ctx, apiKey, apiSecret, candleArchive),
 		signalEngineUpdates: 	signalEngineUpdates,
 		tokenToggles:       	models.NewToggleStore(tokens),
+		store:               	store,
+		journal:             	journal,
+		candleArchive:       	candleArchive,
+		strategyParams:      	strategyParams,
+		risk:                	risk.NewRiskManager(risk.DefaultLimits(float64(maxPL)), store),
+		allocation:          	allocation.NewAllocationPolicy(enum.AllocationEqualWeight, nil),
+		allocationPolicy:    	enum.AllocationEqualWeight,
+		rebalanceInterval:   	defaultRebalanceInterval,
+		rebalanceReset:      	make(chan struct{}, 1),
+		tokenWeights:        	make(map[string]float64),
 	}
 
 	for _, token := range tokens {
 		manager.Cfg.tokenStrategies[token] = startingStrategy
 		manager.Cfg.tokenCandleSizes[token] = startingCandleSize
+		manager.Cfg.tokenOrderTypes[token] = enum.OrderTypeMarket
+		manager.Cfg.tokenExecutions[token] = trader.DefaultExecutionCfg()
+		manager.Cfg.tokenSizings[token] = trader.DefaultSizingCfg()
+		manager.Cfg.tokenDerivatives[token] = trader.DefaultDerivativesCfg()
 		manager.Cfg.tokenEnabled[token] = false
 	}
 
-	manager.engine = signaler.NewSignalEngine(manager.ctx, manager.exchange, signalEngineUpdates)
+	manager.engine = signaler.NewSignalEngine(manager.ctx, manager.exchange, signalEngineUpdates, store, strategyParams)
+	manager.startExchangeFeeds()
 
 	go func() {
 		for {
 			select {
-			case manager.Cfg = <-updates:
-				continue
+			case cfg := <-updates:
+				manager.cfgMu.Lock()
+				manager.Cfg = cfg
+				manager.cfgMu.Unlock()
 			case <-manager.ctx.Done():
 				manager.StopAll()
 				return
@@ -103,29 +204,51 @@ func NewManager(funds float64, maxPL int64, startingStrategy enum.Strategy, star
 					return
 				}
 				manager.handleProfitLossTotalUpdate(profitLossUpdate)
+			case progress := <-manager.executionProgressChannel:
+				manager.executionHub.Publish(progress.Symbol, progress)
 			}
 		}
 	}()
+	go manager.runRebalancing()
 
 	return &manager
 }
 
+// handleProfitLossTotalUpdate hands a trader's P&L to the risk manager and pulls the kill switch when that
+// breaches the daily loss or drawdown limit.
 func (m *Manager) handleProfitLossTotalUpdate(profitLossUpdate models.TokenProfitLossUpdate) {
-	profitLossTotal := 0.0
-	tokenProfitLossTotal := 0.0
-	origValue, exists := m.tokenProfitLossTotals[profitLossUpdate.Symbol]
-	if exists {
-		tokenProfitLossTotal = origValue + profitLossUpdate.ProfitLoss
-	} else {
-		tokenProfitLossTotal = profitLossUpdate.ProfitLoss
+	if reason, breached := m.risk.UpdateProfitLoss(profitLossUpdate.Symbol, profitLossUpdate.ProfitLoss, m.getFunds()); breached {
+		m.KillSwitch(reason)
 	}
-	m.tokenProfitLossTotals[profitLossUpdate.Symbol] = tokenProfitLossTotal
-	for _, value := range m.tokenProfitLossTotals {
-		profitLossTotal += value
-	}
-	if profitLossTotal > float64(m.Cfg.maxPL) {
-		m.StopAll()
+}
+
+// KillSwitch halts trading: every trader cancels its orders and sells its position on the way out, and Start is
+// refused until RearmRisk.
+func (m *Manager) KillSwitch(reason string) {
+	m.risk.Halt(reason)
+	for symbol := range m.safeGetTraderResources() {
+		m.tokenToggles.Set(symbol, false)
+		if err := m.Stop(symbol); err != nil {
+			log.Printf("kill switch could not stop %q: %v", symbol, err)
+		}
 	}
+	log.Printf("kill switch pulled: %s", reason)
+}
+
+func (m *Manager) RearmRisk() {
+	m.risk.Rearm()
+}
+
+func (m *Manager) GetRiskStatus() risk.Status {
+	return m.risk.GetStatus()
+}
+
+func (m *Manager) GetRiskLimits() risk.Limits {
+	return m.risk.GetLimits()
+}
+
+func (m *Manager) UpdateRiskLimits(limits risk.Limits) error {
+	return m.risk.UpdateLimits(limits)
 }
 
 func (m *Manager) safeAddTraderResource(symbol string, cfg trader.TradeCfg, done chan struct{}, cancel context.CancelFunc, updates chan trader.TradeCfg) {
@@ -163,6 +286,7 @@ func (m *Manager) StopAll() {
 	}
 
 	m.engine.Stop()
+	m.risk.Flush()
 	close(m.signalEngineUpdates)
 
 	doneCh := make(chan struct{})
@@ -180,31 +304,90 @@ func (m *Manager) StopAll() {
 }
 
 func (m *Manager) Start(tokenStr string) error {
+	return m.start(tokenStr, nil)
+}
+
+// RestoreTraders restarts every trader that was still running when the process went down, from its persisted
+// snapshot reconciled against the exchange. Traders stopped from the front end leave no snapshot behind.
+func (m *Manager) RestoreTraders() {
+	snapshots, err := trader.LoadTraderSnapshots(m.store)
+	if err != nil {
+		log.Printf("failed to load trader snapshots: %v", err)
+		return
+	}
+	for symbol, snapshot := range snapshots {
+		if _, ok := m.tokenToggles.Get(symbol); !ok {
+			log.Printf("ignoring snapshot for unknown token %q", symbol)
+			continue
+		}
+		m.cfgMu.Lock()
+		m.Cfg.tokenStrategies[symbol] = snapshot.Cfg.Strategy
+		m.Cfg.tokenCandleSizes[symbol] = snapshot.Cfg.CandleSize
+		m.Cfg.tokenOrderTypes[symbol] = snapshot.Cfg.OrderType
+		if snapshot.Cfg.Execution.MaxSliceUSD > 0 {
+			m.Cfg.tokenExecutions[symbol] = snapshot.Cfg.Execution
+		}
+		if snapshot.Cfg.Sizing.AtrLen > 0 {
+			m.Cfg.tokenSizings[symbol] = snapshot.Cfg.Sizing
+		}
+		if snapshot.Cfg.Derivatives.Leverage > 0 {
+			m.Cfg.tokenDerivatives[symbol] = snapshot.Cfg.Derivatives
+		}
+		m.cfgMu.Unlock()
+		m.tokenToggles.Set(symbol, true)
+		if err := m.start(symbol, &snapshot); err != nil {
+			log.Printf("failed to restore trader %q: %v", symbol, err)
+			m.tokenToggles.Set(symbol, false)
+			continue
+		}
+		log.Printf("trader %q restored from snapshot saved at %s", symbol, snapshot.SavedAt.Format(time.RFC3339))
+	}
+}
+
+func (m *Manager) start(tokenStr string, snapshot *trader.TraderSnapshot) error {
 	if _, exists := m.safeGetTraderResources()[tokenStr]; exists {
 		return fmt.Errorf("trader %q already running", tokenStr)
 	}
+	if halted, reason := m.risk.IsHalted(); halted {
+		return fmt.Errorf("trading is halted until the risk manager is re-armed: %s", reason)
+	}
 
 	ctx, cancel := context.WithCancel(m.ctx)
 
 	done := make(chan struct{})
 
+	m.cfgMu.RLock()
 	tradeCfg := trader.TradeCfg{
-		Symbol:     tokenStr,
-		Strategy:   m.Cfg.tokenStrategies[tokenStr],
-		CandleSize: m.Cfg.tokenCandleSizes[tokenStr],
+		Symbol:      tokenStr,
+		Strategy:    m.Cfg.tokenStrategies[tokenStr],
+		CandleSize:  m.Cfg.tokenCandleSizes[tokenStr],
+		OrderType:   m.Cfg.tokenOrderTypes[tokenStr],
+		Execution:   m.Cfg.tokenExecutions[tokenStr],
+		Sizing:      m.Cfg.tokenSizings[tokenStr],
+		Derivatives: m.Cfg.tokenDerivatives[tokenStr],
 	}
+	m.cfgMu.RUnlock()
 
 	updates := make(chan trader.TradeCfg, 4)
 
+	if err := m.exchange.StartNewTokenDataStream(tokenStr, tradeCfg.CandleSize, m.strategyParams.GetWarmUpCandles(tokenStr, tradeCfg.Strategy)); err != nil {
+		cancel()
+		return fmt.Errorf("failed to start data stream for %q: %w", tokenStr, err)
+	}
+
 	m.safeAddTraderResource(tokenStr, tradeCfg, done, cancel, updates)
 
 	// Register with signal engine - note: engine will subscribe to exchange directly
-	m.engine.RegisterToken(tokenStr, tradeCfg.Strategy, tradeCfg.CandleSize, m.traderResources[tokenStr].SignalChan)
+	m.engine.RegisterToken(tokenStr, tradeCfg.Strategy, tradeCfg.CandleSize, tradeCfg.Derivatives.AllowShort, m.traderResources[tokenStr].SignalChan)
 
 	m.RefreshTokenBalances()
 
 	// Create new trader - trader will subscribe to exchange directly for data feeds
-	newTrader := trader.NewTrader(tradeCfg, ctx, cancel, updates, m.traderResources[tokenStr].SignalChan, m.profitLossTotalChannel, m.tokenBalances[tokenStr], m.exchange)
+	tokenBalance := m.tokenBalances[models.GetBaseCurrency(tokenStr)]
+	newTrader := trader.NewTrader(tradeCfg, ctx, cancel, updates, m.traderResources[tokenStr].SignalChan, m.profitLossTotalChannel, m.executionProgressChannel, &m.traderResources[tokenStr].Position, tokenBalance, m.exchange, m.store, m.journal, m.risk)
+	if snapshot != nil {
+		newTrader.RestoreState(*snapshot, tokenBalance)
+	}
 
 	go func() {
 		defer close(done)
@@ -236,16 +419,28 @@ func (m *Manager) Stop(token string) error {
 		case <-tr.Done:
 			log.Printf("trader %q stopped cleanly", tr.Cfg.Symbol)
 			if m.ctx.Err() == nil {
+				// stopped on purpose rather than by shutdown, so don't resume it on the next start
+				m.deleteSnapshots(tr.Cfg.Symbol)
+				m.risk.RemoveToken(tr.Cfg.Symbol)
 				m.reallocateFunds()
 			}
 		case <-time.After(19 * time.Second):
-			log.Printf("trader %q did not stop within timeout - need to pull active positions from exchange upon restart", tr.Cfg.Symbol)
+			log.Printf("trader %q did not stop within timeout - keeping its snapshot so it is reconciled on restart", tr.Cfg.Symbol)
 		}
 	}(t)
 
 	return nil
 }
 
+func (m *Manager) deleteSnapshots(symbol string) {
+	if err := trader.DeleteTraderSnapshot(m.store, symbol); err != nil {
+		log.Printf("failed to delete trader snapshot for %q: %v", symbol, err)
+	}
+	if err := signaler.DeletePositionSnapshot(m.store, symbol); err != nil {
+		log.Printf("failed to delete position snapshot for %q: %v", symbol, err)
+	}
+}
+
 func (m *Manager) RefreshTokenBalances() {
 	balances, err := m.exchange.GetAllTokenBalances(m.ctx)
 	if err != nil {
@@ -254,43 +449,216 @@ func (m *Manager) RefreshTokenBalances() {
 	m.tokenBalances = balances
 }
 
+// UpdateMaxPL sets the daily loss limit, the most the account may lose in a UTC day before the kill switch trips.
 func (m *Manager) UpdateMaxPL(maxPL int64) {
+	limits := m.risk.GetLimits()
+	limits.DailyLossLimitUSD = float64(maxPL)
+	if err := m.risk.UpdateLimits(limits); err != nil {
+		log.Printf("failed to update daily loss limit: %v", err)
+		return
+	}
+	m.cfgMu.RLock()
 	cfg := m.Cfg
+	m.cfgMu.RUnlock()
 	cfg.maxPL = maxPL
 	m.updates <- cfg
 }
 
 func (m *Manager) UpdateAllocatedFunds(allocatedFunds float64) {
+	m.cfgMu.Lock()
 	m.Cfg.funds = allocatedFunds
+	m.cfgMu.Unlock()
 	log.Printf("Allocated funds updated to %v", allocatedFunds)
 	m.reallocateFunds()
 }
 
 func (m *Manager) UpdateStrategy(token string, strategy enum.Strategy) {
+	m.cfgMu.Lock()
 	m.Cfg.tokenStrategies[token] = strategy
-	if _, exists := m.traderResources[token]; exists {
-		newCfg := m.traderResources[token].Cfg	
-		newCfg.Strategy = strategy
-		m.traderResources[token].Updates <- newCfg
+	m.cfgMu.Unlock()
+	if m.updateTraderCfg(token, func(cfg *trader.TradeCfg) { cfg.Strategy = strategy }) {
+		m.updateCandleHistory(token)
 		m.engine.UpdateStrategy(token, strategy)
 	}
 }
 
 func (m *Manager) UpdateCandleSize(token string, candleSize enum.CandleSize) {
+	m.cfgMu.Lock()
 	m.Cfg.tokenCandleSizes[token] = candleSize
-	if _, exists := m.traderResources[token]; exists {
-		newCfg := m.traderResources[token].Cfg
-		newCfg.CandleSize = candleSize
-		m.traderResources[token].Updates <- newCfg
+	m.cfgMu.Unlock()
+	if m.updateTraderCfg(token, func(cfg *trader.TradeCfg) { cfg.CandleSize = candleSize }) {
+		m.updateCandleHistory(token)
 		m.engine.UpdateCandleSize(token, candleSize)
 	}
 }
 
-func (m *Manager) GetAllPriceHistory() map[string][]models.Ticker {
+// updateCandleHistory backfills a running token's candle history again in the background, at its candle size and
+// deep enough to warm up its strategy as they are when the backfill runs. The strategy holds until it is warm.
+func (m *Manager) updateCandleHistory(token string) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		m.backfillMu.Lock()
+		defer m.backfillMu.Unlock()
+		if m.ctx.Err() != nil {
+			return
+		}
+		warmUp := m.strategyParams.GetWarmUpCandles(token, m.GetStrategy(token))
+		if err := m.exchange.UpdateCandleSizeForSymbol(token, m.GetCandleSize(token), warmUp); err != nil {
+			log.Printf("failed to backfill candle history for %q: %v", token, err)
+		}
+	}()
+}
+
+// GetStrategyParams is what the strategy runs with, with the token's overrides applied when a token is given.
+func (m *Manager) GetStrategyParams(strategy enum.Strategy, token string) signaler.StrategyParams {
+	if token == "" {
+		return m.strategyParams.GetParams(strategy)
+	}
+	return m.strategyParams.GetTokenParams(token, strategy)
+}
+
+// UpdateStrategyParams changes the strategy's params, or only the token's overrides when a token is given, and
+// swaps the new params into every running trader they reach. Changes last until a restart, ones that should stick
+// belong in the params file.
+func (m *Manager) UpdateStrategyParams(strategy enum.Strategy, token string, raw []byte) (signaler.StrategyParams, error) {
+	if token != "" && !slices.Contains(m.tokens, token) {
+		return nil, fmt.Errorf("unknown token %q", token)
+	}
+	affected := make(map[string]int) // running token -> its warm-up before the change
+	for t := range m.safeGetTraderResources() {
+		if m.strategyParams.IsAffectedBy(t, m.GetStrategy(t), strategy) && (token == "" || t == token) {
+			affected[t] = m.strategyParams.GetWarmUpCandles(t, m.GetStrategy(t))
+		}
+	}
+
+	var params signaler.StrategyParams
+	var err error
+	if token == "" {
+		params, err = m.strategyParams.UpdateParams(strategy, raw)
+	} else {
+		params, err = m.strategyParams.UpdateTokenParams(token, strategy, raw)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for t, warmUp := range affected {
+		if m.strategyParams.GetWarmUpCandles(t, m.GetStrategy(t)) > warmUp {
+			m.updateCandleHistory(t)
+		}
+		m.engine.ReloadStrategy(t)
+	}
+	log.Printf("%s params updated, %d running token(s) reloaded", strategy.String(), len(affected))
+	return params, nil
+}
+
+// UpdateOrderType switches how the token's trader works its orders. Only market, limit and post-only apply, the
+// trader tracks a target rather than placing stops or brackets.
+func (m *Manager) UpdateOrderType(token string, orderType enum.OrderType) error {
+	if orderType != enum.OrderTypeMarket && orderType != enum.OrderTypeLimit && orderType != enum.OrderTypePostOnly {
+		return fmt.Errorf("traders can't execute with %s orders", orderType.String())
+	}
+	m.cfgMu.Lock()
+	m.Cfg.tokenOrderTypes[token] = orderType
+	m.cfgMu.Unlock()
+	m.updateTraderCfg(token, func(cfg *trader.TradeCfg) { cfg.OrderType = orderType })
+	return nil
+}
+
+// UpdateExecutionCfg changes how the token's trader slices large deficits. It applies from the trader's next
+// execution, one already being worked finishes under the algo it started with.
+func (m *Manager) UpdateExecutionCfg(token string, cfg trader.ExecutionCfg) error {
+	if !slices.Contains(m.tokens, token) {
+		return fmt.Errorf("unknown token %q", token)
+	}
+	if cfg.MaxSliceUSD <= 0 {
+		return fmt.Errorf("max slice size must be positive")
+	}
+	if cfg.Algo == enum.ExecutionTWAP || cfg.Algo == enum.ExecutionNativeTWAP {
+		if cfg.Duration <= 0 {
+			return fmt.Errorf("%s needs a positive duration", cfg.Algo.String())
+		}
+	}
+	if cfg.Algo == enum.ExecutionParticipation && (cfg.ParticipationRate <= 0 || cfg.ParticipationRate > 1) {
+		return fmt.Errorf("participation rate must be within (0, 1]")
+	}
+	if cfg.MaxSlippageBps < 0 {
+		return fmt.Errorf("max slippage can't be negative")
+	}
+	m.cfgMu.Lock()
+	m.Cfg.tokenExecutions[token] = cfg
+	m.cfgMu.Unlock()
+	m.updateTraderCfg(token, func(tradeCfg *trader.TradeCfg) { tradeCfg.Execution = cfg })
+	return nil
+}
+
+// UpdateSizingCfg changes how large the token's full position is. It applies from the next buy signal, what is
+// already held isn't resized.
+func (m *Manager) UpdateSizingCfg(token string, cfg trader.SizingCfg) error {
+	if cfg.RiskPct <= 0 || cfg.RiskPct > 100 {
+		return fmt.Errorf("risk per trade must be within (0, 100]")
+	}
+	if cfg.AtrLen <= 0 || cfg.StopAtrMultiple <= 0 {
+		return fmt.Errorf("ATR length and stop multiple must be positive")
+	}
+	if cfg.KellyFraction <= 0 || cfg.KellyFraction > 1 {
+		return fmt.Errorf("kelly fraction must be within (0, 1]")
+	}
+	if cfg.KellyMinTrades < 1 {
+		return fmt.Errorf("kelly needs at least one closed trade")
+	}
+	m.cfgMu.Lock()
+	m.Cfg.tokenSizings[token] = cfg
+	m.cfgMu.Unlock()
+	m.updateTraderCfg(token, func(tradeCfg *trader.TradeCfg) { tradeCfg.Sizing = cfg })
+	return nil
+}
+
+// UpdateDerivativesCfg changes whether the token's trader may go short and how far it levers its allocated funds.
+// It applies from the next signal, what is already held isn't resized.
+func (m *Manager) UpdateDerivativesCfg(token string, cfg trader.DerivativesCfg) error {
+	if cfg.Leverage < 1 {
+		return fmt.Errorf("leverage must be at least 1")
+	}
+	if cfg.MinLiquidationDistancePct < 0 {
+		return fmt.Errorf("minimum liquidation distance can't be negative")
+	}
+	if 100/cfg.Leverage <= cfg.MinLiquidationDistancePct {
+		return fmt.Errorf("at %vx a full position opens within %.2f%% of liquidation, under the %.2f%% minimum", cfg.Leverage, 100/cfg.Leverage, cfg.MinLiquidationDistancePct)
+	}
+	m.cfgMu.Lock()
+	m.Cfg.tokenDerivatives[token] = cfg
+	m.cfgMu.Unlock()
+	if m.updateTraderCfg(token, func(tradeCfg *trader.TradeCfg) { tradeCfg.Derivatives = cfg }) {
+		m.engine.UpdateAllowShort(token, cfg.AllowShort)
+	}
+	return nil
+}
+
+// GetPositions reports each running trader's live position and average entry, with the units its strategy has on.
+func (m *Manager) GetPositions() map[string]trader.PositionSummary {
+	positions := make(map[string]trader.PositionSummary)
+	for symbol, tr := range m.safeGetTraderResources() {
+		position := trader.PositionSummary{Symbol: symbol}
+		if published := tr.Position.Load(); published != nil {
+			position = *published
+		}
+		if state, ok := m.engine.GetPositionState(symbol); ok && state.InPosition {
+			position.Units = state.Units
+			position.UnitCount = len(state.Units)
+		}
+		positions[symbol] = position
+	}
+	return positions
+}
+
+// GetAllPriceHistory is a page of each running token's ticks, at most limit of them from before before.
+func (m *Manager) GetAllPriceHistory(before time.Time, limit int) map[string][]models.Ticker {
 	allPriceHistory := make(map[string][]models.Ticker)
 	traders := m.safeGetTraderResources()
 	for symbol := range traders {
-		allPriceHistory[symbol] = m.exchange.GetPriceHistory(symbol)
+		allPriceHistory[symbol] = m.exchange.GetPriceHistoryPage(symbol, before, limit)
 	}
 	return allPriceHistory
 }
@@ -305,22 +673,147 @@ func (m *Manager) GetAllCandleHistory() map[string][]models.Candle {
 	return allCandleHistory
 }
 
+func (m *Manager) GetTrades(token string, from time.Time, to time.Time) ([]journal.Entry, error) {
+	return m.journal.Query(token, from, to)
+}
+
+// GetTradeSummary reports realized and unrealized P&L per token and strategy from the journal, marking open
+// positions at the last price the exchange has seen.
+func (m *Manager) GetTradeSummary(token string, from time.Time, to time.Time) ([]journal.Summary, error) {
+	// fills before from are replayed for the cost of what the range sells
+	entries, err := m.journal.Query(token, time.Time{}, to)
+	if err != nil {
+		return nil, err
+	}
+	currentPrices := make(map[string]float64)
+	for _, e := range entries {
+		if _, ok := currentPrices[e.Symbol]; ok {
+			continue
+		}
+		if price, ok := m.exchange.GetLastPrice(e.Symbol); ok {
+			currentPrices[e.Symbol] = price
+		}
+	}
+	return journal.Summarize(entries, from, currentPrices), nil
+}
+
+// updateTraderCfg changes the token's running trader's config, if it has one, and sends the trader the result
+// without blocking on it. It holds allocationMu, reallocateFunds writes AllocatedFunds into the same config.
+func (m *Manager) updateTraderCfg(token string, change func(cfg *trader.TradeCfg)) bool {
+	m.allocationMu.Lock()
+	defer m.allocationMu.Unlock()
+	tr, exists := m.safeGetTraderResources()[token]
+	if !exists {
+		return false
+	}
+	change(&tr.Cfg)
+	channel_helper.WriteToChannelAndBufferLatest(tr.Updates, tr.Cfg)
+	return true
+}
+
+// reallocateFunds splits the funds across the running traders by the allocation policy's weights and pushes the
+// new AllocatedFunds to every trader whose share moved.
 func (m *Manager) reallocateFunds() {
-	numTraders := len(m.safeGetTraderResources())
-	if numTraders == 0 {
+	m.allocationMu.Lock()
+	defer m.allocationMu.Unlock()
+	traders := m.safeGetTraderResources()
+	if len(traders) == 0 {
 		return
 	}
-	allocated := m.Cfg.funds / float64(numTraders)
-	for _, tr := range m.safeGetTraderResources() {
-		channel_helper.WriteToChannelAndBufferLatest(tr.Updates, trader.TradeCfg{
-			Symbol:         tr.Cfg.Symbol,
-			AllocatedFunds: allocated,
-			Strategy:       tr.Cfg.Strategy,
-		})
+	symbols := make([]string, 0, len(traders))
+	for symbol := range traders {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	m.tokenWeights = m.allocation.GetWeights(symbols, m.exchange)
+	m.lastRebalance = time.Now()
+	funds := m.getFunds()
+	for _, symbol := range symbols {
+		tr := traders[symbol]
+		allocated := funds * m.tokenWeights[symbol]
+		if tr.Cfg.AllocatedFunds == allocated {
+			continue
+		}
+		log.Printf("allocating %.2f to %q (weight %.4f, %s)", allocated, symbol, m.tokenWeights[symbol], m.allocationPolicy.String())
+		// kept on the resource too, otherwise the next order type or execution update would zero the allocation
+		tr.Cfg.AllocatedFunds = allocated
+		channel_helper.WriteToChannelAndBufferLatest(tr.Updates, tr.Cfg)
+	}
+}
+
+// runRebalancing reallocates on the rebalance interval, so volatility-based weights follow the market between
+// trader starts and stops.
+func (m *Manager) runRebalancing() {
+	for {
+		m.allocationMu.Lock()
+		interval := m.rebalanceInterval
+		m.allocationMu.Unlock()
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-m.rebalanceReset:
+			continue
+		case <-time.After(interval):
+			m.reallocateFunds()
+		}
+	}
+}
+
+// UpdateAllocationPolicy switches how the funds are split and rebalances straight away. Fixed weights are only
+// used by the fixed weights policy, tokens missing from them get nothing.
+func (m *Manager) UpdateAllocationPolicy(policy enum.AllocationPolicy, fixedWeights map[string]float64, rebalanceInterval time.Duration) error {
+	if rebalanceInterval < time.Minute {
+		return fmt.Errorf("rebalance interval must be at least a minute")
+	}
+	if policy == enum.AllocationFixedWeights {
+		total := 0.0
+		for symbol, weight := range fixedWeights {
+			if _, ok := m.tokenToggles.Get(symbol); !ok {
+				return fmt.Errorf("unknown token %q", symbol)
+			}
+			if weight < 0 {
+				return fmt.Errorf("weight for %q can't be negative", symbol)
+			}
+			total += weight
+		}
+		if total <= 0 {
+			return fmt.Errorf("fixed weights need at least one positive weight")
+		}
+	}
+	m.allocationMu.Lock()
+	m.allocation = allocation.NewAllocationPolicy(policy, fixedWeights)
+	m.allocationPolicy = policy
+	m.fixedWeights = fixedWeights
+	m.rebalanceInterval = rebalanceInterval
+	m.allocationMu.Unlock()
+	select {
+	case m.rebalanceReset <- struct{}{}:
+	default:
+	}
+	log.Printf("Allocation policy updated to %s, rebalancing every %v", policy.String(), rebalanceInterval)
+	m.reallocateFunds()
+	return nil
+}
+
+func (m *Manager) GetAllocation() allocation.Status {
+	m.allocationMu.Lock()
+	defer m.allocationMu.Unlock()
+	status := allocation.Status{
+		Policy:           m.allocationPolicy.String(),
+		FixedWeights:     m.fixedWeights,
+		RebalanceMinutes: m.rebalanceInterval.Minutes(),
+		Weights:          make(map[string]float64),
+		AllocatedFunds:   make(map[string]float64),
+		LastRebalance:    m.lastRebalance,
+	}
+	for symbol, tr := range m.safeGetTraderResources() {
+		status.Weights[symbol] = m.tokenWeights[symbol]
+		status.AllocatedFunds[symbol] = tr.Cfg.AllocatedFunds
 	}
+	return status
 }
 
-func (m *Manager) UpdateExchange(exchange enum.Exchange) error {
+func (m *Manager) UpdateExchange(exchangeType enum.Exchange) error {
 	// TODO: verify a graceful shutdown of the exchange before swapping, 
 	// i.e. make sure all front end toggles are switched off before allowing, otherwise throw a 400 back
 	toggles := m.tokenToggles.Snapshot()
@@ -330,19 +823,55 @@ func (m *Manager) UpdateExchange(exchange enum.Exchange) error {
 		}
 	}
 
-	switch exchange {
+	var newExchange exchange.IExchange
+	switch exchangeType {
 	case enum.ExchangeCoinbase:
-		m.exchange = coinbase_exchange.NewCoinbaseExchange(m.ctx, m.apiKey, m.apiSecret)
+		newExchange = coinbase_exchange.NewCoinbaseExchange(m.ctx, m.apiKey, m.apiSecret, m.candleArchive)
 	case enum.ExchangeUniswap:
-		// m.exchange = uniswap_exchange.NewUniswapExchange(m.ctx, m.apiKey, m.apiSecret)
+		uniswapCfg, err := uniswap_exchange.LoadConfigFromEnv()
+		if err != nil {
+			return err
+		}
+		uniswapExchange, err := uniswap_exchange.DialUniswapExchange(m.ctx, uniswapCfg)
+		if err != nil {
+			return err
+		}
+		newExchange = uniswapExchange
 	case enum.ExchangeDeribit:
-		// m.exchange = deribit_exchange.NewDeribitExchange(m.ctx, m.apiKey, m.apiSecret)
+		deribitCfg, err := deribit_exchange.LoadConfigFromEnv()
+		if err != nil {
+			return err
+		}
+		newExchange = deribit_exchange.NewDeribitExchange(m.ctx, deribitCfg)
+	case enum.ExchangePaper:
+		// live Coinbase market data, simulated fills against the allocated funds
+		marketData := coinbase_exchange.NewCoinbaseExchange(m.ctx, m.apiKey, m.apiSecret, m.candleArchive)
+		paperCfg := paper_exchange.DefaultConfig(m.getFunds())
+		// the book only goes short for tokens allowed to, long-only sells stay capped to the wallet
+		paperCfg.AllowShort = m.isShortAllowed
+		newExchange = paper_exchange.NewPaperExchange(m.ctx, marketData, paperCfg)
 	}
-	m.engine = signaler.NewSignalEngine(m.ctx, m.exchange, m.signalEngineUpdates)
-	log.Printf("Exchange updated to %s", exchange.String())
+
+	m.engine.Stop()
+	if m.exchangeCancel != nil {
+		m.exchangeCancel()
+	}
+	m.exchange = newExchange
+	m.engine = signaler.NewSignalEngine(m.ctx, m.exchange, m.signalEngineUpdates, m.store, m.strategyParams)
+	m.startExchangeFeeds()
+	log.Printf("Exchange updated to %s", exchangeType.String())
 	return nil
 }
 
+// startExchangeFeeds opens the market and user data websockets for the current exchange. They get their own
+// context so swapping exchanges can tear them down without touching the rest of the manager.
+func (m *Manager) startExchangeFeeds() {
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.exchangeCancel = cancel
+	m.exchange.StartCoinbaseFeed(ctx, coinbaseMarketDataWsUrl)
+	m.exchange.StartOrderAndPositionValuationWebSocket(ctx, coinbaseUserDataWsUrl)
+}
+
 func (m *Manager) GetTokenToggles() map[string]bool {
 	return m.tokenToggles.Snapshot()
 }
@@ -3,6 +3,8 @@ package manager
 import (
 	"log"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
 	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
@@ -47,6 +49,14 @@ func (m *Manager) WebSocketHandler(w http.ResponseWriter, r *http.Request) {
 
 	done := make(chan struct{})
 
+	// every symbol's feed writes from its own goroutine, and a websocket takes one writer at a time
+	var writeMutex sync.Mutex
+	writeJSON := func(v any) error {
+		writeMutex.Lock()
+		defer writeMutex.Unlock()
+		return conn.WriteJSON(v)
+	}
+
 	// Goroutine to handle incoming subscription changes
 	go func() {
 		defer close(done)
@@ -62,13 +72,13 @@ func (m *Manager) WebSocketHandler(w http.ResponseWriter, r *http.Request) {
 				for _, symbol := range msg.Symbols {
 					log.Printf("[WS] Subscribing to: %+v", msg.Symbols)
 
-					m.exchange.StartNewTokenDataStream(symbol, enum.CandleSize5m)
+					m.exchange.StartNewTokenDataStream(symbol, enum.CandleSize5m, 0)
 					
-					// Send historical data
-					priceHistory := m.exchange.GetPriceHistory(symbol)
+					// Send historical data, the last tick is all the frontend shows
+					priceHistory := m.exchange.GetPriceHistoryPage(symbol, time.Time{}, 1)
 					if len(priceHistory) > 0 {
-						msg := models.GetFrontEndTicker(priceHistory[len(priceHistory)-1])
-						if err := conn.WriteJSON(msg); err != nil {
+						msg := models.GetFrontEndTicker(priceHistory[0])
+						if err := writeJSON(msg); err != nil {
 							log.Printf("[WS] write error: %v", err)
 						}
 					}
@@ -77,7 +87,7 @@ func (m *Manager) WebSocketHandler(w http.ResponseWriter, r *http.Request) {
 					if len(candleHistory.Candles) > 0 {	
 						for _, data := range candleHistory.Candles {
 							msg := data.GetFrontEndCandle()
-							if err := conn.WriteJSON(msg); err != nil {
+							if err := writeJSON(msg); err != nil {
 								log.Printf("[WS] write error: %v", err)
 							}
 						}
@@ -86,8 +96,10 @@ func (m *Manager) WebSocketHandler(w http.ResponseWriter, r *http.Request) {
 					candleCh, candleCleanup := m.exchange.SubscribeToCandle(symbol)
 					priceCh, priceCleanup := m.exchange.SubscribeToTicker(symbol)
 					orderCh, orderCleanup := m.exchange.SubscribeToOrderUpdates(symbol)
+					progressCh, progressCleanup := m.executionHub.Subscribe(symbol)
 
 					go func(candleCh <-chan models.Candle, candleCleanup func(), priceCh <-chan models.Ticker, priceCleanup func(), orderCh <-chan models.OrderUpdate, orderCleanup func()) {
+						defer progressCleanup()
 						defer func() {
 							if candleCh != nil {
 								candleCleanup()
@@ -108,7 +120,7 @@ func (m *Manager) WebSocketHandler(w http.ResponseWriter, r *http.Request) {
 									continue
 								}
 								msg := candle.GetFrontEndCandle()
-								if err := conn.WriteJSON(msg); err != nil {
+								if err := writeJSON(msg); err != nil {
 									log.Printf("[WS] write error: %v", err)
 									return  // Exit on write error
 								}
@@ -119,7 +131,7 @@ func (m *Manager) WebSocketHandler(w http.ResponseWriter, r *http.Request) {
 									continue
 								}
 								msg := models.GetFrontEndTicker(price)
-								if err := conn.WriteJSON(msg); err != nil {
+								if err := writeJSON(msg); err != nil {
 									log.Printf("[WS] write error: %v", err)
 									return
 								}
@@ -129,7 +141,13 @@ func (m *Manager) WebSocketHandler(w http.ResponseWriter, r *http.Request) {
 									orderCh = nil
 									continue
 								}
-								if err := conn.WriteJSON(order); err != nil {
+								if err := writeJSON(order); err != nil {
+									log.Printf("[WS] write error: %v", err)
+									return
+								}
+
+							case progress := <-progressCh:
+								if err := writeJSON(progress); err != nil {
 									log.Printf("[WS] write error: %v", err)
 									return
 								}
@@ -157,4 +175,6 @@ func (m *Manager) WebSocketHandler(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}()
+
+	<-done // returning closes the connection, so hold it open until the frontend goes away
 }
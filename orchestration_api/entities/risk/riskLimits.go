@@ -0,0 +1,27 @@
+package risk
+
+import "fmt"
+
+// Limits are the account-wide risk bounds. A zero limit is disabled.
+type Limits struct {
+	DailyLossLimitUSD  float64 `json:"dailyLossLimitUSD"`  // realized + unrealized loss since 00:00 UTC
+	MaxDrawdownPct     float64 `json:"maxDrawdownPct"`     // peak-to-trough fall in equity
+	MaxPositionUSD     float64 `json:"maxPositionUSD"`     // per token
+	MaxOrdersPerMinute int     `json:"maxOrdersPerMinute"` // across every trader
+}
+
+// DefaultLimits only carries over the daily loss limit the manager was started with. The other limits are off until
+// they are set through the API.
+func DefaultLimits(dailyLossLimitUSD float64) Limits {
+	return Limits{DailyLossLimitUSD: dailyLossLimitUSD}
+}
+
+func (l Limits) Validate() error {
+	if l.DailyLossLimitUSD < 0 || l.MaxPositionUSD < 0 || l.MaxOrdersPerMinute < 0 {
+		return fmt.Errorf("risk limits can't be negative")
+	}
+	if l.MaxDrawdownPct < 0 || l.MaxDrawdownPct >= 100 {
+		return fmt.Errorf("max drawdown must be within [0, 100)")
+	}
+	return nil
+}
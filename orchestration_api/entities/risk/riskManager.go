@@ -0,0 +1,298 @@
+package risk
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/persistence"
+)
+
+const (
+	stateKey                  = "global"
+	profitLossPersistInterval = 30 * time.Second // P&L comes in on every trader's price updates, so only some of it is written through
+)
+
+// Status is the risk manager's persisted state. ProfitLoss is each running trader's total P&L since it started,
+// realized and unrealized; traders that were stopped are folded into the Closed totals so their P&L isn't lost.
+type Status struct {
+	Limits              Limits             `json:"limits"`
+	Halted              bool               `json:"halted"`
+	HaltReason          string             `json:"haltReason,omitempty"`
+	HaltedAt            time.Time          `json:"haltedAt,omitempty"`
+	Day                 string             `json:"day"` // the UTC date DayStartProfitLoss is from
+	DayStartProfitLoss  map[string]float64 `json:"dayStartProfitLoss"`
+	ProfitLoss          map[string]float64 `json:"profitLoss"`
+	ClosedProfitLoss    float64            `json:"closedProfitLoss"`
+	ClosedDayProfitLoss float64            `json:"closedDayProfitLoss"`
+	PeakProfitLoss      float64            `json:"peakProfitLoss"`
+	TotalProfitLoss     float64            `json:"totalProfitLoss"`
+	DailyProfitLoss     float64            `json:"dailyProfitLoss"`
+	DrawdownPct         float64            `json:"drawdownPct"`
+}
+
+// RiskManager enforces the account-wide limits. The manager feeds it P&L and trips the kill switch on a breach,
+// traders ask it before every order they place to track their target. Exits the exchange makes on a trader's
+// behalf (brackets) and the flattening on shutdown don't go through it.
+type RiskManager struct {
+	mu          sync.Mutex
+	status      Status
+	orderTimes  []time.Time
+	store       *persistence.StateStore
+	lastPersist time.Time
+}
+
+// NewRiskManager picks up the persisted state, so a halt survives a restart. The limits passed in only apply the
+// first time.
+func NewRiskManager(limits Limits, store *persistence.StateStore) *RiskManager {
+	r := &RiskManager{
+		status: Status{Limits: limits, DayStartProfitLoss: make(map[string]float64), ProfitLoss: make(map[string]float64)},
+		store:  store,
+	}
+	var persisted Status
+	found, err := store.Get(persistence.RiskBucket, stateKey, &persisted)
+	if err != nil {
+		log.Printf("[Risk] failed to load persisted state: %v", err)
+	}
+	if found {
+		if persisted.DayStartProfitLoss == nil {
+			persisted.DayStartProfitLoss = make(map[string]float64)
+		}
+		if persisted.ProfitLoss == nil {
+			persisted.ProfitLoss = make(map[string]float64)
+		}
+		r.status = persisted
+		if r.status.Halted {
+			log.Printf("[Risk] trading is halted since %s: %s", r.status.HaltedAt.Format(time.RFC3339), r.status.HaltReason)
+		}
+	}
+	return r
+}
+
+// UpdateProfitLoss records a trader's total P&L and checks the daily loss and drawdown limits against the whole
+// account. It returns the reason when a limit is breached while trading isn't halted yet. The P&L is written
+// through at most every profitLossPersistInterval, but a new day or a new trader's starting point straight away.
+func (r *RiskManager) UpdateProfitLoss(symbol string, profitLoss float64, funds float64) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	mustPersist := r.rollDay()
+	if _, ok := r.status.DayStartProfitLoss[symbol]; !ok {
+		r.status.DayStartProfitLoss[symbol] = profitLoss
+		mustPersist = true
+	}
+	r.status.ProfitLoss[symbol] = profitLoss
+	r.updateDerived(funds)
+	if mustPersist || time.Since(r.lastPersist) >= profitLossPersistInterval {
+		r.persist()
+	}
+
+	if r.status.Halted {
+		return "", false
+	}
+	limits := r.status.Limits
+	if limits.DailyLossLimitUSD > 0 && -r.status.DailyProfitLoss >= limits.DailyLossLimitUSD {
+		return fmt.Sprintf("daily loss of %.2f reached the %.2f limit", -r.status.DailyProfitLoss, limits.DailyLossLimitUSD), true
+	}
+	if limits.MaxDrawdownPct > 0 && r.status.DrawdownPct >= limits.MaxDrawdownPct {
+		return fmt.Sprintf("drawdown of %.2f%% reached the %.2f%% limit", r.status.DrawdownPct, limits.MaxDrawdownPct), true
+	}
+	return "", false
+}
+
+// RemoveToken folds a stopped trader's P&L into the closed totals, its next trader starts counting from zero.
+func (r *RiskManager) RemoveToken(symbol string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	profitLoss, ok := r.status.ProfitLoss[symbol]
+	if !ok {
+		return
+	}
+	r.rollDay()
+	r.status.ClosedProfitLoss += profitLoss
+	r.status.ClosedDayProfitLoss += profitLoss - r.status.DayStartProfitLoss[symbol]
+	delete(r.status.ProfitLoss, symbol)
+	delete(r.status.DayStartProfitLoss, symbol)
+	r.persist()
+}
+
+// CheckOrder clears an order a trader is about to place and returns the amount it may place, cut down to stay
+// under the max position. Whatever part of an order only takes the position back towards flat always passes the
+// halt and position checks, it only reduces risk; that is all of a sell for a long-only trader, and the part of a
+// buy that covers a short.
+func (r *RiskManager) CheckOrder(symbol string, isBuy bool, amountUSD float64, positionUSD float64) (float64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	limits := r.status.Limits
+	direction := -1.0
+	if isBuy {
+		direction = 1
+	}
+	flatRoom := max(-direction*positionUSD, 0)
+	if amountUSD > flatRoom {
+		if r.status.Halted {
+			if flatRoom <= 0 {
+				return 0, fmt.Errorf("trading is halted: %s", r.status.HaltReason)
+			}
+			amountUSD = flatRoom
+		} else if limits.MaxPositionUSD > 0 {
+			room := flatRoom + limits.MaxPositionUSD - max(direction*positionUSD, 0)
+			if room <= 0 {
+				return 0, fmt.Errorf("%s position of %.2f is at the %.2f limit", symbol, positionUSD, limits.MaxPositionUSD)
+			}
+			amountUSD = min(amountUSD, room)
+		}
+	}
+	if limits.MaxOrdersPerMinute > 0 {
+		r.dropOldOrderTimes()
+		if len(r.orderTimes) >= limits.MaxOrdersPerMinute {
+			return 0, fmt.Errorf("%d orders in the last minute, the limit is %d", len(r.orderTimes), limits.MaxOrdersPerMinute)
+		}
+	}
+	return amountUSD, nil
+}
+
+// RecordOrder counts an order the exchange accepted towards the order rate limit. Orders that CheckOrder cleared
+// but the exchange refused don't use up the limit.
+func (r *RiskManager) RecordOrder() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.status.Limits.MaxOrdersPerMinute <= 0 {
+		return
+	}
+	r.dropOldOrderTimes()
+	r.orderTimes = append(r.orderTimes, time.Now())
+}
+
+// dropOldOrderTimes forgets orders placed more than a minute ago. Callers hold r.mu.
+func (r *RiskManager) dropOldOrderTimes() {
+	cutoff := time.Now().Add(-time.Minute)
+	kept := r.orderTimes[:0]
+	for _, t := range r.orderTimes {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	r.orderTimes = kept
+}
+
+// Halt blocks new exposure, long or short, and trader starts until Rearm. It reports false when trading was
+// already halted, so the first reason is the one kept.
+func (r *RiskManager) Halt(reason string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.status.Halted {
+		return false
+	}
+	r.status.Halted = true
+	r.status.HaltReason = reason
+	r.status.HaltedAt = time.Now()
+	r.persist()
+	log.Printf("[Risk] trading halted: %s", reason)
+	return true
+}
+
+// Rearm lifts a halt. The drawdown peak and the daily window restart from the current P&L, otherwise the same
+// breach would trip the switch again on the next update.
+func (r *RiskManager) Rearm() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status.Halted = false
+	r.status.HaltReason = ""
+	r.status.HaltedAt = time.Time{}
+	r.status.PeakProfitLoss = r.status.TotalProfitLoss
+	r.status.DrawdownPct = 0
+	r.status.ClosedDayProfitLoss = 0
+	r.status.DailyProfitLoss = 0
+	for symbol, profitLoss := range r.status.ProfitLoss {
+		r.status.DayStartProfitLoss[symbol] = profitLoss
+	}
+	r.persist()
+	log.Printf("[Risk] re-armed")
+}
+
+func (r *RiskManager) IsHalted() (bool, string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status.Halted, r.status.HaltReason
+}
+
+func (r *RiskManager) UpdateLimits(limits Limits) error {
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status.Limits = limits
+	r.persist()
+	return nil
+}
+
+func (r *RiskManager) GetLimits() Limits {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status.Limits
+}
+
+func (r *RiskManager) GetStatus() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	status := r.status
+	status.DayStartProfitLoss = make(map[string]float64)
+	status.ProfitLoss = make(map[string]float64)
+	for symbol, v := range r.status.DayStartProfitLoss {
+		status.DayStartProfitLoss[symbol] = v
+	}
+	for symbol, v := range r.status.ProfitLoss {
+		status.ProfitLoss[symbol] = v
+	}
+	return status
+}
+
+// rollDay starts a new daily loss window at 00:00 UTC from every trader's current P&L, and reports whether it did.
+// Callers hold r.mu.
+func (r *RiskManager) rollDay() bool {
+	today := time.Now().UTC().Format(time.DateOnly)
+	if r.status.Day == today {
+		return false
+	}
+	r.status.Day = today
+	r.status.ClosedDayProfitLoss = 0
+	for symbol, profitLoss := range r.status.ProfitLoss {
+		r.status.DayStartProfitLoss[symbol] = profitLoss
+	}
+	return true
+}
+
+// updateDerived recomputes the daily P&L and the drawdown. The drawdown is measured on P&L rather than on
+// balances, so moving allocated funds in or out doesn't read as a loss. Callers hold r.mu.
+func (r *RiskManager) updateDerived(funds float64) {
+	daily := r.status.ClosedDayProfitLoss
+	total := r.status.ClosedProfitLoss
+	for symbol, profitLoss := range r.status.ProfitLoss {
+		daily += profitLoss - r.status.DayStartProfitLoss[symbol]
+		total += profitLoss
+	}
+	r.status.DailyProfitLoss = daily
+	r.status.TotalProfitLoss = total
+
+	r.status.PeakProfitLoss = max(r.status.PeakProfitLoss, total)
+	if peakEquity := funds + r.status.PeakProfitLoss; peakEquity > 0 {
+		r.status.DrawdownPct = (r.status.PeakProfitLoss - total) / peakEquity * 100.0
+	}
+}
+
+// Flush writes through whatever P&L UpdateProfitLoss has held back, for when the traders stop.
+func (r *RiskManager) Flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.persist()
+}
+
+// persist writes the state through so a halt survives a restart. Callers hold r.mu.
+func (r *RiskManager) persist() {
+	r.lastPersist = time.Now()
+	if err := r.store.Put(persistence.RiskBucket, stateKey, r.status); err != nil {
+		log.Printf("[Risk] failed to persist state: %v", err)
+	}
+}
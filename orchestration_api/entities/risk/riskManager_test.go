@@ -0,0 +1,81 @@
+package risk
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/persistence"
+)
+
+func newTestRiskManager(t *testing.T, limits Limits) *RiskManager {
+	t.Helper()
+	store, err := persistence.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return NewRiskManager(limits, store)
+}
+
+func TestOrderRateLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    int
+		checks   int // orders cleared before the last check
+		recorded int // of those, how many the exchange accepted
+		wantErr  bool
+	}{
+		{name: "off", limit: 0, checks: 5, recorded: 5},
+		{name: "under the limit", limit: 3, checks: 2, recorded: 2},
+		{name: "at the limit", limit: 3, checks: 3, recorded: 3, wantErr: true},
+		{name: "refused orders don't count", limit: 3, checks: 5, recorded: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRiskManager(t, Limits{MaxOrdersPerMinute: tt.limit})
+			for i := 0; i < tt.checks; i++ {
+				if _, err := r.CheckOrder("ETH-USD", true, 100, 0); err != nil {
+					t.Fatalf("check %d: %v", i, err)
+				}
+				if i < tt.recorded {
+					r.RecordOrder()
+				}
+			}
+			if _, err := r.CheckOrder("ETH-USD", true, 100, 0); (err != nil) != tt.wantErr {
+				t.Errorf("last check: %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProfitLossPersistence(t *testing.T) {
+	r := newTestRiskManager(t, Limits{})
+	persisted := func() Status {
+		var s Status
+		if _, err := r.store.Get(persistence.RiskBucket, stateKey, &s); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	steps := []struct {
+		name   string
+		update func()
+		want   map[string]float64 // persisted P&L by symbol
+	}{
+		{name: "a new trader is written through", update: func() { r.UpdateProfitLoss("ETH-USD", 10, 1000) }, want: map[string]float64{"ETH-USD": 10}},
+		{name: "its next update is held back", update: func() { r.UpdateProfitLoss("ETH-USD", 20, 1000) }, want: map[string]float64{"ETH-USD": 10}},
+		{name: "another new trader writes both", update: func() { r.UpdateProfitLoss("BTC-USD", 5, 1000) }, want: map[string]float64{"ETH-USD": 20, "BTC-USD": 5}},
+		{name: "held back again", update: func() { r.UpdateProfitLoss("BTC-USD", 7, 1000) }, want: map[string]float64{"ETH-USD": 20, "BTC-USD": 5}},
+		{name: "flushed", update: r.Flush, want: map[string]float64{"ETH-USD": 20, "BTC-USD": 7}},
+	}
+	for _, step := range steps {
+		step.update()
+		got := persisted().ProfitLoss
+		for symbol, want := range step.want {
+			if got[symbol] != want {
+				t.Errorf("%s: persisted %v for %s, want %v", step.name, got[symbol], symbol, want)
+			}
+		}
+	}
+}
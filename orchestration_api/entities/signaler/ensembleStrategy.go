@@ -0,0 +1,311 @@
+package signaler
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	helper "github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler/strategy_helper"
+	enum "github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	exchange "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange"
+	models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+// EnsembleParams picks the ensemble's members and how their signals combine. Vote is one of
+//
+//	majority:  more than half the members agree
+//	weighted:  the Weights of the members for, less those against, reach Threshold of the total (equal weights
+//	           when Weights is empty)
+//	unanimous: every member agrees
+//
+// A member's entry or exit keeps counting for VoteWindow candles, members rarely turn on the same candle.
+type EnsembleParams struct {
+	Members     []string  `json:"members"` // strategy names, e.g. Supertrend
+	Vote        string    `json:"vote"`
+	Weights     []float64 `json:"weights"` // one per member, in the same order
+	Threshold   float64   `json:"threshold"`
+	VoteWindow  int       `json:"voteWindow"`
+	MergeLevels string    `json:"mergeLevels"` // "tightest" or "average" of the agreeing members' take profits and stops
+}
+
+func (p EnsembleParams) Validate() error {
+	if len(p.Members) < 2 {
+		return fmt.Errorf("an ensemble needs at least two members")
+	}
+	seen := make(map[enum.Strategy]bool)
+	for _, name := range p.Members {
+		strategy, ok := enum.LookupStrategy(name)
+		if !ok {
+			return fmt.Errorf("unknown member strategy %s", name)
+		}
+		if strategy == enum.Ensemble {
+			return fmt.Errorf("an ensemble can't be a member of itself")
+		}
+		if seen[strategy] {
+			return fmt.Errorf("%s is a member twice", name)
+		}
+		seen[strategy] = true
+	}
+	if !slices.Contains([]string{"majority", "weighted", "unanimous"}, p.Vote) {
+		return fmt.Errorf("vote must be one of majority, weighted or unanimous")
+	}
+	if len(p.Weights) > 0 && len(p.Weights) != len(p.Members) {
+		return fmt.Errorf("weights must have one entry per member")
+	}
+	for _, w := range p.Weights {
+		if w <= 0 {
+			return fmt.Errorf("weights must be positive")
+		}
+	}
+	// only the weighted vote reads the threshold
+	if p.Vote == "weighted" && (p.Threshold <= 0 || p.Threshold > 1) {
+		return fmt.Errorf("threshold must be within (0, 1]")
+	}
+	if p.VoteWindow < 1 {
+		return fmt.Errorf("voteWindow must be at least one candle")
+	}
+	if p.MergeLevels != "tightest" && p.MergeLevels != "average" {
+		return fmt.Errorf("mergeLevels must be tightest or average")
+	}
+	return nil
+}
+
+func (p EnsembleParams) GetMembers() []enum.Strategy {
+	members := make([]enum.Strategy, 0, len(p.Members))
+	for _, name := range p.Members {
+		if strategy, ok := enum.LookupStrategy(name); ok {
+			members = append(members, strategy)
+		}
+	}
+	return members
+}
+
+// ensembleVote is a member's latest entry or exit and the candle it came on.
+type ensembleVote struct {
+	signal models.Signal
+	candle time.Time
+}
+
+// EnsembleStrategy trades on what its members agree on. The ensemble holds the position; every signal it delivers
+// is confirmed to the members as well, so they all judge their exits against the same position and merged levels.
+type EnsembleStrategy struct {
+	*helper.PositionHolder
+	EnsembleParams
+	members []Strategy
+	votesMu sync.Mutex
+	votes   map[string][]*ensembleVote // symbol -> by member, nil until the member gives an entry or exit
+}
+
+// newEnsembleStrategy builds the members with memberParams, so they run with what they would alone.
+func newEnsembleStrategy(params EnsembleParams, memberParams func(enum.Strategy) StrategyParams) *EnsembleStrategy {
+	s := &EnsembleStrategy{
+		PositionHolder: helper.NewPositionHolder(),
+		EnsembleParams: params,
+		votes:          make(map[string][]*ensembleVote),
+	}
+	for _, member := range params.GetMembers() {
+		s.members = append(s.members, NewStrategyWithParams(member, memberParams(member)))
+	}
+	return s
+}
+
+func (s *EnsembleStrategy) GetWarmUpCandles() int {
+	warmUp := 0
+	for _, member := range s.members {
+		warmUp = max(warmUp, member.GetWarmUpCandles())
+	}
+	return warmUp
+}
+
+func (s *EnsembleStrategy) CalculateSignal(symbol string, exchange exchange.IExchange) models.Signal {
+	hold := models.Signal{Symbol: symbol, Type: enum.SignalHold, Percent: 0, Time: time.Now()}
+	hist := exchange.GetCandleHistory(symbol)
+	if len(hist.Candles) == 0 {
+		return hold
+	}
+	candle := hist.Candles[len(hist.Candles)-1]
+
+	// each member holds through its own warm-up and adds to its position by its own rules, as it would alone
+	signals := make([]models.Signal, len(s.members))
+	for i, member := range s.members {
+		signals[i] = CalculateSignal(member, symbol, exchange)
+	}
+	s.votesMu.Lock()
+	if s.votes[symbol] == nil {
+		s.votes[symbol] = make([]*ensembleVote, len(s.members))
+	}
+	votes := s.votes[symbol]
+	for i, signal := range signals {
+		if signal.Type != enum.SignalHold && !signal.Trim {
+			votes[i] = &ensembleVote{signal: signal, candle: candle.Start}
+		}
+	}
+	live := s.getLiveVotes(votes, hist)
+	s.votesMu.Unlock()
+
+	state, ok := s.State[symbol]
+	if ok && state.InPosition {
+		entry, exit := enum.SignalBuy, enum.SignalSell
+		if state.Side == enum.SignalShort {
+			entry, exit = enum.SignalShort, enum.SignalCover
+		}
+		exitSignal := models.Signal{Symbol: symbol, Type: exit, Percent: 100, Time: time.Now(), Price: candle.Close}
+		// the merged levels are the ensemble's own, reaching one exits whatever the members say
+		if isLevelReached(state, candle.Close) {
+			return exitSignal
+		}
+		if s.isAgreed(live, func(t enum.SignalType) bool { return t == exit || (isEntry(t) && t != entry) }, func(t enum.SignalType) bool { return t == entry }) {
+			return exitSignal
+		}
+		return hold
+	}
+
+	for _, entry := range []enum.SignalType{enum.SignalBuy, enum.SignalShort} {
+		opposite := enum.SignalShort
+		if entry == enum.SignalShort {
+			opposite = enum.SignalBuy
+		}
+		if s.isAgreed(live, func(t enum.SignalType) bool { return t == entry }, func(t enum.SignalType) bool { return t == opposite }) {
+			return s.getMergedEntry(symbol, entry, live, candle.Close)
+		}
+	}
+	return hold
+}
+
+// ConfirmSignalDelivered moves the members' positions along with the ensemble's. Any entry or exit starts the
+// vote over, the next one has to be agreed afresh.
+func (s *EnsembleStrategy) ConfirmSignalDelivered(symbol string, signal models.Signal) {
+	s.PositionHolder.ConfirmSignalDelivered(symbol, signal)
+	for _, member := range s.members {
+		member.ConfirmSignalDelivered(symbol, signal)
+	}
+	if signal.Type != enum.SignalHold {
+		s.votesMu.Lock()
+		delete(s.votes, symbol)
+		s.votesMu.Unlock()
+	}
+}
+
+func (s *EnsembleStrategy) UpdateTrailingStop(symbol string, ticker models.Ticker) {
+	s.PositionHolder.UpdateTrailingStop(symbol, ticker)
+	for _, member := range s.members {
+		member.UpdateTrailingStop(symbol, ticker)
+	}
+}
+
+func (s *EnsembleStrategy) RestorePositionState(symbol string, state helper.PositionState) {
+	s.PositionHolder.RestorePositionState(symbol, state)
+	for _, member := range s.members {
+		member.RestorePositionState(symbol, state)
+	}
+}
+
+// getLiveVotes drops the votes given more than VoteWindow candles ago, nil for a member without a vote.
+func (s *EnsembleStrategy) getLiveVotes(votes []*ensembleVote, hist models.CandleHistory) []*models.Signal {
+	live := make([]*models.Signal, len(votes))
+	for i, vote := range votes {
+		if vote == nil {
+			continue
+		}
+		age := 0
+		for j := len(hist.Candles) - 1; j >= 0 && hist.Candles[j].Start.After(vote.candle); j-- {
+			age++
+		}
+		if age < s.VoteWindow {
+			live[i] = &vote.signal
+		}
+	}
+	return live
+}
+
+// isAgreed runs the vote over the members' live signals, isFor and isAgainst sorting them by type.
+func (s *EnsembleStrategy) isAgreed(live []*models.Signal, isFor func(enum.SignalType) bool, isAgainst func(enum.SignalType) bool) bool {
+	forCount := 0
+	total, net := 0.0, 0.0
+	for i, signal := range live {
+		weight := 1.0
+		if len(s.Weights) == len(live) {
+			weight = s.Weights[i]
+		}
+		total += weight
+		if signal == nil {
+			continue
+		}
+		if isFor(signal.Type) {
+			forCount++
+			net += weight
+		} else if isAgainst(signal.Type) {
+			net -= weight
+		}
+	}
+	switch s.Vote {
+	case "unanimous":
+		return forCount == len(live)
+	case "weighted":
+		return total > 0 && net/total >= s.Threshold
+	default:
+		return 2*forCount > len(live)
+	}
+}
+
+// getMergedEntry combines the entries of the members that agreed: their average size and, per level, the tightest
+// or the average of those that set it.
+func (s *EnsembleStrategy) getMergedEntry(symbol string, entry enum.SignalType, live []*models.Signal, price float64) models.Signal {
+	agreeing := make([]models.Signal, 0, len(live))
+	percent := 0.0
+	for _, signal := range live {
+		if signal != nil && signal.Type == entry {
+			agreeing = append(agreeing, *signal)
+			percent += signal.Percent
+		}
+	}
+	short := entry == enum.SignalShort
+	return models.Signal{
+		Symbol:       symbol,
+		Type:         entry,
+		Percent:      percent / float64(len(agreeing)),
+		Time:         time.Now(),
+		Price:        price,
+		TakeProfit:   s.mergeLevel(agreeing, func(sig models.Signal) float64 { return sig.TakeProfit }, !short),
+		StopLoss:     s.mergeLevel(agreeing, func(sig models.Signal) float64 { return sig.StopLoss }, short),
+		TrailingStop: s.mergeLevel(agreeing, func(sig models.Signal) float64 { return sig.TrailingStop }, short),
+	}
+}
+
+// mergeLevel merges one level over the signals that set it. The tightest is the lowest when lowest is true, a
+// long's take profit or a short's stops, and the highest otherwise.
+func (s *EnsembleStrategy) mergeLevel(signals []models.Signal, level func(models.Signal) float64, lowest bool) float64 {
+	merged, sum, count := 0.0, 0.0, 0
+	for _, signal := range signals {
+		v := level(signal)
+		if v <= 0 {
+			continue
+		}
+		if count == 0 || (lowest && v < merged) || (!lowest && v > merged) {
+			merged = v
+		}
+		sum += v
+		count++
+	}
+	if s.MergeLevels == "average" && count > 0 {
+		return sum / float64(count)
+	}
+	return merged
+}
+
+// isLevelReached reports whether price has reached the position's take profit or either of its stops.
+func isLevelReached(state *helper.PositionState, price float64) bool {
+	if state.Side == enum.SignalShort {
+		return (state.TakeProfit > 0 && price <= state.TakeProfit) ||
+			(state.StopLoss > 0 && price >= state.StopLoss) ||
+			(state.TrailingStop > 0 && price >= state.TrailingStop)
+	}
+	return (state.TakeProfit > 0 && price >= state.TakeProfit) ||
+		(state.StopLoss > 0 && price <= state.StopLoss) ||
+		(state.TrailingStop > 0 && price <= state.TrailingStop)
+}
+
+func isEntry(signalType enum.SignalType) bool {
+	return signalType == enum.SignalBuy || signalType == enum.SignalShort
+}
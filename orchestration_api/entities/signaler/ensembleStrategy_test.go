@@ -0,0 +1,183 @@
+package signaler
+
+import (
+	"testing"
+	"time"
+
+	strategies "github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler/strategies"
+	helper "github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler/strategy_helper"
+	enum "github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+func TestEnsembleMemberParams(t *testing.T) {
+	tuned := strategies.SupertrendParams{AtrPeriod: 40, Factor: 2.5, UseVolFilt: true, VolLen: 16, TsAtrMult: 1.5, TpAtrMult: 4}
+	params := EnsembleParams{Members: []string{"Supertrend", "HeikenAshi"}, Vote: "majority", Threshold: 0.5, VoteWindow: 3, MergeLevels: "tightest"}
+	tests := []struct {
+		name         string
+		memberParams func(enum.Strategy) StrategyParams
+		want         strategies.SupertrendParams
+	}{
+		{name: "defaults", want: GetDefaultParams(enum.Supertrend).(strategies.SupertrendParams)},
+		{
+			name: "looked up",
+			memberParams: func(member enum.Strategy) StrategyParams {
+				if member == enum.Supertrend {
+					return tuned
+				}
+				return GetDefaultParams(member)
+			},
+			want: tuned,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStrategyWithMemberParams(enum.Ensemble, params, tt.memberParams).(*EnsembleStrategy)
+			got := s.members[0].(*strategies.SupertrendStrategy).SupertrendParams
+			if got != tt.want {
+				t.Errorf("Supertrend member runs with %+v, want %+v", got, tt.want)
+			}
+			if want := max(2*tt.want.AtrPeriod, tt.want.VolLen, s.members[1].GetWarmUpCandles()); s.GetWarmUpCandles() != want {
+				t.Errorf("warm-up %d, want %d", s.GetWarmUpCandles(), want)
+			}
+		})
+	}
+}
+
+func TestEnsembleParamsValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		vote      string
+		threshold float64
+		wantErr   bool
+	}{
+		{name: "majority without a threshold", vote: "majority"},
+		{name: "unanimous without a threshold", vote: "unanimous"},
+		{name: "weighted", vote: "weighted", threshold: 0.6},
+		{name: "weighted without a threshold", vote: "weighted", wantErr: true},
+		{name: "weighted over 1", vote: "weighted", threshold: 1.5, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := EnsembleParams{Members: []string{"Supertrend", "HeikenAshi"}, Vote: tt.vote, Threshold: tt.threshold, VoteWindow: 3, MergeLevels: "tightest"}
+			if err := params.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("error %v, want an error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// The members' entries are voted on for a long, a vote counting until it's VoteWindow candles old.
+func TestEnsembleIsAgreed(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	hist := models.CandleHistory{Candles: make([]models.Candle, 10)}
+	for i := range hist.Candles {
+		hist.Candles[i].Start = start.Add(time.Duration(i) * time.Minute)
+	}
+	// voteAt is a vote given age candles before the newest
+	voteAt := func(signalType enum.SignalType, age int) *ensembleVote {
+		return &ensembleVote{signal: models.Signal{Type: signalType}, candle: hist.Candles[len(hist.Candles)-1-age].Start}
+	}
+	buy, short := enum.SignalBuy, enum.SignalShort
+
+	tests := []struct {
+		name      string
+		vote      string
+		weights   []float64
+		threshold float64
+		votes     []*ensembleVote
+		want      bool
+	}{
+		{name: "majority", vote: "majority", votes: []*ensembleVote{voteAt(buy, 0), voteAt(buy, 1), nil}, want: true},
+		{name: "majority split", vote: "majority", votes: []*ensembleVote{voteAt(buy, 0), voteAt(short, 0), nil}},
+		{name: "majority still in the window", vote: "majority", votes: []*ensembleVote{voteAt(buy, 0), voteAt(buy, 2), voteAt(short, 0)}, want: true},
+		{name: "majority with a vote aged out", vote: "majority", votes: []*ensembleVote{voteAt(buy, 0), voteAt(buy, 3), nil}},
+		{name: "weighted under the threshold", vote: "weighted", threshold: 0.6, votes: []*ensembleVote{voteAt(buy, 0), voteAt(buy, 0), voteAt(short, 0)}},
+		{name: "weighted over the threshold", vote: "weighted", threshold: 0.3, votes: []*ensembleVote{voteAt(buy, 0), voteAt(buy, 0), voteAt(short, 0)}, want: true},
+		{name: "weighted by one heavy member", vote: "weighted", weights: []float64{3, 1, 1}, threshold: 0.5, votes: []*ensembleVote{voteAt(buy, 0), nil, nil}, want: true},
+		{name: "weighted against a heavy member", vote: "weighted", weights: []float64{1, 1, 3}, threshold: 0.5, votes: []*ensembleVote{voteAt(buy, 0), voteAt(buy, 0), voteAt(short, 0)}},
+		{name: "weighted heavy member aged out", vote: "weighted", weights: []float64{3, 1, 1}, threshold: 0.5, votes: []*ensembleVote{voteAt(buy, 5), voteAt(buy, 0), nil}},
+		{name: "unanimous", vote: "unanimous", votes: []*ensembleVote{voteAt(buy, 0), voteAt(buy, 1), voteAt(buy, 2)}, want: true},
+		{name: "unanimous with one missing", vote: "unanimous", votes: []*ensembleVote{voteAt(buy, 0), voteAt(buy, 1), nil}},
+		{name: "unanimous with a vote aged out", vote: "unanimous", votes: []*ensembleVote{voteAt(buy, 0), voteAt(buy, 1), voteAt(buy, 3)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &EnsembleStrategy{EnsembleParams: EnsembleParams{Vote: tt.vote, Weights: tt.weights, Threshold: tt.threshold, VoteWindow: 3}}
+			live := s.getLiveVotes(tt.votes, hist)
+			got := s.isAgreed(live, func(st enum.SignalType) bool { return st == buy }, func(st enum.SignalType) bool { return st == short })
+			if got != tt.want {
+				t.Errorf("agreed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Only the members that agreed on the entry make its levels, each the tightest or the average of those that set it.
+func TestEnsembleMergedEntry(t *testing.T) {
+	long := []*models.Signal{
+		{Type: enum.SignalBuy, Percent: 50, TakeProfit: 110, StopLoss: 95},
+		{Type: enum.SignalBuy, Percent: 100, TakeProfit: 120, StopLoss: 90, TrailingStop: 97},
+		{Type: enum.SignalShort, Percent: 100, TakeProfit: 80, StopLoss: 105, TrailingStop: 104},
+		nil,
+	}
+	short := []*models.Signal{
+		{Type: enum.SignalShort, Percent: 100, TakeProfit: 90, StopLoss: 105, TrailingStop: 104},
+		{Type: enum.SignalShort, Percent: 50, TakeProfit: 80, StopLoss: 110},
+		{Type: enum.SignalBuy, Percent: 100, TakeProfit: 120, StopLoss: 90, TrailingStop: 97},
+	}
+	tests := []struct {
+		name  string
+		merge string
+		entry enum.SignalType
+		live  []*models.Signal
+		want  models.Signal
+	}{
+		{name: "long tightest", merge: "tightest", entry: enum.SignalBuy, live: long, want: models.Signal{Percent: 75, TakeProfit: 110, StopLoss: 95, TrailingStop: 97}},
+		{name: "long average", merge: "average", entry: enum.SignalBuy, live: long, want: models.Signal{Percent: 75, TakeProfit: 115, StopLoss: 92.5, TrailingStop: 97}},
+		{name: "short tightest", merge: "tightest", entry: enum.SignalShort, live: short, want: models.Signal{Percent: 75, TakeProfit: 90, StopLoss: 105, TrailingStop: 104}},
+		{name: "short average", merge: "average", entry: enum.SignalShort, live: short, want: models.Signal{Percent: 75, TakeProfit: 85, StopLoss: 107.5, TrailingStop: 104}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &EnsembleStrategy{EnsembleParams: EnsembleParams{MergeLevels: tt.merge}}
+			got := s.getMergedEntry("ETH-USD", tt.entry, tt.live, 100)
+			if got.Type != tt.entry || got.Price != 100 {
+				t.Errorf("%s at %v, want %s at 100", got.Type, got.Price, tt.entry)
+			}
+			if got.Percent != tt.want.Percent || got.TakeProfit != tt.want.TakeProfit || got.StopLoss != tt.want.StopLoss || got.TrailingStop != tt.want.TrailingStop {
+				t.Errorf("percent %v, take profit %v, stop %v, trailing %v, want %v, %v, %v, %v", got.Percent, got.TakeProfit, got.StopLoss, got.TrailingStop,
+					tt.want.Percent, tt.want.TakeProfit, tt.want.StopLoss, tt.want.TrailingStop)
+			}
+		})
+	}
+}
+
+func TestIsLevelReached(t *testing.T) {
+	long := helper.PositionState{InPosition: true, Side: enum.SignalBuy, TakeProfit: 110, StopLoss: 95, TrailingStop: 97}
+	short := helper.PositionState{InPosition: true, Side: enum.SignalShort, TakeProfit: 90, StopLoss: 105, TrailingStop: 103}
+	tests := []struct {
+		name  string
+		state helper.PositionState
+		price float64
+		want  bool
+	}{
+		{name: "long take profit", state: long, price: 110, want: true},
+		{name: "long in between", state: long, price: 100},
+		{name: "long trailing stop", state: long, price: 97, want: true},
+		{name: "long stop loss", state: long, price: 94, want: true},
+		{name: "long without levels", state: helper.PositionState{InPosition: true, Side: enum.SignalBuy}, price: 1},
+		{name: "short take profit", state: short, price: 90, want: true},
+		{name: "short in between", state: short, price: 100},
+		{name: "short trailing stop", state: short, price: 103, want: true},
+		{name: "short stop loss", state: short, price: 106, want: true},
+		{name: "short without a trailing stop", state: helper.PositionState{InPosition: true, Side: enum.SignalShort, StopLoss: 105}, price: 104},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isLevelReached(&tt.state, tt.price); got != tt.want {
+				t.Errorf("reached %v at %v, want %v", got, tt.price, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,376 @@
+package signaler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	helper "github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler/strategy_helper"
+	plugin "github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler/strategy_plugin"
+	enum "github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	exchange "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange"
+	models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// PluginParams point the Plugin strategy at a strategy served over gRPC, see strategy_plugin/strategy.proto. Params
+// are the plugin's own and go to it as they are, a JSON object on every call.
+type PluginParams struct {
+	Address          string         `json:"address"`  // host:port, plaintext, plugins run next to us
+	Strategy         string         `json:"strategy"` // the plugin's name for it, one plugin can serve several
+	Params           map[string]any `json:"params"`
+	TimeoutMs        int            `json:"timeoutMs"` // per signal check, a plugin that takes longer holds
+	HealthIntervalMs int            `json:"healthIntervalMs"`
+	WarmUpCandles    int            `json:"warmUpCandles"`
+	HistoryCandles   int            `json:"historyCandles"` // the latest candles each check carries
+}
+
+func (p PluginParams) Validate() error {
+	if p.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+	if p.Strategy == "" {
+		return fmt.Errorf("strategy is required")
+	}
+	if p.TimeoutMs < 1 {
+		return fmt.Errorf("timeoutMs must be at least 1")
+	}
+	if p.HealthIntervalMs < 100 {
+		return fmt.Errorf("healthIntervalMs must be at least 100")
+	}
+	if p.WarmUpCandles < 1 {
+		return fmt.Errorf("warmUpCandles must be at least 1")
+	}
+	if p.HistoryCandles < p.WarmUpCandles {
+		return fmt.Errorf("historyCandles must cover warmUpCandles")
+	}
+	if _, err := json.Marshal(p.Params); err != nil {
+		return fmt.Errorf("params: %w", err)
+	}
+	return nil
+}
+
+// pluginConn is the connection to the plugin at one address, shared by every strategy pointed at it and kept
+// until ClosePlugins, since strategies are swapped out without being closed. It watches the plugin's health and
+// carries the tickers of all of them on one stream.
+type pluginConn struct {
+	address string
+	conn    *grpc.ClientConn
+	client  plugin.StrategyPluginClient
+	health  healthpb.HealthClient
+	healthy atomic.Bool
+	tickers chan *plugin.Ticker
+	done    chan struct{}
+}
+
+var (
+	pluginConnsMu sync.Mutex
+	pluginConns   = make(map[string]*pluginConn)
+)
+
+// getPluginConn is the address's connection, made on first use. The health interval of the strategy that makes it
+// is the one it keeps.
+func getPluginConn(address string, healthInterval time.Duration) (*pluginConn, error) {
+	pluginConnsMu.Lock()
+	defer pluginConnsMu.Unlock()
+	if c, ok := pluginConns[address]; ok {
+		return c, nil
+	}
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	c := &pluginConn{
+		address: address,
+		conn:    conn,
+		client:  plugin.NewStrategyPluginClient(conn),
+		health:  healthpb.NewHealthClient(conn),
+		tickers: make(chan *plugin.Ticker, 256),
+		done:    make(chan struct{}),
+	}
+	c.healthy.Store(true) // until a check says otherwise, the first signal check shouldn't wait on one
+	go c.checkHealth(healthInterval)
+	go c.streamTickers()
+	pluginConns[address] = c
+	return c, nil
+}
+
+// ClosePlugins closes every plugin connection, for shutdown. Strategies still pointed at one hold from then on, a
+// strategy made after it connects again.
+func ClosePlugins() {
+	pluginConnsMu.Lock()
+	defer pluginConnsMu.Unlock()
+	for address, c := range pluginConns {
+		c.close()
+		delete(pluginConns, address)
+	}
+}
+
+// close stops the health checks and the ticker stream and closes the connection, calls in flight fail and hold.
+func (c *pluginConn) close() {
+	close(c.done)
+	c.healthy.Store(false)
+	if err := c.conn.Close(); err != nil {
+		log.Printf("[Plugin %s] closing: %v", c.address, err)
+	}
+}
+
+func (c *pluginConn) checkHealth(interval time.Duration) {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		resp, err := c.health.Check(ctx, &healthpb.HealthCheckRequest{})
+		cancel()
+		healthy := err == nil && resp.GetStatus() == healthpb.HealthCheckResponse_SERVING
+		select {
+		case <-c.done:
+			return
+		default:
+		}
+		if c.healthy.Swap(healthy) != healthy {
+			if healthy {
+				log.Printf("[Plugin %s] serving again", c.address)
+			} else {
+				log.Printf("[Plugin %s] unhealthy, holding until it serves again: status %s, err %v", c.address, resp.GetStatus(), err)
+			}
+		}
+		select {
+		case <-c.done:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// sendTicker queues ticker for the plugin, dropping it when the queue is full rather than holding up the signal
+// engine behind a slow plugin.
+func (c *pluginConn) sendTicker(ticker *plugin.Ticker) {
+	select {
+	case c.tickers <- ticker:
+	default:
+	}
+}
+
+// streamTickers forwards the queued tickers, opening the stream again after it breaks, until the connection is
+// closed. While the plugin is unhealthy the tickers are dropped.
+func (c *pluginConn) streamTickers() {
+	var stream grpc.ClientStreamingClient[plugin.Ticker, plugin.StreamTickersResponse]
+	var cancel context.CancelFunc
+	for {
+		var ticker *plugin.Ticker
+		select {
+		case <-c.done:
+			if stream != nil {
+				cancel()
+			}
+			return
+		case ticker = <-c.tickers:
+		}
+		if !c.healthy.Load() {
+			if stream != nil {
+				cancel()
+				stream = nil
+			}
+			continue
+		}
+		if stream == nil {
+			ctx, streamCancel := context.WithCancel(context.Background())
+			s, err := c.client.StreamTickers(ctx)
+			if err != nil {
+				streamCancel()
+				continue
+			}
+			stream, cancel = s, streamCancel
+		}
+		if err := stream.Send(ticker); err != nil {
+			cancel()
+			stream = nil
+		}
+	}
+}
+
+// PluginStrategy is a strategy that runs out of process. The position is tracked here like any other strategy's,
+// the plugin sees it on every check but doesn't own it, and the levels it set are still enforced here when the
+// plugin is down. Anything else that goes wrong, an unhealthy plugin, a timeout, a malformed signal, is a hold.
+type PluginStrategy struct {
+	*helper.PositionHolder
+	PluginParams
+	conn    *pluginConn
+	params  string
+	failing atomic.Bool // the failure is logged when it starts rather than on every check
+}
+
+func newPluginStrategy(params PluginParams) *PluginStrategy {
+	s := &PluginStrategy{PositionHolder: helper.NewPositionHolder(), PluginParams: params, params: "{}"}
+	if raw, err := json.Marshal(params.Params); err == nil && params.Params != nil {
+		s.params = string(raw)
+	}
+	conn, err := getPluginConn(params.Address, time.Duration(params.HealthIntervalMs)*time.Millisecond)
+	if err != nil {
+		log.Printf("[Plugin %s] can't connect to %s, holding: %v", params.Strategy, params.Address, err)
+	}
+	s.conn = conn
+	return s
+}
+
+func (s *PluginStrategy) GetWarmUpCandles() int {
+	return s.WarmUpCandles
+}
+
+func (s *PluginStrategy) CalculateSignal(symbol string, exchange exchange.IExchange) models.Signal {
+	hold := models.Signal{Symbol: symbol, Type: enum.SignalHold, Percent: 0, Time: time.Now()}
+	hist := exchange.GetCandleHistory(symbol)
+	if len(hist.Candles) == 0 {
+		return hold
+	}
+	candles := hist.Candles[max(len(hist.Candles)-s.HistoryCandles, 0):]
+	closeCurr := candles[len(candles)-1].Close
+
+	state := s.PositionHolder.State[symbol]
+	if exit, ok := s.getLevelExit(state, closeCurr); ok {
+		return models.Signal{Symbol: symbol, Type: exit, Percent: 100, Time: time.Now(), Price: closeCurr}
+	}
+	if s.conn == nil || !s.conn.healthy.Load() {
+		return hold
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.TimeoutMs)*time.Millisecond)
+	defer cancel()
+	resp, err := s.conn.client.CalculateSignal(ctx, &plugin.CalculateSignalRequest{
+		Strategy: s.Strategy,
+		Symbol:   symbol,
+		Candles:  toPluginCandles(candles),
+		Position: toPluginPosition(state),
+		Params:   s.params,
+	})
+	if err == nil {
+		var signal models.Signal
+		if signal, err = fromPluginSignal(symbol, resp, closeCurr); err == nil {
+			if s.failing.Swap(false) {
+				log.Printf("[Plugin %s %s] signals are back", s.Strategy, symbol)
+			}
+			return signal
+		}
+	}
+	if !s.failing.Swap(true) {
+		log.Printf("[Plugin %s %s] holding, the plugin failed: %v", s.Strategy, symbol, err)
+	}
+	return hold
+}
+
+// getLevelExit is the exit for a position whose take profit or stop the close went through.
+func (s *PluginStrategy) getLevelExit(state *helper.PositionState, closeCurr float64) (enum.SignalType, bool) {
+	if state == nil || !state.InPosition {
+		return enum.SignalHold, false
+	}
+	if state.Side == enum.SignalShort {
+		isReachedTakeProfit := state.TakeProfit > 0 && closeCurr <= state.TakeProfit
+		isReachedStop := (state.StopLoss > 0 && closeCurr >= state.StopLoss) || (state.TrailingStop > 0 && closeCurr >= state.TrailingStop)
+		return enum.SignalCover, isReachedTakeProfit || isReachedStop
+	}
+	isReachedTakeProfit := state.TakeProfit > 0 && closeCurr >= state.TakeProfit
+	isReachedStop := (state.StopLoss > 0 && closeCurr <= state.StopLoss) || (state.TrailingStop > 0 && closeCurr <= state.TrailingStop)
+	return enum.SignalSell, isReachedTakeProfit || isReachedStop
+}
+
+// UpdateTrailingStop ratchets the stop here and passes the tick on to the plugin.
+func (s *PluginStrategy) UpdateTrailingStop(symbol string, ticker models.Ticker) {
+	s.PositionHolder.UpdateTrailingStop(symbol, ticker)
+	if s.conn == nil || !s.conn.healthy.Load() {
+		return
+	}
+	s.conn.sendTicker(&plugin.Ticker{
+		Symbol:   symbol,
+		Price:    ticker.Price,
+		Bid:      ticker.Bid,
+		Ask:      ticker.Ask,
+		Time:     timestamppb.New(ticker.Time),
+		Strategy: s.Strategy,
+	})
+}
+
+// The signal types each way. SIGNAL_TYPE_UNSPECIFIED has no enum.SignalType, a plugin that sends it failed to
+// set one and the check holds.
+var (
+	toPluginSignalType = map[enum.SignalType]plugin.SignalType{
+		enum.SignalBuy:   plugin.SignalType_SIGNAL_TYPE_BUY,
+		enum.SignalSell:  plugin.SignalType_SIGNAL_TYPE_SELL,
+		enum.SignalHold:  plugin.SignalType_SIGNAL_TYPE_HOLD,
+		enum.SignalShort: plugin.SignalType_SIGNAL_TYPE_SHORT,
+		enum.SignalCover: plugin.SignalType_SIGNAL_TYPE_COVER,
+	}
+	fromPluginSignalType = map[plugin.SignalType]enum.SignalType{
+		plugin.SignalType_SIGNAL_TYPE_BUY:   enum.SignalBuy,
+		plugin.SignalType_SIGNAL_TYPE_SELL:  enum.SignalSell,
+		plugin.SignalType_SIGNAL_TYPE_HOLD:  enum.SignalHold,
+		plugin.SignalType_SIGNAL_TYPE_SHORT: enum.SignalShort,
+		plugin.SignalType_SIGNAL_TYPE_COVER: enum.SignalCover,
+	}
+)
+
+func toPluginCandles(candles []models.Candle) []*plugin.Candle {
+	out := make([]*plugin.Candle, len(candles))
+	for i, c := range candles {
+		out[i] = &plugin.Candle{Start: timestamppb.New(c.Start), Open: c.Open, High: c.High, Low: c.Low, Close: c.Close, Volume: c.Volume}
+	}
+	return out
+}
+
+func toPluginPosition(state *helper.PositionState) *plugin.PositionState {
+	if state == nil || !state.InPosition {
+		return &plugin.PositionState{Side: plugin.SignalType_SIGNAL_TYPE_HOLD}
+	}
+	units := make([]*plugin.PositionUnit, len(state.Units))
+	for i, u := range state.Units {
+		units[i] = &plugin.PositionUnit{EntryPrice: u.EntryPrice, Percent: u.Percent, StopLoss: u.StopLoss, Time: timestamppb.New(u.Time)}
+	}
+	return &plugin.PositionState{
+		InPosition:                true,
+		Side:                      toPluginSignalType[state.Side],
+		EntryPrice:                state.EntryPrice,
+		TakeProfit:                state.TakeProfit,
+		StopLoss:                  state.StopLoss,
+		TrailingStop:              state.TrailingStop,
+		LastTrailingStopPrice:     state.LastTrailingStopPrice,
+		PositionIncreaseThreshold: state.PositionIncreaseThreshold,
+		Units:                     units,
+	}
+}
+
+// fromPluginSignal checks the plugin's signal before anything trades on it. A signal without a price is at the
+// last close.
+func fromPluginSignal(symbol string, resp *plugin.Signal, closeCurr float64) (models.Signal, error) {
+	signalType, ok := fromPluginSignalType[resp.GetType()]
+	if !ok {
+		return models.Signal{}, fmt.Errorf("unknown signal type %s", resp.GetType())
+	}
+	for name, v := range map[string]float64{"price": resp.GetPrice(), "takeProfit": resp.GetTakeProfit(), "stopLoss": resp.GetStopLoss(), "trailingStop": resp.GetTrailingStop()} {
+		if math.IsNaN(v) || math.IsInf(v, 0) || v < 0 {
+			return models.Signal{}, fmt.Errorf("invalid %s %v", name, v)
+		}
+	}
+	if !(resp.GetPercent() >= 0 && resp.GetPercent() <= 100) {
+		return models.Signal{}, fmt.Errorf("percent %v is outside [0, 100]", resp.GetPercent())
+	}
+	price := resp.GetPrice()
+	if price == 0 {
+		price = closeCurr
+	}
+	return models.Signal{
+		Symbol:       symbol,
+		Type:         signalType,
+		Percent:      resp.GetPercent(),
+		Time:         time.Now(),
+		Price:        price,
+		TakeProfit:   resp.GetTakeProfit(),
+		StopLoss:     resp.GetStopLoss(),
+		TrailingStop: resp.GetTrailingStop(),
+	}, nil
+}
@@ -0,0 +1,67 @@
+package signaler
+
+import (
+	"testing"
+
+	plugin "github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler/strategy_plugin"
+	enum "github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+)
+
+func TestFromPluginSignal(t *testing.T) {
+	tests := []struct {
+		name     string
+		signal   *plugin.Signal
+		wantType enum.SignalType
+		wantErr  bool
+	}{
+		{name: "buy", signal: &plugin.Signal{Type: plugin.SignalType_SIGNAL_TYPE_BUY, Percent: 50}, wantType: enum.SignalBuy},
+		{name: "sell", signal: &plugin.Signal{Type: plugin.SignalType_SIGNAL_TYPE_SELL, Percent: 100}, wantType: enum.SignalSell},
+		{name: "hold", signal: &plugin.Signal{Type: plugin.SignalType_SIGNAL_TYPE_HOLD}, wantType: enum.SignalHold},
+		{name: "short", signal: &plugin.Signal{Type: plugin.SignalType_SIGNAL_TYPE_SHORT, Percent: 50}, wantType: enum.SignalShort},
+		{name: "cover", signal: &plugin.Signal{Type: plugin.SignalType_SIGNAL_TYPE_COVER, Percent: 100}, wantType: enum.SignalCover},
+		{name: "type never set", signal: &plugin.Signal{Percent: 50}, wantErr: true},
+		{name: "unknown type", signal: &plugin.Signal{Type: 9}, wantErr: true},
+		{name: "percent above 100", signal: &plugin.Signal{Type: plugin.SignalType_SIGNAL_TYPE_BUY, Percent: 150}, wantErr: true},
+		{name: "negative stop", signal: &plugin.Signal{Type: plugin.SignalType_SIGNAL_TYPE_BUY, StopLoss: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fromPluginSignal("ETH-USD", tt.signal, 100)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want an error %v", err, tt.wantErr)
+			}
+			if err == nil && got.Type != tt.wantType {
+				t.Errorf("type %s, want %s", got.Type, tt.wantType)
+			}
+		})
+	}
+}
+
+// Every signal type has to make the round trip, a position's side goes out and comes back as the same type.
+func TestPluginSignalTypes(t *testing.T) {
+	for _, signalType := range []enum.SignalType{enum.SignalBuy, enum.SignalSell, enum.SignalHold, enum.SignalShort, enum.SignalCover} {
+		sent, ok := toPluginSignalType[signalType]
+		if !ok || sent == plugin.SignalType_SIGNAL_TYPE_UNSPECIFIED {
+			t.Fatalf("%s has no plugin signal type", signalType)
+		}
+		if back := fromPluginSignalType[sent]; back != signalType {
+			t.Errorf("%s comes back as %s", signalType, back)
+		}
+	}
+}
+
+func TestClosePlugins(t *testing.T) {
+	s := newPluginStrategy(GetDefaultParams(enum.Plugin).(PluginParams))
+	if s.conn == nil {
+		t.Fatal("no connection")
+	}
+	ClosePlugins()
+	if s.conn.healthy.Load() {
+		t.Error("a closed connection still reports healthy")
+	}
+	pluginConnsMu.Lock()
+	defer pluginConnsMu.Unlock()
+	if len(pluginConns) != 0 {
+		t.Errorf("%d connections left open", len(pluginConns))
+	}
+}
@@ -0,0 +1,52 @@
+package signaler
+
+import (
+	"log"
+	"time"
+
+	helper "github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler/strategy_helper"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/persistence"
+)
+
+// PositionSnapshot is a strategy's view of its position (entry, stops, trailing stop) for one symbol. It only
+// applies to the strategy that wrote it.
+type PositionSnapshot struct {
+	Strategy string               `json:"strategy"`
+	State    helper.PositionState `json:"state"`
+	SavedAt  time.Time            `json:"savedAt"`
+}
+
+func DeletePositionSnapshot(store *persistence.StateStore, symbol string) error {
+	return store.Delete(persistence.PositionBucket, symbol)
+}
+
+func (se *SignalEngine) persistPositionState(symbol string, strategyType enum.Strategy, strategy Strategy) {
+	state, ok := strategy.GetPositionState(symbol)
+	if !ok {
+		return
+	}
+	se.positionsMu.Lock()
+	se.positions[symbol] = state
+	se.positionsMu.Unlock()
+	snapshot := PositionSnapshot{Strategy: strategyType.String(), State: state, SavedAt: time.Now()}
+	if err := se.store.Put(persistence.PositionBucket, symbol, snapshot); err != nil {
+		log.Printf("[SignalEngine %s] failed to persist position state: %v", symbol, err)
+	}
+}
+
+// restorePositionState seeds a freshly built strategy with the position it held before a restart, if the
+// snapshot was written by the same strategy.
+func (se *SignalEngine) restorePositionState(symbol string, strategyType enum.Strategy, strategy Strategy) {
+	var snapshot PositionSnapshot
+	found, err := se.store.Get(persistence.PositionBucket, symbol, &snapshot)
+	if err != nil {
+		log.Printf("[SignalEngine %s] failed to load position state: %v", symbol, err)
+		return
+	}
+	if !found || snapshot.Strategy != strategyType.String() {
+		return
+	}
+	strategy.RestorePositionState(symbol, snapshot.State)
+	log.Printf("[SignalEngine %s] restored %s position state: inPosition=%v units=%d entry=%v trailingStop=%v", symbol, snapshot.Strategy, snapshot.State.InPosition, len(snapshot.State.Units), snapshot.State.EntryPrice, snapshot.State.TrailingStop)
+}
@@ -0,0 +1,102 @@
+// Package rules compiles the small expression language rule-based strategies are written in. An expression is
+// evaluated over a symbol's candles as a series, one value per candle, and the strategy acts on the last one.
+//
+//	crossover(ema(close, 9), ema(close, 21)) and rsi(close, 14) > 50
+//	close < lowest(low, 20)[1] or close < entryPrice - 2 * atr(14)
+//
+// Series are open, high, low, close, volume, hl2 and hlc3, plus entryPrice, the open position's entry (0 when flat).
+// Arithmetic is + - * /, comparisons < <= > >= == != give 1 or 0, and/or/not treat anything but 0 as true, and
+// x[n] is x n candles back. See functions.go for the indicators and candle-shape functions.
+package rules
+
+import (
+	"fmt"
+	"math"
+)
+
+// Bars are the candles an expression is evaluated over, oldest first, every series the same length.
+type Bars struct {
+	Open   []float64
+	High   []float64
+	Low    []float64
+	Close  []float64
+	Volume []float64
+}
+
+// Vars are the position values expressions can read, each the same for every candle.
+type Vars struct {
+	EntryPrice float64
+}
+
+const (
+	MaxLength   = 1000 // the longest length or offset an expression may use
+	MaxLookback = 2000 // the most candles an expression may read back, its lengths and offsets stacked
+)
+
+type Expression struct {
+	source string
+	root   node
+}
+
+// Compile parses source, checking function names, argument counts, that lengths are positive constants up to
+// MaxLength and that the whole expression reads no more than MaxLookback candles back.
+func Compile(source string) (*Expression, error) {
+	p := &parser{lexer: newLexer(source)}
+	p.next()
+	root, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.tok.kind != tokenEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+	if lookback := root.lookback(); lookback > MaxLookback {
+		return nil, &CompileError{Pos: 0, Msg: fmt.Sprintf("reads %d candles back, at most %d", lookback, MaxLookback)}
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+// GetLookback is how many candles before the last one the expression reads, its warm-up less one.
+func (e *Expression) GetLookback() int {
+	return e.root.lookback()
+}
+
+// Eval is the expression's value on the last candle, NaN without candles or while it is still warming up.
+func (e *Expression) Eval(bars Bars, vars Vars) float64 {
+	series := e.EvalSeries(bars, vars)
+	if len(series) == 0 {
+		return math.NaN()
+	}
+	return series[len(series)-1]
+}
+
+// IsTrue is Eval read as a condition, true for anything but 0 and NaN.
+func (e *Expression) IsTrue(bars Bars, vars Vars) bool {
+	return isTrue(e.Eval(bars, vars))
+}
+
+// EvalSeries is the expression's value on every candle.
+func (e *Expression) EvalSeries(bars Bars, vars Vars) []float64 {
+	ctx := &evalContext{bars: bars, vars: vars, n: len(bars.Close), cache: make(map[string][]float64)}
+	if ctx.n == 0 {
+		return nil
+	}
+	return ctx.eval(e.root)
+}
+
+// CompileError points at where in the source compiling failed.
+type CompileError struct {
+	Pos int // byte offset into the source
+	Msg string
+}
+
+func (e *CompileError) Error() string {
+	return fmt.Sprintf("at %d: %s", e.Pos, e.Msg)
+}
@@ -0,0 +1,98 @@
+package rules
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		lookback int
+		wantErr  string // a CompileError's message, empty when it compiles
+	}{
+		{name: "series", source: "close", lookback: 0},
+		{name: "precedence", source: "1 + 2 * 3 > 6 and not 0", lookback: 0},
+		{name: "source function", source: "sma(close, 20)", lookback: 19},
+		{name: "offset", source: "ema(close, 9)[2]", lookback: 10},
+		{name: "candle function", source: "adx(14) > 25", lookback: 28},
+		{name: "nested", source: "crossover(ema(close, 9), ema(close, 21))", lookback: 21},
+		{name: "longest length", source: "sma(close, 1000)[1000]", lookback: 1999},
+		{name: "zero length", source: "sma(close, 0)", wantErr: "sma length must be a positive whole number"},
+		{name: "length too long", source: "sma(close, 1001)", wantErr: "sma length must be at most 1000"},
+		{name: "offset too long", source: "close[1001]", wantErr: "offset must be at most 1000"},
+		{name: "lookback too long", source: "sma(sma(close, 1000), 1000)[1000]", wantErr: "reads 2998 candles back, at most 2000"},
+		{name: "macd", source: "macdhist(close, 12, 26, 9)", lookback: 33},
+		{name: "macd lengths all 1", source: "macd(close, 1, 1, 1)", wantErr: "macd: fast length must be less than slow length"},
+		{name: "macd fast not faster", source: "macdsignal(close, 26, 12, 9)", wantErr: "macdsignal: fast length must be less than slow length"},
+		{name: "macd signal of 1", source: "macdhist(close, 1, 2, 1)", wantErr: "macdhist: signal length must be at least 2"},
+		{name: "length not a number", source: "sma(close, n)", wantErr: "sma length must be a number"},
+		{name: "unknown function", source: "foo(close)", wantErr: `unknown function "foo"`},
+		{name: "unknown series", source: "price > 1", wantErr: `unknown series "price"`},
+		{name: "too few arguments", source: "sma(close)", wantErr: "sma takes 2 arguments"},
+		{name: "dangling operator", source: "close +", wantErr: "unexpected end of expression"},
+		{name: "unexpected character", source: "close $ 1", wantErr: `unexpected character '$'`},
+		{name: "unclosed paren", source: "(close > 1", wantErr: "expected ) but found end of expression"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Compile(tt.source)
+			if tt.wantErr != "" {
+				var compileErr *CompileError
+				if !errors.As(err, &compileErr) || compileErr.Msg != tt.wantErr {
+					t.Fatalf("Compile(%q) = %v, want %q", tt.source, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Compile(%q): %v", tt.source, err)
+			}
+			if got := expr.GetLookback(); got != tt.lookback {
+				t.Errorf("lookback %d, want %d", got, tt.lookback)
+			}
+		})
+	}
+}
+
+func TestEval(t *testing.T) {
+	bars := Bars{
+		Open:   []float64{10, 11, 12, 13, 12},
+		High:   []float64{12, 13, 14, 14, 13},
+		Low:    []float64{9, 10, 11, 12, 10},
+		Close:  []float64{11, 12, 13, 12, 11},
+		Volume: []float64{100, 110, 120, 130, 140},
+	}
+	tests := []struct {
+		name   string
+		source string
+		vars   Vars
+		want   float64 // NaN while the expression is still warming up
+	}{
+		{name: "arithmetic", source: "1 + 2 * 3 - 4 / 2", want: 5},
+		{name: "unary minus", source: "-close + 20", want: 9},
+		{name: "comparison", source: "close < open", want: 1},
+		{name: "and or not", source: "not (close > open) and (volume > 200 or high >= 13)", want: 1},
+		{name: "offset", source: "close[2]", want: 13},
+		{name: "sma", source: "sma(close, 3)", want: 12},
+		{name: "highest", source: "highest(high, 3)", want: 14},
+		{name: "hlc3", source: "hlc3", want: (13.0 + 10 + 11) / 3},
+		{name: "entry price", source: "close - entryPrice", vars: Vars{EntryPrice: 10}, want: 1},
+		{name: "crossunder", source: "crossunder(close, 11.5)", want: 1},
+		{name: "still warming up", source: "sma(close, 10)", want: math.NaN()},
+		{name: "shortest macd", source: "macd(close, 1, 2, 2)", want: -7.0 / 18},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Compile(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := expr.Eval(bars, tt.vars)
+			if math.IsNaN(tt.want) != math.IsNaN(got) || (!math.IsNaN(got) && math.Abs(got-tt.want) > 1e-9) {
+				t.Errorf("Eval(%q) = %v, want %v", tt.source, got, tt.want)
+			}
+		})
+	}
+}
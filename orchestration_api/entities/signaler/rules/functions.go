@@ -0,0 +1,236 @@
+package rules
+
+import (
+	"math"
+
+	helper "github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler/strategy_helper"
+	talib "github.com/markcheno/go-talib"
+)
+
+type argKind int
+
+const (
+	argSeries argKind = iota // any expression
+	argLength                // a positive whole number
+	argNumber                // a constant, e.g. a multiplier
+)
+
+type function struct {
+	args     []argKind
+	lookback func(lengths []int) int // candles the function reads before the one it is computed for
+	// checkLengths is why the lengths can't go together, "" when they can. Nil when any lengths can.
+	checkLengths func(lengths []int) string
+	eval         func(ctx *evalContext, series [][]float64, lengths []int, numbers []float64) []float64
+}
+
+// functions are what expressions can call:
+//
+//	sma ema smma rsi roc stdev highest lowest (src, length)
+//	atr adx (length)                     on high, low and close
+//	macd macdsignal macdhist (src, fast, slow, signal)
+//	bbupper bblower (src, length, mult)  Bollinger bands, mult standard deviations from the SMA
+//	crossover crossunder (a, b)          a crossed above or below b on this candle
+//	abs (x) min max (a, b)
+//	body range uppershadow lowershadow bullish bearish engulfing gapup gapdown ()
+//	doji (atrLength, atrMult, rangePerc) a body under atrMult ATRs or rangePerc of the range, with both shadows
+var functions = map[string]function{
+	"sma":     newSourceFunction(func(src []float64, length int) []float64 { return helper.Sma(src, length) }, -1),
+	"ema":     newSourceFunction(func(src []float64, length int) []float64 { return helper.Ema(src, length) }, -1),
+	"smma":    newSourceFunction(helper.Smma, -1),
+	"rsi":     newSourceFunction(talib.Rsi, 0),
+	"roc":     newSourceFunction(talib.Roc, 0),
+	"stdev":   newSourceFunction(func(src []float64, length int) []float64 { return talib.StdDev(src, length, 1) }, -1),
+	"highest": newSourceFunction(talib.Max, -1),
+	"lowest":  newSourceFunction(talib.Min, -1),
+	"atr": {
+		args:     []argKind{argLength},
+		lookback: func(lengths []int) int { return lengths[0] },
+		eval: func(ctx *evalContext, _ [][]float64, lengths []int, _ []float64) []float64 {
+			return talib.Atr(ctx.bars.High, ctx.bars.Low, ctx.bars.Close, lengths[0])
+		},
+	},
+	"adx": {
+		args:     []argKind{argLength},
+		lookback: func(lengths []int) int { return 2 * lengths[0] },
+		eval: func(ctx *evalContext, _ [][]float64, lengths []int, _ []float64) []float64 {
+			return talib.Adx(ctx.bars.High, ctx.bars.Low, ctx.bars.Close, lengths[0])
+		},
+	},
+	"macd":       newMacdFunction(0),
+	"macdsignal": newMacdFunction(1),
+	"macdhist":   newMacdFunction(2),
+	"bbupper":    newBandFunction(true),
+	"bblower":    newBandFunction(false),
+	"crossover": {
+		args:     []argKind{argSeries, argSeries},
+		lookback: func([]int) int { return 1 },
+		eval: func(ctx *evalContext, series [][]float64, _ []int, _ []float64) []float64 {
+			return mapCandles(ctx, 1, func(i int) bool { return helper.CrossOver(series[0][:i+1], series[1][:i+1]) })
+		},
+	},
+	"crossunder": {
+		args:     []argKind{argSeries, argSeries},
+		lookback: func([]int) int { return 1 },
+		eval: func(ctx *evalContext, series [][]float64, _ []int, _ []float64) []float64 {
+			return mapCandles(ctx, 1, func(i int) bool { return helper.CrossUnder(series[0][:i+1], series[1][:i+1]) })
+		},
+	},
+	"abs":   newPointFunction(1, func(v []float64) float64 { return math.Abs(v[0]) }),
+	"min":   newPointFunction(2, func(v []float64) float64 { return math.Min(v[0], v[1]) }),
+	"max":   newPointFunction(2, func(v []float64) float64 { return math.Max(v[0], v[1]) }),
+	"body":  newCandleFunction(0, func(b Bars, i int) float64 { return helper.BodySize(b.Open[i], b.Close[i]) }),
+	"range": newCandleFunction(0, func(b Bars, i int) float64 { return helper.CandleRange(b.High[i], b.Low[i]) }),
+	"uppershadow": newCandleFunction(0, func(b Bars, i int) float64 {
+		return helper.UpperShadow(b.Open[i], b.Close[i], b.High[i])
+	}),
+	"lowershadow": newCandleFunction(0, func(b Bars, i int) float64 {
+		return helper.LowerShadow(b.Open[i], b.Close[i], b.Low[i])
+	}),
+	"bullish": newCandleFunction(0, func(b Bars, i int) float64 { return fromBool(helper.IsBullish(b.Open[i], b.Close[i])) }),
+	"bearish": newCandleFunction(0, func(b Bars, i int) float64 { return fromBool(helper.IsBearish(b.Open[i], b.Close[i])) }),
+	"engulfing": newCandleFunction(1, func(b Bars, i int) float64 {
+		return fromBool(helper.IsEngulfing(b.Open[i], b.Close[i], b.Open[i-1], b.Close[i-1]))
+	}),
+	"gapup":   newCandleFunction(1, func(b Bars, i int) float64 { return fromBool(helper.IsGapUp(b.Open[i], b.High[i-1])) }),
+	"gapdown": newCandleFunction(1, func(b Bars, i int) float64 { return fromBool(helper.IsGapDown(b.Open[i], b.Low[i-1])) }),
+	"doji": {
+		args:     []argKind{argLength, argNumber, argNumber},
+		lookback: func(lengths []int) int { return lengths[0] },
+		eval: func(ctx *evalContext, _ [][]float64, lengths []int, numbers []float64) []float64 {
+			atr := talib.Atr(ctx.bars.High, ctx.bars.Low, ctx.bars.Close, lengths[0])
+			b := ctx.bars
+			return mapCandles(ctx, lengths[0], func(i int) bool {
+				return helper.IsDoji(b.Open[i], b.Close[i], b.High[i], b.Low[i], atr[i], numbers[0], numbers[1])
+			})
+		},
+	},
+}
+
+func init() {
+	// talib indexes past the end of a series shorter than its look-back, so nothing is computed until there is
+	// enough, and its leading zeros are NaN so a comparison during the warm-up is never true
+	for name, fn := range functions {
+		eval := fn.eval
+		lookback := fn.lookback
+		fn.eval = func(ctx *evalContext, series [][]float64, lengths []int, numbers []float64) []float64 {
+			warmUp := lookback(lengths)
+			if ctx.n <= warmUp {
+				return ctx.constant(math.NaN())
+			}
+			for i, s := range series {
+				series[i] = withoutNaN(s)
+			}
+			out := eval(ctx, series, lengths, numbers)
+			for i := 0; i < warmUp; i++ {
+				out[i] = math.NaN()
+			}
+			return out
+		}
+		functions[name] = fn
+	}
+}
+
+// newSourceFunction wraps an indicator of (src, length), reading length plus extra candles back.
+func newSourceFunction(indicator func(src []float64, length int) []float64, extra int) function {
+	return function{
+		args:     []argKind{argSeries, argLength},
+		lookback: func(lengths []int) int { return lengths[0] + extra },
+		eval: func(_ *evalContext, series [][]float64, lengths []int, _ []float64) []float64 {
+			return indicator(series[0], lengths[0])
+		},
+	}
+}
+
+// newMacdFunction picks talib.Macd's output: the MACD line, its signal or the histogram.
+func newMacdFunction(output int) function {
+	return function{
+		args:     []argKind{argSeries, argLength, argLength, argLength},
+		lookback: func(lengths []int) int { return max(lengths[0], lengths[1]) + lengths[2] - 2 },
+		// talib.Macd indexes before the series when every length is 1, the warm-up being 0
+		checkLengths: func(lengths []int) string {
+			if lengths[0] >= lengths[1] {
+				return "fast length must be less than slow length"
+			}
+			if lengths[2] < 2 {
+				return "signal length must be at least 2"
+			}
+			return ""
+		},
+		eval: func(_ *evalContext, series [][]float64, lengths []int, _ []float64) []float64 {
+			macd, signal, hist := talib.Macd(series[0], lengths[0], lengths[1], lengths[2])
+			return [][]float64{macd, signal, hist}[output]
+		},
+	}
+}
+
+func newBandFunction(upper bool) function {
+	return function{
+		args:     []argKind{argSeries, argLength, argNumber},
+		lookback: func(lengths []int) int { return lengths[0] - 1 },
+		eval: func(_ *evalContext, series [][]float64, lengths []int, numbers []float64) []float64 {
+			up, _, down := talib.BBands(series[0], lengths[0], numbers[0], numbers[0], talib.SMA)
+			if upper {
+				return up
+			}
+			return down
+		},
+	}
+}
+
+// newPointFunction applies f candle by candle to its arguments.
+func newPointFunction(arity int, f func(values []float64) float64) function {
+	args := make([]argKind, arity)
+	return function{
+		args:     args,
+		lookback: func([]int) int { return 0 },
+		eval: func(ctx *evalContext, series [][]float64, _ []int, _ []float64) []float64 {
+			out := make([]float64, ctx.n)
+			values := make([]float64, arity)
+			for i := range out {
+				for j := range series {
+					values[j] = series[j][i]
+				}
+				out[i] = f(values)
+			}
+			return out
+		},
+	}
+}
+
+// newCandleFunction computes a candle-shape value from candle i and the lookback before it.
+func newCandleFunction(lookback int, f func(b Bars, i int) float64) function {
+	return function{
+		lookback: func([]int) int { return lookback },
+		eval: func(ctx *evalContext, _ [][]float64, _ []int, _ []float64) []float64 {
+			out := make([]float64, ctx.n)
+			for i := lookback; i < ctx.n; i++ {
+				out[i] = f(ctx.bars, i)
+			}
+			return out
+		},
+	}
+}
+
+// mapCandles is 1 on the candles from first on where f holds and 0 elsewhere.
+func mapCandles(ctx *evalContext, first int, f func(i int) bool) []float64 {
+	out := make([]float64, ctx.n)
+	for i := first; i < ctx.n; i++ {
+		out[i] = fromBool(f(i))
+	}
+	return out
+}
+
+func withoutNaN(series []float64) []float64 {
+	for _, v := range series {
+		if math.IsNaN(v) {
+			out := make([]float64, len(series))
+			for i, v := range series {
+				if !math.IsNaN(v) {
+					out[i] = v
+				}
+			}
+			return out
+		}
+	}
+	return series
+}
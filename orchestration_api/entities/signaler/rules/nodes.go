@@ -0,0 +1,210 @@
+package rules
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+type node interface {
+	eval(ctx *evalContext) []float64
+	lookback() int
+	String() string // canonical, nodes that print the same are evaluated once
+}
+
+type evalContext struct {
+	bars  Bars
+	vars  Vars
+	n     int
+	cache map[string][]float64
+}
+
+func (ctx *evalContext) eval(n node) []float64 {
+	key := n.String()
+	if series, ok := ctx.cache[key]; ok {
+		return series
+	}
+	series := n.eval(ctx)
+	ctx.cache[key] = series
+	return series
+}
+
+func (ctx *evalContext) constant(v float64) []float64 {
+	out := make([]float64, ctx.n)
+	for i := range out {
+		out[i] = v
+	}
+	return out
+}
+
+func isTrue(v float64) bool {
+	return v != 0 && !math.IsNaN(v)
+}
+
+func fromBool(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+type numberNode struct {
+	value float64
+}
+
+func (n *numberNode) eval(ctx *evalContext) []float64 { return ctx.constant(n.value) }
+func (n *numberNode) lookback() int                   { return 0 }
+func (n *numberNode) String() string                  { return fmt.Sprint(n.value) }
+
+var seriesByName = map[string]func(ctx *evalContext) []float64{
+	"open":   func(ctx *evalContext) []float64 { return ctx.bars.Open },
+	"high":   func(ctx *evalContext) []float64 { return ctx.bars.High },
+	"low":    func(ctx *evalContext) []float64 { return ctx.bars.Low },
+	"close":  func(ctx *evalContext) []float64 { return ctx.bars.Close },
+	"volume": func(ctx *evalContext) []float64 { return ctx.bars.Volume },
+	"hl2": func(ctx *evalContext) []float64 {
+		out := make([]float64, ctx.n)
+		for i := range out {
+			out[i] = (ctx.bars.High[i] + ctx.bars.Low[i]) / 2
+		}
+		return out
+	},
+	"hlc3": func(ctx *evalContext) []float64 {
+		out := make([]float64, ctx.n)
+		for i := range out {
+			out[i] = (ctx.bars.High[i] + ctx.bars.Low[i] + ctx.bars.Close[i]) / 3
+		}
+		return out
+	},
+	"entryPrice": func(ctx *evalContext) []float64 { return ctx.constant(ctx.vars.EntryPrice) },
+}
+
+type seriesNode struct {
+	name string
+}
+
+func (n *seriesNode) eval(ctx *evalContext) []float64 { return seriesByName[n.name](ctx) }
+func (n *seriesNode) lookback() int                   { return 0 }
+func (n *seriesNode) String() string                  { return n.name }
+
+type binaryNode struct {
+	op    string
+	left  node
+	right node
+}
+
+func (n *binaryNode) eval(ctx *evalContext) []float64 {
+	left, right := ctx.eval(n.left), ctx.eval(n.right)
+	out := make([]float64, ctx.n)
+	for i := range out {
+		a, b := left[i], right[i]
+		switch n.op {
+		case "+":
+			out[i] = a + b
+		case "-":
+			out[i] = a - b
+		case "*":
+			out[i] = a * b
+		case "/":
+			out[i] = a / b
+		case "<":
+			out[i] = fromBool(a < b)
+		case "<=":
+			out[i] = fromBool(a <= b)
+		case ">":
+			out[i] = fromBool(a > b)
+		case ">=":
+			out[i] = fromBool(a >= b)
+		case "==":
+			out[i] = fromBool(a == b)
+		case "!=":
+			out[i] = fromBool(a != b)
+		case "and":
+			out[i] = fromBool(isTrue(a) && isTrue(b))
+		case "or":
+			out[i] = fromBool(isTrue(a) || isTrue(b))
+		}
+	}
+	return out
+}
+
+func (n *binaryNode) lookback() int { return max(n.left.lookback(), n.right.lookback()) }
+func (n *binaryNode) String() string {
+	return "(" + n.left.String() + " " + n.op + " " + n.right.String() + ")"
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) eval(ctx *evalContext) []float64 {
+	operand := ctx.eval(n.operand)
+	out := make([]float64, ctx.n)
+	for i := range out {
+		out[i] = fromBool(!isTrue(operand[i]))
+	}
+	return out
+}
+
+func (n *notNode) lookback() int  { return n.operand.lookback() }
+func (n *notNode) String() string { return "(not " + n.operand.String() + ")" }
+
+// offsetNode is operand bars candles back, NaN before the first candle.
+type offsetNode struct {
+	operand node
+	bars    int
+}
+
+func (n *offsetNode) eval(ctx *evalContext) []float64 {
+	operand := ctx.eval(n.operand)
+	out := make([]float64, ctx.n)
+	for i := range out {
+		if i < n.bars {
+			out[i] = math.NaN()
+		} else {
+			out[i] = operand[i-n.bars]
+		}
+	}
+	return out
+}
+
+func (n *offsetNode) lookback() int  { return n.operand.lookback() + n.bars }
+func (n *offsetNode) String() string { return fmt.Sprintf("%s[%d]", n.operand.String(), n.bars) }
+
+type callNode struct {
+	name    string
+	fn      function
+	series  []node
+	lengths []int
+	numbers []float64
+}
+
+func (n *callNode) eval(ctx *evalContext) []float64 {
+	series := make([][]float64, len(n.series))
+	for i, arg := range n.series {
+		series[i] = ctx.eval(arg)
+	}
+	return n.fn.eval(ctx, series, n.lengths, n.numbers)
+}
+
+func (n *callNode) lookback() int {
+	lookback := n.fn.lookback(n.lengths)
+	for _, arg := range n.series {
+		lookback = max(lookback, arg.lookback()+n.fn.lookback(n.lengths))
+	}
+	return lookback
+}
+
+func (n *callNode) String() string {
+	args := make([]string, 0, len(n.series)+len(n.lengths)+len(n.numbers))
+	for _, arg := range n.series {
+		args = append(args, arg.String())
+	}
+	for _, length := range n.lengths {
+		args = append(args, fmt.Sprint(length))
+	}
+	for _, number := range n.numbers {
+		args = append(args, fmt.Sprint(number))
+	}
+	return n.name + "(" + strings.Join(args, ", ") + ")"
+}
@@ -0,0 +1,350 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenIdent
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenLBracket
+	tokenRBracket
+	tokenComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+func newLexer(src string) *lexer {
+	return &lexer{src: src}
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) && unicode.IsSpace(rune(l.src[l.pos])) {
+		l.pos++
+	}
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF, pos: start}, nil
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case c >= '0' && c <= '9' || c == '.':
+		for l.pos < len(l.src) && (l.src[l.pos] >= '0' && l.src[l.pos] <= '9' || l.src[l.pos] == '.') {
+			l.pos++
+		}
+		return token{kind: tokenNumber, text: l.src[start:l.pos], pos: start}, nil
+	case c == '_' || unicode.IsLetter(rune(c)):
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || unicode.IsLetter(rune(l.src[l.pos])) || unicode.IsDigit(rune(l.src[l.pos]))) {
+			l.pos++
+		}
+		return token{kind: tokenIdent, text: l.src[start:l.pos], pos: start}, nil
+	}
+
+	single := map[byte]tokenKind{'(': tokenLParen, ')': tokenRParen, '[': tokenLBracket, ']': tokenRBracket, ',': tokenComma}
+	if kind, ok := single[c]; ok {
+		l.pos++
+		return token{kind: kind, text: string(c), pos: start}, nil
+	}
+	for _, op := range []string{"<=", ">=", "==", "!=", "<", ">", "+", "-", "*", "/"} {
+		if strings.HasPrefix(l.src[l.pos:], op) {
+			l.pos += len(op)
+			return token{kind: tokenOperator, text: op, pos: start}, nil
+		}
+	}
+	return token{}, &CompileError{Pos: start, Msg: fmt.Sprintf("unexpected character %q", c)}
+}
+
+// parser is a recursive descent over, loosest first: or, and, not, comparisons, + -, * /, unary minus, x[n].
+type parser struct {
+	lexer *lexer
+	tok   token
+	err   error
+}
+
+func (p *parser) next() {
+	if p.err != nil {
+		return
+	}
+	p.tok, p.err = p.lexer.next()
+	if p.err != nil {
+		p.tok = token{kind: tokenEOF, pos: p.lexer.pos}
+	}
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	if p.err != nil {
+		return p.err
+	}
+	return &CompileError{Pos: p.tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) isKeyword(word string) bool {
+	return p.tok.kind == tokenIdent && p.tok.text == word
+}
+
+func (p *parser) isOperator(ops ...string) bool {
+	if p.tok.kind != tokenOperator {
+		return false
+	}
+	for _, op := range ops {
+		if p.tok.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) parseExpression() (node, error) {
+	return p.parseOr()
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.isKeyword("or") {
+		p.next()
+		var right node
+		if right, err = p.parseAnd(); err == nil {
+			left = &binaryNode{op: "or", left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	for err == nil && p.isKeyword("and") {
+		p.next()
+		var right node
+		if right, err = p.parseNot(); err == nil {
+			left = &binaryNode{op: "and", left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.isKeyword("not") {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if p.isOperator("<", "<=", ">", ">=", "==", "!=") {
+		op := p.tok.text
+		p.next()
+		right, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		return &binaryNode{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseSum() (node, error) {
+	left, err := p.parseProduct()
+	for err == nil && p.isOperator("+", "-") {
+		op := p.tok.text
+		p.next()
+		var right node
+		if right, err = p.parseProduct(); err == nil {
+			left = &binaryNode{op: op, left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) parseProduct() (node, error) {
+	left, err := p.parseUnary()
+	for err == nil && p.isOperator("*", "/") {
+		op := p.tok.text
+		p.next()
+		var right node
+		if right, err = p.parseUnary(); err == nil {
+			left = &binaryNode{op: op, left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOperator("-") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &binaryNode{op: "-", left: &numberNode{value: 0}, right: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	operand, err := p.parsePrimary()
+	for err == nil && p.tok.kind == tokenLBracket {
+		p.next()
+		var bars int
+		if bars, err = p.parseLength("offset"); err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokenRBracket {
+			return nil, p.errorf("expected ] but found %s", p.tok)
+		}
+		p.next()
+		operand = &offsetNode{operand: operand, bars: bars}
+	}
+	return operand, err
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	switch p.tok.kind {
+	case tokenNumber:
+		value, err := strconv.ParseFloat(p.tok.text, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %s", p.tok)
+		}
+		p.next()
+		return &numberNode{value: value}, nil
+	case tokenLParen:
+		p.next()
+		inner, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokenRParen {
+			return nil, p.errorf("expected ) but found %s", p.tok)
+		}
+		p.next()
+		return inner, nil
+	case tokenIdent:
+		name, pos := p.tok.text, p.tok.pos
+		p.next()
+		if p.tok.kind == tokenLParen {
+			return p.parseCall(name, pos)
+		}
+		if _, ok := seriesByName[name]; !ok {
+			return nil, &CompileError{Pos: pos, Msg: fmt.Sprintf("unknown series %q", name)}
+		}
+		return &seriesNode{name: name}, nil
+	}
+	return nil, p.errorf("unexpected %s", p.tok)
+}
+
+func (p *parser) parseCall(name string, pos int) (node, error) {
+	fn, ok := functions[name]
+	if !ok {
+		return nil, &CompileError{Pos: pos, Msg: fmt.Sprintf("unknown function %q", name)}
+	}
+	p.next() // (
+	call := &callNode{name: name, fn: fn}
+	for i, kind := range fn.args {
+		if i > 0 {
+			if p.tok.kind != tokenComma {
+				return nil, p.errorf("%s takes %d arguments", name, len(fn.args))
+			}
+			p.next()
+		}
+		switch kind {
+		case argSeries:
+			arg, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			call.series = append(call.series, arg)
+		case argLength:
+			length, err := p.parseLength(name + " length")
+			if err != nil {
+				return nil, err
+			}
+			call.lengths = append(call.lengths, length)
+		case argNumber:
+			value, err := p.parseConstant(name)
+			if err != nil {
+				return nil, err
+			}
+			call.numbers = append(call.numbers, value)
+		}
+	}
+	if p.tok.kind != tokenRParen {
+		return nil, p.errorf("%s takes %d arguments", name, len(fn.args))
+	}
+	if fn.checkLengths != nil {
+		if msg := fn.checkLengths(call.lengths); msg != "" {
+			return nil, &CompileError{Pos: pos, Msg: fmt.Sprintf("%s: %s", name, msg)}
+		}
+	}
+	p.next()
+	return call, nil
+}
+
+// parseLength reads a positive whole number up to MaxLength, lengths and offsets size the series so they can't be
+// computed.
+func (p *parser) parseLength(what string) (int, error) {
+	if p.tok.kind != tokenNumber {
+		return 0, p.errorf("%s must be a number", what)
+	}
+	length, err := strconv.Atoi(p.tok.text)
+	if err != nil || length <= 0 {
+		return 0, p.errorf("%s must be a positive whole number", what)
+	}
+	if length > MaxLength {
+		return 0, p.errorf("%s must be at most %d", what, MaxLength)
+	}
+	p.next()
+	return length, nil
+}
+
+func (p *parser) parseConstant(name string) (float64, error) {
+	negative := p.isOperator("-")
+	if negative {
+		p.next()
+	}
+	if p.tok.kind != tokenNumber {
+		return 0, p.errorf("%s takes a number here", name)
+	}
+	value, err := strconv.ParseFloat(p.tok.text, 64)
+	if err != nil {
+		return 0, p.errorf("invalid number %s", p.tok)
+	}
+	p.next()
+	if negative {
+		value = -value
+	}
+	return value, nil
+}
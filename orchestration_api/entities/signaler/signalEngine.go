@@ -6,9 +6,11 @@ import (
 	"sync"
 	"time"
 
+	helper "github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler/strategy_helper"
 	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
 	exchange "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange"
 	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/persistence"
 )
 
 type SignalEngineConfigUpdate struct {
@@ -25,15 +27,22 @@ type SignalEngine struct {
 	mu               sync.RWMutex
 	lastSignalAt     map[string]time.Time
 	tokenStrategies  map[string]Strategy
+	strategyTypes    map[string]enum.Strategy
 	tokenCandleSizes map[string]enum.CandleSize
 	signalChannels   map[string]chan models.Signal
 	tickerChannels   map[string]<-chan models.Ticker
 	tickerCleanup    map[string]func()
+	reloadChannels   map[string]chan struct{} // a params change for the symbol, picked up by its run goroutine
 	tokenEnabled     map[string]bool
+	tokenAllowShort  map[string]bool // short entries and covers are held back from tokens without it
 	updateCh         <-chan SignalEngineConfigUpdate
+	store            *persistence.StateStore
+	params           *ParamsStore
+	positionsMu      sync.Mutex
+	positions        map[string]helper.PositionState // each strategy's position as last persisted, for GetPositionState
 }
 
-func NewSignalEngine(parent context.Context, exchange exchange.IExchange, updateCh <-chan SignalEngineConfigUpdate) *SignalEngine {
+func NewSignalEngine(parent context.Context, exchange exchange.IExchange, updateCh <-chan SignalEngineConfigUpdate, store *persistence.StateStore, params *ParamsStore) *SignalEngine {
 	ctx, cancel := context.WithCancel(parent)
 
 	se := SignalEngine{
@@ -42,12 +51,18 @@ func NewSignalEngine(parent context.Context, exchange exchange.IExchange, update
 		exchange:         exchange,
 		lastSignalAt:     make(map[string]time.Time),
 		tokenStrategies:  make(map[string]Strategy),
+		strategyTypes:    make(map[string]enum.Strategy),
 		tokenCandleSizes: make(map[string]enum.CandleSize),
 		signalChannels:   make(map[string]chan models.Signal),
 		tickerChannels:   make(map[string]<-chan models.Ticker),
 		tickerCleanup:    make(map[string]func()),
+		reloadChannels:   make(map[string]chan struct{}),
 		tokenEnabled:     make(map[string]bool),
+		tokenAllowShort:  make(map[string]bool),
 		updateCh:         updateCh,
+		store:            store,
+		params:           params,
+		positions:        make(map[string]helper.PositionState),
 	}
 
 	return &se
@@ -56,7 +71,47 @@ func NewSignalEngine(parent context.Context, exchange exchange.IExchange, update
 func (se *SignalEngine) UpdateStrategy(symbol string, strategy enum.Strategy) {
 	se.mu.Lock()
 	defer se.mu.Unlock()
-	se.tokenStrategies[symbol] = NewStrategy(strategy)
+	se.tokenStrategies[symbol] = se.params.NewStrategy(symbol, strategy)
+	se.strategyTypes[symbol] = strategy
+	// strategies read their position state unguarded, so seed it before the first CalculateSignal
+	se.tokenStrategies[symbol].ConfirmSignalDelivered(symbol, models.Signal{Symbol: symbol, Type: enum.SignalHold})
+	se.restorePositionState(symbol, strategy, se.tokenStrategies[symbol])
+	se.persistPositionState(symbol, strategy, se.tokenStrategies[symbol])
+}
+
+// ReloadStrategy has the symbol's strategy swapped for one built with its current params. The swap happens on the
+// symbol's run goroutine, between signals, so no signal is confirmed on the strategy being replaced.
+func (se *SignalEngine) ReloadStrategy(symbol string) {
+	se.mu.Lock()
+	reloadCh, ok := se.reloadChannels[symbol]
+	se.mu.Unlock()
+	if !ok {
+		return
+	}
+	select {
+	case reloadCh <- struct{}{}:
+	default:
+		// a reload is already waiting and will build from the params as they are then
+	}
+}
+
+// reloadStrategy swaps the strategy, carrying the position over so the new params apply from the next signal on
+// without losing track of an open trade. Only the symbol's run goroutine calls it.
+func (se *SignalEngine) reloadStrategy(symbol string) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	old, ok := se.tokenStrategies[symbol]
+	if !ok {
+		return
+	}
+	strategyType := se.strategyTypes[symbol]
+	strategy := se.params.NewStrategy(symbol, strategyType)
+	strategy.ConfirmSignalDelivered(symbol, models.Signal{Symbol: symbol, Type: enum.SignalHold})
+	if state, ok := old.GetPositionState(symbol); ok {
+		strategy.RestorePositionState(symbol, state)
+	}
+	se.tokenStrategies[symbol] = strategy
+	log.Printf("[SignalEngine %s] reloaded %s with new params", symbol, strategyType.String())
 }
 
 func (se *SignalEngine) UpdateCandleSize(symbol string, candleSize enum.CandleSize) {
@@ -65,15 +120,25 @@ func (se *SignalEngine) UpdateCandleSize(symbol string, candleSize enum.CandleSi
 	se.tokenCandleSizes[symbol] = candleSize
 }
 
+// UpdateAllowShort says whether the token's trader acts on short entries and covers. Without it the strategy never
+// sees them delivered, so it doesn't take a short the trader ignored for its position.
+func (se *SignalEngine) UpdateAllowShort(symbol string, allowShort bool) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.tokenAllowShort[symbol] = allowShort
+}
+
 // RegisterToken wires the channels for a token. Manager should create the channels and pass them in.
-func (se *SignalEngine) RegisterToken(symbol string, strategy enum.Strategy, candleSize enum.CandleSize, signalCh chan models.Signal) {
+func (se *SignalEngine) RegisterToken(symbol string, strategy enum.Strategy, candleSize enum.CandleSize, allowShort bool, signalCh chan models.Signal) {
 	tickerCh, tickerCleanup := se.exchange.SubscribeToTicker(symbol)
 	se.UpdateStrategy(symbol, strategy)
 	se.UpdateCandleSize(symbol, candleSize)
+	se.UpdateAllowShort(symbol, allowShort)
 	se.mu.Lock()
 	se.tickerChannels[symbol] = tickerCh
 	se.tickerCleanup[symbol] = tickerCleanup
 	se.signalChannels[symbol] = signalCh
+	se.reloadChannels[symbol] = make(chan struct{}, 1)
 	se.lastSignalAt[symbol] = time.Time{}
 	se.tokenEnabled[symbol] = true
 	se.mu.Unlock()
@@ -87,12 +152,27 @@ func (se *SignalEngine) UnregisterToken(symbol string) {
 	defer se.mu.Unlock()
 	delete(se.signalChannels, symbol)
 	delete(se.tokenStrategies, symbol)
+	delete(se.strategyTypes, symbol)
 	delete(se.tokenCandleSizes, symbol)
 	delete(se.lastSignalAt, symbol)
 	se.tickerCleanup[symbol]()
 	delete(se.tickerChannels, symbol)
 	delete(se.tickerCleanup, symbol)
+	delete(se.reloadChannels, symbol)
 	delete(se.tokenEnabled, symbol)
+	delete(se.tokenAllowShort, symbol)
+	se.positionsMu.Lock()
+	delete(se.positions, symbol)
+	se.positionsMu.Unlock()
+}
+
+// GetPositionState is the symbol's strategy's current position. Strategies don't guard their own state, so this
+// reads the copy the engine keeps whenever it persists a change, safe from any goroutine.
+func (se *SignalEngine) GetPositionState(symbol string) (helper.PositionState, bool) {
+	se.positionsMu.Lock()
+	defer se.positionsMu.Unlock()
+	state, ok := se.positions[symbol]
+	return state, ok
 }
 
 func (se *SignalEngine) Stop() {
@@ -100,6 +180,9 @@ func (se *SignalEngine) Stop() {
 }
 
 func (se *SignalEngine) run(symbol string) {
+	se.mu.Lock()
+	reloadCh := se.reloadChannels[symbol]
+	se.mu.Unlock()
 	for {
 		interval := se.getWaitInterval(symbol)
 
@@ -112,6 +195,11 @@ func (se *SignalEngine) run(symbol string) {
 			}
 			se.UpdateStrategy(update.Symbol, update.Strategy)
 			se.UpdateCandleSize(update.Symbol, update.CandleSize)
+		case <-reloadCh:
+			if se.tokenIsDisabled(symbol) {
+				return
+			}
+			se.reloadStrategy(symbol)
 		case <-time.After(interval):
 			if se.tokenIsDisabled(symbol) {
 				return
@@ -121,7 +209,7 @@ func (se *SignalEngine) run(symbol string) {
 			if se.tokenIsDisabled(symbol) || !ok {
 				return
 			}
-			se.tokenStrategies[symbol].UpdateTrailingStop(symbol, ticker)
+			se.updateTrailingStop(symbol, ticker)
 		}
 	}
 }
@@ -164,10 +252,11 @@ func (se *SignalEngine) emitSignal(symbol string) {
 	se.mu.Lock()
 	signalCh := se.signalChannels[symbol]
 	strategy := se.tokenStrategies[symbol]
-
+	strategyType := se.strategyTypes[symbol]
+	allowShort := se.tokenAllowShort[symbol]
 	se.mu.Unlock()
 
-	signal := strategy.CalculateSignal(symbol, se.exchange)
+	signal := FilterShortSide(CalculateSignal(strategy, symbol, se.exchange), allowShort)
 	ticker := time.NewTicker(100 * time.Millisecond)
 	defer ticker.Stop()
 	if se.tokenIsDisabled(symbol) {
@@ -177,6 +266,9 @@ func (se *SignalEngine) emitSignal(symbol string) {
 	case signalCh <- signal:
 		log.Printf("[SignalEngine %s] emitted %s %.2f%%\n", symbol, signal.Type.String(), signal.Percent)
 		strategy.ConfirmSignalDelivered(symbol, signal)
+		if signal.Type != enum.SignalHold {
+			se.persistPositionState(symbol, strategyType, strategy)
+		}
 		se.mu.Lock()
 		se.lastSignalAt[symbol] = time.Now()
 		se.mu.Unlock()
@@ -185,6 +277,40 @@ func (se *SignalEngine) emitSignal(symbol string) {
 	}
 }
 
+// updateTrailingStop ratchets the strategy's trailing stop and, when it moved, persists it and passes the new
+// stop on to the trader as a hold so the exchange-side bracket can follow.
+func (se *SignalEngine) updateTrailingStop(symbol string, ticker models.Ticker) {
+	se.mu.Lock()
+	strategy := se.tokenStrategies[symbol]
+	strategyType := se.strategyTypes[symbol]
+	signalCh := se.signalChannels[symbol]
+	se.mu.Unlock()
+	if strategy == nil {
+		return
+	}
+
+	before, _ := strategy.GetPositionState(symbol)
+	strategy.UpdateTrailingStop(symbol, ticker)
+	if after, _ := strategy.GetPositionState(symbol); !after.Equal(before) {
+		se.persistPositionState(symbol, strategyType, strategy)
+		stopUpdate := models.Signal{
+			Symbol:                symbol,
+			Type:                  enum.SignalHold,
+			Time:                  ticker.Time,
+			Price:                 ticker.Price,
+			TakeProfit:            after.TakeProfit,
+			StopLoss:              after.StopLoss,
+			TrailingStop:          after.TrailingStop,
+			LastTrailingStopPrice: after.LastTrailingStopPrice,
+		}
+		select {
+		case signalCh <- stopUpdate:
+		default:
+			// the trader is busy; the next ratchet carries the latest stop anyway
+		}
+	}
+}
+
 func (se *SignalEngine) tokenIsDisabled(symbol string) bool {
 	se.mu.Lock()
 	defer se.mu.Unlock()
@@ -195,4 +321,4 @@ func (se *SignalEngine) tokenIsDisabled(symbol string) bool {
 		return true
 	}
 	return false
-}
\ No newline at end of file
+}
@@ -7,38 +7,79 @@ import (
 	helper "github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler/strategy_helper"
 	enum "github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
 	models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
-	talib "github.com/markcheno/go-talib"
 	exchange "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange"
+	indicators "github.com/A-Here-And-Now/algo-trader/orchestration_api/indicators"
 )
 
+type CandlestickAggregationParams struct {
+	TsAtrMult          float64         `json:"tsAtrMult"`
+	TpAtrMult          float64         `json:"tpAtrMult"`
+	AtrLen             int             `json:"atrLen"`
+	MaLen              int             `json:"maLen"`
+	HigherTfmALen      int             `json:"higherTfMALen"`
+	HigherTf           enum.CandleSize `json:"higherTf"` // by name, e.g. CandleSize1h
+	VolumeMALen        int             `json:"volumeMALen"`
+	VolumeSpikeMul     float64         `json:"volumeSpikeMul"`
+	LongBodyAtrMul     float64         `json:"longBodyAtrMul"`
+	SmallBodyAtrMul    float64         `json:"smallBodyAtrMul"`
+	MinPatternStrength float64         `json:"minPatternStrength"`
+	MinAvgStrength     float64         `json:"minAvgStrength"`
+	SrTolerancePerc    float64         `json:"srTolerancePerc"`
+	SwingPivotLength   int             `json:"swingPivotLength"`
+}
+
+func (p CandlestickAggregationParams) Validate() error {
+	var c paramCheck
+	for name, v := range map[string]int{"atrLen": p.AtrLen, "maLen": p.MaLen, "higherTfMALen": p.HigherTfmALen, "volumeMALen": p.VolumeMALen,
+		"swingPivotLength": p.SwingPivotLength} {
+		c.positiveInt(name, v)
+	}
+	c.within("higherTf", float64(p.HigherTf), float64(enum.CandleSize1m), float64(enum.CandleSize1d))
+	for name, v := range map[string]float64{"tsAtrMult": p.TsAtrMult, "tpAtrMult": p.TpAtrMult, "volumeSpikeMul": p.VolumeSpikeMul,
+		"longBodyAtrMul": p.LongBodyAtrMul, "smallBodyAtrMul": p.SmallBodyAtrMul, "srTolerancePerc": p.SrTolerancePerc} {
+		c.positive(name, v)
+	}
+	c.within("minPatternStrength", p.MinPatternStrength, 0, 10)
+	c.within("minAvgStrength", p.MinAvgStrength, 0, 10)
+	return c.err
+}
+
 type CandlestickAggregationStrategy struct{ 
 	*helper.PositionHolder 
-	TsAtrMult float64
-	TpAtrMult float64
-	AtrLen    int
-	MaLen     int
-	HigherTfmALen int
-	VolumeMALen int
-	VolumeSpikeMul float64
-	LongBodyAtrMul float64
-	SmallBodyAtrMul float64
-	MinPatternStrength float64
-	MinAvgStrength float64
-	SrTolerancePerc float64
-	SwingPivotLength int
+	CandlestickAggregationParams
+}
+
+// GetWarmUpCandles covers the longest pattern look-back and every MA on the strategy's own series, the higher TF
+// MA falling back to it included.
+func (s *CandlestickAggregationStrategy) GetWarmUpCandles() int {
+	return max(100, s.MaLen, s.HigherTfmALen, s.VolumeMALen, s.AtrLen+1, 2*s.SwingPivotLength+1)
 }
 
 func (s *CandlestickAggregationStrategy) CalculateSignal(symbol string, exchange exchange.IExchange) models.Signal {
 	// --------------------------------------------------------------
-	// 1️⃣  Pull merged candle history (the same series the Pine‑Script uses)
+	// 1️⃣  The newest candles and the indicators, off the symbol's set
 	// --------------------------------------------------------------
-	hist := exchange.GetCandleHistory(symbol)
+	ind := exchange.GetIndicators(symbol)
+	// the patterns look back at most 4 bars
+	last := ind.Last(5)
+	atr, atrOk := ind.Atr(s.AtrLen)
+	// Volume SMA (for the volume‑spike filter)
+	volMAVal, volOk := ind.VolumeSma(s.VolumeMALen)
+	// Trend MA (simple SMA – the script uses sma)
+	trendMAVal, trendOk := ind.Sma(s.MaLen)
+	// Higher‑timeframe MA (the script's request.security on a higher TF)
+	htfMAVal, htfOk := s.getHigherTfMA(symbol, exchange, ind, last)
+	if len(last) < 5 || !atrOk || !volOk || !trendOk || !htfOk {
+		return models.Signal{Symbol: symbol, Type: enum.SignalHold, Percent: 0, Time: time.Now()}
+	}
 
-	closes := hist.GetCloses()
-	highs := hist.GetHighs()
-	lows := hist.GetLows()
-	opens := hist.GetOpens()
-	vols := hist.GetVolumes() // assume PriceActionStore can give volume; if not, pass a zero slice
+	closes := make([]float64, len(last))
+	highs := make([]float64, len(last))
+	lows := make([]float64, len(last))
+	opens := make([]float64, len(last))
+	for j, c := range last {
+		closes[j], highs[j], lows[j], opens[j] = c.Close, c.High, c.Low, c.Open
+	}
 	// Short‑hand to the last N values (more readable than repeated len‑calculations)
 	i := len(closes) - 1 // current bar index
 	i1 := i - 1          // 1 bar ago
@@ -50,36 +91,11 @@ func (s *CandlestickAggregationStrategy) CalculateSignal(symbol string, exchange
 		mintick = 0.01
 	}
 
-	if len(closes) < 100 { // enough bars for the longest look‑back (71 patterns, some need 5‑bars)
-		return models.Signal{Symbol: symbol, Type: enum.SignalHold, Percent: 0, Time: time.Now()}
-	}
-
-	// --------------------------------------------------------------
-	// 2️⃣  Indicator calculations (identical to the Pine‑Script)
-	// --------------------------------------------------------------
-	atrVals := talib.Atr(highs, lows, closes, s.AtrLen)
-	atr := atrVals[len(atrVals)-1]
-
-	// Volume SMA (for the volume‑spike filter)
-	volMA := talib.Sma(vols, s.VolumeMALen)
-	volMAVal := volMA[len(volMA)-1]
-	// Trend MA (simple SMA – the script uses sma)
-	trendMA := talib.Sma(closes, s.MaLen)
-	trendMAVal := trendMA[len(trendMA)-1]
-
-	// Higher‑timeframe MA (we approximate it by re‑sampling the same series
-	// with the requested higher TF – the simplest way is to request a “daily”
-	// series from the store; for the purpose of this port we just reuse the
-	// same data and treat it as if it were the higher TF.)
-	// In a real implementation you would call a separate request for TF‑D.
-	htfMA := talib.Sma(closes, s.HigherTfmALen)
-	htfMAVal := htfMA[len(htfMA)-1]
-
 	// ----- Trend, volume & S/R filters -----
 	isUptrend := closes[i] > trendMAVal
 	isDowntrend := closes[i] < trendMAVal
 
-	isVolumeSpike := vols[len(vols)-1] > volMAVal*s.VolumeSpikeMul
+	isVolumeSpike := last[i].Volume > volMAVal*s.VolumeSpikeMul
 
 	// defined here because doji gets checked a lot in all pattern types
 	isDoji := helper.IsDoji(opens[i], closes[i], highs[i], lows[i], atr, s.SmallBodyAtrMul, 0.1)
@@ -941,12 +957,11 @@ func (s *CandlestickAggregationStrategy) CalculateSignal(symbol string, exchange
 		avgNeutralStrength = neutralStrengthSum / float64(neutralCount)
 	}
 
-	// Support / resistance pivots (simple approximation – we just look at the most recent pivot)
-	// In Pine‑Script `ta.pivothigh(high, swingPivotLength, swingPivotLength)` returns a series
-	// that is `na` except on the pivot bar. We emulate it by scanning backwards.
-	_, pl := helper.GetHLPivot(highs, lows, s.SwingPivotLength)
+	// Support / resistance pivots – the most recent confirmed ta.pivotlow(low, swingPivotLength, swingPivotLength)
+	pivots, pivotsOk := ind.SwingPivots(s.SwingPivotLength)
+	pl := pivots.Low
 
-	isNearSupport := !math.IsNaN(pl) && closes[i] >= pl*(1-s.SrTolerancePerc) && closes[i] <= pl*(1+s.SrTolerancePerc)
+	isNearSupport := pivotsOk && !math.IsNaN(pl) && closes[i] >= pl*(1-s.SrTolerancePerc) && closes[i] <= pl*(1+s.SrTolerancePerc)
 	// isNearResistance := !math.IsNaN(ph) && closes[i] <= ph*(1+srTolerancePerc) && closes[i] >= ph*(1-srTolerancePerc)
 
 	// Follow‑through filter (we use the same rule as the script)
@@ -971,8 +986,8 @@ func (s *CandlestickAggregationStrategy) CalculateSignal(symbol string, exchange
 	// exitShort := (avgBullishStrength >= minAvgStrength && isUptrend) ||
 	// 	(avgNeutralStrength >= minAvgStrength)
 		
-	trailingStop := closes[i] - s.TsAtrMult*atrVals[i]
-	takeProfit := closes[i] + s.TpAtrMult*atrVals[i]
+	trailingStop := closes[i] - s.TsAtrMult*atr
+	takeProfit := closes[i] + s.TpAtrMult*atr
 
 	if longSignal && !s.State[symbol].InPosition { // || (s.state[symbol].inPosition && exitShort)
 		return models.Signal{
@@ -1003,3 +1018,23 @@ func (s *CandlestickAggregationStrategy) CalculateSignal(symbol string, exchange
 
 	return models.Signal{Symbol: symbol, Type: enum.SignalHold, Percent: 0, Time: time.Now()}
 }
+
+// getHigherTfMA resamples the store's base candles to HigherTf. Until the store holds HigherTfmALen candles at
+// that size, or when HigherTf is no coarser than the strategy's own candles, it falls back to the MA of the
+// strategy's own series.
+func (s *CandlestickAggregationStrategy) getHigherTfMA(symbol string, exchange exchange.IExchange, ind *indicators.Set, last []models.Candle) (float64, bool) {
+	htfSize := enum.GetTimeDurationFromCandleSize(s.HigherTf)
+	n := len(last)
+	if n >= 2 && htfSize > last[n-1].Start.Sub(last[n-2].Start) {
+		htfHist := exchange.GetResampledCandleHistory(symbol, htfSize)
+		htfCloses := htfHist.GetCloses()
+		if len(htfCloses) >= s.HigherTfmALen {
+			sum := 0.0
+			for _, c := range htfCloses[len(htfCloses)-s.HigherTfmALen:] {
+				sum += c
+			}
+			return sum / float64(s.HigherTfmALen), true
+		}
+	}
+	return ind.Sma(s.HigherTfmALen)
+}
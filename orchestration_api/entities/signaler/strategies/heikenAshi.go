@@ -1,90 +1,74 @@
 package strategies
 
 import (
-	"math"
 	"time"
 
 	helper "github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler/strategy_helper"
 	enum "github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
 	models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
-	talib "github.com/markcheno/go-talib"
 	exchange "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange"
 )
 
+type HeikenAshiParams struct {
+	AtrPeriod         int     `json:"atrPeriod"`
+	AtrLineMultiplier float64 `json:"atrLineMultiplier"`
+	TpATRMultiplier   float64 `json:"tpAtrMultiplier"`
+	SlATRMultiplier   float64 `json:"slAtrMultiplier"`
+	NumEmaPeriods     int     `json:"numEmaPeriods"`
+}
+
+func (p HeikenAshiParams) Validate() error {
+	var c paramCheck
+	c.positiveInt("atrPeriod", p.AtrPeriod)
+	c.positive("atrLineMultiplier", p.AtrLineMultiplier)
+	c.positive("tpAtrMultiplier", p.TpATRMultiplier)
+	c.positive("slAtrMultiplier", p.SlATRMultiplier)
+	c.positiveInt("numEmaPeriods", p.NumEmaPeriods)
+	return c.err
+}
+
 type HeikenAshiStrategy struct {
 	*helper.PositionHolder
-	AtrPeriod         int
-	AtrLineMultiplier float64
-	TpATRMultiplier   float64
-	SlATRMultiplier   float64
-	NumEmaPeriods     int
+	HeikenAshiParams
+}
+
+func (s *HeikenAshiStrategy) GetWarmUpCandles() int {
+	return max(s.AtrPeriod+2, s.NumEmaPeriods)
 }
 
 func (s *HeikenAshiStrategy) CalculateSignal(symbol string, exchange exchange.IExchange) models.Signal {
-	hist := exchange.GetCandleHistory(symbol)
-	haCandles := hist.GetHeikenAshiCandleHistory()
-	haCloses := haCandles.GetHeikenAshiCloses()
-	haHighs := haCandles.GetHeikenAshiHighs()
-	haLows := haCandles.GetHeikenAshiLows()
-	lows := hist.GetLows()
-	highs := hist.GetHighs()
-	closes := hist.GetCloses()
+	ind := exchange.GetIndicators(symbol)
+	candle, ok := ind.Candle()
+	// the ATR trailing stop line and the EMA trend over the Heiken Ashi candles
+	trail, trailOk := ind.AtrTrail(s.AtrPeriod, s.NumEmaPeriods, s.AtrLineMultiplier)
 	state := s.PositionHolder.State[symbol]
-
-	n := len(haCloses)
-	i := n - 1
-	if n < s.AtrPeriod+2 || n < s.NumEmaPeriods {
+	if !ok || !trailOk {
 		return models.Signal{Symbol: symbol, Type: enum.SignalHold, Percent: 0, Time: time.Now()}
 	}
 
-	// --- ATR CALCULATION (from TA-Lib) ---
-	atr := talib.Atr(haHighs, haLows, haCloses, s.AtrPeriod)
-
-	// --- EMA CALCULATION (from TA-Lib) ---
-	ema := talib.Ema(haCloses, s.NumEmaPeriods)
-
-	// --- ATR TRAILING STOP LINE ---
-	// This is a trailing stop line, not a trailing stop price.
-	// They are not the same thing, nor are they functionally related.
-	xATRTrailingStopLine := make([]float64, n)
-	for j := 1; j < n; j++ {
-		nLoss := s.AtrLineMultiplier * atr[j]
-		prev := xATRTrailingStopLine[j-1]
-
-		if haCloses[j] > prev && haCloses[j-1] > prev {
-			xATRTrailingStopLine[j] = math.Max(prev, haCloses[j]-nLoss)
-		} else if haCloses[j] < prev && haCloses[j-1] < prev {
-			xATRTrailingStopLine[j] = math.Min(prev, haCloses[j]+nLoss)
-		} else if haCloses[j] > prev {
-			xATRTrailingStopLine[j] = haCloses[j] - nLoss
-		} else {
-			xATRTrailingStopLine[j] = haCloses[j] + nLoss
-		}
-	}
-
 	// --- SIGNAL GENERATION ---
-	above := haCloses[i-1] <= xATRTrailingStopLine[i-1] && haCloses[i] > xATRTrailingStopLine[i]
-	below := haCloses[i-1] >= xATRTrailingStopLine[i-1] && haCloses[i] < xATRTrailingStopLine[i]
+	above := trail.PrevClose <= trail.PrevLine && trail.Close > trail.Line
+	below := trail.PrevClose >= trail.PrevLine && trail.Close < trail.Line
 
 	// Combine ATR stop and EMA trend
-	inUpTrend := haCloses[i] > ema[i]
-	inDownTrend := haCloses[i] < ema[i]
+	inUpTrend := trail.Close > trail.Ema
+	inDownTrend := trail.Close < trail.Ema
 
-	buySignal := haCloses[i] > xATRTrailingStopLine[i] && above && inUpTrend
-	sellSignal := haCloses[i] < xATRTrailingStopLine[i] && below && inDownTrend
+	buySignal := trail.Close > trail.Line && above && inUpTrend
+	sellSignal := trail.Close < trail.Line && below && inDownTrend
 
 	// --- ENTRY / EXIT CONDITIONS ---
 	longEntry := buySignal && !state.InPosition
-	stopLossHit := state.InPosition && lows[i] <= state.StopLoss
+	stopLossHit := state.InPosition && candle.Low <= state.StopLoss
 	atrExit := sellSignal && state.InPosition
-	takeProfitHit := highs[i] >= state.TakeProfit && state.InPosition
+	takeProfitHit := candle.High >= state.TakeProfit && state.InPosition
 	longExit := atrExit || stopLossHit || takeProfitHit
 
 	// --- UPDATE STATE & RETURN SIGNAL ---
 	if longEntry {
 		// --- STOP LOSS & TAKE PROFIT CALCULATION ---
-		stopLoss := closes[i] - (s.SlATRMultiplier * atr[i])
-		takeProfit := closes[i] + (s.TpATRMultiplier * atr[i])
+		stopLoss := candle.Close - (s.SlATRMultiplier * trail.Atr)
+		takeProfit := candle.Close + (s.TpATRMultiplier * trail.Atr)
 		return models.Signal{
 			Symbol:     symbol,
 			Type:       enum.SignalBuy,
@@ -92,7 +76,7 @@ func (s *HeikenAshiStrategy) CalculateSignal(symbol string, exchange exchange.IE
 			Time:       time.Now(),
 			StopLoss:   stopLoss,
 			TakeProfit: takeProfit,
-			Price:      closes[i],
+			Price:      candle.Close,
 		}
 	}
 	if longExit {
@@ -103,7 +87,7 @@ func (s *HeikenAshiStrategy) CalculateSignal(symbol string, exchange exchange.IE
 			Time:       time.Now(),
 			StopLoss:   0,
 			TakeProfit: 0,
-			Price:      closes[i],
+			Price:      candle.Close,
 		}
 	}
 
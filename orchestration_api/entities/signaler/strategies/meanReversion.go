@@ -7,96 +7,117 @@ import (
 	helper "github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler/strategy_helper"
 	enum "github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
 	models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
-	talib "github.com/markcheno/go-talib"
 	exchange "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange"
 )
 
+type MeanReversionParams struct {
+	RsiLen          int     `json:"rsiLen"`
+	RsiOverbought   float64 `json:"rsiOverbought"`
+	RsiOversold     float64 `json:"rsiOversold"`
+	AtrLen          int     `json:"atrLen"`
+	EmaLenLower     int     `json:"emaLenLower"`
+	EmaLenUpper     int     `json:"emaLenUpper"`
+	FvgLookback     int     `json:"fvgLookback"` // the candle the fair value gap is measured against
+	TpATRMultiplier float64 `json:"tpAtrMultiplier"`
+	SlATRMultiplier float64 `json:"slAtrMultiplier"`
+}
+
+func (p MeanReversionParams) Validate() error {
+	var c paramCheck
+	for name, v := range map[string]int{"rsiLen": p.RsiLen, "atrLen": p.AtrLen, "emaLenLower": p.EmaLenLower, "fvgLookback": p.FvgLookback} {
+		c.positiveInt(name, v)
+	}
+	c.less("emaLenLower", p.EmaLenLower, "emaLenUpper", p.EmaLenUpper)
+	c.within("rsiOverbought", p.RsiOverbought, 0, 100)
+	c.within("rsiOversold", p.RsiOversold, 0, 100)
+	if p.RsiOversold >= p.RsiOverbought {
+		c.fail("rsiOversold must be less than rsiOverbought")
+	}
+	c.positive("tpAtrMultiplier", p.TpATRMultiplier)
+	c.positive("slAtrMultiplier", p.SlATRMultiplier)
+	return c.err
+}
+
 type MeanReversionStrategy struct {
 	*helper.PositionHolder // embed – gives us .state + ConfirmSignalDelivered
-	TpATRMultiplier        float64
-	SlATRMultiplier        float64
+	MeanReversionParams
+}
+
+// GetWarmUpCandles covers the longest of the indicators and the fair value gap's lookback.
+func (s *MeanReversionStrategy) GetWarmUpCandles() int {
+	return max(s.RsiLen+1, s.AtrLen+1, s.EmaLenLower, s.EmaLenUpper, s.FvgLookback+1)
 }
 
 func (s *MeanReversionStrategy) CalculateSignal(symbol string, exchange exchange.IExchange) models.Signal {
-	const (	
-		rsiLength = 14
-		// rsiOverbought := float64(80)
-		rsiOversold    = float64(20)
-		atrLength      = 14
-		emaLengthLower = 20
-		emaLengthUpper = 100
-	)
-	fullHistory := exchange.GetCandleHistory(symbol)
-	closes := fullHistory.GetCloses()
-	highs := fullHistory.GetHighs()
-	lows := fullHistory.GetLows()
-	opens := fullHistory.GetOpens()
+	// indicators
+	ind := exchange.GetIndicators(symbol)
+	// the candle the fair value gap is measured against, through the current one
+	candles := ind.Last(s.FvgLookback + 1)
+	rsi, rsiOk := ind.Rsi(s.RsiLen)
+	atr, atrOk := ind.Atr(s.AtrLen)
+	emaLower, lowerOk := ind.Ema(s.EmaLenLower)
+	emaUpper, upperOk := ind.Ema(s.EmaLenUpper)
 
-	if len(closes) < 100 {
+	if len(candles) < s.FvgLookback+1 || !rsiOk || !atrOk || !lowerOk || !upperOk {
 		return models.Signal{Symbol: symbol, Type: enum.SignalHold, Percent: 0, Time: time.Now()}
 	}
 
-	idx := len(closes) - 1
-	lastClose := closes[idx]
-
-	// indicators
-	rsi := talib.Rsi(closes, rsiLength)
-	atr := talib.Atr(highs, lows, closes, atrLength)
-	emaLower := talib.Ema(closes, emaLengthLower)
-	emaUpper := talib.Ema(closes, emaLengthUpper)
+	gapFrom, curr := candles[0], candles[len(candles)-1]
+	lastClose := curr.Close
 
 	// entry conditions
-	rsiLongOK := rsi[idx] < rsiOversold
-	// rsiShortOK := rsi[idx] > rsiOverbought
-	// validBearishFVG := highs[idx-12] < lows[idx]
-	validBullishFVG := lows[idx-12] > highs[idx]
-	bullishSignal := validBullishFVG && lastClose > opens[idx] && rsiLongOK
-	// bearishSignal := validBearishFVG && lastClose < opens[idx] && rsiShortOK
+	rsiLongOK := rsi < s.RsiOversold
+	rsiShortOK := rsi > s.RsiOverbought
+	validBearishFVG := gapFrom.High < curr.Low
+	validBullishFVG := gapFrom.Low > curr.High
+	bullishSignal := validBullishFVG && lastClose > curr.Open && rsiLongOK
+	bearishSignal := validBearishFVG && lastClose < curr.Open && rsiShortOK
 
 	ps := s.State[symbol]
 
 	// If in a position, only allow the opposing signal when TP/SL is hit
 	if ps.InPosition {
-		// if ps.side == enum.SignalBuy { // currently long, only SELL can trigger
-		if lastClose >= ps.TakeProfit || lastClose <= ps.StopLoss {
-			log.Println("MeanReversionStrategy:", symbol, "Long exit (TP/SL)")
+		if ps.Side != enum.SignalShort { // currently long, only SELL can trigger
+			if lastClose >= ps.TakeProfit || lastClose <= ps.StopLoss {
+				log.Println("MeanReversionStrategy:", symbol, "Long exit (TP/SL)")
+				ps = &helper.PositionState{}
+				s.State[symbol] = ps
+				return models.Signal{Symbol: symbol, Type: enum.SignalSell, Percent: 100, Time: time.Now()}
+			}
+			return models.Signal{Symbol: symbol, Type: enum.SignalHold, Percent: 0, Time: time.Now()}
+		}
+		// currently short, only COVER can trigger
+		if lastClose <= ps.TakeProfit || lastClose >= ps.StopLoss {
+			log.Println("MeanReversionStrategy:", symbol, "Short exit (TP/SL)")
 			ps = &helper.PositionState{}
 			s.State[symbol] = ps
-			return models.Signal{Symbol: symbol, Type: enum.SignalSell, Percent: 100, Time: time.Now()}
+			return models.Signal{Symbol: symbol, Type: enum.SignalCover, Percent: 100, Time: time.Now()}
 		}
 		return models.Signal{Symbol: symbol, Type: enum.SignalHold, Percent: 0, Time: time.Now()}
-		// }
-		// // currently short, only BUY can trigger
-		// if lastClose <= ps.takeProfit || lastClose >= ps.stopLoss {
-		// 	log.Println("MeanReversionStrategy:", symbol, "Short exit (TP/SL)")
-		// 	ps = positionState{}
-		// 	s.state[symbol] = ps
-		// 	return models.Signal{Symbol: symbol, Type: enum.SignalBuy, Percent: 100, Time: time.Now()}
-		// }
-		// return models.Signal{Symbol: symbol, Type: enum.SignalHold, Percent: 0, Time: time.Now()}
 	}
 
-	// Not in a position: consider entries
-	if bullishSignal && lastClose < emaLower[idx] && lastClose < emaUpper[idx] {
+	// Not in a position: consider entries. The levels ride on the signal, ConfirmSignalDelivered rebuilds the
+	// state from it
+	if bullishSignal && lastClose < emaLower && lastClose < emaUpper {
 		ps.Side = enum.SignalBuy
 		ps.EntryPrice = lastClose
-		ps.TakeProfit = lastClose + atr[idx]*s.TpATRMultiplier
-		ps.StopLoss = lastClose - atr[idx]*s.SlATRMultiplier
+		ps.TakeProfit = lastClose + atr*s.TpATRMultiplier
+		ps.StopLoss = lastClose - atr*s.SlATRMultiplier
 		s.State[symbol] = ps
 		log.Println("MeanReversionStrategy:", symbol, "Long entry")
-		return models.Signal{Symbol: symbol, Type: enum.SignalBuy, Percent: 100, Time: time.Now()}
+		return models.Signal{Symbol: symbol, Type: enum.SignalBuy, Percent: 100, Time: time.Now(), TakeProfit: ps.TakeProfit, StopLoss: ps.StopLoss}
 	}
 
-	// if bearishSignal && lastClose > emaUpper[idx] && lastClose > emaLower[idx] {
-	// 	ps.inPosition = true
-	// 	ps.side = enum.SignalSell
-	// 	ps.entryPrice = lastClose
-	// 	ps.takeProfit = lastClose - atr[idx]*s.tpATRMultiplier
-	// 	ps.stopLoss = lastClose + atr[idx]*s.slATRMultiplier
-	// 	s.state[symbol] = ps
-	// 	log.Println("MeanReversionStrategy:", symbol, "Short entry")
-	// 	return models.Signal{Symbol: symbol, Type: enum.SignalSell, Percent: 100, Time: time.Now()}
-	// }
+	// traders that aren't allowed to short ignore this
+	if bearishSignal && lastClose > emaUpper && lastClose > emaLower {
+		ps.Side = enum.SignalShort
+		ps.EntryPrice = lastClose
+		ps.TakeProfit = lastClose - atr*s.TpATRMultiplier
+		ps.StopLoss = lastClose + atr*s.SlATRMultiplier
+		s.State[symbol] = ps
+		log.Println("MeanReversionStrategy:", symbol, "Short entry")
+		return models.Signal{Symbol: symbol, Type: enum.SignalShort, Percent: 100, Time: time.Now(), TakeProfit: ps.TakeProfit, StopLoss: ps.StopLoss}
+	}
 
 	return models.Signal{Symbol: symbol, Type: enum.SignalHold, Percent: 0, Time: time.Now()}
 }
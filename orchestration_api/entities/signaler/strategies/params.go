@@ -0,0 +1,47 @@
+package strategies
+
+import (
+	"fmt"
+	"slices"
+)
+
+// paramCheck keeps the first parameter a Validate finds out of range, so each check reads as one line.
+type paramCheck struct {
+	err error
+}
+
+func (c *paramCheck) fail(format string, args ...any) {
+	if c.err == nil {
+		c.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (c *paramCheck) positiveInt(name string, v int) {
+	if v <= 0 {
+		c.fail("%s must be positive", name)
+	}
+}
+
+func (c *paramCheck) positive(name string, v float64) {
+	if v <= 0 {
+		c.fail("%s must be positive", name)
+	}
+}
+
+func (c *paramCheck) within(name string, v float64, lo float64, hi float64) {
+	if v < lo || v > hi {
+		c.fail("%s must be within [%v, %v]", name, lo, hi)
+	}
+}
+
+func (c *paramCheck) oneOf(name string, v string, options ...string) {
+	if !slices.Contains(options, v) {
+		c.fail("%s must be one of %v", name, options)
+	}
+}
+
+func (c *paramCheck) less(name string, v int, otherName string, other int) {
+	if v >= other {
+		c.fail("%s must be less than %s", name, otherName)
+	}
+}
@@ -10,12 +10,30 @@ import (
 	exchange "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange"
 )
 
+type RenkoCandlesticksParams struct {
+	AtrLen            int     `json:"atrLen"`
+	StopLossPct       float64 `json:"stopLossPct"`
+	TakeProfitPct     float64 `json:"takeProfitPct"`
+	BrickSizeConstant float64 `json:"brickSizeConstant"`
+}
+
+func (p RenkoCandlesticksParams) Validate() error {
+	var c paramCheck
+	c.positiveInt("atrLen", p.AtrLen)
+	c.within("stopLossPct", p.StopLossPct, 0.01, 100)
+	c.positive("takeProfitPct", p.TakeProfitPct)
+	c.positive("brickSizeConstant", p.BrickSizeConstant)
+	return c.err
+}
+
 type RenkoCandlesticksStrategy struct{ 
 	*helper.PositionHolder
-	AtrLen int
-	StopLossPct float64
-	TakeProfitPct float64
-	BrickSizeConstant float64
+	RenkoCandlesticksParams
+}
+
+// GetWarmUpCandles covers the ATR the brick size comes from, the bricks are built once and never resized.
+func (s *RenkoCandlesticksStrategy) GetWarmUpCandles() int {
+	return s.AtrLen + 1
 }
 
 func (s *RenkoCandlesticksStrategy) CalculateSignal(symbol string, exchange exchange.IExchange) models.Signal {
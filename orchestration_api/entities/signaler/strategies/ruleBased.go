@@ -0,0 +1,177 @@
+package strategies
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	rules "github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler/rules"
+	helper "github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler/strategy_helper"
+	enum "github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	exchange "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange"
+	models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+// RuleBasedParams is a whole strategy written as rules expressions (see the rules package). Entry opens a long and
+// Exit closes it, ShortEntry and ShortExit do the same for a short; leave them empty for long-only. The levels are
+// distances from the entry's close, e.g. "2 * atr(14)", set on the side of the price that fits the position.
+type RuleBasedParams struct {
+	Name         string  `json:"name"` // for the logs
+	Entry        string  `json:"entry"`
+	Exit         string  `json:"exit"`
+	ShortEntry   string  `json:"shortEntry"`
+	ShortExit    string  `json:"shortExit"`
+	TakeProfit   string  `json:"takeProfit"`
+	StopLoss     string  `json:"stopLoss"`
+	TrailingStop string  `json:"trailingStop"`
+	Percent      float64 `json:"percent"` // entry size, percent of allocated funds
+}
+
+func (p RuleBasedParams) Validate() error {
+	var c paramCheck
+	c.within("percent", p.Percent, 0.01, 100)
+	if c.err != nil {
+		return c.err
+	}
+	if p.Entry == "" && p.ShortEntry == "" {
+		return fmt.Errorf("entry or shortEntry is required")
+	}
+	if p.Entry != "" && p.Exit == "" && p.TakeProfit == "" && p.StopLoss == "" && p.TrailingStop == "" {
+		return fmt.Errorf("a long needs an exit rule or a take profit or stop")
+	}
+	if p.ShortEntry != "" && p.ShortExit == "" && p.TakeProfit == "" && p.StopLoss == "" && p.TrailingStop == "" {
+		return fmt.Errorf("a short needs a shortExit rule or a take profit or stop")
+	}
+	_, err := compileRules(p)
+	return err
+}
+
+// compiledRules are RuleBasedParams' expressions, nil where the params leave one empty.
+type compiledRules struct {
+	entry, exit, shortEntry, shortExit *rules.Expression
+	takeProfit, stopLoss, trailingStop *rules.Expression
+}
+
+func compileRules(p RuleBasedParams) (compiledRules, error) {
+	var compiled compiledRules
+	for _, rule := range []struct {
+		name   string
+		src    string
+		target **rules.Expression
+	}{
+		{"entry", p.Entry, &compiled.entry},
+		{"exit", p.Exit, &compiled.exit},
+		{"shortEntry", p.ShortEntry, &compiled.shortEntry},
+		{"shortExit", p.ShortExit, &compiled.shortExit},
+		{"takeProfit", p.TakeProfit, &compiled.takeProfit},
+		{"stopLoss", p.StopLoss, &compiled.stopLoss},
+		{"trailingStop", p.TrailingStop, &compiled.trailingStop},
+	} {
+		if rule.src == "" {
+			continue
+		}
+		expr, err := rules.Compile(rule.src)
+		if err != nil {
+			return compiledRules{}, fmt.Errorf("%s: %w", rule.name, err)
+		}
+		*rule.target = expr
+	}
+	return compiled, nil
+}
+
+func (c compiledRules) all() []*rules.Expression {
+	return []*rules.Expression{c.entry, c.exit, c.shortEntry, c.shortExit, c.takeProfit, c.stopLoss, c.trailingStop}
+}
+
+type RuleBasedStrategy struct {
+	*helper.PositionHolder
+	RuleBasedParams
+	compiled compiledRules
+}
+
+// NewRuleBasedStrategy compiles params, which have been validated. Should a rule not compile all the same, it is
+// logged and the strategy holds.
+func NewRuleBasedStrategy(params RuleBasedParams) *RuleBasedStrategy {
+	compiled, err := compileRules(params)
+	if err != nil {
+		log.Printf("RuleBasedStrategy: %s: %v, holding", params.Name, err)
+	}
+	return &RuleBasedStrategy{PositionHolder: helper.NewPositionHolder(), RuleBasedParams: params, compiled: compiled}
+}
+
+func (s *RuleBasedStrategy) GetWarmUpCandles() int {
+	lookback := 0
+	for _, expr := range s.compiled.all() {
+		if expr != nil {
+			lookback = max(lookback, expr.GetLookback())
+		}
+	}
+	return lookback + 1
+}
+
+func (s *RuleBasedStrategy) CalculateSignal(symbol string, exchange exchange.IExchange) models.Signal {
+	hold := models.Signal{Symbol: symbol, Type: enum.SignalHold, Percent: 0, Time: time.Now()}
+	hist := exchange.GetCandleHistory(symbol)
+	bars := rules.Bars{Open: hist.GetOpens(), High: hist.GetHighs(), Low: hist.GetLows(), Close: hist.GetCloses(), Volume: hist.GetVolumes()}
+	n := len(bars.Close)
+	if n < s.GetWarmUpCandles() {
+		return hold
+	}
+	closeCurr := bars.Close[n-1]
+
+	state := s.PositionHolder.State[symbol]
+	if state != nil && state.InPosition {
+		vars := rules.Vars{EntryPrice: state.EntryPrice}
+		if state.Side == enum.SignalShort {
+			isReachedTakeProfit := state.TakeProfit > 0 && closeCurr <= state.TakeProfit
+			isReachedStop := (state.StopLoss > 0 && closeCurr >= state.StopLoss) || (state.TrailingStop > 0 && closeCurr >= state.TrailingStop)
+			if isReachedTakeProfit || isReachedStop || s.isTrue(s.compiled.shortExit, bars, vars) {
+				return models.Signal{Symbol: symbol, Type: enum.SignalCover, Percent: 100, Time: time.Now(), Price: closeCurr}
+			}
+			return hold
+		}
+		isReachedTakeProfit := state.TakeProfit > 0 && closeCurr >= state.TakeProfit
+		isReachedStop := (state.StopLoss > 0 && closeCurr <= state.StopLoss) || (state.TrailingStop > 0 && closeCurr <= state.TrailingStop)
+		if isReachedTakeProfit || isReachedStop || s.isTrue(s.compiled.exit, bars, vars) {
+			return models.Signal{Symbol: symbol, Type: enum.SignalSell, Percent: 100, Time: time.Now(), Price: closeCurr}
+		}
+		return hold
+	}
+
+	for _, side := range []enum.SignalType{enum.SignalBuy, enum.SignalShort} {
+		entry, direction := s.compiled.entry, 1.0
+		if side == enum.SignalShort {
+			entry, direction = s.compiled.shortEntry, -1.0
+		}
+		if !s.isTrue(entry, bars, rules.Vars{}) {
+			continue
+		}
+		return models.Signal{
+			Symbol:       symbol,
+			Type:         side,
+			Percent:      s.Percent,
+			Time:         time.Now(),
+			Price:        closeCurr,
+			TakeProfit:   s.getLevel(s.compiled.takeProfit, bars, closeCurr, direction),
+			StopLoss:     s.getLevel(s.compiled.stopLoss, bars, closeCurr, -direction),
+			TrailingStop: s.getLevel(s.compiled.trailingStop, bars, closeCurr, -direction),
+		}
+	}
+	return hold
+}
+
+func (s *RuleBasedStrategy) isTrue(expr *rules.Expression, bars rules.Bars, vars rules.Vars) bool {
+	return expr != nil && expr.IsTrue(bars, vars)
+}
+
+// getLevel puts the level's distance on the direction side of price, 0 (no level) when there is none.
+func (s *RuleBasedStrategy) getLevel(expr *rules.Expression, bars rules.Bars, price float64, direction float64) float64 {
+	if expr == nil {
+		return 0
+	}
+	distance := expr.Eval(bars, rules.Vars{})
+	if !(distance > 0) {
+		return 0
+	}
+	return max(price+direction*distance, 0)
+}
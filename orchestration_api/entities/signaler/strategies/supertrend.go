@@ -1,101 +1,66 @@
 package strategies
 
 import (
-	"math"
 	"time"
 
 	helper "github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler/strategy_helper"
 	enum "github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
 	models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
-	talib "github.com/markcheno/go-talib"
 	exchange "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange"
 )
 
+type SupertrendParams struct {
+	AtrPeriod  int     `json:"atrPeriod"`
+	Factor     float64 `json:"factor"`
+	UseVolFilt bool    `json:"useVolFilt"`
+	VolLen     int     `json:"volLen"`
+	TsAtrMult  float64 `json:"tsAtrMult"`
+	TpAtrMult  float64 `json:"tpAtrMult"`
+}
+
+func (p SupertrendParams) Validate() error {
+	var c paramCheck
+	c.positiveInt("atrPeriod", p.AtrPeriod)
+	c.positive("factor", p.Factor)
+	c.positiveInt("volLen", p.VolLen)
+	c.positive("tsAtrMult", p.TsAtrMult)
+	c.positive("tpAtrMult", p.TpAtrMult)
+	return c.err
+}
+
 type SupertrendStrategy struct {
 	*helper.PositionHolder
-	AtrPeriod   int
-	Factor      float64
-	UseVolFilt  bool
-	VolLen      int
-	TsAtrMult   float64
-	TpAtrMult   float64
+	SupertrendParams
 }
 
-func (s *SupertrendStrategy) CalculateSignal(symbol string, exchange exchange.IExchange) models.Signal {
-	hist := exchange.GetCandleHistory(symbol)
-	highs := hist.GetHighs()
-	lows := hist.GetLows()
-	closes := hist.GetCloses()
-	volumes := hist.GetVolumes()
+func (s *SupertrendStrategy) GetWarmUpCandles() int {
+	return max(2*s.AtrPeriod, s.VolLen)
+}
 
-	n := len(closes)
-	if n < 2*s.AtrPeriod || n < s.VolLen {
+func (s *SupertrendStrategy) CalculateSignal(symbol string, exchange exchange.IExchange) models.Signal {
+	ind := exchange.GetIndicators(symbol)
+	candle, ok := ind.Candle()
+	st, stOk := ind.Supertrend(s.AtrPeriod, s.Factor)
+	atrVal, atrOk := ind.Atr(s.AtrPeriod)
+	volMA, volOk := ind.VolumeSma(s.VolLen)
+	if !ok || !stOk || !atrOk || !volOk {
 		return models.Signal{Symbol: symbol, Type: enum.SignalHold, Percent: 0, Time: time.Now()}
 	}
 
-	// --- ATR calculation ---
-	atr := talib.Atr(highs, lows, closes, s.AtrPeriod)
-
-	// --- Supertrend calculation ---
-	W := 2 * s.AtrPeriod
-	if W > n {
-		W = n
-	}
-	start := n - W
-
-	up := make([]float64, W)
-	dn := make([]float64, W)
-	trend := make([]int, W)
-
-	for i := 0; i < W; i++ {
-		idx := start + i
-
-		src := (highs[idx] + lows[idx]) / 2.0
-		up[i] = src - s.Factor*atr[idx]
-		dn[i] = src + s.Factor*atr[idx]
-
-		if i == 0 {
-			trend[i] = 1 // initial trend bullish
-			continue
-		}
-
-		// sticky band adjustments
-		if closes[idx-1] > up[i-1] {
-			up[i] = math.Max(up[i], up[i-1])
-		}
-		if closes[idx-1] < dn[i-1] {
-			dn[i] = math.Min(dn[i], dn[i-1])
-		}
-
-		// trend flip logic
-		if trend[i-1] == -1 && closes[idx] > dn[i-1] {
-			trend[i] = 1
-		} else if trend[i-1] == 1 && closes[idx] < up[i-1] {
-			trend[i] = -1
-		} else {
-			trend[i] = trend[i-1]
-		}
-	}
-
 	// --- Volume filter ---
-	volMA := talib.Sma(volumes, s.VolLen)
-	volFilter := volumes[n-1] > volMA[len(volMA)-1]
+	volFilter := candle.Volume > volMA
 
 	// --- Current state ---
-	closeCurr := closes[n-1]
+	closeCurr := candle.Close
 	state := s.PositionHolder.State[symbol]
 	inPosition := state.InPosition
 
-	atrVal := atr[len(atr)-1]
 	trailingStop := closeCurr - s.TsAtrMult*atrVal
 	takeProfit := closeCurr + s.TpAtrMult*atrVal
 
 	// --- Entry / Exit ---
-	last := W - 1
-	prev := W - 2
-
-	buyFlip := trend[last] == 1 && trend[prev] == -1
-	sellFlip := trend[last] == -1 && trend[prev] == 1
+	buyFlip := st.Flipped && st.Trend == 1
+	sellFlip := st.Flipped && st.Trend == -1
 
 	// Buy if supertrend flips bullish and not already in a position
 	if buyFlip && !inPosition && (!s.UseVolFilt || volFilter) {
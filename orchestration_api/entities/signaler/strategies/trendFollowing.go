@@ -6,31 +6,58 @@ import (
 	helper "github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler/strategy_helper"
 	enum "github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
 	models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
-	talib "github.com/markcheno/go-talib"
 	exchange "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange"
 )
 
+type TrendFollowingParams struct {
+	MaType          string  `json:"maType"` // SMA, EMA or SMMA
+	ShortMALen      int     `json:"shortMALen"`
+	LongMALen       int     `json:"longMALen"`
+	BbLen           int     `json:"bbLen"`
+	BbMul           float64 `json:"bbMul"`
+	RsiLen          int     `json:"rsiLen"`
+	RsiLongTh       float64 `json:"rsiLongTh"`
+	RsiShortTh      float64 `json:"rsiShortTh"`
+	MacdFastLen     int     `json:"macdFastLen"`
+	MacdSlowLen     int     `json:"macdSlowLen"`
+	MacdSignalLen   int     `json:"macdSignalLen"`
+	StochLen        int     `json:"stochLen"`
+	StochSmooth     int     `json:"stochSmooth"`
+	StochOverbought float64 `json:"stochOverbought"`
+	StochOversold   float64 `json:"stochOversold"`
+	AdxLen          int     `json:"adxLen"`
+	AdxThreshold    float64 `json:"adxThreshold"`
+	TsAtrMult       float64 `json:"tsAtrMult"`
+	TpAtrMult       float64 `json:"tpAtrMult"`
+}
+
+func (p TrendFollowingParams) Validate() error {
+	var c paramCheck
+	c.oneOf("maType", p.MaType, "SMA", "EMA", "SMMA")
+	for name, v := range map[string]int{"shortMALen": p.ShortMALen, "bbLen": p.BbLen, "rsiLen": p.RsiLen, "macdFastLen": p.MacdFastLen,
+		"macdSignalLen": p.MacdSignalLen, "stochLen": p.StochLen, "stochSmooth": p.StochSmooth, "adxLen": p.AdxLen} {
+		c.positiveInt(name, v)
+	}
+	c.less("shortMALen", p.ShortMALen, "longMALen", p.LongMALen)
+	c.less("macdFastLen", p.MacdFastLen, "macdSlowLen", p.MacdSlowLen)
+	for name, v := range map[string]float64{"rsiLongTh": p.RsiLongTh, "rsiShortTh": p.RsiShortTh, "stochOverbought": p.StochOverbought,
+		"stochOversold": p.StochOversold, "adxThreshold": p.AdxThreshold} {
+		c.within(name, v, 0, 100)
+	}
+	c.positive("bbMul", p.BbMul)
+	c.positive("tsAtrMult", p.TsAtrMult)
+	c.positive("tpAtrMult", p.TpAtrMult)
+	return c.err
+}
+
 type TrendFollowingStrategy struct{ 
 	*helper.PositionHolder 
-	MaType           string
-	ShortMALen       int
-	LongMALen        int
-	BbLen            int
-	BbMul            float64
-	RsiLen           int
-	RsiLongTh        float64
-	RsiShortTh       float64
-	MacdFastLen      int
-	MacdSlowLen      int
-	MacdSignalLen    int
-	StochLen         int
-	StochSmooth      int
-	StochOverbought  float64
-	StochOversold    float64
-	AdxLen           int
-	AdxThreshold     float64
-	TsAtrMult        float64
-	TpAtrMult        float64
+	TrendFollowingParams
+}
+
+// GetWarmUpCandles covers the slowest of the MAs, the MACD signal line and the ADX smoothing.
+func (s *TrendFollowingStrategy) GetWarmUpCandles() int {
+	return max(50, s.LongMALen, s.BbLen+1, s.MacdSlowLen+s.MacdSignalLen, 2*s.AdxLen, s.StochLen+s.StochSmooth)
 }
 
 func (s *TrendFollowingStrategy) CalculateSignal(symbol string, exchange exchange.IExchange) models.Signal {
@@ -54,86 +81,58 @@ func (s *TrendFollowingStrategy) CalculateSignal(symbol string, exchange exchang
 	tsAtrMult := s.TsAtrMult
 	tpAtrMult := s.TpAtrMult
 	// --------------------------------------------------------------
-	// 1️⃣  Pull the merged candle history (exactly what the script uses)
+	// 1️⃣  Read every indicator off the symbol's set
 	// --------------------------------------------------------------
-	hist := exchange.GetCandleHistory(symbol)
-
-	closes := hist.GetCloses()
-	highs := hist.GetHighs()
-	lows := hist.GetLows()
-
-	// Short‑hand to the last N values (more readable than repeated len‑calculations)
-	i := len(closes) - 1 // current bar index
-
-	// opens are only needed for the MA‑cross check (the script does not use them)
-	// but we keep the variable for completeness
-	_ = hist.GetOpens()
+	ind := exchange.GetIndicators(symbol)
+	candle, ok := ind.Candle()
+	shortMA, shortOk := ind.Ma(maType, shortMALen)
+	longMA, longOk := ind.Ma(maType, longMALen)
+	prevShortMA, prevShortOk := ind.PrevMa(maType, shortMALen)
+	prevLongMA, prevLongOk := ind.PrevMa(maType, longMALen)
+	bands, bbOk := ind.Bollinger(bbLen, bbMul) // population std‑dev
+	atr, atrOk := ind.Atr(bbLen)
+	rsi, rsiOk := ind.Rsi(rsiLen)
+	macd, macdOk := ind.Macd(macdFastLen, macdSlowLen, macdSignalLen)
+	stoch, stochOk := ind.Stoch(stochLen, stochSmooth, stochSmooth) // slow‑K and D both smoothed over stochSmooth
+	adx, adxOk := ind.Adx(adxLen)
 
 	// Guard against not‑enough data
-	if len(closes) < 50 {
+	if !ok || !shortOk || !longOk || !prevShortOk || !prevLongOk || !bbOk || !atrOk || !rsiOk || !macdOk || !stochOk || !adxOk {
 		return models.Signal{Symbol: symbol, Type: enum.SignalHold, Percent: 0, Time: time.Now()}
 	}
+	closeCurr := candle.Close
 
 	// --------------------------------------------------------------
-	// 2️⃣  Build every indicator exactly like the Pine‑Script
+	// 2️⃣  Build every filter exactly like the Pine‑Script
 	// --------------------------------------------------------------
-	shortMA := helper.CalcMA(closes, shortMALen, maType)
-	longMA := helper.CalcMA(closes, longMALen, maType)
-
-	// ---- Bollinger Bands -------------------------------------------------
-	basis := helper.Sma(closes, bbLen)
-	dev := talib.StdDev(closes, bbLen, 0) // population std‑dev
-	upperBand := make([]float64, len(basis))
-	lowerBand := make([]float64, len(basis))
-	for i := range basis {
-		upperBand[i] = basis[i] + bbMul*dev[i]
-		lowerBand[i] = basis[i] - bbMul*dev[i]
-	}
-
 	// ---- Volatility filter (wide enough BB) ----------------------------
-	atrVals := talib.Atr(highs, lows, closes, bbLen)
-	volatilityFilter := (upperBand[len(upperBand)-1] - lowerBand[len(lowerBand)-1]) >
-		(atrVals[len(atrVals)-1] * bbMul)
+	volatilityFilter := (bands.Upper - bands.Lower) > (atr * bbMul)
 
 	// ---- BB trend filter -------------------------------------------------
-	bbTrendLong := closes[i] > basis[len(basis)-1]
-	bbTrendShort := closes[i] < basis[len(basis)-1]
+	bbTrendLong := closeCurr > bands.Middle
+	bbTrendShort := closeCurr < bands.Middle
 
 	// ---- RSI ------------------------------------------------------------
-	rsiVals := talib.Rsi(closes, rsiLen)
-	rsiLong := rsiVals[len(rsiVals)-1] > rsiLongTh
-	rsiShort := rsiVals[len(rsiVals)-1] < rsiShortTh
+	rsiLong := rsi > rsiLongTh
+	rsiShort := rsi < rsiShortTh
 
 	// ---- MACD -----------------------------------------------------------
-	macdLine, macdSignal, _ := talib.Macd(closes, macdFastLen, macdSlowLen, macdSignalLen)
-	macdLong := macdLine[len(macdLine)-1] > macdSignal[len(macdSignal)-1]
-	macdShort := macdLine[len(macdLine)-1] < macdSignal[len(macdSignal)-1]
+	macdLong := macd.Macd > macd.Signal
+	macdShort := macd.Macd < macd.Signal
 
 	// ---- Stochastic ------------------------------------------------------
-	stochK, stochD := talib.Stoch(highs, lows, closes,
-		stochLen,    // fast‑K period (14 by default)
-		stochSmooth, // slow‑K period (3 by default)
-		talib.SMA,   // MA type for slow‑K (SMA)
-		stochSmooth, // D period (same as smoothing)
-		talib.SMA)   // MA type for D (SMA)
-
-	// we only need the *last* value of each series for the current bar
-	kLast := stochK[len(stochK)-1]
-	dLast := stochD[len(stochD)-1]
-
 	// Stochastic filter – note the direction of the inequalities
-	stochLong := kLast > stochOversold && kLast > dLast    // oversold + rising
-	stochShort := kLast < stochOverbought && kLast < dLast // overbought + falling
+	stochLong := stoch.K > stochOversold && stoch.K > stoch.D    // oversold + rising
+	stochShort := stoch.K < stochOverbought && stoch.K < stoch.D // overbought + falling
 
 	// ---- ADX -------------------------------------------------------------
-	adxVals := talib.Adx(highs, lows, closes, adxLen)
-	adxOk := adxVals[len(adxVals)-1] > adxThreshold
+	adxOk = adx > adxThreshold
 
 	// --------------------------------------------------------------
 	// 3️⃣  Raw MA‑cross conditions (same as Pine)
 	// --------------------------------------------------------------
-	buyCross := helper.CrossOver(shortMA, longMA)
-	sellCross := helper.CrossUnder(shortMA, longMA)
+	buyCross := prevShortMA < prevLongMA && shortMA > longMA
+	sellCross := prevShortMA > prevLongMA && shortMA < longMA
 
 	// --------------------------------------------------------------
 	// 4️⃣  Apply *all* filters (volatility, BB‑trend, RSI, MACD,
@@ -149,8 +148,8 @@ func (s *TrendFollowingStrategy) CalculateSignal(symbol string, exchange exchang
 
 	state := s.PositionHolder.State[symbol]
 	inPosition := state.InPosition
-	trailingStop := closes[i] - tsAtrMult * atrVals[i]
-	takeProfit := closes[i] + tpAtrMult * atrVals[i]
+	trailingStop := closeCurr - tsAtrMult * atr
+	takeProfit := closeCurr + tpAtrMult * atr
 
 	if buyFiltered && !inPosition {
 		return models.Signal{
@@ -160,19 +159,19 @@ func (s *TrendFollowingStrategy) CalculateSignal(symbol string, exchange exchang
 			Time:    time.Now(),
 			TrailingStop: trailingStop,
 			TakeProfit:   takeProfit,
-			Price:        closes[i],
+			Price:        closeCurr,
 		}
 	} 
 	if inPosition {
-		isReachedTakeProfit := closes[i] >= s.PositionHolder.State[symbol].TakeProfit
-		isReachedTrailingStop := closes[i] <= s.PositionHolder.State[symbol].TrailingStop
+		isReachedTakeProfit := closeCurr >= s.PositionHolder.State[symbol].TakeProfit
+		isReachedTrailingStop := closeCurr <= s.PositionHolder.State[symbol].TrailingStop
 		if isReachedTakeProfit || isReachedTrailingStop || sellFiltered {
 			return models.Signal{
 				Symbol:  symbol,
 				Type:    enum.SignalSell,
 				Percent: 100,
 				Time:    time.Now(),
-				Price:   closes[i],	
+				Price:   closeCurr,	
 			}
 		}
 	}
@@ -1,10 +1,13 @@
 package signaler
 
 import (
+	"time"
+
 	strategies "github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler/strategies"
 	helper "github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler/strategy_helper"
 	enum "github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
 	exchange "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange"
+	indicators "github.com/A-Here-And-Now/algo-trader/orchestration_api/indicators"
 	models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
 )
 
@@ -13,58 +16,115 @@ type Strategy interface {
 	ConfirmSignalDelivered(symbol string, signal models.Signal)
 	CalculateSignal(symbol string, exchange exchange.IExchange) models.Signal
 	UpdateTrailingStop(symbol string, ticker models.Ticker)
+	GetPositionState(symbol string) (helper.PositionState, bool)
+	RestorePositionState(symbol string, state helper.PositionState)
+	ApplyPyramidRules(symbol string, ind *indicators.Set, signal models.Signal) models.Signal
+	// GetWarmUpCandles is how many candles the strategy's longest look-back needs before its signals mean anything.
+	GetWarmUpCandles() int
+}
+
+// CalculateSignal is the strategy's signal with its add and trim rules applied on top, what the signal engine
+// and the backtester both deliver. Until the symbol's indicators have seen the strategy's warm-up it holds. Renko,
+// the rule-based and plugin strategies still read the candle history, which the store sizes to the warm-up too.
+func CalculateSignal(strategy Strategy, symbol string, exchange exchange.IExchange) models.Signal {
+	ind := exchange.GetIndicators(symbol)
+	if ind.Count() < strategy.GetWarmUpCandles() {
+		return models.Signal{Symbol: symbol, Type: enum.SignalHold, Percent: 0, Time: time.Now()}
+	}
+	signal := strategy.CalculateSignal(symbol, exchange)
+	return strategy.ApplyPyramidRules(symbol, ind, signal)
+}
+
+// GetWarmUpCandles is the warm-up of a fresh instance of strategy, for sizing the history before it exists.
+func GetWarmUpCandles(strategy enum.Strategy) int {
+	if s := NewStrategy(strategy); s != nil {
+		return s.GetWarmUpCandles()
+	}
+	return 0
+}
+
+// FilterShortSide turns a short entry or cover into a hold when shorting is off, before it is delivered, so the
+// strategy only confirms positions the trader takes.
+func FilterShortSide(signal models.Signal, allowShort bool) models.Signal {
+	if allowShort || !signal.Type.IsShortSide() {
+		return signal
+	}
+	return models.Signal{Symbol: signal.Symbol, Type: enum.SignalHold, Time: signal.Time, Price: signal.Price}
 }
 
 /* ------------------------------------------------------------------------ FACTORY ------------------------------------------------------------------------ */
 func NewStrategy(strategy enum.Strategy) Strategy {
+	return NewStrategyWithParams(strategy, GetDefaultParams(strategy))
+}
+
+// NewStrategyWithParams builds strategy around params, which must be that strategy's params type. Nil params
+// are its defaults, as are an ensemble's members'.
+func NewStrategyWithParams(strategy enum.Strategy, params StrategyParams) Strategy {
+	return NewStrategyWithMemberParams(strategy, params, nil)
+}
+
+// NewStrategyWithMemberParams is NewStrategyWithParams with memberParams looking up the params an ensemble's
+// members run with. Nil memberParams are the members' defaults.
+func NewStrategyWithMemberParams(strategy enum.Strategy, params StrategyParams, memberParams func(enum.Strategy) StrategyParams) Strategy {
+	if memberParams == nil {
+		memberParams = GetDefaultParams
+	}
+	if params == nil {
+		params = GetDefaultParams(strategy)
+	}
 	switch strategy {
 	case enum.MeanReversion:
 		return &strategies.MeanReversionStrategy{
-			PositionHolder:  helper.NewPositionHolder(),
-			TpATRMultiplier: 3.50, SlATRMultiplier: 1.75,
+			PositionHolder:      helper.NewPositionHolder(),
+			MeanReversionParams: params.(strategies.MeanReversionParams),
 		}
 	case enum.TrendFollowing:
 		return &strategies.TrendFollowingStrategy{
-			PositionHolder: helper.NewPositionHolder(),
-			MaType: "SMA", ShortMALen: 9, LongMALen: 21, BbLen: 20, BbMul: 2.0, RsiLen: 14, RsiLongTh: 55.0, RsiShortTh: 45.0, MacdFastLen: 12, MacdSlowLen: 26,
-			MacdSignalLen: 9, StochLen: 14, StochSmooth: 3, StochOverbought: 80.0, StochOversold: 20.0, AdxLen: 14, AdxThreshold: 25.0, TsAtrMult: 1.5, TpAtrMult: 4,
+			PositionHolder:       helper.NewPositionHolder(),
+			TrendFollowingParams: params.(strategies.TrendFollowingParams),
 		}
 	case enum.CandlestickAggregation:
 		return &strategies.CandlestickAggregationStrategy{
-			PositionHolder:     helper.NewPositionHolder(),
-			TsAtrMult: 1.25, TpAtrMult: 3.5, AtrLen: 14, MaLen: 20, HigherTfmALen: 50, SwingPivotLength: 10, SrTolerancePerc: 0.01,
-			VolumeMALen: 20, VolumeSpikeMul: 1.5, LongBodyAtrMul: 0.8, SmallBodyAtrMul: 0.3, MinAvgStrength: 7.0, MinPatternStrength: 5.0,
+			PositionHolder:               helper.NewPositionHolder(),
+			CandlestickAggregationParams: params.(strategies.CandlestickAggregationParams),
 		}
 	case enum.RenkoCandlesticks:
 		return &strategies.RenkoCandlesticksStrategy{
-			PositionHolder:    helper.NewPositionHolder(),
-			AtrLen: 26, StopLossPct: 10.0, TakeProfitPct: 50.0, BrickSizeConstant: 1.5,
+			PositionHolder:          helper.NewPositionHolder(),
+			RenkoCandlesticksParams: params.(strategies.RenkoCandlesticksParams),
 		}
 	case enum.HeikenAshi:
 		return &strategies.HeikenAshiStrategy{
-			PositionHolder:    helper.NewPositionHolder(),
-			AtrPeriod: 26, AtrLineMultiplier: 4.0, TpATRMultiplier: 3.50, SlATRMultiplier: 1.75, NumEmaPeriods: 20,
+			PositionHolder:   helper.NewPositionHolder(),
+			HeikenAshiParams: params.(strategies.HeikenAshiParams),
 		}
 	case enum.TurtleTrader:
 		return &strategies.TurtleTraderStrategy{
-			PositionHolder:       helper.NewPositionHolder(),
-			NumberOfPeriods: 26, PredictionUnit: "atr", PredictionMultiplier: 4.0, UsePullbackFilter: true,
+			// a half-size entry and up to four 12.5% adds, each 0.8 ATR above the last
+			PositionHolder:     helper.NewPyramidingPositionHolder(helper.PyramidRules{MaxUnits: 5, AddPercent: 12.5, AddAtrStep: 0.8, AtrLen: 26}),
+			TurtleTraderParams: params.(strategies.TurtleTraderParams),
 		}
 	case enum.TrendlineBreakout:
 		return &strategies.TrendlineBreakoutStrategy{
-			PositionHolder: helper.NewPositionHolder(),
-			PivLR: 5, UseEmaFilter: true, EmaLen: 120, AtrLen: 14, TsAtrMult: 1.5, TpAtrMult: 4,
+			PositionHolder:          helper.NewPositionHolder(),
+			TrendlineBreakoutParams: params.(strategies.TrendlineBreakoutParams),
 		}
 	case enum.Supertrend:
 		return &strategies.SupertrendStrategy{
-			PositionHolder: helper.NewPositionHolder(),
-			AtrPeriod: 26, Factor: 1.5, UseVolFilt: true, VolLen: 16, TsAtrMult: 1.5, TpAtrMult: 4,
+			PositionHolder:   helper.NewPositionHolder(),
+			SupertrendParams: params.(strategies.SupertrendParams),
 		}
 	case enum.GroverLlorensActivator:
 		return &strategies.GroverLlorensActivatorStrategy{
-			PositionHolder: helper.NewPositionHolder(),
-			Length: 26, Mult: 1.5, TsAtrMult: 1.5, TpAtrMult: 4,
+			PositionHolder:               helper.NewPositionHolder(),
+			GroverLlorensActivatorParams: params.(strategies.GroverLlorensActivatorParams),
 		}
+	case enum.Ensemble:
+		return newEnsembleStrategy(params.(EnsembleParams), memberParams)
+	case enum.RuleBased:
+		return strategies.NewRuleBasedStrategy(params.(strategies.RuleBasedParams))
+	case enum.Plugin:
+		return newPluginStrategy(params.(PluginParams))
 	}
 	return nil
 }
@@ -0,0 +1,293 @@
+package signaler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"sync"
+
+	strategies "github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler/strategies"
+	enum "github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"gopkg.in/yaml.v3"
+)
+
+// StrategyParams is one of the strategies' XParams structs, the tunable half of a strategy.
+type StrategyParams interface {
+	Validate() error
+}
+
+// GetDefaultParams is what a strategy runs with when the params file and the API leave it alone.
+func GetDefaultParams(strategy enum.Strategy) StrategyParams {
+	switch strategy {
+	case enum.MeanReversion:
+		return strategies.MeanReversionParams{
+			RsiLen: 14, RsiOverbought: 80, RsiOversold: 20, AtrLen: 14, EmaLenLower: 20, EmaLenUpper: 100, FvgLookback: 12,
+			TpATRMultiplier: 3.50, SlATRMultiplier: 1.75,
+		}
+	case enum.TrendFollowing:
+		return strategies.TrendFollowingParams{
+			MaType: "SMA", ShortMALen: 9, LongMALen: 21, BbLen: 20, BbMul: 2.0, RsiLen: 14, RsiLongTh: 55.0, RsiShortTh: 45.0, MacdFastLen: 12, MacdSlowLen: 26,
+			MacdSignalLen: 9, StochLen: 14, StochSmooth: 3, StochOverbought: 80.0, StochOversold: 20.0, AdxLen: 14, AdxThreshold: 25.0, TsAtrMult: 1.5, TpAtrMult: 4,
+		}
+	case enum.CandlestickAggregation:
+		return strategies.CandlestickAggregationParams{
+			TsAtrMult: 1.25, TpAtrMult: 3.5, AtrLen: 14, MaLen: 20, HigherTfmALen: 50, HigherTf: enum.CandleSize1h, SwingPivotLength: 10, SrTolerancePerc: 0.01,
+			VolumeMALen: 20, VolumeSpikeMul: 1.5, LongBodyAtrMul: 0.8, SmallBodyAtrMul: 0.3, MinAvgStrength: 7.0, MinPatternStrength: 5.0,
+		}
+	case enum.RenkoCandlesticks:
+		return strategies.RenkoCandlesticksParams{AtrLen: 26, StopLossPct: 10.0, TakeProfitPct: 50.0, BrickSizeConstant: 1.5}
+	case enum.HeikenAshi:
+		return strategies.HeikenAshiParams{AtrPeriod: 26, AtrLineMultiplier: 4.0, TpATRMultiplier: 3.50, SlATRMultiplier: 1.75, NumEmaPeriods: 20}
+	case enum.TurtleTrader:
+		return strategies.TurtleTraderParams{NumberOfPeriods: 26, PredictionUnit: "atr", PredictionMultiplier: 4.0, UsePullbackFilter: true}
+	case enum.TrendlineBreakout:
+		return strategies.TrendlineBreakoutParams{PivLR: 5, UseEmaFilter: true, EmaLen: 120, AtrLen: 14, TsAtrMult: 1.5, TpAtrMult: 4}
+	case enum.Supertrend:
+		return strategies.SupertrendParams{AtrPeriod: 26, Factor: 1.5, UseVolFilt: true, VolLen: 16, TsAtrMult: 1.5, TpAtrMult: 4}
+	case enum.GroverLlorensActivator:
+		return strategies.GroverLlorensActivatorParams{Length: 26, Mult: 1.5, TsAtrMult: 1.5, TpAtrMult: 4}
+	case enum.Ensemble:
+		return EnsembleParams{Members: []string{"Supertrend", "TrendFollowing", "HeikenAshi"}, Vote: "majority", Threshold: 0.5, VoteWindow: 3, MergeLevels: "tightest"}
+	case enum.RuleBased:
+		return strategies.RuleBasedParams{
+			Name:         "EmaCross",
+			Entry:        "crossover(ema(close, 9), ema(close, 21)) and rsi(close, 14) > 50",
+			Exit:         "crossunder(ema(close, 9), ema(close, 21))",
+			TakeProfit:   "4 * atr(14)",
+			TrailingStop: "1.5 * atr(14)",
+			Percent:      100,
+		}
+	case enum.Plugin:
+		return PluginParams{Address: "localhost:50051", Strategy: "example", TimeoutMs: 2000, HealthIntervalMs: 5000, WarmUpCandles: 50, HistoryCandles: 300}
+	}
+	return nil
+}
+
+// ApplyParams overlays raw, a JSON object holding any subset of the params' fields, on base and validates the
+// result. Fields base doesn't have are an error rather than silently ignored, a typo shouldn't look like a change.
+func ApplyParams(base StrategyParams, raw []byte) (StrategyParams, error) {
+	// base goes through JSON rather than being copied, decoding into a copy's slice would write into base's array
+	target := reflect.New(reflect.TypeOf(base))
+	baseRaw, err := json.Marshal(base)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(baseRaw, target.Interface()); err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(raw)) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(target.Interface()); err != nil {
+			return nil, fmt.Errorf("invalid params: %w", err)
+		}
+	}
+	params := target.Elem().Interface().(StrategyParams)
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// ParamsStore holds the params each strategy runs with and the per-token overrides on top of them. Overrides are
+// kept as the fields the token set, so a later change to the strategy's params still reaches the fields it didn't.
+type ParamsStore struct {
+	mu        sync.RWMutex
+	params    map[enum.Strategy]StrategyParams
+	overrides map[string]map[enum.Strategy]map[string]json.RawMessage // symbol -> strategy -> field -> value
+}
+
+// paramsFile is the layout of the params file, YAML or JSON:
+//
+//	strategies:
+//	  Supertrend: {atrPeriod: 20, factor: 2}
+//	tokens:
+//	  ETH-USD:
+//	    Supertrend: {factor: 2.5}
+type paramsFile struct {
+	Strategies map[string]map[string]any            `yaml:"strategies"`
+	Tokens     map[string]map[string]map[string]any `yaml:"tokens"`
+}
+
+func NewParamsStore() *ParamsStore {
+	ps := &ParamsStore{
+		params:    make(map[enum.Strategy]StrategyParams),
+		overrides: make(map[string]map[enum.Strategy]map[string]json.RawMessage),
+	}
+	for _, strategy := range enum.GetKnownStrategies() {
+		ps.params[strategy] = GetDefaultParams(strategy)
+	}
+	return ps
+}
+
+// LoadParamsStore reads the params file at path over the defaults. A missing file is just the defaults.
+func LoadParamsStore(path string) (*ParamsStore, error) {
+	ps := NewParamsStore()
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ps, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file paramsFile
+	if err := yaml.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for name, fields := range file.Strategies {
+		strategy, ok := enum.LookupStrategy(name)
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown strategy %s", path, name)
+		}
+		body, err := json.Marshal(fields)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, name, err)
+		}
+		if _, err := ps.UpdateParams(strategy, body); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, name, err)
+		}
+	}
+	for symbol, tokenStrategies := range file.Tokens {
+		for name, fields := range tokenStrategies {
+			strategy, ok := enum.LookupStrategy(name)
+			if !ok {
+				return nil, fmt.Errorf("%s: %s: unknown strategy %s", path, symbol, name)
+			}
+			body, err := json.Marshal(fields)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %s: %w", path, symbol, name, err)
+			}
+			if _, err := ps.UpdateTokenParams(symbol, strategy, body); err != nil {
+				return nil, fmt.Errorf("%s: %s: %s: %w", path, symbol, name, err)
+			}
+		}
+	}
+	return ps, nil
+}
+
+func (ps *ParamsStore) GetParams(strategy enum.Strategy) StrategyParams {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	return ps.params[strategy]
+}
+
+// GetTokenParams is the strategy's params with the token's overrides applied, what its trader runs with.
+func (ps *ParamsStore) GetTokenParams(symbol string, strategy enum.Strategy) StrategyParams {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	return ps.getTokenParams(symbol, strategy)
+}
+
+func (ps *ParamsStore) getTokenParams(symbol string, strategy enum.Strategy) StrategyParams {
+	base := ps.params[strategy]
+	fields := ps.overrides[symbol][strategy]
+	if base == nil || len(fields) == 0 {
+		return base
+	}
+	raw, _ := json.Marshal(fields)
+	params, err := ApplyParams(base, raw)
+	if err != nil {
+		return base // overrides are validated against the base whenever either changes
+	}
+	return params
+}
+
+// UpdateParams applies raw, a JSON object with the fields to change, to the strategy's params. It fails, changing
+// nothing, if the result or any token's overrides on top of it don't validate.
+func (ps *ParamsStore) UpdateParams(strategy enum.Strategy, raw []byte) (StrategyParams, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	base, ok := ps.params[strategy]
+	if !ok {
+		return nil, fmt.Errorf("unknown strategy %d", strategy)
+	}
+	params, err := ApplyParams(base, raw)
+	if err != nil {
+		return nil, err
+	}
+	for symbol, tokenOverrides := range ps.overrides {
+		if fields := tokenOverrides[strategy]; len(fields) > 0 {
+			body, _ := json.Marshal(fields)
+			if _, err := ApplyParams(params, body); err != nil {
+				return nil, fmt.Errorf("%s overrides: %w", symbol, err)
+			}
+		}
+	}
+	ps.params[strategy] = params
+	return params, nil
+}
+
+// UpdateTokenParams merges raw's fields into the token's overrides for the strategy and returns the params the
+// token now runs with. A field set to null drops the override, going back to the strategy's value.
+func (ps *ParamsStore) UpdateTokenParams(symbol string, strategy enum.Strategy, raw []byte) (StrategyParams, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	base, ok := ps.params[strategy]
+	if !ok {
+		return nil, fmt.Errorf("unknown strategy %d", strategy)
+	}
+	var changes map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &changes); err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+
+	fields := maps.Clone(ps.overrides[symbol][strategy])
+	if fields == nil {
+		fields = make(map[string]json.RawMessage)
+	}
+	for name, value := range changes {
+		if string(value) == "null" {
+			delete(fields, name)
+			continue
+		}
+		fields[name] = value
+	}
+	body, _ := json.Marshal(fields)
+	params, err := ApplyParams(base, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if ps.overrides[symbol] == nil {
+		ps.overrides[symbol] = make(map[enum.Strategy]map[string]json.RawMessage)
+	}
+	ps.overrides[symbol][strategy] = fields
+	return params, nil
+}
+
+// NewStrategy builds the strategy the way the token runs it, an ensemble's members included.
+func (ps *ParamsStore) NewStrategy(symbol string, strategy enum.Strategy) Strategy {
+	return NewStrategyWithMemberParams(strategy, ps.GetTokenParams(symbol, strategy), ps.GetMemberParams(symbol))
+}
+
+// GetMemberParams looks up the params an ensemble's members run with for the token, its overrides applied.
+func (ps *ParamsStore) GetMemberParams(symbol string) func(enum.Strategy) StrategyParams {
+	return func(member enum.Strategy) StrategyParams {
+		return ps.GetTokenParams(symbol, member)
+	}
+}
+
+// IsAffectedBy reports whether a change to changed's params reaches a token running strategy, which it does
+// when they are the same or changed is one of the token's ensemble members.
+func (ps *ParamsStore) IsAffectedBy(symbol string, strategy enum.Strategy, changed enum.Strategy) bool {
+	if strategy == changed {
+		return true
+	}
+	if strategy != enum.Ensemble {
+		return false
+	}
+	return slices.Contains(ps.GetTokenParams(symbol, enum.Ensemble).(EnsembleParams).GetMembers(), changed)
+}
+
+// GetWarmUpCandles is the warm-up of the strategy as the token would run it, for sizing its history.
+func (ps *ParamsStore) GetWarmUpCandles(symbol string, strategy enum.Strategy) int {
+	if s := ps.NewStrategy(symbol, strategy); s != nil {
+		return s.GetWarmUpCandles()
+	}
+	return 0
+}
@@ -0,0 +1,58 @@
+package signaler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	strategies "github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler/strategies"
+	enum "github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+)
+
+func TestLoadParamsStore(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		want    enum.CandleSize
+		wantErr bool
+	}{
+		{name: "default", file: "strategies: {}\n", want: enum.CandleSize1h},
+		{name: "by name", file: "strategies:\n  CandlestickAggregation:\n    higherTf: CandleSize4h\n", want: enum.CandleSize4h},
+		{name: "by number", file: "strategies:\n  CandlestickAggregation:\n    higherTf: 5\n", want: enum.CandleSize2h},
+		{name: "unknown name", file: "strategies:\n  CandlestickAggregation:\n    higherTf: CandleSize3h\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "strategyParams.yaml")
+			if err := os.WriteFile(path, []byte(tt.file), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			ps, err := LoadParamsStore(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("loaded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ps.GetParams(enum.CandlestickAggregation).(strategies.CandlestickAggregationParams).HigherTf; got != tt.want {
+				t.Errorf("higherTf %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// The shipped file has to load over the defaults and leave every strategy valid.
+func TestShippedParamsFile(t *testing.T) {
+	ps, err := LoadParamsStore("../../config/strategyParams.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, strategy := range enum.GetKnownStrategies() {
+		if err := ps.GetParams(strategy).Validate(); err != nil {
+			t.Errorf("%s: %v", strategy.String(), err)
+		}
+	}
+}
@@ -1,40 +1,70 @@
 package strategy_helper
 
 import (
+	"reflect"
+	"time"
+
 	enum "github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	indicators "github.com/A-Here-And-Now/algo-trader/orchestration_api/indicators"
 	models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
 )
 
 type PositionState struct {
 	InPosition            	  bool
-	Side                  	  enum.SignalType
-	EntryPrice            	  float64
+	Side                  	  enum.SignalType // SignalBuy for a long, SignalShort for a short
+	EntryPrice            	  float64 // average over the units, weighted by their percent
 	TakeProfit            	  float64
 	StopLoss              	  float64
 	TrailingStop              float64
 	LastTrailingStopPrice     float64
-	PositionIncreaseThreshold float64
+	PositionIncreaseThreshold float64 // the close at which the next unit is added
+	Units                     []models.PositionUnit
+}
+
+// Equal compares two states unit by unit, the signal engine persists the state whenever it changes.
+func (s PositionState) Equal(other PositionState) bool {
+	return reflect.DeepEqual(s, other)
+}
+
+// PyramidRules are the add and trim rules a strategy declares. A MaxUnits of 1 leaves pyramiding off, so only
+// the strategy's own entries and exits apply.
+type PyramidRules struct {
+	MaxUnits    int
+	AddPercent  float64 // each add, in the same percent of allocated funds as the strategy's entries
+	AddAtrStep  float64 // add once the close is this many ATR past the newest unit's entry, above it for a long
+	UnitStopAtr float64 // each add's own stop this many ATR behind its entry, 0 keeps adds until the strategy exits
+	AtrLen      int
 }
 
 // Holds the map and the common ConfirmSignalDelivered implementation.
 type PositionHolder struct {
-	State map[string]*PositionState
+	State   map[string]*PositionState
+	Pyramid PyramidRules
 }
 
 func NewPositionHolder() *PositionHolder {
-	return &PositionHolder{State: make(map[string]*PositionState)}
+	return &PositionHolder{State: make(map[string]*PositionState), Pyramid: PyramidRules{MaxUnits: 1}}
+}
+
+func NewPyramidingPositionHolder(rules PyramidRules) *PositionHolder {
+	return &PositionHolder{State: make(map[string]*PositionState), Pyramid: rules}
 }
 
 func NewInPositionState(signal models.Signal) *PositionState {
+	lastTrailingStopPrice := signal.LastTrailingStopPrice
+	if lastTrailingStopPrice == 0 {
+		lastTrailingStopPrice = signal.Price // otherwise the first ticker ratchets the stop up by the whole price
+	}
 	return &PositionState{
 		Side: signal.Type,
 		EntryPrice: signal.Price,
-		InPosition: signal.Type == enum.SignalBuy,
+		InPosition: signal.Type == enum.SignalBuy || signal.Type == enum.SignalShort,
 		TakeProfit: signal.TakeProfit,
 		StopLoss: signal.StopLoss,
 		TrailingStop: signal.TrailingStop,
 		PositionIncreaseThreshold: signal.PositionIncreaseThreshold,
-		LastTrailingStopPrice: signal.LastTrailingStopPrice,
+		LastTrailingStopPrice: lastTrailingStopPrice,
+		Units: []models.PositionUnit{{EntryPrice: signal.Price, Percent: signal.Percent, StopLoss: signal.StopLoss, Time: signal.Time}},
 	}
 }
 
@@ -42,25 +72,183 @@ func NewPositionState(symbol string) *PositionState {
 	return &PositionState{ }
 }
 
+// ConfirmSignalDelivered moves the position on once the trader has the signal: a buy or a short opens it or, when
+// already in on that side, adds a unit; a trim takes the newest unit off and any other sell or cover closes it.
 func (h *PositionHolder) ConfirmSignalDelivered(symbol string, signal models.Signal) {
 	if _, ok := h.State[symbol]; !ok {
 		h.State[symbol] = &PositionState{}
 	}
-	h.State[symbol].Side = signal.Type
-	h.State[symbol].EntryPrice = signal.Price
-	h.State[symbol].InPosition = signal.Type == enum.SignalBuy
-	if h.State[symbol].InPosition {
+	state := h.State[symbol]
+	switch {
+	case signal.Type == enum.SignalHold:
+		return // a hold doesn't change the position, it only makes sure there is state to read
+	case isEntry(signal.Type) && state.InPosition && state.Side == signal.Type:
+		state.addUnit(signal)
+	case isEntry(signal.Type):
 		h.State[symbol] = NewInPositionState(signal)
-	} else {
+	case signal.Trim && len(state.Units) > 1:
+		state.Units = state.Units[:len(state.Units)-1]
+		state.updateEntryPrice()
+	default:
 		h.State[symbol] = NewPositionState(symbol)
 	}
 }
 
+// ApplyPyramidRules runs the shared add and trim rules when the strategy itself holds, and stamps the close on
+// signals that go out without a price so every unit has an entry.
+func (h *PositionHolder) ApplyPyramidRules(symbol string, ind *indicators.Set, signal models.Signal) models.Signal {
+	candle, ok := ind.Candle()
+	if !ok {
+		return signal
+	}
+	lastClose := candle.Close
+	if signal.Type != enum.SignalHold {
+		if signal.Price == 0 {
+			signal.Price = lastClose
+		}
+		return signal
+	}
+	state, ok := h.State[symbol]
+	if !ok || !state.InPosition || len(state.Units) == 0 {
+		return signal
+	}
+
+	// a short mirrors a long: its stops sit above the price and it adds as the price falls
+	direction, exit := 1.0, enum.SignalSell
+	if state.Side == enum.SignalShort {
+		direction, exit = -1.0, enum.SignalCover
+	}
+	newest := state.Units[len(state.Units)-1]
+	if len(state.Units) > 1 && newest.StopLoss > 0 && (lastClose-newest.StopLoss)*direction <= 0 {
+		return models.Signal{Symbol: symbol, Type: exit, Percent: state.getNewestUnitShare(), Time: time.Now(), Price: lastClose, Trim: true}
+	}
+	if len(state.Units) >= h.Pyramid.MaxUnits || h.Pyramid.AddPercent <= 0 {
+		return signal
+	}
+	// 0 until the ATR is warm, the adds then wait for the strategy's own threshold
+	atr, _ := ind.Atr(h.Pyramid.AtrLen)
+	threshold := state.PositionIncreaseThreshold
+	if threshold <= 0 && atr > 0 {
+		threshold = newest.EntryPrice + direction*h.Pyramid.AddAtrStep*atr
+	}
+	if threshold <= 0 || (lastClose-threshold)*direction < 0 {
+		return signal
+	}
+	add := models.Signal{
+		Symbol:                symbol,
+		Type:                  state.Side,
+		Percent:               h.Pyramid.AddPercent,
+		Time:                  time.Now(),
+		Price:                 lastClose,
+		TakeProfit:            state.TakeProfit,
+		TrailingStop:          state.TrailingStop,
+		LastTrailingStopPrice: state.LastTrailingStopPrice,
+	}
+	if atr > 0 {
+		add.PositionIncreaseThreshold = lastClose + direction*h.Pyramid.AddAtrStep*atr
+		if h.Pyramid.UnitStopAtr > 0 {
+			add.UnitStopLoss = lastClose - direction*h.Pyramid.UnitStopAtr*atr
+		}
+	}
+	return add
+}
+
+// UpdateTrailingStop ratchets the trailing stop up behind a long as the price makes new highs, and down behind a
+// short as it makes new lows.
 func (h *PositionHolder) UpdateTrailingStop(symbol string, ticker models.Ticker) {
 	if s, ok := h.State[symbol]; ok && s.InPosition && s.TrailingStop != 0 {
+		if s.Side == enum.SignalShort {
+			if ticker.Price < s.LastTrailingStopPrice {
+				s.TrailingStop -= s.LastTrailingStopPrice - ticker.Price
+				s.LastTrailingStopPrice = ticker.Price
+			}
+			return
+		}
 		if ticker.Price > s.LastTrailingStopPrice {
 			s.TrailingStop += ticker.Price - s.LastTrailingStopPrice
 			s.LastTrailingStopPrice = ticker.Price
 		}
 	}
 }
+
+func (h *PositionHolder) GetPositionState(symbol string) (PositionState, bool) {
+	s, ok := h.State[symbol]
+	if !ok {
+		return PositionState{}, false
+	}
+	state := *s
+	state.Units = append([]models.PositionUnit(nil), s.Units...)
+	return state, true
+}
+
+// RestorePositionState puts back a position persisted before a restart. Snapshots from before units were tracked
+// come back as a single unit.
+func (h *PositionHolder) RestorePositionState(symbol string, state PositionState) {
+	if state.InPosition && len(state.Units) == 0 {
+		state.Units = []models.PositionUnit{{EntryPrice: state.EntryPrice, Percent: 100, StopLoss: state.StopLoss}}
+	}
+	h.State[symbol] = &state
+}
+
+// addUnit puts another unit on an open position. The position's own exit levels move only when the add brings
+// new ones.
+func (s *PositionState) addUnit(signal models.Signal) {
+	s.Units = append(s.Units, models.PositionUnit{EntryPrice: signal.Price, Percent: signal.Percent, StopLoss: signal.UnitStopLoss, Time: signal.Time})
+	s.updateEntryPrice()
+	if signal.PositionIncreaseThreshold > 0 {
+		s.PositionIncreaseThreshold = signal.PositionIncreaseThreshold
+	}
+	if signal.TakeProfit > 0 {
+		s.TakeProfit = signal.TakeProfit
+	}
+	if isTighterStop(s.Side, signal.TrailingStop, s.TrailingStop) {
+		s.TrailingStop = signal.TrailingStop
+		if s.Side == enum.SignalShort {
+			s.LastTrailingStopPrice = min(signal.LastTrailingStopPrice, s.LastTrailingStopPrice)
+		} else {
+			s.LastTrailingStopPrice = max(signal.LastTrailingStopPrice, s.LastTrailingStopPrice)
+		}
+	}
+	if isTighterStop(s.Side, signal.StopLoss, s.StopLoss) {
+		s.StopLoss = signal.StopLoss
+	}
+}
+
+func isEntry(signalType enum.SignalType) bool {
+	return signalType == enum.SignalBuy || signalType == enum.SignalShort
+}
+
+// isTighterStop reports whether stop sits closer to the price than current: higher behind a long, lower behind a
+// short. A zero stop is no stop.
+func isTighterStop(side enum.SignalType, stop float64, current float64) bool {
+	if stop <= 0 {
+		return false
+	}
+	if side == enum.SignalShort {
+		return current <= 0 || stop < current
+	}
+	return stop > current
+}
+
+// getNewestUnitShare is the newest unit's percent of the whole position, what a trim takes off the trader's target.
+func (s *PositionState) getNewestUnitShare() float64 {
+	total := 0.0
+	for _, u := range s.Units {
+		total += u.Percent
+	}
+	if total <= 0 {
+		return 0
+	}
+	return s.Units[len(s.Units)-1].Percent / total * 100
+}
+
+func (s *PositionState) updateEntryPrice() {
+	total, weighted := 0.0, 0.0
+	for _, u := range s.Units {
+		total += u.Percent
+		weighted += u.Percent * u.EntryPrice
+	}
+	if total > 0 {
+		s.EntryPrice = weighted / total
+	}
+}
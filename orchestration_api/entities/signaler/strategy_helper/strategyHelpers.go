@@ -141,34 +141,6 @@ func IsLongShadowPercent(shadow, candleRange, perc float64) bool {
     return shadow > candleRange*perc
 }
 
-func GetHLPivot(highs []float64, lows []float64, swingPivotLength int) (float64, float64) {
-	ph := float64(0)
-	pl := float64(0)
-	for i := len(highs) - 1; i >= 0 && i > swingPivotLength; i-- {
-		// look‑back `swingPivotLength` bars on each side
-		highPeak := true
-		lowTrough := true
-		for j := 1; j <= swingPivotLength; j++ {
-			if highs[i] <= highs[i-j] || highs[i] <= highs[i+j] {
-				highPeak = false
-			}
-			if lows[i] >= lows[i-j] || lows[i] >= lows[i+j] {
-				lowTrough = false
-			}
-		}
-		if highPeak && math.IsNaN(ph) {
-			ph = highs[i]
-		}
-		if lowTrough && math.IsNaN(pl) {
-			pl = lows[i]
-		}
-		if !math.IsNaN(ph) && !math.IsNaN(pl) {
-			break
-		}
-	}
-	return ph, pl
-}
-
 func LinePriceAt(x1 float64, y1 float64, x2 float64, y2 float64, idx float64) float64 {
 	if x2 == x1 {
 		return y2
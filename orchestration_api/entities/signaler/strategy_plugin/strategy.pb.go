@@ -0,0 +1,773 @@
+// StrategyPlugin is the contract for strategies that run outside orchestration_api, e.g. in Python. The signal
+// engine calls a plugin the way it calls a local strategy: it sends the symbol's candle history and position on
+// every signal check, streams the ticker to it, and reads back a signal. The position is tracked on our side, a
+// plugin only ever sees it, so a plugin can restart without losing a trade.
+//
+// A plugin also serves the standard grpc.health.v1.Health service, signals are only asked for while it reports
+// SERVING for the empty service name, and a call that fails or runs past its timeout is a hold.
+//
+// After changing this file regenerate the Go code, in this directory, with
+//
+//	protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative strategy.proto
+//
+// and a Python plugin's stubs with python -m grpc_tools.protoc -I. --python_out=. --grpc_python_out=. strategy.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: strategy.proto
+
+package strategy_plugin
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// SignalType is enum.SignalType. UNSPECIFIED is what a plugin that never set the type sends, a signal with it is
+// rejected and holds.
+type SignalType int32
+
+const (
+	SignalType_SIGNAL_TYPE_UNSPECIFIED SignalType = 0
+	SignalType_SIGNAL_TYPE_BUY         SignalType = 1
+	SignalType_SIGNAL_TYPE_SELL        SignalType = 2
+	SignalType_SIGNAL_TYPE_HOLD        SignalType = 3
+	SignalType_SIGNAL_TYPE_SHORT       SignalType = 4
+	SignalType_SIGNAL_TYPE_COVER       SignalType = 5
+)
+
+// Enum value maps for SignalType.
+var (
+	SignalType_name = map[int32]string{
+		0: "SIGNAL_TYPE_UNSPECIFIED",
+		1: "SIGNAL_TYPE_BUY",
+		2: "SIGNAL_TYPE_SELL",
+		3: "SIGNAL_TYPE_HOLD",
+		4: "SIGNAL_TYPE_SHORT",
+		5: "SIGNAL_TYPE_COVER",
+	}
+	SignalType_value = map[string]int32{
+		"SIGNAL_TYPE_UNSPECIFIED": 0,
+		"SIGNAL_TYPE_BUY":         1,
+		"SIGNAL_TYPE_SELL":        2,
+		"SIGNAL_TYPE_HOLD":        3,
+		"SIGNAL_TYPE_SHORT":       4,
+		"SIGNAL_TYPE_COVER":       5,
+	}
+)
+
+func (x SignalType) Enum() *SignalType {
+	p := new(SignalType)
+	*p = x
+	return p
+}
+
+func (x SignalType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SignalType) Descriptor() protoreflect.EnumDescriptor {
+	return file_strategy_proto_enumTypes[0].Descriptor()
+}
+
+func (SignalType) Type() protoreflect.EnumType {
+	return &file_strategy_proto_enumTypes[0]
+}
+
+func (x SignalType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SignalType.Descriptor instead.
+func (SignalType) EnumDescriptor() ([]byte, []int) {
+	return file_strategy_proto_rawDescGZIP(), []int{0}
+}
+
+type Candle struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Start         *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=start,proto3" json:"start,omitempty"`
+	Open          float64                `protobuf:"fixed64,2,opt,name=open,proto3" json:"open,omitempty"`
+	High          float64                `protobuf:"fixed64,3,opt,name=high,proto3" json:"high,omitempty"`
+	Low           float64                `protobuf:"fixed64,4,opt,name=low,proto3" json:"low,omitempty"`
+	Close         float64                `protobuf:"fixed64,5,opt,name=close,proto3" json:"close,omitempty"`
+	Volume        float64                `protobuf:"fixed64,6,opt,name=volume,proto3" json:"volume,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Candle) Reset() {
+	*x = Candle{}
+	mi := &file_strategy_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Candle) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Candle) ProtoMessage() {}
+
+func (x *Candle) ProtoReflect() protoreflect.Message {
+	mi := &file_strategy_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Candle.ProtoReflect.Descriptor instead.
+func (*Candle) Descriptor() ([]byte, []int) {
+	return file_strategy_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Candle) GetStart() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Start
+	}
+	return nil
+}
+
+func (x *Candle) GetOpen() float64 {
+	if x != nil {
+		return x.Open
+	}
+	return 0
+}
+
+func (x *Candle) GetHigh() float64 {
+	if x != nil {
+		return x.High
+	}
+	return 0
+}
+
+func (x *Candle) GetLow() float64 {
+	if x != nil {
+		return x.Low
+	}
+	return 0
+}
+
+func (x *Candle) GetClose() float64 {
+	if x != nil {
+		return x.Close
+	}
+	return 0
+}
+
+func (x *Candle) GetVolume() float64 {
+	if x != nil {
+		return x.Volume
+	}
+	return 0
+}
+
+type PositionUnit struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EntryPrice    float64                `protobuf:"fixed64,1,opt,name=entry_price,json=entryPrice,proto3" json:"entry_price,omitempty"`
+	Percent       float64                `protobuf:"fixed64,2,opt,name=percent,proto3" json:"percent,omitempty"`
+	StopLoss      float64                `protobuf:"fixed64,3,opt,name=stop_loss,json=stopLoss,proto3" json:"stop_loss,omitempty"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=time,proto3" json:"time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PositionUnit) Reset() {
+	*x = PositionUnit{}
+	mi := &file_strategy_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PositionUnit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PositionUnit) ProtoMessage() {}
+
+func (x *PositionUnit) ProtoReflect() protoreflect.Message {
+	mi := &file_strategy_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PositionUnit.ProtoReflect.Descriptor instead.
+func (*PositionUnit) Descriptor() ([]byte, []int) {
+	return file_strategy_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PositionUnit) GetEntryPrice() float64 {
+	if x != nil {
+		return x.EntryPrice
+	}
+	return 0
+}
+
+func (x *PositionUnit) GetPercent() float64 {
+	if x != nil {
+		return x.Percent
+	}
+	return 0
+}
+
+func (x *PositionUnit) GetStopLoss() float64 {
+	if x != nil {
+		return x.StopLoss
+	}
+	return 0
+}
+
+func (x *PositionUnit) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+type PositionState struct {
+	state                     protoimpl.MessageState `protogen:"open.v1"`
+	InPosition                bool                   `protobuf:"varint,1,opt,name=in_position,json=inPosition,proto3" json:"in_position,omitempty"`
+	Side                      SignalType             `protobuf:"varint,2,opt,name=side,proto3,enum=strategyplugin.v1.SignalType" json:"side,omitempty"` // BUY for a long, SHORT for a short, HOLD when not in a position
+	EntryPrice                float64                `protobuf:"fixed64,3,opt,name=entry_price,json=entryPrice,proto3" json:"entry_price,omitempty"`
+	TakeProfit                float64                `protobuf:"fixed64,4,opt,name=take_profit,json=takeProfit,proto3" json:"take_profit,omitempty"`
+	StopLoss                  float64                `protobuf:"fixed64,5,opt,name=stop_loss,json=stopLoss,proto3" json:"stop_loss,omitempty"`
+	TrailingStop              float64                `protobuf:"fixed64,6,opt,name=trailing_stop,json=trailingStop,proto3" json:"trailing_stop,omitempty"`
+	LastTrailingStopPrice     float64                `protobuf:"fixed64,7,opt,name=last_trailing_stop_price,json=lastTrailingStopPrice,proto3" json:"last_trailing_stop_price,omitempty"`
+	PositionIncreaseThreshold float64                `protobuf:"fixed64,8,opt,name=position_increase_threshold,json=positionIncreaseThreshold,proto3" json:"position_increase_threshold,omitempty"`
+	Units                     []*PositionUnit        `protobuf:"bytes,9,rep,name=units,proto3" json:"units,omitempty"`
+	unknownFields             protoimpl.UnknownFields
+	sizeCache                 protoimpl.SizeCache
+}
+
+func (x *PositionState) Reset() {
+	*x = PositionState{}
+	mi := &file_strategy_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PositionState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PositionState) ProtoMessage() {}
+
+func (x *PositionState) ProtoReflect() protoreflect.Message {
+	mi := &file_strategy_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PositionState.ProtoReflect.Descriptor instead.
+func (*PositionState) Descriptor() ([]byte, []int) {
+	return file_strategy_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *PositionState) GetInPosition() bool {
+	if x != nil {
+		return x.InPosition
+	}
+	return false
+}
+
+func (x *PositionState) GetSide() SignalType {
+	if x != nil {
+		return x.Side
+	}
+	return SignalType_SIGNAL_TYPE_UNSPECIFIED
+}
+
+func (x *PositionState) GetEntryPrice() float64 {
+	if x != nil {
+		return x.EntryPrice
+	}
+	return 0
+}
+
+func (x *PositionState) GetTakeProfit() float64 {
+	if x != nil {
+		return x.TakeProfit
+	}
+	return 0
+}
+
+func (x *PositionState) GetStopLoss() float64 {
+	if x != nil {
+		return x.StopLoss
+	}
+	return 0
+}
+
+func (x *PositionState) GetTrailingStop() float64 {
+	if x != nil {
+		return x.TrailingStop
+	}
+	return 0
+}
+
+func (x *PositionState) GetLastTrailingStopPrice() float64 {
+	if x != nil {
+		return x.LastTrailingStopPrice
+	}
+	return 0
+}
+
+func (x *PositionState) GetPositionIncreaseThreshold() float64 {
+	if x != nil {
+		return x.PositionIncreaseThreshold
+	}
+	return 0
+}
+
+func (x *PositionState) GetUnits() []*PositionUnit {
+	if x != nil {
+		return x.Units
+	}
+	return nil
+}
+
+type CalculateSignalRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Strategy      string                 `protobuf:"bytes,1,opt,name=strategy,proto3" json:"strategy,omitempty"` // the name the plugin was configured with, one plugin can serve several
+	Symbol        string                 `protobuf:"bytes,2,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Candles       []*Candle              `protobuf:"bytes,3,rep,name=candles,proto3" json:"candles,omitempty"` // oldest first, the last one is still forming
+	Position      *PositionState         `protobuf:"bytes,4,opt,name=position,proto3" json:"position,omitempty"`
+	Params        string                 `protobuf:"bytes,5,opt,name=params,proto3" json:"params,omitempty"` // the plugin's own params from the params API, a JSON object
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CalculateSignalRequest) Reset() {
+	*x = CalculateSignalRequest{}
+	mi := &file_strategy_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CalculateSignalRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CalculateSignalRequest) ProtoMessage() {}
+
+func (x *CalculateSignalRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_strategy_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CalculateSignalRequest.ProtoReflect.Descriptor instead.
+func (*CalculateSignalRequest) Descriptor() ([]byte, []int) {
+	return file_strategy_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CalculateSignalRequest) GetStrategy() string {
+	if x != nil {
+		return x.Strategy
+	}
+	return ""
+}
+
+func (x *CalculateSignalRequest) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *CalculateSignalRequest) GetCandles() []*Candle {
+	if x != nil {
+		return x.Candles
+	}
+	return nil
+}
+
+func (x *CalculateSignalRequest) GetPosition() *PositionState {
+	if x != nil {
+		return x.Position
+	}
+	return nil
+}
+
+func (x *CalculateSignalRequest) GetParams() string {
+	if x != nil {
+		return x.Params
+	}
+	return ""
+}
+
+// Signal is models.Signal. Levels are prices, 0 for none.
+type Signal struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          SignalType             `protobuf:"varint,1,opt,name=type,proto3,enum=strategyplugin.v1.SignalType" json:"type,omitempty"`
+	Percent       float64                `protobuf:"fixed64,2,opt,name=percent,proto3" json:"percent,omitempty"` // of allocated funds
+	Price         float64                `protobuf:"fixed64,3,opt,name=price,proto3" json:"price,omitempty"`
+	TakeProfit    float64                `protobuf:"fixed64,4,opt,name=take_profit,json=takeProfit,proto3" json:"take_profit,omitempty"`
+	StopLoss      float64                `protobuf:"fixed64,5,opt,name=stop_loss,json=stopLoss,proto3" json:"stop_loss,omitempty"`
+	TrailingStop  float64                `protobuf:"fixed64,6,opt,name=trailing_stop,json=trailingStop,proto3" json:"trailing_stop,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Signal) Reset() {
+	*x = Signal{}
+	mi := &file_strategy_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Signal) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Signal) ProtoMessage() {}
+
+func (x *Signal) ProtoReflect() protoreflect.Message {
+	mi := &file_strategy_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Signal.ProtoReflect.Descriptor instead.
+func (*Signal) Descriptor() ([]byte, []int) {
+	return file_strategy_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Signal) GetType() SignalType {
+	if x != nil {
+		return x.Type
+	}
+	return SignalType_SIGNAL_TYPE_UNSPECIFIED
+}
+
+func (x *Signal) GetPercent() float64 {
+	if x != nil {
+		return x.Percent
+	}
+	return 0
+}
+
+func (x *Signal) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Signal) GetTakeProfit() float64 {
+	if x != nil {
+		return x.TakeProfit
+	}
+	return 0
+}
+
+func (x *Signal) GetStopLoss() float64 {
+	if x != nil {
+		return x.StopLoss
+	}
+	return 0
+}
+
+func (x *Signal) GetTrailingStop() float64 {
+	if x != nil {
+		return x.TrailingStop
+	}
+	return 0
+}
+
+type Ticker struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Symbol        string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Price         float64                `protobuf:"fixed64,2,opt,name=price,proto3" json:"price,omitempty"`
+	Bid           float64                `protobuf:"fixed64,3,opt,name=bid,proto3" json:"bid,omitempty"`
+	Ask           float64                `protobuf:"fixed64,4,opt,name=ask,proto3" json:"ask,omitempty"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=time,proto3" json:"time,omitempty"`
+	Strategy      string                 `protobuf:"bytes,6,opt,name=strategy,proto3" json:"strategy,omitempty"` // as in CalculateSignalRequest
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Ticker) Reset() {
+	*x = Ticker{}
+	mi := &file_strategy_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Ticker) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Ticker) ProtoMessage() {}
+
+func (x *Ticker) ProtoReflect() protoreflect.Message {
+	mi := &file_strategy_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Ticker.ProtoReflect.Descriptor instead.
+func (*Ticker) Descriptor() ([]byte, []int) {
+	return file_strategy_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Ticker) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *Ticker) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Ticker) GetBid() float64 {
+	if x != nil {
+		return x.Bid
+	}
+	return 0
+}
+
+func (x *Ticker) GetAsk() float64 {
+	if x != nil {
+		return x.Ask
+	}
+	return 0
+}
+
+func (x *Ticker) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *Ticker) GetStrategy() string {
+	if x != nil {
+		return x.Strategy
+	}
+	return ""
+}
+
+type StreamTickersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamTickersResponse) Reset() {
+	*x = StreamTickersResponse{}
+	mi := &file_strategy_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamTickersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamTickersResponse) ProtoMessage() {}
+
+func (x *StreamTickersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_strategy_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamTickersResponse.ProtoReflect.Descriptor instead.
+func (*StreamTickersResponse) Descriptor() ([]byte, []int) {
+	return file_strategy_proto_rawDescGZIP(), []int{6}
+}
+
+var File_strategy_proto protoreflect.FileDescriptor
+
+const file_strategy_proto_rawDesc = "" +
+	"\n" +
+	"\x0estrategy.proto\x12\x11strategyplugin.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xa2\x01\n" +
+	"\x06Candle\x120\n" +
+	"\x05start\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x05start\x12\x12\n" +
+	"\x04open\x18\x02 \x01(\x01R\x04open\x12\x12\n" +
+	"\x04high\x18\x03 \x01(\x01R\x04high\x12\x10\n" +
+	"\x03low\x18\x04 \x01(\x01R\x03low\x12\x14\n" +
+	"\x05close\x18\x05 \x01(\x01R\x05close\x12\x16\n" +
+	"\x06volume\x18\x06 \x01(\x01R\x06volume\"\x96\x01\n" +
+	"\fPositionUnit\x12\x1f\n" +
+	"\ventry_price\x18\x01 \x01(\x01R\n" +
+	"entryPrice\x12\x18\n" +
+	"\apercent\x18\x02 \x01(\x01R\apercent\x12\x1b\n" +
+	"\tstop_loss\x18\x03 \x01(\x01R\bstopLoss\x12.\n" +
+	"\x04time\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\"\x97\x03\n" +
+	"\rPositionState\x12\x1f\n" +
+	"\vin_position\x18\x01 \x01(\bR\n" +
+	"inPosition\x121\n" +
+	"\x04side\x18\x02 \x01(\x0e2\x1d.strategyplugin.v1.SignalTypeR\x04side\x12\x1f\n" +
+	"\ventry_price\x18\x03 \x01(\x01R\n" +
+	"entryPrice\x12\x1f\n" +
+	"\vtake_profit\x18\x04 \x01(\x01R\n" +
+	"takeProfit\x12\x1b\n" +
+	"\tstop_loss\x18\x05 \x01(\x01R\bstopLoss\x12#\n" +
+	"\rtrailing_stop\x18\x06 \x01(\x01R\ftrailingStop\x127\n" +
+	"\x18last_trailing_stop_price\x18\a \x01(\x01R\x15lastTrailingStopPrice\x12>\n" +
+	"\x1bposition_increase_threshold\x18\b \x01(\x01R\x19positionIncreaseThreshold\x125\n" +
+	"\x05units\x18\t \x03(\v2\x1f.strategyplugin.v1.PositionUnitR\x05units\"\xd7\x01\n" +
+	"\x16CalculateSignalRequest\x12\x1a\n" +
+	"\bstrategy\x18\x01 \x01(\tR\bstrategy\x12\x16\n" +
+	"\x06symbol\x18\x02 \x01(\tR\x06symbol\x123\n" +
+	"\acandles\x18\x03 \x03(\v2\x19.strategyplugin.v1.CandleR\acandles\x12<\n" +
+	"\bposition\x18\x04 \x01(\v2 .strategyplugin.v1.PositionStateR\bposition\x12\x16\n" +
+	"\x06params\x18\x05 \x01(\tR\x06params\"\xce\x01\n" +
+	"\x06Signal\x121\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x1d.strategyplugin.v1.SignalTypeR\x04type\x12\x18\n" +
+	"\apercent\x18\x02 \x01(\x01R\apercent\x12\x14\n" +
+	"\x05price\x18\x03 \x01(\x01R\x05price\x12\x1f\n" +
+	"\vtake_profit\x18\x04 \x01(\x01R\n" +
+	"takeProfit\x12\x1b\n" +
+	"\tstop_loss\x18\x05 \x01(\x01R\bstopLoss\x12#\n" +
+	"\rtrailing_stop\x18\x06 \x01(\x01R\ftrailingStop\"\xa6\x01\n" +
+	"\x06Ticker\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12\x14\n" +
+	"\x05price\x18\x02 \x01(\x01R\x05price\x12\x10\n" +
+	"\x03bid\x18\x03 \x01(\x01R\x03bid\x12\x10\n" +
+	"\x03ask\x18\x04 \x01(\x01R\x03ask\x12.\n" +
+	"\x04time\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x1a\n" +
+	"\bstrategy\x18\x06 \x01(\tR\bstrategy\"\x17\n" +
+	"\x15StreamTickersResponse*\x98\x01\n" +
+	"\n" +
+	"SignalType\x12\x1b\n" +
+	"\x17SIGNAL_TYPE_UNSPECIFIED\x10\x00\x12\x13\n" +
+	"\x0fSIGNAL_TYPE_BUY\x10\x01\x12\x14\n" +
+	"\x10SIGNAL_TYPE_SELL\x10\x02\x12\x14\n" +
+	"\x10SIGNAL_TYPE_HOLD\x10\x03\x12\x15\n" +
+	"\x11SIGNAL_TYPE_SHORT\x10\x04\x12\x15\n" +
+	"\x11SIGNAL_TYPE_COVER\x10\x052\xc1\x01\n" +
+	"\x0eStrategyPlugin\x12W\n" +
+	"\x0fCalculateSignal\x12).strategyplugin.v1.CalculateSignalRequest\x1a\x19.strategyplugin.v1.Signal\x12V\n" +
+	"\rStreamTickers\x12\x19.strategyplugin.v1.Ticker\x1a(.strategyplugin.v1.StreamTickersResponse(\x01B[ZYgithub.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler/strategy_pluginb\x06proto3"
+
+var (
+	file_strategy_proto_rawDescOnce sync.Once
+	file_strategy_proto_rawDescData []byte
+)
+
+func file_strategy_proto_rawDescGZIP() []byte {
+	file_strategy_proto_rawDescOnce.Do(func() {
+		file_strategy_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_strategy_proto_rawDesc), len(file_strategy_proto_rawDesc)))
+	})
+	return file_strategy_proto_rawDescData
+}
+
+var file_strategy_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_strategy_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_strategy_proto_goTypes = []any{
+	(SignalType)(0),                // 0: strategyplugin.v1.SignalType
+	(*Candle)(nil),                 // 1: strategyplugin.v1.Candle
+	(*PositionUnit)(nil),           // 2: strategyplugin.v1.PositionUnit
+	(*PositionState)(nil),          // 3: strategyplugin.v1.PositionState
+	(*CalculateSignalRequest)(nil), // 4: strategyplugin.v1.CalculateSignalRequest
+	(*Signal)(nil),                 // 5: strategyplugin.v1.Signal
+	(*Ticker)(nil),                 // 6: strategyplugin.v1.Ticker
+	(*StreamTickersResponse)(nil),  // 7: strategyplugin.v1.StreamTickersResponse
+	(*timestamppb.Timestamp)(nil),  // 8: google.protobuf.Timestamp
+}
+var file_strategy_proto_depIdxs = []int32{
+	8,  // 0: strategyplugin.v1.Candle.start:type_name -> google.protobuf.Timestamp
+	8,  // 1: strategyplugin.v1.PositionUnit.time:type_name -> google.protobuf.Timestamp
+	0,  // 2: strategyplugin.v1.PositionState.side:type_name -> strategyplugin.v1.SignalType
+	2,  // 3: strategyplugin.v1.PositionState.units:type_name -> strategyplugin.v1.PositionUnit
+	1,  // 4: strategyplugin.v1.CalculateSignalRequest.candles:type_name -> strategyplugin.v1.Candle
+	3,  // 5: strategyplugin.v1.CalculateSignalRequest.position:type_name -> strategyplugin.v1.PositionState
+	0,  // 6: strategyplugin.v1.Signal.type:type_name -> strategyplugin.v1.SignalType
+	8,  // 7: strategyplugin.v1.Ticker.time:type_name -> google.protobuf.Timestamp
+	4,  // 8: strategyplugin.v1.StrategyPlugin.CalculateSignal:input_type -> strategyplugin.v1.CalculateSignalRequest
+	6,  // 9: strategyplugin.v1.StrategyPlugin.StreamTickers:input_type -> strategyplugin.v1.Ticker
+	5,  // 10: strategyplugin.v1.StrategyPlugin.CalculateSignal:output_type -> strategyplugin.v1.Signal
+	7,  // 11: strategyplugin.v1.StrategyPlugin.StreamTickers:output_type -> strategyplugin.v1.StreamTickersResponse
+	10, // [10:12] is the sub-list for method output_type
+	8,  // [8:10] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_strategy_proto_init() }
+func file_strategy_proto_init() {
+	if File_strategy_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_strategy_proto_rawDesc), len(file_strategy_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_strategy_proto_goTypes,
+		DependencyIndexes: file_strategy_proto_depIdxs,
+		EnumInfos:         file_strategy_proto_enumTypes,
+		MessageInfos:      file_strategy_proto_msgTypes,
+	}.Build()
+	File_strategy_proto = out.File
+	file_strategy_proto_goTypes = nil
+	file_strategy_proto_depIdxs = nil
+}
@@ -0,0 +1,173 @@
+// StrategyPlugin is the contract for strategies that run outside orchestration_api, e.g. in Python. The signal
+// engine calls a plugin the way it calls a local strategy: it sends the symbol's candle history and position on
+// every signal check, streams the ticker to it, and reads back a signal. The position is tracked on our side, a
+// plugin only ever sees it, so a plugin can restart without losing a trade.
+//
+// A plugin also serves the standard grpc.health.v1.Health service, signals are only asked for while it reports
+// SERVING for the empty service name, and a call that fails or runs past its timeout is a hold.
+//
+// After changing this file regenerate the Go code, in this directory, with
+//
+//	protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative strategy.proto
+//
+// and a Python plugin's stubs with python -m grpc_tools.protoc -I. --python_out=. --grpc_python_out=. strategy.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: strategy.proto
+
+package strategy_plugin
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	StrategyPlugin_CalculateSignal_FullMethodName = "/strategyplugin.v1.StrategyPlugin/CalculateSignal"
+	StrategyPlugin_StreamTickers_FullMethodName   = "/strategyplugin.v1.StrategyPlugin/StreamTickers"
+)
+
+// StrategyPluginClient is the client API for StrategyPlugin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type StrategyPluginClient interface {
+	// CalculateSignal is asked on every signal check, a hold when there is nothing to do.
+	CalculateSignal(ctx context.Context, in *CalculateSignalRequest, opts ...grpc.CallOption) (*Signal, error)
+	// StreamTickers carries every tick of the symbols the plugin trades, best effort, ticks are dropped rather than
+	// held up when the plugin falls behind.
+	StreamTickers(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[Ticker, StreamTickersResponse], error)
+}
+
+type strategyPluginClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewStrategyPluginClient(cc grpc.ClientConnInterface) StrategyPluginClient {
+	return &strategyPluginClient{cc}
+}
+
+func (c *strategyPluginClient) CalculateSignal(ctx context.Context, in *CalculateSignalRequest, opts ...grpc.CallOption) (*Signal, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Signal)
+	err := c.cc.Invoke(ctx, StrategyPlugin_CalculateSignal_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *strategyPluginClient) StreamTickers(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[Ticker, StreamTickersResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &StrategyPlugin_ServiceDesc.Streams[0], StrategyPlugin_StreamTickers_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Ticker, StreamTickersResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StrategyPlugin_StreamTickersClient = grpc.ClientStreamingClient[Ticker, StreamTickersResponse]
+
+// StrategyPluginServer is the server API for StrategyPlugin service.
+// All implementations must embed UnimplementedStrategyPluginServer
+// for forward compatibility.
+type StrategyPluginServer interface {
+	// CalculateSignal is asked on every signal check, a hold when there is nothing to do.
+	CalculateSignal(context.Context, *CalculateSignalRequest) (*Signal, error)
+	// StreamTickers carries every tick of the symbols the plugin trades, best effort, ticks are dropped rather than
+	// held up when the plugin falls behind.
+	StreamTickers(grpc.ClientStreamingServer[Ticker, StreamTickersResponse]) error
+	mustEmbedUnimplementedStrategyPluginServer()
+}
+
+// UnimplementedStrategyPluginServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedStrategyPluginServer struct{}
+
+func (UnimplementedStrategyPluginServer) CalculateSignal(context.Context, *CalculateSignalRequest) (*Signal, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CalculateSignal not implemented")
+}
+func (UnimplementedStrategyPluginServer) StreamTickers(grpc.ClientStreamingServer[Ticker, StreamTickersResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamTickers not implemented")
+}
+func (UnimplementedStrategyPluginServer) mustEmbedUnimplementedStrategyPluginServer() {}
+func (UnimplementedStrategyPluginServer) testEmbeddedByValue()                        {}
+
+// UnsafeStrategyPluginServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to StrategyPluginServer will
+// result in compilation errors.
+type UnsafeStrategyPluginServer interface {
+	mustEmbedUnimplementedStrategyPluginServer()
+}
+
+func RegisterStrategyPluginServer(s grpc.ServiceRegistrar, srv StrategyPluginServer) {
+	// If the following call pancis, it indicates UnimplementedStrategyPluginServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&StrategyPlugin_ServiceDesc, srv)
+}
+
+func _StrategyPlugin_CalculateSignal_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CalculateSignalRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StrategyPluginServer).CalculateSignal(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StrategyPlugin_CalculateSignal_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StrategyPluginServer).CalculateSignal(ctx, req.(*CalculateSignalRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StrategyPlugin_StreamTickers_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(StrategyPluginServer).StreamTickers(&grpc.GenericServerStream[Ticker, StreamTickersResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StrategyPlugin_StreamTickersServer = grpc.ClientStreamingServer[Ticker, StreamTickersResponse]
+
+// StrategyPlugin_ServiceDesc is the grpc.ServiceDesc for StrategyPlugin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var StrategyPlugin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "strategyplugin.v1.StrategyPlugin",
+	HandlerType: (*StrategyPluginServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CalculateSignal",
+			Handler:    _StrategyPlugin_CalculateSignal_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamTickers",
+			Handler:       _StrategyPlugin_StreamTickers_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "strategy.proto",
+}
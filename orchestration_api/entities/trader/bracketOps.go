@@ -0,0 +1,309 @@
+package trader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+	cb_models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models/coinbase"
+)
+
+const (
+	stopLimitSlippagePct   = 0.5  // how far past a stop-only exit's trigger its limit sits, so it still fills in a fast move
+	bracketResizeTolerance = 0.01 // re-place the bracket once the position drifts this far from what it covers
+)
+
+// getSignalStopLoss is the tighter of the signal's fixed and trailing stops; strategies set one or the other. A
+// short's stops sit above the price, so the tighter one is the lower.
+func getSignalStopLoss(s models.Signal, short bool) float64 {
+	if !short {
+		return max(s.StopLoss, s.TrailingStop)
+	}
+	if s.StopLoss <= 0 || (s.TrailingStop > 0 && s.TrailingStop < s.StopLoss) {
+		return s.TrailingStop
+	}
+	return s.StopLoss
+}
+
+// updateBracketLevels takes the exit levels off a signal. A buy or a short sets them fresh, a hold (the signal
+// engine sends one whenever the trailing stop ratchets) only ever tightens the stop of a bracket already in place.
+func (t *Trader) updateBracketLevels(s models.Signal) {
+	switch s.Type {
+	case enum.SignalBuy, enum.SignalShort:
+		short := s.Type == enum.SignalShort
+		stopLoss := getSignalStopLoss(s, short)
+		if stopLoss <= 0 {
+			return
+		}
+		if t.state.Bracket != nil && t.state.Bracket.Short != short {
+			// the old bracket protects the side being closed
+			if t.cancelBracket() != nil {
+				return
+			}
+			t.state.Bracket = nil
+		}
+		if t.state.Bracket == nil {
+			t.state.Bracket = &models.Bracket{Short: short}
+		}
+		t.state.Bracket.TakeProfit = s.TakeProfit
+		t.state.Bracket.StopLoss = stopLoss
+	case enum.SignalHold:
+		b := t.state.Bracket
+		if b == nil {
+			return
+		}
+		stopLoss := getSignalStopLoss(s, b.Short)
+		if stopLoss <= 0 || (!b.Short && stopLoss <= b.StopLoss) || (b.Short && stopLoss >= b.StopLoss) {
+			return
+		}
+		b.StopLoss = stopLoss
+		if s.TakeProfit > 0 {
+			b.TakeProfit = s.TakeProfit
+		}
+	}
+	t.syncBracket()
+}
+
+// getBracketDirection is 1 for a bracket behind a long and -1 behind a short, so the level checks read the same
+// for both.
+func getBracketDirection(b *models.Bracket) float64 {
+	if b.Short {
+		return -1
+	}
+	return 1
+}
+
+// syncBracket makes the exit order on the exchange match the position and the strategy's levels: placed once the
+// position settles, re-placed when the position or take profit changes, and edited as the stop ratchets.
+func (t *Trader) syncBracket() {
+	b := t.state.Bracket
+	if b == nil || t.hasPendingOrder() {
+		return
+	}
+	dir := getBracketDirection(b)
+	tokens := dir * t.state.ActualPositionToken
+	if tokens <= 0 {
+		if dir*t.state.TargetPositionUSD > 0 {
+			return // the entry hasn't gone out yet, it takes the bracket with it where the venue allows
+		}
+		if b.IsPlaced() {
+			t.cancelBracket()
+		}
+		t.state.Bracket = nil
+		return
+	}
+	if !b.IsPlaced() {
+		t.placeBracket()
+		return
+	}
+	if math.Abs(b.GetRestingTokens()-tokens) > tokens*bracketResizeTolerance || b.TakeProfit != b.PlacedTakeProfit || (b.StopLoss-b.PlacedStopLoss)*dir < 0 {
+		if t.cancelBracket() == nil {
+			t.placeBracket()
+		}
+		return
+	}
+	// the trailing stop ratchets on every tick, only chase it once it has moved far enough to be worth an edit
+	if (b.StopLoss-b.PlacedStopLoss)*dir/b.PlacedStopLoss*10000.0 >= repriceThresholdBps {
+		if err := t.editBracketStop(); err != nil && t.cancelBracket() == nil {
+			t.placeBracket()
+		}
+	}
+}
+
+// isPriceInsideBracket reports whether the price is still between the bracket's levels. Once it is through one,
+// the strategy's own exit fires on its next signal instead.
+func isPriceInsideBracket(b *models.Bracket, price float64) bool {
+	dir := getBracketDirection(b)
+	return price > 0 && (price-b.StopLoss)*dir > 0 && (b.TakeProfit <= 0 || (b.TakeProfit-price)*dir > 0)
+}
+
+// getSpecWithAttachedBracket attaches the bracket to an order that opens or adds to the side it protects while no
+// exit is resting yet, so the exchange protects the entry from the moment it fills. Later adds resize the resting
+// bracket instead.
+func (t *Trader) getSpecWithAttachedBracket(side enum.SignalType, reducing bool, spec models.OrderSpec) models.OrderSpec {
+	b := t.state.Bracket
+	if b == nil || b.IsPlaced() || reducing || b.Short != (side == enum.SignalSell) || b.TakeProfit <= 0 || time.Now().Before(b.RetryAfter) {
+		return spec
+	}
+	if !isPriceInsideBracket(b, t.state.CurrentPriceUSDPerToken) {
+		return spec
+	}
+	spec.AttachedBracket = &models.AttachedBracket{TakeProfit: b.TakeProfit, StopLoss: b.StopLoss}
+	return spec
+}
+
+// trackAttachedBracket takes on the exit the exchange attached to an entry order. It covers nothing until the
+// entry fills; a venue that couldn't attach it leaves the bracket to be placed once the entry settles.
+func (t *Trader) trackAttachedBracket(response cb_models.CreateOrderResponse, spec models.OrderSpec) {
+	b := t.state.Bracket
+	if b == nil || spec.AttachedBracket == nil || response.AttachedOrderID == "" {
+		return
+	}
+	log.Printf("[Trader %s] bracket %s attached to %s: take profit %v, stop %v", t.cfg.Symbol, response.AttachedOrderID, response.OrderID, spec.AttachedBracket.TakeProfit, spec.AttachedBracket.StopLoss)
+	b.OrderID = response.AttachedOrderID
+	b.EntryOrderID = response.OrderID
+	b.PlacedTokens = 0
+	b.PlacedTakeProfit = spec.AttachedBracket.TakeProfit
+	b.PlacedStopLoss = spec.AttachedBracket.StopLoss
+	b.FilledTokens, b.FilledUSD, b.PaidFeesUSD = 0, 0, 0
+	t.persistState()
+}
+
+// followAttachedBracket sizes an attached bracket to what its entry has filled. An entry that closes without
+// filling anything takes its bracket with it.
+func (t *Trader) followAttachedBracket(up models.OrderUpdate) {
+	b := t.state.Bracket
+	if b == nil || b.EntryOrderID == "" || b.EntryOrderID != up.OrderID {
+		return
+	}
+	filledTokens, _ := strconv.ParseFloat(up.FilledQty, 64)
+	b.PlacedTokens = max(filledTokens, b.PlacedTokens)
+	if up.Status == "FILLED" || isOrderClosedUnfilled(up.Status) {
+		b.EntryOrderID = ""
+		if b.PlacedTokens <= 0 {
+			b.OrderID = ""
+		}
+	}
+}
+
+func (t *Trader) placeBracket() {
+	b := t.state.Bracket
+	if time.Now().Before(b.RetryAfter) || !isPriceInsideBracket(b, t.state.CurrentPriceUSDPerToken) {
+		return
+	}
+
+	dir := getBracketDirection(b)
+	tokens := dir * t.state.ActualPositionToken
+	spec := models.OrderSpec{Type: enum.OrderTypeBracket, LimitPrice: b.TakeProfit, StopPrice: b.StopLoss}
+	if b.TakeProfit <= 0 {
+		spec = models.OrderSpec{Type: enum.OrderTypeStopLimit, LimitPrice: b.StopLoss * (1 - dir*stopLimitSlippagePct/100.0), StopPrice: b.StopLoss}
+	}
+	spec = t.getDerivativesSpec(spec, true)
+	var response cb_models.CreateOrderResponse
+	err := t.executeWithTimeout(10, "Place bracket", func(ctx context.Context) error {
+		var err error
+		response, err = t.exchange.CreateOrder(ctx, t.cfg.Symbol, tokens*spec.LimitPrice, b.Short, spec)
+		return err
+	})
+	if err != nil {
+		b.RetryAfter = time.Now().Add(enum.GetTimeDurationFromCandleSize(t.cfg.CandleSize))
+		return
+	}
+	log.Printf("[Trader %s] %s %s protecting %v tokens: take profit %v, stop %v", t.cfg.Symbol, spec.Type.String(), response.OrderID, tokens, b.TakeProfit, b.StopLoss)
+	b.OrderID = response.OrderID
+	b.EntryOrderID = ""
+	b.PlacedTokens = tokens
+	b.FilledTokens, b.FilledUSD, b.PaidFeesUSD = 0, 0, 0
+	b.PlacedTakeProfit = b.TakeProfit
+	b.PlacedStopLoss = b.StopLoss
+	t.persistState()
+}
+
+func (t *Trader) editBracketStop() error {
+	b := t.state.Bracket
+	limitPrice := b.TakeProfit
+	if limitPrice <= 0 {
+		limitPrice = b.StopLoss * (1 - getBracketDirection(b)*stopLimitSlippagePct/100.0)
+	}
+	body, err := json.Marshal(cb_models.EditOrderRequest{
+		OrderID:   b.OrderID,
+		Price:     strconv.FormatFloat(limitPrice, 'f', -1, 64),
+		Size:      strconv.FormatFloat(b.PlacedTokens, 'f', -1, 64),
+		StopPrice: strconv.FormatFloat(b.StopLoss, 'f', -1, 64),
+	})
+	if err != nil {
+		return err
+	}
+	err = t.executeWithTimeout(5, "Move bracket stop", func(ctx context.Context) error {
+		response, err := t.exchange.EditOrder(ctx, body)
+		if err == nil && !response.Success {
+			err = fmt.Errorf("edit rejected: %s", response.Error)
+		}
+		return err
+	})
+	if err == nil {
+		log.Printf("[Trader %s] moved bracket %s stop from %v to %v", t.cfg.Symbol, b.OrderID, b.PlacedStopLoss, b.StopLoss)
+		b.PlacedStopLoss = b.StopLoss
+		t.persistState()
+	}
+	return err
+}
+
+// cancelBracket pulls the exit order, which frees the tokens it holds so the trader can sell them itself.
+func (t *Trader) cancelBracket() error {
+	b := t.state.Bracket
+	if !b.IsPlaced() {
+		return nil
+	}
+	orderID := b.OrderID
+	err := t.executeWithTimeout(10, "Cancel bracket", func(ctx context.Context) error {
+		return t.exchange.CancelOrders(ctx, orderID)
+	})
+	if err == nil {
+		b.OrderID = ""
+		t.persistState()
+	}
+	return err
+}
+
+// cancelBracketInTheWay pulls the bracket before an order that trades against the position it protects: the
+// tokens a long's bracket holds have to be freed before they can be sold, and a short's bracket would buy back
+// what the trader is covering.
+func (t *Trader) cancelBracketInTheWay(side enum.SignalType) error {
+	b := t.state.Bracket
+	if b == nil || b.Short != (side == enum.SignalBuy) {
+		return nil
+	}
+	return t.cancelBracket()
+}
+
+// handleBracketUpdate books an exit the exchange made on the trader's behalf. Updates carry running totals, so each
+// one books only what filled since the last. The target shrinks with the position rather than have the trader buy
+// (or short) straight back in, and goes to zero once the bracket has filled.
+func (t *Trader) handleBracketUpdate(up models.OrderUpdate) {
+	b := t.state.Bracket
+	filledTokens, _ := strconv.ParseFloat(up.FilledQty, 64)
+	filledUSD, _ := strconv.ParseFloat(up.FilledValue, 64)
+	fees, _ := strconv.ParseFloat(up.Fees, 64)
+	if tokens := filledTokens - b.FilledTokens; tokens > 0 {
+		t.bookBracketFill(up.OrderID, up.Side, tokens, filledUSD-b.FilledUSD, max(fees-b.PaidFeesUSD, 0))
+		b.FilledTokens = filledTokens
+		b.FilledUSD = filledUSD
+		b.PaidFeesUSD = max(fees, b.PaidFeesUSD)
+	}
+
+	switch {
+	case up.Status == "FILLED":
+		t.state.TargetPositionUSD = 0
+		t.state.Bracket = nil
+	case isOrderClosedUnfilled(up.Status):
+		b.OrderID = "" // re-placed on the next sync if the position is still there
+	}
+	t.persistState()
+}
+
+// bookBracketFill moves the position by a fill of the bracket and scales the target down by the
+// share of the position that was exited.
+func (t *Trader) bookBracketFill(orderID string, side string, tokens float64, usd float64, fees float64) {
+	t.journalFill(orderID, side, tokens, usd, fees)
+	before := math.Abs(t.state.ActualPositionToken)
+	if side == "BUY" {
+		// a short's bracket buys it back
+		t.state.UsdAmountPerFulfilledOrders += usd
+		t.state.ActualPositionToken += tokens
+	} else {
+		t.state.UsdAmountPerFulfilledOrders -= usd
+		t.state.ActualPositionToken -= tokens
+	}
+	if before > 0 {
+		t.state.TargetPositionUSD *= max(before-tokens, 0) / before
+	}
+	log.Printf("[Trader %s] bracket %s exited %v tokens for %v", t.cfg.Symbol, orderID, tokens, usd)
+	t.reportProfitLossTotal()
+}
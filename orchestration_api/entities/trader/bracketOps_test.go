@@ -0,0 +1,182 @@
+package trader
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/journal"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+	cb_models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models/coinbase"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/persistence"
+)
+
+// newTestTrader is a trader on ETH-USD with a journal and a state store in a temp dir and nothing behind its exchange.
+func newTestTrader(t *testing.T, state models.TraderState) *Trader {
+	t.Helper()
+	j, err := journal.Open(filepath.Join(t.TempDir(), "journal.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { j.Close() })
+	store, err := persistence.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return &Trader{
+		cfg:                    TradeCfg{Symbol: "ETH-USD", AllocatedFunds: 1000},
+		journal:                j,
+		store:                  store,
+		profitLossTotalChannel: make(chan models.TokenProfitLossUpdate, 100),
+		state:                  state,
+	}
+}
+
+func TestHandleBracketUpdate(t *testing.T) {
+	type update struct{ status, qty, value string }
+	tests := []struct {
+		name        string
+		updates     []update
+		wantTokens  float64
+		wantTarget  float64
+		wantBracket bool
+		wantPlaced  bool
+		wantFills   int
+	}{
+		{
+			name:        "resting",
+			updates:     []update{{"OPEN", "0", "0"}},
+			wantTokens:  10,
+			wantTarget:  1000,
+			wantBracket: true,
+			wantPlaced:  true,
+		},
+		{
+			name:        "partly filled",
+			updates:     []update{{"OPEN", "4", "440"}, {"OPEN", "4", "440"}},
+			wantTokens:  6,
+			wantTarget:  600,
+			wantBracket: true,
+			wantPlaced:  true,
+			wantFills:   1,
+		},
+		{
+			name:       "partly filled then filled",
+			updates:    []update{{"OPEN", "4", "440"}, {"FILLED", "10", "1100"}},
+			wantTokens: 0,
+			wantTarget: 0,
+			wantFills:  2,
+		},
+		{
+			name:        "partly filled then cancelled",
+			updates:     []update{{"OPEN", "4", "440"}, {"CANCELLED", "4", "440"}},
+			wantTokens:  6,
+			wantTarget:  600,
+			wantBracket: true,
+			wantFills:   1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newTestTrader(t, models.TraderState{
+				ActualPositionToken:         10,
+				UsdAmountPerFulfilledOrders: 1000,
+				TargetPositionUSD:           1000,
+				CurrentPriceUSDPerToken:     100,
+				Bracket:                     &models.Bracket{TakeProfit: 110, StopLoss: 90, OrderID: "b", PlacedTakeProfit: 110, PlacedStopLoss: 90, PlacedTokens: 10},
+			})
+			for _, u := range tt.updates {
+				tr.handleOrderUpdate(models.OrderUpdate{OrderID: "b", Status: u.status, Side: "SELL", FilledQty: u.qty, FilledValue: u.value})
+			}
+
+			if math.Abs(tr.state.ActualPositionToken-tt.wantTokens) > 1e-9 || math.Abs(tr.state.TargetPositionUSD-tt.wantTarget) > 1e-9 {
+				t.Errorf("position %v tokens with target %v, want %v with %v", tr.state.ActualPositionToken, tr.state.TargetPositionUSD, tt.wantTokens, tt.wantTarget)
+			}
+			if (tr.state.Bracket != nil) != tt.wantBracket || tr.state.Bracket.IsPlaced() != tt.wantPlaced {
+				t.Errorf("bracket %+v, want kept %v and placed %v", tr.state.Bracket, tt.wantBracket, tt.wantPlaced)
+			}
+			entries, err := tr.journal.Query("ETH-USD", time.Time{}, time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != tt.wantFills {
+				t.Errorf("journaled %d fills, want %d", len(entries), tt.wantFills)
+			}
+
+			snapshots, err := LoadTraderSnapshots(tr.store)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if saved := snapshots["ETH-USD"].State; saved.ActualPositionToken != tr.state.ActualPositionToken || saved.Bracket.IsPlaced() != tt.wantPlaced {
+				t.Errorf("persisted %v tokens and bracket %+v, want the state after the last update", saved.ActualPositionToken, saved.Bracket)
+			}
+		})
+	}
+}
+
+func TestGetSpecWithAttachedBracket(t *testing.T) {
+	long := func() *models.Bracket { return &models.Bracket{TakeProfit: 110, StopLoss: 90} }
+	tests := []struct {
+		name     string
+		bracket  *models.Bracket
+		side     enum.SignalType
+		reducing bool
+		price    float64
+		want     bool
+	}{
+		{name: "long entry", bracket: long(), side: enum.SignalBuy, price: 100, want: true},
+		{name: "short entry", bracket: &models.Bracket{Short: true, TakeProfit: 90, StopLoss: 110}, side: enum.SignalSell, price: 100, want: true},
+		{name: "no bracket", side: enum.SignalBuy, price: 100},
+		{name: "already resting", bracket: &models.Bracket{TakeProfit: 110, StopLoss: 90, OrderID: "b"}, side: enum.SignalBuy, price: 100},
+		{name: "reducing", bracket: long(), side: enum.SignalBuy, reducing: true, price: 100},
+		{name: "other side", bracket: long(), side: enum.SignalSell, price: 100},
+		{name: "no take profit", bracket: &models.Bracket{StopLoss: 90}, side: enum.SignalBuy, price: 100},
+		{name: "through the stop", bracket: long(), side: enum.SignalBuy, price: 89},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &Trader{state: models.TraderState{Bracket: tt.bracket, CurrentPriceUSDPerToken: tt.price}}
+			spec := tr.getSpecWithAttachedBracket(tt.side, tt.reducing, models.NewMarketOrderSpec())
+			if (spec.AttachedBracket != nil) != tt.want {
+				t.Fatalf("attached %+v, want attached %v", spec.AttachedBracket, tt.want)
+			}
+			if tt.want && (spec.AttachedBracket.TakeProfit != tt.bracket.TakeProfit || spec.AttachedBracket.StopLoss != tt.bracket.StopLoss) {
+				t.Errorf("attached %+v, want the bracket's levels", spec.AttachedBracket)
+			}
+		})
+	}
+}
+
+func TestFollowAttachedBracket(t *testing.T) {
+	type update struct{ status, qty string }
+	tests := []struct {
+		name       string
+		updates    []update
+		wantPlaced bool
+		wantTokens float64
+		wantEntry  string
+	}{
+		{name: "entry resting", updates: []update{{"OPEN", "0"}}, wantPlaced: true, wantEntry: "e"},
+		{name: "entry partly filled", updates: []update{{"OPEN", "4"}}, wantPlaced: true, wantTokens: 4, wantEntry: "e"},
+		{name: "entry filled", updates: []update{{"OPEN", "4"}, {"FILLED", "10"}}, wantPlaced: true, wantTokens: 10},
+		{name: "entry cancelled unfilled", updates: []update{{"CANCELLED", "0"}}},
+		{name: "entry cancelled partly filled", updates: []update{{"OPEN", "4"}, {"CANCELLED", "4"}}, wantPlaced: true, wantTokens: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newTestTrader(t, models.TraderState{Bracket: &models.Bracket{TakeProfit: 110, StopLoss: 90}, CurrentPriceUSDPerToken: 100})
+			spec := models.OrderSpec{Type: enum.OrderTypeLimit, LimitPrice: 100, AttachedBracket: &models.AttachedBracket{TakeProfit: 110, StopLoss: 90}}
+			tr.trackAttachedBracket(cb_models.CreateOrderResponse{OrderID: "e", AttachedOrderID: "b"}, spec)
+			for _, u := range tt.updates {
+				tr.followAttachedBracket(models.OrderUpdate{OrderID: "e", Status: u.status, Side: "BUY", FilledQty: u.qty})
+			}
+			b := tr.state.Bracket
+			if b.IsPlaced() != tt.wantPlaced || b.PlacedTokens != tt.wantTokens || b.EntryOrderID != tt.wantEntry {
+				t.Errorf("bracket %+v, want placed %v for %v tokens behind entry %q", b, tt.wantPlaced, tt.wantTokens, tt.wantEntry)
+			}
+		})
+	}
+}
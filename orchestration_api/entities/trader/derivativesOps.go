@@ -0,0 +1,131 @@
+package trader
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+const (
+	derivativesPollPeriod       = time.Minute // how often funding is accrued and the liquidation price refreshed
+	liquidationDeleverageFactor = 0.5         // what's left of the target each poll the liquidation price is too close
+)
+
+// DerivativesCfg lets a trader on a perpetuals venue (Coinbase INTX, Deribit) go short and lever its allocated
+// funds. Left at the defaults the trader is long-only spot, as before.
+type DerivativesCfg struct {
+	AllowShort                bool            `json:"allowShort"`
+	Leverage                  float64         `json:"leverage"` // a full position is AllocatedFunds × Leverage of notional
+	MarginType                enum.MarginType `json:"marginType"`
+	MinLiquidationDistancePct float64         `json:"minLiquidationDistancePct"` // closer than this, no new exposure and the target is cut
+}
+
+func DefaultDerivativesCfg() DerivativesCfg {
+	return DerivativesCfg{
+		AllowShort:                false,
+		Leverage:                  1,
+		MarginType:                enum.MarginCross,
+		MinLiquidationDistancePct: 15,
+	}
+}
+
+// IsEnabled reports whether the trader trades derivatives, which is when orders carry margin parameters and funding
+// and the liquidation price are watched.
+func (c DerivativesCfg) IsEnabled() bool {
+	return c.AllowShort || c.Leverage > 1
+}
+
+// getBuyingPower is the notional a full position is sized against.
+func (t *Trader) getBuyingPower() float64 {
+	return t.cfg.AllocatedFunds * max(t.cfg.Derivatives.Leverage, 1)
+}
+
+// getDerivativesSpec adds the trader's leverage and margin type to an order on a derivatives venue. Spot specs go
+// out untouched.
+func (t *Trader) getDerivativesSpec(spec models.OrderSpec, reduceOnly bool) models.OrderSpec {
+	cfg := t.cfg.Derivatives
+	if !cfg.IsEnabled() {
+		return spec
+	}
+	spec.Leverage = max(cfg.Leverage, 1)
+	spec.MarginType = cfg.MarginType
+	spec.ReduceOnly = reduceOnly
+	return spec
+}
+
+// isReducingOrder reports whether an order only takes the position towards flat without crossing it.
+func isReducingOrder(side enum.SignalType, amount float64, positionUSD float64) bool {
+	if side == enum.SignalBuy {
+		return positionUSD < 0 && amount <= -positionUSD
+	}
+	return positionUSD > 0 && amount <= positionUSD
+}
+
+// isLiquidationTooClose reports whether the last known liquidation price is within the configured distance.
+func (t *Trader) isLiquidationTooClose() bool {
+	distance := models.GetLiquidationDistancePct(t.state.CurrentPriceUSDPerToken, t.state.LiquidationPrice)
+	return distance < t.cfg.Derivatives.MinLiquidationDistancePct
+}
+
+// pollDerivatives accrues funding and refreshes the liquidation price at most once per poll period.
+func (t *Trader) pollDerivatives() {
+	if !t.cfg.Derivatives.IsEnabled() || time.Since(t.timeOfLastDerivativesPoll) < derivativesPollPeriod {
+		return
+	}
+	t.timeOfLastDerivativesPoll = time.Now()
+	t.accrueFunding()
+	t.checkLiquidationDistance()
+}
+
+// accrueFunding books the funding the position paid, or received, since the last accrual, pro rata at the
+// current rate. Positive funding costs longs and pays shorts.
+func (t *Trader) accrueFunding() {
+	now := time.Now()
+	last := t.state.LastFundingAccrual
+	if last.IsZero() || t.state.ActualPositionToken == 0 {
+		t.state.LastFundingAccrual = now
+		return
+	}
+	var rate models.FundingRate
+	err := t.executeWithTimeout(5, "Get funding rate", func(ctx context.Context) error {
+		var err error
+		rate, err = t.exchange.GetFundingRate(ctx, t.cfg.Symbol)
+		return err
+	})
+	if err != nil {
+		return // the next poll accrues over the whole gap
+	}
+	paid := rate.GetFundingUSD(t.state.ActualPositionUSD, now.Sub(last))
+	t.state.FundingPaidUSD += paid
+	t.state.LastFundingAccrual = now
+	log.Printf("[Trader %s] funding at %v per %s: paid %v, %v in total", t.cfg.Symbol, rate.Rate, rate.Interval, paid, t.state.FundingPaidUSD)
+}
+
+// checkLiquidationDistance refreshes the liquidation price from the exchange and, when the price has come within
+// the configured distance of it, cuts the target so the trader sheds exposure on its next pass.
+func (t *Trader) checkLiquidationDistance() {
+	var position models.PerpPosition
+	err := t.executeWithTimeout(5, "Get perp position", func(ctx context.Context) error {
+		var err error
+		position, err = t.exchange.GetPerpPosition(ctx, t.cfg.Symbol)
+		return err
+	})
+	if err != nil {
+		return
+	}
+	t.state.LiquidationPrice = position.LiquidationPrice
+	if position.Size == 0 || !t.isLiquidationTooClose() {
+		return
+	}
+	distance := models.GetLiquidationDistancePct(t.state.CurrentPriceUSDPerToken, position.LiquidationPrice)
+	newTarget := t.state.TargetPositionUSD * liquidationDeleverageFactor
+	log.Printf("[Trader %s] liquidation at %v is %.2f%% away, under the %.2f%% minimum: cutting the target from %v to %v", t.cfg.Symbol, position.LiquidationPrice, distance, t.cfg.Derivatives.MinLiquidationDistancePct, t.state.TargetPositionUSD, newTarget)
+	if math.Abs(newTarget) < t.cfg.AllocatedFunds*0.01 {
+		newTarget = 0
+	}
+	t.state.TargetPositionUSD = newTarget
+}
@@ -0,0 +1,222 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+const executionPollPeriod = 5 * time.Second // the soonest an execution sends its next slice after the last one
+
+// executeDeficit sends a deficit as one order when it's small enough or no algo is configured, and otherwise hands
+// it to the execution working it in slices.
+func (t *Trader) executeDeficit(side enum.SignalType, amount float64, tolerance float64) {
+	execCfg := t.cfg.Execution
+	if execCfg.Algo == enum.ExecutionImmediate || execCfg.MaxSliceUSD <= 0 || (t.state.Execution == nil && amount <= execCfg.MaxSliceUSD) {
+		t.endExecution("replaced")
+		t.submitToCoinbase(side, amount, t.getOrderSpec(side))
+		return
+	}
+	t.workExecution(side, amount, tolerance)
+}
+
+// workExecutionIfDue lets an execution send its next slice on a price update instead of waiting for the trader's
+// ticker, which only fires every fifth of a candle.
+func (t *Trader) workExecutionIfDue() {
+	e := t.state.Execution
+	if e == nil || t.hasPendingOrder() || time.Now().Before(e.NextSliceTime) {
+		return
+	}
+	t.executeTradesToMakeActualTrackTarget()
+	t.persistState()
+}
+
+// workExecution sends the next slice of the deficit once it's due. A deficit that grew past what the execution
+// started with, or flipped side, means the target moved, so the execution starts over from the new deficit. A
+// change of algo applies from the next execution.
+func (t *Trader) workExecution(side enum.SignalType, amount float64, tolerance float64) {
+	e := t.state.Execution
+	if e == nil || e.Side != side || amount > e.TotalUSD+tolerance {
+		t.endExecution("replaced")
+		e = t.startExecution(side, amount)
+	}
+	if time.Now().Before(e.NextSliceTime) {
+		return
+	}
+
+	var slice float64
+	switch e.Algo {
+	case enum.ExecutionNativeTWAP:
+		if e.Slices > 0 {
+			// the exchange's TWAP ended short of the deficit, a fresh one picks up the rest on the next tick
+			t.endExecution("done")
+			return
+		}
+		if t.submitNativeTWAP(e, amount) == nil {
+			e.Slices++
+			t.reportExecutionProgress("working")
+			return
+		}
+		log.Printf("[Trader %s] falling back to sliced TWAP", t.cfg.Symbol)
+		e.Algo = enum.ExecutionTWAP
+		slice = t.getTWAPSlice(e, e.TotalUSD-amount)
+	case enum.ExecutionTWAP:
+		slice = t.getTWAPSlice(e, e.TotalUSD-amount)
+	case enum.ExecutionParticipation:
+		slice = t.getParticipationSlice(e)
+	}
+	e.NextSliceTime = maxTime(e.NextSliceTime, time.Now().Add(executionPollPeriod))
+	slice = min(slice, amount, t.cfg.Execution.MaxSliceUSD)
+	if slice < tolerance {
+		return // not enough of the schedule or the volume has come due to be worth an order
+	}
+
+	if t.submitToCoinbase(side, slice, t.getSliceSpec(side)) != nil {
+		return
+	}
+	e.Slices++
+	if e.Algo == enum.ExecutionParticipation {
+		e.AllowanceUSD -= slice
+	}
+	log.Printf("[Trader %s] %s slice %d: %v of %v left", t.cfg.Symbol, e.Algo.String(), e.Slices, slice, amount)
+	t.reportExecutionProgress("working")
+}
+
+func (t *Trader) startExecution(side enum.SignalType, amount float64) *models.Execution {
+	now := time.Now()
+	e := &models.Execution{
+		Algo:         t.cfg.Execution.Algo,
+		Side:         side,
+		TotalUSD:     amount,
+		ArrivalPrice: t.state.CurrentPriceUSDPerToken,
+		StartTime:    now,
+		EndTime:      now.Add(t.cfg.Execution.Duration),
+	}
+	// participation only counts volume traded from here on, and the newest candle is still forming
+	if candles := t.exchange.GetCandleHistory(t.cfg.Symbol).Candles; len(candles) > 1 {
+		e.LastCandleStart = candles[len(candles)-2].Start
+	}
+	t.state.Execution = e
+	log.Printf("[Trader %s] working a %s of %v with %s", t.cfg.Symbol, side.String(), amount, e.Algo.String())
+	t.reportExecutionProgress("working")
+	return e
+}
+
+// endExecution reports the execution's final progress and drops it. Safe to call without one.
+func (t *Trader) endExecution(status string) {
+	if t.state.Execution == nil {
+		return
+	}
+	t.reportExecutionProgress(status)
+	t.state.Execution = nil
+}
+
+// getExecutionBuckets splits the execution into slices no bigger than the max slice size.
+func (t *Trader) getExecutionBuckets(e *models.Execution) int {
+	return max(int(math.Ceil(e.TotalUSD/t.cfg.Execution.MaxSliceUSD)), 1)
+}
+
+// getTWAPSlice is what the schedule says should have been sent by the current bucket, less what already filled.
+// Buckets are evenly spaced over the horizon and the first one is due straight away.
+func (t *Trader) getTWAPSlice(e *models.Execution, filledUSD float64) float64 {
+	buckets := t.getExecutionBuckets(e)
+	bucketDuration := e.EndTime.Sub(e.StartTime) / time.Duration(buckets)
+	if bucketDuration <= 0 {
+		return e.TotalUSD - filledUSD
+	}
+	bucket := min(int(time.Since(e.StartTime)/bucketDuration)+1, buckets)
+	e.NextSliceTime = e.StartTime.Add(time.Duration(bucket) * bucketDuration)
+	return e.TotalUSD*float64(bucket)/float64(buckets) - filledUSD
+}
+
+// getParticipationSlice adds the configured share of every candle that closed since the last look to the
+// execution's allowance, and returns the allowance.
+func (t *Trader) getParticipationSlice(e *models.Execution) float64 {
+	candles := t.exchange.GetCandleHistory(t.cfg.Symbol).Candles
+	for i := 0; i < len(candles)-1; i++ {
+		c := candles[i]
+		if c.Start.After(e.LastCandleStart) {
+			e.AllowanceUSD += c.Volume * c.Close * t.cfg.Execution.ParticipationRate
+			e.LastCandleStart = c.Start
+		}
+	}
+	return e.AllowanceUSD
+}
+
+// getSliceSpec sends slices as IOC limits at most MaxSlippageBps through the last ticker, so a thin book can't walk
+// a slice away from where the market was quoted. Whatever doesn't fill is picked up by a later slice.
+func (t *Trader) getSliceSpec(side enum.SignalType) models.OrderSpec {
+	price := t.state.CurrentPriceUSDPerToken
+	if price <= 0 || t.cfg.Execution.MaxSlippageBps <= 0 {
+		return models.NewMarketOrderSpec()
+	}
+	return models.OrderSpec{Type: enum.OrderTypeLimitIOC, LimitPrice: getSlippageBoundPrice(side, price, t.cfg.Execution.MaxSlippageBps)}
+}
+
+// getSlippageBoundPrice is the worst price a slice accepts: over the market for a buy, under it for a sell.
+func getSlippageBoundPrice(side enum.SignalType, price float64, slippageBps float64) float64 {
+	if side == enum.SignalBuy {
+		return price * (1 + slippageBps/10000.0)
+	}
+	return price * (1 - slippageBps/10000.0)
+}
+
+// submitNativeTWAP hands the whole deficit to the exchange as one TWAP order over the configured horizon, capped
+// at the slippage bound from the arrival price. Exchanges without native TWAPs reject it.
+func (t *Trader) submitNativeTWAP(e *models.Execution, amount float64) error {
+	if e.ArrivalPrice <= 0 {
+		return fmt.Errorf("no price to cap the TWAP at")
+	}
+	spec := models.OrderSpec{
+		Type:       enum.OrderTypeTWAP,
+		LimitPrice: getSlippageBoundPrice(e.Side, e.ArrivalPrice, t.cfg.Execution.MaxSlippageBps),
+		Expiry:     e.EndTime,
+		Buckets:    t.getExecutionBuckets(e),
+	}
+	return t.submitToCoinbase(e.Side, amount, spec)
+}
+
+// reportExecutionProgress sends the execution's progress to the manager for the frontend. FilledUSD only counts
+// fills, not a slice still working.
+func (t *Trader) reportExecutionProgress(status string) {
+	e := t.state.Execution
+	if e == nil || t.executionProgressChannel == nil {
+		return
+	}
+	remaining := math.Abs(t.state.TargetPositionUSD - t.state.UsdAmountPerFulfilledOrders)
+	side := "BUY"
+	if e.Side == enum.SignalSell {
+		side = "SELL"
+	}
+	progress := models.ExecutionProgress{
+		Type:         "execution",
+		Symbol:       t.cfg.Symbol,
+		Algo:         e.Algo.String(),
+		Side:         side,
+		Status:       status,
+		TotalUSD:     e.TotalUSD,
+		FilledUSD:    min(max(e.TotalUSD-remaining, 0), e.TotalUSD),
+		Slices:       e.Slices,
+		ArrivalPrice: e.ArrivalPrice,
+		LastPrice:    t.state.CurrentPriceUSDPerToken,
+		StartTime:    e.StartTime,
+		EndTime:      e.EndTime,
+		Time:         time.Now(),
+	}
+	select {
+	case t.executionProgressChannel <- progress:
+	default:
+		// progress is informational, never hold up trading for it
+	}
+}
+
+func maxTime(a time.Time, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,181 @@
+package trader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+	cb_models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models/coinbase"
+)
+
+const (
+	makerPriceOffsetBps  = 1.0 // without a quote, how far inside the last trade a maker order rests, so post-only isn't rejected on the spot
+	repriceThresholdBps  = 5.0 // how far the market has to move away before a resting order chases it
+	repriceMinimumPeriod = 5 * time.Second
+)
+
+// getOrderSpec works orders as maker limits at the touch when the trader is configured for limit or post-only
+// execution and a price is known, and at market otherwise. Stop and bracket types don't make sense for tracking a
+// target, so they fall back to market too.
+func (t *Trader) getOrderSpec(side enum.SignalType) models.OrderSpec {
+	if t.useMarketForNextOrder {
+		t.useMarketForNextOrder = false
+		return models.NewMarketOrderSpec()
+	}
+	if t.state.CurrentPriceUSDPerToken <= 0 {
+		return models.NewMarketOrderSpec()
+	}
+	switch t.cfg.OrderType {
+	case enum.OrderTypeLimit, enum.OrderTypePostOnly:
+		return models.OrderSpec{Type: t.cfg.OrderType, LimitPrice: t.getMakerPrice(side)}
+	default:
+		return models.NewMarketOrderSpec()
+	}
+}
+
+// getMakerPrice joins the touch on the passive side: the best bid for a buy, the best ask for a sell. Without a
+// quote, or with a crossed one, it rests just inside the last trade instead.
+func (t *Trader) getMakerPrice(side enum.SignalType) float64 {
+	bid, ask, price := t.state.CurrentBidUSDPerToken, t.state.CurrentAskUSDPerToken, t.state.CurrentPriceUSDPerToken
+	if bid > 0 && ask >= bid {
+		if side == enum.SignalBuy {
+			return bid
+		}
+		return ask
+	}
+	if side == enum.SignalBuy {
+		return price * (1 - makerPriceOffsetBps/10000.0)
+	}
+	return price * (1 + makerPriceOffsetBps/10000.0)
+}
+
+func isMakerOrder(pending *models.PendingOrder) bool {
+	return pending.ExecutionType == enum.OrderTypeLimit || pending.ExecutionType == enum.OrderTypePostOnly
+}
+
+// repricePendingOrder moves a resting maker order back to the touch when the market has run away from it. Moves
+// towards the order are left alone, they fill it.
+func (t *Trader) repricePendingOrder() {
+	pending := t.state.PendingOrder
+	if pending == nil || !isMakerOrder(pending) || pending.CancelRequested || pending.LimitPrice <= 0 || t.state.CurrentPriceUSDPerToken <= 0 {
+		return
+	}
+	if time.Since(pending.LastRepriceTime) < repriceMinimumPeriod {
+		return
+	}
+	newPrice := t.getMakerPrice(pending.OrderType)
+	movedAway := newPrice > pending.LimitPrice
+	if pending.OrderType == enum.SignalSell {
+		movedAway = newPrice < pending.LimitPrice
+	}
+	if !movedAway || math.Abs(newPrice-pending.LimitPrice)/pending.LimitPrice*10000.0 < repriceThresholdBps {
+		return
+	}
+
+	// the edit size is the order's new total, so keep what filled and re-size what's left at the new price
+	newSize := pending.AlreadyFilledInTokens + pending.CurrentAmountLeftToBeFilledInUSD/newPrice
+	body, err := json.Marshal(cb_models.EditOrderRequest{
+		OrderID: pending.OrderID,
+		Price:   strconv.FormatFloat(newPrice, 'f', -1, 64),
+		Size:    strconv.FormatFloat(newSize, 'f', -1, 64),
+	})
+	if err != nil {
+		return
+	}
+	pending.LastRepriceTime = time.Now()
+	err = t.executeWithTimeout(5, "Reprice order", func(ctx context.Context) error {
+		response, err := t.exchange.EditOrder(ctx, body)
+		if err == nil && !response.Success {
+			err = fmt.Errorf("edit rejected: %s", response.Error)
+		}
+		return err
+	})
+	if err == nil {
+		log.Printf("[Trader %s] repriced order %s from %v to %v", t.cfg.Symbol, pending.OrderID, pending.LimitPrice, newPrice)
+		pending.LimitPrice = newPrice
+		pending.OriginalAmountInTokens = newSize
+	}
+	t.persistState()
+}
+
+// cancelPendingOrderIfRestingTooLong pulls a maker order that has been chasing the market for more than half a
+// candle. The pending order stays until the exchange confirms the cancel, and the next order goes out at market.
+func (t *Trader) cancelPendingOrderIfRestingTooLong() {
+	pending := t.state.PendingOrder
+	if !isMakerOrder(pending) || pending.CancelRequested {
+		return
+	}
+	if time.Since(pending.SubmitTime) < enum.GetTimeDurationFromCandleSize(t.cfg.CandleSize)/2 {
+		return
+	}
+	orderID := pending.OrderID
+	err := t.executeWithTimeout(10, "Cancel resting order", func(ctx context.Context) error {
+		return t.exchange.CancelOrders(ctx, orderID)
+	})
+	if err == nil {
+		log.Printf("[Trader %s] maker order %s rested since %s, cancelled to go to market", t.cfg.Symbol, orderID, pending.SubmitTime.Format(time.RFC3339))
+		pending.CancelRequested = true
+	}
+}
+
+// handlePendingOrderPartlyFilled books what a resting order has filled so far. Updates carry running totals, so
+// only what filled since the last one moves the position, and what is left is what a reprice re-sizes.
+func (t *Trader) handlePendingOrderPartlyFilled(up models.OrderUpdate) {
+	pending := t.state.PendingOrder
+	cumulativeQuantity, _ := strconv.ParseFloat(up.FilledQty, 64)
+	cumulativeValue, _ := strconv.ParseFloat(up.FilledValue, 64)
+	filledTokens := cumulativeQuantity - pending.AlreadyFilledInTokens
+	filledUSD := cumulativeValue - pending.AlreadyFilledInUSD
+	if filledTokens <= 0 {
+		return
+	}
+	t.recordFill(up)
+	if pending.OrderType == enum.SignalBuy {
+		t.state.UsdAmountPerFulfilledOrders += filledUSD
+		t.state.ActualPositionToken += filledTokens
+	} else {
+		t.state.UsdAmountPerFulfilledOrders -= filledUSD
+		t.state.ActualPositionToken -= filledTokens
+	}
+	t.updatePendingOrderBalances(max(pending.OriginalAmountInUSD-cumulativeValue, 0), cumulativeValue, cumulativeQuantity)
+	log.Printf("[Trader %s] order %s partly filled, %v tokens so far", t.cfg.Symbol, up.OrderID, cumulativeQuantity)
+	t.reportProfitLossTotal()
+}
+
+func isOrderClosedUnfilled(status string) bool {
+	return status == "CANCELLED" || status == "EXPIRED" || status == "FAILED"
+}
+
+// handlePendingOrderClosed books whatever part of a cancelled, expired or failed order filled before it closed,
+// then frees the trader to place the next one.
+func (t *Trader) handlePendingOrderClosed(up models.OrderUpdate) {
+	pending := t.state.PendingOrder
+	t.recordFill(up)
+
+	cumulativeQuantity, _ := strconv.ParseFloat(up.FilledQty, 64)
+	cumulativeValue, _ := strconv.ParseFloat(up.FilledValue, 64)
+	filledTokens := cumulativeQuantity - pending.AlreadyFilledInTokens
+	filledUSD := cumulativeValue - pending.AlreadyFilledInUSD
+	if filledTokens > 0 {
+		if pending.OrderType == enum.SignalBuy {
+			t.state.UsdAmountPerFulfilledOrders += filledUSD
+			t.state.ActualPositionToken += filledTokens
+		} else {
+			t.state.UsdAmountPerFulfilledOrders -= filledUSD
+			t.state.ActualPositionToken -= filledTokens
+		}
+		t.reportProfitLossTotal()
+	}
+
+	log.Printf("[Trader %s] order %s closed as %s with %v tokens filled", t.cfg.Symbol, up.OrderID, up.Status, cumulativeQuantity)
+	if pending.CancelRequested {
+		t.useMarketForNextOrder = true
+	}
+	t.clearPendingOrder()
+}
@@ -0,0 +1,89 @@
+package trader
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/journal"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+func TestGetMakerPrice(t *testing.T) {
+	tests := []struct {
+		name         string
+		side         enum.SignalType
+		bid, ask, px float64
+		want         float64
+	}{
+		{name: "buy joins the bid", side: enum.SignalBuy, bid: 99, ask: 101, px: 100.5, want: 99},
+		{name: "sell joins the ask", side: enum.SignalSell, bid: 99, ask: 101, px: 100.5, want: 101},
+		{name: "buy without a quote", side: enum.SignalBuy, px: 100, want: 99.99},
+		{name: "sell without a quote", side: enum.SignalSell, px: 100, want: 100.01},
+		{name: "crossed quote", side: enum.SignalBuy, bid: 101, ask: 99, px: 100, want: 99.99},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &Trader{state: models.TraderState{CurrentBidUSDPerToken: tt.bid, CurrentAskUSDPerToken: tt.ask, CurrentPriceUSDPerToken: tt.px}}
+			if got := tr.getMakerPrice(tt.side); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleOrderUpdateTracksPartialFills(t *testing.T) {
+	j, err := journal.Open(filepath.Join(t.TempDir(), "journal.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { j.Close() })
+	tr := &Trader{
+		cfg:                    TradeCfg{Symbol: "ETH-USD"},
+		journal:                j,
+		profitLossTotalChannel: make(chan models.TokenProfitLossUpdate, 10),
+		state: models.TraderState{
+			CurrentPriceUSDPerToken: 100,
+			PendingOrder: &models.PendingOrder{
+				OrderID:                          "o",
+				OrderType:                        enum.SignalBuy,
+				OriginalAmountInUSD:              1000,
+				CurrentAmountLeftToBeFilledInUSD: 1000,
+				OriginalAmountInTokens:           10,
+				ExecutionType:                    enum.OrderTypeLimit,
+				LimitPrice:                       100,
+			},
+		},
+	}
+
+	// running totals, as the exchange sends them
+	updates := []struct {
+		status, qty, value, leaves string
+		wantTokens, wantUSD        float64
+		wantLeft                   float64
+	}{
+		{status: "OPEN", qty: "3", value: "300", leaves: "7", wantTokens: 3, wantUSD: 300, wantLeft: 700},
+		{status: "OPEN", qty: "3", value: "300", leaves: "7", wantTokens: 3, wantUSD: 300, wantLeft: 700}, // repeated
+		{status: "OPEN", qty: "5", value: "500", leaves: "5", wantTokens: 5, wantUSD: 500, wantLeft: 500},
+		{status: "FILLED", qty: "10", value: "990", leaves: "0", wantTokens: 10, wantUSD: 990, wantLeft: 0}, // the rest filled better than asked
+	}
+	for i, u := range updates {
+		tr.handleOrderUpdate(models.OrderUpdate{OrderID: "o", Status: u.status, Side: "BUY", FilledQty: u.qty, FilledValue: u.value, Leaves: u.leaves})
+		if math.Abs(tr.state.ActualPositionToken-u.wantTokens) > 1e-9 || math.Abs(tr.state.UsdAmountPerFulfilledOrders-u.wantUSD) > 1e-9 {
+			t.Errorf("update %d: position %v tokens for %v, want %v for %v", i, tr.state.ActualPositionToken, tr.state.UsdAmountPerFulfilledOrders, u.wantTokens, u.wantUSD)
+		}
+		if left := tr.state.PendingOrder.CurrentAmountLeftToBeFilledInUSD; math.Abs(left-u.wantLeft) > 1e-9 {
+			t.Errorf("update %d: %v left to fill, want %v", i, left, u.wantLeft)
+		}
+	}
+
+	entries, err := j.Query("ETH-USD", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("journaled %d fills, want 3", len(entries))
+	}
+}
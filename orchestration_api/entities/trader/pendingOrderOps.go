@@ -9,16 +9,20 @@ import (
 	cb_models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models/coinbase"
 )
 
-func (t *Trader) getPendingOrderFromResponse(response cb_models.CreateOrderResponse, orderType enum.SignalType, amount float64) models.PendingOrder {
+func (t *Trader) getPendingOrderFromResponse(response cb_models.CreateOrderResponse, orderType enum.SignalType, amount float64, spec models.OrderSpec) models.PendingOrder {
+	now := time.Now()
 	return models.PendingOrder{
 		OrderID:                          response.OrderID,
-		SubmitTime:                       time.Now(),
+		SubmitTime:                       now,
 		OrderType:                        orderType,
 		OriginalAmountInUSD:              amount,
 		CurrentAmountLeftToBeFilledInUSD: amount,
 		AlreadyFilledInUSD:               0,
 		OriginalAmountInTokens:           amount / t.state.CurrentPriceUSDPerToken,
 		AlreadyFilledInTokens:            0,
+		ExecutionType:                    spec.Type,
+		LimitPrice:                       spec.LimitPrice,
+		LastRepriceTime:                  now,
 	}
 }
 
@@ -0,0 +1,152 @@
+package trader
+
+import (
+	"log"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/journal"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+	talib "github.com/markcheno/go-talib"
+)
+
+// SizingCfg picks how large a full position is. Strategies keep their own percents (a turtle entry is 50, each
+// add 12.5) and those apply to the full position rather than to all of the allocated funds.
+type SizingCfg struct {
+	Method          enum.PositionSizing `json:"method"`
+	RiskPct         float64             `json:"riskPct"`         // fixed risk: share of allocated funds lost if the stop is hit
+	AtrLen          int                 `json:"atrLen"`          // fixed risk: the N of the turtle rules
+	StopAtrMultiple float64             `json:"stopAtrMultiple"` // fixed risk: stop distance in N when the signal has no stop loss
+	KellyFraction   float64             `json:"kellyFraction"`   // kelly: e.g. 0.5 for half Kelly
+	KellyMinTrades  int                 `json:"kellyMinTrades"`  // kelly: closed trades needed, fixed percent until then
+}
+
+func DefaultSizingCfg() SizingCfg {
+	return SizingCfg{
+		Method:          enum.SizingFixedPercent,
+		RiskPct:         1,
+		AtrLen:          20,
+		StopAtrMultiple: 2,
+		KellyFraction:   0.5,
+		KellyMinTrades:  20,
+	}
+}
+
+// GetSizedSignal scales a buy or short signal's percent by the full position's share of the buying power
+// (allocated funds times leverage), so it can go through GetTargetPositionUSDAfterSignal as is. Sells and covers
+// are left alone: they unwind what the entries put on, and the strategies' exits are all 100%. candles are the
+// trader's own, the stats are its journaled round trips.
+func GetSizedSignal(s models.Signal, cfg SizingCfg, allocatedFunds float64, leverage float64, price float64, candles []models.Candle, stats journal.TradeStats) models.Signal {
+	if (s.Type != enum.SignalBuy && s.Type != enum.SignalShort) || s.Percent <= 0 || allocatedFunds <= 0 {
+		return s
+	}
+	scale := 1.0
+	switch cfg.Method {
+	case enum.SizingFixedRisk:
+		scale = getFixedRiskScale(s, cfg, allocatedFunds, max(leverage, 1), price, candles)
+	case enum.SizingKelly:
+		scale = getKellyScale(cfg, stats)
+	}
+	s.Percent *= scale
+	return s
+}
+
+// getSizedSignal sizes a signal against the trader's own candles, last price and journaled round trips under its
+// current strategy.
+func (t *Trader) getSizedSignal(s models.Signal) models.Signal {
+	if (s.Type != enum.SignalBuy && s.Type != enum.SignalShort) || t.cfg.Sizing.Method == enum.SizingFixedPercent {
+		return s
+	}
+	price := t.state.CurrentPriceUSDPerToken
+	if price <= 0 {
+		price = s.Price
+	}
+	var candles []models.Candle
+	if t.cfg.Sizing.Method == enum.SizingFixedRisk {
+		candles = t.exchange.GetCandleHistory(t.cfg.Symbol).Candles
+	}
+	var stats journal.TradeStats
+	if t.cfg.Sizing.Method == enum.SizingKelly {
+		stats = t.getJournaledTradeStats()
+	}
+	sized := GetSizedSignal(s, t.cfg.Sizing, t.cfg.AllocatedFunds, t.cfg.Derivatives.Leverage, price, candles, stats)
+	log.Printf("[Trader %s] %s sized the %s from %v%% to %v%% of buying power", t.cfg.Symbol, t.cfg.Sizing.Method.String(), s.Type.String(), s.Percent, sized.Percent)
+	return sized
+}
+
+// getJournaledTradeStats reads the journal once per strategy and from then on keeps the round trips' totals
+// running as journalFill adds the trader's fills.
+func (t *Trader) getJournaledTradeStats() journal.TradeStats {
+	strategy := t.cfg.Strategy.String()
+	if t.roundTrips == nil || t.roundTripsStrategy != strategy {
+		entries, err := t.journal.Query(t.cfg.Symbol, time.Time{}, time.Time{})
+		if err != nil {
+			log.Printf("[Trader %s] failed to read the journal for sizing: %v", t.cfg.Symbol, err)
+			return journal.TradeStats{}
+		}
+		t.roundTrips, t.roundTripsStrategy = &journal.RoundTrips{}, strategy
+		for _, e := range entries {
+			if e.Strategy == strategy {
+				t.roundTrips.Add(e)
+			}
+		}
+	}
+	return t.roundTrips.Stats
+}
+
+// getFixedRiskScale sizes the full position so that being stopped out loses RiskPct of the allocated funds. The
+// stop is the signal's own when it has one on the losing side of the price (below for a buy, above for a short),
+// StopAtrMultiple N away otherwise. Leverage only raises the cap, the risk stays a share of the margin.
+func getFixedRiskScale(s models.Signal, cfg SizingCfg, allocatedFunds float64, leverage float64, price float64, candles []models.Candle) float64 {
+	if price <= 0 {
+		return 1
+	}
+	stopDistance := 0.0
+	if s.Type == enum.SignalShort && s.StopLoss > price {
+		stopDistance = s.StopLoss - price
+	} else if s.Type == enum.SignalBuy && s.StopLoss > 0 && s.StopLoss < price {
+		stopDistance = price - s.StopLoss
+	} else if n := getAtr(candles, cfg.AtrLen); n > 0 {
+		stopDistance = cfg.StopAtrMultiple * n
+	}
+	if stopDistance <= 0 {
+		return 1 // nothing to size against yet
+	}
+	riskUSD := cfg.RiskPct / 100.0 * allocatedFunds
+	positionUSD := riskUSD / stopDistance * price
+	return min(positionUSD/(allocatedFunds*leverage), 1)
+}
+
+// getKellyScale is KellyFraction of f = W − L / R, W the win rate, L the loss rate and R the average win over the
+// average loss; break-evens count towards neither. With no losses yet the edge can't be measured, so it sizes at
+// KellyFraction. A negative edge sizes buys to nothing.
+func getKellyScale(cfg SizingCfg, stats journal.TradeStats) float64 {
+	if stats.Trades == 0 || stats.Trades < cfg.KellyMinTrades {
+		return 1
+	}
+	if stats.Wins == 0 && stats.Losses == 0 {
+		return 1 // only break-evens, nothing to size against
+	}
+	if stats.Wins == 0 {
+		return 0
+	}
+	if stats.Losses == 0 || stats.AverageLoss <= 0 {
+		return min(cfg.KellyFraction, 1)
+	}
+	f := stats.WinRate - stats.GetLossRate()/(stats.AverageWin/stats.AverageLoss)
+	return min(max(f*cfg.KellyFraction, 0), 1)
+}
+
+func getAtr(candles []models.Candle, atrLen int) float64 {
+	if atrLen <= 0 || len(candles) <= atrLen {
+		return 0
+	}
+	highs := make([]float64, len(candles))
+	lows := make([]float64, len(candles))
+	closes := make([]float64, len(candles))
+	for i, c := range candles {
+		highs[i], lows[i], closes[i] = c.High, c.Low, c.Close
+	}
+	atr := talib.Atr(highs, lows, closes, atrLen)
+	return atr[len(atr)-1]
+}
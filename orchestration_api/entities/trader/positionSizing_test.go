@@ -0,0 +1,69 @@
+package trader
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/journal"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+func TestGetKellyScale(t *testing.T) {
+	cfg := SizingCfg{Method: enum.SizingKelly, KellyFraction: 0.5, KellyMinTrades: 4}
+	tests := []struct {
+		name  string
+		stats journal.TradeStats
+		want  float64
+	}{
+		{name: "no trades", want: 1},
+		{name: "below min trades", stats: journal.NewTradeStats([]float64{10, -5, 10}), want: 1},
+		{name: "only winners", stats: journal.NewTradeStats([]float64{10, 10, 10, 10}), want: 0.5},
+		{name: "only losers", stats: journal.NewTradeStats([]float64{-10, -10, -10, -10}), want: 0},
+		{name: "only break-evens", stats: journal.NewTradeStats([]float64{0, 0, 0, 0}), want: 1},
+		// W = 0.5, L = 0.5, R = 2: f = 0.25
+		{name: "edge", stats: journal.NewTradeStats([]float64{20, -10, 20, -10}), want: 0.125},
+		// break-evens dilute the win rate without counting as losses: W = 0.4, L = 0.2, R = 2, f = 0.3
+		{name: "edge with break-evens", stats: journal.NewTradeStats([]float64{20, 20, -10, 0, 0}), want: 0.15},
+		// W = 0.25, L = 0.75, R = 1: f = -0.5
+		{name: "negative edge", stats: journal.NewTradeStats([]float64{10, -10, -10, -10}), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getKellyScale(cfg, tt.stats); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetJournaledTradeStatsKeepsRunning(t *testing.T) {
+	tr := newTestTrader(t, models.TraderState{})
+	tr.cfg.Strategy = enum.MeanReversion
+	tr.journalFill("a", "BUY", 1, 100, 0)
+	tr.journalFill("b", "SELL", 1, 110, 0)
+	if got := tr.getJournaledTradeStats(); got.Trades != 1 || got.Wins != 1 {
+		t.Fatalf("got %+v, want the journaled win", got)
+	}
+
+	// fills after the first read are added as they're journaled
+	tr.journalFill("c", "BUY", 1, 100, 0)
+	tr.journalFill("d", "SELL", 1, 95, 0)
+	tr.journalFill("e", "BUY", 1, 100, 0)
+	tr.journalFill("f", "SELL", 1, 100, 0)
+	got := tr.getJournaledTradeStats()
+	entries, err := tr.journal.Query("ETH-USD", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := journal.GetTradeStats(entries); got != want || got.Trades != 3 || got.BreakEvens != 1 {
+		t.Errorf("running %+v, journal has %+v", got, want)
+	}
+
+	// another strategy starts from its own round trips
+	tr.cfg.Strategy = enum.TurtleTrader
+	if got := tr.getJournaledTradeStats(); got.Trades != 0 {
+		t.Errorf("got %+v for a strategy with no fills", got)
+	}
+}
@@ -0,0 +1,44 @@
+package trader
+
+import (
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+// PositionSummary is a trader's position as the front end sees it. The trader publishes it after every event it
+// handles; the units are the strategy's, which decides when they are added and trimmed.
+type PositionSummary struct {
+	Symbol              string                `json:"symbol"`
+	UnitCount           int                   `json:"unitCount"`
+	AverageEntryPrice   float64               `json:"averageEntryPrice"`
+	TargetPositionUSD   float64               `json:"targetPositionUSD"`
+	ActualPositionUSD   float64               `json:"actualPositionUSD"`
+	ActualPositionToken float64               `json:"actualPositionToken"`
+	Units               []models.PositionUnit `json:"units"`
+	UpdatedAt           time.Time             `json:"updatedAt"`
+}
+
+// publishPosition stores the trader's current position for the manager to read from another goroutine.
+func (t *Trader) publishPosition() {
+	if t.position == nil {
+		return
+	}
+	t.position.Store(&PositionSummary{
+		Symbol:              t.cfg.Symbol,
+		AverageEntryPrice:   GetAverageEntryPrice(t.state),
+		TargetPositionUSD:   t.state.TargetPositionUSD,
+		ActualPositionUSD:   t.state.ActualPositionUSD,
+		ActualPositionToken: t.state.ActualPositionToken,
+		UpdatedAt:           time.Now(),
+	})
+}
+
+// GetAverageEntryPrice is what went into the position net of what was sold along the way, per token held: the
+// price it breaks even at. Both sides are negative for a short.
+func GetAverageEntryPrice(state models.TraderState) float64 {
+	if state.ActualPositionToken == 0 {
+		return 0
+	}
+	return state.UsdAmountPerFulfilledOrders / state.ActualPositionToken
+}
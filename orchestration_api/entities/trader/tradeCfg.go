@@ -1,10 +1,39 @@
 package trader
 
-import "github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+import (
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+)
 
 type TradeCfg struct {
 	Symbol         string          `json:"symbol"`   // e.g. "BTCUSD"
 	AllocatedFunds float64         `json:"size"`     // position size
 	Strategy       enum.Strategy   `json:"strategy"` // trading strategy
 	CandleSize     enum.CandleSize `json:"candleSize"` // candle size
+	OrderType      enum.OrderType  `json:"orderType"`  // how orders are worked: market, or maker limits at the touch
+	Execution      ExecutionCfg    `json:"execution"`  // how large deficits are sliced
+	Sizing         SizingCfg       `json:"sizing"`     // how large a full position is
+	Derivatives    DerivativesCfg  `json:"derivatives"` // shorting and leverage on a perpetuals venue
+}
+
+// ExecutionCfg bounds how a trader works a deficit too large to send as one order. Deficits up to MaxSliceUSD
+// always go out whole.
+type ExecutionCfg struct {
+	Algo              enum.ExecutionAlgo `json:"algo"`
+	MaxSliceUSD       float64            `json:"maxSliceUSD"`
+	Duration          time.Duration      `json:"duration"`          // TWAP horizon
+	ParticipationRate float64            `json:"participationRate"` // share of traded volume, e.g. 0.1 for 10%
+	MaxSlippageBps    float64            `json:"maxSlippageBps"`    // how far through the last ticker a slice may fill
+}
+
+// DefaultExecutionCfg sends every deficit as a single order; the limits only apply once an algo is picked.
+func DefaultExecutionCfg() ExecutionCfg {
+	return ExecutionCfg{
+		Algo:              enum.ExecutionImmediate,
+		MaxSliceUSD:       1000,
+		Duration:          10 * time.Minute,
+		ParticipationRate: 0.1,
+		MaxSlippageBps:    25,
+	}
 }
\ No newline at end of file
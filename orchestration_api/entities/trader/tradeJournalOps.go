@@ -0,0 +1,58 @@
+package trader
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/journal"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+// recordFill journals whatever part of the pending order filled since the last update. Order updates carry
+// running totals, so the pending order remembers how much has been journaled already.
+func (t *Trader) recordFill(up models.OrderUpdate) {
+	pending := t.state.PendingOrder
+	cumulativeQuantity, _ := strconv.ParseFloat(up.FilledQty, 64)
+	cumulativeValue, _ := strconv.ParseFloat(up.FilledValue, 64)
+	cumulativeFees, _ := strconv.ParseFloat(up.Fees, 64)
+	if cumulativeValue == 0 {
+		averagePrice, _ := strconv.ParseFloat(up.Price, 64)
+		cumulativeValue = cumulativeQuantity * averagePrice
+	}
+
+	quantity := cumulativeQuantity - pending.AlreadyJournaledInTokens
+	if quantity <= 0 {
+		return
+	}
+	value := cumulativeValue - pending.AlreadyJournaledInUSD
+	fees := max(cumulativeFees-pending.AlreadyPaidFeesInUSD, 0)
+	pending.AlreadyJournaledInTokens = cumulativeQuantity
+	pending.AlreadyJournaledInUSD = cumulativeValue
+	pending.AlreadyPaidFeesInUSD = max(cumulativeFees, pending.AlreadyPaidFeesInUSD)
+
+	t.journalFill(up.OrderID, up.Side, quantity, value, fees)
+}
+
+func (t *Trader) journalFill(orderID string, side string, quantity float64, value float64, fees float64) {
+	entry := journal.Entry{
+		Time:         time.Now(), // an order update's Ts is when the order was created, not when it filled
+		OrderID:      orderID,
+		Symbol:       t.cfg.Symbol,
+		Side:         side,
+		Quantity:     quantity,
+		AveragePrice: value / quantity,
+		Value:        value,
+		Fees:         fees,
+		Strategy:     t.cfg.Strategy.String(),
+		CandleSize:   t.cfg.CandleSize.String(),
+		Signal:       t.state.LastSignal,
+	}
+	if err := t.journal.Append(entry); err != nil {
+		log.Printf("[Trader %s] failed to journal fill of order %s: %v", t.cfg.Symbol, orderID, err)
+		return
+	}
+	if t.roundTrips != nil && entry.Strategy == t.roundTripsStrategy {
+		t.roundTrips.Add(entry)
+	}
+}
@@ -3,13 +3,18 @@ package trader
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"strconv"
+	"sync/atomic"
 	"time"
 
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/risk"
 	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
 	exchange "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/journal"
 	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/persistence"
 )
 
 
@@ -23,12 +28,21 @@ type Trader struct {
 	state    models.TraderState
 	exchange exchange.IExchange
 	profitLossTotalChannel chan models.TokenProfitLossUpdate
+	executionProgressChannel chan models.ExecutionProgress
 	timeOfLastProfitLossReport time.Time
+	store    *persistence.StateStore
+	journal  *journal.Journal
+	risk     *risk.RiskManager
+	useMarketForNextOrder bool // set when a maker order rested too long and had to be pulled
+	timeOfLastDerivativesPoll time.Time
+	roundTrips *journal.RoundTrips // the current strategy's journaled round trips, read on the first Kelly sizing
+	roundTripsStrategy string
+	position *atomic.Pointer[PositionSummary] // where publishPosition leaves the position for the manager
 }
 
 // NewTrader builds a trader instance from a config.
-func NewTrader(cfg TradeCfg, ctx context.Context, cancel context.CancelFunc, updates chan TradeCfg, signalCh chan models.Signal, profitLossTotalChannel chan models.TokenProfitLossUpdate, startingTokenBalance float64, exchange exchange.IExchange) *Trader {
-	return &Trader{cfg: cfg, ctx: ctx, cancel: cancel, updates: updates, signalCh: signalCh, state: models.TraderState{ActualPositionToken: startingTokenBalance}, exchange: exchange, profitLossTotalChannel: profitLossTotalChannel}
+func NewTrader(cfg TradeCfg, ctx context.Context, cancel context.CancelFunc, updates chan TradeCfg, signalCh chan models.Signal, profitLossTotalChannel chan models.TokenProfitLossUpdate, executionProgressChannel chan models.ExecutionProgress, position *atomic.Pointer[PositionSummary], startingTokenBalance float64, exchange exchange.IExchange, store *persistence.StateStore, journal *journal.Journal, risk *risk.RiskManager) *Trader {
+	return &Trader{cfg: cfg, ctx: ctx, cancel: cancel, updates: updates, signalCh: signalCh, state: models.TraderState{ActualPositionToken: startingTokenBalance}, exchange: exchange, profitLossTotalChannel: profitLossTotalChannel, executionProgressChannel: executionProgressChannel, position: position, store: store, journal: journal, risk: risk}
 }
 
 func (t *Trader) Run() {
@@ -40,13 +54,20 @@ func (t *Trader) Run() {
 	defer orderUpdateCleanup()
 	ticker := time.NewTicker(enum.GetTimeDurationFromCandleSize(t.cfg.CandleSize) / 5)
 	defer ticker.Stop()
+	// Stop cancels the context and closes the channels together, so whichever case notices first the trader
+	// still has to get flat on its way out
+	defer func() {
+		if t.ctx.Err() != nil {
+			log.Printf("[Trader %s] Context done... closing positions", t.cfg.Symbol)
+			t.cancelPendingOrderWithTimeout()
+			t.cancelBracket()
+			t.closePositionWithTimeout()
+		}
+	}()
 
 	for {
 		select {
 		case <-t.ctx.Done():
-			log.Printf("[Trader %s] Context done... closing positions", t.cfg.Symbol)
-			t.cancelPendingOrderWithTimeout()
-			t.sellTokensWithTimeout()
 			return
 
 		case price, ok := <-tickerCh:
@@ -62,12 +83,15 @@ func (t *Trader) Run() {
 				return
 			}
 			t.handleOrderUpdate(ord)
+			t.persistState()
 
 		case <-ticker.C:
 			if t.ctx.Err() != nil {
 				return
 			}
+			t.pollDerivatives()
 			t.executeTradesToMakeActualTrackTarget()
+			t.persistState()
 
 		case update, ok := <-t.updates:
 			if !ok || t.ctx.Err() != nil {
@@ -75,6 +99,7 @@ func (t *Trader) Run() {
 				return // Manager stopped us
 			}
 			t.adjustTargetPositionAccordingToAllocatedFundsUpdate(update)
+			t.persistState()
 
 		case sig, ok := <-t.signalCh:
 			if !ok || t.ctx.Err() != nil {
@@ -82,17 +107,28 @@ func (t *Trader) Run() {
 				return // Manager stopped us
 			}
 			t.handleSignal(sig)
+			t.persistState()
 		}
+		t.publishPosition()
 	}
 }
 
+// reportProfitLossTotal sends the trader's P&L since it started, realized and unrealized: what the position is
+// worth less what went into it net of sales, and less any perpetual funding paid. Both sides are negative for a
+// short, so it gains as the price falls.
 func (t *Trader) reportProfitLossTotal() {
-	profitLoss := t.state.ActualPositionUSD - t.cfg.AllocatedFunds
+	if t.state.CurrentPriceUSDPerToken <= 0 {
+		return // the position can't be valued before the first price
+	}
+	profitLoss := t.state.ActualPositionUSD - t.state.UsdAmountPerFulfilledOrders - t.state.FundingPaidUSD
 	t.profitLossTotalChannel <- models.TokenProfitLossUpdate{Symbol: t.cfg.Symbol, ProfitLoss: profitLoss}
 	log.Printf("[Trader %s] Reported profit loss total: %v", t.cfg.Symbol, profitLoss)
 }
 
 func (t *Trader) getTargetPositionPct() float64 {
+	if t.cfg.AllocatedFunds == 0 {
+		return 0
+	}
 	return t.state.TargetPositionUSD / t.cfg.AllocatedFunds
 }
 
@@ -102,10 +138,15 @@ func (t *Trader) getActualPositionPct() float64 {
 
 func (t *Trader) handlePriceUpdate(ticker models.Ticker) {
 	t.state.CurrentPriceUSDPerToken = ticker.Price
+	t.state.CurrentBidUSDPerToken = ticker.Bid
+	t.state.CurrentAskUSDPerToken = ticker.Ask
 	t.state.ActualPositionUSD = t.state.ActualPositionToken * t.state.CurrentPriceUSDPerToken
 	if t.state.UsdAmountPerFulfilledOrders == 0 { // with this, the current logic can know about the pre-existing position and adjust accordingly
 		t.state.UsdAmountPerFulfilledOrders = t.state.ActualPositionUSD
+		t.persistState()
 	}
+	t.repricePendingOrder()
+	t.workExecutionIfDue()
 	if time.Since(t.timeOfLastProfitLossReport) > 20 * time.Second {
 		t.reportProfitLossTotal()
 		t.timeOfLastProfitLossReport = time.Now()
@@ -113,13 +154,28 @@ func (t *Trader) handlePriceUpdate(ticker models.Ticker) {
 }
 
 func (t *Trader) handleOrderUpdate(up models.OrderUpdate) {
+	if t.state.Bracket.IsPlaced() && t.state.Bracket.OrderID == up.OrderID {
+		t.handleBracketUpdate(up)
+		return
+	}
+	t.followAttachedBracket(up)
 	if t.state.PendingOrder != nil {
+		if t.state.PendingOrder.OrderID == up.OrderID && isOrderClosedUnfilled(up.Status) {
+			t.handlePendingOrderClosed(up)
+			return
+		}
+		if t.state.PendingOrder.OrderID == up.OrderID && up.Status == "OPEN" {
+			t.handlePendingOrderPartlyFilled(up)
+			return
+		}
 		if t.state.PendingOrder.OrderID == up.OrderID && up.Status == "FILLED" {
+			t.recordFill(up)
 			leaves, _ := strconv.ParseFloat(up.Leaves, 64)
 			if leaves != t.state.PendingOrder.CurrentAmountLeftToBeFilledInUSD {
 				cumulativeQuantity, _ := strconv.ParseFloat(up.FilledQty, 64)
-				filledUSD := t.state.PendingOrder.OriginalAmountInUSD - leaves
-				filledTokens := cumulativeQuantity - t.state.PendingOrder.AlreadyFilledInTokens
+				// booked at what the exchange filled it for, as the partial fills were, not at the USD asked for
+				filledUSD, _ := strconv.ParseFloat(up.FilledValue, 64)
+				filledTokens := cumulativeQuantity
 				alreadyFilledUSD := t.state.PendingOrder.AlreadyFilledInUSD
 				alreadyFilledTokens := t.state.PendingOrder.AlreadyFilledInTokens
 
@@ -149,64 +205,126 @@ func (t *Trader) adjustTargetPositionAccordingToAllocatedFundsUpdate(update Trad
 	log.Printf("[Trader %s] AllocatedFunds updating from %v to %v", t.cfg.Symbol, t.cfg.AllocatedFunds, update.AllocatedFunds)
 	if t.cfg.Strategy != update.Strategy {
 		t.state.TargetPositionUSD = 0
+		t.updateCfg(update)
+		return
 	}
 
 	t.updateCfg(update)
 
 	newTargetPct := t.getTargetPositionPct()
 	if newTargetPct != oldTargetPct {
-		targetPositionIncrease := (newTargetPct - oldTargetPct) * t.cfg.AllocatedFunds / 100.0
+		targetPositionIncrease := oldTargetPct*t.cfg.AllocatedFunds - t.state.TargetPositionUSD
 		t.state.TargetPositionUSD += targetPositionIncrease
 		log.Printf("[Trader %s] Target position increased by %v to a resulting value of %v", t.cfg.Symbol, targetPositionIncrease, t.state.TargetPositionUSD)
 	}
 }
 
-// handleSignal executes buy/sell respecting rules on allocated funds and bounds 0..100
+// handleSignal executes buy/sell respecting rules on allocated funds and bounds 0..100, or -100..100 of the
+// leveraged buying power for a trader allowed to go short
 func (t *Trader) handleSignal(s models.Signal) {
 	log.Printf("[Trader %s] Signal received: Percent=%v Type=%s", t.cfg.Symbol, s.Percent, s.Type)
-	if s.Percent <= 0 {
+	if s.Type.IsShortSide() && !t.cfg.Derivatives.AllowShort {
+		log.Printf("[Trader %s] ignoring %s, shorting is off", t.cfg.Symbol, s.Type.String())
 		return
 	}
+	if s.Type != enum.SignalHold {
+		t.state.LastSignal = &s
+	}
+	sized := t.getSizedSignal(s)
+	t.state.TargetPositionUSD = GetTargetPositionUSDAfterSignal(t.state.TargetPositionUSD, t.state.ActualPositionUSD, t.getBuyingPower(), sized)
+	t.updateBracketLevels(s)
+}
+
+// GetTargetPositionUSDAfterSignal is the sizing rule shared by the live trader and the backtester: a buy adds
+// its percent of allocated funds (capped at 100%), a sell removes its percent, scaled up when the actual position
+// has drifted above the target, and never takes the target below zero. Shorts mirror it below zero: a short adds
+// its percent to the short (capped at -100%) and a cover takes it back off, never past zero. A buy or a short
+// first closes whatever is held on the other side. A trim takes its percent of the position itself off, the
+// newest unit's share of it.
+func GetTargetPositionUSDAfterSignal(targetPositionUSD float64, actualPositionUSD float64, allocatedFunds float64, s models.Signal) float64 {
+	if s.Percent <= 0 || allocatedFunds <= 0 {
+		return targetPositionUSD
+	}
+	if s.Trim {
+		if (s.Type == enum.SignalSell && targetPositionUSD > 0) || (s.Type == enum.SignalCover && targetPositionUSD < 0) {
+			return targetPositionUSD * (1 - min(s.Percent, 100)/100.0)
+		}
+		return targetPositionUSD
+	}
 	pct := s.Percent
 	switch s.Type {
 	case enum.SignalBuy:
 		// Buy percent pertains to allocated funds but cannot exceed 100% target
-		t.state.TargetPositionUSD += pct * t.cfg.AllocatedFunds / 100.0
-		if t.state.TargetPositionUSD > t.cfg.AllocatedFunds {
-			t.state.TargetPositionUSD = t.cfg.AllocatedFunds
+		targetPositionUSD = max(targetPositionUSD, 0) + pct*allocatedFunds/100.0
+		if targetPositionUSD > allocatedFunds {
+			targetPositionUSD = allocatedFunds
 		}
 	case enum.SignalSell:
-		// Sell percent pertains to position if position > 100, else allocated funds percent
-		targetPct := t.getTargetPositionPct()
-		actualPct := t.getActualPositionPct()
-		if actualPct > targetPct {
-			pct *= actualPct / targetPct
+		if targetPositionUSD <= 0 {
+			return targetPositionUSD // nothing long to sell
+		}
+		targetPositionUSD -= getExitPercent(pct, targetPositionUSD, actualPositionUSD, allocatedFunds) * allocatedFunds / 100.0
+	case enum.SignalShort:
+		targetPositionUSD = min(targetPositionUSD, 0) - pct*allocatedFunds/100.0
+		if targetPositionUSD < -allocatedFunds {
+			targetPositionUSD = -allocatedFunds
 		}
-		if pct > targetPct {
-			pct = targetPct
+	case enum.SignalCover:
+		if targetPositionUSD >= 0 {
+			return targetPositionUSD // nothing short to cover
 		}
-		t.state.TargetPositionUSD -= pct * t.cfg.AllocatedFunds / 100.0
+		targetPositionUSD += getExitPercent(pct, -targetPositionUSD, -actualPositionUSD, allocatedFunds) * allocatedFunds / 100.0
 	default:
 		// hold not emitted
 	}
+	return targetPositionUSD
+}
+
+// getExitPercent is how much of allocated funds an exit takes off a position of either side, given as positive
+// sizes: percent pertains to position if position > 100, else allocated funds percent, and never more than the
+// target holds.
+func getExitPercent(pct float64, targetUSD float64, actualUSD float64, allocatedFunds float64) float64 {
+	targetPct := targetUSD / allocatedFunds * 100.0
+	actualPct := actualUSD / allocatedFunds * 100.0
+	if actualPct > targetPct && targetPct > 0 {
+		pct *= actualPct / targetPct
+	}
+	if pct > targetPct {
+		pct = targetPct
+	}
+	return pct
 }
 
 func (t *Trader) executeTradesToMakeActualTrackTarget() {
 	if t.hasPendingOrder() {
+		t.cancelPendingOrderIfRestingTooLong()
 		return
 	}
 	var tolerance float64 = t.cfg.AllocatedFunds * 0.01
 	var deficitOrExcess float64 = t.getTotalPositionAsFulfilledOrdersPlusPending() - t.state.TargetPositionUSD
 	log.Printf("[Trader %s] deficitOrExcess: %v, tolerance: %v", t.cfg.Symbol, deficitOrExcess, tolerance)
 	if deficitOrExcess > 0 && deficitOrExcess > tolerance {
-		t.submitBuyToCoinbase(deficitOrExcess)
+		if t.cancelBracketInTheWay(enum.SignalSell) != nil {
+			return
+		}
+		t.executeDeficit(enum.SignalSell, deficitOrExcess, tolerance)
 	} else if deficitOrExcess < 0 && deficitOrExcess < -tolerance {
-		t.submitSellToCoinbase(-deficitOrExcess)
+		if t.cancelBracketInTheWay(enum.SignalBuy) != nil {
+			return
+		}
+		t.executeDeficit(enum.SignalBuy, -deficitOrExcess, tolerance)
+	} else {
+		t.endExecution("done")
+		t.syncBracket()
 	}
 }
 
 func (t *Trader) executeWithTimeout(timeoutSeconds int, operationName string, operation func(context.Context) error) error {
-	ctx, cancel := context.WithTimeout(t.ctx, time.Duration(timeoutSeconds)*time.Second)
+	parent := t.ctx
+	if parent.Err() != nil {
+		parent = context.WithoutCancel(parent) // shutting down, but the cancels and the final sell still have to go out
+	}
+	ctx, cancel := context.WithTimeout(parent, time.Duration(timeoutSeconds)*time.Second)
 	defer cancel()
 
 	err := operation(ctx)
@@ -241,20 +359,31 @@ func (t *Trader) cancelPendingOrderWithTimeout() error {
 	return err
 }
 
-func (t *Trader) sellTokensWithTimeout() error {
-	if t.state.ActualPositionToken <= 0 {
+// closePositionWithTimeout flattens whatever the trader holds on shutdown: a long is sold, a short is bought back
+// at the last price with a reduce-only order.
+func (t *Trader) closePositionWithTimeout() error {
+	symbol := t.cfg.Symbol
+	tokens := t.state.ActualPositionToken
+	price := t.state.CurrentPriceUSDPerToken
+	var err error
+	switch {
+	case tokens > 0:
+		err = t.executeWithTimeout(10, "Sell tokens", func(ctx context.Context) error {
+			_, err := t.exchange.SellTokens(ctx, symbol, tokens)
+			return err
+		})
+	case tokens < 0 && price > 0:
+		spec := t.getDerivativesSpec(models.NewMarketOrderSpec(), true)
+		err = t.executeWithTimeout(10, "Cover short", func(ctx context.Context) error {
+			_, err := t.exchange.CreateOrder(ctx, symbol, -tokens*price, true, spec)
+			return err
+		})
+	default:
 		return nil
 	}
 
-	symbol := t.cfg.Symbol
-	amount := t.state.ActualPositionToken
-	err := t.executeWithTimeout(10, "Sell tokens", func(ctx context.Context) error {
-		_, err := t.exchange.SellTokens(ctx, symbol, amount)
-		return err
-	})
-
 	if err == nil {
-		log.Printf("Successfully submitted sell order for tokens of %s", symbol)
+		log.Printf("Successfully submitted order closing the position in %s", symbol)
 	}
 
 	return err
@@ -273,24 +402,54 @@ func (t *Trader) getTotalPositionAsFulfilledOrdersPlusPending() float64 {
 	return total
 }
 
-func (t *Trader) submitBuyToCoinbase(amount float64) error {
-	response, err := t.exchange.CreateOrder(t.ctx, t.cfg.Symbol, amount, true)
+// submitToCoinbase sends an order on the side given. On a derivatives venue the order carries the trader's
+// leverage, and nothing that adds exposure goes out while the liquidation price is too close.
+func (t *Trader) submitToCoinbase(side enum.SignalType, amount float64, spec models.OrderSpec) error {
+	reducing := isReducingOrder(side, amount, t.getTotalPositionAsFulfilledOrdersPlusPending())
+	if t.cfg.Derivatives.IsEnabled() && !reducing && t.isLiquidationTooClose() {
+		log.Printf("[Trader %s] not adding exposure, liquidation at %v is too close to %v", t.cfg.Symbol, t.state.LiquidationPrice, t.state.CurrentPriceUSDPerToken)
+		return fmt.Errorf("liquidation price %v too close", t.state.LiquidationPrice)
+	}
+	spec = t.getDerivativesSpec(spec, reducing)
+	spec = t.getSpecWithAttachedBracket(side, reducing, spec)
+	if side == enum.SignalBuy {
+		return t.submitBuyToCoinbase(amount, spec)
+	}
+	return t.submitSellToCoinbase(amount, spec)
+}
+
+func (t *Trader) submitBuyToCoinbase(amount float64, spec models.OrderSpec) error {
+	amount, err := t.risk.CheckOrder(t.cfg.Symbol, true, amount, t.getTotalPositionAsFulfilledOrdersPlusPending())
+	if err != nil {
+		log.Printf("[Trader %s] risk manager blocked buy: %v", t.cfg.Symbol, err)
+		return err
+	}
+	response, err := t.exchange.CreateOrder(t.ctx, t.cfg.Symbol, amount, true, spec)
 	if err != nil {
 		log.Printf("failed to submit buy to coinbase: %v", err)
 		return err
 	}
 	log.Printf("submitted buy to coinbase: %v", response)
-	t.setPendingOrder(t.getPendingOrderFromResponse(response, enum.SignalBuy, amount))
+	t.risk.RecordOrder()
+	t.trackAttachedBracket(response, spec)
+	t.setPendingOrder(t.getPendingOrderFromResponse(response, enum.SignalBuy, amount, spec))
 	return nil
 }
 
-func (t *Trader) submitSellToCoinbase(amount float64) error {
-	response, err := t.exchange.CreateOrder(t.ctx, t.cfg.Symbol, amount, false)
+func (t *Trader) submitSellToCoinbase(amount float64, spec models.OrderSpec) error {
+	amount, err := t.risk.CheckOrder(t.cfg.Symbol, false, amount, t.getTotalPositionAsFulfilledOrdersPlusPending())
 	if err != nil {
-		log.Printf("failed to submit buy to coinbase: %v", err)
+		log.Printf("[Trader %s] risk manager blocked sell: %v", t.cfg.Symbol, err)
 		return err
 	}
-	log.Printf("submitted buy to coinbase: %v", response)
-	t.setPendingOrder(t.getPendingOrderFromResponse(response, enum.SignalBuy, amount))
+	response, err := t.exchange.CreateOrder(t.ctx, t.cfg.Symbol, amount, false, spec)
+	if err != nil {
+		log.Printf("failed to submit sell to coinbase: %v", err)
+		return err
+	}
+	log.Printf("submitted sell to coinbase: %v", response)
+	t.risk.RecordOrder()
+	t.trackAttachedBracket(response, spec)
+	t.setPendingOrder(t.getPendingOrderFromResponse(response, enum.SignalSell, amount, spec))
 	return nil
 }
@@ -2,6 +2,7 @@ package trader
 
 import (
 	"context"
+	"sync/atomic"
 
 	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
 )
@@ -12,6 +13,7 @@ type TraderResource struct {
 	Done                     chan struct{}      // closed when Run() exits
 	Cfg                      TradeCfg           // keep the config for introspection / restart
 	Updates                  chan TradeCfg
+	Position                 atomic.Pointer[PositionSummary] // the trader's latest, safe to read from any goroutine
 }
 
 func NewTraderResource(cfg TradeCfg, done chan struct{}, cancel context.CancelFunc, updates chan TradeCfg) *TraderResource {
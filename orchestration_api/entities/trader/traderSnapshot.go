@@ -0,0 +1,170 @@
+package trader
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	exchange "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/persistence"
+)
+
+// TraderSnapshot is what a trader persists on every state change, enough to pick up where it left off after a
+// crash or restart.
+type TraderSnapshot struct {
+	Cfg     TradeCfg           `json:"cfg"`
+	State   models.TraderState `json:"state"`
+	SavedAt time.Time          `json:"savedAt"`
+}
+
+func LoadTraderSnapshots(store *persistence.StateStore) (map[string]TraderSnapshot, error) {
+	snapshots := make(map[string]TraderSnapshot)
+	err := store.ForEach(persistence.TraderBucket, func(symbol string, raw []byte) error {
+		var snapshot TraderSnapshot
+		if err := json.Unmarshal(raw, &snapshot); err != nil {
+			log.Printf("[Trader %s] skipping unreadable snapshot: %v", symbol, err)
+			return nil
+		}
+		snapshots[symbol] = snapshot
+		return nil
+	})
+	return snapshots, err
+}
+
+func DeleteTraderSnapshot(store *persistence.StateStore, symbol string) error {
+	return store.Delete(persistence.TraderBucket, symbol)
+}
+
+func (t *Trader) persistState() {
+	snapshot := TraderSnapshot{Cfg: t.cfg, State: t.state, SavedAt: time.Now()}
+	if err := t.store.Put(persistence.TraderBucket, t.cfg.Symbol, snapshot); err != nil {
+		log.Printf("[Trader %s] failed to persist state: %v", t.cfg.Symbol, err)
+	}
+}
+
+// RestoreState seeds the trader from a snapshot, reconciled against the exchange, before Run is called.
+func (t *Trader) RestoreState(snapshot TraderSnapshot, tokenBalance float64) {
+	ctx, cancel := context.WithTimeout(t.ctx, 10*time.Second)
+	defer cancel()
+	t.cfg.AllocatedFunds = snapshot.Cfg.AllocatedFunds
+	t.state = ReconcileTraderState(ctx, t.exchange, t.cfg.Symbol, snapshot.State, tokenBalance)
+	t.persistState()
+	log.Printf("[Trader %s] restored state: target=%v position=%v tokens, pending order=%v", t.cfg.Symbol, t.state.TargetPositionUSD, t.state.ActualPositionToken, t.state.PendingOrder != nil)
+}
+
+// ReconcileTraderState brings a persisted state up to date with the exchange: the pending order is settled
+// against ListOrders and the token position is taken from the balance, re-basing the cost basis when they disagree.
+// Price-derived fields are left for the first ticker to refresh.
+func ReconcileTraderState(ctx context.Context, exchange exchange.IExchange, symbol string, state models.TraderState, tokenBalance float64) models.TraderState {
+	if state.PendingOrder != nil {
+		state = reconcilePendingOrder(ctx, exchange, symbol, state)
+	}
+	if state.Bracket.IsPlaced() {
+		state = reconcileBracket(ctx, exchange, symbol, state)
+	}
+
+	if tokenBalance != state.ActualPositionToken {
+		log.Printf("[Trader %s] persisted position of %v tokens differs from the exchange balance of %v, using the balance", symbol, state.ActualPositionToken, tokenBalance)
+		if state.ActualPositionToken > 0 && tokenBalance > 0 {
+			state.UsdAmountPerFulfilledOrders *= tokenBalance / state.ActualPositionToken
+		} else {
+			state.UsdAmountPerFulfilledOrders = 0 // handlePriceUpdate re-bases on the next price
+		}
+		state.ActualPositionToken = tokenBalance
+	}
+	state.ActualPositionUSD = 0
+	state.CurrentPriceUSDPerToken = 0
+	return state
+}
+
+func reconcilePendingOrder(ctx context.Context, exchange exchange.IExchange, symbol string, state models.TraderState) models.TraderState {
+	pending := *state.PendingOrder
+	orders, err := exchange.ListOrders(ctx, symbol, 50)
+	if err != nil {
+		log.Printf("[Trader %s] could not list orders to reconcile pending order %s, keeping it: %v", symbol, pending.OrderID, err)
+		return state
+	}
+
+	for _, order := range orders.Orders {
+		if order.OrderID != pending.OrderID {
+			continue
+		}
+		filledTokens, _ := strconv.ParseFloat(order.FilledSize, 64)
+		averagePrice, _ := strconv.ParseFloat(order.AverageFilledPrice, 64)
+		filledUSD := filledTokens * averagePrice
+
+		// account for whatever filled while we were down, the same way handleOrderUpdate does
+		newlyFilledTokens := filledTokens - pending.AlreadyFilledInTokens
+		newlyFilledUSD := filledUSD - pending.AlreadyFilledInUSD
+		if newlyFilledTokens > 0 {
+			if pending.OrderType == enum.SignalBuy {
+				state.UsdAmountPerFulfilledOrders += newlyFilledUSD
+				state.ActualPositionToken += newlyFilledTokens
+			} else {
+				state.UsdAmountPerFulfilledOrders -= newlyFilledUSD
+				state.ActualPositionToken -= newlyFilledTokens
+			}
+			pending.AlreadyFilledInTokens = filledTokens
+			pending.AlreadyFilledInUSD = filledUSD
+			pending.CurrentAmountLeftToBeFilledInUSD = max(pending.OriginalAmountInUSD-filledUSD, 0)
+		}
+
+		switch order.Status {
+		case "OPEN", "PENDING", "QUEUED":
+			log.Printf("[Trader %s] pending order %s is still %s", symbol, pending.OrderID, order.Status)
+			state.PendingOrder = &pending
+		default:
+			log.Printf("[Trader %s] pending order %s finished as %s while we were down", symbol, pending.OrderID, order.Status)
+			state.PendingOrder = nil
+		}
+		return state
+	}
+
+	log.Printf("[Trader %s] pending order %s not found on the exchange, dropping it", symbol, pending.OrderID)
+	state.PendingOrder = nil
+	return state
+}
+
+// reconcileBracket books what the bracket exited while we were down and forgets it once it is no longer resting,
+// so it gets placed again for whatever position is left. The token balance already shows the exits, but the
+// target has to follow them or the trader would buy straight back in.
+func reconcileBracket(ctx context.Context, exchange exchange.IExchange, symbol string, state models.TraderState) models.TraderState {
+	bracket := *state.Bracket
+	state.Bracket = &bracket
+	orders, err := exchange.ListOrders(ctx, symbol, 50)
+	if err != nil {
+		log.Printf("[Trader %s] could not list orders to reconcile bracket %s, keeping it: %v", symbol, bracket.OrderID, err)
+		return state
+	}
+	for _, order := range orders.Orders {
+		if order.OrderID != bracket.OrderID {
+			continue
+		}
+		filledTokens, _ := strconv.ParseFloat(order.FilledSize, 64)
+		averagePrice, _ := strconv.ParseFloat(order.AverageFilledPrice, 64)
+		if tokens := filledTokens - bracket.FilledTokens; tokens > 0 && state.ActualPositionToken != 0 {
+			position := math.Abs(state.ActualPositionToken)
+			state.TargetPositionUSD *= max(position-tokens, 0) / position
+			bracket.FilledTokens = filledTokens
+			bracket.FilledUSD = filledTokens * averagePrice
+		}
+		switch order.Status {
+		case "OPEN", "PENDING", "QUEUED":
+			return state
+		case "FILLED":
+			log.Printf("[Trader %s] bracket %s filled while we were down", symbol, bracket.OrderID)
+			state.TargetPositionUSD = 0
+			state.Bracket = nil
+			return state
+		}
+		break
+	}
+	log.Printf("[Trader %s] bracket %s is no longer open, it will be placed again", symbol, bracket.OrderID)
+	bracket.OrderID = ""
+	return state
+}
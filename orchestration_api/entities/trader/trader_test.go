@@ -0,0 +1,34 @@
+package trader
+
+import (
+	"math"
+	"testing"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+func TestGetTargetPositionUSDAfterSignal(t *testing.T) {
+	tests := []struct {
+		name   string
+		target float64
+		signal models.Signal
+		want   float64
+	}{
+		{name: "buy", target: 0, signal: models.Signal{Type: enum.SignalBuy, Percent: 50}, want: 500},
+		{name: "buy capped", target: 800, signal: models.Signal{Type: enum.SignalBuy, Percent: 50}, want: 1000},
+		{name: "sell", target: 500, signal: models.Signal{Type: enum.SignalSell, Percent: 100}, want: 0},
+		{name: "short", target: 0, signal: models.Signal{Type: enum.SignalShort, Percent: 50}, want: -500},
+		{name: "trim takes its share of the position", target: 600, signal: models.Signal{Type: enum.SignalSell, Percent: 20, Trim: true}, want: 480},
+		{name: "trim a short", target: -600, signal: models.Signal{Type: enum.SignalCover, Percent: 20, Trim: true}, want: -480},
+		{name: "trim on the other side", target: -600, signal: models.Signal{Type: enum.SignalSell, Percent: 20, Trim: true}, want: -600},
+		{name: "trim when flat", target: 0, signal: models.Signal{Type: enum.SignalSell, Percent: 20, Trim: true}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetTargetPositionUSDAfterSignal(tt.target, tt.target, 1000, tt.signal); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
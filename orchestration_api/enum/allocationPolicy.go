@@ -0,0 +1,42 @@
+package enum
+
+import "fmt"
+
+type AllocationPolicy int
+
+const (
+	AllocationEqualWeight       AllocationPolicy = iota
+	AllocationInverseVolatility                  // weights inversely proportional to each token's ATR as a share of price
+	AllocationRiskParity                         // every token contributes the same share of portfolio variance
+	AllocationFixedWeights                       // weights set by hand
+)
+
+func GetAllocationPolicyFromString(s string) AllocationPolicy {
+	switch s {
+	case "AllocationEqualWeight":
+		return AllocationEqualWeight
+	case "AllocationInverseVolatility":
+		return AllocationInverseVolatility
+	case "AllocationRiskParity":
+		return AllocationRiskParity
+	case "AllocationFixedWeights":
+		return AllocationFixedWeights
+	default:
+		panic(fmt.Sprintf("Unknown AllocationPolicy (%s)", s))
+	}
+}
+
+func (a AllocationPolicy) String() string {
+	switch a {
+	case AllocationEqualWeight:
+		return "AllocationEqualWeight"
+	case AllocationInverseVolatility:
+		return "AllocationInverseVolatility"
+	case AllocationRiskParity:
+		return "AllocationRiskParity"
+	case AllocationFixedWeights:
+		return "AllocationFixedWeights"
+	default:
+		panic(fmt.Sprintf("Unknown AllocationPolicy (%d)", a))
+	}
+}
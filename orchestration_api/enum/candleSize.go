@@ -1,6 +1,7 @@
 package enum
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -69,6 +70,43 @@ func GetCandleSizeFromString(s string) CandleSize {
 	}
 }
 
+// LookupCandleSize is the candle size named s, e.g. CandleSize1h, false for a name that isn't one.
+func LookupCandleSize(s string) (CandleSize, bool) {
+	for c := CandleSize1m; c <= CandleSize1d; c++ {
+		if c.String() == s {
+			return c, true
+		}
+	}
+	return 0, false
+}
+
+// MarshalJSON writes the candle size's name, e.g. "CandleSize1h".
+func (c CandleSize) MarshalJSON() ([]byte, error) {
+	if c < CandleSize1m || c > CandleSize1d {
+		return json.Marshal(int(c))
+	}
+	return json.Marshal(c.String())
+}
+
+// UnmarshalJSON reads a candle size's name, or the number it was written as before it had one.
+func (c *CandleSize) UnmarshalJSON(raw []byte) error {
+	var name string
+	if err := json.Unmarshal(raw, &name); err != nil {
+		var n int
+		if err := json.Unmarshal(raw, &n); err != nil {
+			return fmt.Errorf("candle size %s is neither a name nor a number", raw)
+		}
+		*c = CandleSize(n)
+		return nil
+	}
+	size, ok := LookupCandleSize(name)
+	if !ok {
+		return fmt.Errorf("unknown candle size %q", name)
+	}
+	*c = size
+	return nil
+}
+
 func GetCoinbaseGranularityFromCandleSize(candleSize CandleSize) string {
 	switch candleSize {
 	case CandleSize1m:
@@ -94,6 +132,31 @@ func GetCoinbaseGranularityFromCandleSize(candleSize CandleSize) string {
 	}
 }
 
+// GetDeribitResolutionFromCandleSize returns the chart resolution Deribit uses for a candle size. Deribit has no
+// 4h resolution, so 4h maps to 2h and the caller has to resample.
+func GetDeribitResolutionFromCandleSize(candleSize CandleSize) string {
+	switch candleSize {
+	case CandleSize1m:
+		return "1"
+	case CandleSize5m:
+		return "5"
+	case CandleSize15m:
+		return "15"
+	case CandleSize30m:
+		return "30"
+	case CandleSize1h:
+		return "60"
+	case CandleSize2h, CandleSize4h:
+		return "120"
+	case CandleSize6h:
+		return "360"
+	case CandleSize1d:
+		return "1D"
+	default:
+		panic(fmt.Sprintf("Unknown CandleSize (%d)", candleSize))
+	}
+}
+
 func GetTimeDurationFromCandleSize(tf CandleSize) time.Duration {
 	switch tf {
 	case CandleSize1m:
@@ -8,6 +8,7 @@ const (
 	ExchangeCoinbase Exchange = iota
 	ExchangeUniswap
 	ExchangeDeribit
+	ExchangePaper
 )
 
 func GetExchangeFromString(s string) Exchange {
@@ -18,6 +19,8 @@ func GetExchangeFromString(s string) Exchange {
 		return ExchangeUniswap
 	case "ExchangeDeribit":
 		return ExchangeDeribit
+	case "ExchangePaper":
+		return ExchangePaper
 	default:
 		panic(fmt.Sprintf("Unknown Exchange (%s)", s))
 	}
@@ -31,6 +34,8 @@ func (e Exchange) String() string {
 		return "ExchangeUniswap"
 	case ExchangeDeribit:
 		return "ExchangeDeribit"
+	case ExchangePaper:
+		return "ExchangePaper"
 	default:
 		panic(fmt.Sprintf("Unknown Exchange (%d)", e))
 	}
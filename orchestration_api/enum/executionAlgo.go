@@ -0,0 +1,42 @@
+package enum
+
+import "fmt"
+
+type ExecutionAlgo int
+
+const (
+	ExecutionImmediate     ExecutionAlgo = iota // the whole deficit in one order
+	ExecutionTWAP                               // evenly sized slices over a fixed horizon
+	ExecutionParticipation                      // slices sized as a share of the volume traded since the last one
+	ExecutionNativeTWAP                         // one exchange-side TWAP order, where the exchange has them
+)
+
+func GetExecutionAlgoFromString(s string) ExecutionAlgo {
+	switch s {
+	case "ExecutionImmediate":
+		return ExecutionImmediate
+	case "ExecutionTWAP":
+		return ExecutionTWAP
+	case "ExecutionParticipation":
+		return ExecutionParticipation
+	case "ExecutionNativeTWAP":
+		return ExecutionNativeTWAP
+	default:
+		panic(fmt.Sprintf("Unknown ExecutionAlgo (%s)", s))
+	}
+}
+
+func (a ExecutionAlgo) String() string {
+	switch a {
+	case ExecutionImmediate:
+		return "ExecutionImmediate"
+	case ExecutionTWAP:
+		return "ExecutionTWAP"
+	case ExecutionParticipation:
+		return "ExecutionParticipation"
+	case ExecutionNativeTWAP:
+		return "ExecutionNativeTWAP"
+	default:
+		panic(fmt.Sprintf("Unknown ExecutionAlgo (%d)", a))
+	}
+}
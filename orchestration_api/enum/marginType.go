@@ -0,0 +1,51 @@
+package enum
+
+import (
+	"fmt"
+	"strings"
+)
+
+type MarginType int
+
+const (
+	MarginCross    MarginType = iota // the position draws on the whole account's collateral
+	MarginIsolated                   // the position can only lose the margin posted for it
+)
+
+func GetMarginTypeFromString(s string) MarginType {
+	switch s {
+	case "MarginCross":
+		return MarginCross
+	case "MarginIsolated":
+		return MarginIsolated
+	default:
+		panic(fmt.Sprintf("Unknown MarginType (%s)", s))
+	}
+}
+
+func (m MarginType) String() string {
+	switch m {
+	case MarginCross:
+		return "MarginCross"
+	case MarginIsolated:
+		return "MarginIsolated"
+	default:
+		panic(fmt.Sprintf("Unknown MarginType (%d)", m))
+	}
+}
+
+// GetCoinbaseMarginTypeFromMarginType is the margin_type an order request takes.
+func GetCoinbaseMarginTypeFromMarginType(m MarginType) string {
+	if m == MarginIsolated {
+		return "ISOLATED"
+	}
+	return "CROSS"
+}
+
+// GetMarginTypeFromCoinbaseMarginType reads the margin_type of an INTX position, e.g. "MARGIN_TYPE_ISOLATED".
+func GetMarginTypeFromCoinbaseMarginType(s string) MarginType {
+	if strings.HasSuffix(s, "ISOLATED") {
+		return MarginIsolated
+	}
+	return MarginCross
+}
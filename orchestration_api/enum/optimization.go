@@ -0,0 +1,78 @@
+package enum
+
+import "fmt"
+
+// SearchMethod is how the optimizer picks the params it backtests
+type SearchMethod int
+
+const (
+	SearchGrid     SearchMethod = iota // every combination of the ranges' steps
+	SearchRandom                       // uniform draws from the ranges
+	SearchBayesian                     // random draws first, then draws steered towards what scored well so far
+)
+
+func GetSearchMethodFromString(s string) SearchMethod {
+	switch s {
+	case "SearchGrid":
+		return SearchGrid
+	case "SearchRandom":
+		return SearchRandom
+	case "SearchBayesian":
+		return SearchBayesian
+	default:
+		panic(fmt.Sprintf("Unknown SearchMethod (%s)", s))
+	}
+}
+
+func (m SearchMethod) String() string {
+	switch m {
+	case SearchGrid:
+		return "SearchGrid"
+	case SearchRandom:
+		return "SearchRandom"
+	case SearchBayesian:
+		return "SearchBayesian"
+	default:
+		panic(fmt.Sprintf("Unknown SearchMethod (%d)", m))
+	}
+}
+
+// Objective is the backtest metric the optimizer maximises
+type Objective int
+
+const (
+	ObjectiveSharpe Objective = iota
+	ObjectiveSortino
+	ObjectiveReturn             // total return
+	ObjectiveReturnOverDrawdown // total return over the max drawdown
+)
+
+func GetObjectiveFromString(s string) Objective {
+	switch s {
+	case "ObjectiveSharpe":
+		return ObjectiveSharpe
+	case "ObjectiveSortino":
+		return ObjectiveSortino
+	case "ObjectiveReturn":
+		return ObjectiveReturn
+	case "ObjectiveReturnOverDrawdown":
+		return ObjectiveReturnOverDrawdown
+	default:
+		panic(fmt.Sprintf("Unknown Objective (%s)", s))
+	}
+}
+
+func (o Objective) String() string {
+	switch o {
+	case ObjectiveSharpe:
+		return "ObjectiveSharpe"
+	case ObjectiveSortino:
+		return "ObjectiveSortino"
+	case ObjectiveReturn:
+		return "ObjectiveReturn"
+	case ObjectiveReturnOverDrawdown:
+		return "ObjectiveReturnOverDrawdown"
+	default:
+		panic(fmt.Sprintf("Unknown Objective (%d)", o))
+	}
+}
@@ -0,0 +1,57 @@
+package enum
+
+import "fmt"
+
+type OrderType int
+
+const (
+	OrderTypeMarket OrderType = iota
+	OrderTypeLimit
+	OrderTypePostOnly // a limit order that is rejected rather than taking liquidity
+	OrderTypeStopLimit
+	OrderTypeBracket  // a resting take-profit limit plus a stop trigger on an existing position
+	OrderTypeLimitIOC // fills what it can up to the limit price right away and cancels the rest
+	OrderTypeTWAP     // the exchange slices it evenly until the expiry, never worse than the limit price
+)
+
+func GetOrderTypeFromString(s string) OrderType {
+	switch s {
+	case "OrderTypeMarket":
+		return OrderTypeMarket
+	case "OrderTypeLimit":
+		return OrderTypeLimit
+	case "OrderTypePostOnly":
+		return OrderTypePostOnly
+	case "OrderTypeStopLimit":
+		return OrderTypeStopLimit
+	case "OrderTypeBracket":
+		return OrderTypeBracket
+	case "OrderTypeLimitIOC":
+		return OrderTypeLimitIOC
+	case "OrderTypeTWAP":
+		return OrderTypeTWAP
+	default:
+		panic(fmt.Sprintf("Unknown OrderType (%s)", s))
+	}
+}
+
+func (o OrderType) String() string {
+	switch o {
+	case OrderTypeMarket:
+		return "OrderTypeMarket"
+	case OrderTypeLimit:
+		return "OrderTypeLimit"
+	case OrderTypePostOnly:
+		return "OrderTypePostOnly"
+	case OrderTypeStopLimit:
+		return "OrderTypeStopLimit"
+	case OrderTypeBracket:
+		return "OrderTypeBracket"
+	case OrderTypeLimitIOC:
+		return "OrderTypeLimitIOC"
+	case OrderTypeTWAP:
+		return "OrderTypeTWAP"
+	default:
+		panic(fmt.Sprintf("Unknown OrderType (%d)", o))
+	}
+}
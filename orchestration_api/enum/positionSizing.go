@@ -0,0 +1,37 @@
+package enum
+
+import "fmt"
+
+type PositionSizing int
+
+const (
+	SizingFixedPercent PositionSizing = iota // the signal's percent of allocated funds, as is
+	SizingFixedRisk                          // a full position loses RiskPct of allocated funds if the stop is hit
+	SizingKelly                              // a fraction of the Kelly bet from the journaled wins and losses
+)
+
+func GetPositionSizingFromString(s string) PositionSizing {
+	switch s {
+	case "SizingFixedPercent":
+		return SizingFixedPercent
+	case "SizingFixedRisk":
+		return SizingFixedRisk
+	case "SizingKelly":
+		return SizingKelly
+	default:
+		panic(fmt.Sprintf("Unknown PositionSizing (%s)", s))
+	}
+}
+
+func (p PositionSizing) String() string {
+	switch p {
+	case SizingFixedPercent:
+		return "SizingFixedPercent"
+	case SizingFixedRisk:
+		return "SizingFixedRisk"
+	case SizingKelly:
+		return "SizingKelly"
+	default:
+		panic(fmt.Sprintf("Unknown PositionSizing (%d)", p))
+	}
+}
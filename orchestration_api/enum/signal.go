@@ -2,13 +2,15 @@ package enum
 
 import "fmt"
 
-// SignalType represents buy/sell/hold
+// SignalType represents buy/sell/hold, and short/cover for traders allowed to go short
 type SignalType int
 
 const (
 	SignalBuy SignalType = iota
 	SignalSell
 	SignalHold
+	SignalShort
+	SignalCover
 )
 
 func (s SignalType) String() string {
@@ -19,6 +21,10 @@ func (s SignalType) String() string {
 		return "SignalSell"
 	case SignalHold:
 		return "SignalHold"
+	case SignalShort:
+		return "SignalShort"
+	case SignalCover:
+		return "SignalCover"
 	default:
 		return ""
 	}
@@ -32,7 +38,16 @@ func GetSignalType(s string) SignalType {
 		return SignalSell
 	case "SignalHold":
 		return SignalHold
+	case "SignalShort":
+		return SignalShort
+	case "SignalCover":
+		return SignalCover
 	default:
 		panic(fmt.Sprintf("Unknown SignalType (%s)", s))
 	}
 }
+
+// IsShortSide reports whether the signal opens, adds to or covers a short position.
+func (s SignalType) IsShortSide() bool {
+	return s == SignalShort || s == SignalCover
+}
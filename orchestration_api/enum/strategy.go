@@ -15,6 +15,9 @@ const (
 	TrendlineBreakout               // https://www.tradingview.com/script/grMQIRAr-Trendline-Breakout-Strategy-KedArc-Quant/
 	Supertrend                      // https://www.tradingview.com/script/r6dAP7yi/ + a simple volume filter
 	GroverLlorensActivator          // https://www.tradingview.com/script/VuYM89Tw-Grover-Llorens-Activator-Strategy-Analysis/
+	Ensemble                        // votes across a set of the strategies above, see signaler/ensembleStrategy.go
+	RuleBased                       // entries and exits written as rules expressions, loaded at runtime
+	Plugin                          // an out-of-process strategy served over gRPC, see signaler/pluginStrategy.go
 )
 
 func (s Strategy) String() string {
@@ -37,6 +40,12 @@ func (s Strategy) String() string {
 		return "Supertrend"
 	case GroverLlorensActivator:
 		return "GroverLlorensActivator"
+	case Ensemble:
+		return "Ensemble"
+	case RuleBased:
+		return "RuleBased"
+	case Plugin:
+		return "Plugin"
 	default:
 		return ""
 	}
@@ -62,7 +71,34 @@ func GetStrategy(s string) Strategy {
 		return Supertrend
 	case "GroverLlorensActivator":
 		return GroverLlorensActivator
+	case "Ensemble":
+		return Ensemble
+	case "RuleBased":
+		return RuleBased
+	case "Plugin":
+		return Plugin
 	default:
 		panic(fmt.Sprintf("Unknown Strategy (%s)", s))
 	}
 }
+
+// GetAllStrategies is every strategy that runs in process. Plugin is left out, it needs a plugin process to talk
+// to, so a run over all of them, e.g. a backtest, would only hold.
+func GetAllStrategies() []Strategy {
+	return []Strategy{MeanReversion, TrendFollowing, CandlestickAggregation, RenkoCandlesticks, HeikenAshi, TurtleTrader, TrendlineBreakout, Supertrend, GroverLlorensActivator, Ensemble, RuleBased}
+}
+
+// GetKnownStrategies is every strategy a trader can run and params can be set for, GetAllStrategies and Plugin.
+func GetKnownStrategies() []Strategy {
+	return append(GetAllStrategies(), Plugin)
+}
+
+// LookupStrategy is GetStrategy for names that come from outside, e.g. an API path, where an unknown one is not a bug.
+func LookupStrategy(s string) (Strategy, bool) {
+	for _, strategy := range GetKnownStrategies() {
+		if strategy.String() == s {
+			return strategy, true
+		}
+	}
+	return 0, false
+}
@@ -7,11 +7,14 @@ import (
 	"encoding/pem"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
 	cb_models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models/coinbase"
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -21,19 +24,54 @@ type CoinbaseClient struct {
 	http      *http.Client
 	apiKey    string
 	apiSecret string
+
+	mu              sync.Mutex
+	products        map[string]cb_models.Product // increments don't change, so fetch them once
+	orderProducts   map[string]string            // order id -> product id, so edits can be rounded too
+	intxPortfolioID string                       // the portfolio perpetual positions live in, looked up once
 }
 
+const (
+	defaultCandleBuckets = 100
+	maxCandlesPerRequest = 300 // the candles endpoint rejects a start/end window wider than this many buckets
+)
+
 func (c *CoinbaseClient) GetHistoricalCandles(ctx context.Context, productID string, candleSize enum.CandleSize) (cb_models.CandlesResponse, error) {
+	return c.GetHistoricalCandlesWithDepth(ctx, productID, candleSize, defaultCandleBuckets)
+}
+
+// GetHistoricalCandlesWithDepth pages back from now, maxCandlesPerRequest buckets at a time, until depth candles
+// are in hand or a page comes back empty because the product doesn't trade that far back. Buckets without a trade
+// are left out of a page, so a quiet product takes more than depth buckets. Newest first, like a single page.
+func (c *CoinbaseClient) GetHistoricalCandlesWithDepth(ctx context.Context, productID string, candleSize enum.CandleSize, depth int) (cb_models.CandlesResponse, error) {
+	size := enum.GetTimeDurationFromCandleSize(candleSize)
+	end := time.Now()
+	out := cb_models.CandlesResponse{Candles: make([]cb_models.CoinbaseHistoricalCandle, 0, depth)}
+	for remaining := depth; remaining > 0; {
+		buckets := min(remaining, maxCandlesPerRequest)
+		start := end.Add(-time.Duration(buckets) * size)
+		page, err := c.getHistoricalCandlesPage(ctx, productID, candleSize, start, end)
+		if err != nil {
+			return cb_models.CandlesResponse{}, err
+		}
+		if len(page.Candles) == 0 {
+			break
+		}
+		out.Candles = append(out.Candles, page.Candles...)
+		remaining -= len(page.Candles)
+		end = start.Add(-time.Second) // both ends are inclusive, so the bucket at start is already in this page
+	}
+	return out, nil
+}
+
+func (c *CoinbaseClient) getHistoricalCandlesPage(ctx context.Context, productID string, candleSize enum.CandleSize, start time.Time, end time.Time) (cb_models.CandlesResponse, error) {
 	url := fmt.Sprintf("%s/api/v3/brokerage/market/products/%s/candles", c.baseURL, productID)
 	req, _ := http.NewRequest(http.MethodGet, url, nil)
 
-	startUnix := time.Now().Add(-100 * enum.GetTimeDurationFromCandleSize(candleSize)).Unix() // 26-ish days ago (should be 314 candles aka buckets)
-	endUnix := time.Now().Unix()
-
 	// Convert the int64 values to strings for the URL query.
 	q := req.URL.Query()
-	q.Set("start", strconv.FormatInt(startUnix, 10))
-	q.Set("end", strconv.FormatInt(endUnix, 10))
+	q.Set("start", strconv.FormatInt(start.Unix(), 10))
+	q.Set("end", strconv.FormatInt(end.Unix(), 10))
 	q.Set("granularity", enum.GetCoinbaseGranularityFromCandleSize(candleSize))
 	req.URL.RawQuery = q.Encode()
 	var out cb_models.CandlesResponse
@@ -61,7 +99,8 @@ func (c *CoinbaseClient) GetAllTokenBalances(ctx context.Context) (map[string]fl
 		if account.Active && account.Ready {
 			// Calculate total balance (available + hold)
 			availableVal := account.AvailableBalance.Value
-			balances[account.Currency] = parseFloatSafe(availableVal)
+			holdVal := account.Hold.Value // resting orders, e.g. a bracket protecting the position, hold funds
+			balances[account.Currency] = parseFloatSafe(availableVal) + parseFloatSafe(holdVal)
 		}
 	}
 
@@ -75,9 +114,47 @@ func (c *CoinbaseClient) ListOrders(ctx context.Context, productID string, limit
 	return out, c.sendWithJwt(ctx, req, &out)
 }
 
-func (c *CoinbaseClient) CreateOrder(ctx context.Context, productID string, amountOfUSD float64, isBuy bool) (cb_models.CreateOrderResponse, error) {
-	body := cb_models.GetOrderRequest(productID, amountOfUSD, isBuy, false)
-	return c.createOrder(ctx, body)
+func (c *CoinbaseClient) GetProduct(ctx context.Context, productID string) (cb_models.Product, error) {
+	url := fmt.Sprintf("%s/api/v3/brokerage/market/products/%s", c.baseURL, productID)
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	var out cb_models.Product
+	return out, c.send(ctx, req, &out)
+}
+
+func (c *CoinbaseClient) CreateOrder(ctx context.Context, productID string, amountOfUSD float64, isBuy bool, spec models.OrderSpec) (cb_models.CreateOrderResponse, error) {
+	if !spec.IsResting() {
+		body := cb_models.GetOrderRequest(productID, amountOfUSD, isBuy, false)
+		addMarginParams(&body, spec)
+		if spec.AttachedBracket == nil {
+			return c.createOrder(ctx, body)
+		}
+		addAttachedBracket(&body, c.getProduct(ctx, productID), spec)
+		out, err := c.createOrder(ctx, body)
+		c.rememberOrderProduct(productID, out.AttachedOrderID)
+		return out, err
+	}
+	body, err := getOrderRequestFromSpec(c.getProduct(ctx, productID), amountOfUSD, isBuy, spec)
+	if err != nil {
+		return cb_models.CreateOrderResponse{Success: false, Error: err.Error()}, err
+	}
+	addMarginParams(&body, spec)
+	addAttachedBracket(&body, c.getProduct(ctx, productID), spec)
+	out, err := c.createOrder(ctx, body)
+	if err == nil {
+		c.rememberOrderProduct(productID, out.OrderID)
+		c.rememberOrderProduct(productID, out.AttachedOrderID)
+	}
+	return out, err
+}
+
+// rememberOrderProduct keeps the product of a resting order, so edits to it can be rounded to its increments.
+func (c *CoinbaseClient) rememberOrderProduct(productID string, orderID string) {
+	if orderID == "" {
+		return
+	}
+	c.mu.Lock()
+	c.orderProducts[orderID] = productID
+	c.mu.Unlock()
 }
 
 func (c *CoinbaseClient) SellTokens(ctx context.Context, productID string, amountOfUSD float64) (cb_models.CreateOrderResponse, error) {
@@ -86,6 +163,17 @@ func (c *CoinbaseClient) SellTokens(ctx context.Context, productID string, amoun
 }
 
 func (c *CoinbaseClient) EditOrder(ctx context.Context, body []byte) (cb_models.EditOrderResponse, error) {
+	var editReq cb_models.EditOrderRequest
+	if err := json.Unmarshal(body, &editReq); err == nil {
+		c.mu.Lock()
+		productID, ok := c.orderProducts[editReq.OrderID]
+		c.mu.Unlock()
+		if ok {
+			if normalized, err := json.Marshal(normalizeEditOrderBody(c.getProduct(ctx, productID), editReq)); err == nil {
+				body = normalized
+			}
+		}
+	}
 	url := fmt.Sprintf("%s/api/v3/brokerage/orders/edit", c.baseURL)
 	req, _ := http.NewRequest(http.MethodPost, url, cb_models.BytesReader(body))
 	var out cb_models.EditOrderResponse
@@ -103,12 +191,88 @@ func (c *CoinbaseClient) CancelOrders(ctx context.Context, orderID string) error
 	return c.sendWithJwt(ctx, req, nil)
 }
 
+// GetPerpPosition reads the product's position from the INTX portfolio, the only one that can hold perpetuals.
+func (c *CoinbaseClient) GetPerpPosition(ctx context.Context, productID string) (models.PerpPosition, error) {
+	portfolioID, err := c.getIntxPortfolioID(ctx)
+	if err != nil {
+		return models.PerpPosition{}, err
+	}
+	url := fmt.Sprintf("%s/api/v3/brokerage/intx/positions/%s/%s", c.baseURL, portfolioID, productID)
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	var out cb_models.IntxPositionResponse
+	if err := c.sendWithJwt(ctx, req, &out); err != nil {
+		return models.PerpPosition{}, err
+	}
+	p := out.Position
+	size := math.Abs(parseFloatSafe(p.NetSize))
+	if p.PositionSide == "POSITION_SIDE_SHORT" {
+		size = -size
+	}
+	return models.PerpPosition{
+		ProductID:        productID,
+		Size:             size,
+		EntryPrice:       parseFloatSafe(p.Vwap.Value),
+		MarkPrice:        parseFloatSafe(p.MarkPrice.Value),
+		LiquidationPrice: parseFloatSafe(p.LiquidationPrice.Value),
+		Leverage:         parseFloatSafe(p.Leverage),
+		MarginType:       enum.GetMarginTypeFromCoinbaseMarginType(p.MarginType),
+	}, nil
+}
+
+// GetFundingRate reads the perpetual's current funding off the product. It isn't cached like the increments, the
+// rate changes every interval.
+func (c *CoinbaseClient) GetFundingRate(ctx context.Context, productID string) (models.FundingRate, error) {
+	product, err := c.GetProduct(ctx, productID)
+	if err != nil {
+		return models.FundingRate{}, err
+	}
+	details := product.FutureProductDetails
+	if details == nil {
+		return models.FundingRate{}, fmt.Errorf("%s is not a perpetual", productID)
+	}
+	interval, err := time.ParseDuration(details.FundingInterval)
+	if err != nil {
+		interval = time.Hour // INTX funds hourly
+	}
+	out := models.FundingRate{ProductID: productID, Rate: parseFloatSafe(details.PerpetualDetails.FundingRate), Interval: interval}
+	if fundingTime, err := time.Parse(time.RFC3339, details.PerpetualDetails.FundingTime); err == nil {
+		out.NextFundingTime = fundingTime
+	}
+	return out, nil
+}
+
+func (c *CoinbaseClient) getIntxPortfolioID(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	portfolioID := c.intxPortfolioID
+	c.mu.Unlock()
+	if portfolioID != "" {
+		return portfolioID, nil
+	}
+	url := fmt.Sprintf("%s/api/v3/brokerage/portfolios?portfolio_type=INTX", c.baseURL)
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	var out cb_models.PortfoliosResponse
+	if err := c.sendWithJwt(ctx, req, &out); err != nil {
+		return "", err
+	}
+	for _, p := range out.Portfolios {
+		if !p.Deleted {
+			c.mu.Lock()
+			c.intxPortfolioID = p.UUID
+			c.mu.Unlock()
+			return p.UUID, nil
+		}
+	}
+	return "", fmt.Errorf("no INTX portfolio, perpetuals can't be traded from this account")
+}
+
 func newCoinbaseClient(baseURL string, apiKey string, apiSecret string) *CoinbaseClient {
 	return &CoinbaseClient{
-		baseURL:   baseURL,
-		http:      &http.Client{Timeout: 10 * time.Second},
-		apiKey:    apiKey,
-		apiSecret: apiSecret,
+		baseURL:       baseURL,
+		http:          &http.Client{Timeout: 10 * time.Second},
+		apiKey:        apiKey,
+		apiSecret:     apiSecret,
+		products:      make(map[string]cb_models.Product),
+		orderProducts: make(map[string]string),
 	}
 }
 
@@ -147,7 +311,14 @@ func (c *CoinbaseClient) createOrder(ctx context.Context, body cb_models.CreateO
 	url := fmt.Sprintf("%s/api/v3/brokerage/orders", c.baseURL)
 	req, _ := http.NewRequest(http.MethodPost, url, cb_models.BytesReader(jsonBody))
 	var out cb_models.CreateOrderResponse
-	return out, c.sendWithJwt(ctx, req, &out)
+	if err := c.sendWithJwt(ctx, req, &out); err != nil {
+		return out, err
+	}
+	out = out.Normalize()
+	if !out.Success {
+		return out, fmt.Errorf("coinbase rejected order: %s", out.Error)
+	}
+	return out, nil
 }
 
 func parseFloatSafe(s string) float64 {
@@ -5,22 +5,27 @@ import (
 	"fmt"
 	"log"
 	"net/url"
-
+	"slices"
 	"sync"
+	"time"
 
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/archive"
 	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
 	exchange_helper "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange/helper"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/indicators"
 	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
 	cb_models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models/coinbase"
-	"github.com/gorilla/websocket"
 )
 
+// orderBookDepth is how many levels a side of a published order book carries.
+const orderBookDepth = 50
+
 // CoinbaseExchange implements the Exchange interface for Coinbase Advanced Trade API
 type CoinbaseExchange struct {
 	mu           sync.RWMutex
 	ctx          context.Context
-	marketDataWS *websocket.Conn
-	userDataWS   *websocket.Conn
+	marketDataWS *wsConn
+	userDataWS   *wsConn
 	apiKey       string
 	apiSecret    string
 
@@ -36,11 +41,17 @@ type CoinbaseExchange struct {
 	// Order update channels per symbol
 	orderChannels map[string][]chan models.OrderUpdate
 
-	client           *CoinbaseClient
-	priceActionStore *exchange_helper.PriceActionStore
+	orderBooks   *exchange_helper.OrderBookStore
+	orderBookHub *exchange_helper.SubscriptionHub[models.OrderBook]
+	tradeHub     *exchange_helper.SubscriptionHub[models.Trade]
+
+	client            *CoinbaseClient
+	priceActionStore  *exchange_helper.PriceActionStore
+	inboundCandleSize enum.CandleSize
+	candleArchive     *archive.CandleArchive // nil when candles aren't archived
 }
 
-func NewCoinbaseExchange(ctx context.Context, apiKey, apiSecret string) *CoinbaseExchange {
+func NewCoinbaseExchange(ctx context.Context, apiKey, apiSecret string, candleArchive *archive.CandleArchive) *CoinbaseExchange {
 	return &CoinbaseExchange{
 		ctx:                 ctx,
 		apiKey:              apiKey,
@@ -49,8 +60,13 @@ func NewCoinbaseExchange(ctx context.Context, apiKey, apiSecret string) *Coinbas
 		candleChannels:      make(map[string][]chan models.Candle),
 		tickerChannels:      make(map[string][]chan models.Ticker),
 		orderChannels:       make(map[string][]chan models.OrderUpdate),
+		orderBooks:          exchange_helper.NewOrderBookStore(),
+		orderBookHub:        exchange_helper.NewSubscriptionHub[models.OrderBook](10),
+		tradeHub:            exchange_helper.NewSubscriptionHub[models.Trade](100),
 		client:              newCoinbaseClient("https://api.coinbase.com", apiKey, apiSecret),
 		priceActionStore:    exchange_helper.NewStore(enum.CandleSize5m),
+		inboundCandleSize:   enum.CandleSize5m,
+		candleArchive:       candleArchive,
 	}
 }
 
@@ -140,6 +156,18 @@ func (e *CoinbaseExchange) SubscribeToCandle(symbol string) (<-chan models.Candl
 	return ch, cleanup
 }
 
+func (e *CoinbaseExchange) SubscribeToOrderBook(symbol string) (<-chan models.OrderBook, func()) {
+	return e.orderBookHub.Subscribe(symbol)
+}
+
+func (e *CoinbaseExchange) SubscribeToTrades(symbol string) (<-chan models.Trade, func()) {
+	return e.tradeHub.Subscribe(symbol)
+}
+
+func (e *CoinbaseExchange) GetOrderBook(symbol string) (models.OrderBook, bool) {
+	return e.orderBooks.GetOrderBook(symbol, orderBookDepth)
+}
+
 func (e *CoinbaseExchange) GetCandleHistory(symbol string) models.CandleHistory {
 	e.mu.Lock()
 	defer e.mu.Unlock()
@@ -154,6 +182,13 @@ func (e *CoinbaseExchange) GetLongCandleHistory(symbol string) models.CandleHist
 	return e.priceActionStore.GetLongCandleHistory(symbol)
 }
 
+func (e *CoinbaseExchange) GetResampledCandleHistory(symbol string, size time.Duration) models.CandleHistory {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.priceActionStore.GetResampledCandleHistory(symbol, size)
+}
+
 func (e *CoinbaseExchange) GetPriceHistory(symbol string) []models.Ticker {
 	e.mu.Lock()
 	defer e.mu.Unlock()
@@ -161,20 +196,43 @@ func (e *CoinbaseExchange) GetPriceHistory(symbol string) []models.Ticker {
 	return e.priceActionStore.GetPriceHistory(symbol)
 }
 
+func (e *CoinbaseExchange) GetPriceHistoryPage(symbol string, before time.Time, limit int) []models.Ticker {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.priceActionStore.GetPriceHistoryPage(symbol, before, limit)
+}
+
+func (e *CoinbaseExchange) GetLastPrice(symbol string) (float64, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.priceActionStore.GetLastPrice(symbol)
+}
+
 func (e *CoinbaseExchange) UpdateInboundCandleSize(candleSize enum.CandleSize) {
+	e.mu.Lock()
+	e.inboundCandleSize = candleSize
+	e.mu.Unlock()
 	e.priceActionStore.UpdateInboundCandleSize(candleSize)
 }
 
-func (e *CoinbaseExchange) StartNewTokenDataStream(symbol string, candleSize enum.CandleSize) error {
-	if e.symbolSubscriptions[symbol] {
-		return nil
+func (e *CoinbaseExchange) StartNewTokenDataStream(symbol string, candleSize enum.CandleSize, historyDepth int) error {
+	e.mu.RLock()
+	subscribed := e.symbolSubscriptions[symbol]
+	e.mu.RUnlock()
+	if subscribed {
+		if e.priceActionStore.HasHistory(symbol, candleSize, historyDepth) {
+			return nil
+		}
+		return e.UpdateCandleSizeForSymbol(symbol, candleSize, historyDepth)
 	}
-	historicalCandles, longHistoricalCandles, err := e.getHistoricalCandleSets(symbol, candleSize)
+	seeds, err := e.getSeedCandles(symbol, candleSize, historyDepth)
 	if err != nil {
 		log.Printf("%v", err)
 		return err
 	}
-	e.priceActionStore.AddToken(symbol, candleSize, historicalCandles, longHistoricalCandles)
+	e.priceActionStore.AddToken(symbol, candleSize, historyDepth, seeds)
 
 	e.mu.Lock()
 	e.symbolSubscriptions[symbol] = true
@@ -236,6 +294,9 @@ func (e *CoinbaseExchange) StopTokenDataStream(symbol string) error {
 	userDataWS := e.userDataWS
 	e.mu.Unlock()
 	e.priceActionStore.RemoveToken(symbol)
+	e.orderBooks.RemoveToken(symbol)
+	e.orderBookHub.CloseSymbol(symbol)
+	e.tradeHub.CloseSymbol(symbol)
 	marketDataSubPayload := cb_models.GetMarketSubscriptionPayload([]string{symbol}, true)
 
 	if marketDataWS != nil {
@@ -263,14 +324,14 @@ func (e *CoinbaseExchange) StopTokenDataStream(symbol string) error {
 	return nil
 }
 
-func (e *CoinbaseExchange) UpdateCandleSizeForSymbol(symbol string, candleSize enum.CandleSize) error {
-	historicalCandles, longHistoricalCandles, err := e.getHistoricalCandleSets(symbol, candleSize)
+func (e *CoinbaseExchange) UpdateCandleSizeForSymbol(symbol string, candleSize enum.CandleSize, historyDepth int) error {
+	seeds, err := e.getSeedCandles(symbol, candleSize, historyDepth)
 	if err != nil {
 		log.Printf("%v", err)
 		return err
 	}
 
-	e.priceActionStore.UpdateCandleSize(symbol, candleSize, historicalCandles, longHistoricalCandles)
+	e.priceActionStore.UpdateCandleSize(symbol, candleSize, historyDepth, seeds)
 	return nil
 }
 
@@ -280,6 +341,12 @@ func (e *CoinbaseExchange) GetRenkoCandleHistory(symbol string) models.RenkoCand
 	return e.priceActionStore.GetRenkoCandleHistory(symbol)
 }
 
+func (e *CoinbaseExchange) GetIndicators(symbol string) *indicators.Set {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.priceActionStore.GetIndicators(symbol)
+}
+
 func (e *CoinbaseExchange) IsRenkoCandleHistoryBuilt(symbol string) bool {
 	e.mu.Lock()
 	defer e.mu.Unlock()
@@ -315,8 +382,8 @@ func (e *CoinbaseExchange) ListOrders(ctx context.Context, productID string, lim
 	return e.client.ListOrders(ctx, productID, limit)
 }
 
-func (e *CoinbaseExchange) CreateOrder(ctx context.Context, productID string, amountOfUSD float64, isBuy bool) (cb_models.CreateOrderResponse, error) {
-	return e.client.CreateOrder(ctx, productID, amountOfUSD, isBuy)
+func (e *CoinbaseExchange) CreateOrder(ctx context.Context, productID string, amountOfUSD float64, isBuy bool, spec models.OrderSpec) (cb_models.CreateOrderResponse, error) {
+	return e.client.CreateOrder(ctx, productID, amountOfUSD, isBuy, spec)
 }
 
 func (e *CoinbaseExchange) SellTokens(ctx context.Context, productID string, amountOfUSD float64) (cb_models.CreateOrderResponse, error) {
@@ -331,15 +398,43 @@ func (e *CoinbaseExchange) CancelOrders(ctx context.Context, orderID string) err
 	return e.client.CancelOrders(ctx, orderID)
 }
 
-func (e *CoinbaseExchange) getHistoricalCandleSets(symbol string, candleSize enum.CandleSize) ([]models.Candle, []models.Candle, error) {
-	historicalCandles, err1 := e.client.GetHistoricalCandles(e.ctx, symbol, candleSize)
-	longHistoricalCandles, err2 := e.client.GetHistoricalCandles(e.ctx, symbol, enum.GetLongCandleSizeFromCandleSize(candleSize))
-	if err1 != nil || err2 != nil {
-		err := fmt.Errorf("failed to get historical candles: %v, %v", err1, err2)
-		log.Printf("%v", err)
-		return nil, nil, err
+func (e *CoinbaseExchange) GetPerpPosition(ctx context.Context, productID string) (models.PerpPosition, error) {
+	return e.client.GetPerpPosition(ctx, productID)
+}
+
+func (e *CoinbaseExchange) GetFundingRate(ctx context.Context, productID string) (models.FundingRate, error) {
+	return e.client.GetFundingRate(ctx, productID)
+}
+
+// getSeedCandles fetches the history the price action store starts the symbol from, historyDepth buckets at each
+// of the seed sizes. A size the archive already holds in full is read from disk instead, a fetched one is archived.
+func (e *CoinbaseExchange) getSeedCandles(symbol string, candleSize enum.CandleSize, historyDepth int) (map[enum.CandleSize][]models.Candle, error) {
+	seeds := make(map[enum.CandleSize][]models.Candle)
+	depth := exchange_helper.GetCandleHistoryDepth(historyDepth)
+	for _, size := range exchange_helper.GetSeedCandleSizes(candleSize) {
+		if e.candleArchive != nil {
+			if candles, ok := e.candleArchive.GetLastClosedCandles(symbol, size, depth); ok {
+				seeds[size] = candles
+				continue
+			}
+		}
+		historicalCandles, err := e.client.GetHistoricalCandlesWithDepth(e.ctx, symbol, size, depth)
+		if err != nil {
+			err = fmt.Errorf("failed to get %s historical candles: %v", size.String(), err)
+			log.Printf("%v", err)
+			return nil, err
+		}
+		// Coinbase returns the newest candle first, the store keeps them oldest first
+		candles := models.GetDomainCandlesFromHistoricalCandles(symbol, historicalCandles.Candles)
+		slices.SortFunc(candles, func(a, b models.Candle) int { return a.Start.Compare(b.Start) })
+		seeds[size] = candles
+		if e.candleArchive != nil {
+			if err := e.candleArchive.WriteMissing(symbol, size, candles); err != nil {
+				log.Printf("failed to archive %s %s candles: %v", symbol, size.String(), err)
+			}
+		}
 	}
-	return models.GetDomainCandlesFromHistoricalCandles(symbol, historicalCandles.Candles), models.GetDomainCandlesFromHistoricalCandles(symbol, longHistoricalCandles.Candles), nil
+	return seeds, nil
 }
 
 func (e *CoinbaseExchange) clearSymbolSubscriptions(symbol string) {
@@ -0,0 +1,165 @@
+package coinbase
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+	cb_models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models/coinbase"
+	"github.com/google/uuid"
+)
+
+// getOrderRequestFromSpec builds the order configuration for a non-market spec. Coinbase only takes quote_size on
+// market orders, so everything else is sized in base units at the limit price and rounded to the product's
+// increments.
+func getOrderRequestFromSpec(product cb_models.Product, amountOfUSD float64, isBuy bool, spec models.OrderSpec) (cb_models.CreateOrderRequest, error) {
+	if spec.LimitPrice <= 0 {
+		return cb_models.CreateOrderRequest{}, fmt.Errorf("%s needs a limit price", spec.Type.String())
+	}
+	side := "SELL"
+	stopDirection := "STOP_DIRECTION_STOP_DOWN"
+	if isBuy {
+		side = "BUY"
+		stopDirection = "STOP_DIRECTION_STOP_UP"
+	}
+	baseSize := formatToIncrement(amountOfUSD/spec.LimitPrice, product.BaseIncrement)
+	limitPrice := formatToIncrement(spec.LimitPrice, product.GetPriceIncrement())
+	stopPrice := formatToIncrement(spec.StopPrice, product.GetPriceIncrement())
+	endTime := ""
+	if !spec.Expiry.IsZero() {
+		endTime = spec.Expiry.UTC().Format(time.RFC3339)
+	}
+
+	cfg := cb_models.OrderConfiguration{}
+	switch spec.Type {
+	case enum.OrderTypeLimit, enum.OrderTypePostOnly:
+		postOnly := spec.Type == enum.OrderTypePostOnly
+		if endTime == "" {
+			cfg.LimitLimitGTC = &cb_models.LimitLimitGTC{BaseSize: baseSize, LimitPrice: limitPrice, PostOnly: postOnly}
+		} else {
+			cfg.LimitLimitGTD = &cb_models.LimitLimitGTD{BaseSize: baseSize, LimitPrice: limitPrice, EndTime: endTime, PostOnly: postOnly}
+		}
+	case enum.OrderTypeStopLimit:
+		if spec.StopPrice <= 0 {
+			return cb_models.CreateOrderRequest{}, fmt.Errorf("stop-limit order needs a stop price")
+		}
+		if endTime == "" {
+			cfg.StopLimitStopLimitGTC = &cb_models.StopLimitStopLimitGTC{BaseSize: baseSize, LimitPrice: limitPrice, StopPrice: stopPrice, StopDirection: stopDirection}
+		} else {
+			cfg.StopLimitStopLimitGTD = &cb_models.StopLimitStopLimitGTD{BaseSize: baseSize, LimitPrice: limitPrice, StopPrice: stopPrice, EndTime: endTime, StopDirection: stopDirection}
+		}
+	case enum.OrderTypeBracket:
+		if spec.StopPrice <= 0 {
+			return cb_models.CreateOrderRequest{}, fmt.Errorf("bracket order needs a stop trigger price")
+		}
+		if endTime == "" {
+			cfg.TriggerBracketGTC = &cb_models.TriggerBracketGTC{BaseSize: baseSize, LimitPrice: limitPrice, StopTriggerPrice: stopPrice}
+		} else {
+			cfg.TriggerBracketGTD = &cb_models.TriggerBracketGTD{BaseSize: baseSize, LimitPrice: limitPrice, StopTriggerPrice: stopPrice, EndTime: endTime}
+		}
+	case enum.OrderTypeLimitIOC:
+		cfg.SORLimitIOC = &cb_models.SORLimitIOC{BaseSize: baseSize, LimitPrice: limitPrice}
+	case enum.OrderTypeTWAP:
+		if endTime == "" || spec.Buckets <= 0 {
+			return cb_models.CreateOrderRequest{}, fmt.Errorf("TWAP order needs an expiry and a number of buckets")
+		}
+		start := time.Now()
+		bucketDuration := spec.Expiry.Sub(start) / time.Duration(spec.Buckets)
+		cfg.TWAPLimitGTD = &cb_models.TWAPLimitGTD{
+			BaseSize:       baseSize,
+			StartTime:      start.UTC().Format(time.RFC3339),
+			EndTime:        endTime,
+			LimitPrice:     limitPrice,
+			NumberBuckets:  strconv.Itoa(spec.Buckets),
+			BucketSize:     formatToIncrement(amountOfUSD/spec.LimitPrice/float64(spec.Buckets), product.BaseIncrement),
+			BucketDuration: fmt.Sprintf("%ds", int(bucketDuration.Seconds())),
+		}
+	default:
+		return cb_models.CreateOrderRequest{}, fmt.Errorf("unsupported order type %s", spec.Type.String())
+	}
+
+	return cb_models.CreateOrderRequest{
+		ClientOrderID:      uuid.New().String(),
+		ProductID:          product.ProductID,
+		Side:               side,
+		OrderConfiguration: cfg,
+	}, nil
+}
+
+// addMarginParams sets the leverage and margin type of a perpetual order; spot orders carry neither. Coinbase has
+// no reduce-only flag, so ReduceOnly is left to the trader sizing its closes to the position.
+func addMarginParams(req *cb_models.CreateOrderRequest, spec models.OrderSpec) {
+	if spec.Leverage <= 0 {
+		return
+	}
+	req.Leverage = strconv.FormatFloat(spec.Leverage, 'f', -1, 64)
+	req.MarginType = enum.GetCoinbaseMarginTypeFromMarginType(spec.MarginType)
+}
+
+// addAttachedBracket attaches the spec's bracket as a trigger bracket, which Coinbase sizes to the entry's fill and
+// places once the entry fills. Coinbase only attaches a take profit and stop together, so a bracket without a
+// take profit is left off for the trader to place itself.
+func addAttachedBracket(req *cb_models.CreateOrderRequest, product cb_models.Product, spec models.OrderSpec) {
+	bracket := spec.AttachedBracket
+	if bracket == nil || bracket.TakeProfit <= 0 || bracket.StopLoss <= 0 {
+		return
+	}
+	req.AttachedOrderConfiguration = &cb_models.OrderConfiguration{
+		TriggerBracketGTC: &cb_models.TriggerBracketGTC{
+			LimitPrice:       formatToIncrement(bracket.TakeProfit, product.GetPriceIncrement()),
+			StopTriggerPrice: formatToIncrement(bracket.StopLoss, product.GetPriceIncrement()),
+		},
+	}
+}
+
+// normalizeEditOrderBody rounds the prices and size of an edit to the product's increments.
+func normalizeEditOrderBody(product cb_models.Product, req cb_models.EditOrderRequest) cb_models.EditOrderRequest {
+	if price, err := strconv.ParseFloat(req.Price, 64); err == nil {
+		req.Price = formatToIncrement(price, product.GetPriceIncrement())
+	}
+	if size, err := strconv.ParseFloat(req.Size, 64); err == nil {
+		req.Size = formatToIncrement(size, product.BaseIncrement)
+	}
+	if stopPrice, err := strconv.ParseFloat(req.StopPrice, 64); err == nil {
+		req.StopPrice = formatToIncrement(stopPrice, product.GetPriceIncrement())
+	}
+	return req
+}
+
+// formatToIncrement rounds value down to a multiple of increment (e.g. "0.01") and formats it with the
+// increment's precision. An unparseable increment leaves the value unrounded.
+func formatToIncrement(value float64, increment string) string {
+	step, err := strconv.ParseFloat(increment, 64)
+	if err != nil || step <= 0 {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	decimals := 0
+	if i := strings.IndexByte(increment, '.'); i >= 0 {
+		decimals = len(strings.TrimRight(increment[i+1:], "0"))
+	}
+	rounded := math.Floor(value/step+1e-9) * step
+	return strconv.FormatFloat(rounded, 'f', decimals, 64)
+}
+
+func (c *CoinbaseClient) getProduct(ctx context.Context, productID string) cb_models.Product {
+	c.mu.Lock()
+	product, ok := c.products[productID]
+	c.mu.Unlock()
+	if ok {
+		return product
+	}
+	product, err := c.GetProduct(ctx, productID)
+	if err != nil {
+		// without increments the exchange may reject the precision, but that is better than not trading
+		return cb_models.Product{ProductID: productID}
+	}
+	c.mu.Lock()
+	c.products[productID] = product
+	c.mu.Unlock()
+	return product
+}
@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/A-Here-And-Now/algo-trader/orchestration_api/channel_helper"
+	exchange_helper "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange/helper"
 	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
 	cb_models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models/coinbase"
 	"github.com/gorilla/websocket"
@@ -23,7 +25,7 @@ type UserChannelMessage struct {
 }
 
 // dialWebSocket connects with the JWT included in the HTTP headers for the WebSocket handshake.
-func dialWebSocketWithAuth(ctx context.Context, wsURL string, apiKey string, apiSecret string) (*websocket.Conn, *http.Response, error) {
+func dialWebSocketWithAuth(ctx context.Context, wsURL string, apiKey string, apiSecret string) (*wsConn, *http.Response, error) {
 	jwtTok, err := buildJWT(apiKey, apiSecret)
 	if err != nil {
 		return nil, nil, fmt.Errorf("build jwt: %w", err)
@@ -43,10 +45,32 @@ func dialWebSocketWithAuth(ctx context.Context, wsURL string, apiKey string, api
 
 	// DialContext will respect ctx cancelation.
 	conn, resp, err := d.DialContext(ctx, wsURL, headers)
-	return conn, resp, err
+	if err != nil {
+		return nil, resp, err
+	}
+	return &wsConn{Conn: conn}, resp, nil
+}
+
+// wsConn lets one write through to the websocket at a time, which is all it takes. The read loop resubscribes, the
+// API subscribes and unsubscribes as tokens start and stop, and the ping loop writes too.
+type wsConn struct {
+	*websocket.Conn
+	writeMu sync.Mutex
+}
+
+func (c *wsConn) WriteJSON(v any) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.Conn.WriteJSON(v)
+}
+
+func (c *wsConn) WriteMessage(messageType int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.Conn.WriteMessage(messageType, data)
 }
 
-func pingLoop(conn *websocket.Conn, ctx context.Context) {
+func pingLoop(conn *wsConn, ctx context.Context) {
 	t := time.NewTicker(30 * time.Second)
 	defer t.Stop()
 	for {
@@ -54,8 +78,7 @@ func pingLoop(conn *websocket.Conn, ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-t.C:
-			conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
-			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(5*time.Second)); err != nil {
 				log.Printf("ping failed: %v", err)
 				return
 			}
@@ -78,7 +101,7 @@ func (e *CoinbaseExchange) runMarketDataWebSocket(ctx context.Context, wsURL str
 		}
 
 		// DialContext will respect ctx cancelation.
-		conn, resp, err := d.DialContext(ctx, wsURL, nil)
+		rawConn, resp, err := d.DialContext(ctx, wsURL, nil)
 		if err != nil {
 			if resp != nil {
 				log.Printf("websocket dial failed, status=%d: %v", resp.StatusCode, err)
@@ -93,6 +116,7 @@ func (e *CoinbaseExchange) runMarketDataWebSocket(ctx context.Context, wsURL str
 			}
 			continue
 		}
+		conn := &wsConn{Conn: rawConn}
 		e.mu.Lock()
 		e.marketDataWS = conn
 		e.mu.Unlock()
@@ -144,7 +168,10 @@ func (e *CoinbaseExchange) runMarketDataWebSocket(ctx context.Context, wsURL str
 	}
 }
 
-func (e *CoinbaseExchange) readLoop(conn *websocket.Conn) {
+func (e *CoinbaseExchange) readLoop(conn *wsConn) {
+	// A new connection numbers its messages from scratch and resends the level2 snapshots
+	e.orderBooks.InvalidateAll()
+	lastSequence := int64(-1)
 	for {
 		// Read a raw JSON message
 		_, raw, err := conn.ReadMessage()
@@ -155,16 +182,19 @@ func (e *CoinbaseExchange) readLoop(conn *websocket.Conn) {
 			return
 		}
 
-		// First, peek at the "type" field to decide how to unmarshal.
-		var channelType struct {
-			Channel string `json:"channel"`
-		}
-		if err := json.Unmarshal(raw, &channelType); err != nil {
+		// First, peek at the channel and sequence number to decide how to unmarshal.
+		var envelope cb_models.MarketDataMsg
+		if err := json.Unmarshal(raw, &envelope); err != nil {
 			log.Printf("[WS] malformed message: %v", err)
 			continue
 		}
+		if lastSequence >= 0 && envelope.SequenceNum > lastSequence+1 {
+			log.Printf("[WS] sequence gap %d -> %d, resyncing order books", lastSequence, envelope.SequenceNum)
+			e.resyncOrderBooks(conn)
+		}
+		lastSequence = envelope.SequenceNum
 
-		switch channelType.Channel {
+		switch envelope.Channel {
 		case "candles":
 			var c models.CandleMsg
 			if err := json.Unmarshal(raw, &c); err != nil {
@@ -175,9 +205,30 @@ func (e *CoinbaseExchange) readLoop(conn *websocket.Conn) {
 			for _, event := range c.Events {
 				for _, coinbaseCandle := range event.Candles {
 					candle := coinbaseCandle.ToCandle()
-					e.consumeCandle(candle)
+					e.consumeCandle(candle, c.Timestamp)
 				}
 			}
+		case "l2_data":
+			var l2 cb_models.Level2Msg
+			if err := json.Unmarshal(raw, &l2); err != nil {
+				log.Printf("[WS] level2 unmarshal error: %v", err)
+				continue
+			}
+			e.consumeLevel2(l2, envelope)
+		case "market_trades":
+			var trades cb_models.MarketTradesMsg
+			if err := json.Unmarshal(raw, &trades); err != nil {
+				log.Printf("[WS] market trades unmarshal error: %v", err)
+				continue
+			}
+			e.consumeTrades(trades)
+		case "ticker":
+			var ticker cb_models.TickerMsg
+			if err := json.Unmarshal(raw, &ticker); err != nil {
+				log.Printf("[WS] ticker unmarshal error: %v", err)
+				continue
+			}
+			e.consumeTicker(ticker, envelope)
 		// Coinbase also sends keep‑alive messages like {"type":"heartbeat"}
 		// – we just ignore them.
 		default:
@@ -186,10 +237,84 @@ func (e *CoinbaseExchange) readLoop(conn *websocket.Conn) {
 	}
 }
 
-func (e *CoinbaseExchange) consumeCandle(inboundCandle models.Candle) {
-	candleToPublish := e.priceActionStore.IngestCandleOfInboundCandleSize(inboundCandle)
+func (e *CoinbaseExchange) consumeCandle(inboundCandle models.Candle, at time.Time) {
+	if e.candleArchive != nil {
+		e.mu.RLock()
+		inboundCandleSize := e.inboundCandleSize
+		e.mu.RUnlock()
+		if err := e.candleArchive.Record(inboundCandleSize, inboundCandle); err != nil {
+			log.Printf("failed to archive %s candle: %v", inboundCandle.ProductID, err)
+		}
+	}
+	candleToPublish := e.priceActionStore.IngestCandleOfInboundCandleSize(inboundCandle, at)
 	e.publishCandle(candleToPublish)
-	e.publishPrice(candleToPublish)
+}
+
+// consumeLevel2 applies a snapshot or a batch of updates to the product's book and publishes the result. Updates
+// for a book waiting on its resync snapshot are dropped.
+func (e *CoinbaseExchange) consumeLevel2(msg cb_models.Level2Msg, envelope cb_models.MarketDataMsg) {
+	for _, event := range msg.Events {
+		changes := make([]exchange_helper.OrderBookChange, 0, len(event.Updates))
+		for _, u := range event.Updates {
+			changes = append(changes, exchange_helper.OrderBookChange{IsBid: u.Side == "bid", Price: u.PriceLevel, Size: u.NewQuantity})
+		}
+		if event.Type == "snapshot" {
+			e.orderBooks.ApplySnapshot(event.ProductID, changes, envelope.SequenceNum, envelope.Timestamp)
+		} else if !e.orderBooks.ApplyChanges(event.ProductID, changes, envelope.SequenceNum, envelope.Timestamp) {
+			continue
+		}
+		if book, ok := e.orderBooks.GetOrderBook(event.ProductID, orderBookDepth); ok {
+			e.orderBookHub.Publish(event.ProductID, book)
+		}
+	}
+}
+
+// consumeTrades publishes the prints off the market_trades channel. Its first event is a snapshot of recent
+// trades, already history by the time it arrives, so only updates go out.
+func (e *CoinbaseExchange) consumeTrades(msg cb_models.MarketTradesMsg) {
+	for _, event := range msg.Events {
+		if event.Type != "update" {
+			continue
+		}
+		for _, t := range event.Trades {
+			e.tradeHub.Publish(t.ProductID, models.Trade{
+				Symbol:  t.ProductID,
+				TradeID: t.TradeID,
+				Price:   t.Price,
+				Size:    t.Size,
+				Side:    t.Side,
+				Time:    t.Time,
+			})
+		}
+	}
+}
+
+// consumeTicker takes a tick off the ticker channel, which Coinbase sends on every trade.
+func (e *CoinbaseExchange) consumeTicker(msg cb_models.TickerMsg, envelope cb_models.MarketDataMsg) {
+	for _, event := range msg.Events {
+		for _, t := range event.Tickers {
+			ticker := models.Ticker{Symbol: t.ProductID, Price: t.Price, Bid: t.BestBid, Ask: t.BestAsk, Time: envelope.Timestamp}
+			e.priceActionStore.IngestTicker(ticker)
+			e.publishPrice(ticker)
+		}
+	}
+}
+
+// resyncOrderBooks drops every book and resubscribes to level2, which starts each product over with a snapshot.
+func (e *CoinbaseExchange) resyncOrderBooks(conn *wsConn) {
+	e.orderBooks.InvalidateAll()
+	symbols := e.getSubscribedSymbols()
+	if len(symbols) == 0 {
+		return
+	}
+	for _, isUnsubscribe := range []bool{true, false} {
+		for _, p := range cb_models.GetChannelSubscriptionPayload(symbols, []string{"level2"}, isUnsubscribe) {
+			if err := conn.WriteJSON(p); err != nil {
+				log.Printf("Failed to resubscribe to level2: %v", err)
+				return
+			}
+		}
+	}
 }
 
 func (e *CoinbaseExchange) publishCandle(candle models.Candle) {
@@ -204,40 +329,40 @@ func (e *CoinbaseExchange) publishCandle(candle models.Candle) {
 	}
 }
 
-func (e *CoinbaseExchange) publishPrice(candle models.Candle) {
+func (e *CoinbaseExchange) publishPrice(ticker models.Ticker) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	symbol := candle.ProductID
-
-	ticker := models.Ticker{
-		Symbol: symbol,
-		Price:  candle.Close,
-		Time:   time.Now(),
-	}
-
 	// Write to all subscribed ticker channels for this symbol
-	if channels, ok := e.tickerChannels[symbol]; ok {
+	if channels, ok := e.tickerChannels[ticker.Symbol]; ok {
 		for _, ch := range channels {
 			channel_helper.WriteToChannelAndBufferLatest(ch, ticker)
 		}
 	}
 }
 
-func (e *CoinbaseExchange) subscribeToMarketDataForAllTokens(conn *websocket.Conn) {
+func (e *CoinbaseExchange) getSubscribedSymbols() []string {
 	e.mu.Lock()
-	// Get all currently subscribed symbols
+	defer e.mu.Unlock()
+
 	symbols := make([]string, 0, len(e.symbolSubscriptions))
-	for symbol := range e.symbolSubscriptions {
-		symbols = append(symbols, symbol)
+	for symbol, subscribed := range e.symbolSubscriptions {
+		if subscribed {
+			symbols = append(symbols, symbol)
+		}
 	}
-	e.mu.Unlock()
+	return symbols
+}
+
+func (e *CoinbaseExchange) subscribeToMarketDataForAllTokens(conn *wsConn) {
+	// Get all currently subscribed symbols
+	symbols := e.getSubscribedSymbols()
 
 	if len(symbols) == 0 {
 		return
 	}
 
-	// Subscription json to the two channels we need, for all products in the "tokens" array
+	// Subscription json to the market data channels we need, for all products in the "tokens" array
 	subPayload := cb_models.GetMarketSubscriptionPayload(symbols, false)
 	for _, p := range subPayload {
 		if err := conn.WriteJSON(p); err != nil {
@@ -318,7 +443,7 @@ func (e *CoinbaseExchange) runUserWebSocket(ctx context.Context, wsURL string) {
 	}
 }
 
-func (e *CoinbaseExchange) sendUserSubscriptions(conn *websocket.Conn) error {
+func (e *CoinbaseExchange) sendUserSubscriptions(conn *wsConn) error {
 	e.mu.Lock()
 	symbols := make([]string, 0, len(e.symbolSubscriptions))
 	for symbol := range e.symbolSubscriptions {
@@ -356,7 +481,7 @@ func (e *CoinbaseExchange) getUserDataSubscriptionPayload(tokens []string, isUns
 	}, nil
 }
 
-func (e *CoinbaseExchange) readUserLoop(conn *websocket.Conn) {
+func (e *CoinbaseExchange) readUserLoop(conn *wsConn) {
 	for {
 		_, raw, err := conn.ReadMessage()
 		if err != nil {
@@ -393,6 +518,7 @@ func getOrderUpdate(o cb_models.Order) models.OrderUpdate {
 		Leaves:        o.Leaves,
 		Price:         o.AvgPrice,
 		Side:          o.OrderSide,
+		Fees:          o.TotalFees,
 		Ts:            models.GetTimeFromUnixTimestamp(o.CreationTime),
 	}
 }
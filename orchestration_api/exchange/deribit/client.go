@@ -0,0 +1,545 @@
+package deribit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	exchange_helper "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange/helper"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+	cb_models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models/coinbase"
+)
+
+// orders are labelled with the product id that placed them, since one instrument can back several product ids
+const orderLabelPrefix = "algo-trader:"
+
+// what GetHistoricalCandles returns, the same depth the Coinbase client asks for
+const historyBuckets = 100
+
+// how many levels a side of a published order book carries
+const orderBookDepth = 50
+
+type chartDataResponse struct {
+	Status string    `json:"status"`
+	Ticks  []int64   `json:"ticks"` // ms
+	Open   []float64 `json:"open"`
+	High   []float64 `json:"high"`
+	Low    []float64 `json:"low"`
+	Close  []float64 `json:"close"`
+	Volume []float64 `json:"volume"`
+}
+
+type deribitOrder struct {
+	OrderID             string  `json:"order_id"`
+	InstrumentName      string  `json:"instrument_name"`
+	Direction           string  `json:"direction"`   // buy, sell
+	OrderState          string  `json:"order_state"` // open, filled, rejected, cancelled, untriggered
+	OrderType           string  `json:"order_type"`
+	Amount              float64 `json:"amount"`
+	FilledAmount        float64 `json:"filled_amount"`
+	AveragePrice        float64 `json:"average_price"`
+	Price               any     `json:"price"` // a number, or "market_price" for market orders
+	Label               string  `json:"label"`
+	CreationTimestamp   int64   `json:"creation_timestamp"`
+	LastUpdateTimestamp int64   `json:"last_update_timestamp"`
+}
+
+type deribitTrade struct {
+	TradeID     string  `json:"trade_id"`
+	OrderID     string  `json:"order_id"`
+	Price       float64 `json:"price"`
+	Fee         float64 `json:"fee"`
+	FeeCurrency string  `json:"fee_currency"`
+}
+
+type orderResponse struct {
+	Order  deribitOrder   `json:"order"`
+	Trades []deribitTrade `json:"trades"`
+}
+
+// getFeesInUSD sums the trade fees, converting coin-settled fees at the trade price.
+func (r orderResponse) getFeesInUSD() float64 {
+	total := 0.0
+	for _, t := range r.Trades {
+		if strings.HasPrefix(t.FeeCurrency, "USD") {
+			total += t.Fee
+		} else {
+			total += t.Fee * t.Price
+		}
+	}
+	return total
+}
+
+type accountSummary struct {
+	Currency       string  `json:"currency"`
+	Equity         float64 `json:"equity"`
+	Balance        float64 `json:"balance"`
+	AvailableFunds float64 `json:"available_funds"`
+}
+
+type position struct {
+	InstrumentName            string  `json:"instrument_name"`
+	Size                      float64 `json:"size"`
+	SizeCurrency              float64 `json:"size_currency"`
+	AveragePrice              float64 `json:"average_price"`
+	MarkPrice                 float64 `json:"mark_price"`
+	EstimatedLiquidationPrice float64 `json:"estimated_liquidation_price"`
+	Leverage                  float64 `json:"leverage"`
+}
+
+type ticker struct {
+	InstrumentName string  `json:"instrument_name"`
+	MarkPrice      float64 `json:"mark_price"`
+	Funding8h      float64 `json:"funding_8h"`
+}
+
+/* ------------------------------------------------------------------------ MARKET DATA ------------------------------------------------------------------------ */
+
+// GetHistoricalCandles fetches the last 100 buckets of candleSize through public/get_tradingview_chart_data,
+// oldest first.
+func (e *DeribitExchange) GetHistoricalCandles(ctx context.Context, productID string, candleSize enum.CandleSize) (cb_models.CandlesResponse, error) {
+	candles, err := e.getHistoricalCandles(ctx, productID, candleSize, historyBuckets)
+	if err != nil {
+		return cb_models.CandlesResponse{}, err
+	}
+	out := cb_models.CandlesResponse{Candles: make([]cb_models.CoinbaseHistoricalCandle, 0, len(candles))}
+	for _, c := range candles {
+		out.Candles = append(out.Candles, cb_models.CoinbaseHistoricalCandle{
+			Start:  strconv.FormatInt(c.Start.Unix(), 10),
+			High:   c.High,
+			Low:    c.Low,
+			Open:   c.Open,
+			Close:  c.Close,
+			Volume: c.Volume,
+		})
+	}
+	return out, nil
+}
+
+// getSeedCandles fetches the history the price action store starts the symbol from, historyDepth buckets at each
+// of the seed sizes.
+func (e *DeribitExchange) getSeedCandles(symbol string, candleSize enum.CandleSize, historyDepth int) (map[enum.CandleSize][]models.Candle, error) {
+	seeds := make(map[enum.CandleSize][]models.Candle)
+	depth := exchange_helper.GetCandleHistoryDepth(historyDepth)
+	for _, size := range exchange_helper.GetSeedCandleSizes(candleSize) {
+		candles, err := e.getHistoricalCandles(e.ctx, symbol, size, depth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s historical candles: %v", size.String(), err)
+		}
+		seeds[size] = candles
+	}
+	return seeds, nil
+}
+
+// getHistoricalCandles fetches the last depth buckets of candleSize. The chart endpoint takes any time range, so
+// unlike Coinbase's candles there is nothing to page.
+func (e *DeribitExchange) getHistoricalCandles(ctx context.Context, symbol string, candleSize enum.CandleSize, depth int) ([]models.Candle, error) {
+	instrument, err := e.getInstrument(symbol)
+	if err != nil {
+		return nil, err
+	}
+	size := enum.GetTimeDurationFromCandleSize(candleSize)
+	end := time.Now()
+	start := end.Add(-time.Duration(depth) * size)
+
+	var out chartDataResponse
+	err = e.call(ctx, "public/get_tradingview_chart_data", map[string]any{
+		"instrument_name": instrument.Name,
+		"start_timestamp": start.UnixMilli(),
+		"end_timestamp":   end.UnixMilli(),
+		"resolution":      enum.GetDeribitResolutionFromCandleSize(candleSize),
+	}, &out)
+	if err != nil {
+		return nil, err
+	}
+
+	candles := make([]models.Candle, 0, len(out.Ticks))
+	for i, tick := range out.Ticks {
+		if i >= len(out.Open) || i >= len(out.High) || i >= len(out.Low) || i >= len(out.Close) || i >= len(out.Volume) {
+			break
+		}
+		candles = append(candles, models.Candle{
+			Start:     time.UnixMilli(tick),
+			Open:      out.Open[i],
+			High:      out.High[i],
+			Low:       out.Low[i],
+			Close:     out.Close[i],
+			Volume:    out.Volume[i],
+			ProductID: symbol,
+		})
+	}
+	if len(candles) == 0 {
+		return nil, fmt.Errorf("deribit returned no %s candles for %s", candleSize.String(), instrument.Name)
+	}
+	// no-op except for 4h, which is fetched as 2h
+	return models.ResampleCandles(candles, size), nil
+}
+
+func (e *DeribitExchange) getLastPrice(symbol string) (float64, error) {
+	if price, ok := e.priceActionStore.GetLastPrice(symbol); ok {
+		return price, nil
+	}
+	return 0, fmt.Errorf("no price for %s yet", symbol)
+}
+
+/* ------------------------------------------------------------------------ ACCOUNT ------------------------------------------------------------------------ */
+
+// ListAccounts reports the collateral held in each settlement currency.
+func (e *DeribitExchange) ListAccounts(ctx context.Context) (cb_models.AccountsListResponse, error) {
+	currencies := e.getSettlementCurrencies()
+	out := cb_models.AccountsListResponse{Accounts: make([]cb_models.Account, 0, len(currencies)), Size: len(currencies)}
+	for _, currency := range currencies {
+		var summary accountSummary
+		if err := e.call(ctx, "private/get_account_summary", map[string]any{"currency": currency}, &summary); err != nil {
+			return cb_models.AccountsListResponse{}, err
+		}
+		out.Accounts = append(out.Accounts, cb_models.Account{
+			UUID:             "deribit-" + currency,
+			Name:             currency + " Margin Account",
+			Currency:         currency,
+			AvailableBalance: cb_models.TokenHolding{Value: strconv.FormatFloat(summary.AvailableFunds, 'f', -1, 64), Currency: currency},
+			Active:           true,
+			Ready:            true,
+			Type:             "ACCOUNT_TYPE_MARGIN",
+			Platform:         "ACCOUNT_PLATFORM_DERIBIT",
+		})
+	}
+	return out, nil
+}
+
+// GetAllTokenBalances reports the open position of every configured instrument in base units, keyed by the
+// product's base currency, since that is what a trader holds. Collateral is in ListAccounts.
+func (e *DeribitExchange) GetAllTokenBalances(ctx context.Context) (map[string]float64, error) {
+	balances := make(map[string]float64)
+	for _, currency := range e.getSettlementCurrencies() {
+		var positions []position
+		if err := e.call(ctx, "private/get_positions", map[string]any{"currency": currency, "kind": "future"}, &positions); err != nil {
+			return nil, err
+		}
+		for symbol, instrument := range e.cfg.Instruments {
+			if instrument.Currency != currency {
+				continue
+			}
+			balances[models.GetBaseCurrency(symbol)] = 0
+			for _, p := range positions {
+				if p.InstrumentName == instrument.Name {
+					balances[models.GetBaseCurrency(symbol)] = p.SizeCurrency
+				}
+			}
+		}
+	}
+	return balances, nil
+}
+
+// GetPerpPosition reads the instrument's position through private/get_position. Deribit margins every position
+// from the account's collateral, so it is always cross margin.
+func (e *DeribitExchange) GetPerpPosition(ctx context.Context, productID string) (models.PerpPosition, error) {
+	instrument, err := e.getInstrument(productID)
+	if err != nil {
+		return models.PerpPosition{}, err
+	}
+	var p position
+	if err := e.call(ctx, "private/get_position", map[string]any{"instrument_name": instrument.Name}, &p); err != nil {
+		return models.PerpPosition{}, err
+	}
+	return models.PerpPosition{
+		ProductID:        productID,
+		Size:             p.SizeCurrency,
+		EntryPrice:       p.AveragePrice,
+		MarkPrice:        p.MarkPrice,
+		LiquidationPrice: p.EstimatedLiquidationPrice,
+		Leverage:         p.Leverage,
+		MarginType:       enum.MarginCross,
+	}, nil
+}
+
+// GetFundingRate takes the 8h funding rate off public/ticker. Deribit accrues funding continuously, which the
+// trader's pro rata accrual matches.
+func (e *DeribitExchange) GetFundingRate(ctx context.Context, productID string) (models.FundingRate, error) {
+	instrument, err := e.getInstrument(productID)
+	if err != nil {
+		return models.FundingRate{}, err
+	}
+	var t ticker
+	if err := e.call(ctx, "public/ticker", map[string]any{"instrument_name": instrument.Name}, &t); err != nil {
+		return models.FundingRate{}, err
+	}
+	return models.FundingRate{ProductID: productID, Rate: t.Funding8h, Interval: 8 * time.Hour}, nil
+}
+
+func (e *DeribitExchange) getSettlementCurrencies() []string {
+	seen := make(map[string]bool)
+	currencies := make([]string, 0)
+	for _, instrument := range e.cfg.Instruments {
+		if !seen[instrument.Currency] {
+			seen[instrument.Currency] = true
+			currencies = append(currencies, instrument.Currency)
+		}
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+/* ------------------------------------------------------------------------ ORDERS ------------------------------------------------------------------------ */
+
+func (e *DeribitExchange) ListOrders(ctx context.Context, productID string, limit int) (cb_models.ListOrdersResponse, error) {
+	instrument, err := e.getInstrument(productID)
+	if err != nil {
+		return cb_models.ListOrdersResponse{}, err
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	var open, history []deribitOrder
+	if err := e.call(ctx, "private/get_open_orders_by_instrument", map[string]any{"instrument_name": instrument.Name}, &open); err != nil {
+		return cb_models.ListOrdersResponse{}, err
+	}
+	if err := e.call(ctx, "private/get_order_history_by_instrument", map[string]any{"instrument_name": instrument.Name, "count": limit}, &history); err != nil {
+		return cb_models.ListOrdersResponse{}, err
+	}
+
+	orders := append(open, history...)
+	sort.Slice(orders, func(i, j int) bool { return orders[i].CreationTimestamp > orders[j].CreationTimestamp })
+	if len(orders) > limit {
+		orders = orders[:limit]
+	}
+
+	out := cb_models.ListOrdersResponse{Orders: make([]cb_models.ListOrder, 0, len(orders))}
+	for _, o := range orders {
+		filledQty, _ := instrument.getQuantityAndValue(o.FilledAmount, o.AveragePrice)
+		remainingQty, _ := instrument.getQuantityAndValue(o.Amount-o.FilledAmount, o.AveragePrice)
+		out.Orders = append(out.Orders, cb_models.ListOrder{
+			OrderID:            o.OrderID,
+			ProductID:          productID,
+			OrderType:          strings.ToUpper(o.OrderType),
+			OrderSide:          strings.ToUpper(o.Direction),
+			Status:             getStatus(o.OrderState),
+			ClientOrderID:      o.Label,
+			CreatedTime:        time.UnixMilli(o.CreationTimestamp).Format(time.RFC3339),
+			CompletionTime:     time.UnixMilli(o.LastUpdateTimestamp).Format(time.RFC3339),
+			Price:              fmt.Sprint(o.Price),
+			AverageFilledPrice: strconv.FormatFloat(o.AveragePrice, 'f', -1, 64),
+			FilledSize:         strconv.FormatFloat(filledQty, 'f', -1, 64),
+			RemainingSize:      strconv.FormatFloat(remainingQty, 'f', -1, 64),
+		})
+	}
+	return out, nil
+}
+
+// CreateOrder places an order from a spec. Leverage and margin type aren't order parameters on Deribit, the
+// account's margin model applies, so of the derivatives fields only ReduceOnly is passed on.
+func (e *DeribitExchange) CreateOrder(ctx context.Context, productID string, amountOfUSD float64, isBuy bool, spec models.OrderSpec) (cb_models.CreateOrderResponse, error) {
+	instrument, err := e.getInstrument(productID)
+	if err != nil {
+		return cb_models.CreateOrderResponse{Success: false, Error: err.Error()}, err
+	}
+	price := spec.LimitPrice
+	if !spec.IsResting() {
+		if price, err = e.getLastPrice(productID); err != nil {
+			return cb_models.CreateOrderResponse{Success: false, Error: err.Error()}, err
+		}
+	}
+	return e.submitOrder(ctx, productID, instrument, instrument.getOrderAmount(amountOfUSD, price), isBuy, spec.ReduceOnly, spec)
+}
+
+// SellTokens mirrors the Coinbase client, where the amount is a base size rather than USD. It is reduce-only so it
+// can close a long but never flip it short.
+func (e *DeribitExchange) SellTokens(ctx context.Context, productID string, amountOfUSD float64) (cb_models.CreateOrderResponse, error) {
+	instrument, err := e.getInstrument(productID)
+	if err != nil {
+		return cb_models.CreateOrderResponse{Success: false, Error: err.Error()}, err
+	}
+	price, err := e.getLastPrice(productID)
+	if err != nil {
+		return cb_models.CreateOrderResponse{Success: false, Error: err.Error()}, err
+	}
+	return e.submitOrder(ctx, productID, instrument, instrument.getOrderAmount(amountOfUSD*price, price), false, true, models.NewMarketOrderSpec())
+}
+
+// submitOrder places an order from a spec. Deribit orders are good till cancelled only, and brackets would need
+// its OTOCO linked orders, so both are refused.
+func (e *DeribitExchange) submitOrder(ctx context.Context, productID string, instrument InstrumentConfig, amount float64, isBuy bool, reduceOnly bool, spec models.OrderSpec) (cb_models.CreateOrderResponse, error) {
+	if amount <= 0 {
+		err := fmt.Errorf("order for %s is smaller than one contract", instrument.Name)
+		return cb_models.CreateOrderResponse{Success: false, Error: err.Error()}, err
+	}
+	method := "private/sell"
+	if isBuy {
+		method = "private/buy"
+	}
+	params := map[string]any{
+		"instrument_name": instrument.Name,
+		"amount":          amount,
+		"type":            "market",
+		"label":           orderLabelPrefix + productID,
+		"reduce_only":     reduceOnly,
+	}
+	if err := addOrderSpecParams(params, spec); err != nil {
+		return cb_models.CreateOrderResponse{Success: false, Error: err.Error()}, err
+	}
+	var out orderResponse
+	if err := e.call(ctx, method, params, &out); err != nil {
+		return cb_models.CreateOrderResponse{Success: false, Error: err.Error()}, err
+	}
+
+	// market orders usually come back already filled; user.orders repeats the same state, which the trader ignores
+	e.publishOrderUpdate(out.Order, out.getFeesInUSD())
+	return cb_models.CreateOrderResponse{Success: true, OrderID: out.Order.OrderID}, nil
+}
+
+// EditOrder takes the Coinbase edit body ({"order_id","price","size"}, size in base units) and maps it onto
+// private/edit. A missing price or size keeps the order's current one.
+func (e *DeribitExchange) EditOrder(ctx context.Context, body []byte) (cb_models.EditOrderResponse, error) {
+	var req cb_models.EditOrderRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return cb_models.EditOrderResponse{Success: false, Error: err.Error()}, err
+	}
+	price, _ := strconv.ParseFloat(req.Price, 64)
+	size, _ := strconv.ParseFloat(req.Size, 64)
+
+	var current deribitOrder
+	if err := e.call(ctx, "private/get_order_state", map[string]any{"order_id": req.OrderID}, &current); err != nil {
+		return cb_models.EditOrderResponse{Success: false, Error: err.Error()}, err
+	}
+	symbol := e.getSymbolForOrder(current)
+	instrument, err := e.getInstrument(symbol)
+	if err != nil {
+		return cb_models.EditOrderResponse{Success: false, Error: err.Error()}, err
+	}
+	amount := current.Amount
+	if price <= 0 {
+		price = current.getLimitPrice()
+	}
+	if size > 0 {
+		amount = instrument.getOrderAmount(size*price, price)
+	}
+
+	params := map[string]any{
+		"order_id": req.OrderID,
+		"amount":   amount,
+		"price":    price,
+	}
+	if stopPrice, err := strconv.ParseFloat(req.StopPrice, 64); err == nil && stopPrice > 0 {
+		params["trigger_price"] = stopPrice
+	}
+
+	var out orderResponse
+	err = e.call(ctx, "private/edit", params, &out)
+	if err != nil {
+		return cb_models.EditOrderResponse{Success: false, Error: err.Error()}, err
+	}
+	return cb_models.EditOrderResponse{Success: true, OrderID: out.Order.OrderID}, nil
+}
+
+func (e *DeribitExchange) CancelOrders(ctx context.Context, orderID string) error {
+	return e.call(ctx, "private/cancel", map[string]any{"order_id": orderID}, nil)
+}
+
+// publishOrderUpdate translates a Deribit order into the Coinbase user-channel shape the trader expects: filled
+// quantity in base units, filled value, leaves and fees in USD. user.orders carries no fees, so updates from the
+// websocket report 0.
+func (e *DeribitExchange) publishOrderUpdate(o deribitOrder, feesInUSD float64) {
+	symbol := e.getSymbolForOrder(o)
+	instrument, err := e.getInstrument(symbol)
+	if err != nil {
+		return
+	}
+	filledQty, filledValue := instrument.getQuantityAndValue(o.FilledAmount, o.AveragePrice)
+	leavesPrice := o.AveragePrice
+	if leavesPrice == 0 {
+		leavesPrice = o.getLimitPrice()
+	}
+	_, leavesValue := instrument.getQuantityAndValue(o.Amount-o.FilledAmount, leavesPrice)
+	completionPct := 0.0
+	if o.Amount > 0 {
+		completionPct = o.FilledAmount / o.Amount * 100
+	}
+
+	e.orderHub.Publish(symbol, models.OrderUpdate{
+		Channel:       "user",
+		ProductID:     symbol,
+		OrderID:       o.OrderID,
+		Status:        getStatus(o.OrderState),
+		FilledQty:     strconv.FormatFloat(filledQty, 'f', -1, 64),
+		FilledValue:   strconv.FormatFloat(filledValue, 'f', -1, 64),
+		CompletionPct: strconv.FormatFloat(completionPct, 'f', -1, 64),
+		Leaves:        strconv.FormatFloat(leavesValue, 'f', -1, 64),
+		Price:         strconv.FormatFloat(o.AveragePrice, 'f', -1, 64),
+		Side:          strings.ToUpper(o.Direction),
+		Fees:          strconv.FormatFloat(feesInUSD, 'f', -1, 64),
+		Ts:            time.UnixMilli(o.CreationTimestamp),
+	})
+}
+
+func (e *DeribitExchange) getSymbolForOrder(o deribitOrder) string {
+	if symbol, ok := strings.CutPrefix(o.Label, orderLabelPrefix); ok {
+		if instrument, err := e.getInstrument(symbol); err == nil && instrument.Name == o.InstrumentName {
+			return symbol
+		}
+	}
+	return e.getSymbolForInstrument(o.InstrumentName)
+}
+
+// getLimitPrice is the order's price, or 0 for market orders where Deribit sends "market_price".
+func (o deribitOrder) getLimitPrice() float64 {
+	if price, ok := o.Price.(float64); ok {
+		return price
+	}
+	return 0
+}
+
+// addOrderSpecParams sets the private/buy and private/sell parameters for a spec on top of a market order's.
+func addOrderSpecParams(params map[string]any, spec models.OrderSpec) error {
+	if !spec.Expiry.IsZero() {
+		return fmt.Errorf("deribit orders are good till cancelled, an expiry is not supported")
+	}
+	switch spec.Type {
+	case enum.OrderTypeMarket:
+	case enum.OrderTypeLimit, enum.OrderTypePostOnly, enum.OrderTypeLimitIOC:
+		if spec.LimitPrice <= 0 {
+			return fmt.Errorf("%s needs a limit price", spec.Type.String())
+		}
+		params["type"] = "limit"
+		params["price"] = spec.LimitPrice
+		if spec.Type == enum.OrderTypePostOnly {
+			params["post_only"] = true
+			params["reject_post_only"] = true
+		}
+		if spec.Type == enum.OrderTypeLimitIOC {
+			params["time_in_force"] = "immediate_or_cancel"
+		}
+	case enum.OrderTypeStopLimit:
+		if spec.LimitPrice <= 0 || spec.StopPrice <= 0 {
+			return fmt.Errorf("stop-limit order needs a limit and a stop price")
+		}
+		params["type"] = "stop_limit"
+		params["price"] = spec.LimitPrice
+		params["trigger_price"] = spec.StopPrice
+		params["trigger"] = "last_price"
+	default:
+		return fmt.Errorf("deribit does not support %s orders", spec.Type.String())
+	}
+	return nil
+}
+
+func getStatus(orderState string) string {
+	switch orderState {
+	case "filled":
+		return "FILLED"
+	case "open":
+		return "OPEN"
+	case "cancelled":
+		return "CANCELLED"
+	case "rejected":
+		return "FAILED"
+	case "untriggered":
+		return "PENDING"
+	default:
+		return strings.ToUpper(orderState)
+	}
+}
@@ -0,0 +1,89 @@
+package deribit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+const defaultWsUrl = "wss://www.deribit.com/ws/api/v2"
+
+// InstrumentConfig maps a product id onto a Deribit instrument. Inverse instruments (ETH-PERPETUAL, BTC-PERPETUAL)
+// are sized in USD, linear ones (ETH_USDC-PERPETUAL) in the base currency.
+type InstrumentConfig struct {
+	Name         string  `json:"name"`
+	Currency     string  `json:"currency"` // settlement currency, used for account summary and positions
+	Inverse      bool    `json:"inverse"`
+	ContractSize float64 `json:"contractSize"` // order amounts are rounded down to a multiple of this
+}
+
+type Config struct {
+	WSURL        string                      `json:"wsUrl"`
+	ClientID     string                      `json:"clientId"`
+	ClientSecret string                      `json:"clientSecret"`
+	Instruments  map[string]InstrumentConfig `json:"instruments"` // keyed by product id, e.g. "ETH-USD"
+}
+
+func DefaultConfig() Config {
+	return Config{
+		WSURL: defaultWsUrl,
+		Instruments: map[string]InstrumentConfig{
+			"ETH-USD":  {Name: "ETH-PERPETUAL", Currency: "ETH", Inverse: true, ContractSize: 1},
+			"BTC-USD":  {Name: "BTC-PERPETUAL", Currency: "BTC", Inverse: true, ContractSize: 10},
+			"WBTC-USD": {Name: "BTC-PERPETUAL", Currency: "BTC", Inverse: true, ContractSize: 10},
+		},
+	}
+}
+
+// LoadConfigFromEnv starts from DefaultConfig, overlays the JSON file named by DERIBIT_CONFIG and then
+// DERIBIT_WS_URL, DERIBIT_CLIENT_ID and DERIBIT_CLIENT_SECRET. Point DERIBIT_WS_URL at test.deribit.com or at a
+// fixture server (cmd/deribitFixture) to develop without touching a real account.
+func LoadConfigFromEnv() (Config, error) {
+	cfg := DefaultConfig()
+	if path := os.Getenv("DERIBIT_CONFIG"); path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("read deribit config: %w", err)
+		}
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			return cfg, fmt.Errorf("parse deribit config: %w", err)
+		}
+	}
+	if v := os.Getenv("DERIBIT_WS_URL"); v != "" {
+		cfg.WSURL = v
+	}
+	if v := os.Getenv("DERIBIT_CLIENT_ID"); v != "" {
+		cfg.ClientID = v
+	}
+	if v := os.Getenv("DERIBIT_CLIENT_SECRET"); v != "" {
+		cfg.ClientSecret = v
+	}
+	if len(cfg.Instruments) == 0 {
+		return cfg, fmt.Errorf("deribit config: at least one instrument is required")
+	}
+	return cfg, nil
+}
+
+// getOrderAmount converts a USD notional into the instrument's order amount.
+func (i InstrumentConfig) getOrderAmount(amountOfUSD float64, price float64) float64 {
+	amount := amountOfUSD
+	if !i.Inverse {
+		amount = amountOfUSD / price
+	}
+	if i.ContractSize > 0 {
+		amount = math.Floor(amount/i.ContractSize) * i.ContractSize
+	}
+	return amount
+}
+
+// getQuantityAndValue converts an instrument amount into base quantity and USD value at price.
+func (i InstrumentConfig) getQuantityAndValue(amount float64, price float64) (float64, float64) {
+	if i.Inverse {
+		if price == 0 {
+			return 0, amount
+		}
+		return amount / price, amount
+	}
+	return amount, amount * price
+}
@@ -0,0 +1,272 @@
+package deribit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	exchange_helper "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange/helper"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/indicators"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+// DeribitExchange implements the Exchange interface over Deribit's JSON-RPC websocket. Market data, order
+// updates and requests all share one authenticated connection.
+type DeribitExchange struct {
+	mu            sync.RWMutex
+	ctx           context.Context
+	cfg           Config
+	conn          *rpcConn // nil until connected (and authenticated, when credentials are set)
+	authenticated bool
+
+	symbolSubscriptions map[string]bool
+	instrumentToSymbols map[string][]string // BTC-PERPETUAL backs both BTC-USD and WBTC-USD
+	inboundCandleSize   enum.CandleSize
+
+	candleHub *exchange_helper.SubscriptionHub[models.Candle]
+	tickerHub *exchange_helper.SubscriptionHub[models.Ticker]
+	orderHub  *exchange_helper.SubscriptionHub[models.OrderUpdate]
+
+	orderBookHub *exchange_helper.SubscriptionHub[models.OrderBook]
+	tradeHub     *exchange_helper.SubscriptionHub[models.Trade]
+	orderBooks   *exchange_helper.OrderBookStore // keyed by instrument name
+
+	priceActionStore *exchange_helper.PriceActionStore
+}
+
+func NewDeribitExchange(ctx context.Context, cfg Config) *DeribitExchange {
+	if cfg.WSURL == "" {
+		cfg.WSURL = defaultWsUrl
+	}
+	instrumentToSymbols := make(map[string][]string)
+	for symbol, instrument := range cfg.Instruments {
+		instrumentToSymbols[instrument.Name] = append(instrumentToSymbols[instrument.Name], symbol)
+	}
+	return &DeribitExchange{
+		ctx:                 ctx,
+		cfg:                 cfg,
+		symbolSubscriptions: make(map[string]bool),
+		instrumentToSymbols: instrumentToSymbols,
+		inboundCandleSize:   enum.CandleSize5m,
+		candleHub:           exchange_helper.NewSubscriptionHub[models.Candle](10),
+		tickerHub:           exchange_helper.NewSubscriptionHub[models.Ticker](10),
+		orderHub:            exchange_helper.NewSubscriptionHub[models.OrderUpdate](10),
+		orderBookHub:        exchange_helper.NewSubscriptionHub[models.OrderBook](10),
+		tradeHub:            exchange_helper.NewSubscriptionHub[models.Trade](100),
+		orderBooks:          exchange_helper.NewOrderBookStore(),
+		priceActionStore:    exchange_helper.NewStore(enum.CandleSize5m),
+	}
+}
+
+func (e *DeribitExchange) getInstrument(symbol string) (InstrumentConfig, error) {
+	instrument, ok := e.cfg.Instruments[symbol]
+	if !ok {
+		return InstrumentConfig{}, fmt.Errorf("no deribit instrument configured for %s", symbol)
+	}
+	return instrument, nil
+}
+
+// getSubscribedSymbols returns the subscribed product ids an instrument is feeding.
+func (e *DeribitExchange) getSubscribedSymbols(instrumentName string) []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	symbols := make([]string, 0, 1)
+	for _, symbol := range e.instrumentToSymbols[instrumentName] {
+		if e.symbolSubscriptions[symbol] {
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols
+}
+
+// getSymbolForInstrument prefers a subscribed product id, falling back to any configured one.
+func (e *DeribitExchange) getSymbolForInstrument(instrumentName string) string {
+	if symbols := e.getSubscribedSymbols(instrumentName); len(symbols) > 0 {
+		return symbols[0]
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if symbols := e.instrumentToSymbols[instrumentName]; len(symbols) > 0 {
+		return symbols[0]
+	}
+	return ""
+}
+
+// call waits for the feed to be connected and then makes the request over it.
+func (e *DeribitExchange) call(ctx context.Context, method string, params any, result any) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultCallTimeout)
+		defer cancel()
+	}
+	for {
+		e.mu.RLock()
+		conn := e.conn
+		e.mu.RUnlock()
+		if conn != nil {
+			return conn.call(ctx, method, params, result)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s: deribit feed not connected: %w", method, ctx.Err())
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func (e *DeribitExchange) SubscribeToOrderUpdates(symbol string) (<-chan models.OrderUpdate, func()) {
+	return e.orderHub.Subscribe(symbol)
+}
+
+func (e *DeribitExchange) SubscribeToTicker(symbol string) (<-chan models.Ticker, func()) {
+	return e.tickerHub.Subscribe(symbol)
+}
+
+func (e *DeribitExchange) SubscribeToCandle(symbol string) (<-chan models.Candle, func()) {
+	return e.candleHub.Subscribe(symbol)
+}
+
+func (e *DeribitExchange) SubscribeToOrderBook(symbol string) (<-chan models.OrderBook, func()) {
+	return e.orderBookHub.Subscribe(symbol)
+}
+
+func (e *DeribitExchange) SubscribeToTrades(symbol string) (<-chan models.Trade, func()) {
+	return e.tradeHub.Subscribe(symbol)
+}
+
+func (e *DeribitExchange) GetOrderBook(symbol string) (models.OrderBook, bool) {
+	instrument, err := e.getInstrument(symbol)
+	if err != nil {
+		return models.OrderBook{}, false
+	}
+	book, ok := e.orderBooks.GetOrderBook(instrument.Name, orderBookDepth)
+	book.Symbol = symbol
+	return book, ok
+}
+
+func (e *DeribitExchange) GetCandleHistory(symbol string) models.CandleHistory {
+	return e.priceActionStore.GetCandleHistory(symbol)
+}
+
+func (e *DeribitExchange) GetLongCandleHistory(symbol string) models.CandleHistory {
+	return e.priceActionStore.GetLongCandleHistory(symbol)
+}
+
+func (e *DeribitExchange) GetResampledCandleHistory(symbol string, size time.Duration) models.CandleHistory {
+	return e.priceActionStore.GetResampledCandleHistory(symbol, size)
+}
+
+func (e *DeribitExchange) GetPriceHistory(symbol string) []models.Ticker {
+	return e.priceActionStore.GetPriceHistory(symbol)
+}
+
+func (e *DeribitExchange) GetPriceHistoryPage(symbol string, before time.Time, limit int) []models.Ticker {
+	return e.priceActionStore.GetPriceHistoryPage(symbol, before, limit)
+}
+
+func (e *DeribitExchange) GetLastPrice(symbol string) (float64, bool) {
+	return e.priceActionStore.GetLastPrice(symbol)
+}
+
+func (e *DeribitExchange) GetRenkoCandleHistory(symbol string) models.RenkoCandleHistory {
+	return e.priceActionStore.GetRenkoCandleHistory(symbol)
+}
+
+func (e *DeribitExchange) GetIndicators(symbol string) *indicators.Set {
+	return e.priceActionStore.GetIndicators(symbol)
+}
+
+func (e *DeribitExchange) IsRenkoCandleHistoryBuilt(symbol string) bool {
+	return e.priceActionStore.IsRenkoCandleHistoryBuilt(symbol)
+}
+
+func (e *DeribitExchange) BuildRenkoCandleHistory(symbol string, brickSize float64) {
+	e.priceActionStore.BuildRenkoCandleHistory(symbol, brickSize)
+}
+
+func (e *DeribitExchange) UpdateInboundCandleSize(candleSize enum.CandleSize) {
+	e.mu.Lock()
+	e.inboundCandleSize = candleSize
+	e.mu.Unlock()
+	e.priceActionStore.UpdateInboundCandleSize(candleSize)
+}
+
+func (e *DeribitExchange) StartNewTokenDataStream(symbol string, candleSize enum.CandleSize, historyDepth int) error {
+	e.mu.RLock()
+	subscribed := e.symbolSubscriptions[symbol]
+	e.mu.RUnlock()
+	if subscribed {
+		if e.priceActionStore.HasHistory(symbol, candleSize, historyDepth) {
+			return nil
+		}
+		return e.UpdateCandleSizeForSymbol(symbol, candleSize, historyDepth)
+	}
+	if _, err := e.getInstrument(symbol); err != nil {
+		return err
+	}
+
+	seeds, err := e.getSeedCandles(symbol, candleSize, historyDepth)
+	if err != nil {
+		log.Printf("%v", err)
+		return err
+	}
+	e.priceActionStore.AddToken(symbol, candleSize, historyDepth, seeds)
+
+	e.mu.Lock()
+	e.symbolSubscriptions[symbol] = true
+	conn := e.conn
+	e.mu.Unlock()
+
+	if conn != nil {
+		if err := e.updateSubscriptions(e.ctx, conn, []string{symbol}, false); err != nil {
+			log.Printf("Failed to send subscription: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *DeribitExchange) StopTokenDataStream(symbol string) error {
+	e.mu.Lock()
+	e.symbolSubscriptions[symbol] = false
+	conn := e.conn
+	e.mu.Unlock()
+
+	e.candleHub.CloseSymbol(symbol)
+	e.tickerHub.CloseSymbol(symbol)
+	e.orderHub.CloseSymbol(symbol)
+	e.orderBookHub.CloseSymbol(symbol)
+	e.tradeHub.CloseSymbol(symbol)
+	e.priceActionStore.RemoveToken(symbol)
+
+	if conn != nil {
+		if err := e.updateSubscriptions(e.ctx, conn, []string{symbol}, true); err != nil {
+			log.Printf("Failed to send unsubscription: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *DeribitExchange) UpdateCandleSizeForSymbol(symbol string, candleSize enum.CandleSize, historyDepth int) error {
+	seeds, err := e.getSeedCandles(symbol, candleSize, historyDepth)
+	if err != nil {
+		log.Printf("%v", err)
+		return err
+	}
+
+	e.priceActionStore.UpdateCandleSize(symbol, candleSize, historyDepth, seeds)
+	return nil
+}
+
+// StartOrderAndPositionValuationWebSocket is a no-op, user.orders rides on the connection StartCoinbaseFeed opens.
+func (e *DeribitExchange) StartOrderAndPositionValuationWebSocket(ctx context.Context, wsURL string) {
+}
+
+// StartCoinbaseFeed opens the Deribit websocket. The url is ignored in favour of cfg.WSURL.
+func (e *DeribitExchange) StartCoinbaseFeed(ctx context.Context, cbAdvUrl string) {
+	go e.runWebSocket(ctx, e.cfg.WSURL)
+}
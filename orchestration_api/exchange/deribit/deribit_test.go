@@ -0,0 +1,251 @@
+package deribit
+
+import (
+	"context"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+var fixturePrice = 2509.88 // every order in the fixture fills at this price
+
+// newFixtureExchange connects an exchange to a fixture server replaying fixtures/eth_perpetual.json and subscribes
+// ETH-USD, which starts the fixture's market data. subscribe runs before that so nothing replayed is missed.
+func newFixtureExchange(t *testing.T, subscribe func(e *DeribitExchange)) *DeribitExchange {
+	t.Helper()
+	fixture, err := LoadFixture("fixtures/eth_perpetual.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(NewFixtureServer(fixture))
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	cfg := DefaultConfig()
+	cfg.WSURL = "ws" + strings.TrimPrefix(server.URL, "http")
+	cfg.ClientID, cfg.ClientSecret = "fixture", "fixture"
+	e := NewDeribitExchange(ctx, cfg)
+	e.StartCoinbaseFeed(ctx, "")
+
+	if subscribe != nil {
+		subscribe(e)
+	}
+	if err := e.StartNewTokenDataStream("ETH-USD", enum.CandleSize5m, 10); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// waitFor reads from ch until done holds for a value, and returns that value.
+func waitFor[T any](t *testing.T, ch <-chan T, done func(v T) bool) T {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				t.Fatal("channel closed")
+			}
+			if done(v) {
+				return v
+			}
+		case <-timeout:
+			var zero T
+			t.Fatal("timed out")
+			return zero
+		}
+	}
+}
+
+func TestMarketData(t *testing.T) {
+	var tickers <-chan models.Ticker
+	var candles <-chan models.Candle
+	e := newFixtureExchange(t, func(e *DeribitExchange) {
+		tickers, _ = e.SubscribeToTicker("ETH-USD")
+		candles, _ = e.SubscribeToCandle("ETH-USD")
+	})
+
+	if history := e.GetCandleHistory("ETH-USD").Candles; len(history) == 0 {
+		t.Error("no candles seeded from public/get_tradingview_chart_data")
+	}
+
+	last := waitFor(t, tickers, func(tk models.Ticker) bool { return tk.Price == 2514.34 })
+	if last.Symbol != "ETH-USD" || last.Bid >= last.Price || last.Ask <= last.Price {
+		t.Errorf("last ticker %+v, want ETH-USD with the price inside the spread", last)
+	}
+	if !last.Time.Equal(time.UnixMilli(1760700009000)) {
+		t.Errorf("last ticker at %v, want the exchange timestamp", last.Time)
+	}
+
+	c := waitFor(t, candles, func(c models.Candle) bool { return c.Close == 2514.34 })
+	if c.ProductID != "ETH-USD" || c.High < 2514.34 || c.Low > 2507.89 {
+		t.Errorf("last candle %+v, want it to span the fixture's 2507.89 to 2514.34", c)
+	}
+}
+
+func TestOrderBookAndTrades(t *testing.T) {
+	var books <-chan models.OrderBook
+	var trades <-chan models.Trade
+	newFixtureExchange(t, func(e *DeribitExchange) {
+		books, _ = e.SubscribeToOrderBook("ETH-USD")
+		trades, _ = e.SubscribeToTrades("ETH-USD")
+	})
+
+	bookTests := []struct {
+		name string
+		at   int64 // the notification's timestamp, in ms
+		bids []models.OrderBookLevel
+		asks []models.OrderBookLevel
+	}{
+		{
+			name: "snapshot",
+			at:   1760700000000,
+			bids: []models.OrderBookLevel{{Price: 2509.9, Size: 12000}, {Price: 2509.85, Size: 30500}, {Price: 2509.8, Size: 8200}, {Price: 2509.7, Size: 45000}},
+			asks: []models.OrderBookLevel{{Price: 2509.95, Size: 9800}, {Price: 2510.0, Size: 22000}, {Price: 2510.05, Size: 15400}, {Price: 2510.15, Size: 51000}},
+		},
+		{
+			name: "change, delete and new levels",
+			at:   1760700000700,
+			bids: []models.OrderBookLevel{{Price: 2509.9, Size: 11300}, {Price: 2509.85, Size: 30500}, {Price: 2509.8, Size: 8200}, {Price: 2509.7, Size: 45000}},
+			asks: []models.OrderBookLevel{{Price: 2509.95, Size: 8300}, {Price: 2510.05, Size: 15400}, {Price: 2510.1, Size: 6000}, {Price: 2510.15, Size: 51000}},
+		},
+	}
+	for _, tt := range bookTests {
+		t.Run(tt.name, func(t *testing.T) {
+			book := waitFor(t, books, func(b models.OrderBook) bool { return b.Time.Equal(time.UnixMilli(tt.at)) })
+			if book.Symbol != "ETH-USD" || !slices.Equal(book.Bids, tt.bids) || !slices.Equal(book.Asks, tt.asks) {
+				t.Errorf("book %+v, want bids %v and asks %v", book, tt.bids, tt.asks)
+			}
+		})
+	}
+
+	tradeTests := []struct {
+		name string
+		want models.Trade
+	}{
+		{name: "buy", want: models.Trade{Symbol: "ETH-USD", TradeID: "ETH-310001", Price: 2509.95, Size: 1500, Side: "BUY", Time: time.UnixMilli(1760700000400)}},
+		{name: "sell", want: models.Trade{Symbol: "ETH-USD", TradeID: "ETH-310002", Price: 2509.9, Size: 700, Side: "SELL", Time: time.UnixMilli(1760700000600)}},
+	}
+	for _, tt := range tradeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := waitFor(t, trades, func(tr models.Trade) bool { return tr.TradeID == tt.want.TradeID })
+			if got.Symbol != tt.want.Symbol || got.Price != tt.want.Price || got.Size != tt.want.Size || got.Side != tt.want.Side ||
+				!got.Time.Equal(tt.want.Time) {
+				t.Errorf("trade %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequests(t *testing.T) {
+	var tickers <-chan models.Ticker
+	var orders <-chan models.OrderUpdate
+	e := newFixtureExchange(t, func(e *DeribitExchange) {
+		tickers, _ = e.SubscribeToTicker("ETH-USD")
+		orders, _ = e.SubscribeToOrderUpdates("ETH-USD")
+	})
+	// market orders are sized off the last price
+	waitFor(t, tickers, func(models.Ticker) bool { return true })
+
+	qty := strconv.FormatFloat(500/fixturePrice, 'f', -1, 64)
+	tests := []struct {
+		name    string
+		request func(ctx context.Context) (string, error) // the order id, if any
+		status  string                                    // the order update that follows, if any
+		qty     string
+	}{
+		{
+			name: "buy",
+			request: func(ctx context.Context) (string, error) {
+				resp, err := e.CreateOrder(ctx, "ETH-USD", 500, true, models.NewMarketOrderSpec())
+				return resp.OrderID, err
+			},
+			status: "FILLED",
+			qty:    qty,
+		},
+		{
+			name: "sell",
+			request: func(ctx context.Context) (string, error) {
+				resp, err := e.SellTokens(ctx, "ETH-USD", 500/fixturePrice)
+				return resp.OrderID, err
+			},
+			status: "FILLED",
+			qty:    qty,
+		},
+		{
+			name: "cancel",
+			request: func(ctx context.Context) (string, error) {
+				return "ETH-1003", e.CancelOrders(ctx, "ETH-1003")
+			},
+			status: "CANCELLED",
+			qty:    "0",
+		},
+		{
+			name: "edit",
+			request: func(ctx context.Context) (string, error) {
+				resp, err := e.EditOrder(ctx, []byte(`{"order_id":"ETH-1003","size":"0.08"}`))
+				if err == nil && !resp.Success {
+					t.Errorf("edit not successful: %s", resp.Error)
+				}
+				return resp.OrderID, err
+			},
+		},
+		{
+			name: "position",
+			request: func(ctx context.Context) (string, error) {
+				p, err := e.GetPerpPosition(ctx, "ETH-USD")
+				if p.Size != 0.199213 || p.EntryPrice != fixturePrice || p.Leverage != 50 || p.MarginType != enum.MarginCross {
+					t.Errorf("position %+v", p)
+				}
+				return "", err
+			},
+		},
+		{
+			name: "funding rate",
+			request: func(ctx context.Context) (string, error) {
+				f, err := e.GetFundingRate(ctx, "ETH-USD")
+				if f.Rate != 8.63e-05 || f.Interval != 8*time.Hour {
+					t.Errorf("funding rate %+v", f)
+				}
+				return "", err
+			},
+		},
+		{
+			name: "list orders",
+			request: func(ctx context.Context) (string, error) {
+				resp, err := e.ListOrders(ctx, "ETH-USD", 10)
+				for i := 1; i < len(resp.Orders); i++ {
+					if resp.Orders[i-1].CreatedTime < resp.Orders[i].CreatedTime {
+						t.Errorf("orders not newest first: %s before %s", resp.Orders[i-1].CreatedTime, resp.Orders[i].CreatedTime)
+					}
+				}
+				if len(resp.Orders) == 0 || resp.Orders[0].OrderID != "ETH-1003" || resp.Orders[0].Status != "OPEN" {
+					t.Errorf("orders %+v, want the open ETH-1003 first", resp.Orders)
+				}
+				return "", err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderID, err := tt.request(t.Context())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.status == "" {
+				return
+			}
+			up := waitFor(t, orders, func(up models.OrderUpdate) bool { return up.OrderID == orderID })
+			if up.Status != tt.status || up.FilledQty != tt.qty {
+				t.Errorf("update %s %s, want %s %s", up.Status, up.FilledQty, tt.status, tt.qty)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package deribit
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Fixture is a recorded Deribit session: canned results per method and the notifications the exchange pushed.
+type Fixture struct {
+	Responses     map[string]json.RawMessage `json:"responses"` // keyed by method, e.g. "private/buy"
+	Notifications []FixtureNotification      `json:"notifications"`
+}
+
+// FixtureNotification is replayed once the method named by After has been called, or right after the first
+// subscribe when After is empty.
+type FixtureNotification struct {
+	After   string          `json:"after"`
+	Channel string          `json:"channel"`
+	DelayMs int             `json:"delayMs"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func LoadFixture(path string) (Fixture, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Fixture{}, fmt.Errorf("read deribit fixture: %w", err)
+	}
+	var f Fixture
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return Fixture{}, fmt.Errorf("parse deribit fixture: %w", err)
+	}
+	return f, nil
+}
+
+// FixtureServer speaks enough of the Deribit websocket API to replay a Fixture, so the adapter can be developed
+// and exercised offline. Point DERIBIT_WS_URL at it.
+type FixtureServer struct {
+	fixture  Fixture
+	upgrader websocket.Upgrader
+}
+
+func NewFixtureServer(f Fixture) *FixtureServer {
+	return &FixtureServer{
+		fixture:  f,
+		upgrader: websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }},
+	}
+}
+
+func (s *FixtureServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("[Deribit fixture] upgrade failed: %v", err)
+		return
+	}
+	defer ws.Close()
+
+	var writeMu sync.Mutex
+	write := func(v any) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		_ = ws.WriteJSON(v)
+	}
+	subscribed := false
+	for {
+		_, raw, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		var req struct {
+			ID     int64           `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(raw, &req); err != nil {
+			continue
+		}
+
+		switch req.Method {
+		case "public/subscribe", "private/subscribe", "public/unsubscribe", "private/unsubscribe":
+			var params struct {
+				Channels []string `json:"channels"`
+			}
+			_ = json.Unmarshal(req.Params, &params)
+			write(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": params.Channels})
+			if req.Method == "public/subscribe" && !subscribed {
+				subscribed = true
+				go s.replay("", write)
+			}
+			continue
+		}
+
+		if result, ok := s.fixture.Responses[req.Method]; ok {
+			write(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result})
+		} else {
+			write(map[string]any{"jsonrpc": "2.0", "id": req.ID, "error": rpcError{Code: -32601, Message: "Method not found"}})
+		}
+		go s.replay(req.Method, write)
+	}
+}
+
+func (s *FixtureServer) replay(after string, write func(v any)) {
+	for _, n := range s.fixture.Notifications {
+		if n.After != after {
+			continue
+		}
+		time.Sleep(time.Duration(n.DelayMs) * time.Millisecond)
+		write(map[string]any{
+			"jsonrpc": "2.0",
+			"method":  "subscription",
+			"params":  map[string]any{"channel": n.Channel, "data": n.Data},
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package deribit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const defaultCallTimeout = 10 * time.Second
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int64  `json:"id"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("deribit error %d: %s", e.Code, e.Message)
+}
+
+// rpcMessage is anything coming off the socket: a response (ID set) or a notification (Method set).
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type subscriptionParams struct {
+	Channel string          `json:"channel"`
+	Data    json.RawMessage `json:"data"`
+}
+
+var errConnectionClosed = errors.New("deribit connection closed")
+
+// rpcConn multiplexes JSON-RPC calls over one websocket, matching responses to callers by id.
+type rpcConn struct {
+	ws      *websocket.Conn
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan rpcMessage
+	closed  bool
+}
+
+func newRPCConn(ws *websocket.Conn) *rpcConn {
+	return &rpcConn{ws: ws, pending: make(map[int64]chan rpcMessage)}
+}
+
+func (c *rpcConn) call(ctx context.Context, method string, params any, result any) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultCallTimeout)
+		defer cancel()
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return errConnectionClosed
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan rpcMessage, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	c.writeMu.Lock()
+	err := c.ws.WriteJSON(rpcRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+	c.writeMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("%s: %w", method, ctx.Err())
+	case resp, ok := <-ch:
+		if !ok {
+			return errConnectionClosed
+		}
+		if resp.Error != nil {
+			return fmt.Errorf("%s: %w", method, resp.Error)
+		}
+		if result == nil {
+			return nil
+		}
+		return json.Unmarshal(resp.Result, result)
+	}
+}
+
+// readLoop routes responses to their callers and everything else to onNotification until the socket errors.
+func (c *rpcConn) readLoop(onNotification func(method string, params json.RawMessage)) error {
+	defer c.close()
+	for {
+		_, raw, err := c.ws.ReadMessage()
+		if err != nil {
+			return err
+		}
+		var msg rpcMessage
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			continue
+		}
+		if msg.ID != nil {
+			c.mu.Lock()
+			ch, ok := c.pending[*msg.ID]
+			c.mu.Unlock()
+			if ok {
+				ch <- msg
+			}
+			continue
+		}
+		if msg.Method != "" {
+			onNotification(msg.Method, msg.Params)
+		}
+	}
+}
+
+func (c *rpcConn) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+}
@@ -0,0 +1,391 @@
+package deribit
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	exchange_helper "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange/helper"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+	"github.com/gorilla/websocket"
+)
+
+type chartTradesData struct {
+	Tick   int64   `json:"tick"` // ms
+	Open   float64 `json:"open"`
+	High   float64 `json:"high"`
+	Low    float64 `json:"low"`
+	Close  float64 `json:"close"`
+	Volume float64 `json:"volume"`
+	Cost   float64 `json:"cost"`
+}
+
+type tickerData struct {
+	InstrumentName string  `json:"instrument_name"`
+	Timestamp      int64   `json:"timestamp"` // ms
+	LastPrice      float64 `json:"last_price"`
+	BestBidPrice   float64 `json:"best_bid_price"`
+	BestAskPrice   float64 `json:"best_ask_price"`
+	MarkPrice      float64 `json:"mark_price"`
+}
+
+// bookData is a book.<instrument>.100ms notification, the whole book first and then changes. Each change carries
+// the change_id of the one before it, anything else means a notification was missed.
+type bookData struct {
+	Type         string            `json:"type"`      // snapshot or change
+	Timestamp    int64             `json:"timestamp"` // ms
+	ChangeID     int64             `json:"change_id"`
+	PrevChangeID int64             `json:"prev_change_id"`
+	Bids         []bookLevelChange `json:"bids"`
+	Asks         []bookLevelChange `json:"asks"`
+}
+
+// bookLevelChange is one ["new"|"change"|"delete", price, amount] entry.
+type bookLevelChange struct {
+	Action string
+	Price  float64
+	Amount float64
+}
+
+func (c *bookLevelChange) UnmarshalJSON(raw []byte) error {
+	var entry [3]json.RawMessage
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(entry[0], &c.Action); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(entry[1], &c.Price); err != nil {
+		return err
+	}
+	return json.Unmarshal(entry[2], &c.Amount)
+}
+
+type tradeData struct {
+	TradeID   string  `json:"trade_id"`
+	Timestamp int64   `json:"timestamp"` // ms
+	Price     float64 `json:"price"`
+	Amount    float64 `json:"amount"`
+	Direction string  `json:"direction"` // the taker's, buy or sell
+}
+
+func (e *DeribitExchange) runWebSocket(ctx context.Context, wsURL string) {
+	backoff := 1 * time.Second
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		d := websocket.Dialer{
+			HandshakeTimeout: 10 * time.Second,
+		}
+
+		ws, resp, err := d.DialContext(ctx, wsURL, nil)
+		if err != nil {
+			if resp != nil {
+				log.Printf("deribit websocket dial failed, status=%d: %v", resp.StatusCode, err)
+			} else {
+				log.Printf("deribit websocket dial failed: %v", err)
+			}
+			time.Sleep(backoff)
+			backoff *= 2
+			if backoff > 30*time.Second {
+				backoff = 30 * time.Second
+			}
+			continue
+		}
+
+		conn := newRPCConn(ws)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			if err := conn.readLoop(func(method string, params json.RawMessage) { e.handleNotification(ctx, conn, method, params) }); err != nil {
+				log.Printf("[Deribit WS] read error: %v", err)
+			}
+		}()
+
+		if err := e.initializeConnection(ctx, conn); err != nil {
+			log.Printf("deribit websocket setup failed: %v", err)
+			_ = ws.Close()
+			<-done
+			time.Sleep(backoff)
+			continue
+		}
+
+		e.mu.Lock()
+		e.conn = conn
+		e.mu.Unlock()
+		backoff = 1 * time.Second
+		log.Printf("deribit websocket connected")
+
+		select {
+		case <-ctx.Done():
+			_ = ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "client shutdown"))
+			_ = ws.Close()
+			e.clearConnection(conn)
+			<-done
+			return
+		case <-done:
+			_ = ws.Close()
+			e.clearConnection(conn)
+			log.Printf("deribit websocket disconnected; will attempt reconnect")
+			time.Sleep(500 * time.Millisecond)
+		}
+	}
+}
+
+func (e *DeribitExchange) clearConnection(conn *rpcConn) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.conn == conn {
+		e.conn = nil
+	}
+}
+
+// initializeConnection authenticates (when credentials are configured), turns on heartbeats and resubscribes
+// every symbol that was subscribed before a reconnect.
+func (e *DeribitExchange) initializeConnection(ctx context.Context, conn *rpcConn) error {
+	authenticated := false
+	if e.cfg.ClientID != "" && e.cfg.ClientSecret != "" {
+		err := conn.call(ctx, "public/auth", map[string]any{
+			"grant_type":    "client_credentials",
+			"client_id":     e.cfg.ClientID,
+			"client_secret": e.cfg.ClientSecret,
+		}, nil)
+		if err != nil {
+			return err
+		}
+		authenticated = true
+	}
+	e.mu.Lock()
+	e.authenticated = authenticated
+	e.mu.Unlock()
+
+	if err := conn.call(ctx, "public/set_heartbeat", map[string]any{"interval": 30}, nil); err != nil {
+		return err
+	}
+
+	e.mu.RLock()
+	symbols := make([]string, 0, len(e.symbolSubscriptions))
+	for symbol, subscribed := range e.symbolSubscriptions {
+		if subscribed {
+			symbols = append(symbols, symbol)
+		}
+	}
+	e.mu.RUnlock()
+	if len(symbols) == 0 {
+		return nil
+	}
+	return e.updateSubscriptions(ctx, conn, symbols, false)
+}
+
+func (e *DeribitExchange) updateSubscriptions(ctx context.Context, conn *rpcConn, symbols []string, isUnsubscribe bool) error {
+	e.mu.RLock()
+	resolution := enum.GetDeribitResolutionFromCandleSize(e.inboundCandleSize)
+	authenticated := e.authenticated
+	e.mu.RUnlock()
+
+	publicChannels := make([]string, 0, 2*len(symbols))
+	privateChannels := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		instrument, err := e.getInstrument(symbol)
+		if err != nil {
+			return err
+		}
+		if isUnsubscribe && len(e.getSubscribedSymbols(instrument.Name)) > 0 {
+			continue // still feeding another product id
+		}
+		if isUnsubscribe {
+			e.orderBooks.RemoveToken(instrument.Name)
+		}
+		publicChannels = append(publicChannels, "chart.trades."+instrument.Name+"."+resolution, "ticker."+instrument.Name+".100ms",
+			"book."+instrument.Name+".100ms", "trades."+instrument.Name+".100ms")
+		privateChannels = append(privateChannels, "user.orders."+instrument.Name+".raw")
+	}
+
+	if len(publicChannels) == 0 {
+		return nil
+	}
+	subType := "subscribe"
+	if isUnsubscribe {
+		subType = "unsubscribe"
+	}
+	if err := conn.call(ctx, "public/"+subType, map[string]any{"channels": publicChannels}, nil); err != nil {
+		return err
+	}
+	if authenticated {
+		return conn.call(ctx, "private/"+subType, map[string]any{"channels": privateChannels}, nil)
+	}
+	return nil
+}
+
+func (e *DeribitExchange) handleNotification(ctx context.Context, conn *rpcConn, method string, params json.RawMessage) {
+	switch method {
+	case "heartbeat":
+		var hb struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(params, &hb); err == nil && hb.Type == "test_request" {
+			go func() {
+				if err := conn.call(ctx, "public/test", map[string]any{}, nil); err != nil {
+					log.Printf("[Deribit WS] heartbeat reply failed: %v", err)
+				}
+			}()
+		}
+	case "subscription":
+		var sub subscriptionParams
+		if err := json.Unmarshal(params, &sub); err != nil {
+			log.Printf("[Deribit WS] malformed subscription message: %v", err)
+			return
+		}
+		e.handleSubscriptionData(ctx, conn, sub)
+	}
+}
+
+// handleSubscriptionData dispatches on the channel name, e.g. chart.trades.ETH-PERPETUAL.5
+func (e *DeribitExchange) handleSubscriptionData(ctx context.Context, conn *rpcConn, sub subscriptionParams) {
+	parts := strings.Split(sub.Channel, ".")
+	var kind, instrumentName string
+	switch {
+	case strings.HasPrefix(sub.Channel, "chart.trades.") && len(parts) >= 4:
+		kind, instrumentName = "candle", parts[2]
+	case strings.HasPrefix(sub.Channel, "user.orders.") && len(parts) >= 4:
+		kind, instrumentName = "order", parts[2]
+	case strings.HasPrefix(sub.Channel, "ticker.") && len(parts) >= 3:
+		kind, instrumentName = "ticker", parts[1]
+	case strings.HasPrefix(sub.Channel, "book.") && len(parts) >= 3:
+		e.consumeBook(ctx, conn, sub.Channel, parts[1], sub.Data)
+		return
+	case strings.HasPrefix(sub.Channel, "trades.") && len(parts) >= 3:
+		kind, instrumentName = "trades", parts[1]
+	default:
+		return
+	}
+
+	for _, symbol := range e.getSubscribedSymbols(instrumentName) {
+		switch kind {
+		case "candle":
+			var c chartTradesData
+			if err := json.Unmarshal(sub.Data, &c); err != nil {
+				log.Printf("[Deribit WS] candle unmarshal error: %v", err)
+				return
+			}
+			e.consumeCandle(models.Candle{
+				Start:     time.UnixMilli(c.Tick),
+				High:      c.High,
+				Low:       c.Low,
+				Open:      c.Open,
+				Close:     c.Close,
+				Volume:    c.Volume,
+				ProductID: symbol,
+			})
+		case "ticker":
+			var t tickerData
+			if err := json.Unmarshal(sub.Data, &t); err != nil {
+				log.Printf("[Deribit WS] ticker unmarshal error: %v", err)
+				return
+			}
+			ticker := models.Ticker{Symbol: symbol, Price: t.LastPrice, Bid: t.BestBidPrice, Ask: t.BestAskPrice, Time: time.UnixMilli(t.Timestamp)}
+			e.priceActionStore.IngestTicker(ticker)
+			e.tickerHub.Publish(symbol, ticker)
+		case "trades":
+			var trades []tradeData
+			if err := json.Unmarshal(sub.Data, &trades); err != nil {
+				log.Printf("[Deribit WS] trades unmarshal error: %v", err)
+				return
+			}
+			for _, t := range trades {
+				e.tradeHub.Publish(symbol, models.Trade{
+					Symbol:  symbol,
+					TradeID: t.TradeID,
+					Price:   t.Price,
+					Size:    t.Amount,
+					Side:    strings.ToUpper(t.Direction),
+					Time:    time.UnixMilli(t.Timestamp),
+				})
+			}
+		case "order":
+			var o deribitOrder
+			if err := json.Unmarshal(sub.Data, &o); err != nil {
+				log.Printf("[Deribit WS] order unmarshal error: %v", err)
+				return
+			}
+			// only the symbol that placed the order should see it
+			if symbol == e.getSymbolForOrder(o) {
+				e.publishOrderUpdate(o, 0)
+			}
+		}
+	}
+}
+
+func (e *DeribitExchange) consumeCandle(inboundCandle models.Candle) {
+	// chart.trades carries no time of its own, the store places it by the last ticker's
+	candleToPublish := e.priceActionStore.IngestCandleOfInboundCandleSize(inboundCandle, time.Time{})
+	e.candleHub.Publish(candleToPublish.ProductID, candleToPublish)
+}
+
+// consumeBook applies a book notification to the instrument's book and publishes it to every symbol the instrument
+// feeds. A change that doesn't follow on from the last one drops the book and resubscribes the channel for a fresh
+// snapshot, changes arriving in the meantime are ignored.
+func (e *DeribitExchange) consumeBook(ctx context.Context, conn *rpcConn, channel string, instrumentName string, raw json.RawMessage) {
+	var b bookData
+	if err := json.Unmarshal(raw, &b); err != nil {
+		log.Printf("[Deribit WS] book unmarshal error: %v", err)
+		return
+	}
+	changes := make([]exchange_helper.OrderBookChange, 0, len(b.Bids)+len(b.Asks))
+	for _, side := range []struct {
+		isBid  bool
+		levels []bookLevelChange
+	}{{true, b.Bids}, {false, b.Asks}} {
+		for _, l := range side.levels {
+			size := l.Amount
+			if l.Action == "delete" {
+				size = 0
+			}
+			changes = append(changes, exchange_helper.OrderBookChange{IsBid: side.isBid, Price: l.Price, Size: size})
+		}
+	}
+
+	t := time.UnixMilli(b.Timestamp)
+	if b.Type == "snapshot" {
+		e.orderBooks.ApplySnapshot(instrumentName, changes, b.ChangeID, t)
+	} else {
+		lastChangeID, synced := e.orderBooks.GetSequence(instrumentName)
+		if !synced {
+			return
+		}
+		if b.PrevChangeID != lastChangeID {
+			log.Printf("[Deribit WS] %s book gap %d -> %d, resubscribing", instrumentName, lastChangeID, b.PrevChangeID)
+			e.orderBooks.Invalidate(instrumentName)
+			// the reply to a call is read by the loop this runs on, so it can't wait for it here
+			go e.resubscribeBook(ctx, conn, channel)
+			return
+		}
+		e.orderBooks.ApplyChanges(instrumentName, changes, b.ChangeID, t)
+	}
+
+	book, ok := e.orderBooks.GetOrderBook(instrumentName, orderBookDepth)
+	if !ok {
+		return
+	}
+	for _, symbol := range e.getSubscribedSymbols(instrumentName) {
+		book.Symbol = symbol
+		e.orderBookHub.Publish(symbol, book)
+	}
+}
+
+func (e *DeribitExchange) resubscribeBook(ctx context.Context, conn *rpcConn, channel string) {
+	if err := conn.call(ctx, "public/unsubscribe", map[string]any{"channels": []string{channel}}, nil); err != nil {
+		log.Printf("[Deribit WS] book unsubscribe failed: %v", err)
+		return
+	}
+	if err := conn.call(ctx, "public/subscribe", map[string]any{"channels": []string{channel}}, nil); err != nil {
+		log.Printf("[Deribit WS] book resubscribe failed: %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package helper
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+// OrderBookChange sets the size resting at a price level, a zero size removes the level.
+type OrderBookChange struct {
+	IsBid bool
+	Price float64
+	Size  float64
+}
+
+type orderBookState struct {
+	bids     []models.OrderBookLevel // highest first
+	asks     []models.OrderBookLevel // lowest first
+	sequence int64
+	time     time.Time
+	synced   bool
+}
+
+// OrderBookStore keeps a level 2 book per symbol from an exchange's snapshot and incremental updates. Sequencing
+// is the exchange's business: when it sees a gap it invalidates the book, and the book reads as missing until
+// the exchange has fetched and applied a fresh snapshot.
+type OrderBookStore struct {
+	mu    sync.Mutex
+	books map[string]*orderBookState
+}
+
+func NewOrderBookStore() *OrderBookStore {
+	return &OrderBookStore{books: make(map[string]*orderBookState)}
+}
+
+// ApplySnapshot replaces the symbol's book and marks it in sync as of sequence.
+func (s *OrderBookStore) ApplySnapshot(symbol string, changes []OrderBookChange, sequence int64, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	book := &orderBookState{sequence: sequence, time: t, synced: true}
+	for _, c := range changes {
+		book.apply(c)
+	}
+	s.books[symbol] = book
+}
+
+// ApplyChanges applies incremental updates on top of the last snapshot. It returns false, and applies nothing,
+// while the book is out of sync.
+func (s *OrderBookStore) ApplyChanges(symbol string, changes []OrderBookChange, sequence int64, t time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	book, ok := s.books[symbol]
+	if !ok || !book.synced {
+		return false
+	}
+	for _, c := range changes {
+		book.apply(c)
+	}
+	book.sequence = sequence
+	book.time = t
+	return true
+}
+
+// GetSequence is the sequence the symbol's book was last updated to, false if it is out of sync.
+func (s *OrderBookStore) GetSequence(symbol string) (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	book, ok := s.books[symbol]
+	if !ok || !book.synced {
+		return 0, false
+	}
+	return book.sequence, true
+}
+
+// Invalidate drops the symbol's book until its next snapshot.
+func (s *OrderBookStore) Invalidate(symbol string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if book, ok := s.books[symbol]; ok {
+		book.synced = false
+	}
+}
+
+func (s *OrderBookStore) InvalidateAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, book := range s.books {
+		book.synced = false
+	}
+}
+
+func (s *OrderBookStore) RemoveToken(symbol string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.books, symbol)
+}
+
+// GetOrderBook copies the top depth levels of each side, false while the book is missing or out of sync.
+func (s *OrderBookStore) GetOrderBook(symbol string, depth int) (models.OrderBook, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	book, ok := s.books[symbol]
+	if !ok || !book.synced {
+		return models.OrderBook{}, false
+	}
+	return models.OrderBook{
+		Symbol: symbol,
+		Bids:   append([]models.OrderBookLevel(nil), book.bids[:min(depth, len(book.bids))]...),
+		Asks:   append([]models.OrderBookLevel(nil), book.asks[:min(depth, len(book.asks))]...),
+		Time:   book.time,
+	}, true
+}
+
+func (b *orderBookState) apply(c OrderBookChange) {
+	if c.IsBid {
+		b.bids = setLevel(b.bids, c, func(p float64) bool { return p <= c.Price })
+	} else {
+		b.asks = setLevel(b.asks, c, func(p float64) bool { return p >= c.Price })
+	}
+}
+
+// setLevel sets c's level in a side kept sorted best first, where reached reports whether a price is at or past
+// c's in that order.
+func setLevel(levels []models.OrderBookLevel, c OrderBookChange, reached func(float64) bool) []models.OrderBookLevel {
+	i := sort.Search(len(levels), func(i int) bool { return reached(levels[i].Price) })
+	exists := i < len(levels) && levels[i].Price == c.Price
+	switch {
+	case c.Size == 0 && exists:
+		return append(levels[:i], levels[i+1:]...)
+	case c.Size == 0:
+		return levels
+	case exists:
+		levels[i].Size = c.Size
+		return levels
+	}
+	levels = append(levels, models.OrderBookLevel{})
+	copy(levels[i+1:], levels[i:])
+	levels[i] = models.OrderBookLevel{Price: c.Price, Size: c.Size}
+	return levels
+}
@@ -1,39 +1,62 @@
 package helper
 
 import (
+	"sort"
 	"sync"
 	"time"
 	"math"
 
 	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/indicators"
 	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
 )
 
+const (
+	baseCandleSize      = enum.CandleSize1m
+	maxBaseCandles      = 7 * 24 * 60 // a week of base candles, anything older comes from the seeded histories
+	candleHistoryLength = 100         // the least GetCandleHistory and GetLongCandleHistory hand the strategies
+	maxPriceHistory     = 20000       // ticks, an hour or two of a busy product
+	maxHistoryDepth     = 5000        // the most candles a strategy's warm-up can have backfilled and kept
+)
+
 type IPriceActionStore interface {
 	UpdateInboundCandleSize(candleSize enum.CandleSize)
-	UpdateCandleSize(symbol string, candleSize enum.CandleSize, longCandleSize enum.CandleSize)
-	AddToken(symbol string, candleSize enum.CandleSize, candleHistory []models.Candle, longCandleHistory []models.Candle)
+	UpdateCandleSize(symbol string, candleSize enum.CandleSize, historyDepth int, seeds map[enum.CandleSize][]models.Candle)
+	AddToken(symbol string, candleSize enum.CandleSize, historyDepth int, seeds map[enum.CandleSize][]models.Candle)
+	HasHistory(symbol string, candleSize enum.CandleSize, historyDepth int) bool
 	RemoveToken(symbol string)
-	IngestCandleOfInboundCandleSize(candle models.Candle) models.Candle
+	IngestCandleOfInboundCandleSize(candle models.Candle, at time.Time) models.Candle
+	IngestTicker(ticker models.Ticker)
 	GetPriceHistory(symbol string) []models.Ticker
+	GetPriceHistoryPage(symbol string, before time.Time, limit int) []models.Ticker
+	GetLastPrice(symbol string) (float64, bool)
 	GetCandleHistory(symbol string) models.CandleHistory
 	GetLongCandleHistory(symbol string) models.CandleHistory
+	GetResampledCandleHistory(symbol string, size time.Duration) models.CandleHistory
 	GetRenkoCandleHistory(symbol string) models.RenkoCandleHistory
+	GetIndicators(symbol string) *indicators.Set
 	IsRenkoCandleHistoryBuilt(symbol string) bool
 	BuildRenkoCandleHistory(symbol string, brickSize float64)
 }
 
+// PriceActionStore keeps one series of 1m base candles per symbol, built from the inbound running candles, and
+// resamples it to whatever size is asked for. History from before the base reaches back comes from the candles the
+// exchange seeded the symbol with, at whichever seeded size divides the one asked for. A size is resampled once,
+// the first time it is asked for, and from then on kept current one base update at a time. Each symbol's
+// indicators at its trader's size are fed the current candle as it comes in.
 type PriceActionStore struct {
 	mu                        sync.RWMutex
 	tokens                    []string
 	priceHistory              map[string][]models.Ticker
-	candleHistory             map[string]*models.CandleHistory
-	longCandleHistory         map[string]*models.CandleHistory
+	baseCandles               map[string][]models.Candle
+	seededCandles             map[string]map[time.Duration][]models.Candle
+	seededUntil               map[string]time.Time // base candles after this were built live, not seeded
+	lastInboundCandle         map[string]models.Candle
+	lastTickTime              map[string]time.Time // the exchange's time of the symbol's last tick
+	resampled                 map[string]map[time.Duration][]models.Candle // by size, every candle getLastCandles puts together
 	candleSize                map[string]enum.CandleSize
-	longCandleSize            map[string]enum.CandleSize
-	lastFiveMinuteCandleStart map[string]time.Time
-	storedCandleVolume        map[string]float64
-	volumeOfLastInboundCandle map[string]float64
+	historyDepth              map[string]int
+	indicators                map[string]*indicators.Set
 	inboundCandleSize         enum.CandleSize
 	renkoCandleHistory        map[string]models.RenkoCandleHistory
 	isRenkoCandleHistoryBuilt map[string]bool
@@ -43,13 +66,16 @@ func NewStore(inboundCandleSize enum.CandleSize) *PriceActionStore {
 	store := PriceActionStore{
 		tokens:                    []string{},
 		priceHistory:              make(map[string][]models.Ticker),
-		candleHistory:             make(map[string]*models.CandleHistory),
-		longCandleHistory:         make(map[string]*models.CandleHistory),
+		baseCandles:               make(map[string][]models.Candle),
+		seededCandles:             make(map[string]map[time.Duration][]models.Candle),
+		seededUntil:               make(map[string]time.Time),
+		lastInboundCandle:         make(map[string]models.Candle),
+		lastTickTime:              make(map[string]time.Time),
+		resampled:                 make(map[string]map[time.Duration][]models.Candle),
 		candleSize:                make(map[string]enum.CandleSize),
-		longCandleSize:            make(map[string]enum.CandleSize),
-		lastFiveMinuteCandleStart: make(map[string]time.Time),
-		storedCandleVolume:        make(map[string]float64),
-		volumeOfLastInboundCandle: make(map[string]float64),
+		historyDepth:              make(map[string]int),
+		indicators:                make(map[string]*indicators.Set),
+		inboundCandleSize:         inboundCandleSize,
 		renkoCandleHistory:        make(map[string]models.RenkoCandleHistory),
 		isRenkoCandleHistoryBuilt: make(map[string]bool),
 	}
@@ -57,10 +83,30 @@ func NewStore(inboundCandleSize enum.CandleSize) *PriceActionStore {
 	return &store
 }
 
+// GetSeedCandleSizes are the sizes an exchange fetches history at when a symbol is added: the base, the trader's
+// candle size and its long candle size, so both series are full from the start.
+func GetSeedCandleSizes(candleSize enum.CandleSize) []enum.CandleSize {
+	sizes := []enum.CandleSize{baseCandleSize}
+	if candleSize != baseCandleSize {
+		sizes = append(sizes, candleSize)
+	}
+	if candleSize < enum.CandleSize6h {
+		sizes = append(sizes, enum.GetLongCandleSizeFromCandleSize(candleSize))
+	}
+	return sizes
+}
+
+// GetCandleHistoryDepth is how many candles of each series the store keeps for a trader whose strategy needs
+// warmUp of them, and so how many an exchange backfills: never fewer than the 100 the strategies were written
+// against, nor more than maxHistoryDepth however long a warm-up asks for.
+func GetCandleHistoryDepth(warmUp int) int {
+	return min(max(warmUp, candleHistoryLength), maxHistoryDepth)
+}
+
 func (s *PriceActionStore) GetRenkoCandleHistory(symbol string) models.RenkoCandleHistory {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if !s.IsRenkoCandleHistoryBuilt(symbol) {
+	if !s.isRenkoCandleHistoryBuilt[symbol] {
 		return models.RenkoCandleHistory{RenkoCandles: []models.RenkoCandle{}}
 	}
 	return s.renkoCandleHistory[symbol]
@@ -78,37 +124,56 @@ func (s *PriceActionStore) UpdateInboundCandleSize(candleSize enum.CandleSize) {
 	s.inboundCandleSize = candleSize
 }
 
-func (s *PriceActionStore) UpdateCandleSize(symbol string, candleSize enum.CandleSize, candleHistory []models.Candle, longCandleHistory []models.Candle) {
+// UpdateCandleSize switches the size the symbol's trader works at, and how many candles its histories hold. The
+// base candles are kept, only the seeded histories are replaced, and the resampled series and the indicators start
+// over at the new size.
+func (s *PriceActionStore) UpdateCandleSize(symbol string, candleSize enum.CandleSize, historyDepth int, seeds map[enum.CandleSize][]models.Candle) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	delete(s.resampled, symbol)
 	s.candleSize[symbol] = candleSize
-	s.longCandleSize[symbol] = enum.GetLongCandleSizeFromCandleSize(candleSize)
-	s.candleHistory[symbol] = models.NewCandleHistory(candleHistory)
-	s.longCandleHistory[symbol] = models.NewCandleHistory(longCandleHistory)
-	s.lastFiveMinuteCandleStart[symbol] = time.Time{}
-	s.storedCandleVolume[symbol] = 0.0
-	s.volumeOfLastInboundCandle[symbol] = 0.0
+	s.historyDepth[symbol] = GetCandleHistoryDepth(historyDepth)
+	s.seededCandles[symbol] = make(map[time.Duration][]models.Candle)
+	for size, candles := range seeds {
+		if size == baseCandleSize {
+			s.seedBaseCandles(symbol, candles)
+			continue
+		}
+		s.seededCandles[symbol][enum.GetTimeDurationFromCandleSize(size)] = candles
+	}
+	s.indicators[symbol] = indicators.NewSet(s.getLastCandles(symbol, enum.GetTimeDurationFromCandleSize(candleSize), 0).Candles)
 }
 
-func (s *PriceActionStore) AddToken(symbol string, candleSize enum.CandleSize, candleHistory []models.Candle, longCandleHistory []models.Candle) {
+func (s *PriceActionStore) AddToken(symbol string, candleSize enum.CandleSize, historyDepth int, seeds map[enum.CandleSize][]models.Candle) {
 	s.mu.Lock()
 	s.tokens = append(s.tokens, symbol)
 	s.priceHistory[symbol] = make([]models.Ticker, 0)
+	s.baseCandles[symbol] = make([]models.Candle, 0)
 	s.mu.Unlock()
-	s.UpdateCandleSize(symbol, candleSize, candleHistory, longCandleHistory)
+	s.UpdateCandleSize(symbol, candleSize, historyDepth, seeds)
+}
+
+// HasHistory reports whether the symbol is already kept at candleSize with at least historyDepth candles of history.
+func (s *PriceActionStore) HasHistory(symbol string, candleSize enum.CandleSize, historyDepth int) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	size, ok := s.candleSize[symbol]
+	return ok && size == candleSize && s.historyDepth[symbol] >= GetCandleHistoryDepth(historyDepth)
 }
 
 func (s *PriceActionStore) RemoveToken(symbol string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	delete(s.priceHistory, symbol)
-	delete(s.candleHistory, symbol)
-	delete(s.longCandleHistory, symbol)
+	delete(s.baseCandles, symbol)
+	delete(s.seededCandles, symbol)
+	delete(s.seededUntil, symbol)
+	delete(s.lastInboundCandle, symbol)
+	delete(s.lastTickTime, symbol)
+	delete(s.resampled, symbol)
 	delete(s.candleSize, symbol)
-	delete(s.longCandleSize, symbol)
-	delete(s.storedCandleVolume, symbol)
-	delete(s.volumeOfLastInboundCandle, symbol)
-	delete(s.lastFiveMinuteCandleStart, symbol)
+	delete(s.historyDepth, symbol)
+	delete(s.indicators, symbol)
 	idx := 0
 	for i, t := range s.tokens {
 		if t == symbol {
@@ -119,32 +184,167 @@ func (s *PriceActionStore) RemoveToken(symbol string) {
 	s.tokens = append(s.tokens[:idx], s.tokens[idx+1:]...)
 }
 
-func (s *PriceActionStore) IngestCandleOfInboundCandleSize(candle models.Candle) models.Candle {
+// seedBaseCandles puts fetched 1m history under the base candles built so far. Called with s.mu held.
+func (s *PriceActionStore) seedBaseCandles(symbol string, candles []models.Candle) {
+	if len(candles) == 0 {
+		return
+	}
+	base := s.baseCandles[symbol]
+	last := candles[len(candles)-1].Start
+	merged := make([]models.Candle, 0, len(candles)+len(base))
+	merged = append(merged, candles...)
+	for _, c := range base {
+		if c.Start.After(last) {
+			merged = append(merged, c)
+		}
+	}
+	if len(merged) > maxBaseCandles {
+		merged = merged[len(merged)-maxBaseCandles:]
+	}
+	s.baseCandles[symbol] = merged
+	if last.After(s.seededUntil[symbol]) {
+		s.seededUntil[symbol] = last
+	}
+}
+
+// IngestCandleOfInboundCandleSize folds a running candle from the exchange feed into the base candle of the minute
+// the exchange sent it at, and returns the symbol's current candle at its trader's size. A zero at is taken as the
+// time of the symbol's last tick, for feeds whose candle updates carry no time of their own. The inbound candle's
+// volume is cumulative within its own bucket, so only what it added since the last update is counted, and a new
+// high or low can only have been made since then. The symbol's indicators take the current candle too.
+func (s *PriceActionStore) IngestCandleOfInboundCandleSize(candle models.Candle, at time.Time) models.Candle {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	symbol := candle.ProductID
-	s.ingestPrice(symbol, candle.Close, candle.Start)
-	s.updateCandleHistory(symbol, candle, s.candleSize[symbol])
-	s.updateCandleHistory(symbol, candle, s.longCandleSize[symbol])
-	if s.lastFiveMinuteCandleStart[symbol].IsZero() || s.lastFiveMinuteCandleStart[symbol].Before(candle.Start) {
-		s.lastFiveMinuteCandleStart[symbol] = candle.Start
+
+	last, seen := s.lastInboundCandle[symbol]
+	volume := candle.Volume
+	high, low := candle.Close, candle.Close
+	if seen && last.Start.Equal(candle.Start) {
+		volume = max(candle.Volume-last.Volume, 0)
+		if candle.High > last.High {
+			high = candle.High
+		}
+		if candle.Low < last.Low {
+			low = candle.Low
+		}
+	} else {
+		high, low = candle.High, candle.Low
+	}
+	s.lastInboundCandle[symbol] = candle
+
+	if at.IsZero() {
+		at = s.lastTickTime[symbol]
+	}
+	minute := at.Truncate(time.Minute)
+	inboundEnd := candle.Start.Add(enum.GetTimeDurationFromCandleSize(s.inboundCandleSize))
+	if minute.Before(candle.Start) {
+		minute = candle.Start
+	} else if !minute.Before(inboundEnd) {
+		minute = inboundEnd.Add(-time.Minute) // a late update still belongs to its own bucket
+	}
+	s.updateBaseCandle(symbol, minute, candle.Close, high, low, volume)
+
+	current := s.getCurrentCandle(symbol, enum.GetTimeDurationFromCandleSize(s.candleSize[symbol]))
+	if set, ok := s.indicators[symbol]; ok {
+		set.Ingest(current)
+	}
+	return current
+}
+
+// updateBaseCandle moves the base candle of minute, or starts it, and every resampled series along with it.
+func (s *PriceActionStore) updateBaseCandle(symbol string, minute time.Time, price float64, high float64, low float64, volume float64) {
+	base := s.baseCandles[symbol]
+	if n := len(base); n > 0 && !base[n-1].Start.Before(minute) {
+		c := &base[n-1]
+		c.Close = price
+		c.High = max(c.High, high)
+		c.Low = min(c.Low, low)
+		c.Volume += volume
+		s.updateResampled(symbol, *c, high, low, volume)
+		return
+	}
+	open := price
+	if n := len(base); n > 0 {
+		open = base[n-1].Close
+	}
+	c := models.Candle{
+		Start:     minute,
+		Open:      open,
+		High:      max(open, high),
+		Low:       min(open, low),
+		Close:     price,
+		Volume:    volume,
+		ProductID: symbol,
+	}
+	base = append(base, c)
+	if len(base) > maxBaseCandles {
+		base = base[len(base)-maxBaseCandles:]
+	}
+	s.baseCandles[symbol] = base
+	s.updateResampled(symbol, c, c.High, c.Low, volume)
+}
+
+// updateResampled folds a base update into each resampled series the way ResampleCandles would: c is the base
+// candle after it, high, low and volume what it added.
+func (s *PriceActionStore) updateResampled(symbol string, c models.Candle, high float64, low float64, volume float64) {
+	for size, series := range s.resampled[symbol] {
+		bucketStart := c.Start.Truncate(size)
+		if n := len(series); n > 0 && !series[n-1].Start.Before(bucketStart) {
+			last := &series[n-1]
+			last.High = max(last.High, high)
+			last.Low = min(last.Low, low)
+			last.Close = c.Close
+			last.Volume += volume
+			continue
+		}
+		series = append(series, models.Candle{
+			Start:     bucketStart,
+			Open:      c.Open,
+			High:      c.High,
+			Low:       c.Low,
+			Close:     c.Close,
+			Volume:    c.Volume,
+			ProductID: symbol,
+		})
+		if len(series) > maxBaseCandles {
+			series = series[len(series)-maxBaseCandles:]
+		}
+		s.resampled[symbol][size] = series
 	}
-	return s.candleHistory[symbol].Candles[len(s.candleHistory[symbol].Candles)-1]
 }
 
-func (s *PriceActionStore) ingestPrice(symbol string, price float64, time time.Time) {
-	s.priceHistory[symbol] = append(s.priceHistory[symbol], models.Ticker{
-		Symbol: symbol,
-		Price:  price,
-		Time:   time,
-	})
+func (s *PriceActionStore) getCurrentCandle(symbol string, size time.Duration) models.Candle {
+	series := s.getResampled(symbol, size)
+	if len(series) == 0 {
+		return models.Candle{ProductID: symbol}
+	}
+	return series[len(series)-1]
+}
+
+// IngestTicker adds a tick off the exchange's ticker feed to the symbol's price history and renko bricks. Ticks
+// for a symbol the store isn't tracking are dropped.
+func (s *PriceActionStore) IngestTicker(ticker models.Ticker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.priceHistory[ticker.Symbol]; !ok {
+		return
+	}
+	s.lastTickTime[ticker.Symbol] = ticker.Time
+	s.ingestPrice(ticker)
+}
+
+func (s *PriceActionStore) ingestPrice(ticker models.Ticker) {
+	symbol, price := ticker.Symbol, ticker.Price
+	s.priceHistory[symbol] = append(s.priceHistory[symbol], ticker)
 
 	length := len(s.priceHistory[symbol])
-	if length > 1200 {
-		s.priceHistory[symbol] = s.priceHistory[symbol][length-1200:]
+	if length > maxPriceHistory {
+		s.priceHistory[symbol] = s.priceHistory[symbol][length-maxPriceHistory:]
 	}
 
-	if (s.IsRenkoCandleHistoryBuilt(symbol)){
+	// called with s.mu held, so read the map directly rather than through IsRenkoCandleHistoryBuilt
+	if (s.isRenkoCandleHistoryBuilt[symbol]){
 		renkoCandleHistory := s.renkoCandleHistory[symbol]
         brickSize := renkoCandleHistory.BrickSize
         lastClose := renkoCandleHistory.LastCandlePrice
@@ -152,89 +352,186 @@ func (s *PriceActionStore) ingestPrice(symbol string, price float64, time time.T
             newRenkoCandles, newLastClose := getNewRenkoCandles(price, lastClose, brickSize)
             renkoCandleHistory.RenkoCandles = append(renkoCandleHistory.RenkoCandles, newRenkoCandles...)
             renkoCandleHistory.LastCandlePrice = newLastClose
+            s.renkoCandleHistory[symbol] = renkoCandleHistory
         }
     }
 }
 
+// GetIndicators is the symbol's indicators at its trader's candle size, an empty set for a symbol the store isn't
+// tracking.
+func (s *PriceActionStore) GetIndicators(symbol string) *indicators.Set {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if set, ok := s.indicators[symbol]; ok {
+		return set
+	}
+	return indicators.NewSet(nil)
+}
+
+// GetPriceHistory is a copy of the symbol's ticks, oldest first.
 func (s *PriceActionStore) GetPriceHistory(symbol string) []models.Ticker {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if _, ok := s.priceHistory[symbol]; !ok {
 		return []models.Ticker{}
 	}
-	return s.priceHistory[symbol]
+	return append([]models.Ticker(nil), s.priceHistory[symbol]...)
 }
 
-func (s *PriceActionStore) GetCandleHistory(symbol string) models.CandleHistory {
+// GetPriceHistoryPage is a copy of at most limit of the symbol's ticks from before before, oldest first. A zero
+// before pages back from the newest tick.
+func (s *PriceActionStore) GetPriceHistoryPage(symbol string, before time.Time, limit int) []models.Ticker {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if _, ok := s.candleHistory[symbol]; !ok {
-		return models.CandleHistory{Candles: []models.Candle{}}
+	prices := s.priceHistory[symbol]
+	end := len(prices)
+	if !before.IsZero() {
+		end = sort.Search(len(prices), func(i int) bool { return !prices[i].Time.Before(before) })
 	}
-	orig := s.candleHistory[symbol]
+	start := max(0, end-max(limit, 0))
+	return append([]models.Ticker{}, prices[start:end]...)
+}
 
-	merged := make([]models.Candle, 0, len(orig.Candles))
-	merged = append(merged, orig.Candles...)
+// GetLastPrice is the symbol's last tick, or the close of its last candle at the trader size before the first tick.
+func (s *PriceActionStore) GetLastPrice(symbol string) (float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if prices := s.priceHistory[symbol]; len(prices) > 0 {
+		return prices[len(prices)-1].Price, true
+	}
+	candleSize, ok := s.candleSize[symbol]
+	if !ok {
+		return 0, false
+	}
+	if candles := s.getResampled(symbol, enum.GetTimeDurationFromCandleSize(candleSize)); len(candles) > 0 {
+		return candles[len(candles)-1].Close, true
+	}
+	return 0, false
+}
 
-	return models.CandleHistory{Candles: merged}
+// GetCandleHistory is the last candles at the symbol's trader size, as many as its history depth.
+func (s *PriceActionStore) GetCandleHistory(symbol string) models.CandleHistory {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.candleSize[symbol]; !ok {
+		return models.CandleHistory{Candles: []models.Candle{}}
+	}
+	return s.getLastCandles(symbol, enum.GetTimeDurationFromCandleSize(s.candleSize[symbol]), s.historyDepth[symbol])
 }
 
+// GetLongCandleHistory is the last candles, as many as the history depth, at the long candle size of the symbol's trader size, or at the
+// trader size itself past 4h, which has no long size.
 func (s *PriceActionStore) GetLongCandleHistory(symbol string) models.CandleHistory {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if _, ok := s.longCandleHistory[symbol]; !ok {
+	candleSize, ok := s.candleSize[symbol]
+	if !ok {
 		return models.CandleHistory{Candles: []models.Candle{}}
 	}
-	orig := s.longCandleHistory[symbol]
+	if candleSize < enum.CandleSize6h {
+		candleSize = enum.GetLongCandleSizeFromCandleSize(candleSize)
+	}
+	return s.getLastCandles(symbol, enum.GetTimeDurationFromCandleSize(candleSize), s.historyDepth[symbol])
+}
 
-	merged := make([]models.Candle, 0, len(orig.Candles))
-	merged = append(merged, orig.Candles...)
+// GetResampledCandleHistory is every candle the store can put together at any size that is a whole number of
+// minutes, one of the enum.CandleSize durations or a custom one, e.g. 3h.
+func (s *PriceActionStore) GetResampledCandleHistory(symbol string, size time.Duration) models.CandleHistory {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.candleSize[symbol]; !ok || size < time.Minute {
+		return models.CandleHistory{Candles: []models.Candle{}}
+	}
+	return s.getLastCandles(symbol, size.Truncate(time.Minute), 0)
+}
 
-	return models.CandleHistory{Candles: merged}
+// getLastCandles is a copy of the last count candles of the symbol's series at size. A count of 0 keeps them all.
+func (s *PriceActionStore) getLastCandles(symbol string, size time.Duration, count int) models.CandleHistory {
+	series := s.getResampled(symbol, size)
+	if count > 0 && len(series) > count {
+		series = series[len(series)-count:]
+	}
+	return models.CandleHistory{Candles: append(make([]models.Candle, 0, len(series)), series...)}
 }
 
-func (s *PriceActionStore) updateCandleHistory(symbol string, candle models.Candle, candleSize enum.CandleSize) {
-	candleHistory := s.candleHistory[symbol]
-	timeLastCandle := candleHistory.Candles[len(candleHistory.Candles)-1].Start
-	volumeOfCurrentCandle := s.getCurrentCandleVolume(symbol, candle, candleSize)
-	if time.Since(timeLastCandle) > enum.GetTimeDurationFromCandleSize(candleSize) {
-		candleHistory.Candles = append(candleHistory.Candles, models.NewCandle(symbol, timeLastCandle, candleSize, candle.Close, volumeOfCurrentCandle))
-	} else {
-		candleHistory.Candles[len(candleHistory.Candles)-1].UpdateCandle(candle.Close, volumeOfCurrentCandle)
+// getResampled is the symbol's series at size, resampled the first time it is asked for and kept current by
+// updateResampled after that. Callers hold s.mu and copy what they hand out.
+func (s *PriceActionStore) getResampled(symbol string, size time.Duration) []models.Candle {
+	if series, ok := s.resampled[symbol][size]; ok {
+		return series
 	}
-	if len(candleHistory.Candles) > 100 {
-		candleHistory.Candles = candleHistory.Candles[1:]
+	series := s.resample(symbol, size)
+	if _, ok := s.candleSize[symbol]; ok {
+		if s.resampled[symbol] == nil {
+			s.resampled[symbol] = make(map[time.Duration][]models.Candle)
+		}
+		s.resampled[symbol][size] = series
 	}
+	return series
 }
 
-func (s *PriceActionStore) getCurrentCandleVolume(symbol string, candle models.Candle, candleSize enum.CandleSize) float64 {
-	volumeToSubstract := 0.0
-	length := len(s.candleHistory[symbol].Candles)
-	if candleSize == enum.CandleSize5m {
-		return candle.Volume
-	} else if enum.GetTimeDurationFromCandleSize(candleSize) < enum.GetTimeDurationFromCandleSize(enum.CandleSize5m) {
-		numCandles := int(time.Since(s.lastFiveMinuteCandleStart[symbol]) / enum.GetTimeDurationFromCandleSize(candleSize))
-		s.storedCandleVolume[symbol] = 0.0
-
-		for i := int(0); i < numCandles; i++ {
-			volumeToSubstract += s.candleHistory[symbol].Candles[length-1-i].Volume
+// resample puts together the base and, before the base reaches back, the coarsest seeded history that divides size.
+func (s *PriceActionStore) resample(symbol string, size time.Duration) []models.Candle {
+	live := s.mergeSeededCandle(symbol, size, models.ResampleCandles(s.baseCandles[symbol], size))
+	seeded := s.getSeededCandles(symbol, size)
+	candles := make([]models.Candle, 0, len(seeded)+len(live))
+	for _, c := range seeded {
+		if len(live) == 0 || c.Start.Before(live[0].Start) {
+			candles = append(candles, c)
 		}
+	}
+	candles = append(candles, live...)
+	if len(candles) > maxBaseCandles {
+		candles = candles[len(candles)-maxBaseCandles:]
+	}
+	return candles
+}
 
-		return candle.Volume - volumeToSubstract
-	} else {
-		timeLastCandle := s.candleHistory[symbol].Candles[len(s.candleHistory[symbol].Candles)-1].Start
-
-		if time.Since(timeLastCandle) > enum.GetTimeDurationFromCandleSize(candleSize) {
-			s.storedCandleVolume[symbol] = 0.0
-		} else if s.volumeOfLastInboundCandle[symbol] > candle.Volume {
-			s.storedCandleVolume[symbol] += s.volumeOfLastInboundCandle[symbol]
-			s.volumeOfLastInboundCandle[symbol] = candle.Volume
+func (s *PriceActionStore) getSeededCandles(symbol string, size time.Duration) []models.Candle {
+	var seeded []models.Candle
+	seededSize := time.Duration(0)
+	for d, candles := range s.seededCandles[symbol] {
+		if d <= size && size%d == 0 && d > seededSize && len(candles) > 0 {
+			seeded, seededSize = candles, d
 		}
-
-		return s.storedCandleVolume[symbol] + candle.Volume
 	}
+	if seededSize == 0 {
+		return nil
+	}
+	return models.ResampleCandles(seeded, size)
 }
 
+// mergeSeededCandle completes the first live candle when the base starts partway into its bucket: the seeded
+// candle of that bucket has its open and the move before the base began, the base has everything since.
+func (s *PriceActionStore) mergeSeededCandle(symbol string, size time.Duration, live []models.Candle) []models.Candle {
+	base := s.baseCandles[symbol]
+	if len(live) == 0 || len(base) == 0 || !base[0].Start.After(live[0].Start) {
+		return live
+	}
+	first := live[0]
+	for _, c := range s.getSeededCandles(symbol, size) {
+		if !c.Start.Equal(first.Start) {
+			continue
+		}
+		merged := c
+		merged.High = max(c.High, first.High)
+		merged.Low = min(c.Low, first.Low)
+		merged.Close = first.Close
+		for _, b := range base {
+			if !b.Start.Before(first.Start.Add(size)) {
+				break
+			}
+			if b.Start.After(s.seededUntil[symbol]) {
+				merged.Volume += b.Volume // the seeded candle already counts what was traded before it was fetched
+			}
+		}
+		out := make([]models.Candle, len(live))
+		copy(out, live)
+		out[0] = merged
+		return out
+	}
+	return live
+}
 
 func (p *PriceActionStore) BuildRenkoCandleHistory(symbol string, brickSize float64) {
 	priceHistory := p.GetPriceHistory(symbol)
@@ -247,11 +544,29 @@ func (p *PriceActionStore) BuildRenkoCandleHistory(symbol string, brickSize floa
 		return
 	}
 
+	prices := make([]float64, len(priceHistory))
+	for i, t := range priceHistory {
+		prices[i] = t.Price
+	}
+
+	p.renkoCandleHistory[symbol] = GetRenkoCandleHistoryFromPrices(prices, brickSize)
+	p.isRenkoCandleHistoryBuilt[symbol] = true
+}
+
+// GetRenkoCandleHistoryFromPrices builds renko bricks from a raw price series, starting from the first price.
+func GetRenkoCandleHistoryFromPrices(prices []float64, brickSize float64) models.RenkoCandleHistory {
+	if len(prices) == 0 {
+		return models.RenkoCandleHistory{
+			RenkoCandles: make([]models.RenkoCandle, 0),
+			LastCandlePrice: 0,
+			BrickSize: brickSize,
+		}
+	}
+
 	var renkoCandles []models.RenkoCandle
-	lastClose := priceHistory[0].Price
+	lastClose := prices[0]
 
-	for _, p := range priceHistory {
-		price := p.Price
+	for _, price := range prices {
 		if (math.Abs(price-lastClose) >= brickSize){
 			newRenkoCandles, newLastClose := getNewRenkoCandles(price, lastClose, brickSize)
 			renkoCandles = append(renkoCandles, newRenkoCandles...)
@@ -259,12 +574,11 @@ func (p *PriceActionStore) BuildRenkoCandleHistory(symbol string, brickSize floa
 		}
 	}
 
-	p.renkoCandleHistory[symbol] = models.RenkoCandleHistory{
+	return models.RenkoCandleHistory{
 		RenkoCandles: renkoCandles,
 		LastCandlePrice: lastClose,
 		BrickSize: brickSize,
 	}
-	p.isRenkoCandleHistoryBuilt[symbol] = true
 }
 
 func getNewRenkoCandles(price float64, lastClose float64, brickSize float64) ([]models.RenkoCandle, float64) {
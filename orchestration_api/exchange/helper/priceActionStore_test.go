@@ -0,0 +1,216 @@
+package helper
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+var start = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// feed sends minutes of inbound 5m candles, one update every 20 seconds of exchange time, walking the price
+// around 100.
+func feed(s *PriceActionStore, minutes int) {
+	var inbound models.Candle
+	for i := 0; i < minutes*3; i++ {
+		at := start.Add(time.Duration(i) * 20 * time.Second)
+		price := 100 + 5*math.Sin(float64(i)/7) + float64(i%5)
+		bucket := at.Truncate(5 * time.Minute)
+		if !inbound.Start.Equal(bucket) {
+			inbound = models.Candle{Start: bucket, Open: price, High: price, Low: price, ProductID: "ETH-USD"}
+		}
+		inbound.Close = price
+		inbound.High = max(inbound.High, price)
+		inbound.Low = min(inbound.Low, price)
+		inbound.Volume += 1.5
+		s.IngestCandleOfInboundCandleSize(inbound, at)
+	}
+}
+
+func TestResampledHistoryKeptCurrent(t *testing.T) {
+	seeds := map[enum.CandleSize][]models.Candle{
+		enum.CandleSize5m: {
+			{Start: start.Add(-10 * time.Minute), Open: 90, High: 95, Low: 89, Close: 94, Volume: 10, ProductID: "ETH-USD"},
+			{Start: start.Add(-5 * time.Minute), Open: 94, High: 99, Low: 93, Close: 98, Volume: 10, ProductID: "ETH-USD"},
+		},
+	}
+	sizes := []time.Duration{time.Minute, 3 * time.Minute, 5 * time.Minute, 30 * time.Minute, time.Hour}
+	tests := []struct {
+		name    string
+		minutes int
+		before  bool // ask for every size before the feed starts, so they are all kept current from the seeds on
+	}{
+		{name: "asked for before the feed", minutes: 200, before: true},
+		{name: "asked for after the feed", minutes: 200},
+		{name: "partway into a bucket", minutes: 47, before: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStore(enum.CandleSize5m)
+			s.AddToken("ETH-USD", enum.CandleSize5m, 100, seeds)
+			if tt.before {
+				for _, size := range sizes {
+					s.GetResampledCandleHistory("ETH-USD", size)
+				}
+			}
+			feed(s, tt.minutes)
+
+			for _, size := range sizes {
+				got := s.GetResampledCandleHistory("ETH-USD", size).Candles
+				s.mu.Lock()
+				want := s.resample("ETH-USD", size)
+				s.mu.Unlock()
+				if len(got) != len(want) {
+					t.Fatalf("%v: %d candles, want %d", size, len(got), len(want))
+				}
+				for i := range want {
+					if !candlesEqual(got[i], want[i]) {
+						t.Errorf("%v candle %d: got %+v, want %+v", size, i, got[i], want[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestIngestPlacesUpdatesByExchangeTime(t *testing.T) {
+	tests := []struct {
+		name      string
+		at        time.Time
+		tick      time.Time // the last tick's time, for an update without one
+		wantStart time.Time
+	}{
+		{name: "exchange time", at: start.Add(2*time.Minute + 30*time.Second), wantStart: start.Add(2 * time.Minute)},
+		{name: "before the bucket", at: start.Add(-time.Minute), wantStart: start},
+		{name: "late update stays in its bucket", at: start.Add(7 * time.Minute), wantStart: start.Add(4 * time.Minute)},
+		{name: "no time, last tick's", tick: start.Add(3*time.Minute + 10*time.Second), wantStart: start.Add(3 * time.Minute)},
+		{name: "no time and no tick", wantStart: start},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStore(enum.CandleSize5m)
+			s.AddToken("ETH-USD", enum.CandleSize1m, 100, nil)
+			if !tt.tick.IsZero() {
+				s.IngestTicker(models.Ticker{Symbol: "ETH-USD", Price: 100, Time: tt.tick})
+			}
+			got := s.IngestCandleOfInboundCandleSize(models.Candle{Start: start, Open: 100, High: 101, Low: 99, Close: 100, Volume: 1, ProductID: "ETH-USD"}, tt.at)
+			if !got.Start.Equal(tt.wantStart) {
+				t.Errorf("base candle at %v, want %v", got.Start, tt.wantStart)
+			}
+		})
+	}
+}
+
+func TestHasHistory(t *testing.T) {
+	tests := []struct {
+		name       string
+		symbol     string
+		candleSize enum.CandleSize
+		depth      int
+		want       bool
+	}{
+		{name: "same size and depth", symbol: "ETH-USD", candleSize: enum.CandleSize5m, depth: 300, want: true},
+		{name: "shallower", symbol: "ETH-USD", candleSize: enum.CandleSize5m, depth: 20, want: true},
+		{name: "deeper", symbol: "ETH-USD", candleSize: enum.CandleSize5m, depth: 500},
+		{name: "other size", symbol: "ETH-USD", candleSize: enum.CandleSize1h, depth: 300},
+		{name: "untracked symbol", symbol: "BTC-USD", candleSize: enum.CandleSize5m, depth: 20},
+	}
+	s := NewStore(enum.CandleSize5m)
+	s.AddToken("ETH-USD", enum.CandleSize5m, 300, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.HasHistory(tt.symbol, tt.candleSize, tt.depth); got != tt.want {
+				t.Errorf("HasHistory(%q, %v, %d) = %v, want %v", tt.symbol, tt.candleSize, tt.depth, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetCandleHistoryDepth(t *testing.T) {
+	tests := []struct {
+		warmUp int
+		want   int
+	}{
+		{warmUp: 0, want: candleHistoryLength},
+		{warmUp: 300, want: 300},
+		{warmUp: maxHistoryDepth + 1, want: maxHistoryDepth},
+		{warmUp: 1 << 30, want: maxHistoryDepth},
+	}
+	for _, tt := range tests {
+		if got := GetCandleHistoryDepth(tt.warmUp); got != tt.want {
+			t.Errorf("GetCandleHistoryDepth(%d) = %d, want %d", tt.warmUp, got, tt.want)
+		}
+	}
+}
+
+func TestGetPriceHistoryPage(t *testing.T) {
+	s := NewStore(enum.CandleSize5m)
+	s.AddToken("ETH-USD", enum.CandleSize5m, 100, nil)
+	for i := 0; i < 10; i++ {
+		s.IngestTicker(models.Ticker{Symbol: "ETH-USD", Price: float64(100 + i), Time: start.Add(time.Duration(i) * time.Second)})
+	}
+	tests := []struct {
+		name   string
+		before time.Time
+		limit  int
+		want   []float64
+	}{
+		{name: "newest page", limit: 3, want: []float64{107, 108, 109}},
+		{name: "page before a tick", before: start.Add(4 * time.Second), limit: 3, want: []float64{101, 102, 103}},
+		{name: "runs out at the oldest", before: start.Add(2 * time.Second), limit: 5, want: []float64{100, 101}},
+		{name: "before the first tick", before: start, limit: 5, want: []float64{}},
+		{name: "more than there is", limit: 50, want: []float64{100, 101, 102, 103, 104, 105, 106, 107, 108, 109}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := s.GetPriceHistoryPage("ETH-USD", tt.before, tt.limit)
+			got := make([]float64, len(page))
+			for i, p := range page {
+				got[i] = p.Price
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("prices %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetLastPrice(t *testing.T) {
+	seeds := map[enum.CandleSize][]models.Candle{
+		enum.CandleSize5m: {{Start: start.Add(-5 * time.Minute), Open: 90, High: 95, Low: 89, Close: 94, Volume: 10, ProductID: "ETH-USD"}},
+	}
+	tests := []struct {
+		name   string
+		seeds  map[enum.CandleSize][]models.Candle
+		ticks  []float64
+		want   float64
+		wantOk bool
+	}{
+		{name: "last tick", seeds: seeds, ticks: []float64{100, 101}, want: 101, wantOk: true},
+		{name: "last close before any tick", seeds: seeds, want: 94, wantOk: true},
+		{name: "nothing yet"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStore(enum.CandleSize5m)
+			s.AddToken("ETH-USD", enum.CandleSize5m, 100, tt.seeds)
+			for i, price := range tt.ticks {
+				s.IngestTicker(models.Ticker{Symbol: "ETH-USD", Price: price, Time: start.Add(time.Duration(i) * time.Second)})
+			}
+			got, ok := s.GetLastPrice("ETH-USD")
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("GetLastPrice = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func candlesEqual(a models.Candle, b models.Candle) bool {
+	near := func(x, y float64) bool { return math.Abs(x-y) < 1e-9 }
+	return a.Start.Equal(b.Start) && near(a.Open, b.Open) && near(a.High, b.High) && near(a.Low, b.Low) &&
+		near(a.Close, b.Close) && near(a.Volume, b.Volume)
+}
@@ -0,0 +1,73 @@
+package helper
+
+import (
+	"sync"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/channel_helper"
+)
+
+// SubscriptionHub fans values out to every subscriber of a symbol, the same way CoinbaseExchange does for its
+// candle/ticker/order channels. Slow subscribers get the latest value rather than blocking the publisher.
+type SubscriptionHub[T any] struct {
+	mu         sync.Mutex
+	channels   map[string][]chan T
+	bufferSize int
+}
+
+func NewSubscriptionHub[T any](bufferSize int) *SubscriptionHub[T] {
+	return &SubscriptionHub[T]{
+		channels:   make(map[string][]chan T),
+		bufferSize: bufferSize,
+	}
+}
+
+// Subscribe returns a channel for symbol and a cleanup func that is safe to call after CloseSymbol.
+func (h *SubscriptionHub[T]) Subscribe(symbol string) (<-chan T, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan T, h.bufferSize)
+	h.channels[symbol] = append(h.channels[symbol], ch)
+
+	cleanup := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		channels := h.channels[symbol]
+		for i, c := range channels {
+			if c == ch {
+				close(ch)
+				h.channels[symbol] = append(channels[:i], channels[i+1:]...)
+				break
+			}
+		}
+	}
+
+	return ch, cleanup
+}
+
+func (h *SubscriptionHub[T]) Publish(symbol string, v T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, ch := range h.channels[symbol] {
+		channel_helper.WriteToChannelAndBufferLatest(ch, v)
+	}
+}
+
+// CloseSymbol closes and forgets every subscriber of symbol.
+func (h *SubscriptionHub[T]) CloseSymbol(symbol string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, ch := range h.channels[symbol] {
+		close(ch)
+	}
+	delete(h.channels, symbol)
+}
+
+func (h *SubscriptionHub[T]) HasSubscribers(symbol string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.channels[symbol]) > 0
+}
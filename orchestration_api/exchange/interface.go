@@ -2,8 +2,10 @@ package exchange
 
 import (
 	"context"
+	"time"
 
 	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/indicators"
 	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
 	cb_models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models/coinbase"
 )
@@ -12,19 +14,36 @@ type IExchange interface {
 	SubscribeToOrderUpdates(symbol string) (<-chan models.OrderUpdate, func())
 	SubscribeToTicker(symbol string) (<-chan models.Ticker, func())
 	SubscribeToCandle(symbol string) (<-chan models.Candle, func())
+	// SubscribeToOrderBook gets the top of the symbol's level 2 book after every update, for spread and depth.
+	SubscribeToOrderBook(symbol string) (<-chan models.OrderBook, func())
+	SubscribeToTrades(symbol string) (<-chan models.Trade, func())
+	// GetOrderBook is the symbol's book now, false while the exchange has none or is resyncing it.
+	GetOrderBook(symbol string) (models.OrderBook, bool)
 
 	GetCandleHistory(symbol string) models.CandleHistory
 	GetLongCandleHistory(symbol string) models.CandleHistory
+	// GetResampledCandleHistory is the symbol's candles at any size, an enum.CandleSize duration or a custom one.
+	GetResampledCandleHistory(symbol string, size time.Duration) models.CandleHistory
 	GetPriceHistory(symbol string) []models.Ticker
+	// GetPriceHistoryPage is at most limit of the symbol's ticks from before before, oldest first. A zero before
+	// pages back from the newest tick.
+	GetPriceHistoryPage(symbol string, before time.Time, limit int) []models.Ticker
+	// GetLastPrice is the symbol's last tick, or the close of its last candle before the first tick, false while it
+	// has neither.
+	GetLastPrice(symbol string) (float64, bool)
 	GetRenkoCandleHistory(symbol string) models.RenkoCandleHistory
+	// GetIndicators is the symbol's streaming indicators at its trader's candle size, kept current as candles come in.
+	GetIndicators(symbol string) *indicators.Set
 
 	IsRenkoCandleHistoryBuilt(symbol string) bool
 	BuildRenkoCandleHistory(symbol string, brickSize float64)
 
 	UpdateInboundCandleSize(candleSize enum.CandleSize)
-	StartNewTokenDataStream(symbol string, candleSize enum.CandleSize) error
+	// historyDepth is how many candles the symbol's strategy needs to warm up; the exchange backfills at least that
+	// many and keeps its candle histories that long.
+	StartNewTokenDataStream(symbol string, candleSize enum.CandleSize, historyDepth int) error
 	StopTokenDataStream(symbol string) error
-	UpdateCandleSizeForSymbol(symbol string, candleSize enum.CandleSize) error
+	UpdateCandleSizeForSymbol(symbol string, candleSize enum.CandleSize, historyDepth int) error
 	
 	StartOrderAndPositionValuationWebSocket(ctx context.Context, wsURL string)
 	StartCoinbaseFeed(ctx context.Context, cbAdvUrl string)
@@ -34,8 +53,12 @@ type IExchange interface {
 	ListAccounts(ctx context.Context) (cb_models.AccountsListResponse, error)
 	GetAllTokenBalances(ctx context.Context) (map[string]float64, error)
 	ListOrders(ctx context.Context, productID string, limit int) (cb_models.ListOrdersResponse, error)
-	CreateOrder(ctx context.Context, productID string, amountOfUSD float64, isBuy bool) (cb_models.CreateOrderResponse, error)
+	CreateOrder(ctx context.Context, productID string, amountOfUSD float64, isBuy bool, spec models.OrderSpec) (cb_models.CreateOrderResponse, error)
 	SellTokens(ctx context.Context, productID string, amountOfUSD float64) (cb_models.CreateOrderResponse, error)
 	EditOrder(ctx context.Context, body []byte) (cb_models.EditOrderResponse, error)
 	CancelOrders(ctx context.Context, orderID string) error
+
+	// Derivatives, for traders allowed to go short or use leverage. Spot-only venues return an error.
+	GetPerpPosition(ctx context.Context, productID string) (models.PerpPosition, error)
+	GetFundingRate(ctx context.Context, productID string) (models.FundingRate, error)
 }
@@ -0,0 +1,65 @@
+package paper
+
+import (
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+// Config controls how the simulated book fills orders.
+type Config struct {
+	StartingBalances   map[string]float64          // by currency, e.g. {"USD": 50000}
+	FeeRate            float64                     // taker fee as a fraction of notional
+	MakerFeeRate       float64                     // fee for resting limit orders that get filled
+	SlippageBps        float64                     // fixed slippage applied to every fill
+	ImpactBpsPer10kUSD float64                     // extra slippage per $10k of notional, a crude stand-in for book depth
+	AllowShort         func(productID string) bool // asked on each sell whether it may take the base balance negative rather than be capped, nil never
+	FundingRate8h      float64                     // what GetFundingRate reports, so a short's funding can be rehearsed
+}
+
+func DefaultConfig(startingUSD float64) Config {
+	return Config{
+		StartingBalances:   map[string]float64{"USD": startingUSD},
+		FeeRate:            0.006,
+		MakerFeeRate:       0.004,
+		SlippageBps:        5,
+		ImpactBpsPer10kUSD: 2,
+	}
+}
+
+type paperOrder struct {
+	OrderID       string
+	ClientOrderID string
+	ProductID     string
+	Side          string
+	Status        string
+	CreatedAt     time.Time
+	CompletedAt   time.Time
+	FilledQty     float64
+	FilledValue   float64
+	AvgPrice      float64
+	Fees          float64
+
+	// resting orders only
+	Type       enum.OrderType
+	BaseSize   float64
+	LimitPrice float64
+	StopPrice  float64
+	Expiry     time.Time
+	Triggered  bool    // a stop-limit whose stop has been hit and now rests as a limit
+	HoldQuote  float64 // funds set aside while the order rests, released on fill or cancel
+	HoldBase   float64
+
+	AttachedBracket *models.AttachedBracket // the exit to place once the order fills, under AttachedOrderID
+	AttachedOrderID string
+}
+
+// getFillPrice walks the price away from the reference by the configured slippage plus size impact.
+func (c Config) getFillPrice(side enum.SignalType, referencePrice float64, notional float64) float64 {
+	bps := c.SlippageBps + c.ImpactBpsPer10kUSD*notional/10000.0
+	if side == enum.SignalBuy {
+		return referencePrice * (1 + bps/10000.0)
+	}
+	return referencePrice * (1 - bps/10000.0)
+}
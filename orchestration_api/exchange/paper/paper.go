@@ -0,0 +1,402 @@
+package paper
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	exchange "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange"
+	exchange_helper "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange/helper"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/indicators"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+	cb_models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models/coinbase"
+	"github.com/google/uuid"
+)
+
+// PaperExchange implements exchange.IExchange by passing market data straight through from another exchange
+// (live Coinbase or a replay) and filling orders against a simulated book. Order updates are published in the
+// same shape the Coinbase user channel produces so Trader can't tell the difference.
+type PaperExchange struct {
+	mu         sync.RWMutex
+	ctx        context.Context
+	marketData exchange.IExchange
+	cfg        Config
+	balances   map[string]float64
+	orders     map[string]*paperOrder
+	orderHub   *exchange_helper.SubscriptionHub[models.OrderUpdate]
+	watchers   map[string]bool // symbols with a goroutine matching resting orders against the ticker
+}
+
+func NewPaperExchange(ctx context.Context, marketData exchange.IExchange, cfg Config) *PaperExchange {
+	balances := make(map[string]float64)
+	for currency, amount := range cfg.StartingBalances {
+		balances[currency] = amount
+	}
+	return &PaperExchange{
+		ctx:        ctx,
+		marketData: marketData,
+		cfg:        cfg,
+		balances:   balances,
+		orders:     make(map[string]*paperOrder),
+		orderHub:   exchange_helper.NewSubscriptionHub[models.OrderUpdate](10),
+		watchers:   make(map[string]bool),
+	}
+}
+
+/* ------------------------------------------------------------------------ MARKET DATA (pass-through) ------------------------------------------------------------------------ */
+
+func (e *PaperExchange) SubscribeToOrderUpdates(symbol string) (<-chan models.OrderUpdate, func()) {
+	return e.orderHub.Subscribe(symbol)
+}
+
+func (e *PaperExchange) SubscribeToTicker(symbol string) (<-chan models.Ticker, func()) {
+	return e.marketData.SubscribeToTicker(symbol)
+}
+
+func (e *PaperExchange) SubscribeToCandle(symbol string) (<-chan models.Candle, func()) {
+	return e.marketData.SubscribeToCandle(symbol)
+}
+
+func (e *PaperExchange) SubscribeToOrderBook(symbol string) (<-chan models.OrderBook, func()) {
+	return e.marketData.SubscribeToOrderBook(symbol)
+}
+
+func (e *PaperExchange) SubscribeToTrades(symbol string) (<-chan models.Trade, func()) {
+	return e.marketData.SubscribeToTrades(symbol)
+}
+
+func (e *PaperExchange) GetOrderBook(symbol string) (models.OrderBook, bool) {
+	return e.marketData.GetOrderBook(symbol)
+}
+
+func (e *PaperExchange) GetCandleHistory(symbol string) models.CandleHistory {
+	return e.marketData.GetCandleHistory(symbol)
+}
+
+func (e *PaperExchange) GetLongCandleHistory(symbol string) models.CandleHistory {
+	return e.marketData.GetLongCandleHistory(symbol)
+}
+
+func (e *PaperExchange) GetResampledCandleHistory(symbol string, size time.Duration) models.CandleHistory {
+	return e.marketData.GetResampledCandleHistory(symbol, size)
+}
+
+func (e *PaperExchange) GetPriceHistory(symbol string) []models.Ticker {
+	return e.marketData.GetPriceHistory(symbol)
+}
+
+func (e *PaperExchange) GetPriceHistoryPage(symbol string, before time.Time, limit int) []models.Ticker {
+	return e.marketData.GetPriceHistoryPage(symbol, before, limit)
+}
+
+func (e *PaperExchange) GetLastPrice(symbol string) (float64, bool) {
+	return e.marketData.GetLastPrice(symbol)
+}
+
+func (e *PaperExchange) GetRenkoCandleHistory(symbol string) models.RenkoCandleHistory {
+	return e.marketData.GetRenkoCandleHistory(symbol)
+}
+
+func (e *PaperExchange) GetIndicators(symbol string) *indicators.Set {
+	return e.marketData.GetIndicators(symbol)
+}
+
+func (e *PaperExchange) IsRenkoCandleHistoryBuilt(symbol string) bool {
+	return e.marketData.IsRenkoCandleHistoryBuilt(symbol)
+}
+
+func (e *PaperExchange) BuildRenkoCandleHistory(symbol string, brickSize float64) {
+	e.marketData.BuildRenkoCandleHistory(symbol, brickSize)
+}
+
+func (e *PaperExchange) UpdateInboundCandleSize(candleSize enum.CandleSize) {
+	e.marketData.UpdateInboundCandleSize(candleSize)
+}
+
+func (e *PaperExchange) StartNewTokenDataStream(symbol string, candleSize enum.CandleSize, historyDepth int) error {
+	return e.marketData.StartNewTokenDataStream(symbol, candleSize, historyDepth)
+}
+
+func (e *PaperExchange) StopTokenDataStream(symbol string) error {
+	e.orderHub.CloseSymbol(symbol)
+	return e.marketData.StopTokenDataStream(symbol)
+}
+
+func (e *PaperExchange) UpdateCandleSizeForSymbol(symbol string, candleSize enum.CandleSize, historyDepth int) error {
+	return e.marketData.UpdateCandleSizeForSymbol(symbol, candleSize, historyDepth)
+}
+
+// StartOrderAndPositionValuationWebSocket is a no-op, order updates come from the simulated book.
+func (e *PaperExchange) StartOrderAndPositionValuationWebSocket(ctx context.Context, wsURL string) {}
+
+func (e *PaperExchange) StartCoinbaseFeed(ctx context.Context, cbAdvUrl string) {
+	e.marketData.StartCoinbaseFeed(ctx, cbAdvUrl)
+}
+
+func (e *PaperExchange) GetHistoricalCandles(ctx context.Context, productID string, candleSize enum.CandleSize) (cb_models.CandlesResponse, error) {
+	return e.marketData.GetHistoricalCandles(ctx, productID, candleSize)
+}
+
+/* ------------------------------------------------------------------------ ACCOUNT + ORDERS (simulated) ------------------------------------------------------------------------ */
+
+func (e *PaperExchange) ListAccounts(ctx context.Context) (cb_models.AccountsListResponse, error) {
+	balances, _ := e.GetAllTokenBalances(ctx)
+	currencies := make([]string, 0, len(balances))
+	for currency := range balances {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	out := cb_models.AccountsListResponse{Accounts: make([]cb_models.Account, 0, len(currencies)), Size: len(currencies)}
+	for _, currency := range currencies {
+		out.Accounts = append(out.Accounts, cb_models.Account{
+			UUID:             "paper-" + currency,
+			Name:             currency + " Paper Wallet",
+			Currency:         currency,
+			AvailableBalance: cb_models.TokenHolding{Value: strconv.FormatFloat(balances[currency], 'f', -1, 64), Currency: currency},
+			Active:           true,
+			Ready:            true,
+			Type:             "ACCOUNT_TYPE_CRYPTO",
+			Platform:         "ACCOUNT_PLATFORM_PAPER",
+		})
+	}
+	return out, nil
+}
+
+func (e *PaperExchange) GetAllTokenBalances(ctx context.Context) (map[string]float64, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	out := make(map[string]float64, len(e.balances))
+	for currency, amount := range e.balances {
+		out[currency] = amount
+	}
+	// like Coinbase, funds held by resting orders still count towards the balance
+	for _, o := range e.orders {
+		if o.HoldQuote > 0 {
+			out[models.GetQuoteCurrency(o.ProductID)] += o.HoldQuote
+		}
+		if o.HoldBase > 0 {
+			out[models.GetBaseCurrency(o.ProductID)] += o.HoldBase
+		}
+	}
+	return out, nil
+}
+
+func (e *PaperExchange) ListOrders(ctx context.Context, productID string, limit int) (cb_models.ListOrdersResponse, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	orders := make([]*paperOrder, 0)
+	for _, o := range e.orders {
+		if o.ProductID == productID {
+			orders = append(orders, o)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].CreatedAt.After(orders[j].CreatedAt) })
+	if limit > 0 && len(orders) > limit {
+		orders = orders[:limit]
+	}
+
+	out := cb_models.ListOrdersResponse{Orders: make([]cb_models.ListOrder, 0, len(orders))}
+	for _, o := range orders {
+		out.Orders = append(out.Orders, cb_models.ListOrder{
+			OrderID:            o.OrderID,
+			ProductID:          o.ProductID,
+			OrderType:          getListOrderType(o.Type),
+			OrderSide:          o.Side,
+			Status:             o.Status,
+			ClientOrderID:      o.ClientOrderID,
+			CreatedTime:        o.CreatedAt.Format(time.RFC3339),
+			CompletionTime:     o.CompletedAt.Format(time.RFC3339),
+			Price:              strconv.FormatFloat(o.LimitPrice, 'f', -1, 64),
+			AverageFilledPrice: strconv.FormatFloat(o.AvgPrice, 'f', -1, 64),
+			FilledSize:         strconv.FormatFloat(o.FilledQty, 'f', -1, 64),
+			RemainingSize:      strconv.FormatFloat(o.getRemainingSize(), 'f', -1, 64),
+		})
+	}
+	return out, nil
+}
+
+func (e *PaperExchange) CreateOrder(ctx context.Context, productID string, amountOfUSD float64, isBuy bool, spec models.OrderSpec) (cb_models.CreateOrderResponse, error) {
+	side := enum.SignalSell
+	if isBuy {
+		side = enum.SignalBuy
+	}
+	if spec.Type == enum.OrderTypeTWAP {
+		return cb_models.CreateOrderResponse{Success: false, Error: "UNSUPPORTED_ORDER_CONFIGURATION"}, fmt.Errorf("paper exchange does not support %s orders", spec.Type.String())
+	}
+	var response cb_models.CreateOrderResponse
+	var err error
+	if spec.IsResting() || spec.Type == enum.OrderTypeLimitIOC {
+		response, err = e.placeRestingOrder(uuid.New().String(), productID, side, amountOfUSD, spec)
+	} else {
+		response, err = e.fillMarketOrder(productID, side, amountOfUSD, 0)
+	}
+	if err != nil || spec.AttachedBracket == nil || spec.AttachedBracket.TakeProfit <= 0 {
+		return response, err
+	}
+	response.AttachedOrderID = e.attachBracket(response.OrderID, *spec.AttachedBracket)
+	return response, nil
+}
+
+// SellTokens mirrors the Coinbase client, where the amount is a base size rather than USD.
+func (e *PaperExchange) SellTokens(ctx context.Context, productID string, amountOfUSD float64) (cb_models.CreateOrderResponse, error) {
+	return e.fillMarketOrder(productID, enum.SignalSell, 0, amountOfUSD)
+}
+
+func (e *PaperExchange) CancelOrders(ctx context.Context, orderID string) error {
+	e.mu.Lock()
+	o, ok := e.orders[orderID]
+	if !ok {
+		e.mu.Unlock()
+		return fmt.Errorf("paper order %s not found", orderID)
+	}
+	if o.Status != "OPEN" {
+		// market orders fill immediately, so there is never anything left to cancel
+		e.mu.Unlock()
+		return nil
+	}
+	e.releaseHold(o)
+	o.Status = "CANCELLED"
+	o.CompletedAt = time.Now()
+	e.mu.Unlock()
+
+	e.publishOrderUpdate(o, "CANCELLED")
+	return nil
+}
+
+// isShortAllowed is whether a sell of productID may take its base balance below zero, as the config says now.
+func (e *PaperExchange) isShortAllowed(productID string) bool {
+	return e.cfg.AllowShort != nil && e.cfg.AllowShort(productID)
+}
+
+// GetPerpPosition treats the base balance as the position, so with AllowShort the paper book stands in for a
+// perpetual. Nothing is ever liquidated.
+func (e *PaperExchange) GetPerpPosition(ctx context.Context, productID string) (models.PerpPosition, error) {
+	balances, _ := e.GetAllTokenBalances(ctx)
+	return models.PerpPosition{
+		ProductID:  productID,
+		Size:       balances[models.GetBaseCurrency(productID)],
+		MarkPrice:  e.getReferencePrice(productID),
+		Leverage:   1,
+		MarginType: enum.MarginCross,
+	}, nil
+}
+
+func (e *PaperExchange) GetFundingRate(ctx context.Context, productID string) (models.FundingRate, error) {
+	return models.FundingRate{ProductID: productID, Rate: e.cfg.FundingRate8h, Interval: 8 * time.Hour}, nil
+}
+
+func (e *PaperExchange) getReferencePrice(productID string) float64 {
+	price, _ := e.marketData.GetLastPrice(productID)
+	return price
+}
+
+// fillMarketOrder fills either a quote amount (USD) or a base amount (tokens) immediately at the reference price
+// moved by slippage. Sells larger than the wallet are capped to the wallet instead of being rejected so a trader
+// chasing a drifting target can always get flat, unless shorting is allowed.
+func (e *PaperExchange) fillMarketOrder(productID string, side enum.SignalType, quoteSize float64, baseSize float64) (cb_models.CreateOrderResponse, error) {
+	referencePrice := e.getReferencePrice(productID)
+	if referencePrice <= 0 {
+		return cb_models.CreateOrderResponse{Success: false, Error: "no market data for " + productID}, fmt.Errorf("no market data for %s", productID)
+	}
+	notional := quoteSize
+	if notional == 0 {
+		notional = baseSize * referencePrice
+	}
+	fillPrice := e.cfg.getFillPrice(side, referencePrice, notional)
+	base := models.GetBaseCurrency(productID)
+	quote := models.GetQuoteCurrency(productID)
+
+	e.mu.Lock()
+	var qty, value, fee float64
+	sideStr := "SELL"
+	if side == enum.SignalBuy {
+		sideStr = "BUY"
+		value = notional
+		fee = value * e.cfg.FeeRate
+		if e.balances[quote] < value+fee {
+			e.mu.Unlock()
+			return cb_models.CreateOrderResponse{Success: false, Error: "INSUFFICIENT_FUND"}, fmt.Errorf("insufficient %s balance for paper buy of %v", quote, value)
+		}
+		qty = value / fillPrice
+		e.balances[quote] -= value + fee
+		e.balances[base] += qty
+	} else {
+		qty = notional / fillPrice
+		if baseSize > 0 {
+			qty = baseSize
+		}
+		if qty > e.balances[base] && !e.isShortAllowed(productID) {
+			qty = e.balances[base]
+		}
+		if qty <= 0 {
+			e.mu.Unlock()
+			return cb_models.CreateOrderResponse{Success: false, Error: "INSUFFICIENT_FUND"}, fmt.Errorf("no %s balance to sell", base)
+		}
+		value = qty * fillPrice
+		fee = value * e.cfg.FeeRate
+		e.balances[base] -= qty
+		e.balances[quote] += value - fee
+	}
+
+	now := time.Now()
+	order := &paperOrder{
+		OrderID:       uuid.New().String(),
+		ClientOrderID: uuid.New().String(),
+		ProductID:     productID,
+		Side:          sideStr,
+		Status:        "FILLED",
+		CreatedAt:     now,
+		CompletedAt:   now,
+		FilledQty:     qty,
+		FilledValue:   value,
+		AvgPrice:      fillPrice,
+		Fees:          fee,
+	}
+	e.orders[order.OrderID] = order
+	e.mu.Unlock()
+
+	log.Printf("[Paper] %s %s %.8f @ %.4f (value %.2f, fee %.2f)", sideStr, productID, qty, fillPrice, value, fee)
+
+	// the user channel sends the order as OPEN first and then FILLED; Trader only reacts to FILLED
+	e.publishOrderUpdate(order, "OPEN")
+	e.publishOrderUpdate(order, "FILLED")
+
+	return cb_models.CreateOrderResponse{Success: true, OrderID: order.OrderID}, nil
+}
+
+func (e *PaperExchange) publishOrderUpdate(o *paperOrder, status string) {
+	update := models.OrderUpdate{
+		Channel:       "user",
+		ProductID:     o.ProductID,
+		OrderID:       o.OrderID,
+		Status:        status,
+		FilledQty:     "0",
+		FilledValue:   "0",
+		CompletionPct: "0",
+		Leaves:        strconv.FormatFloat(o.FilledQty, 'f', -1, 64),
+		Price:         "0",
+		Side:          o.Side,
+		Fees:          "0",
+		Ts:            o.CreatedAt,
+	}
+	if o.Type != enum.OrderTypeMarket {
+		update.Leaves = strconv.FormatFloat(o.BaseSize*o.LimitPrice, 'f', -1, 64)
+		update.Price = strconv.FormatFloat(o.LimitPrice, 'f', -1, 64)
+	}
+	if status == "FILLED" {
+		update.FilledQty = strconv.FormatFloat(o.FilledQty, 'f', -1, 64)
+		update.FilledValue = strconv.FormatFloat(o.FilledValue, 'f', -1, 64)
+		update.CompletionPct = "100"
+		update.Leaves = "0"
+		update.Price = strconv.FormatFloat(o.AvgPrice, 'f', -1, 64)
+		update.Fees = strconv.FormatFloat(o.Fees, 'f', -1, 64)
+	}
+	e.orderHub.Publish(o.ProductID, update)
+}
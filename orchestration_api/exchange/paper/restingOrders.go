@@ -0,0 +1,389 @@
+package paper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+	cb_models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models/coinbase"
+	"github.com/google/uuid"
+)
+
+// placeRestingOrder books a non-market order and sets its funds aside. A post-only order that would cross the
+// reference price is rejected, a plain limit that crosses fills straight away as a taker, an IOC that doesn't is
+// cancelled on the spot, and everything else waits for the ticker to reach it.
+func (e *PaperExchange) placeRestingOrder(orderID string, productID string, side enum.SignalType, amountOfUSD float64, spec models.OrderSpec) (cb_models.CreateOrderResponse, error) {
+	if spec.LimitPrice <= 0 {
+		return cb_models.CreateOrderResponse{Success: false, Error: "INVALID_LIMIT_PRICE"}, fmt.Errorf("%s needs a limit price", spec.Type.String())
+	}
+	if (spec.Type == enum.OrderTypeStopLimit || spec.Type == enum.OrderTypeBracket) && spec.StopPrice <= 0 {
+		return cb_models.CreateOrderResponse{Success: false, Error: "INVALID_STOP_PRICE"}, fmt.Errorf("%s needs a stop price", spec.Type.String())
+	}
+	if !spec.Expiry.IsZero() && !spec.Expiry.After(time.Now()) {
+		return cb_models.CreateOrderResponse{Success: false, Error: "INVALID_END_TIME"}, fmt.Errorf("order expiry %v is in the past", spec.Expiry)
+	}
+	referencePrice := e.getReferencePrice(productID)
+	if referencePrice <= 0 {
+		return cb_models.CreateOrderResponse{Success: false, Error: "no market data for " + productID}, fmt.Errorf("no market data for %s", productID)
+	}
+	crosses := isLimitReached(side, spec.LimitPrice, referencePrice)
+	if spec.Type == enum.OrderTypePostOnly && crosses {
+		return cb_models.CreateOrderResponse{Success: false, Error: "INVALID_LIMIT_PRICE_POST_ONLY"}, fmt.Errorf("post-only %s at %v would cross the market at %v", productID, spec.LimitPrice, referencePrice)
+	}
+
+	sideStr := "SELL"
+	if side == enum.SignalBuy {
+		sideStr = "BUY"
+	}
+	order := &paperOrder{
+		OrderID:       orderID,
+		ClientOrderID: uuid.New().String(),
+		ProductID:     productID,
+		Side:          sideStr,
+		Status:        "OPEN",
+		CreatedAt:     time.Now(),
+		Type:          spec.Type,
+		BaseSize:      amountOfUSD / spec.LimitPrice,
+		LimitPrice:    spec.LimitPrice,
+		StopPrice:     spec.StopPrice,
+		Expiry:        spec.Expiry,
+	}
+
+	e.mu.Lock()
+	if err := e.placeHold(order); err != nil {
+		e.mu.Unlock()
+		return cb_models.CreateOrderResponse{Success: false, Error: "INSUFFICIENT_FUND"}, err
+	}
+	e.orders[order.OrderID] = order
+	isIOC := spec.Type == enum.OrderTypeLimitIOC
+	takerFill := (spec.Type == enum.OrderTypeLimit || isIOC) && crosses
+	if takerFill {
+		e.settle(order, getCappedFillPrice(side, e.cfg.getFillPrice(side, referencePrice, amountOfUSD), spec.LimitPrice), e.cfg.FeeRate)
+	} else if isIOC {
+		e.releaseHold(order)
+		order.Status = "CANCELLED"
+		order.CompletedAt = time.Now()
+	}
+	e.mu.Unlock()
+
+	log.Printf("[Paper] %s %s %s %.8f @ %.4f (stop %.4f)", spec.Type.String(), sideStr, productID, order.BaseSize, spec.LimitPrice, spec.StopPrice)
+	e.publishOrderUpdate(order, "OPEN")
+	if takerFill {
+		e.publishOrderUpdate(order, "FILLED")
+	} else if isIOC {
+		e.publishOrderUpdate(order, "CANCELLED")
+	} else {
+		e.ensureOrderWatcher(productID)
+	}
+	return cb_models.CreateOrderResponse{Success: true, OrderID: order.OrderID}, nil
+}
+
+// attachBracket hands out the id of the exit attached to an order and places it once the order has filled. Like
+// Coinbase, it is sized to the fill and a bracket always has both a take profit and a stop.
+func (e *PaperExchange) attachBracket(orderID string, bracket models.AttachedBracket) string {
+	e.mu.Lock()
+	o, ok := e.orders[orderID]
+	if !ok {
+		e.mu.Unlock()
+		return ""
+	}
+	o.AttachedBracket = &bracket
+	o.AttachedOrderID = uuid.New().String()
+	e.mu.Unlock()
+
+	e.placeAttachedBracket(o) // a market order, or a limit that crossed, has filled already
+	return o.AttachedOrderID
+}
+
+// placeAttachedBracket rests the exit attached to an order once the order has filled, and only the once.
+func (e *PaperExchange) placeAttachedBracket(o *paperOrder) {
+	e.mu.Lock()
+	if o.AttachedBracket == nil || o.Status != "FILLED" {
+		e.mu.Unlock()
+		return
+	}
+	bracket := *o.AttachedBracket
+	o.AttachedBracket = nil
+	e.mu.Unlock()
+
+	side := enum.SignalBuy
+	if o.getSide() == enum.SignalBuy {
+		side = enum.SignalSell
+	}
+	spec := models.OrderSpec{Type: enum.OrderTypeBracket, LimitPrice: bracket.TakeProfit, StopPrice: bracket.StopLoss}
+	if _, err := e.placeRestingOrder(o.AttachedOrderID, o.ProductID, side, o.FilledQty*spec.LimitPrice, spec); err != nil {
+		log.Printf("[Paper] could not place the bracket attached to %s: %v", o.OrderID, err)
+	}
+}
+
+// EditOrder takes the Coinbase edit body and moves a resting order's price, size and/or stop.
+func (e *PaperExchange) EditOrder(ctx context.Context, body []byte) (cb_models.EditOrderResponse, error) {
+	var req cb_models.EditOrderRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return cb_models.EditOrderResponse{Success: false, Error: err.Error()}, fmt.Errorf("parse paper edit: %w", err)
+	}
+	price, _ := strconv.ParseFloat(req.Price, 64)
+	size, _ := strconv.ParseFloat(req.Size, 64)
+	stopPrice, _ := strconv.ParseFloat(req.StopPrice, 64)
+
+	e.mu.RLock()
+	o, ok := e.orders[req.OrderID]
+	e.mu.RUnlock()
+	if !ok {
+		return cb_models.EditOrderResponse{Success: false, Error: "ORDER_NOT_FOUND"}, fmt.Errorf("paper order %s not found", req.OrderID)
+	}
+	referencePrice := e.getReferencePrice(o.ProductID)
+
+	e.mu.Lock()
+	if o.Status != "OPEN" {
+		e.mu.Unlock()
+		return cb_models.EditOrderResponse{Success: false, Error: "ORDER_IS_NOT_OPEN"}, fmt.Errorf("paper order %s is %s", o.OrderID, o.Status)
+	}
+	if price <= 0 {
+		price = o.LimitPrice
+	}
+	if size <= 0 {
+		size = o.BaseSize
+	}
+	if stopPrice <= 0 {
+		stopPrice = o.StopPrice
+	}
+	if o.Type == enum.OrderTypePostOnly && isLimitReached(o.getSide(), price, referencePrice) {
+		e.mu.Unlock()
+		return cb_models.EditOrderResponse{Success: false, Error: "INVALID_LIMIT_PRICE_POST_ONLY"}, fmt.Errorf("post-only edit to %v would cross the market at %v", price, referencePrice)
+	}
+	oldPrice, oldSize, oldStopPrice := o.LimitPrice, o.BaseSize, o.StopPrice
+	e.releaseHold(o)
+	o.LimitPrice, o.BaseSize, o.StopPrice = price, size, stopPrice
+	if err := e.placeHold(o); err != nil {
+		o.LimitPrice, o.BaseSize, o.StopPrice = oldPrice, oldSize, oldStopPrice
+		_ = e.placeHold(o)
+		e.mu.Unlock()
+		return cb_models.EditOrderResponse{Success: false, Error: "INSUFFICIENT_FUND"}, err
+	}
+	e.mu.Unlock()
+
+	e.publishOrderUpdate(o, "OPEN")
+	return cb_models.EditOrderResponse{Success: true, OrderID: o.OrderID}, nil
+}
+
+// ensureOrderWatcher starts matching resting orders for a symbol against its ticker. The watcher stops once the
+// symbol has no open orders left.
+func (e *PaperExchange) ensureOrderWatcher(productID string) {
+	e.mu.Lock()
+	if e.watchers[productID] {
+		e.mu.Unlock()
+		return
+	}
+	e.watchers[productID] = true
+	e.mu.Unlock()
+
+	tickerCh, cleanup := e.marketData.SubscribeToTicker(productID)
+	go func() {
+		defer cleanup()
+		for {
+			select {
+			case <-e.ctx.Done():
+				return
+			case ticker, ok := <-tickerCh:
+				if !ok {
+					e.mu.Lock()
+					delete(e.watchers, productID)
+					e.mu.Unlock()
+					return
+				}
+				if !e.matchRestingOrders(productID, ticker.Price) {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// matchRestingOrders fills, triggers and expires the symbol's open orders at the latest price and reports
+// whether any are still open.
+func (e *PaperExchange) matchRestingOrders(productID string, price float64) bool {
+	type update struct {
+		order  *paperOrder
+		status string
+	}
+	updates := make([]update, 0)
+	now := time.Now()
+
+	e.mu.Lock()
+	stillOpen := false
+	for _, o := range e.orders {
+		if o.ProductID != productID || o.Status != "OPEN" {
+			continue
+		}
+		side := o.getSide()
+		if !o.Expiry.IsZero() && now.After(o.Expiry) {
+			e.releaseHold(o)
+			o.Status = "EXPIRED"
+			o.CompletedAt = now
+			updates = append(updates, update{o, "EXPIRED"})
+			continue
+		}
+
+		switch o.Type {
+		case enum.OrderTypeLimit, enum.OrderTypePostOnly:
+			if isLimitReached(side, o.LimitPrice, price) {
+				e.settle(o, o.LimitPrice, e.cfg.MakerFeeRate)
+			}
+		case enum.OrderTypeStopLimit:
+			if !o.Triggered && isStopReached(side, o.StopPrice, price) {
+				// the limit is born crossing the market, so it takes liquidity
+				o.Triggered = true
+				if isLimitReached(side, o.LimitPrice, price) {
+					e.settle(o, getCappedFillPrice(side, e.cfg.getFillPrice(side, price, o.BaseSize*price), o.LimitPrice), e.cfg.FeeRate)
+				}
+			} else if o.Triggered && isLimitReached(side, o.LimitPrice, price) {
+				e.settle(o, o.LimitPrice, e.cfg.MakerFeeRate)
+			}
+		case enum.OrderTypeBracket:
+			if isLimitReached(side, o.LimitPrice, price) {
+				e.settle(o, o.LimitPrice, e.cfg.MakerFeeRate)
+			} else if isStopReached(side, o.StopPrice, price) {
+				// the stop leg goes out as a market order
+				e.settle(o, e.cfg.getFillPrice(side, price, o.BaseSize*price), e.cfg.FeeRate)
+			}
+		}
+
+		if o.Status == "FILLED" {
+			updates = append(updates, update{o, "FILLED"})
+		} else {
+			stillOpen = true
+		}
+	}
+	if !stillOpen {
+		delete(e.watchers, productID)
+	}
+	e.mu.Unlock()
+
+	for _, u := range updates {
+		log.Printf("[Paper] %s %s %s %s %.8f @ %.4f (fee %.2f)", u.status, u.order.Type.String(), u.order.Side, productID, u.order.FilledQty, u.order.AvgPrice, u.order.Fees)
+		e.publishOrderUpdate(u.order, u.status)
+		if u.status == "FILLED" {
+			e.placeAttachedBracket(u.order)
+		}
+	}
+	return stillOpen
+}
+
+// placeHold sets aside what the order could cost: quote for buys (at the worse of its two prices, plus taker
+// fees) and base for sells. Like market sells, a resting sell larger than the wallet is shrunk to fit, unless
+// shorting is allowed, in which case only what the wallet has is held. Callers hold e.mu.
+func (e *PaperExchange) placeHold(o *paperOrder) error {
+	base := models.GetBaseCurrency(o.ProductID)
+	quote := models.GetQuoteCurrency(o.ProductID)
+	if o.getSide() == enum.SignalBuy {
+		need := o.BaseSize * max(o.LimitPrice, o.StopPrice) * (1 + e.cfg.FeeRate)
+		if e.balances[quote] < need {
+			return fmt.Errorf("insufficient %s balance for paper buy of %v", quote, need)
+		}
+		e.balances[quote] -= need
+		o.HoldQuote = need
+		return nil
+	}
+	if e.isShortAllowed(o.ProductID) {
+		o.HoldBase = min(o.BaseSize, max(e.balances[base], 0))
+		e.balances[base] -= o.HoldBase
+		return nil
+	}
+	qty := min(o.BaseSize, e.balances[base])
+	if qty <= 0 {
+		return fmt.Errorf("no %s balance to sell", base)
+	}
+	e.balances[base] -= qty
+	o.BaseSize = qty
+	o.HoldBase = qty
+	return nil
+}
+
+// releaseHold gives back whatever the order had set aside. Callers hold e.mu.
+func (e *PaperExchange) releaseHold(o *paperOrder) {
+	e.balances[models.GetQuoteCurrency(o.ProductID)] += o.HoldQuote
+	e.balances[models.GetBaseCurrency(o.ProductID)] += o.HoldBase
+	o.HoldQuote = 0
+	o.HoldBase = 0
+}
+
+// settle fills the whole order at price out of its hold. Callers hold e.mu.
+func (e *PaperExchange) settle(o *paperOrder, price float64, feeRate float64) {
+	base := models.GetBaseCurrency(o.ProductID)
+	quote := models.GetQuoteCurrency(o.ProductID)
+	value := o.BaseSize * price
+	fee := value * feeRate
+	if o.getSide() == enum.SignalBuy {
+		e.balances[quote] += o.HoldQuote - value - fee
+		e.balances[base] += o.BaseSize
+	} else {
+		e.balances[base] -= o.BaseSize - o.HoldBase // the part of a short sale the wallet didn't hold
+		e.balances[quote] += value - fee
+	}
+	o.HoldQuote = 0
+	o.HoldBase = 0
+	o.Status = "FILLED"
+	o.CompletedAt = time.Now()
+	o.FilledQty = o.BaseSize
+	o.FilledValue = value
+	o.AvgPrice = price
+	o.Fees = fee
+}
+
+func (o *paperOrder) getSide() enum.SignalType {
+	if o.Side == "BUY" {
+		return enum.SignalBuy
+	}
+	return enum.SignalSell
+}
+
+func (o *paperOrder) getRemainingSize() float64 {
+	if o.Status != "OPEN" {
+		return 0
+	}
+	return o.BaseSize
+}
+
+// isLimitReached reports whether price is at or through a limit, i.e. a buy limit at or above it or a sell
+// limit at or below it.
+func isLimitReached(side enum.SignalType, limitPrice float64, price float64) bool {
+	if side == enum.SignalBuy {
+		return price <= limitPrice
+	}
+	return price >= limitPrice
+}
+
+// isStopReached is the mirror image: buy stops trigger on the way up, sell stops on the way down.
+func isStopReached(side enum.SignalType, stopPrice float64, price float64) bool {
+	if side == enum.SignalBuy {
+		return price >= stopPrice
+	}
+	return price <= stopPrice
+}
+
+// getCappedFillPrice keeps a slipped taker fill inside the order's limit.
+func getCappedFillPrice(side enum.SignalType, fillPrice float64, limitPrice float64) float64 {
+	if side == enum.SignalBuy {
+		return min(fillPrice, limitPrice)
+	}
+	return max(fillPrice, limitPrice)
+}
+
+func getListOrderType(orderType enum.OrderType) string {
+	switch orderType {
+	case enum.OrderTypeLimit, enum.OrderTypePostOnly, enum.OrderTypeLimitIOC:
+		return "LIMIT"
+	case enum.OrderTypeStopLimit:
+		return "STOP_LIMIT"
+	case enum.OrderTypeBracket:
+		return "BRACKET"
+	default:
+		return "MARKET"
+	}
+}
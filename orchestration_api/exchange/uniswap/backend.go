@@ -0,0 +1,137 @@
+package uniswap
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Backend is everything the exchange needs from a node. *ethclient.Client satisfies it, and so does the Client of
+// go-ethereum's ethclient/simulated backend, which is how this is exercised without a live chain.
+type Backend interface {
+	ethereum.ChainReader
+	ethereum.ChainIDReader
+	ethereum.ChainStateReader
+	ethereum.ContractCaller
+	ethereum.LogFilterer
+	ethereum.PendingStateReader
+	ethereum.TransactionReader
+	ethereum.TransactionSender
+	ethereum.GasPricer1559
+}
+
+func DialBackend(ctx context.Context, rpcURL string) (Backend, error) {
+	return ethclient.DialContext(ctx, rpcURL)
+}
+
+const erc20ABIJson = `[
+	{"type":"function","name":"balanceOf","stateMutability":"view","inputs":[{"name":"account","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"allowance","stateMutability":"view","inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"approve","stateMutability":"nonpayable","inputs":[{"name":"spender","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]}
+]`
+
+const poolABIJson = `[
+	{"type":"event","name":"Swap","anonymous":false,"inputs":[
+		{"name":"sender","type":"address","indexed":true},
+		{"name":"recipient","type":"address","indexed":true},
+		{"name":"amount0","type":"int256","indexed":false},
+		{"name":"amount1","type":"int256","indexed":false},
+		{"name":"sqrtPriceX96","type":"uint160","indexed":false},
+		{"name":"liquidity","type":"uint128","indexed":false},
+		{"name":"tick","type":"int24","indexed":false}
+	]}
+]`
+
+const routerABIJson = `[
+	{"type":"function","name":"exactInputSingle","stateMutability":"payable","inputs":[{"name":"params","type":"tuple","components":[
+		{"name":"tokenIn","type":"address"},
+		{"name":"tokenOut","type":"address"},
+		{"name":"fee","type":"uint24"},
+		{"name":"recipient","type":"address"},
+		{"name":"deadline","type":"uint256"},
+		{"name":"amountIn","type":"uint256"},
+		{"name":"amountOutMinimum","type":"uint256"},
+		{"name":"sqrtPriceLimitX96","type":"uint160"}
+	]}],"outputs":[{"name":"amountOut","type":"uint256"}]}
+]`
+
+var (
+	erc20ABI  = mustParseABI(erc20ABIJson)
+	poolABI   = mustParseABI(poolABIJson)
+	routerABI = mustParseABI(routerABIJson)
+	swapTopic = poolABI.Events["Swap"].ID
+)
+
+// exactInputSingleParams field names must match the tuple components for abi packing.
+type exactInputSingleParams struct {
+	TokenIn           common.Address
+	TokenOut          common.Address
+	Fee               *big.Int
+	Recipient         common.Address
+	Deadline          *big.Int
+	AmountIn          *big.Int
+	AmountOutMinimum  *big.Int
+	SqrtPriceLimitX96 *big.Int
+}
+
+type swapEvent struct {
+	Amount0      *big.Int
+	Amount1      *big.Int
+	SqrtPriceX96 *big.Int
+}
+
+func mustParseABI(raw string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(raw))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+func decodeSwap(data []byte) (swapEvent, error) {
+	values, err := poolABI.Unpack("Swap", data)
+	if err != nil {
+		return swapEvent{}, err
+	}
+	return swapEvent{
+		Amount0:      values[0].(*big.Int),
+		Amount1:      values[1].(*big.Int),
+		SqrtPriceX96: values[2].(*big.Int),
+	}, nil
+}
+
+// priceFromSqrtX96 computes token1 per token0 price adjusted by decimals.
+func priceFromSqrtX96(sqrtX96 *big.Int, dec0, dec1 int) float64 {
+	if sqrtX96.Sign() == 0 {
+		return 0
+	}
+	// P = (sqrtX96^2 / 2^192) * 10^(dec0 - dec1)
+	num := new(big.Float).SetInt(new(big.Int).Mul(sqrtX96, sqrtX96))
+	den := new(big.Float).SetInt(new(big.Int).Lsh(big.NewInt(1), 192))
+	q := new(big.Float).Quo(num, den)
+	// decimals adj
+	pow := new(big.Float).SetFloat64(math.Pow10(dec0 - dec1))
+	q.Mul(q, pow)
+	val, _ := q.Float64()
+	return val
+}
+
+func fromTokenUnits(amount *big.Int, decimals int) float64 {
+	f := new(big.Float).SetInt(amount)
+	f.Quo(f, new(big.Float).SetFloat64(math.Pow10(decimals)))
+	val, _ := f.Float64()
+	return val
+}
+
+func toTokenUnits(amount float64, decimals int) *big.Int {
+	f := new(big.Float).SetFloat64(amount)
+	f.Mul(f, new(big.Float).SetFloat64(math.Pow10(decimals)))
+	out, _ := f.Int(nil)
+	return out
+}
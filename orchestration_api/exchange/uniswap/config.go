@@ -0,0 +1,105 @@
+package uniswap
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TokenConfig describes one side of a pool. Currency is the name balances are reported under, so WETH should be
+// configured as "ETH" and USDC as "USD" to line up with product ids like "ETH-USD".
+type TokenConfig struct {
+	Currency string `json:"currency"`
+	Address  string `json:"address"`
+	Decimals int    `json:"decimals"`
+}
+
+// PoolConfig maps a product id onto a Uniswap v3 pool. Which of the two tokens is token0 is worked out from the
+// addresses, the same way the pool factory orders them.
+type PoolConfig struct {
+	Address string      `json:"address"`
+	Base    TokenConfig `json:"base"`
+	Quote   TokenConfig `json:"quote"`
+	FeeTier uint32      `json:"feeTier"` // 500, 3000, 10000 (hundredths of a bip)
+}
+
+// Config holds the RPC endpoints, wallet and pools the Uniswap exchange trades. The quote token of every pool is
+// expected to be a USD stablecoin since the traders size orders in USD.
+type Config struct {
+	RPCURL        string                `json:"rpcUrl"` // must be a websocket endpoint for log subscriptions
+	SubgraphURL   string                `json:"subgraphUrl"`
+	RouterAddress string                `json:"routerAddress"` // SwapRouter (v1), the one whose exactInputSingle takes a deadline
+	PrivateKey    string                `json:"privateKey"`
+	WalletAddress string                `json:"walletAddress"` // only needed without a private key, i.e. watch-only
+	Pools         map[string]PoolConfig `json:"pools"`         // keyed by product id, e.g. "ETH-USD"
+	SlippageBps   float64               `json:"slippageBps"`
+	SwapGasLimit  uint64                `json:"swapGasLimit"`
+	// HistoryBlockRange is how many blocks a single eth_getLogs call may span when backfilling from swap logs.
+	HistoryBlockRange uint64 `json:"historyBlockRange"`
+}
+
+func (c *Config) setDefaults() {
+	if c.SlippageBps == 0 {
+		c.SlippageBps = 50
+	}
+	if c.SwapGasLimit == 0 {
+		c.SwapGasLimit = 350000
+	}
+	if c.HistoryBlockRange == 0 {
+		c.HistoryBlockRange = 2000
+	}
+}
+
+func (c Config) validate() error {
+	if c.RPCURL == "" {
+		return fmt.Errorf("uniswap config: rpcUrl is required")
+	}
+	if len(c.Pools) == 0 {
+		return fmt.Errorf("uniswap config: at least one pool is required")
+	}
+	for symbol, pool := range c.Pools {
+		if pool.Address == "" || pool.Base.Address == "" || pool.Quote.Address == "" {
+			return fmt.Errorf("uniswap config: pool %s needs pool, base and quote addresses", symbol)
+		}
+	}
+	return nil
+}
+
+// LoadConfig reads a JSON config file.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("read uniswap config: %w", err)
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return cfg, fmt.Errorf("parse uniswap config: %w", err)
+	}
+	cfg.setDefaults()
+	return cfg, nil
+}
+
+// LoadConfigFromEnv reads the file named by UNISWAP_CONFIG and lets UNISWAP_RPC_URL, UNISWAP_SUBGRAPH_URL and
+// UNISWAP_PRIVATE_KEY override it, so the key never has to live in the file.
+func LoadConfigFromEnv() (Config, error) {
+	cfg := Config{}
+	if path := os.Getenv("UNISWAP_CONFIG"); path != "" {
+		loaded, err := LoadConfig(path)
+		if err != nil {
+			return cfg, err
+		}
+		cfg = loaded
+	}
+	if v := os.Getenv("UNISWAP_RPC_URL"); v != "" {
+		cfg.RPCURL = v
+	}
+	if v := os.Getenv("UNISWAP_SUBGRAPH_URL"); v != "" {
+		cfg.SubgraphURL = v
+	}
+	if v := os.Getenv("UNISWAP_PRIVATE_KEY"); v != "" {
+		cfg.PrivateKey = strings.TrimPrefix(v, "0x")
+	}
+	cfg.setDefaults()
+	return cfg, cfg.validate()
+}
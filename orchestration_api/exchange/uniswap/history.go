@@ -0,0 +1,171 @@
+package uniswap
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	exchange_helper "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange/helper"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+	cb_models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models/coinbase"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// same depth the Coinbase client asks for
+const historyBuckets = 100
+
+// GetHistoricalCandles backfills the last 100 buckets of candleSize, oldest first. Hourly and longer sizes come
+// from the subgraph when one is configured, anything finer (or a chain without a subgraph, like a simulated
+// backend) is rebuilt from the pool's Swap logs.
+func (e *UniswapExchange) GetHistoricalCandles(ctx context.Context, productID string, candleSize enum.CandleSize) (cb_models.CandlesResponse, error) {
+	p, err := e.getPool(productID)
+	if err != nil {
+		return cb_models.CandlesResponse{}, err
+	}
+	candles, err := e.getHistoricalCandles(ctx, p, candleSize, historyBuckets)
+	if err != nil {
+		return cb_models.CandlesResponse{}, err
+	}
+
+	out := cb_models.CandlesResponse{Candles: make([]cb_models.CoinbaseHistoricalCandle, 0, len(candles))}
+	for _, c := range candles {
+		out.Candles = append(out.Candles, cb_models.CoinbaseHistoricalCandle{
+			Start:  strconv.FormatInt(c.Start.Unix(), 10),
+			High:   c.High,
+			Low:    c.Low,
+			Open:   c.Open,
+			Close:  c.Close,
+			Volume: c.Volume,
+		})
+	}
+	return out, nil
+}
+
+// getSeedCandles fetches the history the price action store starts the pool from, historyDepth buckets at each of
+// the seed sizes.
+func (e *UniswapExchange) getSeedCandles(p pool, candleSize enum.CandleSize, historyDepth int) (map[enum.CandleSize][]models.Candle, error) {
+	seeds := make(map[enum.CandleSize][]models.Candle)
+	depth := exchange_helper.GetCandleHistoryDepth(historyDepth)
+	for _, size := range exchange_helper.GetSeedCandleSizes(candleSize) {
+		candles, err := e.getHistoricalCandles(e.ctx, p, size, depth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s historical candles: %v", size.String(), err)
+		}
+		seeds[size] = candles
+	}
+	return seeds, nil
+}
+
+// getHistoricalCandles backfills the last depth buckets of candleSize. Both sources already page on their own, the
+// subgraph by its page size and the swap logs by cfg.HistoryBlockRange.
+func (e *UniswapExchange) getHistoricalCandles(ctx context.Context, p pool, candleSize enum.CandleSize, depth int) ([]models.Candle, error) {
+	size := enum.GetTimeDurationFromCandleSize(candleSize)
+	since := time.Now().Add(-time.Duration(depth) * size).Truncate(size)
+
+	if e.cfg.SubgraphURL != "" && size >= time.Hour {
+		hours, err := FetchPoolHourData(ctx, SubgraphConfig{URL: e.cfg.SubgraphURL}, p.address.Hex(), since)
+		if err != nil {
+			return nil, fmt.Errorf("subgraph backfill for %s: %w", p.symbol, err)
+		}
+		candles := make([]models.Candle, 0, len(hours))
+		for _, h := range hours {
+			candles = append(candles, h.toCandle(p))
+		}
+		if len(candles) == 0 {
+			return nil, fmt.Errorf("subgraph has no hour data for %s", p.symbol)
+		}
+		return models.ResampleCandles(candles, size), nil
+	}
+
+	return e.getCandlesFromSwapLogs(ctx, p, since, size)
+}
+
+// getCandlesFromSwapLogs rebuilds candles from eth_getLogs. Fetching a header per log would be far too slow, so
+// block times are interpolated from the head and a sample block further back.
+func (e *UniswapExchange) getCandlesFromSwapLogs(ctx context.Context, p pool, since time.Time, size time.Duration) ([]models.Candle, error) {
+	head, err := e.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get head block: %w", err)
+	}
+	headNumber := head.Number.Uint64()
+	headTime := time.Unix(int64(head.Time), 0)
+	secondsPerBlock := e.estimateSecondsPerBlock(ctx, headNumber, head.Time)
+
+	blocksBack := uint64(time.Since(since).Seconds()/secondsPerBlock) + 1
+	fromBlock := uint64(0)
+	if blocksBack < headNumber {
+		fromBlock = headNumber - blocksBack
+	}
+
+	candles := make([]models.Candle, 0, int(time.Since(since)/size)+1)
+	for start := fromBlock; start <= headNumber; start += e.cfg.HistoryBlockRange {
+		end := min(start+e.cfg.HistoryBlockRange-1, headNumber)
+		logs, err := e.backend.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(start),
+			ToBlock:   new(big.Int).SetUint64(end),
+			Addresses: []common.Address{p.address},
+			Topics:    [][]common.Hash{{swapTopic}},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("get swap logs %d-%d for %s: %w", start, end, p.symbol, err)
+		}
+		for _, lg := range logs {
+			ev, err := decodeSwap(lg.Data)
+			if err != nil {
+				log.Printf("uniswap: bad swap log for %s: %v", p.symbol, err)
+				continue
+			}
+			price := p.getPrice(ev.SqrtPriceX96)
+			if price <= 0 {
+				continue
+			}
+			baseAmount, _ := p.getBaseAndQuoteAmounts(ev)
+			volume := math.Abs(fromTokenUnits(baseAmount, p.baseDecimals))
+			t := headTime.Add(-time.Duration(float64(headNumber-lg.BlockNumber) * secondsPerBlock * float64(time.Second)))
+			candles = appendSwapToCandles(candles, p.symbol, t.Truncate(size), price, volume)
+		}
+	}
+
+	if len(candles) == 0 {
+		return nil, fmt.Errorf("no swaps found for %s since %s", p.symbol, since.Format(time.RFC3339))
+	}
+	return candles, nil
+}
+
+func (e *UniswapExchange) estimateSecondsPerBlock(ctx context.Context, headNumber uint64, headTime uint64) float64 {
+	const defaultSecondsPerBlock = 12.0
+	if headNumber == 0 {
+		return defaultSecondsPerBlock
+	}
+	sampleNumber := uint64(0)
+	if headNumber > 10000 {
+		sampleNumber = headNumber - 10000
+	}
+	sample, err := e.backend.HeaderByNumber(ctx, new(big.Int).SetUint64(sampleNumber))
+	if err != nil || headTime <= sample.Time {
+		return defaultSecondsPerBlock
+	}
+	return float64(headTime-sample.Time) / float64(headNumber-sampleNumber)
+}
+
+func appendSwapToCandles(candles []models.Candle, symbol string, bucketStart time.Time, price float64, volume float64) []models.Candle {
+	if n := len(candles); n > 0 && !bucketStart.After(candles[n-1].Start) {
+		candles[n-1].UpdateCandle(price, candles[n-1].Volume+volume)
+		return candles
+	}
+	return append(candles, models.Candle{
+		Start:     bucketStart,
+		Open:      price,
+		High:      price,
+		Low:       price,
+		Close:     price,
+		Volume:    volume,
+		ProductID: symbol,
+	})
+}
@@ -0,0 +1,61 @@
+package uniswap
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// pool is a PoolConfig resolved into addresses and token0/token1 orientation.
+type pool struct {
+	symbol        string
+	address       common.Address
+	base          common.Address
+	quote         common.Address
+	baseCurrency  string
+	quoteCurrency string
+	baseDecimals  int
+	quoteDecimals int
+	baseIsToken0  bool
+	fee           *big.Int
+}
+
+func newPool(symbol string, cfg PoolConfig) pool {
+	base := common.HexToAddress(cfg.Base.Address)
+	quote := common.HexToAddress(cfg.Quote.Address)
+	return pool{
+		symbol:        symbol,
+		address:       common.HexToAddress(cfg.Address),
+		base:          base,
+		quote:         quote,
+		baseCurrency:  cfg.Base.Currency,
+		quoteCurrency: cfg.Quote.Currency,
+		baseDecimals:  cfg.Base.Decimals,
+		quoteDecimals: cfg.Quote.Decimals,
+		// the factory sorts tokens by address, token0 is always the lower one
+		baseIsToken0: bytes.Compare(base.Bytes(), quote.Bytes()) < 0,
+		fee:          new(big.Int).SetUint64(uint64(cfg.FeeTier)),
+	}
+}
+
+// getPrice returns quote per base from the pool's sqrtPriceX96.
+func (p pool) getPrice(sqrtPriceX96 *big.Int) float64 {
+	if p.baseIsToken0 {
+		return priceFromSqrtX96(sqrtPriceX96, p.baseDecimals, p.quoteDecimals)
+	}
+	price := priceFromSqrtX96(sqrtPriceX96, p.quoteDecimals, p.baseDecimals)
+	if price == 0 {
+		return 0
+	}
+	return 1 / price
+}
+
+// getBaseAndQuoteAmounts returns the swap's token deltas from the pool's point of view (positive means the pool
+// received the token).
+func (p pool) getBaseAndQuoteAmounts(ev swapEvent) (*big.Int, *big.Int) {
+	if p.baseIsToken0 {
+		return ev.Amount0, ev.Amount1
+	}
+	return ev.Amount1, ev.Amount0
+}
@@ -1,14 +1,17 @@
-//go:build uniswap
-
 package uniswap
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
 )
 
 // SubgraphConfig contains endpoint for historical backfill.
@@ -16,7 +19,8 @@ type SubgraphConfig struct {
 	URL string
 }
 
-// PoolHourData represents a minimal subset of Uniswap v3 poolHourData.
+// PoolHourData represents a minimal subset of Uniswap v3 poolHourData. Prices are the subgraph's token0Price,
+// i.e. token0 per token1.
 type PoolHourData struct {
 	PeriodStartUnix int64  `json:"periodStartUnix"`
 	Open            string `json:"open"`
@@ -36,11 +40,34 @@ type poolHourDataResp struct {
 	Errors any `json:"errors"`
 }
 
-// FetchPoolHourData fetches recent hour snapshots for a pool.
-func FetchPoolHourData(cfg SubgraphConfig, poolAddr string, first int) ([]PoolHourData, error) {
-	q := fmt.Sprintf(`{"query":"{ pool(id:\"%s\"){ poolHourData(first:%d, orderBy: periodStartUnix, orderDirection: desc){ periodStartUnix open high low close volumeToken0 volumeToken1 } } }"}`,
-		toLower0x(poolAddr), first)
-	req, err := http.NewRequest("POST", cfg.URL, bytes.NewBufferString(q))
+// the subgraph caps a page at 1000 rows
+const subgraphPageSize = 1000
+
+// FetchPoolHourData fetches hour snapshots for a pool starting at since, oldest first, paging until now.
+func FetchPoolHourData(ctx context.Context, cfg SubgraphConfig, poolAddr string, since time.Time) ([]PoolHourData, error) {
+	out := make([]PoolHourData, 0)
+	from := since.Unix()
+	for {
+		page, err := fetchPoolHourDataPage(ctx, cfg, poolAddr, from)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, page...)
+		if len(page) < subgraphPageSize {
+			return out, nil
+		}
+		from = page[len(page)-1].PeriodStartUnix + 1
+	}
+}
+
+func fetchPoolHourDataPage(ctx context.Context, cfg SubgraphConfig, poolAddr string, from int64) ([]PoolHourData, error) {
+	query := fmt.Sprintf(`{ pool(id:"%s"){ poolHourData(first:%d, where:{periodStartUnix_gte:%d}, orderBy: periodStartUnix, orderDirection: asc){ periodStartUnix open high low close volumeToken0 volumeToken1 } } }`,
+		strings.ToLower(poolAddr), subgraphPageSize, from)
+	payload, err := json.Marshal(map[string]string{"query": query})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.URL, bytes.NewReader(payload))
 	if err != nil {
 		return nil, err
 	}
@@ -52,6 +79,9 @@ func FetchPoolHourData(cfg SubgraphConfig, poolAddr string, first int) ([]PoolHo
 	}
 	defer resp.Body.Close()
 	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("subgraph http %d: %s", resp.StatusCode, string(body))
+	}
 	var out poolHourDataResp
 	if err := json.Unmarshal(body, &out); err != nil {
 		return nil, err
@@ -62,9 +92,34 @@ func FetchPoolHourData(cfg SubgraphConfig, poolAddr string, first int) ([]PoolHo
 	return out.Data.Pool.PoolHourData, nil
 }
 
-func toLower0x(s string) string {
-	if len(s) >= 2 && (s[:2] == "0x" || s[:2] == "0X") {
-		return "0x" + s[2:]
+// toCandle converts an hour snapshot into a quote-per-base candle.
+func (d PoolHourData) toCandle(p pool) models.Candle {
+	open, high, low, close := parseFloat(d.Open), parseFloat(d.High), parseFloat(d.Low), parseFloat(d.Close)
+	volume := parseFloat(d.VolumeToken1)
+	if p.baseIsToken0 {
+		// token0Price is quote per base only when base is token1, otherwise flip it (which also swaps high and low)
+		open, high, low, close = invert(open), invert(low), invert(high), invert(close)
+		volume = parseFloat(d.VolumeToken0)
+	}
+	return models.Candle{
+		Start:     time.Unix(d.PeriodStartUnix, 0),
+		Open:      open,
+		High:      high,
+		Low:       low,
+		Close:     close,
+		Volume:    volume,
+		ProductID: p.symbol,
+	}
+}
+
+func parseFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+func invert(f float64) float64 {
+	if f == 0 {
+		return 0
 	}
-	return s
+	return 1 / f
 }
@@ -0,0 +1,473 @@
+package uniswap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+	cb_models "github.com/A-Here-And-Now/algo-trader/orchestration_api/models/coinbase"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	approveGasLimit = 100000
+	// swapReceiptGrace is how long past its deadline a swap is waited on, for a block mined right at the deadline
+	// to show up
+	swapReceiptGrace = 2 * time.Minute
+	maxSwapMisses    = 5 // polls in a row the node may not know a swap before it counts as dropped
+)
+
+type swapOrder struct {
+	OrderID     string // tx hash
+	ProductID   string
+	Side        string
+	Status      string
+	CreatedAt   time.Time
+	CompletedAt time.Time
+	FilledQty   float64
+	FilledValue float64
+	AvgPrice    float64
+	Fees        float64 // the pool's LP fee in the quote currency, gas is paid separately in ETH
+}
+
+func (e *UniswapExchange) ListAccounts(ctx context.Context) (cb_models.AccountsListResponse, error) {
+	balances, err := e.GetAllTokenBalances(ctx)
+	if err != nil {
+		return cb_models.AccountsListResponse{}, err
+	}
+	currencies := make([]string, 0, len(balances))
+	for currency := range balances {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	out := cb_models.AccountsListResponse{Accounts: make([]cb_models.Account, 0, len(currencies)), Size: len(currencies)}
+	for _, currency := range currencies {
+		out.Accounts = append(out.Accounts, cb_models.Account{
+			UUID:             e.wallet.Hex() + "-" + currency,
+			Name:             currency + " Wallet",
+			Currency:         currency,
+			AvailableBalance: cb_models.TokenHolding{Value: strconv.FormatFloat(balances[currency], 'f', -1, 64), Currency: currency},
+			Active:           true,
+			Ready:            true,
+			Type:             "ACCOUNT_TYPE_CRYPTO",
+			Platform:         "ACCOUNT_PLATFORM_UNISWAP",
+		})
+	}
+	return out, nil
+}
+
+// GetAllTokenBalances returns the wallet's ERC20 balance of every pool token, keyed by the configured currency.
+func (e *UniswapExchange) GetAllTokenBalances(ctx context.Context) (map[string]float64, error) {
+	balances := make(map[string]float64)
+	for _, p := range e.pools {
+		for _, token := range []struct {
+			currency string
+			address  common.Address
+			decimals int
+		}{{p.baseCurrency, p.base, p.baseDecimals}, {p.quoteCurrency, p.quote, p.quoteDecimals}} {
+			if _, done := balances[token.currency]; done {
+				continue
+			}
+			balance, err := e.callERC20(ctx, token.address, "balanceOf", e.wallet)
+			if err != nil {
+				return nil, fmt.Errorf("balanceOf %s: %w", token.currency, err)
+			}
+			balances[token.currency] = fromTokenUnits(balance, token.decimals)
+		}
+	}
+	return balances, nil
+}
+
+func (e *UniswapExchange) callERC20(ctx context.Context, token common.Address, method string, args ...interface{}) (*big.Int, error) {
+	data, err := erc20ABI.Pack(method, args...)
+	if err != nil {
+		return nil, err
+	}
+	out, err := e.backend.CallContract(ctx, ethereum.CallMsg{To: &token, Data: data}, nil)
+	if err != nil {
+		return nil, err
+	}
+	values, err := erc20ABI.Unpack(method, out)
+	if err != nil {
+		return nil, err
+	}
+	return values[0].(*big.Int), nil
+}
+
+func (e *UniswapExchange) ListOrders(ctx context.Context, productID string, limit int) (cb_models.ListOrdersResponse, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	orders := make([]*swapOrder, 0)
+	for _, o := range e.orders {
+		if o.ProductID == productID {
+			orders = append(orders, o)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].CreatedAt.After(orders[j].CreatedAt) })
+	if limit > 0 && len(orders) > limit {
+		orders = orders[:limit]
+	}
+
+	out := cb_models.ListOrdersResponse{Orders: make([]cb_models.ListOrder, 0, len(orders))}
+	for _, o := range orders {
+		out.Orders = append(out.Orders, cb_models.ListOrder{
+			OrderID:            o.OrderID,
+			ProductID:          o.ProductID,
+			OrderType:          "MARKET",
+			OrderSide:          o.Side,
+			Status:             o.Status,
+			CreatedTime:        o.CreatedAt.Format(time.RFC3339),
+			CompletionTime:     o.CompletedAt.Format(time.RFC3339),
+			AverageFilledPrice: strconv.FormatFloat(o.AvgPrice, 'f', -1, 64),
+			FilledSize:         strconv.FormatFloat(o.FilledQty, 'f', -1, 64),
+		})
+	}
+	return out, nil
+}
+
+// CreateOrder swaps at market, or for a limit spec sends an immediate-or-cancel swap whose amountOutMinimum is
+// set by the limit price so it reverts rather than fill worse. Nothing rests on an AMM, so post-only, stop and
+// bracket orders aren't supported.
+func (e *UniswapExchange) CreateOrder(ctx context.Context, productID string, amountOfUSD float64, isBuy bool, spec models.OrderSpec) (cb_models.CreateOrderResponse, error) {
+	p, err := e.getPool(productID)
+	if err != nil {
+		return cb_models.CreateOrderResponse{Success: false, Error: err.Error()}, err
+	}
+	price, err := e.getLastPrice(productID)
+	if err != nil {
+		return cb_models.CreateOrderResponse{Success: false, Error: err.Error()}, err
+	}
+	slippageBps := e.cfg.SlippageBps
+	deadline := time.Now().Add(10 * time.Minute)
+	switch spec.Type {
+	case enum.OrderTypeMarket:
+	case enum.OrderTypeLimit, enum.OrderTypeLimitIOC: // a swap either executes or reverts, so every limit is IOC
+		if spec.LimitPrice <= 0 {
+			err := fmt.Errorf("limit order needs a limit price")
+			return cb_models.CreateOrderResponse{Success: false, Error: err.Error()}, err
+		}
+		price, slippageBps = spec.LimitPrice, 0
+		if !spec.Expiry.IsZero() && spec.Expiry.Before(deadline) {
+			deadline = spec.Expiry
+		}
+	default:
+		err := fmt.Errorf("uniswap does not support %s orders", spec.Type.String())
+		return cb_models.CreateOrderResponse{Success: false, Error: err.Error()}, err
+	}
+	if isBuy {
+		return e.submitSwap(ctx, p, true, toTokenUnits(amountOfUSD, p.quoteDecimals), amountOfUSD/price, slippageBps, deadline)
+	}
+	return e.submitSwap(ctx, p, false, toTokenUnits(amountOfUSD/price, p.baseDecimals), amountOfUSD, slippageBps, deadline)
+}
+
+// SellTokens mirrors the Coinbase client, where the amount is a base size rather than USD.
+func (e *UniswapExchange) SellTokens(ctx context.Context, productID string, amountOfUSD float64) (cb_models.CreateOrderResponse, error) {
+	p, err := e.getPool(productID)
+	if err != nil {
+		return cb_models.CreateOrderResponse{Success: false, Error: err.Error()}, err
+	}
+	price, err := e.getLastPrice(productID)
+	if err != nil {
+		return cb_models.CreateOrderResponse{Success: false, Error: err.Error()}, err
+	}
+	return e.submitSwap(ctx, p, false, toTokenUnits(amountOfUSD, p.baseDecimals), amountOfUSD*price, e.cfg.SlippageBps, time.Now().Add(10*time.Minute))
+}
+
+func (e *UniswapExchange) EditOrder(ctx context.Context, body []byte) (cb_models.EditOrderResponse, error) {
+	return cb_models.EditOrderResponse{Success: false, Error: "uniswap swaps cannot be edited"}, fmt.Errorf("uniswap swaps cannot be edited")
+}
+
+// CancelOrders only succeeds for swaps that already settled, a broadcast transaction can't be pulled back.
+func (e *UniswapExchange) CancelOrders(ctx context.Context, orderID string) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	o, ok := e.orders[orderID]
+	if !ok {
+		return fmt.Errorf("uniswap order %s not found", orderID)
+	}
+	if o.Status == "OPEN" {
+		return fmt.Errorf("swap %s is already broadcast and cannot be cancelled", orderID)
+	}
+	return nil
+}
+
+// GetPerpPosition and GetFundingRate refuse: pools only swap spot tokens, there is nothing to short or lever.
+func (e *UniswapExchange) GetPerpPosition(ctx context.Context, productID string) (models.PerpPosition, error) {
+	return models.PerpPosition{}, fmt.Errorf("uniswap is spot only, %s has no perpetual", productID)
+}
+
+func (e *UniswapExchange) GetFundingRate(ctx context.Context, productID string) (models.FundingRate, error) {
+	return models.FundingRate{}, fmt.Errorf("uniswap is spot only, %s has no funding rate", productID)
+}
+
+func (e *UniswapExchange) getLastPrice(productID string) (float64, error) {
+	if price, ok := e.priceActionStore.GetLastPrice(productID); ok {
+		return price, nil
+	}
+	return 0, fmt.Errorf("no price for %s yet", productID)
+}
+
+// submitSwap sends an exactInputSingle through the router, approving the router first if needed. expectedOut is
+// in output-token units and is used with slippageBps to set amountOutMinimum.
+func (e *UniswapExchange) submitSwap(ctx context.Context, p pool, isBuy bool, amountIn *big.Int, expectedOut float64, slippageBps float64, deadline time.Time) (cb_models.CreateOrderResponse, error) {
+	if e.key == nil {
+		err := errors.New("uniswap exchange is watch-only, no private key configured")
+		return cb_models.CreateOrderResponse{Success: false, Error: err.Error()}, err
+	}
+	if amountIn.Sign() <= 0 {
+		err := errors.New("swap amount rounds to zero")
+		return cb_models.CreateOrderResponse{Success: false, Error: err.Error()}, err
+	}
+
+	tokenIn, tokenOut, outDecimals, side := p.base, p.quote, p.quoteDecimals, "SELL"
+	if isBuy {
+		tokenIn, tokenOut, outDecimals, side = p.quote, p.base, p.baseDecimals, "BUY"
+	}
+	minOut := toTokenUnits(expectedOut*(1-slippageBps/10000.0), outDecimals)
+	router := common.HexToAddress(e.cfg.RouterAddress)
+
+	e.txMu.Lock()
+	defer e.txMu.Unlock()
+
+	nonce, err := e.backend.PendingNonceAt(ctx, e.wallet)
+	if err != nil {
+		return cb_models.CreateOrderResponse{Success: false, Error: err.Error()}, fmt.Errorf("get nonce: %w", err)
+	}
+	if nonce, err = e.ensureAllowance(ctx, tokenIn, router, amountIn, nonce); err != nil {
+		return cb_models.CreateOrderResponse{Success: false, Error: err.Error()}, err
+	}
+
+	data, err := routerABI.Pack("exactInputSingle", exactInputSingleParams{
+		TokenIn:           tokenIn,
+		TokenOut:          tokenOut,
+		Fee:               p.fee,
+		Recipient:         e.wallet,
+		Deadline:          big.NewInt(deadline.Unix()),
+		AmountIn:          amountIn,
+		AmountOutMinimum:  minOut,
+		SqrtPriceLimitX96: big.NewInt(0),
+	})
+	if err != nil {
+		return cb_models.CreateOrderResponse{Success: false, Error: err.Error()}, err
+	}
+	tx, err := e.sendTransaction(ctx, router, data, e.cfg.SwapGasLimit, nonce)
+	if err != nil {
+		return cb_models.CreateOrderResponse{Success: false, Error: err.Error()}, fmt.Errorf("send swap: %w", err)
+	}
+
+	order := &swapOrder{
+		OrderID:   tx.Hash().Hex(),
+		ProductID: p.symbol,
+		Side:      side,
+		Status:    "OPEN",
+		CreatedAt: time.Now(),
+	}
+	e.mu.Lock()
+	e.orders[order.OrderID] = order
+	e.mu.Unlock()
+	log.Printf("uniswap: submitted %s swap for %s in tx %s", side, p.symbol, order.OrderID)
+
+	e.publishOrderUpdate(*order)
+	go e.waitForSwap(p, order.OrderID, tx, deadline)
+
+	return cb_models.CreateOrderResponse{Success: true, OrderID: order.OrderID}, nil
+}
+
+// ensureAllowance approves the router for the max amount once per token. The approval and the swap go out back to
+// back with consecutive nonces rather than waiting for the approval to be mined.
+func (e *UniswapExchange) ensureAllowance(ctx context.Context, token common.Address, spender common.Address, amount *big.Int, nonce uint64) (uint64, error) {
+	e.mu.RLock()
+	approved := e.approved[token]
+	e.mu.RUnlock()
+	if approved {
+		return nonce, nil
+	}
+
+	allowance, err := e.callERC20(ctx, token, "allowance", e.wallet, spender)
+	if err != nil {
+		return nonce, fmt.Errorf("get allowance: %w", err)
+	}
+	if allowance.Cmp(amount) < 0 {
+		maxAmount := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+		data, err := erc20ABI.Pack("approve", spender, maxAmount)
+		if err != nil {
+			return nonce, err
+		}
+		tx, err := e.sendTransaction(ctx, token, data, approveGasLimit, nonce)
+		if err != nil {
+			return nonce, fmt.Errorf("send approve: %w", err)
+		}
+		log.Printf("uniswap: approving router for %s in tx %s", token.Hex(), tx.Hash().Hex())
+		nonce++
+	}
+
+	e.mu.Lock()
+	e.approved[token] = true
+	e.mu.Unlock()
+	return nonce, nil
+}
+
+func (e *UniswapExchange) sendTransaction(ctx context.Context, to common.Address, data []byte, gasLimit uint64, nonce uint64) (*types.Transaction, error) {
+	tip, err := e.backend.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("suggest tip: %w", err)
+	}
+	head, err := e.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get head block: %w", err)
+	}
+	feeCap := new(big.Int).Set(tip)
+	if head.BaseFee != nil {
+		feeCap.Add(feeCap, new(big.Int).Mul(head.BaseFee, big.NewInt(2)))
+	}
+
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   e.chainID,
+		Nonce:     nonce,
+		GasTipCap: tip,
+		GasFeeCap: feeCap,
+		Gas:       gasLimit,
+		To:        &to,
+		Data:      data,
+	})
+	signed, err := types.SignTx(tx, types.LatestSignerForChainID(e.chainID), e.key)
+	if err != nil {
+		return nil, err
+	}
+	return signed, e.backend.SendTransaction(ctx, signed)
+}
+
+// waitForSwap polls for the receipt and publishes the fill from the pool's Swap log, the same FILLED update the
+// Coinbase user channel would send. A swap that will never get a receipt, replaced or dropped, is FAILED, and once
+// past its deadline it can only revert, so a grace period later the wait gives up on it as EXPIRED.
+func (e *UniswapExchange) waitForSwap(p pool, orderID string, tx *types.Transaction, deadline time.Time) {
+	ctx, cancel := context.WithDeadline(e.ctx, deadline.Add(swapReceiptGrace))
+	defer cancel()
+	ticker := time.NewTicker(e.receiptPollInterval)
+	defer ticker.Stop()
+
+	misses := 0
+	for {
+		select {
+		case <-ctx.Done():
+			if e.ctx.Err() == nil {
+				e.closeSwap(orderID, "EXPIRED", "no receipt by its deadline")
+			}
+			return
+		case <-ticker.C:
+		}
+
+		receipt, err := e.backend.TransactionReceipt(ctx, tx.Hash())
+		if err == nil {
+			e.settleSwap(p, orderID, receipt)
+			return
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			log.Printf("uniswap: receipt for %s: %v", orderID, err)
+			continue
+		}
+		if reason := e.getLostReason(ctx, tx, &misses); reason != "" {
+			e.closeSwap(orderID, "FAILED", reason)
+			return
+		}
+	}
+}
+
+// getLostReason is why a swap with no receipt will never get one, empty while it still may. Its nonce being used
+// means another transaction took it, the node not knowing the swap for a few polls in a row means it was dropped.
+func (e *UniswapExchange) getLostReason(ctx context.Context, tx *types.Transaction, misses *int) string {
+	mined, err := e.backend.NonceAt(ctx, e.wallet, nil)
+	if err == nil && mined > tx.Nonce() {
+		// mined between the two calls
+		if _, err := e.backend.TransactionReceipt(ctx, tx.Hash()); errors.Is(err, ethereum.NotFound) {
+			return "replaced by another transaction with its nonce"
+		}
+		return ""
+	}
+	if _, _, err := e.backend.TransactionByHash(ctx, tx.Hash()); errors.Is(err, ethereum.NotFound) {
+		if *misses++; *misses >= maxSwapMisses {
+			return "dropped by the node"
+		}
+		return ""
+	}
+	*misses = 0
+	return ""
+}
+
+func (e *UniswapExchange) settleSwap(p pool, orderID string, receipt *types.Receipt) {
+	e.mu.Lock()
+	order := e.orders[orderID]
+	order.CompletedAt = time.Now()
+	order.Status = "FAILED"
+	if receipt.Status == types.ReceiptStatusSuccessful {
+		for _, lg := range receipt.Logs {
+			if lg.Address != p.address || len(lg.Topics) == 0 || lg.Topics[0] != swapTopic {
+				continue
+			}
+			ev, err := decodeSwap(lg.Data)
+			if err != nil {
+				continue
+			}
+			baseAmount, quoteAmount := p.getBaseAndQuoteAmounts(ev)
+			order.FilledQty = math.Abs(fromTokenUnits(baseAmount, p.baseDecimals))
+			order.FilledValue = math.Abs(fromTokenUnits(quoteAmount, p.quoteDecimals))
+			if order.FilledQty > 0 {
+				order.AvgPrice = order.FilledValue / order.FilledQty
+			}
+			order.Fees = order.FilledValue * float64(p.fee.Uint64()) / 1e6
+			order.Status = "FILLED"
+			break
+		}
+	}
+	snapshot := *order
+	e.mu.Unlock()
+
+	log.Printf("uniswap: swap %s %s (qty %v, value %v)", orderID, snapshot.Status, snapshot.FilledQty, snapshot.FilledValue)
+	e.publishOrderUpdate(snapshot)
+}
+
+// closeSwap ends a swap that got no receipt with nothing filled.
+func (e *UniswapExchange) closeSwap(orderID string, status string, reason string) {
+	e.mu.Lock()
+	order := e.orders[orderID]
+	order.CompletedAt = time.Now()
+	order.Status = status
+	snapshot := *order
+	e.mu.Unlock()
+
+	log.Printf("uniswap: swap %s %s, %s", orderID, status, reason)
+	e.publishOrderUpdate(snapshot)
+}
+
+func (e *UniswapExchange) publishOrderUpdate(o swapOrder) {
+	update := models.OrderUpdate{
+		Channel:       "user",
+		ProductID:     o.ProductID,
+		OrderID:       o.OrderID,
+		Status:        o.Status,
+		FilledQty:     strconv.FormatFloat(o.FilledQty, 'f', -1, 64),
+		FilledValue:   strconv.FormatFloat(o.FilledValue, 'f', -1, 64),
+		CompletionPct: "0",
+		Leaves:        "0",
+		Price:         strconv.FormatFloat(o.AvgPrice, 'f', -1, 64),
+		Side:          o.Side,
+		Fees:          strconv.FormatFloat(o.Fees, 'f', -1, 64),
+		Ts:            o.CreatedAt,
+	}
+	if o.Status == "FILLED" {
+		update.CompletionPct = "100"
+	}
+	e.orderHub.Publish(o.ProductID, update)
+}
@@ -0,0 +1,182 @@
+package uniswap
+
+import (
+	"context"
+	"math/big"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+)
+
+var (
+	testBase   = common.HexToAddress("0x0000000000000000000000000000000000001000")
+	testQuote  = common.HexToAddress("0x0000000000000000000000000000000000002000")
+	testRouter = common.HexToAddress("0x0000000000000000000000000000000000003000")
+)
+
+// tokenCode answers every call with the largest uint256, so balances and allowances never get in the way.
+func tokenCode() []byte {
+	code := []byte{0x7f} // PUSH32
+	for range 32 {
+		code = append(code, 0xff)
+	}
+	return append(code, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3) // MSTORE at 0, RETURN 32 bytes
+}
+
+// routerCode stands in for both the router and the pool: whatever it is sent, it logs a Swap of amount0 and
+// amount1 from its own address.
+func routerCode(amount0 *big.Int, amount1 *big.Int) []byte {
+	var code []byte
+	push32 := func(b []byte) { code = append(append(code, 0x7f), common.LeftPadBytes(b, 32)...) }
+	word := func(v *big.Int) []byte { return common.BigToHash(new(big.Int).And(v, uint256Mask())).Bytes() }
+	push32(word(amount0))
+	code = append(code, 0x60, 0x00, 0x52) // MSTORE at 0
+	push32(word(amount1))
+	code = append(code, 0x60, 0x20, 0x52) // MSTORE at 32, sqrtPriceX96, liquidity and tick stay 0
+	push32(swapTopic.Bytes())
+	return append(code, 0x60, 0xa0, 0x60, 0x00, 0xa1, 0x00) // LOG1 the 160 bytes, STOP
+}
+
+// uint256Mask masks a big.Int to its 256 bit two's complement.
+func uint256Mask() *big.Int {
+	return new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+}
+
+func newTestExchange(t *testing.T) (*UniswapExchange, *simulated.Backend) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a buy of 0.5 ETH for 1000 USD, from the pool's side
+	sim := simulated.NewBackend(types.GenesisAlloc{
+		crypto.PubkeyToAddress(key.PublicKey): {Balance: new(big.Int).Lsh(big.NewInt(1), 100)},
+		testBase:                              {Code: tokenCode()},
+		testQuote:                             {Code: tokenCode()},
+		testRouter:                            {Code: routerCode(big.NewInt(-5e17), big.NewInt(1000e6))},
+	})
+	t.Cleanup(func() { sim.Close() })
+
+	cfg := Config{
+		RPCURL:        "simulated",
+		RouterAddress: testRouter.Hex(),
+		PrivateKey:    common.Bytes2Hex(crypto.FromECDSA(key)),
+		Pools: map[string]PoolConfig{"ETH-USD": {
+			Address: testRouter.Hex(),
+			Base:    TokenConfig{Currency: "ETH", Address: testBase.Hex(), Decimals: 18},
+			Quote:   TokenConfig{Currency: "USD", Address: testQuote.Hex(), Decimals: 6},
+			FeeTier: 3000,
+		}},
+	}
+	e, err := NewUniswapExchange(t.Context(), cfg, sim.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.receiptPollInterval = 10 * time.Millisecond
+	return e, sim
+}
+
+// replaceNonce sends a transfer to self with the swap's nonce at triple its fees, which the transaction pool takes instead.
+func replaceNonce(t *testing.T, e *UniswapExchange, nonce uint64) {
+	ctx := context.Background()
+	tip, err := e.backend.SuggestGasTipCap(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := e.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tip.Mul(tip, big.NewInt(3))
+	feeCap := new(big.Int).Add(tip, new(big.Int).Mul(head.BaseFee, big.NewInt(6)))
+	tx, err := types.SignTx(types.NewTx(&types.DynamicFeeTx{
+		ChainID:   e.chainID,
+		Nonce:     nonce,
+		GasTipCap: tip,
+		GasFeeCap: feeCap,
+		Gas:       21000,
+		To:        &e.wallet,
+	}), types.LatestSignerForChainID(e.chainID), e.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.backend.SendTransaction(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitForSwap(t *testing.T) {
+	tests := []struct {
+		name     string
+		deadline time.Duration // from now, the wait gives up swapReceiptGrace after it
+		settle   func(t *testing.T, e *UniswapExchange, sim *simulated.Backend)
+		status   string
+		qty      float64
+		value    float64
+	}{
+		{
+			name:     "mined",
+			deadline: time.Minute,
+			settle:   func(t *testing.T, e *UniswapExchange, sim *simulated.Backend) { sim.Commit() },
+			status:   "FILLED",
+			qty:      0.5,
+			value:    1000,
+		},
+		{
+			name:     "replaced",
+			deadline: time.Minute,
+			settle: func(t *testing.T, e *UniswapExchange, sim *simulated.Backend) {
+				replaceNonce(t, e, 0)
+				sim.Commit()
+			},
+			status: "FAILED",
+		},
+		{
+			name:     "never mined",
+			deadline: -swapReceiptGrace + 100*time.Millisecond,
+			settle:   func(t *testing.T, e *UniswapExchange, sim *simulated.Backend) {},
+			status:   "EXPIRED",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, sim := newTestExchange(t)
+			updates, unsubscribe := e.SubscribeToOrderUpdates("ETH-USD")
+			defer unsubscribe()
+
+			p, _ := e.getPool("ETH-USD")
+			resp, err := e.submitSwap(t.Context(), p, true, toTokenUnits(1000, 6), 0.5, 50, time.Now().Add(tt.deadline))
+			if err != nil || !resp.Success {
+				t.Fatalf("submitSwap: %v %+v", err, resp)
+			}
+			if up := nextUpdate(t, updates); up.Status != "OPEN" {
+				t.Fatalf("first update %s, want OPEN", up.Status)
+			}
+			tt.settle(t, e, sim)
+
+			up := nextUpdate(t, updates)
+			if up.OrderID != resp.OrderID || up.Status != tt.status {
+				t.Fatalf("got %s %s, want %s %s", up.OrderID, up.Status, resp.OrderID, tt.status)
+			}
+			if up.FilledQty != strconv.FormatFloat(tt.qty, 'f', -1, 64) || up.FilledValue != strconv.FormatFloat(tt.value, 'f', -1, 64) {
+				t.Errorf("filled %s for %s, want %v for %v", up.FilledQty, up.FilledValue, tt.qty, tt.value)
+			}
+		})
+	}
+}
+
+func nextUpdate(t *testing.T, updates <-chan models.OrderUpdate) models.OrderUpdate {
+	t.Helper()
+	select {
+	case up := <-updates:
+		return up
+	case <-time.After(5 * time.Second):
+		t.Fatal("no order update")
+		return models.OrderUpdate{}
+	}
+}
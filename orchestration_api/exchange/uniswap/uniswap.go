@@ -0,0 +1,273 @@
+package uniswap
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	exchange_helper "github.com/A-Here-And-Now/algo-trader/orchestration_api/exchange/helper"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/indicators"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// UniswapExchange implements the Exchange interface against Uniswap v3 pools. Market data comes from the pools'
+// Swap logs (streamed) and the subgraph (backfill), balances are ERC20 balances of the wallet and orders are
+// exactInputSingle swaps through the router.
+type UniswapExchange struct {
+	mu      sync.RWMutex
+	txMu    sync.Mutex // serialises nonce use across approve + swap
+	ctx     context.Context
+	cfg     Config
+	backend Backend
+	pools   map[string]pool
+	wallet  common.Address
+	key     *ecdsa.PrivateKey // nil means watch-only
+	chainID *big.Int
+
+	receiptPollInterval time.Duration
+
+	feedCtx             context.Context
+	symbolSubscriptions map[string]bool
+	streamCancels       map[string]context.CancelFunc
+	inboundCandleSize   enum.CandleSize
+	inboundCandles      map[string]*models.Candle
+
+	candleHub *exchange_helper.SubscriptionHub[models.Candle]
+	tickerHub *exchange_helper.SubscriptionHub[models.Ticker]
+	orderHub  *exchange_helper.SubscriptionHub[models.OrderUpdate]
+	tradeHub  *exchange_helper.SubscriptionHub[models.Trade]
+
+	orders   map[string]*swapOrder
+	approved map[common.Address]bool
+
+	priceActionStore *exchange_helper.PriceActionStore
+}
+
+// DialUniswapExchange connects to cfg.RPCURL and builds the exchange on top of it.
+func DialUniswapExchange(ctx context.Context, cfg Config) (*UniswapExchange, error) {
+	backend, err := DialBackend(ctx, cfg.RPCURL)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", cfg.RPCURL, err)
+	}
+	return NewUniswapExchange(ctx, cfg, backend)
+}
+
+func NewUniswapExchange(ctx context.Context, cfg Config, backend Backend) (*UniswapExchange, error) {
+	cfg.setDefaults()
+	e := &UniswapExchange{
+		ctx:                 ctx,
+		cfg:                 cfg,
+		backend:             backend,
+		pools:               make(map[string]pool),
+		symbolSubscriptions: make(map[string]bool),
+		streamCancels:       make(map[string]context.CancelFunc),
+		inboundCandleSize:   enum.CandleSize5m,
+		inboundCandles:      make(map[string]*models.Candle),
+		candleHub:           exchange_helper.NewSubscriptionHub[models.Candle](10),
+		tickerHub:           exchange_helper.NewSubscriptionHub[models.Ticker](10),
+		orderHub:            exchange_helper.NewSubscriptionHub[models.OrderUpdate](10),
+		tradeHub:            exchange_helper.NewSubscriptionHub[models.Trade](100),
+		orders:              make(map[string]*swapOrder),
+		approved:            make(map[common.Address]bool),
+		priceActionStore:    exchange_helper.NewStore(enum.CandleSize5m),
+		receiptPollInterval: 2 * time.Second,
+	}
+	for symbol, poolCfg := range cfg.Pools {
+		e.pools[symbol] = newPool(symbol, poolCfg)
+	}
+
+	if cfg.PrivateKey != "" {
+		key, err := crypto.HexToECDSA(cfg.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid uniswap private key: %w", err)
+		}
+		e.key = key
+		e.wallet = crypto.PubkeyToAddress(key.PublicKey)
+	} else {
+		e.wallet = common.HexToAddress(cfg.WalletAddress)
+		log.Printf("uniswap: no private key configured, running watch-only for %s", e.wallet.Hex())
+	}
+
+	chainID, err := backend.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get chain id: %w", err)
+	}
+	e.chainID = chainID
+	return e, nil
+}
+
+func (e *UniswapExchange) getPool(symbol string) (pool, error) {
+	p, ok := e.pools[symbol]
+	if !ok {
+		return pool{}, fmt.Errorf("no uniswap pool configured for %s", symbol)
+	}
+	return p, nil
+}
+
+func (e *UniswapExchange) SubscribeToOrderUpdates(symbol string) (<-chan models.OrderUpdate, func()) {
+	return e.orderHub.Subscribe(symbol)
+}
+
+func (e *UniswapExchange) SubscribeToTicker(symbol string) (<-chan models.Ticker, func()) {
+	return e.tickerHub.Subscribe(symbol)
+}
+
+func (e *UniswapExchange) SubscribeToCandle(symbol string) (<-chan models.Candle, func()) {
+	return e.candleHub.Subscribe(symbol)
+}
+
+// SubscribeToOrderBook never delivers, a pool has no order book.
+func (e *UniswapExchange) SubscribeToOrderBook(symbol string) (<-chan models.OrderBook, func()) {
+	return make(chan models.OrderBook), func() {}
+}
+
+// SubscribeToTrades gets the pool's swaps.
+func (e *UniswapExchange) SubscribeToTrades(symbol string) (<-chan models.Trade, func()) {
+	return e.tradeHub.Subscribe(symbol)
+}
+
+func (e *UniswapExchange) GetOrderBook(symbol string) (models.OrderBook, bool) {
+	return models.OrderBook{}, false
+}
+
+func (e *UniswapExchange) GetCandleHistory(symbol string) models.CandleHistory {
+	return e.priceActionStore.GetCandleHistory(symbol)
+}
+
+func (e *UniswapExchange) GetLongCandleHistory(symbol string) models.CandleHistory {
+	return e.priceActionStore.GetLongCandleHistory(symbol)
+}
+
+func (e *UniswapExchange) GetResampledCandleHistory(symbol string, size time.Duration) models.CandleHistory {
+	return e.priceActionStore.GetResampledCandleHistory(symbol, size)
+}
+
+func (e *UniswapExchange) GetPriceHistory(symbol string) []models.Ticker {
+	return e.priceActionStore.GetPriceHistory(symbol)
+}
+
+func (e *UniswapExchange) GetPriceHistoryPage(symbol string, before time.Time, limit int) []models.Ticker {
+	return e.priceActionStore.GetPriceHistoryPage(symbol, before, limit)
+}
+
+func (e *UniswapExchange) GetLastPrice(symbol string) (float64, bool) {
+	return e.priceActionStore.GetLastPrice(symbol)
+}
+
+func (e *UniswapExchange) GetRenkoCandleHistory(symbol string) models.RenkoCandleHistory {
+	return e.priceActionStore.GetRenkoCandleHistory(symbol)
+}
+
+func (e *UniswapExchange) GetIndicators(symbol string) *indicators.Set {
+	return e.priceActionStore.GetIndicators(symbol)
+}
+
+func (e *UniswapExchange) IsRenkoCandleHistoryBuilt(symbol string) bool {
+	return e.priceActionStore.IsRenkoCandleHistoryBuilt(symbol)
+}
+
+func (e *UniswapExchange) BuildRenkoCandleHistory(symbol string, brickSize float64) {
+	e.priceActionStore.BuildRenkoCandleHistory(symbol, brickSize)
+}
+
+func (e *UniswapExchange) UpdateInboundCandleSize(candleSize enum.CandleSize) {
+	e.mu.Lock()
+	e.inboundCandleSize = candleSize
+	e.mu.Unlock()
+	e.priceActionStore.UpdateInboundCandleSize(candleSize)
+}
+
+func (e *UniswapExchange) StartNewTokenDataStream(symbol string, candleSize enum.CandleSize, historyDepth int) error {
+	e.mu.RLock()
+	subscribed := e.symbolSubscriptions[symbol]
+	e.mu.RUnlock()
+	if subscribed {
+		if e.priceActionStore.HasHistory(symbol, candleSize, historyDepth) {
+			return nil
+		}
+		return e.UpdateCandleSizeForSymbol(symbol, candleSize, historyDepth)
+	}
+	p, err := e.getPool(symbol)
+	if err != nil {
+		return err
+	}
+
+	seeds, err := e.getSeedCandles(p, candleSize, historyDepth)
+	if err != nil {
+		log.Printf("%v", err)
+		return err
+	}
+	e.priceActionStore.AddToken(symbol, candleSize, historyDepth, seeds)
+
+	e.mu.Lock()
+	e.symbolSubscriptions[symbol] = true
+	feedCtx := e.feedCtx
+	e.mu.Unlock()
+
+	if feedCtx != nil {
+		e.startSwapLogStream(feedCtx, p)
+	}
+	return nil
+}
+
+func (e *UniswapExchange) StopTokenDataStream(symbol string) error {
+	e.mu.Lock()
+	e.symbolSubscriptions[symbol] = false
+	delete(e.inboundCandles, symbol)
+	if cancel, ok := e.streamCancels[symbol]; ok {
+		cancel()
+		delete(e.streamCancels, symbol)
+	}
+	e.mu.Unlock()
+
+	e.candleHub.CloseSymbol(symbol)
+	e.tickerHub.CloseSymbol(symbol)
+	e.orderHub.CloseSymbol(symbol)
+	e.tradeHub.CloseSymbol(symbol)
+	e.priceActionStore.RemoveToken(symbol)
+	return nil
+}
+
+func (e *UniswapExchange) UpdateCandleSizeForSymbol(symbol string, candleSize enum.CandleSize, historyDepth int) error {
+	p, err := e.getPool(symbol)
+	if err != nil {
+		return err
+	}
+	seeds, err := e.getSeedCandles(p, candleSize, historyDepth)
+	if err != nil {
+		log.Printf("%v", err)
+		return err
+	}
+
+	e.priceActionStore.UpdateCandleSize(symbol, candleSize, historyDepth, seeds)
+	return nil
+}
+
+// StartOrderAndPositionValuationWebSocket is a no-op, order updates come from watching swap receipts.
+func (e *UniswapExchange) StartOrderAndPositionValuationWebSocket(ctx context.Context, wsURL string) {
+}
+
+// StartCoinbaseFeed starts streaming Swap logs for every subscribed pool. The url is ignored, logs come from the
+// configured RPC endpoint.
+func (e *UniswapExchange) StartCoinbaseFeed(ctx context.Context, cbAdvUrl string) {
+	e.mu.Lock()
+	e.feedCtx = ctx
+	symbols := make([]string, 0, len(e.symbolSubscriptions))
+	for symbol, subscribed := range e.symbolSubscriptions {
+		if subscribed {
+			symbols = append(symbols, symbol)
+		}
+	}
+	e.mu.Unlock()
+
+	for _, symbol := range symbols {
+		e.startSwapLogStream(ctx, e.pools[symbol])
+	}
+}
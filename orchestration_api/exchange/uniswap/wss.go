@@ -1,228 +1,152 @@
-//go:build uniswap
-
 package uniswap
 
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"math"
 	"math/big"
-	"strconv"
-	"sync"
 	"time"
 
-	"github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/marketdata"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
 	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
 )
 
-// Config holds RPC endpoints and pool settings for Uniswap market data.
-type Config struct {
-	WSSURL string
-	// Map from symbol like "ETH-USDC" to pool address
-	SymbolToPool map[string]string
-	// Decimals for token0/token1 per symbol (needed to compute prices)
-	SymbolToDecimals map[string]struct{ Dec0, Dec1 int }
-	// Optional: topic0 of the Swap event. If empty, we subscribe by address only.
-	SwapTopic string
-}
-
-type Provider struct {
-	cli    *ethclient.Client
-	cfg    Config
-	cancel context.CancelFunc
-	mu     sync.Mutex
-	closed bool
-}
-
-func NewProvider(ctx context.Context, cfg Config) (*Provider, error) {
-	cli, err := ethclient.DialContext(ctx, cfg.WSSURL)
-	if err != nil {
-		return nil, err
+func (e *UniswapExchange) startSwapLogStream(feedCtx context.Context, p pool) {
+	ctx, cancel := context.WithCancel(feedCtx)
+	e.mu.Lock()
+	if existing, ok := e.streamCancels[p.symbol]; ok {
+		existing()
 	}
-	ctx, cancel := context.WithCancel(ctx)
-	return &Provider{cli: cli, cfg: cfg, cancel: cancel}, nil
-}
+	e.streamCancels[p.symbol] = cancel
+	e.mu.Unlock()
 
-func (p *Provider) Close() {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	if p.closed {
-		return
-	}
-	p.closed = true
-	if p.cancel != nil {
-		p.cancel()
-	}
+	go e.runSwapLogStream(ctx, p)
 }
 
-// SubscribeTicker implements marketdata.MarketData.
-func (p *Provider) SubscribeTicker(symbol string) (<-chan models.Ticker, func(), error) {
-	poolAddrHex, ok := p.cfg.SymbolToPool[symbol]
-	if !ok {
-		return nil, nil, errors.New("unknown symbol: " + symbol)
+// runSwapLogStream subscribes to the pool's Swap logs and reconnects with backoff until ctx is cancelled.
+func (e *UniswapExchange) runSwapLogStream(ctx context.Context, p pool) {
+	backoff := 1 * time.Second
+	query := ethereum.FilterQuery{
+		Addresses: []common.Address{p.address},
+		Topics:    [][]common.Hash{{swapTopic}},
 	}
-	poolAddr := common.HexToAddress(poolAddrHex)
-
-	out := make(chan models.Ticker, 64)
-	ctx, cancel := context.WithCancel(context.Background())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
 
-	go p.streamSwaps(ctx, poolAddr, symbol, out, nil)
+		logsCh := make(chan types.Log, 128)
+		sub, err := e.backend.SubscribeFilterLogs(ctx, query, logsCh)
+		if err != nil {
+			log.Printf("uniswap: subscribe to %s swaps failed: %v", p.symbol, err)
+			time.Sleep(backoff)
+			backoff *= 2
+			if backoff > 30*time.Second {
+				backoff = 30 * time.Second
+			}
+			continue
+		}
 
-	return out, func() {
-		cancel()
-	}, nil
-}
+		backoff = 1 * time.Second
+		log.Printf("uniswap: streaming swaps for %s", p.symbol)
 
-// SubscribeCandles implements marketdata.MarketData using an in-memory bucket aggregator.
-func (p *Provider) SubscribeCandles(symbol string, tf marketdata.CandleTF) (<-chan models.Candle, func(), error) {
-	poolAddrHex, ok := p.cfg.SymbolToPool[symbol]
-	if !ok {
-		return nil, nil, errors.New("unknown symbol: " + symbol)
+		err = e.readSwapLogs(ctx, p, sub, logsCh)
+		sub.Unsubscribe()
+		if err == nil {
+			return
+		}
+		log.Printf("uniswap: %s swap stream dropped (%v); will attempt reconnect", p.symbol, err)
+		time.Sleep(500 * time.Millisecond)
 	}
-	poolAddr := common.HexToAddress(poolAddrHex)
-
-	dec, _ := p.cfg.SymbolToDecimals[symbol]
-
-	out := make(chan models.Candle, 32)
-	ctx, cancel := context.WithCancel(context.Background())
-
-	interval := tf.Duration()
-	go p.streamSwaps(ctx, poolAddr, symbol, nil, &candleAgg{
-		out:      out,
-		interval: interval,
-		symbol:   symbol,
-		dec0:     dec.Dec0,
-		dec1:     dec.Dec1,
-	})
-
-	return out, func() {
-		cancel()
-	}, nil
 }
 
-// streamSwaps subscribes to pool Swap logs, computing price and dispatching ticker/candles.
-func (p *Provider) streamSwaps(ctx context.Context, pool common.Address, symbol string, tickCh chan<- models.Ticker, agg *candleAgg) {
-	q := ethereum.FilterQuery{
-		Addresses: []common.Address{pool},
-	}
-	if p.cfg.SwapTopic != "" {
-		q.Topics = [][]common.Hash{{common.HexToHash(p.cfg.SwapTopic)}}
-	}
-	logsCh := make(chan types.Log, 128)
-	sub, err := p.cli.SubscribeFilterLogs(ctx, q, logsCh)
-	if err != nil {
-		log.Printf("uniswap: subscribe error: %v", err)
-		return
-	}
-	defer sub.Unsubscribe()
-
+func (e *UniswapExchange) readSwapLogs(ctx context.Context, p pool, sub ethereum.Subscription, logsCh <-chan types.Log) error {
 	for {
 		select {
 		case <-ctx.Done():
-			return
+			return nil
 		case err := <-sub.Err():
-			if err != nil {
-				log.Printf("uniswap: subscription error: %v", err)
+			if err == nil {
+				err = errors.New("subscription closed")
 			}
-			return
+			return err
 		case lg := <-logsCh:
-			// Decode minimal fields: data contains (amount0, amount1, sqrtPriceX96, liquidity, tick)
-			if len(lg.Data) < 32*5 { // rough guard
+			if lg.Removed {
+				// reorged out, the replacement log will arrive on its own
 				continue
 			}
-			sqrtPriceX96 := new(big.Int).SetBytes(lg.Data[32*2 : 32*3])
-			decs, ok := p.cfg.SymbolToDecimals[symbol]
-			if !ok {
-				continue
-			}
-			price := priceFromSqrtX96(sqrtPriceX96, decs.Dec0, decs.Dec1)
-
-			if tickCh != nil {
-				out := models.Ticker{Type: "ticker", ProductID: symbol, Price: floatToString(price)}
-				select {
-				case tickCh <- out:
-				default:
-				}
-			}
-			if agg != nil {
-				// types.Log does not include block time; use local time as an approximation
-				agg.onPrice(price, time.Now().UTC())
-			}
+			// types.Log does not include block time; use local time as an approximation
+			e.consumeSwapLog(p, lg, time.Now())
 		}
 	}
 }
 
-// candleAgg aggregates spot updates into OHLCV buckets.
-type candleAgg struct {
-	out      chan<- models.Candle
-	interval time.Duration
-	symbol   string
-	dec0     int
-	dec1     int
-
-	mu    sync.Mutex
-	start time.Time
-	open  float64
-	high  float64
-	low   float64
-	close float64
-}
-
-func (c *candleAgg) onPrice(price float64, t time.Time) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	bucketStart := t.Truncate(c.interval)
-	if c.start.IsZero() || bucketStart.After(c.start) {
-		if !c.start.IsZero() {
-			c.out <- models.Candle{
-				Start:     c.start.UTC().Format(time.RFC3339),
-				High:      c.high,
-				Low:       c.low,
-				Open:      c.open,
-				Close:     c.close,
-				Volume:    0,
-				ProductID: c.symbol,
-			}
-		}
-		c.start = bucketStart
-		c.open = price
-		c.high = price
-		c.low = price
-		c.close = price
+func (e *UniswapExchange) consumeSwapLog(p pool, lg types.Log, t time.Time) {
+	ev, err := decodeSwap(lg.Data)
+	if err != nil {
+		log.Printf("uniswap: bad swap log for %s: %v", p.symbol, err)
+		return
+	}
+	price := p.getPrice(ev.SqrtPriceX96)
+	if price <= 0 {
 		return
 	}
+	baseAmount, quoteAmount := p.getBaseAndQuoteAmounts(ev)
+	volume := math.Abs(fromTokenUnits(baseAmount, p.baseDecimals))
 
-	if price > c.high {
-		c.high = price
+	inboundCandle, ok := e.updateInboundCandle(p.symbol, price, volume, t)
+	if !ok {
+		return
 	}
-	if price < c.low {
-		c.low = price
+	candleToPublish := e.priceActionStore.IngestCandleOfInboundCandleSize(inboundCandle, t)
+	e.candleHub.Publish(p.symbol, candleToPublish)
+	ticker := models.Ticker{Symbol: p.symbol, Price: price, Time: t}
+	e.priceActionStore.IngestTicker(ticker)
+	e.tickerHub.Publish(p.symbol, ticker)
+	if volume > 0 {
+		e.tradeHub.Publish(p.symbol, getSwapTrade(p.symbol, lg, baseAmount, volume, math.Abs(fromTokenUnits(quoteAmount, p.quoteDecimals)), t))
 	}
-	c.close = price
 }
 
-// priceFromSqrtX96 computes token1 per token0 price adjusted by decimals.
-func priceFromSqrtX96(sqrtX96 *big.Int, dec0, dec1 int) float64 {
-	if sqrtX96.Sign() == 0 {
-		return 0
+// getSwapTrade prints a swap as a trade at its average price. The amounts are the pool's side of it, so base
+// flowing into the pool was a sell.
+func getSwapTrade(symbol string, lg types.Log, baseAmount *big.Int, baseVolume float64, quoteVolume float64, t time.Time) models.Trade {
+	side := "BUY"
+	if baseAmount.Sign() > 0 {
+		side = "SELL"
+	}
+	return models.Trade{
+		Symbol:  symbol,
+		TradeID: fmt.Sprintf("%s-%d", lg.TxHash.Hex(), lg.Index),
+		Price:   quoteVolume / baseVolume,
+		Size:    baseVolume,
+		Side:    side,
+		Time:    t,
 	}
-	// P = (sqrtX96^2 / 2^192) * 10^(dec0 - dec1)
-	num := new(big.Float).SetInt(new(big.Int).Mul(sqrtX96, sqrtX96))
-	den := new(big.Float).SetInt(new(big.Int).Lsh(big.NewInt(1), 192))
-	q := new(big.Float).Quo(num, den)
-	// decimals adj
-	pow := new(big.Float).SetFloat64(math.Pow10(dec0 - dec1))
-	q.Mul(q, pow)
-	val, _ := q.Float64()
-	return val
 }
 
-func floatToString(f float64) string {
-	return strconv.FormatFloat(f, 'f', -1, 64)
+// updateInboundCandle folds a swap into the in-progress inbound candle, mimicking the running candle Coinbase
+// pushes on its candles channel (volume is cumulative within the bucket).
+func (e *UniswapExchange) updateInboundCandle(symbol string, price float64, volume float64, t time.Time) (models.Candle, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.symbolSubscriptions[symbol] {
+		return models.Candle{}, false
+	}
+
+	bucketStart := t.Truncate(enum.GetTimeDurationFromCandleSize(e.inboundCandleSize))
+	current := e.inboundCandles[symbol]
+	if current == nil || bucketStart.After(current.Start) {
+		current = &models.Candle{Start: bucketStart, Open: price, High: price, Low: price, Close: price, ProductID: symbol}
+		e.inboundCandles[symbol] = current
+	}
+	current.UpdateCandle(price, current.Volume+volume)
+	return *current, true
 }
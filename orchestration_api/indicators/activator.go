@@ -0,0 +1,76 @@
+package indicators
+
+import "github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+
+type ActivatorValue struct {
+	Stop float64 // the line the close crosses
+	Up   bool    // the close crossed above it on the newest candle
+	Down bool    // the close crossed below it
+	Atr  float64 // at the newest candle, 0 until the ATR is warm
+}
+
+// Activator is the Grover Llorens activator: a line that starts at the first close and, once the close crosses
+// it, jumps mult ATRs to the other side of the price, then walks toward the price by a period-th of the ATR at
+// the cross for every candle since.
+type Activator struct {
+	period     int
+	mult       float64
+	atr        Atr
+	count      int
+	diff       float64 // the close less the line before it
+	sinceCross int
+	crossAtr   float64 // the ATR on the candle of the last cross
+	value      ActivatorValue
+}
+
+func NewActivator(period int, mult float64) *Activator {
+	return &Activator{period: period, mult: mult, atr: Atr{period: period}}
+}
+
+func (a *Activator) Update(c models.Candle) {
+	a.atr.Update(c)
+	atr, _ := a.atr.Value()
+	a.count++
+	a.sinceCross++
+	if a.count == 1 {
+		a.value = ActivatorValue{Stop: c.Close, Atr: atr}
+		a.sinceCross, a.crossAtr = 0, atr
+		return
+	}
+	diff := c.Close - a.value.Stop
+	v := ActivatorValue{Stop: a.value.Stop, Up: a.diff <= 0 && diff > 0, Down: a.diff >= 0 && diff < 0, Atr: atr}
+	switch {
+	case v.Up:
+		v.Stop -= atr * a.mult
+		a.sinceCross, a.crossAtr = 0, atr
+	case v.Down:
+		v.Stop += atr * a.mult
+		a.sinceCross, a.crossAtr = 0, atr
+	default:
+		v.Stop += getSign(diff) * a.crossAtr / float64(a.period) * float64(a.sinceCross)
+	}
+	a.diff = diff
+	a.value = v
+}
+
+func (a *Activator) Value() (ActivatorValue, bool) {
+	_, ok := a.atr.Value()
+	return a.value, ok
+}
+
+func (a *Activator) Peek(c models.Candle) (ActivatorValue, bool) {
+	next := *a
+	next.Update(c)
+	return next.Value()
+}
+
+func getSign(x float64) float64 {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,85 @@
+package indicators
+
+import (
+	"math"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+type AtrTrailValue struct {
+	Close     float64 // the Heiken Ashi close
+	PrevClose float64
+	Line      float64
+	PrevLine  float64
+	Atr       float64 // of the Heiken Ashi candles
+	Ema       float64 // of the Heiken Ashi closes
+}
+
+// AtrTrail is the ATR trailing stop line over Heiken Ashi candles: it follows the close mult ATRs behind while the
+// close stays on one side of it and flips to the other side when the close crosses it. The line starts at 0 on the
+// first candle, as the slice version did.
+type AtrTrail struct {
+	mult   float64
+	atr    Atr
+	ema    Ema
+	count  int
+	haOpen float64 // of the newest Heiken Ashi candle
+	value  AtrTrailValue
+}
+
+func NewAtrTrail(atrPeriod int, emaPeriod int, mult float64) *AtrTrail {
+	return &AtrTrail{mult: mult, atr: *NewAtr(atrPeriod), ema: *NewEma(emaPeriod, Close)}
+}
+
+func (a *AtrTrail) Update(c models.Candle) {
+	a.count++
+	haClose := (c.Open + c.High + c.Low + c.Close) / 4
+	haOpen := (c.Open + c.Close) / 2
+	if a.count > 1 {
+		haOpen = (a.haOpen + a.value.Close) / 2
+	}
+	ha := models.Candle{
+		Open:  haOpen,
+		High:  math.Max(c.High, math.Max(haOpen, haClose)),
+		Low:   math.Min(c.Low, math.Min(haOpen, haClose)),
+		Close: haClose,
+	}
+	a.atr.Update(ha)
+	a.ema.Update(ha)
+	atr, _ := a.atr.Value()
+	ema, _ := a.ema.Value()
+
+	v := AtrTrailValue{Close: haClose, PrevClose: a.value.Close, PrevLine: a.value.Line, Atr: atr, Ema: ema}
+	if a.count > 1 {
+		v.Line = a.getLine(haClose, atr)
+	}
+	a.haOpen = haOpen
+	a.value = v
+}
+
+func (a *AtrTrail) getLine(haClose float64, atr float64) float64 {
+	nLoss := a.mult * atr
+	prev, prevClose := a.value.Line, a.value.Close
+	switch {
+	case haClose > prev && prevClose > prev:
+		return math.Max(prev, haClose-nLoss)
+	case haClose < prev && prevClose < prev:
+		return math.Min(prev, haClose+nLoss)
+	case haClose > prev:
+		return haClose - nLoss
+	default:
+		return haClose + nLoss
+	}
+}
+
+func (a *AtrTrail) Value() (AtrTrailValue, bool) {
+	_, atrOk := a.atr.Value()
+	_, emaOk := a.ema.Value()
+	return a.value, atrOk && emaOk
+}
+
+func (a *AtrTrail) Peek(c models.Candle) (AtrTrailValue, bool) {
+	next := *a
+	next.Update(c)
+	return next.Value()
+}
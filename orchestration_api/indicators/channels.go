@@ -0,0 +1,86 @@
+package indicators
+
+import (
+	"math"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+type Bands struct {
+	Upper  float64
+	Middle float64
+	Lower  float64
+}
+
+// Donchian is the highest high and lowest low of the last period candles, Middle halfway between.
+type Donchian struct {
+	period int
+	highs  *extremum
+	lows   *extremum
+	count  int
+}
+
+func NewDonchian(period int) *Donchian {
+	return &Donchian{period: period, highs: newExtremum(period, true), lows: newExtremum(period, false)}
+}
+
+func (d *Donchian) Update(c models.Candle) {
+	d.highs.push(c.High)
+	d.lows.push(c.Low)
+	d.count++
+}
+
+func (d *Donchian) Value() (Bands, bool) {
+	if d.count < d.period {
+		return Bands{}, false
+	}
+	return getDonchianBands(d.highs.value(), d.lows.value()), true
+}
+
+func (d *Donchian) Peek(c models.Candle) (Bands, bool) {
+	if d.count+1 < d.period {
+		return Bands{}, false
+	}
+	return getDonchianBands(d.highs.peek(c.High), d.lows.peek(c.Low)), true
+}
+
+func getDonchianBands(upper float64, lower float64) Bands {
+	return Bands{Upper: upper, Middle: (upper + lower) / 2, Lower: lower}
+}
+
+// Bollinger is the simple moving average of the last period closes with bands mult population standard
+// deviations either side, what talib.BBands gives with an SMA.
+type Bollinger struct {
+	mult   float64
+	window *window
+}
+
+func NewBollinger(period int, mult float64) *Bollinger {
+	return &Bollinger{mult: mult, window: newWindow(period)}
+}
+
+func (b *Bollinger) Update(c models.Candle) {
+	b.window.push(c.Close)
+}
+
+func (b *Bollinger) Value() (Bands, bool) {
+	if !b.window.isFull() {
+		return Bands{}, false
+	}
+	return b.getBands(b.window.sum, b.window.sumSq, b.window.period), true
+}
+
+func (b *Bollinger) Peek(c models.Candle) (Bands, bool) {
+	sum, sumSq, n := b.window.peek(c.Close)
+	if n < b.window.period {
+		return Bands{}, false
+	}
+	return b.getBands(sum, sumSq, n), true
+}
+
+func (b *Bollinger) getBands(sum float64, sumSq float64, n int) Bands {
+	mean := sum / float64(n)
+	// the running sums can leave a flat window a hair below zero
+	dev := math.Sqrt(math.Max(sumSq/float64(n)-mean*mean, 0))
+	return Bands{Upper: mean + b.mult*dev, Middle: mean, Lower: mean - b.mult*dev}
+}
@@ -0,0 +1,94 @@
+package indicators
+
+import "github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+
+type DonchianFibValue struct {
+	Bands
+	PrevUpper float64 // the channel on the candle before, for breakouts
+	PrevLower float64
+	High      float64 // the 23.6% retracement from the top of the channel
+	Low       float64 // the 76.4% retracement
+	// LastHighPullback and LastLowPullback are the retracements the channel had when a run at its top, or
+	// bottom, last started.
+	LastHighPullback float64
+	LastLowPullback  float64
+	// BrokeHigh is whether the top of the channel broke more recently than the bottom.
+	BrokeHigh bool
+}
+
+// DonchianFib is the Donchian channel with its Fibonacci retracements and which side last broke out: the top
+// breaks when a high clears a channel top that held flat for three candles, or the candle before made the new
+// top, and the bottom likewise.
+type DonchianFib struct {
+	donchian *Donchian
+	state    donchianFibState
+}
+
+// donchianFibState is everything but the channel, plain values so a peek can step a copy.
+type donchianFibState struct {
+	count    int
+	uppers   [3]float64 // the channel's top on the three candles before, oldest first
+	lowers   [3]float64
+	prevHigh float64
+	prevLow  float64
+	value    DonchianFibValue
+}
+
+func NewDonchianFib(period int) *DonchianFib {
+	return &DonchianFib{donchian: NewDonchian(period)}
+}
+
+func (d *DonchianFib) Update(c models.Candle) {
+	d.donchian.Update(c)
+	bands, _ := d.donchian.Value()
+	d.state = d.state.next(c, bands)
+}
+
+func (d *DonchianFib) Value() (DonchianFibValue, bool) {
+	return d.state.value, d.donchian.count >= d.donchian.period
+}
+
+func (d *DonchianFib) Peek(c models.Candle) (DonchianFibValue, bool) {
+	bands, ok := d.donchian.Peek(c)
+	return d.state.next(c, bands).value, ok
+}
+
+// next is the state after c, bands being the channel with c in it, zero while the channel isn't full.
+func (s donchianFibState) next(c models.Candle, bands Bands) donchianFibState {
+	dist := bands.Upper - bands.Lower
+	v := DonchianFibValue{
+		Bands:            bands,
+		PrevUpper:        s.uppers[2],
+		PrevLower:        s.lowers[2],
+		High:             bands.Upper - dist*0.236,
+		Low:              bands.Upper - dist*0.764,
+		LastHighPullback: s.value.LastHighPullback,
+		LastLowPullback:  s.value.LastLowPullback,
+		BrokeHigh:        s.value.BrokeHigh,
+	}
+	if s.count >= 3 {
+		breaksHigh := s.uppers[0] == s.uppers[1] && s.uppers[1] == s.uppers[2] && c.High > s.uppers[2]
+		breaksLow := s.lowers[0] == s.lowers[1] && s.lowers[1] == s.lowers[2] && c.Low < s.lowers[2]
+		highRun := breaksHigh || s.prevHigh == bands.Upper
+		lowRun := breaksLow || s.prevLow == bands.Lower
+		if highRun {
+			v.LastHighPullback = v.High
+		}
+		if lowRun {
+			v.LastLowPullback = v.Low
+		}
+		if highRun {
+			v.BrokeHigh = true
+		} else if lowRun {
+			v.BrokeHigh = false
+		}
+	}
+	return donchianFibState{
+		count:    s.count + 1,
+		uppers:   [3]float64{s.uppers[1], s.uppers[2], bands.Upper},
+		lowers:   [3]float64{s.lowers[1], s.lowers[2], bands.Lower},
+		prevHigh: c.High,
+		prevLow:  c.Low,
+		value:    v,
+	}
+}
@@ -0,0 +1,206 @@
+package indicators
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+	talib "github.com/markcheno/go-talib"
+)
+
+// randomCandles is n 1m candles of a random walk, with a few flat ones so zero ranges get hit.
+func randomCandles(n int, seed uint64) []models.Candle {
+	rng := rand.New(rand.NewPCG(seed, seed))
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	candles := make([]models.Candle, n)
+	price := 100.0
+	for i := range candles {
+		open := price
+		high, low := open, open
+		if rng.IntN(20) != 0 {
+			price += rng.NormFloat64()
+			high = math.Max(open, price) + rng.Float64()
+			low = math.Min(open, price) - rng.Float64()
+		}
+		candles[i] = models.Candle{
+			Start:  start.Add(time.Duration(i) * time.Minute),
+			Open:   open,
+			High:   high,
+			Low:    low,
+			Close:  price,
+			Volume: 100 + 50*rng.Float64(),
+		}
+	}
+	return candles
+}
+
+type series struct {
+	highs, lows, closes, volumes []float64
+}
+
+func getSeries(candles []models.Candle) series {
+	var s series
+	for _, c := range candles {
+		s.highs = append(s.highs, c.High)
+		s.lows = append(s.lows, c.Low)
+		s.closes = append(s.closes, c.Close)
+		s.volumes = append(s.volumes, c.Volume)
+	}
+	return s
+}
+
+// picked reads one number off an indicator with a compound value.
+type picked[T any] struct {
+	valuer[T]
+	pick func(T) float64
+}
+
+func (p picked[T]) Value() (float64, bool) {
+	v, ok := p.valuer.Value()
+	return p.pick(v), ok
+}
+
+func (p picked[T]) Peek(c models.Candle) (float64, bool) {
+	v, ok := p.valuer.Peek(c)
+	return p.pick(v), ok
+}
+
+func pick[T any](ind valuer[T], f func(T) float64) valuer[float64] {
+	return picked[T]{valuer: ind, pick: f}
+}
+
+func isNear(a float64, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+}
+
+// Every indicator has to agree with talib over the same candles from the first index talib gives a value at, and
+// peeking at a candle has to read what updating with it does.
+func TestTalibParity(t *testing.T) {
+	candles := randomCandles(600, 1)
+	s := getSeries(candles)
+	bbUpper, _, bbLower := talib.BBands(s.closes, 20, 2, 2, talib.SMA)
+	macd, macdSignal, macdHist := talib.Macd(s.closes, 12, 26, 9)
+	slowMacd, _, _ := talib.Macd(s.closes, 26, 12, 9)
+	stochK, stochD := talib.Stoch(s.highs, s.lows, s.closes, 14, 3, talib.SMA, 3, talib.SMA)
+
+	tests := []struct {
+		name     string
+		ind      valuer[float64]
+		want     []float64
+		lookback int // the first index with a value
+	}{
+		{name: "sma", ind: NewSma(14, Close), want: talib.Sma(s.closes, 14), lookback: 13},
+		{name: "volume sma", ind: NewSma(20, Volume), want: talib.Sma(s.volumes, 20), lookback: 19},
+		{name: "ema", ind: NewEma(10, Close), want: talib.Ema(s.closes, 10), lookback: 9},
+		{name: "atr", ind: NewAtr(14), want: talib.Atr(s.highs, s.lows, s.closes, 14), lookback: 14},
+		{name: "rsi", ind: NewRsi(14), want: talib.Rsi(s.closes, 14), lookback: 14},
+		{name: "adx", ind: NewAdx(14), want: talib.Adx(s.highs, s.lows, s.closes, 14), lookback: 27},
+		{name: "adx period 1", ind: NewAdx(1), want: talib.Adx(s.highs, s.lows, s.closes, 1), lookback: 1},
+		{name: "bollinger upper", ind: pick(NewBollinger(20, 2), func(b Bands) float64 { return b.Upper }), want: bbUpper, lookback: 19},
+		{name: "bollinger lower", ind: pick(NewBollinger(20, 2), func(b Bands) float64 { return b.Lower }), want: bbLower, lookback: 19},
+		{name: "donchian upper", ind: pick(NewDonchian(20), func(b Bands) float64 { return b.Upper }), want: talib.Max(s.highs, 20), lookback: 19},
+		{name: "donchian lower", ind: pick(NewDonchian(20), func(b Bands) float64 { return b.Lower }), want: talib.Min(s.lows, 20), lookback: 19},
+		{name: "macd", ind: pick(NewMacd(12, 26, 9), func(m MacdValue) float64 { return m.Macd }), want: macd, lookback: 33},
+		{name: "macd signal", ind: pick(NewMacd(12, 26, 9), func(m MacdValue) float64 { return m.Signal }), want: macdSignal, lookback: 33},
+		{name: "macd histogram", ind: pick(NewMacd(12, 26, 9), func(m MacdValue) float64 { return m.Hist }), want: macdHist, lookback: 33},
+		{name: "macd periods swapped", ind: pick(NewMacd(26, 12, 9), func(m MacdValue) float64 { return m.Macd }), want: slowMacd, lookback: 33},
+		{name: "stoch k", ind: pick(NewStoch(14, 3, 3), func(v StochValue) float64 { return v.K }), want: stochK, lookback: 17},
+		{name: "stoch d", ind: pick(NewStoch(14, 3, 3), func(v StochValue) float64 { return v.D }), want: stochD, lookback: 17},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, c := range candles {
+				peeked, peekedOk := tt.ind.Peek(c)
+				tt.ind.Update(c)
+				got, ok := tt.ind.Value()
+				if ok != (i >= tt.lookback) {
+					t.Fatalf("index %d: ok %v, want %v", i, ok, i >= tt.lookback)
+				}
+				// the windows add their sums up afresh as they wrap, so a peek can be a rounding off
+				if !isNear(peeked, got) || peekedOk != ok {
+					t.Fatalf("index %d: peeked %v %v, updated to %v %v", i, peeked, peekedOk, got, ok)
+				}
+				if ok && !isNear(got, tt.want[i]) {
+					t.Fatalf("index %d: %v, talib %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// A set has to read the same with the newest candle forming as with it closed, whether the indicator was asked
+// for before the candles came in or warmed up on them after.
+func TestSet(t *testing.T) {
+	candles := randomCandles(300, 2)
+	s := getSeries(candles)
+	ema := talib.Ema(s.closes, 20)
+
+	live := NewSet(candles[:1])
+	live.Ema(20) // registered before the candles, updated as they close
+	for i, c := range candles[1:] {
+		// every candle comes in twice, the second time as it closes
+		live.Ingest(models.Candle{Start: c.Start, Open: c.Open, High: c.Open, Low: c.Open, Close: c.Open})
+		live.Ingest(c)
+		if got := live.Count(); got != i+2 {
+			t.Fatalf("count %d, want %d", got, i+2)
+		}
+	}
+	replay := NewSet(nil)
+	for _, c := range candles {
+		replay.Update(c)
+	}
+
+	for name, set := range map[string]*Set{"forming": live, "closed": replay, "from history": NewSet(candles)} {
+		t.Run(name, func(t *testing.T) {
+			if got := set.Count(); got != len(candles) {
+				t.Errorf("count %d, want %d", got, len(candles))
+			}
+			last := set.Last(3)
+			if len(last) != 3 || last[2] != candles[len(candles)-1] || last[0] != candles[len(candles)-3] {
+				t.Errorf("last 3 %v, want the newest three candles", last)
+			}
+			if c, ok := set.Candle(); !ok || c != candles[len(candles)-1] {
+				t.Errorf("candle %v, want the newest", c)
+			}
+			if got, ok := set.Ema(20); !ok || !isNear(got, ema[len(ema)-1]) {
+				t.Errorf("ema %v, want %v", got, ema[len(ema)-1])
+			}
+			if got, ok := set.PrevMa("EMA", 20); !ok || !isNear(got, ema[len(ema)-2]) {
+				t.Errorf("previous ema %v, want %v", got, ema[len(ema)-2])
+			}
+			if _, ok := set.Macd(12, 26, 0); ok {
+				t.Error("macd with no signal period has a value")
+			}
+		})
+	}
+}
+
+func TestSetLast(t *testing.T) {
+	candles := randomCandles(5, 3)
+	tests := []struct {
+		name string
+		set  *Set
+		n    int
+		want []models.Candle
+	}{
+		{name: "empty", set: NewSet(nil), n: 3, want: []models.Candle{}},
+		{name: "fewer than asked", set: NewSet(candles[:2]), n: 3, want: candles[:2]},
+		{name: "forming last", set: NewSet(candles), n: 2, want: candles[3:]},
+		{name: "none asked", set: NewSet(candles), n: 0, want: []models.Candle{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.set.Last(tt.n)
+			if len(got) != len(tt.want) {
+				t.Fatalf("%d candles, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("candle %d is %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
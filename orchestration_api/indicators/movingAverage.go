@@ -0,0 +1,114 @@
+package indicators
+
+import "github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+
+// Source picks the value of a candle an indicator averages.
+type Source func(c models.Candle) float64
+
+func Close(c models.Candle) float64  { return c.Close }
+func Volume(c models.Candle) float64 { return c.Volume }
+
+// Sma is the simple moving average of the last period values.
+type Sma struct {
+	source Source
+	window *window
+}
+
+func NewSma(period int, source Source) *Sma {
+	return &Sma{source: source, window: newWindow(period)}
+}
+
+func (s *Sma) Update(c models.Candle) {
+	s.window.push(s.source(c))
+}
+
+func (s *Sma) Value() (float64, bool) {
+	if !s.window.isFull() {
+		return 0, false
+	}
+	return s.window.sum / float64(s.window.period), true
+}
+
+// Peek is the average if c were the next candle.
+func (s *Sma) Peek(c models.Candle) (float64, bool) {
+	sum, _, n := s.window.peek(s.source(c))
+	if n < s.window.period {
+		return 0, false
+	}
+	return sum / float64(n), true
+}
+
+// Ema is the exponential moving average, seeded with the average of the first period values the way talib is, so
+// both agree over the same candles.
+type Ema struct {
+	source Source
+	period int
+	k      float64
+	count  int
+	sum    float64
+	value  float64
+}
+
+func NewEma(period int, source Source) *Ema {
+	return &Ema{source: source, period: period, k: 2 / float64(period+1)}
+}
+
+func (e *Ema) Update(c models.Candle) {
+	x := e.source(c)
+	e.count++
+	switch {
+	case e.count < e.period:
+		e.sum += x
+	case e.count == e.period:
+		e.value = (e.sum + x) / float64(e.period)
+	default:
+		e.value += e.k * (x - e.value)
+	}
+}
+
+func (e *Ema) Value() (float64, bool) {
+	return e.value, e.count >= e.period
+}
+
+func (e *Ema) Peek(c models.Candle) (float64, bool) {
+	next := *e
+	next.Update(c)
+	return next.Value()
+}
+
+// Smma is the smoothed moving average, Wilder's: seeded with the average of the first period values, then each
+// value weighs in at one period-th.
+type Smma struct {
+	source Source
+	period int
+	count  int
+	sum    float64
+	value  float64
+}
+
+func NewSmma(period int, source Source) *Smma {
+	return &Smma{source: source, period: period}
+}
+
+func (s *Smma) Update(c models.Candle) {
+	x := s.source(c)
+	s.count++
+	switch {
+	case s.count < s.period:
+		s.sum += x
+	case s.count == s.period:
+		s.value = (s.sum + x) / float64(s.period)
+	default:
+		s.value = (s.value*float64(s.period-1) + x) / float64(s.period)
+	}
+}
+
+func (s *Smma) Value() (float64, bool) {
+	return s.value, s.count >= s.period
+}
+
+func (s *Smma) Peek(c models.Candle) (float64, bool) {
+	next := *s
+	next.Update(c)
+	return next.Value()
+}
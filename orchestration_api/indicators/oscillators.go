@@ -0,0 +1,131 @@
+package indicators
+
+import "github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+
+type MacdValue struct {
+	Macd   float64 // the fast EMA less the slow one
+	Signal float64 // the EMA of Macd
+	Hist   float64 // Macd less Signal
+}
+
+// Macd is the moving average convergence divergence of the closes the way talib.Macd has it: the longer of fast
+// and slow is the slow EMA, and the signal line averages a MACD line that reads 0 until talib would first output
+// it, so both agree over the same candles.
+type Macd struct {
+	fast     Ema
+	slow     Ema
+	signal   int
+	k        float64
+	lookback int // candles before talib's first value
+	count    int
+	sum      float64 // of the MACD line, the signal line's seed
+	value    MacdValue
+}
+
+func NewMacd(fast int, slow int, signal int) *Macd {
+	if slow < fast {
+		fast, slow = slow, fast
+	}
+	return &Macd{
+		fast:     *NewEma(fast, Close),
+		slow:     *NewEma(slow, Close),
+		signal:   signal,
+		k:        2 / float64(signal+1),
+		lookback: signal - 1 + slow - 1,
+	}
+}
+
+func (m *Macd) Update(c models.Candle) {
+	m.fast.Update(c)
+	m.slow.Update(c)
+	m.count++
+	macd := 0.0
+	if m.count >= m.lookback {
+		macd = m.fast.value - m.slow.value
+	}
+	switch {
+	case m.count < m.signal:
+		m.sum += macd
+	case m.count == m.signal:
+		m.value.Signal = (m.sum + macd) / float64(m.signal)
+	default:
+		m.value.Signal += m.k * (macd - m.value.Signal)
+	}
+	m.value.Macd = macd
+	m.value.Hist = macd - m.value.Signal
+}
+
+func (m *Macd) Value() (MacdValue, bool) {
+	return m.value, m.count > m.lookback
+}
+
+func (m *Macd) Peek(c models.Candle) (MacdValue, bool) {
+	next := *m
+	next.Update(c)
+	return next.Value()
+}
+
+type StochValue struct {
+	K float64 // slow %K
+	D float64
+}
+
+// Stoch is the slow stochastic, talib.Stoch with SMAs: fast %K over kPeriod candles, smoothed over kSmooth
+// candles for %K and that over dPeriod for %D. A candle whose range is flat reads 0.
+type Stoch struct {
+	kPeriod int
+	highs   *extremum
+	lows    *extremum
+	count   int
+	k       *window // fast %K
+	d       *window // slow %K
+}
+
+func NewStoch(kPeriod int, kSmooth int, dPeriod int) *Stoch {
+	return &Stoch{kPeriod: kPeriod, highs: newExtremum(kPeriod, true), lows: newExtremum(kPeriod, false), k: newWindow(kSmooth), d: newWindow(dPeriod)}
+}
+
+func (s *Stoch) Update(c models.Candle) {
+	s.highs.push(c.High)
+	s.lows.push(c.Low)
+	s.count++
+	if s.count < s.kPeriod {
+		return
+	}
+	s.k.push(getFastK(c.Close, s.highs.value(), s.lows.value()))
+	if s.k.isFull() {
+		s.d.push(s.k.sum / float64(s.k.period))
+	}
+}
+
+func (s *Stoch) Value() (StochValue, bool) {
+	if !s.d.isFull() {
+		return StochValue{}, false
+	}
+	return StochValue{K: s.k.sum / float64(s.k.period), D: s.d.sum / float64(s.d.period)}, true
+}
+
+func (s *Stoch) Peek(c models.Candle) (StochValue, bool) {
+	if s.count+1 < s.kPeriod {
+		return StochValue{}, false
+	}
+	kSum, _, kn := s.k.peek(getFastK(c.Close, s.highs.peek(c.High), s.lows.peek(c.Low)))
+	if kn < s.k.period {
+		return StochValue{}, false
+	}
+	slowK := kSum / float64(kn)
+	dSum, _, dn := s.d.peek(slowK)
+	if dn < s.d.period {
+		return StochValue{}, false
+	}
+	return StochValue{K: slowK, D: dSum / float64(dn)}, true
+}
+
+// getFastK divides as talib does, so the two agree to the last bit.
+func getFastK(close float64, highest float64, lowest float64) float64 {
+	diff := (highest - lowest) / 100
+	if diff == 0 {
+		return 0
+	}
+	return (close - lowest) / diff
+}
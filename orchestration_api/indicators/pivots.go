@@ -0,0 +1,162 @@
+package indicators
+
+import (
+	"math"
+	"slices"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+// pivotWindow is the highs and lows of the last 2*length+1 candles, oldest first. Once full, the candle in the
+// middle has length candles either side and can be judged a pivot.
+type pivotWindow struct {
+	length int
+	count  int // candles pushed, the index of the next one
+	highs  []float64
+	lows   []float64
+}
+
+func newPivotWindow(length int) pivotWindow {
+	return pivotWindow{length: length, highs: make([]float64, 0, 2*length+2), lows: make([]float64, 0, 2*length+2)}
+}
+
+func (w *pivotWindow) push(c models.Candle) {
+	w.count++
+	w.highs = append(w.highs, c.High)
+	w.lows = append(w.lows, c.Low)
+	if len(w.highs) > 2*w.length+1 {
+		w.highs = append(w.highs[:0], w.highs[1:]...)
+		w.lows = append(w.lows[:0], w.lows[1:]...)
+	}
+}
+
+func (w *pivotWindow) isFull() bool {
+	return len(w.highs) == 2*w.length+1
+}
+
+// center is the index of the candle in the middle.
+func (w *pivotWindow) center() int {
+	return w.count - 1 - w.length
+}
+
+func (w pivotWindow) clone() pivotWindow {
+	w.highs = slices.Clone(w.highs)
+	w.lows = slices.Clone(w.lows)
+	return w
+}
+
+type pivot struct {
+	index int
+	price float64
+}
+
+type TrendlinesValue struct {
+	// Up is the line through the last two pivot lows at the newest candle and PrevUp at the one before, NaN
+	// unless the second low is the higher. Down is the line through the last two pivot highs, NaN unless the
+	// second high is the lower.
+	Up       float64
+	PrevUp   float64
+	Down     float64
+	PrevDown float64
+}
+
+// Trendlines are the lines through the last two pivot lows and the last two pivot highs, a pivot being the lowest
+// low, or highest high, of the pivotLen candles either side of it, ties included.
+type Trendlines struct {
+	window pivotWindow
+	lows   [2]*pivot // older first
+	highs  [2]*pivot
+}
+
+func NewTrendlines(pivotLen int) *Trendlines {
+	return &Trendlines{window: newPivotWindow(pivotLen)}
+}
+
+func (t *Trendlines) Update(c models.Candle) {
+	t.window.push(c)
+	if !t.window.isFull() {
+		return
+	}
+	mid := t.window.length
+	if low := t.window.lows[mid]; low == slices.Min(t.window.lows) {
+		t.lows = [2]*pivot{t.lows[1], {index: t.window.center(), price: low}}
+	}
+	if high := t.window.highs[mid]; high == slices.Max(t.window.highs) {
+		t.highs = [2]*pivot{t.highs[1], {index: t.window.center(), price: high}}
+	}
+}
+
+func (t *Trendlines) Value() (TrendlinesValue, bool) {
+	newest := float64(t.window.count - 1)
+	v := TrendlinesValue{Up: math.NaN(), PrevUp: math.NaN(), Down: math.NaN(), PrevDown: math.NaN()}
+	if t.lows[0] != nil && t.lows[1].price > t.lows[0].price {
+		v.Up = getLinePrice(t.lows, newest)
+		v.PrevUp = getLinePrice(t.lows, newest-1)
+	}
+	if t.highs[0] != nil && t.highs[1].price < t.highs[0].price {
+		v.Down = getLinePrice(t.highs, newest)
+		v.PrevDown = getLinePrice(t.highs, newest-1)
+	}
+	return v, t.window.count >= 2
+}
+
+func (t *Trendlines) Peek(c models.Candle) (TrendlinesValue, bool) {
+	next := Trendlines{window: t.window.clone(), lows: t.lows, highs: t.highs}
+	next.Update(c)
+	return next.Value()
+}
+
+func getLinePrice(pivots [2]*pivot, index float64) float64 {
+	x1, y1 := float64(pivots[0].index), pivots[0].price
+	x2, y2 := float64(pivots[1].index), pivots[1].price
+	return y2 + (y2-y1)/(x2-x1)*(index-x2)
+}
+
+type SwingPivotsValue struct {
+	High float64 // the most recent pivot high, NaN until there's been one
+	Low  float64
+}
+
+// SwingPivots are the most recent pivot high and low, a pivot being higher, or lower, than each of the length
+// candles either side of it, what Pine's ta.pivothigh and ta.pivotlow find.
+type SwingPivots struct {
+	window pivotWindow
+	value  SwingPivotsValue
+}
+
+func NewSwingPivots(length int) *SwingPivots {
+	return &SwingPivots{window: newPivotWindow(length), value: SwingPivotsValue{High: math.NaN(), Low: math.NaN()}}
+}
+
+func (s *SwingPivots) Update(c models.Candle) {
+	s.window.push(c)
+	if !s.window.isFull() {
+		return
+	}
+	mid := s.window.length
+	isHigh, isLow := true, true
+	for j, high := range s.window.highs {
+		if j != mid && high >= s.window.highs[mid] {
+			isHigh = false
+		}
+		if j != mid && s.window.lows[j] <= s.window.lows[mid] {
+			isLow = false
+		}
+	}
+	if isHigh {
+		s.value.High = s.window.highs[mid]
+	}
+	if isLow {
+		s.value.Low = s.window.lows[mid]
+	}
+}
+
+func (s *SwingPivots) Value() (SwingPivotsValue, bool) {
+	return s.value, s.window.isFull()
+}
+
+func (s *SwingPivots) Peek(c models.Candle) (SwingPivotsValue, bool) {
+	next := SwingPivots{window: s.window.clone(), value: s.value}
+	next.Update(c)
+	return next.Value()
+}
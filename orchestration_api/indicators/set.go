@@ -0,0 +1,292 @@
+// Package indicators keeps technical indicators up to date one candle at a time, so reading one costs the same
+// however long the history behind it is.
+package indicators
+
+import (
+	"sync"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+// maxCandles is how many closed candles a Set keeps for indicators asked for after it was built to warm up on,
+// plenty for Wilder averages and long EMAs to settle.
+const maxCandles = 1000
+
+type indicator interface {
+	Update(c models.Candle)
+}
+
+type valuer[T any] interface {
+	indicator
+	Value() (T, bool)
+	Peek(c models.Candle) (T, bool)
+}
+
+type key struct {
+	name    string
+	period  int
+	period2 int // the indicators with more than one length
+	period3 int
+	factor  float64
+}
+
+// Set is one symbol's indicators at its trader's candle size. An indicator is registered the first time it is
+// asked for, warmed up on the candles the set has kept, and from then on updated as each candle closes. Like
+// GetCandleHistory the values take in the candle still forming, which only costs a step of each one read.
+// The second return of every getter is false until the indicator has seen enough candles.
+type Set struct {
+	mu         sync.Mutex
+	candles    []models.Candle // closed, oldest first
+	forming    *models.Candle
+	closed     int // candles that have closed since the set started, the ones dropped from candles included
+	indicators map[key]indicator
+}
+
+// NewSet starts a set from a history the way the price action store hands it out, oldest first with the newest
+// candle still forming.
+func NewSet(history []models.Candle) *Set {
+	s := &Set{indicators: make(map[key]indicator)}
+	if len(history) == 0 {
+		return s
+	}
+	s.candles = append([]models.Candle(nil), history[max(len(history)-1-maxCandles, 0):len(history)-1]...)
+	s.closed = len(history) - 1
+	forming := history[len(history)-1]
+	s.forming = &forming
+	return s
+}
+
+// Update adds a closed candle.
+func (s *Set) Update(c models.Candle) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.forming = nil
+	s.update(c)
+}
+
+// Ingest takes the symbol's current candle, still forming. The one it had before closed when c is of a later
+// bucket.
+func (s *Set) Ingest(c models.Candle) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.forming != nil {
+		if c.Start.Before(s.forming.Start) {
+			return
+		}
+		if c.Start.After(s.forming.Start) {
+			s.update(*s.forming)
+		}
+	}
+	s.forming = &c
+}
+
+func (s *Set) update(c models.Candle) {
+	s.closed++
+	s.candles = append(s.candles, c)
+	if len(s.candles) > 2*maxCandles {
+		s.candles = append(s.candles[:0], s.candles[len(s.candles)-maxCandles:]...)
+	}
+	for _, ind := range s.indicators {
+		ind.Update(c)
+	}
+}
+
+// Candle is the newest candle, the forming one when there is one.
+func (s *Set) Candle() (models.Candle, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.forming != nil {
+		return *s.forming, true
+	}
+	if len(s.candles) == 0 {
+		return models.Candle{}, false
+	}
+	return s.candles[len(s.candles)-1], true
+}
+
+// Count is how many candles the set has seen, the forming one included, what a strategy's warm-up is measured
+// against.
+func (s *Set) Count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.forming != nil {
+		return s.closed + 1
+	}
+	return s.closed
+}
+
+// Last is the newest n candles, oldest first and the forming one last when there is one. Fewer when the set holds
+// fewer, it keeps maxCandles at least.
+func (s *Set) Last(n int) []models.Candle {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	closed := n
+	if s.forming != nil {
+		closed--
+	}
+	closed = max(min(closed, len(s.candles)), 0)
+	out := make([]models.Candle, 0, closed+1)
+	out = append(out, s.candles[len(s.candles)-closed:]...)
+	if s.forming != nil && n > 0 {
+		out = append(out, *s.forming)
+	}
+	return out
+}
+
+func (s *Set) Sma(period int) (float64, bool) {
+	return get(s, key{name: "sma", period: period}, func() *Sma { return NewSma(period, Close) })
+}
+
+func (s *Set) VolumeSma(period int) (float64, bool) {
+	return get(s, key{name: "volumeSma", period: period}, func() *Sma { return NewSma(period, Volume) })
+}
+
+func (s *Set) Ema(period int) (float64, bool) {
+	return get(s, key{name: "ema", period: period}, func() *Ema { return NewEma(period, Close) })
+}
+
+func (s *Set) Atr(period int) (float64, bool) {
+	return get(s, key{name: "atr", period: period}, func() *Atr { return NewAtr(period) })
+}
+
+func (s *Set) Rsi(period int) (float64, bool) {
+	return get(s, key{name: "rsi", period: period}, func() *Rsi { return NewRsi(period) })
+}
+
+func (s *Set) Donchian(period int) (Bands, bool) {
+	return get(s, key{name: "donchian", period: period}, func() *Donchian { return NewDonchian(period) })
+}
+
+func (s *Set) Bollinger(period int, mult float64) (Bands, bool) {
+	return get(s, key{name: "bollinger", period: period, factor: mult}, func() *Bollinger { return NewBollinger(period, mult) })
+}
+
+func (s *Set) Supertrend(period int, factor float64) (SupertrendValue, bool) {
+	return get(s, key{name: "supertrend", period: period, factor: factor}, func() *Supertrend { return NewSupertrend(period, factor) })
+}
+
+func (s *Set) Smma(period int) (float64, bool) {
+	return get(s, key{name: "smma", period: period}, func() *Smma { return NewSmma(period, Close) })
+}
+
+// Ma is the moving average of the closes by its type, SMA, EMA or SMMA, anything else being an SMA.
+func (s *Set) Ma(maType string, period int) (float64, bool) {
+	switch maType {
+	case "EMA":
+		return s.Ema(period)
+	case "SMMA":
+		return s.Smma(period)
+	default:
+		return s.Sma(period)
+	}
+}
+
+// PrevMa is Ma as it was on the candle before the newest, for crosses.
+func (s *Set) PrevMa(maType string, period int) (float64, bool) {
+	switch maType {
+	case "EMA":
+		return getPrev(s, key{name: "ema", period: period}, func() *Ema { return NewEma(period, Close) })
+	case "SMMA":
+		return getPrev(s, key{name: "smma", period: period}, func() *Smma { return NewSmma(period, Close) })
+	default:
+		return getPrev(s, key{name: "sma", period: period}, func() *Sma { return NewSma(period, Close) })
+	}
+}
+
+func (s *Set) Macd(fast int, slow int, signal int) (MacdValue, bool) {
+	if min(slow, signal) < 1 {
+		return MacdValue{}, false
+	}
+	return get(s, key{name: "macd", period: fast, period2: slow, period3: signal}, func() *Macd { return NewMacd(fast, slow, signal) })
+}
+
+func (s *Set) Stoch(kPeriod int, kSmooth int, dPeriod int) (StochValue, bool) {
+	if min(kSmooth, dPeriod) < 1 {
+		return StochValue{}, false
+	}
+	return get(s, key{name: "stoch", period: kPeriod, period2: kSmooth, period3: dPeriod}, func() *Stoch { return NewStoch(kPeriod, kSmooth, dPeriod) })
+}
+
+func (s *Set) Adx(period int) (float64, bool) {
+	return get(s, key{name: "adx", period: period}, func() *Adx { return NewAdx(period) })
+}
+
+func (s *Set) DonchianFib(period int) (DonchianFibValue, bool) {
+	return get(s, key{name: "donchianFib", period: period}, func() *DonchianFib { return NewDonchianFib(period) })
+}
+
+func (s *Set) AtrTrail(atrPeriod int, emaPeriod int, mult float64) (AtrTrailValue, bool) {
+	if emaPeriod < 1 {
+		return AtrTrailValue{}, false
+	}
+	return get(s, key{name: "atrTrail", period: atrPeriod, period2: emaPeriod, factor: mult}, func() *AtrTrail { return NewAtrTrail(atrPeriod, emaPeriod, mult) })
+}
+
+func (s *Set) Activator(period int, mult float64) (ActivatorValue, bool) {
+	return get(s, key{name: "activator", period: period, factor: mult}, func() *Activator { return NewActivator(period, mult) })
+}
+
+func (s *Set) Trendlines(pivotLen int) (TrendlinesValue, bool) {
+	return get(s, key{name: "trendlines", period: pivotLen}, func() *Trendlines { return NewTrendlines(pivotLen) })
+}
+
+func (s *Set) SwingPivots(length int) (SwingPivotsValue, bool) {
+	return get(s, key{name: "swingPivots", period: length}, func() *SwingPivots { return NewSwingPivots(length) })
+}
+
+// tracked is an indicator with the value it had before the newest closed candle, for reading the candle before
+// the newest when no candle is forming.
+type tracked[T any, I valuer[T]] struct {
+	ind    I
+	prev   T
+	prevOk bool
+}
+
+func (t *tracked[T, I]) Update(c models.Candle) {
+	t.prev, t.prevOk = t.ind.Value()
+	t.ind.Update(c)
+}
+
+// get is the indicator under k with the newest candle taken in. A period under 1 has no value.
+func get[T any, I valuer[T]](s *Set, k key, create func() I) (T, bool) {
+	var zero T
+	if k.period < 1 {
+		return zero, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := lookup[T](s, k, create)
+	if s.forming != nil {
+		return t.ind.Peek(*s.forming)
+	}
+	return t.ind.Value()
+}
+
+// getPrev is the indicator under k as of the candle before the newest.
+func getPrev[T any, I valuer[T]](s *Set, k key, create func() I) (T, bool) {
+	var zero T
+	if k.period < 1 {
+		return zero, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := lookup[T](s, k, create)
+	if s.forming != nil {
+		return t.ind.Value()
+	}
+	return t.prev, t.prevOk
+}
+
+// lookup is the indicator under k, registered with create and warmed up when the set doesn't have it yet. The
+// caller holds s.mu.
+func lookup[T any, I valuer[T]](s *Set, k key, create func() I) *tracked[T, I] {
+	t, ok := s.indicators[k].(*tracked[T, I])
+	if !ok {
+		t = &tracked[T, I]{ind: create()}
+		for _, c := range s.candles {
+			t.Update(c)
+		}
+		s.indicators[k] = t
+	}
+	return t
+}
@@ -0,0 +1,68 @@
+package indicators
+
+import (
+	"math"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+type SupertrendValue struct {
+	Up      float64 // the band under the price, the stop while the trend is up
+	Down    float64 // the band over the price, the stop while the trend is down
+	Trend   int     // 1 up, -1 down
+	Flipped bool    // the trend turned on the newest candle
+}
+
+// Supertrend trails bands factor ATRs either side of each candle's midpoint, each only ever tightening while the
+// close stays on its side, and turns when a close crosses the band it is riding. It starts in an up trend once
+// the ATR is warm.
+type Supertrend struct {
+	factor    float64
+	atr       Atr
+	started   bool
+	prevClose float64
+	value     SupertrendValue
+}
+
+func NewSupertrend(period int, factor float64) *Supertrend {
+	return &Supertrend{factor: factor, atr: Atr{period: period}}
+}
+
+func (s *Supertrend) Update(c models.Candle) {
+	s.atr.Update(c)
+	defer func() { s.prevClose = c.Close }()
+	atr, ok := s.atr.Value()
+	if !ok {
+		return
+	}
+	mid := (c.High + c.Low) / 2
+	next := SupertrendValue{Up: mid - s.factor*atr, Down: mid + s.factor*atr, Trend: 1}
+	if s.started {
+		prev := s.value
+		if s.prevClose > prev.Up {
+			next.Up = math.Max(next.Up, prev.Up)
+		}
+		if s.prevClose < prev.Down {
+			next.Down = math.Min(next.Down, prev.Down)
+		}
+		next.Trend = prev.Trend
+		if prev.Trend == -1 && c.Close > prev.Down {
+			next.Trend = 1
+		} else if prev.Trend == 1 && c.Close < prev.Up {
+			next.Trend = -1
+		}
+		next.Flipped = next.Trend != prev.Trend
+	}
+	s.value = next
+	s.started = true
+}
+
+func (s *Supertrend) Value() (SupertrendValue, bool) {
+	return s.value, s.started
+}
+
+func (s *Supertrend) Peek(c models.Candle) (SupertrendValue, bool) {
+	next := *s
+	next.Update(c)
+	return next.Value()
+}
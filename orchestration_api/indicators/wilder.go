@@ -0,0 +1,182 @@
+package indicators
+
+import (
+	"math"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+// Atr is Wilder's average true range. The first candle has no close before it, so like talib the first value is
+// the average true range of the period candles after it, and each candle after that is smoothed in.
+type Atr struct {
+	period    int
+	count     int
+	prevClose float64
+	sum       float64
+	value     float64
+}
+
+func NewAtr(period int) *Atr {
+	return &Atr{period: period}
+}
+
+func (a *Atr) Update(c models.Candle) {
+	a.count++
+	defer func() { a.prevClose = c.Close }()
+	if a.count == 1 {
+		return
+	}
+	tr := math.Max(c.High-c.Low, math.Max(math.Abs(c.High-a.prevClose), math.Abs(c.Low-a.prevClose)))
+	switch {
+	case a.count <= a.period:
+		a.sum += tr
+	case a.count == a.period+1:
+		a.value = (a.sum + tr) / float64(a.period)
+	default:
+		a.value = (a.value*float64(a.period-1) + tr) / float64(a.period)
+	}
+}
+
+func (a *Atr) Value() (float64, bool) {
+	return a.value, a.count > a.period
+}
+
+func (a *Atr) Peek(c models.Candle) (float64, bool) {
+	next := *a
+	next.Update(c)
+	return next.Value()
+}
+
+// Rsi is Wilder's relative strength index of the closes, seeded with the plain average gain and loss of the first
+// period changes as talib does.
+type Rsi struct {
+	period    int
+	count     int
+	prevClose float64
+	gain      float64
+	loss      float64
+}
+
+func NewRsi(period int) *Rsi {
+	return &Rsi{period: period}
+}
+
+func (r *Rsi) Update(c models.Candle) {
+	r.count++
+	defer func() { r.prevClose = c.Close }()
+	if r.count == 1 {
+		return
+	}
+	change := c.Close - r.prevClose
+	gain, loss := math.Max(change, 0), math.Max(-change, 0)
+	switch {
+	case r.count <= r.period+1:
+		r.gain += gain
+		r.loss += loss
+		if r.count == r.period+1 {
+			r.gain /= float64(r.period)
+			r.loss /= float64(r.period)
+		}
+	default:
+		r.gain = (r.gain*float64(r.period-1) + gain) / float64(r.period)
+		r.loss = (r.loss*float64(r.period-1) + loss) / float64(r.period)
+	}
+}
+
+// Value is 0 while the closes haven't moved at all, as talib has it.
+func (r *Rsi) Value() (float64, bool) {
+	if r.count <= r.period {
+		return 0, false
+	}
+	if total := r.gain + r.loss; total > 1e-14 {
+		return 100 * r.gain / total, true
+	}
+	return 0, true
+}
+
+func (r *Rsi) Peek(c models.Candle) (float64, bool) {
+	next := *r
+	next.Update(c)
+	return next.Value()
+}
+
+// Adx is Wilder's average directional index as talib has it: the directional movement and true range summed over
+// the first period-1 changes, then smoothed, and the first value the average DX of the period candles after.
+type Adx struct {
+	period    int
+	count     int
+	prevHigh  float64
+	prevLow   float64
+	prevClose float64
+	plusDM    float64
+	minusDM   float64
+	tr        float64
+	sumDX     float64
+	value     float64
+}
+
+func NewAdx(period int) *Adx {
+	return &Adx{period: period}
+}
+
+func (a *Adx) Update(c models.Candle) {
+	a.count++
+	defer func() { a.prevHigh, a.prevLow, a.prevClose = c.High, c.Low, c.Close }()
+	if a.count == 1 {
+		return
+	}
+	p := float64(a.period)
+	diffP, diffM := c.High-a.prevHigh, a.prevLow-c.Low
+	tr := math.Max(c.High-c.Low, math.Max(math.Abs(c.High-a.prevClose), math.Abs(c.Low-a.prevClose)))
+	if a.count > a.period {
+		a.minusDM -= a.minusDM / p
+		a.plusDM -= a.plusDM / p
+		a.tr -= a.tr / p
+	}
+	if diffM > 0 && diffP < diffM {
+		a.minusDM += diffM
+	} else if diffP > 0 && diffP > diffM {
+		a.plusDM += diffP
+	}
+	a.tr += tr
+	if a.count <= a.period {
+		return
+	}
+	dx, ok := a.getDX()
+	switch {
+	case a.count < 2*a.period:
+		if ok {
+			a.sumDX += dx
+		}
+	case a.count == 2*a.period:
+		if ok {
+			a.sumDX += dx
+		}
+		a.value = a.sumDX / p
+	case ok:
+		a.value = (a.value*(p-1) + dx) / p
+	}
+}
+
+// getDX is the directional index of the smoothed movement, none while the range or the movement is nil.
+func (a *Adx) getDX() (float64, bool) {
+	if math.Abs(a.tr) < 1e-14 {
+		return 0, false
+	}
+	minusDI, plusDI := 100*a.minusDM/a.tr, 100*a.plusDM/a.tr
+	sum := minusDI + plusDI
+	if math.Abs(sum) < 1e-14 {
+		return 0, false
+	}
+	return 100 * math.Abs(minusDI-plusDI) / sum, true
+}
+
+func (a *Adx) Value() (float64, bool) {
+	return a.value, a.count >= 2*a.period
+}
+
+func (a *Adx) Peek(c models.Candle) (float64, bool) {
+	next := *a
+	next.Update(c)
+	return next.Value()
+}
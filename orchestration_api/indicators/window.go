@@ -0,0 +1,109 @@
+package indicators
+
+// window is the last period values with their running sum and sum of squares. The sums are added up afresh each
+// time the ring wraps, so rounding can't build up however long the window runs.
+type window struct {
+	period int
+	values []float64
+	next   int // where the next value goes, the oldest once the ring is full
+	sum    float64
+	sumSq  float64
+}
+
+func newWindow(period int) *window {
+	return &window{period: period, values: make([]float64, 0, period)}
+}
+
+func (w *window) isFull() bool {
+	return len(w.values) == w.period
+}
+
+func (w *window) push(x float64) {
+	if !w.isFull() {
+		w.values = append(w.values, x)
+		w.sum += x
+		w.sumSq += x * x
+		return
+	}
+	old := w.values[w.next]
+	w.values[w.next] = x
+	w.next = (w.next + 1) % w.period
+	if w.next == 0 {
+		w.sum, w.sumSq = 0, 0
+		for _, v := range w.values {
+			w.sum += v
+			w.sumSq += v * v
+		}
+		return
+	}
+	w.sum += x - old
+	w.sumSq += x*x - old*old
+}
+
+// peek is the sums and count the window would have after pushing x.
+func (w *window) peek(x float64) (float64, float64, int) {
+	if !w.isFull() {
+		return w.sum + x, w.sumSq + x*x, len(w.values) + 1
+	}
+	old := w.values[w.next]
+	return w.sum + x - old, w.sumSq + x*x - old*old, w.period
+}
+
+type indexedValue struct {
+	index int
+	value float64
+}
+
+// extremum is the highest, or lowest, of the last period values: a queue of the values that can still become
+// the extreme, each better than every one after it, so the front is always the answer.
+type extremum struct {
+	period  int
+	highest bool
+	count   int
+	queue   []indexedValue
+	head    int
+}
+
+func newExtremum(period int, highest bool) *extremum {
+	return &extremum{period: period, highest: highest}
+}
+
+// beats is whether a is as extreme as b or more.
+func (e *extremum) beats(a float64, b float64) bool {
+	if e.highest {
+		return a >= b
+	}
+	return a <= b
+}
+
+func (e *extremum) push(x float64) {
+	for len(e.queue) > e.head && e.beats(x, e.queue[len(e.queue)-1].value) {
+		e.queue = e.queue[:len(e.queue)-1]
+	}
+	e.queue = append(e.queue, indexedValue{index: e.count, value: x})
+	e.count++
+	for e.queue[e.head].index <= e.count-1-e.period {
+		e.head++
+	}
+	if e.head > 32 && e.head > len(e.queue)/2 {
+		e.queue = append(e.queue[:0], e.queue[e.head:]...)
+		e.head = 0
+	}
+}
+
+func (e *extremum) value() float64 {
+	return e.queue[e.head].value
+}
+
+// peek is the extreme after pushing x. Only the front can drop out of the window, and the one after it is the
+// extreme of the rest.
+func (e *extremum) peek(x float64) float64 {
+	i := e.head
+	if i < len(e.queue) && e.queue[i].index <= e.count-e.period {
+		i++
+	}
+	if i < len(e.queue) && e.beats(e.queue[i].value, x) {
+		return e.queue[i].value
+	}
+	return x
+}
@@ -0,0 +1,97 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+// Entry is one fill: the part of an order that filled since the previous update, not the order's running total.
+type Entry struct {
+	Time         time.Time      `json:"time"`
+	OrderID      string         `json:"orderId"`
+	Symbol       string         `json:"symbol"`
+	Side         string         `json:"side"` // BUY, SELL
+	Quantity     float64        `json:"quantity"`
+	AveragePrice float64        `json:"averagePrice"`
+	Value        float64        `json:"value"` // USD
+	Fees         float64        `json:"fees"`  // USD
+	Strategy     string         `json:"strategy"`
+	CandleSize   string         `json:"candleSize"`
+	Signal       *models.Signal `json:"signal,omitempty"` // the last signal the trader acted on before the fill
+}
+
+// Journal is an append-only JSONL file of fills. Queries scan the whole file, which is fine at the rate a handful
+// of traders fill orders.
+type Journal struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+func Open(path string) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open trade journal %s: %w", path, err)
+	}
+	return &Journal{path: path, file: f}, nil
+}
+
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.file.Close()
+}
+
+func (j *Journal) Append(entry Entry) error {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.file.Write(append(raw, '\n')); err != nil {
+		return err
+	}
+	return j.file.Sync()
+}
+
+// Query returns the fills for symbol (all symbols when empty) with from <= Time < to, oldest first. A zero from
+// or to leaves that side open.
+func (j *Journal) Query(symbol string, from time.Time, to time.Time) ([]Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	f, err := os.Open(j.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := make([]Entry, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue // a torn last line from a crash mid-write
+		}
+		if symbol != "" && e.Symbol != symbol {
+			continue
+		}
+		if (!from.IsZero() && e.Time.Before(from)) || (!to.IsZero() && !e.Time.Before(to)) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,118 @@
+package journal
+
+import (
+	"sort"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+)
+
+// Summary is the P&L of one token under one strategy, using average cost: sells realize against the average
+// price paid for what is held, and whatever is still held is marked at the current price. A short mirrors it,
+// buys realize against the average price it was sold at.
+type Summary struct {
+	Symbol       string  `json:"symbol"`
+	Strategy     string  `json:"strategy"`
+	NumFills     int     `json:"numFills"`
+	BoughtValue  float64 `json:"boughtValue"`
+	SoldValue    float64 `json:"soldValue"`
+	Fees         float64 `json:"fees"`
+	Position     float64 `json:"position"` // tokens still held, negative while short
+	AverageCost  float64 `json:"averageCost"`
+	RealizedPL   float64 `json:"realizedPL"` // net of fees
+	UnrealizedPL float64 `json:"unrealizedPL"`
+	CurrentPrice float64 `json:"currentPrice,omitempty"`
+	TotalPL      float64 `json:"totalPL"`
+}
+
+// Summarize groups fills by token and strategy. Fills before from (none when it is zero) only build up the position
+// and its average cost, so a sell inside the range realizes against a buy made before it, and a token and strategy
+// without a fill inside the range is left out. currentPrices is keyed by symbol; a token without a price gets no
+// unrealized P&L.
+func Summarize(entries []Entry, from time.Time, currentPrices map[string]float64) []Summary {
+	type key struct{ symbol, strategy string }
+	byKey := make(map[key]*Summary)
+	keys := make([]key, 0)
+
+	for _, e := range entries {
+		k := key{e.Symbol, e.Strategy}
+		s, ok := byKey[k]
+		if !ok {
+			s = &Summary{Symbol: e.Symbol, Strategy: e.Strategy}
+			byKey[k] = s
+			keys = append(keys, k)
+		}
+		// before the range, realized P&L and the totals go to a throwaway copy
+		tally := s
+		if !from.IsZero() && e.Time.Before(from) {
+			tally = &Summary{}
+		}
+		tally.NumFills++
+		tally.Fees += e.Fees
+		tally.RealizedPL -= e.Fees
+		switch e.Side {
+		case "BUY":
+			tally.BoughtValue += e.Value
+			quantity := e.Quantity
+			if s.Position < 0 {
+				matched := min(quantity, -s.Position)
+				tally.RealizedPL += matched * (s.AverageCost - e.AveragePrice)
+				s.Position += matched
+				quantity -= matched
+				if s.Position >= 0 {
+					s.Position = 0
+					s.AverageCost = 0
+				}
+			}
+			if quantity > 0 && s.Position+quantity > 0 {
+				s.AverageCost = (s.AverageCost*s.Position + e.Value*(quantity/e.Quantity)) / (s.Position + quantity)
+				s.Position += quantity
+			}
+		case "SELL":
+			tally.SoldValue += e.Value
+			quantity := e.Quantity
+			if s.Position > 0 {
+				// a sell only realizes against what this strategy bought; the starting balance predates the journal
+				matched := min(quantity, s.Position)
+				tally.RealizedPL += matched * (e.AveragePrice - s.AverageCost)
+				s.Position -= matched
+				quantity -= matched
+				if s.Position <= 0 {
+					s.Position = 0
+					s.AverageCost = 0
+				}
+			}
+			if quantity > 0 && (s.Position < 0 || isShortEntry(e)) {
+				s.AverageCost = (s.AverageCost*-s.Position + e.Value*(quantity/e.Quantity)) / (-s.Position + quantity)
+				s.Position -= quantity
+			}
+		}
+	}
+
+	out := make([]Summary, 0, len(keys))
+	for _, k := range keys {
+		s := byKey[k]
+		if s.NumFills == 0 {
+			continue
+		}
+		if price, ok := currentPrices[s.Symbol]; ok && price > 0 {
+			s.CurrentPrice = price
+			s.UnrealizedPL = s.Position * (price - s.AverageCost)
+		}
+		s.TotalPL = s.RealizedPL + s.UnrealizedPL
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Symbol != out[j].Symbol {
+			return out[i].Symbol < out[j].Symbol
+		}
+		return out[i].Strategy < out[j].Strategy
+	})
+	return out
+}
+
+// isShortEntry reports whether a sell went out to open a short rather than to sell down a balance that
+// predates the journal.
+func isShortEntry(e Entry) bool {
+	return e.Signal != nil && e.Signal.Type == enum.SignalShort
+}
@@ -0,0 +1,135 @@
+package journal
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/models"
+)
+
+var start = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// fill is a fill of quantity at price, minutes after start, for strategy "s" unless changed.
+func fill(minutes int, side string, quantity float64, price float64) Entry {
+	return Entry{
+		Time:         start.Add(time.Duration(minutes) * time.Minute),
+		Symbol:       "ETH-USD",
+		Side:         side,
+		Quantity:     quantity,
+		AveragePrice: price,
+		Value:        quantity * price,
+		Strategy:     "s",
+	}
+}
+
+func withFees(e Entry, fees float64) Entry {
+	e.Fees = fees
+	return e
+}
+
+func short(e Entry) Entry {
+	e.Signal = &models.Signal{Type: enum.SignalShort}
+	return e
+}
+
+func strategy(e Entry, name string) Entry {
+	e.Strategy = name
+	return e
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []Entry
+		from    time.Time
+		price   float64 // current price, 0 for none
+		want    []Summary
+	}{
+		{
+			name:    "round trip net of fees",
+			entries: []Entry{withFees(fill(0, "BUY", 1, 100), 1), withFees(fill(1, "SELL", 1, 110), 1)},
+			want:    []Summary{{NumFills: 2, BoughtValue: 100, SoldValue: 110, Fees: 2, RealizedPL: 8, TotalPL: 8}},
+		},
+		{
+			name:    "open remainder marked at the current price",
+			entries: []Entry{fill(0, "BUY", 2, 100), fill(1, "SELL", 1, 120)},
+			price:   130,
+			want: []Summary{{NumFills: 2, BoughtValue: 200, SoldValue: 120, Position: 1, AverageCost: 100, RealizedPL: 20,
+				UnrealizedPL: 30, CurrentPrice: 130, TotalPL: 50}},
+		},
+		{
+			name:    "sells realize against the average cost",
+			entries: []Entry{fill(0, "BUY", 1, 100), fill(1, "BUY", 1, 200), fill(2, "SELL", 2, 150)},
+			want:    []Summary{{NumFills: 3, BoughtValue: 300, SoldValue: 300}},
+		},
+		{
+			name:    "short covered lower",
+			entries: []Entry{short(fill(0, "SELL", 1, 100)), fill(1, "BUY", 1, 90)},
+			want:    []Summary{{NumFills: 2, BoughtValue: 90, SoldValue: 100, RealizedPL: 10, TotalPL: 10}},
+		},
+		{
+			name:    "sell of a balance that predates the journal",
+			entries: []Entry{fill(0, "SELL", 1, 100)},
+			want:    []Summary{{NumFills: 1, SoldValue: 100}},
+		},
+		{
+			name:    "sell in range realizes against a buy before it",
+			entries: []Entry{withFees(fill(0, "BUY", 1, 100), 1), withFees(fill(10, "SELL", 1, 110), 1)},
+			from:    start.Add(5 * time.Minute),
+			want:    []Summary{{NumFills: 1, SoldValue: 110, Fees: 1, RealizedPL: 9, TotalPL: 9}},
+		},
+		{
+			name:    "short opened before the range",
+			entries: []Entry{short(fill(0, "SELL", 2, 100)), fill(10, "BUY", 1, 80)},
+			from:    start.Add(5 * time.Minute),
+			price:   90,
+			want: []Summary{{NumFills: 1, BoughtValue: 80, Position: -1, AverageCost: 100, RealizedPL: 20, UnrealizedPL: 10,
+				CurrentPrice: 90, TotalPL: 30}},
+		},
+		{
+			name:    "no fills in range",
+			entries: []Entry{fill(0, "BUY", 1, 100), fill(1, "SELL", 1, 110)},
+			from:    start.Add(5 * time.Minute),
+			want:    []Summary{},
+		},
+		{
+			name:    "grouped by strategy",
+			entries: []Entry{strategy(fill(0, "BUY", 1, 100), "b"), strategy(fill(1, "BUY", 1, 100), "a"), strategy(fill(2, "SELL", 1, 90), "b")},
+			want: []Summary{
+				{Strategy: "a", NumFills: 1, BoughtValue: 100, Position: 1, AverageCost: 100},
+				{Strategy: "b", NumFills: 2, BoughtValue: 100, SoldValue: 90, RealizedPL: -10, TotalPL: -10},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prices := map[string]float64{}
+			if tt.price > 0 {
+				prices["ETH-USD"] = tt.price
+			}
+			got := Summarize(tt.entries, tt.from, prices)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d summaries, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, want := range tt.want {
+				want.Symbol = "ETH-USD"
+				if want.Strategy == "" {
+					want.Strategy = "s"
+				}
+				if !summariesEqual(got[i], want) {
+					t.Errorf("got %+v\nwant %+v", got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func summariesEqual(a Summary, b Summary) bool {
+	near := func(x, y float64) bool { return math.Abs(x-y) < 1e-9 }
+	return a.Symbol == b.Symbol && a.Strategy == b.Strategy && a.NumFills == b.NumFills && near(a.BoughtValue, b.BoughtValue) &&
+		near(a.SoldValue, b.SoldValue) && near(a.Fees, b.Fees) && near(a.Position, b.Position) && near(a.AverageCost, b.AverageCost) &&
+		near(a.RealizedPL, b.RealizedPL) && near(a.UnrealizedPL, b.UnrealizedPL) && near(a.CurrentPrice, b.CurrentPrice) &&
+		near(a.TotalPL, b.TotalPL)
+}
@@ -0,0 +1,109 @@
+package journal
+
+// TradeStats are the win/loss statistics of closed round trips, flat to flat. A trade that nets exactly zero is a
+// break-even, neither a win nor a loss.
+type TradeStats struct {
+	Trades      int     `json:"trades"`
+	Wins        int     `json:"wins"`
+	Losses      int     `json:"losses"`
+	BreakEvens  int     `json:"breakEvens"`
+	WinRate     float64 `json:"winRate"`     // 0..1
+	AverageWin  float64 `json:"averageWin"`  // USD
+	AverageLoss float64 `json:"averageLoss"` // USD, positive
+}
+
+// NewTradeStats summarizes the net P&L of each closed trade.
+func NewTradeStats(profitLosses []float64) TradeStats {
+	var stats TradeStats
+	for _, pl := range profitLosses {
+		stats.Add(pl)
+	}
+	return stats
+}
+
+// Add counts one more closed trade, keeping the averages running.
+func (s *TradeStats) Add(pl float64) {
+	s.Trades++
+	switch {
+	case pl > 0:
+		s.Wins++
+		s.AverageWin += (pl - s.AverageWin) / float64(s.Wins)
+	case pl < 0:
+		s.Losses++
+		s.AverageLoss += (-pl - s.AverageLoss) / float64(s.Losses)
+	default:
+		s.BreakEvens++
+	}
+	s.WinRate = float64(s.Wins) / float64(s.Trades)
+}
+
+// GetLossRate is the share of trades that lost, 0..1.
+func (s TradeStats) GetLossRate() float64 {
+	if s.Trades == 0 {
+		return 0
+	}
+	return float64(s.Losses) / float64(s.Trades)
+}
+
+// GetTradeStats splits the fills of one token and strategy into round trips that start and end flat, realizing
+// sells against the average cost the same way Summarize does, and buys against a short's average sale price. A
+// round trip still open is left out, and so are sells of a position that predates the journal.
+func GetTradeStats(entries []Entry) TradeStats {
+	var trips RoundTrips
+	for _, e := range entries {
+		trips.Add(e)
+	}
+	return trips.Stats
+}
+
+// RoundTrips follows one token and strategy's fills in order, as GetTradeStats does, and keeps the stats of the
+// round trips closed so far, so they can be kept current one fill at a time.
+type RoundTrips struct {
+	Stats       TradeStats
+	position    float64
+	averageCost float64
+	tradePL     float64
+}
+
+// Add takes the next fill, closing the round trip when it leaves the position flat.
+func (r *RoundTrips) Add(e Entry) {
+	quantity := e.Quantity
+	r.tradePL -= e.Fees
+	switch e.Side {
+	case "BUY":
+		if r.position < 0 {
+			matched := min(quantity, -r.position)
+			r.tradePL += matched * (r.averageCost - e.AveragePrice)
+			r.position += matched
+			quantity -= matched
+			if -r.position*e.AveragePrice < 1 { // dust left behind by the exchange's size increments counts as flat
+				r.close()
+			}
+		}
+		if quantity > 0 && r.position >= 0 {
+			r.averageCost = (r.averageCost*r.position + e.Value*(quantity/e.Quantity)) / (r.position + quantity)
+			r.position += quantity
+		}
+	case "SELL":
+		if r.position > 0 {
+			matched := min(quantity, r.position)
+			r.tradePL += matched * (e.AveragePrice - r.averageCost)
+			r.position -= matched
+			quantity -= matched
+			if r.position*e.AveragePrice < 1 {
+				r.close()
+			}
+		}
+		if quantity > 0 && r.position <= 0 && (r.position < 0 || isShortEntry(e)) {
+			r.averageCost = (r.averageCost*-r.position + e.Value*(quantity/e.Quantity)) / (-r.position + quantity)
+			r.position -= quantity
+		} else if r.position == 0 {
+			r.tradePL = 0 // a sell of a balance that predates the journal
+		}
+	}
+}
+
+func (r *RoundTrips) close() {
+	r.Stats.Add(r.tradePL)
+	r.position, r.averageCost, r.tradePL = 0, 0, 0
+}
@@ -0,0 +1,91 @@
+package journal
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGetTradeStats(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []Entry
+		want    TradeStats
+	}{
+		{
+			name:    "no fills",
+			entries: nil,
+			want:    TradeStats{},
+		},
+		{
+			name: "a win and a loss net of fees",
+			entries: []Entry{
+				withFees(fill(0, "BUY", 1, 100), 1), withFees(fill(1, "SELL", 1, 120), 1),
+				fill(2, "BUY", 1, 100), fill(3, "SELL", 1, 95),
+			},
+			want: TradeStats{Trades: 2, Wins: 1, Losses: 1, WinRate: 0.5, AverageWin: 18, AverageLoss: 5},
+		},
+		{
+			name:    "scaled out in two sells is one trade",
+			entries: []Entry{fill(0, "BUY", 2, 100), fill(1, "SELL", 1, 110), fill(2, "SELL", 1, 120)},
+			want:    TradeStats{Trades: 1, Wins: 1, WinRate: 1, AverageWin: 30},
+		},
+		{
+			name:    "open round trip left out",
+			entries: []Entry{fill(0, "BUY", 1, 100), fill(1, "SELL", 1, 110), fill(2, "BUY", 1, 100)},
+			want:    TradeStats{Trades: 1, Wins: 1, WinRate: 1, AverageWin: 10},
+		},
+		{
+			name:    "dust counts as flat",
+			entries: []Entry{fill(0, "BUY", 1, 100), fill(1, "SELL", 0.995, 110)},
+			want:    TradeStats{Trades: 1, Wins: 1, WinRate: 1, AverageWin: 9.95},
+		},
+		{
+			name:    "short",
+			entries: []Entry{short(fill(0, "SELL", 1, 100)), fill(1, "BUY", 1, 110)},
+			want:    TradeStats{Trades: 1, Losses: 1, AverageLoss: 10},
+		},
+		{
+			name:    "break-even is neither a win nor a loss",
+			entries: []Entry{fill(0, "BUY", 1, 100), fill(1, "SELL", 1, 100), fill(2, "BUY", 1, 100), fill(3, "SELL", 1, 90)},
+			want:    TradeStats{Trades: 2, Losses: 1, BreakEvens: 1, AverageLoss: 10},
+		},
+		{
+			name:    "sell of a balance that predates the journal",
+			entries: []Entry{withFees(fill(0, "SELL", 1, 100), 1), fill(1, "BUY", 1, 100), fill(2, "SELL", 1, 105)},
+			want:    TradeStats{Trades: 1, Wins: 1, WinRate: 1, AverageWin: 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GetTradeStats(tt.entries)
+			if !tradeStatsEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTradeStats(t *testing.T) {
+	tests := []struct {
+		name         string
+		profitLosses []float64
+		want         TradeStats
+	}{
+		{name: "none", want: TradeStats{}},
+		{name: "only winners", profitLosses: []float64{10, 20}, want: TradeStats{Trades: 2, Wins: 2, WinRate: 1, AverageWin: 15}},
+		{name: "break-even", profitLosses: []float64{0, 10, -4, -6}, want: TradeStats{Trades: 4, Wins: 1, Losses: 2, BreakEvens: 1, WinRate: 0.25, AverageWin: 10, AverageLoss: 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewTradeStats(tt.profitLosses); !tradeStatsEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func tradeStatsEqual(a TradeStats, b TradeStats) bool {
+	near := func(x, y float64) bool { return math.Abs(x-y) < 1e-9 }
+	return a.Trades == b.Trades && a.Wins == b.Wins && a.Losses == b.Losses && a.BreakEvens == b.BreakEvens && near(a.WinRate, b.WinRate) &&
+		near(a.AverageWin, b.AverageWin) && near(a.AverageLoss, b.AverageLoss)
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Bracket is the exchange-side exit protecting a trader's position: a take-profit limit plus a stop trigger, or
+// just a stop-limit when the strategy gave no take profit. The levels come from the strategy, the Placed fields
+// describe the order currently resting on the exchange. A short's bracket is a buy, with the take profit below the
+// price and the stop above it.
+type Bracket struct {
+	Short            bool
+	TakeProfit       float64
+	StopLoss         float64
+	OrderID          string // empty until placed
+	EntryOrderID     string // the entry order the bracket is attached to, until that order is done filling
+	PlacedTakeProfit float64
+	PlacedStopLoss   float64
+	PlacedTokens     float64
+	FilledTokens     float64 // running totals of what the order has exited so far, as the exchange reports them
+	FilledUSD        float64
+	PaidFeesUSD      float64
+	RetryAfter       time.Time // set when the exchange refused the order, so it isn't resubmitted every tick
+}
+
+// GetRestingTokens is what is left of the order to exit.
+func (b *Bracket) GetRestingTokens() float64 {
+	return max(b.PlacedTokens-b.FilledTokens, 0)
+}
+
+// IsPlaced reports whether an exit order is resting on the exchange.
+func (b *Bracket) IsPlaced() bool {
+	return b != nil && b.OrderID != ""
+}
@@ -6,16 +6,25 @@ type CoinbaseSubscription struct {
 	Channel    string   `json:"channel"`
 }
 
+// MarketDataChannels are the public channels subscribed to for every product being traded.
+var MarketDataChannels = []string{"candles", "level2", "market_trades", "ticker"}
+
 func GetMarketSubscriptionPayload(productIDs []string, isUnsubscribe bool) []CoinbaseSubscription {
+	return GetChannelSubscriptionPayload(productIDs, MarketDataChannels, isUnsubscribe)
+}
+
+func GetChannelSubscriptionPayload(productIDs []string, channels []string, isUnsubscribe bool) []CoinbaseSubscription {
 	subType := "subscribe"
 	if isUnsubscribe {
 		subType = "unsubscribe"
 	}
-	return []CoinbaseSubscription{
-		{
+	subs := make([]CoinbaseSubscription, 0, len(channels))
+	for _, channel := range channels {
+		subs = append(subs, CoinbaseSubscription{
 			Type:       subType,
 			ProductIDs: productIDs,
-			Channel:    "candles",
-		},
+			Channel:    channel,
+		})
 	}
+	return subs
 }
@@ -1,7 +1,46 @@
 package coinbase
 
 type CreateOrderResponse struct {
-	Success bool   `json:"success"`
-	OrderID string `json:"order_id"`
-	Error   string `json:"error_message"`
-}
\ No newline at end of file
+	Success         bool                      `json:"success"`
+	OrderID         string                    `json:"order_id"`
+	AttachedOrderID string                    `json:"attached_order_id,omitempty"` // the exit attached to the order, if any
+	Error           string                    `json:"error_message"`
+	SuccessResponse *CreateOrderSuccess       `json:"success_response,omitempty"`
+	ErrorResponse   *CreateOrderErrorResponse `json:"error_response,omitempty"`
+}
+
+type CreateOrderSuccess struct {
+	OrderID         string `json:"order_id"`
+	ProductID       string `json:"product_id"`
+	Side            string `json:"side"`
+	ClientOrderID   string `json:"client_order_id"`
+	AttachedOrderID string `json:"attached_order_id"`
+}
+
+type CreateOrderErrorResponse struct {
+	Error                 string `json:"error"`
+	Message               string `json:"message"`
+	ErrorDetails          string `json:"error_details"`
+	PreviewFailureReason  string `json:"preview_failure_reason"`
+	NewOrderFailureReason string `json:"new_order_failure_reason"`
+}
+
+// Normalize lifts the order ids and error out of the nested success_response/error_response Coinbase returns.
+func (r CreateOrderResponse) Normalize() CreateOrderResponse {
+	if r.OrderID == "" && r.SuccessResponse != nil {
+		r.OrderID = r.SuccessResponse.OrderID
+	}
+	if r.AttachedOrderID == "" && r.SuccessResponse != nil {
+		r.AttachedOrderID = r.SuccessResponse.AttachedOrderID
+	}
+	if r.Error == "" && r.ErrorResponse != nil {
+		r.Error = r.ErrorResponse.Error
+		if r.ErrorResponse.NewOrderFailureReason != "" {
+			r.Error = r.ErrorResponse.NewOrderFailureReason
+		}
+		if r.ErrorResponse.Message != "" {
+			r.Error += ": " + r.ErrorResponse.Message
+		}
+	}
+	return r
+}
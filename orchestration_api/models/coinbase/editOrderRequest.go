@@ -0,0 +1,10 @@
+package coinbase
+
+// EditOrderRequest is the body of POST /api/v3/brokerage/orders/edit. Size is the order's new total base size,
+// StopPrice only applies to stop-limit and bracket orders.
+type EditOrderRequest struct {
+	OrderID   string `json:"order_id"`
+	Price     string `json:"price"`
+	Size      string `json:"size"`
+	StopPrice string `json:"stop_price,omitempty"`
+}
@@ -0,0 +1,30 @@
+package coinbase
+
+// Portfolio is an entry of GET /api/v3/brokerage/portfolios. Perpetuals live in the INTX portfolio.
+type Portfolio struct {
+	Name    string `json:"name"`
+	UUID    string `json:"uuid"`
+	Type    string `json:"type"` // DEFAULT, CONSUMER, INTX
+	Deleted bool   `json:"deleted"`
+}
+
+type PortfoliosResponse struct {
+	Portfolios []Portfolio `json:"portfolios"`
+}
+
+// IntxPosition is a perpetual position from GET /api/v3/brokerage/intx/positions/{portfolio_uuid}/{symbol}.
+type IntxPosition struct {
+	ProductID        string       `json:"product_id"`
+	Symbol           string       `json:"symbol"`
+	Vwap             TokenHolding `json:"vwap"`
+	PositionSide     string       `json:"position_side"` // POSITION_SIDE_LONG, POSITION_SIDE_SHORT
+	NetSize          string       `json:"net_size"`
+	MarkPrice        TokenHolding `json:"mark_price"`
+	LiquidationPrice TokenHolding `json:"liquidation_price"`
+	Leverage         string       `json:"leverage"`
+	MarginType       string       `json:"margin_type"` // MARGIN_TYPE_CROSS, MARGIN_TYPE_ISOLATED
+}
+
+type IntxPositionResponse struct {
+	Position IntxPosition `json:"position"`
+}
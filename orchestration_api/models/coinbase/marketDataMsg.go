@@ -0,0 +1,52 @@
+package coinbase
+
+import "time"
+
+// MarketDataMsg is the envelope every market data channel message shares. SequenceNum counts messages across the
+// whole connection, a jump of more than one means messages were dropped.
+type MarketDataMsg struct {
+	Channel     string    `json:"channel"`
+	Timestamp   time.Time `json:"timestamp"`
+	SequenceNum int64     `json:"sequence_num"`
+}
+
+// Level2Msg arrives on the "l2_data" channel after subscribing to "level2", a snapshot event first and then
+// updates, each update the new quantity at a price level.
+type Level2Msg struct {
+	Events []struct {
+		Type      string `json:"type"`
+		ProductID string `json:"product_id"`
+		Updates   []struct {
+			Side        string    `json:"side"` // bid or offer
+			EventTime   time.Time `json:"event_time"`
+			PriceLevel  float64   `json:"price_level,string"`
+			NewQuantity float64   `json:"new_quantity,string"`
+		} `json:"updates"`
+	} `json:"events"`
+}
+
+type MarketTradesMsg struct {
+	Events []struct {
+		Type   string `json:"type"`
+		Trades []struct {
+			TradeID   string    `json:"trade_id"`
+			ProductID string    `json:"product_id"`
+			Price     float64   `json:"price,string"`
+			Size      float64   `json:"size,string"`
+			Side      string    `json:"side"`
+			Time      time.Time `json:"time"`
+		} `json:"trades"`
+	} `json:"events"`
+}
+
+type TickerMsg struct {
+	Events []struct {
+		Type    string `json:"type"`
+		Tickers []struct {
+			ProductID string  `json:"product_id"`
+			Price     float64 `json:"price,string"`
+			BestBid   float64 `json:"best_bid,string"`
+			BestAsk   float64 `json:"best_ask,string"`
+		} `json:"tickers"`
+	} `json:"events"`
+}
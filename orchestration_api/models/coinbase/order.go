@@ -13,5 +13,6 @@ type Order struct {
 	Leaves             string `json:"leaves_quantity"`
 	LimitPrice         string `json:"limit_price"`
 	AvgPrice           string `json:"avg_price"`
+	TotalFees          string `json:"total_fees"`
 	CreationTime       string `json:"creation_time"`
 }
\ No newline at end of file
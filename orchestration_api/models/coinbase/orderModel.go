@@ -71,7 +71,7 @@ type StopLimitStopLimitGTD struct {
 }
 
 type TriggerBracketGTC struct {
-	BaseSize         string `json:"base_size"`
+	BaseSize         string `json:"base_size,omitempty"` // left out when attached, the bracket takes the entry's filled size
 	LimitPrice       string `json:"limit_price"`
 	StopTriggerPrice string `json:"stop_trigger_price"`
 }
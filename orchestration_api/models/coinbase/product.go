@@ -0,0 +1,32 @@
+package coinbase
+
+// Product is the part of GET /api/v3/brokerage/market/products/{product_id} needed to size and price orders.
+type Product struct {
+	ProductID            string                `json:"product_id"`
+	Price                string                `json:"price"`
+	BaseIncrement        string                `json:"base_increment"`
+	QuoteIncrement       string                `json:"quote_increment"`
+	PriceIncrement       string                `json:"price_increment"`
+	BaseMinSize          string                `json:"base_min_size"`
+	FutureProductDetails *FutureProductDetails `json:"future_product_details,omitempty"` // perpetuals and futures only
+}
+
+type FutureProductDetails struct {
+	PerpetualDetails PerpetualDetails `json:"perpetual_details"`
+	FundingInterval  string           `json:"funding_interval"` // e.g. "3600s"
+}
+
+type PerpetualDetails struct {
+	OpenInterest string `json:"open_interest"`
+	FundingRate  string `json:"funding_rate"`
+	FundingTime  string `json:"funding_time"`
+	MaxLeverage  string `json:"max_leverage"`
+}
+
+// GetPriceIncrement is the tick size for limit and stop prices.
+func (p Product) GetPriceIncrement() string {
+	if p.PriceIncrement != "" {
+		return p.PriceIncrement
+	}
+	return p.QuoteIncrement
+}
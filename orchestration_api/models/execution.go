@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+)
+
+// Execution is a large deficit being worked in slices rather than one order. TotalUSD is the deficit when the
+// execution started, what is still missing from it is read off the trader's position, so fills that land between
+// slices or a partial IOC are accounted for without tracking every child order.
+type Execution struct {
+	Algo            enum.ExecutionAlgo
+	Side            enum.SignalType
+	TotalUSD        float64
+	ArrivalPrice    float64 // the last ticker when the execution started
+	StartTime       time.Time
+	EndTime         time.Time // TWAP horizon
+	Slices          int       // child orders sent so far
+	NextSliceTime   time.Time
+	LastCandleStart time.Time // participation: volume up to and including this candle is already counted
+	AllowanceUSD    float64   // participation: volume share earned but not yet sent
+}
+
+// ExecutionProgress is what the frontend websocket gets each time an execution sends a slice, starts or ends.
+type ExecutionProgress struct {
+	Type         string    `json:"type"` // always "execution", to tell it apart from tickers and candles
+	Symbol       string    `json:"symbol"`
+	Algo         string    `json:"algo"`
+	Side         string    `json:"side"`
+	Status       string    `json:"status"` // working, done or replaced
+	TotalUSD     float64   `json:"totalUSD"`
+	FilledUSD    float64   `json:"filledUSD"`
+	Slices       int       `json:"slices"`
+	ArrivalPrice float64   `json:"arrivalPrice"`
+	LastPrice    float64   `json:"lastPrice"`
+	StartTime    time.Time `json:"startTime"`
+	EndTime      time.Time `json:"endTime"`
+	Time         time.Time `json:"time"`
+}
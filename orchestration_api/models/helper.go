@@ -3,6 +3,7 @@ package models
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,4 +15,19 @@ func GetTimeFromUnixTimestamp(unixTimestampStr string) time.Time {
 	}
 
 	return time.Unix(timestampInt, 0)
-}
\ No newline at end of file
+}
+
+// GetBaseCurrency returns the traded asset of a product id, e.g. "ETH" for "ETH-USD".
+func GetBaseCurrency(productID string) string {
+	base, _, _ := strings.Cut(productID, "-")
+	return base
+}
+
+// GetQuoteCurrency returns the pricing currency of a product id, e.g. "USD" for "ETH-USD".
+func GetQuoteCurrency(productID string) string {
+	_, quote, found := strings.Cut(productID, "-")
+	if !found {
+		return "USD"
+	}
+	return quote
+}
@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+type OrderBookLevel struct {
+	Price float64 `json:"price"`
+	Size  float64 `json:"size"`
+}
+
+// OrderBook is a snapshot of the top of a symbol's book, Bids highest first and Asks lowest first.
+type OrderBook struct {
+	Symbol string           `json:"symbol"`
+	Bids   []OrderBookLevel `json:"bids"`
+	Asks   []OrderBookLevel `json:"asks"`
+	Time   time.Time        `json:"time"`
+}
+
+func (b OrderBook) GetBestBid() float64 {
+	if len(b.Bids) == 0 {
+		return 0
+	}
+	return b.Bids[0].Price
+}
+
+func (b OrderBook) GetBestAsk() float64 {
+	if len(b.Asks) == 0 {
+		return 0
+	}
+	return b.Asks[0].Price
+}
+
+// GetMidPrice is halfway between the best bid and ask, 0 while either side is empty.
+func (b OrderBook) GetMidPrice() float64 {
+	if len(b.Bids) == 0 || len(b.Asks) == 0 {
+		return 0
+	}
+	return (b.GetBestBid() + b.GetBestAsk()) / 2
+}
+
+func (b OrderBook) GetSpread() float64 {
+	if len(b.Bids) == 0 || len(b.Asks) == 0 {
+		return 0
+	}
+	return b.GetBestAsk() - b.GetBestBid()
+}
+
+// GetSpreadBps is the spread in basis points of the mid price.
+func (b OrderBook) GetSpreadBps() float64 {
+	mid := b.GetMidPrice()
+	if mid == 0 {
+		return 0
+	}
+	return b.GetSpread() / mid * 10000
+}
+
+// GetDepthUSD is the notional resting on each side within bps of the mid price.
+func (b OrderBook) GetDepthUSD(bps float64) (float64, float64) {
+	mid := b.GetMidPrice()
+	if mid == 0 {
+		return 0, 0
+	}
+	band := mid * bps / 10000
+	bidDepth, askDepth := 0.0, 0.0
+	for _, l := range b.Bids {
+		if l.Price < mid-band {
+			break
+		}
+		bidDepth += l.Price * l.Size
+	}
+	for _, l := range b.Asks {
+		if l.Price > mid+band {
+			break
+		}
+		askDepth += l.Price * l.Size
+	}
+	return bidDepth, askDepth
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+)
+
+// OrderSpec says how an order should be worked. Prices are in the quote currency; which of them matter depends
+// on Type:
+//   - market: none
+//   - limit, post-only: LimitPrice
+//   - stop-limit: StopPrice triggers a limit order at LimitPrice
+//   - bracket: LimitPrice is the take profit, StopPrice the stop loss trigger
+//   - limit IOC: LimitPrice
+//   - TWAP: LimitPrice caps every slice, Buckets slices are spread evenly until Expiry
+//
+// A zero Expiry means good till cancelled. Leverage, MarginType and ReduceOnly only apply on derivatives venues;
+// a zero Leverage leaves the order a spot order. AttachedBracket asks the exchange to protect whatever the order
+// fills; venues that can't attach one ignore it and leave the response's AttachedOrderID empty.
+type OrderSpec struct {
+	Type       enum.OrderType  `json:"type"`
+	LimitPrice float64         `json:"limitPrice,omitempty"`
+	StopPrice  float64         `json:"stopPrice,omitempty"`
+	Expiry     time.Time       `json:"expiry,omitempty"`
+	Buckets    int             `json:"buckets,omitempty"`
+	Leverage   float64         `json:"leverage,omitempty"`
+	MarginType enum.MarginType `json:"marginType,omitempty"`
+	ReduceOnly bool            `json:"reduceOnly,omitempty"` // may only shrink the position, never open or flip it
+
+	AttachedBracket *AttachedBracket `json:"attachedBracket,omitempty"`
+}
+
+// AttachedBracket is a take profit and stop loss the exchange places as one exit order once the order it is
+// attached to fills, sized to the fill, so the position is protected from the moment it exists.
+type AttachedBracket struct {
+	TakeProfit float64 `json:"takeProfit"`
+	StopLoss   float64 `json:"stopLoss"`
+}
+
+func NewMarketOrderSpec() OrderSpec {
+	return OrderSpec{Type: enum.OrderTypeMarket}
+}
+
+// IsResting reports whether the order can sit on the book unfilled.
+func (s OrderSpec) IsResting() bool {
+	return s.Type != enum.OrderTypeMarket && s.Type != enum.OrderTypeLimitIOC
+}
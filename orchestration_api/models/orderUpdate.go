@@ -15,5 +15,6 @@ type OrderUpdate struct {
 	Leaves    	  string    `json:"leaves"`
 	Price     	  string    `json:"price"`
 	Side      	  string    `json:"side"`
+	Fees      	  string    `json:"total_fees"` // cumulative, in the quote currency
 	Ts        	  time.Time `json:"ts"`
 }
\ No newline at end of file
@@ -15,5 +15,12 @@ type PendingOrder struct {
 	AlreadyFilledInUSD               float64
 	OriginalAmountInTokens           float64
 	AlreadyFilledInTokens            float64
+	AlreadyJournaledInTokens         float64
+	AlreadyJournaledInUSD            float64
+	AlreadyPaidFeesInUSD             float64
+	ExecutionType                    enum.OrderType // market, or a maker limit that gets repriced while it rests
+	LimitPrice                       float64
+	LastRepriceTime                  time.Time
+	CancelRequested                  bool // cancelled for resting too long, the next order goes out at market
 }
 
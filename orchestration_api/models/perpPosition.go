@@ -0,0 +1,46 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+)
+
+// PerpPosition is the exchange's view of a derivatives position. Size is in base units and negative when short.
+type PerpPosition struct {
+	ProductID        string          `json:"productId"`
+	Size             float64         `json:"size"`
+	EntryPrice       float64         `json:"entryPrice"`
+	MarkPrice        float64         `json:"markPrice"`
+	LiquidationPrice float64         `json:"liquidationPrice"` // 0 when there is no position or the venue can't liquidate it
+	Leverage         float64         `json:"leverage"`
+	MarginType       enum.MarginType `json:"marginType"`
+}
+
+// GetLiquidationDistancePct is how far the price has to move, in percent, before a position is liquidated. It is
+// +Inf when there is no liquidation price.
+func GetLiquidationDistancePct(price float64, liquidationPrice float64) float64 {
+	if liquidationPrice <= 0 || price <= 0 {
+		return math.Inf(1)
+	}
+	return math.Abs(price-liquidationPrice) / price * 100.0
+}
+
+// FundingRate is what a perpetual's longs pay its shorts every Interval, as a fraction of the position's
+// notional. A negative rate is paid by the shorts.
+type FundingRate struct {
+	ProductID       string        `json:"productId"`
+	Rate            float64       `json:"rate"`
+	Interval        time.Duration `json:"interval"`
+	NextFundingTime time.Time     `json:"nextFundingTime,omitempty"`
+}
+
+// GetFundingUSD is the funding a position of positionUSD (negative when short) pays over elapsed, accrued pro
+// rata so it doesn't matter how often it is polled. A negative result was received.
+func (f FundingRate) GetFundingUSD(positionUSD float64, elapsed time.Duration) float64 {
+	if f.Interval <= 0 || elapsed <= 0 {
+		return 0
+	}
+	return positionUSD * f.Rate * float64(elapsed) / float64(f.Interval)
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// PositionUnit is one entry into a position. The first unit opens it, pyramiding adds more on top, and a trim
+// takes the newest one back off.
+type PositionUnit struct {
+	EntryPrice float64   `json:"entryPrice"`
+	Percent    float64   `json:"percent"`            // of allocated funds, as the signal asked for it
+	StopLoss   float64   `json:"stopLoss,omitempty"` // the unit's own stop, trimmed when the close falls through it
+	Time       time.Time `json:"time"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// ResampleCandles aggregates candles into buckets of the given size. Buckets are aligned to the unix epoch
+// (the same way exchanges align their own granularities) and the input is expected to be sorted by start time.
+func ResampleCandles(candles []Candle, size time.Duration) []Candle {
+	out := make([]Candle, 0, len(candles))
+	if size <= 0 {
+		return append(out, candles...)
+	}
+	for _, c := range candles {
+		bucketStart := c.Start.Truncate(size)
+		if n := len(out); n > 0 && out[n-1].Start.Equal(bucketStart) {
+			last := &out[n-1]
+			if c.High > last.High {
+				last.High = c.High
+			}
+			if c.Low < last.Low {
+				last.Low = c.Low
+			}
+			last.Close = c.Close
+			last.Volume += c.Volume
+			continue
+		}
+		out = append(out, Candle{
+			Start:     bucketStart,
+			High:      c.High,
+			Low:       c.Low,
+			Open:      c.Open,
+			Close:     c.Close,
+			Volume:    c.Volume,
+			ProductID: c.ProductID,
+		})
+	}
+	return out
+}
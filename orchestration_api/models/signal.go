@@ -17,4 +17,6 @@ type Signal struct {
 	PositionIncreaseThreshold float64
 	Price                     float64
 	LastTrailingStopPrice     float64
+	UnitStopLoss              float64 // pyramid add: the unit's own stop, it is trimmed alone rather than exiting the position
+	Trim                      bool    // an exit that only takes the newest unit back off, Percent is then its share of the position
 }
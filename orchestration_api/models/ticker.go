@@ -4,15 +4,21 @@ import (
 	"time"
 )
 
+// Ticker is a tick off an exchange's ticker feed. Price is the last trade, Bid and Ask the top of the book when
+// the tick was sent (0 on venues without one), and Time the exchange's timestamp, not when the tick arrived.
 type Ticker struct {
 	Symbol string    `json:"symbol"`
 	Price  float64   `json:"price"`
+	Bid    float64   `json:"bid"`
+	Ask    float64   `json:"ask"`
 	Time   time.Time `json:"time"`
 }
 
 type FrontEndTicker struct {
 	Symbol string    `json:"symbol"`
 	Price  float64   `json:"price"`
+	Bid    float64   `json:"bid"`
+	Ask    float64   `json:"ask"`
 	Time   time.Time `json:"time"`
 }
 
@@ -20,6 +26,8 @@ func GetFrontEndTicker(ticker Ticker) FrontEndTicker {
 	return FrontEndTicker{
 		Symbol: ticker.Symbol,
 		Price:  ticker.Price,
+		Bid:    ticker.Bid,
+		Ask:    ticker.Ask,
 		Time:   ticker.Time,
 	}
 }
@@ -31,6 +31,17 @@ func (s *ToggleStore) Toggle(token string) (bool, error) {
 	return s.toggles[token], nil
 }
 
+func (s *ToggleStore) Set(token string, value bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.toggles[token]; !ok {
+		return fmt.Errorf("unknown token: %s", token)
+	}
+	s.toggles[token] = value
+	return nil
+}
+
 func (s *ToggleStore) Get(token string) (bool, bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Trade is a print off an exchange's public trades feed. Side is the taker's, BUY or SELL.
+type Trade struct {
+	Symbol  string    `json:"symbol"`
+	TradeID string    `json:"trade_id"`
+	Price   float64   `json:"price"`
+	Size    float64   `json:"size"`
+	Side    string    `json:"side"`
+	Time    time.Time `json:"time"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type TraderState struct {
 	PendingOrder                *PendingOrder
 	ActualPositionToken         float64
@@ -7,4 +9,12 @@ type TraderState struct {
 	UsdAmountPerFulfilledOrders float64 // actual position in USD without gains or losses
 	TargetPositionUSD           float64 // target position in USD
 	CurrentPriceUSDPerToken     float64
+	CurrentBidUSDPerToken       float64 // best bid and ask, 0 when the venue's ticker doesn't quote them
+	CurrentAskUSDPerToken       float64
+	LastSignal                  *Signal // the signal behind the current target, recorded with each fill
+	Bracket                     *Bracket // exchange-side take profit / stop loss on the position
+	Execution                   *Execution // a large deficit being worked in slices
+	FundingPaidUSD              float64 // perpetual funding paid since the trader started, negative when received
+	LastFundingAccrual          time.Time
+	LiquidationPrice            float64 // as the exchange last reported it, 0 when there is none
 }
\ No newline at end of file
@@ -3,24 +3,40 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"log"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/archive"
 	"github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/manager"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/entities/signaler"
 	"github.com/A-Here-And-Now/algo-trader/orchestration_api/enum"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/journal"
+	"github.com/A-Here-And-Now/algo-trader/orchestration_api/persistence"
 )
 
 var (
 	apiKey = os.Getenv("COINBASE_API_KEY")
 	// privateKeyPem = os.Getenv("PRIVATE_KEY_PEM")
 	apiSecret = os.Getenv("COINBASE_API_SECRET")
+	stateDbPath = getEnvOrDefault("STATE_DB_PATH", "data/state.db")
+	tradeJournalPath = getEnvOrDefault("TRADE_JOURNAL_PATH", "data/trades.jsonl")
+	candleArchiveDir = getEnvOrDefault("CANDLE_ARCHIVE_DIR", "data/candles")
+	strategyParamsPath = getEnvOrDefault("STRATEGY_PARAMS_PATH", "config/strategyParams.yaml")
 )
+const (
+	defaultPriceHistoryLimit = 500  // ticks per token a /priceHistory page holds unless asked for more
+	maxPriceHistoryLimit     = 5000 // the most ticks per token a /priceHistory page may ask for
+)
+
 var tokens = []string{"ETH-USD", "WBTC-USD", "LINK-USD", "UNI-USD", "AAVE-USD", "DOT-USD", "ENA-USD", "MNT-USD", "OKB-USD", "POL-USD"}
 
 var mgr *manager.Manager
@@ -46,6 +62,7 @@ func ToggleTokenHandler(w http.ResponseWriter, r *http.Request) {
 	var status string
 	if newTokenToggles[token] {
 		if err := mgr.Start(token); err != nil {
+			mgr.ToggleToken(token) // back off, it never started
 			http.Error(w, "cannot start: "+err.Error(), http.StatusConflict)
 			return
 		}
@@ -58,7 +75,7 @@ func ToggleTokenHandler(w http.ResponseWriter, r *http.Request) {
 		status = "stopped"
 	}
 
-	log.Printf("Token is now: %s ('true' is ON, 'false' is OFF)", tokenToggles[token])
+	log.Printf("Token is now: %t ('true' is ON, 'false' is OFF)", newTokenToggles[token])
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]any{
@@ -89,6 +106,201 @@ func UpdateCandleSizeHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 }
 
+func UpdateOrderTypeHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	orderType := enum.GetOrderTypeFromString(r.URL.Query().Get("orderType"))
+	log := LoggerFrom(r)
+	log.Printf("Updating order type from %s to %s for token %s", mgr.GetOrderType(token).String(), orderType.String(), token)
+	if err := mgr.UpdateOrderType(token, orderType); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+}
+
+// UpdateExecutionHandler sets how a token's large deficits are sliced, e.g.
+// /updateExecution?token=BTC-USD&algo=ExecutionTWAP&maxSliceUSD=500&durationMinutes=15&maxSlippageBps=20.
+// Parameters left out keep their current values.
+func UpdateExecutionHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	token := query.Get("token")
+	cfg := mgr.GetExecutionCfg(token)
+	if algo := query.Get("algo"); algo != "" {
+		cfg.Algo = enum.GetExecutionAlgoFromString(algo)
+	}
+	for name, field := range map[string]*float64{
+		"maxSliceUSD":       &cfg.MaxSliceUSD,
+		"participationRate": &cfg.ParticipationRate,
+		"maxSlippageBps":    &cfg.MaxSlippageBps,
+	} {
+		if raw := query.Get(name); raw != "" {
+			value, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				http.Error(w, "invalid "+name, http.StatusBadRequest)
+				return
+			}
+			*field = value
+		}
+	}
+	if raw := query.Get("durationMinutes"); raw != "" {
+		minutes, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			http.Error(w, "invalid durationMinutes", http.StatusBadRequest)
+			return
+		}
+		cfg.Duration = time.Duration(minutes * float64(time.Minute))
+	}
+	log := LoggerFrom(r)
+	log.Printf("Updating execution for token %s to %s (max slice %v)", token, cfg.Algo.String(), cfg.MaxSliceUSD)
+	if err := mgr.UpdateExecutionCfg(token, cfg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+}
+
+// UpdateSizingHandler sets how large a token's full position is, e.g.
+// /updateSizing?token=ETH-USD&method=SizingFixedRisk&riskPct=1&atrLen=20&stopAtrMultiple=2 or
+// /updateSizing?token=ETH-USD&method=SizingKelly&kellyFraction=0.5&kellyMinTrades=20.
+// Parameters left out keep their current values.
+func UpdateSizingHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	token := query.Get("token")
+	if _, ok := mgr.GetTokenToggles()[token]; !ok {
+		http.Error(w, "token not found", http.StatusNotFound)
+		return
+	}
+	cfg := mgr.GetSizingCfg(token)
+	if method := query.Get("method"); method != "" {
+		cfg.Method = enum.GetPositionSizingFromString(method)
+	}
+	for name, field := range map[string]*float64{
+		"riskPct":         &cfg.RiskPct,
+		"stopAtrMultiple": &cfg.StopAtrMultiple,
+		"kellyFraction":   &cfg.KellyFraction,
+	} {
+		if raw := query.Get(name); raw != "" {
+			value, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				http.Error(w, "invalid "+name, http.StatusBadRequest)
+				return
+			}
+			*field = value
+		}
+	}
+	for name, field := range map[string]*int{
+		"atrLen":         &cfg.AtrLen,
+		"kellyMinTrades": &cfg.KellyMinTrades,
+	} {
+		if raw := query.Get(name); raw != "" {
+			value, err := strconv.Atoi(raw)
+			if err != nil {
+				http.Error(w, "invalid "+name, http.StatusBadRequest)
+				return
+			}
+			*field = value
+		}
+	}
+	log := LoggerFrom(r)
+	log.Printf("Updating sizing for token %s to %s", token, cfg.Method.String())
+	if err := mgr.UpdateSizingCfg(token, cfg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(cfg)
+}
+
+// UpdateDerivativesHandler lets a token's trader go short and lever its allocated funds on a perpetuals venue, e.g.
+// /updateDerivatives?token=ETH-PERP-INTX&allowShort=true&leverage=3&marginType=MarginIsolated&minLiquidationDistancePct=15.
+// Parameters left out keep their current values.
+func UpdateDerivativesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	token := query.Get("token")
+	if _, ok := mgr.GetTokenToggles()[token]; !ok {
+		http.Error(w, "token not found", http.StatusNotFound)
+		return
+	}
+	cfg := mgr.GetDerivativesCfg(token)
+	if raw := query.Get("allowShort"); raw != "" {
+		allowShort, err := strconv.ParseBool(raw)
+		if err != nil {
+			http.Error(w, "invalid allowShort", http.StatusBadRequest)
+			return
+		}
+		cfg.AllowShort = allowShort
+	}
+	if marginType := query.Get("marginType"); marginType != "" {
+		cfg.MarginType = enum.GetMarginTypeFromString(marginType)
+	}
+	for name, field := range map[string]*float64{
+		"leverage":                  &cfg.Leverage,
+		"minLiquidationDistancePct": &cfg.MinLiquidationDistancePct,
+	} {
+		if raw := query.Get(name); raw != "" {
+			value, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				http.Error(w, "invalid "+name, http.StatusBadRequest)
+				return
+			}
+			*field = value
+		}
+	}
+	log := LoggerFrom(r)
+	log.Printf("Updating derivatives for token %s: allowShort=%v leverage=%v %s", token, cfg.AllowShort, cfg.Leverage, cfg.MarginType.String())
+	if err := mgr.UpdateDerivativesCfg(token, cfg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(cfg)
+}
+
+// StrategyParamsHandler serves GET and PUT /strategies/{name}/params. GET returns the params the strategy runs with
+// next to its defaults, PUT takes a JSON object with just the fields to change. With ?token=ETH-USD both work on
+// that token's overrides, where a field set to null goes back to the strategy's value. A PUT swaps the new params
+// into the running traders straight away but only lasts until a restart, the params file is what persists. The
+// RuleBased strategy is loaded the same way, its params are the rules, e.g. {"entry": "close > sma(close, 50)"},
+// and the Plugin strategy is pointed at its gRPC plugin, e.g. {"address": "10.0.0.5:50051", "strategy": "lstm"}.
+func StrategyParamsHandler(w http.ResponseWriter, r *http.Request) {
+	strategy, ok := enum.LookupStrategy(r.PathValue("name"))
+	if !ok {
+		http.Error(w, "strategy not found", http.StatusNotFound)
+		return
+	}
+	token := r.URL.Query().Get("token")
+	if _, ok := mgr.GetTokenToggles()[token]; token != "" && !ok {
+		http.Error(w, "token not found", http.StatusNotFound)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<16))
+		if err != nil {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+		log := LoggerFrom(r)
+		log.Printf("Updating %s params (token %q): %s", strategy.String(), token, body)
+		if _, err := mgr.UpdateStrategyParams(strategy, token, body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"strategy": strategy.String(),
+		"token":    token,
+		"params":   mgr.GetStrategyParams(strategy, token),
+		"defaults": signaler.GetDefaultParams(strategy),
+	})
+}
+
 func UpdateMaxPLHandler(w http.ResponseWriter, r *http.Request) {
 	maxPL := r.URL.Query().Get("maxPL")
 	maxPLInt, err := strconv.ParseInt(maxPL, 10, 64)
@@ -101,8 +313,90 @@ func UpdateMaxPLHandler(w http.ResponseWriter, r *http.Request) {
 	mgr.UpdateMaxPL(maxPLInt)
 }
 
+// KillSwitchHandler cancels every trader's orders, flattens their positions and blocks new starts until
+// /rearmRisk.
+func KillSwitchHandler(w http.ResponseWriter, r *http.Request) {
+	log := LoggerFrom(r)
+	log.Printf("Kill switch pulled from the front end")
+	mgr.KillSwitch("kill switch pulled manually")
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(mgr.GetRiskStatus())
+}
+
+func RearmRiskHandler(w http.ResponseWriter, r *http.Request) {
+	log := LoggerFrom(r)
+	log.Printf("Re-arming the risk manager")
+	mgr.RearmRisk()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(mgr.GetRiskStatus())
+}
+
+func RiskStatusHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(mgr.GetRiskStatus())
+}
+
+// UpdateRiskLimitsHandler sets any of dailyLossLimitUSD, maxDrawdownPct, maxPositionUSD and maxOrdersPerMinute;
+// the ones left out keep their values and 0 disables a limit.
+func UpdateRiskLimitsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limits := mgr.GetRiskLimits()
+	for name, field := range map[string]*float64{
+		"dailyLossLimitUSD": &limits.DailyLossLimitUSD,
+		"maxDrawdownPct":    &limits.MaxDrawdownPct,
+		"maxPositionUSD":    &limits.MaxPositionUSD,
+	} {
+		if raw := query.Get(name); raw != "" {
+			value, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				http.Error(w, "invalid "+name, http.StatusBadRequest)
+				return
+			}
+			*field = value
+		}
+	}
+	if raw := query.Get("maxOrdersPerMinute"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			http.Error(w, "invalid maxOrdersPerMinute", http.StatusBadRequest)
+			return
+		}
+		limits.MaxOrdersPerMinute = value
+	}
+	if err := mgr.UpdateRiskLimits(limits); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(limits)
+}
+
+// PositionsHandler lists each running trader's entry units, unit count and average entry price.
+func PositionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(mgr.GetPositions())
+}
+
+// PriceHistoryHandler pages back through each running token's ticks, limit (default 500, at most 5000) at a time
+// from before before (unix seconds or RFC3339), the newest page when before is left out.
 func PriceHistoryHandler(w http.ResponseWriter, r *http.Request) {
-	json.NewEncoder(w).Encode(mgr.GetAllPriceHistory())
+	before, err := parseTimeParam(r.URL.Query().Get("before"))
+	if err != nil {
+		http.Error(w, "invalid before: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := defaultPriceHistoryLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > maxPriceHistoryLimit {
+			http.Error(w, fmt.Sprintf("invalid limit, expected 1 to %d", maxPriceHistoryLimit), http.StatusBadRequest)
+			return
+		}
+	}
+	json.NewEncoder(w).Encode(mgr.GetAllPriceHistory(before, limit))
 }
 
 func CandleHistoryHandler(w http.ResponseWriter, r *http.Request) {
@@ -119,10 +413,129 @@ func UpdateAllocatedFundsHandler(w http.ResponseWriter, r *http.Request) {
 	mgr.UpdateAllocatedFunds(allocatedFundsFloat)
 }
 
+// UpdateAllocationPolicyHandler sets how the funds are split across the running traders, e.g.
+// /updateAllocationPolicy?policy=AllocationFixedWeights&weights=ETH-USD:0.6,LINK-USD:0.4&rebalanceMinutes=60.
+// Weights only apply to AllocationFixedWeights, rebalanceMinutes defaults to the current interval.
+func UpdateAllocationPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	current := mgr.GetAllocation()
+	policy := enum.GetAllocationPolicyFromString(current.Policy)
+	if raw := query.Get("policy"); raw != "" {
+		policy = enum.GetAllocationPolicyFromString(raw)
+	}
+	weights := current.FixedWeights
+	if raw := query.Get("weights"); raw != "" {
+		weights = make(map[string]float64)
+		for _, pair := range strings.Split(raw, ",") {
+			token, value, ok := strings.Cut(pair, ":")
+			weight, err := strconv.ParseFloat(value, 64)
+			if !ok || err != nil {
+				http.Error(w, "invalid weight "+pair, http.StatusBadRequest)
+				return
+			}
+			weights[token] = weight
+		}
+	}
+	interval := time.Duration(current.RebalanceMinutes * float64(time.Minute))
+	if raw := query.Get("rebalanceMinutes"); raw != "" {
+		minutes, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			http.Error(w, "invalid rebalanceMinutes", http.StatusBadRequest)
+			return
+		}
+		interval = time.Duration(minutes * float64(time.Minute))
+	}
+	log := LoggerFrom(r)
+	log.Printf("Updating allocation policy to %s", policy.String())
+	if err := mgr.UpdateAllocationPolicy(policy, weights, interval); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(mgr.GetAllocation())
+}
+
+func AllocationHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(mgr.GetAllocation())
+}
+
 func UpdateExchangeHandler(w http.ResponseWriter, r *http.Request) {
 	exchange := r.URL.Query().Get("exchange")
 	exchangeEnum := enum.GetExchangeFromString(exchange)
-	mgr.UpdateExchange(exchangeEnum)
+	if err := mgr.UpdateExchange(exchangeEnum); err != nil {
+		http.Error(w, "cannot update exchange: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"exchange": exchangeEnum.String(),
+	})
+}
+
+// TradesHandler lists journaled fills, optionally for one token and a [from, to) window given as RFC3339 or unix
+// seconds.
+func TradesHandler(w http.ResponseWriter, r *http.Request) {
+	token, from, to, err := getTradeQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	trades, err := mgr.GetTrades(token, from, to)
+	if err != nil {
+		http.Error(w, "cannot read trades: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(trades)
+}
+
+// TradeSummaryHandler reports realized/unrealized P&L per token and strategy, with the same filters as /trades.
+func TradeSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	token, from, to, err := getTradeQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	summary, err := mgr.GetTradeSummary(token, from, to)
+	if err != nil {
+		http.Error(w, "cannot read trades: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(summary)
+}
+
+func getTradeQuery(r *http.Request) (string, time.Time, time.Time, error) {
+	q := r.URL.Query()
+	from, err := parseTimeParam(q.Get("from"))
+	if err != nil {
+		return "", time.Time{}, time.Time{}, fmt.Errorf("invalid from: %w", err)
+	}
+	to, err := parseTimeParam(q.Get("to"))
+	if err != nil {
+		return "", time.Time{}, time.Time{}, fmt.Errorf("invalid to: %w", err)
+	}
+	return q.Get("token"), from, to, nil
+}
+
+func parseTimeParam(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if seconds, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+func getEnvOrDefault(key string, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
 }
 
 // ---------- MAIN ----------
@@ -135,11 +548,37 @@ func main() {
 		l = &logger{log.New(os.Stdout, "", log.LstdFlags)}
 	}
 
+	// trader and strategy state that has to survive a restart
+	store, err := persistence.Open(stateDbPath)
+	if err != nil {
+		log.Fatalf("Could not open state store: %v", err)
+	}
+	defer store.Close()
+
+	// append-only record of every fill
+	tradeJournal, err := journal.Open(tradeJournalPath)
+	if err != nil {
+		log.Fatalf("Could not open trade journal: %v", err)
+	}
+	defer tradeJournal.Close()
+
+	// closed candles from the feed and every history fetch, for research and for seeding without refetching
+	candleArchive, err := archive.Open(candleArchiveDir)
+	if err != nil {
+		log.Fatalf("Could not open candle archive: %v", err)
+	}
+
+	// strategy params, the defaults with the params file's changes and per-token overrides on top
+	strategyParams, err := signaler.LoadParamsStore(strategyParamsPath)
+	if err != nil {
+		log.Fatalf("Could not load strategy params: %v", err)
+	}
+
 	// create shutdown context
 	shutdownCtx, shutdown := context.WithCancel(context.Background())
 
 	// propagate manager lifecycle context so we can skip reallocations during shutdown
-	mgr = manager.NewManager(50000.0, 1000, enum.TrendFollowing, enum.CandleSize5m, shutdownCtx, apiKey, apiSecret, tokens)
+	mgr = manager.NewManager(50000.0, 1000, enum.TrendFollowing, enum.CandleSize5m, shutdownCtx, apiKey, apiSecret, tokens, store, tradeJournal, candleArchive, strategyParams)
 
 	// listen to OS signals
 	sigCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
@@ -154,12 +593,27 @@ func main() {
 	mux.HandleFunc("/toggleToken", ToggleTokenHandler)
 	mux.HandleFunc("/updateTradingStrategy", UpdateTradingStrategyHandler)
 	mux.HandleFunc("/updateMaxPL", UpdateMaxPLHandler)
+	mux.HandleFunc("/updateRiskLimits", UpdateRiskLimitsHandler)
+	mux.HandleFunc("/riskStatus", RiskStatusHandler)
+	mux.HandleFunc("/killSwitch", KillSwitchHandler)
+	mux.HandleFunc("/rearmRisk", RearmRiskHandler)
 	mux.HandleFunc("/updateCandleSize", UpdateCandleSizeHandler)
+	mux.HandleFunc("/updateOrderType", UpdateOrderTypeHandler)
+	mux.HandleFunc("/updateExecution", UpdateExecutionHandler)
+	mux.HandleFunc("/updateSizing", UpdateSizingHandler)
+	mux.HandleFunc("/updateDerivatives", UpdateDerivativesHandler)
 	mux.HandleFunc("/updateAllocatedFunds", UpdateAllocatedFundsHandler)
+	mux.HandleFunc("/updateAllocationPolicy", UpdateAllocationPolicyHandler)
+	mux.HandleFunc("/allocation", AllocationHandler)
 	mux.HandleFunc("/updateExchange", UpdateExchangeHandler)
 	mux.HandleFunc("/ws", mgr.WebSocketHandler) // note: `mgr` is a *value* of type *Manager
+	mux.HandleFunc("/positions", PositionsHandler)
 	mux.HandleFunc("/priceHistory", PriceHistoryHandler)
 	mux.HandleFunc("/candleHistory", CandleHistoryHandler)
+	mux.HandleFunc("/trades", TradesHandler)
+	mux.HandleFunc("/tradeSummary", TradeSummaryHandler)
+	mux.HandleFunc("GET /strategies/{name}/params", StrategyParamsHandler)
+	mux.HandleFunc("PUT /strategies/{name}/params", StrategyParamsHandler)
 
 	// wrap with logging
 	handler := LoggingMiddleware(mux, l)
@@ -180,6 +634,7 @@ func main() {
 	}()
 
 	mgr.RefreshTokenBalances()
+	mgr.RestoreTraders()
 
 	// wait for shutdown signal
 	<-shutdownCtx.Done()
@@ -194,6 +649,7 @@ func main() {
 
 	//stop all traders
 	mgr.StopAll()
+	signaler.ClosePlugins()
 
 	log.Println("Server exiting")
 }
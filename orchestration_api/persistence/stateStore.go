@@ -0,0 +1,90 @@
+package persistence
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	TraderBucket   = "traders"   // one TraderSnapshot per symbol
+	PositionBucket = "positions" // one strategy PositionSnapshot per symbol
+	RiskBucket     = "risk"      // the risk manager's limits and halt state
+)
+
+// StateStore is a small embedded key/value store for the state that has to survive a restart. Values are JSON so
+// the packages that own the state also own its shape.
+type StateStore struct {
+	db *bolt.DB
+}
+
+func Open(path string) (*StateStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 2 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open state store %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range []string{TraderBucket, PositionBucket, RiskBucket} {
+			if _, err := tx.CreateBucketIfNotExists([]byte(bucket)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &StateStore{db: db}, nil
+}
+
+func (s *StateStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *StateStore) Put(bucket string, key string, value any) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(bucket)).Put([]byte(key), raw)
+	})
+}
+
+// Get unmarshals the value under key into value, reporting whether it was there.
+func (s *StateStore) Get(bucket string, key string, value any) (bool, error) {
+	var raw []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket([]byte(bucket)).Get([]byte(key)); v != nil {
+			raw = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	if err != nil || raw == nil {
+		return false, err
+	}
+	return true, json.Unmarshal(raw, value)
+}
+
+func (s *StateStore) Delete(bucket string, key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(bucket)).Delete([]byte(key))
+	})
+}
+
+// ForEach hands every raw value in bucket to fn. fn must not keep raw past the call.
+func (s *StateStore) ForEach(bucket string, fn func(key string, raw []byte) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(bucket)).ForEach(func(k, v []byte) error {
+			return fn(string(k), v)
+		})
+	})
+}
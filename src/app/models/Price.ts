@@ -3,6 +3,8 @@ type Price = {
     symbol: string;
     time: Date;
     price: number;
+    bid: number;
+    ask: number;
 };
 
 export default Price;
\ No newline at end of file